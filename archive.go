@@ -0,0 +1,530 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrChecksumMismatch represents that the checksum of an archived
+	// object reported by the archive store does not match the checksum of
+	// the local file.
+	ErrChecksumMismatch = errors.New("archive checksum mismatch")
+)
+
+// ArchiveObject is a structure that contains the information of an object
+// uploaded by an archive uploader.
+type ArchiveObject struct {
+	// Key represents the key of the object in the object storage.
+	Key string
+
+	// Size represents the number of bytes of the object.
+	Size int64
+
+	// Checksum represents the SHA-256 checksum of the object, which can
+	// be sent to the object storage to validate the upload (for example:
+	// the ChecksumSHA256 parameter of the S3 PutObject operation).
+	Checksum []byte
+
+	// File represents the local log file of the object.
+	File LogFile
+}
+
+// ArchiveStore is the public interface of an object storage (for example:
+// S3 or GCS) that an archive uploader uploads log files to.
+//
+// The archive uploader does not depend on the SDK of any object storage,
+// so applications implement this interface with a few lines of code using
+// the SDK they already use.
+type ArchiveStore interface {
+	// Upload uploads an object with the given information and the data
+	// read from the given reader, and then returns any errors encountered.
+	// The upload must be cancelled when the given context is done.
+	Upload(ctx context.Context, object ArchiveObject, body io.Reader) error
+}
+
+// ArchiveVerifier is the public interface of an archive store that can
+// report the checksum of an uploaded object. If an archive store
+// implements this interface, each uploaded object is validated against
+// the checksum of the local file before the local file is deleted.
+type ArchiveVerifier interface {
+	// Checksum returns the SHA-256 checksum of the object with the given
+	// key, and any errors encountered.
+	Checksum(ctx context.Context, key string) ([]byte, error)
+}
+
+// ArchiveUploader is the structure of an archive uploader instance.
+//
+// The archive uploader watches a log directory for completed rotated (and
+// optionally compressed) log files that match a pattern, uploads them to
+// an archive store with keys rendered from a key template, and then
+// deletes the local copies on success. Failed uploads are retried with an
+// exponential backoff, and are tried again by the next scan if all retries
+// fail.
+//
+// A log file is completed if it has not been modified for the settle time.
+// The archive uploader can also be used as the BeforeDelete hook of a
+// retention manager (see the Hook function), so that log files are always
+// archived before they are deleted.
+type ArchiveUploader struct {
+	store ArchiveStore
+	directory string
+	pattern string
+	template string
+	host string
+	settle time.Duration
+	retries int
+	backoff time.Duration
+	remove bool
+	handler func(err error)
+
+	mutex sync.Mutex
+	uploaded map[string]time.Time
+
+	context context.Context
+	contextCancel context.CancelFunc
+	contextWaitGroup *sync.WaitGroup
+}
+
+// Key renders the key template for the given log file, and then returns
+// the key of the object. For details, please refer to the comment section
+// of the KeyTemplate option.
+func (u *ArchiveUploader) Key(file LogFile) string {
+	name := filepath.Base(file.Path)
+	end := file.End.UTC()
+	pad := func(value int) string {
+		if value < 10 {
+			return "0" + strconv.Itoa(value)
+		}
+		return strconv.Itoa(value)
+	}
+	return strings.NewReplacer(
+		"{name}", name,
+		"{base}", strings.SplitN(name, ".", 2)[0],
+		"{host}", u.host,
+		"{year}", strconv.Itoa(end.Year()),
+		"{month}", pad(int(end.Month())),
+		"{day}", pad(end.Day()),
+		"{hour}", pad(end.Hour()),
+	).Replace(u.template)
+}
+
+// checksum returns the SHA-256 checksum and the size of the given file,
+// and any errors encountered.
+func (*ArchiveUploader) checksum(path string) ([]byte, int64, error) {
+	handle, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer handle.Close()
+	hash := sha256.New()
+	size, err := io.Copy(hash, handle)
+	if err != nil {
+		return nil, 0, err
+	}
+	return hash.Sum(nil), size, nil
+}
+
+// upload uploads the given object once, validates it if the archive store
+// implements the ArchiveVerifier interface, and then returns any errors
+// encountered.
+func (u *ArchiveUploader) upload(object ArchiveObject) error {
+	handle, err := os.Open(object.File.Path)
+	if err != nil {
+		return err
+	}
+	err = u.store.Upload(u.context, object, io.LimitReader(handle,
+		object.Size))
+	_ = handle.Close()
+	if err != nil {
+		return err
+	}
+	verifier, ok := u.store.(ArchiveVerifier)
+	if !ok {
+		return nil
+	}
+	checksum, err := verifier.Checksum(u.context, object.Key)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(checksum, object.Checksum) {
+		return ErrChecksumMismatch
+	}
+	return nil
+}
+
+// Upload uploads the given log file to the archive store, retrying failed
+// uploads with an exponential backoff, and then deletes the local copy on
+// success if enabled. Finally, any errors encountered are returned.
+func (u *ArchiveUploader) Upload(file LogFile) error {
+	return u.archive(file, u.remove)
+}
+
+// archive uploads the given log file to the archive store, and then
+// deletes the local copy on success if the given remove is true. Finally,
+// any errors encountered are returned.
+func (u *ArchiveUploader) archive(file LogFile, remove bool) error {
+	checksum, size, err := u.checksum(file.Path)
+	if err != nil {
+		return err
+	}
+	object := ArchiveObject {
+		Key: u.Key(file),
+		Size: size,
+		Checksum: checksum,
+		File: file,
+	}
+	backoff := u.backoff
+	for attempt := 0; ; attempt++ {
+		err = u.upload(object)
+		if err == nil || attempt >= u.retries {
+			break
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-u.context.Done():
+			return u.context.Err()
+		}
+	}
+	if err != nil {
+		return &os.PathError {
+			Op: "archive",
+			Path: file.Path,
+			Err: err,
+		}
+	}
+	if remove {
+		if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	u.mutex.Lock()
+	u.uploaded[file.Path] = file.End
+	u.mutex.Unlock()
+	return nil
+}
+
+// Scan uploads the completed log files that match the pattern and have not
+// been uploaded yet, from the oldest to the newest, and then returns any
+// errors encountered. The errors of the log files are returned as a
+// MultiError, and the other log files are still uploaded.
+func (u *ArchiveUploader) Scan() error {
+	paths, err := filepath.Glob(filepath.Join(u.directory, u.pattern))
+	if err != nil {
+		return err
+	}
+	// The log files that have been uploaded but no longer exist (for
+	// example: deleted by a retention manager) are forgotten.
+	u.mutex.Lock()
+	existing := make(map[string]time.Time, len(u.uploaded))
+	for _, path := range paths {
+		if modified, ok := u.uploaded[path]; ok {
+			existing[path] = modified
+		}
+	}
+	u.uploaded = existing
+	u.mutex.Unlock()
+
+	now := time.Now()
+	files := make([]LogFile, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if now.Sub(info.ModTime()) < u.settle {
+			continue
+		}
+		u.mutex.Lock()
+		modified, ok := u.uploaded[path]
+		u.mutex.Unlock()
+		if ok && modified.Equal(info.ModTime()) {
+			continue
+		}
+		files = append(files, LogFile {
+			Path: path,
+			Size: info.Size(),
+			End: info.ModTime(),
+		})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].End.Before(files[j].End)
+	})
+	var errs MultiError
+	for _, file := range files {
+		errs = errs.Append(u.Upload(file))
+	}
+	return errs.ErrorOrNil()
+}
+
+// Hook returns a retention hook that uploads each log file before it is
+// deleted by a retention manager, unless the log file has been uploaded.
+// The local copy is deleted by the retention manager.
+func (u *ArchiveUploader) Hook() RetentionHook {
+	return func(file LogFile) error {
+		u.mutex.Lock()
+		modified, ok := u.uploaded[file.Path]
+		u.mutex.Unlock()
+		if ok && modified.Equal(file.End) {
+			return nil
+		}
+		return u.archive(file, false)
+	}
+}
+
+// run scans the log directory on the given interval until the archive
+// uploader is closed.
+func (u *ArchiveUploader) run(interval time.Duration) {
+	defer u.contextWaitGroup.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-u.context.Done():
+			return
+		case <-ticker.C:
+			if err := u.Scan(); err != nil && u.handler != nil {
+				u.handler(err)
+			}
+		}
+	}
+}
+
+// Close stops scanning the log directory, and cancels the uploads in
+// progress.
+func (u *ArchiveUploader) Close() error {
+	u.contextCancel()
+	u.contextWaitGroup.Wait()
+	return nil
+}
+
+// ArchiveUploaderOption is a structure that contains archive uploader
+// options.
+type ArchiveUploaderOption struct {
+	// Store represents the archive store that the log files are uploaded
+	// to. This option is required.
+	Store ArchiveStore
+
+	// Directory represents the log directory that contains the rotated log
+	// files. This option is required.
+	Directory string
+
+	// Pattern represents the glob pattern (see the filepath.Match function)
+	// of the names of the rotated log files within the log directory. It
+	// must not match the log file being written. If not provided, the
+	// default value is "*.log.*".
+	Pattern string
+
+	// KeyTemplate represents the template of the keys of the uploaded
+	// objects. The placeholders {name} (the name of the log file), {base}
+	// (the name of the log file before the first dot), {host} (the host
+	// name), {year}, {month}, {day} and {hour} (the UTC modification time
+	// of the log file) are replaced. If not provided, the default value is
+	// "{host}/{year}/{month}/{day}/{name}".
+	KeyTemplate string
+
+	// SettleTime represents the duration that a log file must not have
+	// been modified for to be considered completed. If not provided, the
+	// default value is 1 minute.
+	SettleTime time.Duration
+
+	// MaxRetries represents the maximum number of retries of a failed
+	// upload. If not provided, the default value is 3.
+	MaxRetries int
+
+	// Backoff represents the delay before the first retry, which doubles
+	// for each retry. If not provided, the default value is 1 second.
+	Backoff time.Duration
+
+	// KeepLocal represents whether to keep the local copies of the log
+	// files after they are uploaded. If not provided, the default value is
+	// false.
+	KeepLocal bool
+
+	// Interval represents the interval at which the log directory is
+	// scanned. If the value is 0, the log directory is only scanned by
+	// calling the Scan function. If not provided, the default value is 1
+	// minute.
+	Interval time.Duration
+
+	// ErrorHandler represents the function called with the errors
+	// encountered by the scheduled scans. If not provided, the errors are
+	// discarded.
+	ErrorHandler func(err error)
+}
+
+// UseStore uses the given archive store as the value of the option Store.
+// Then return to the option instance itself.
+func (o *ArchiveUploaderOption) UseStore(store ArchiveStore) *ArchiveUploaderOption {
+	o.Store = store
+	return o
+}
+
+// UseDirectory uses the given directory and pattern as the values of the
+// options Directory and Pattern. For details, please refer to the comment
+// section of these options. Then return to the option instance itself.
+func (o *ArchiveUploaderOption) UseDirectory(directory, pattern string) *ArchiveUploaderOption {
+	o.Directory = directory
+	o.Pattern = pattern
+	return o
+}
+
+// UseKeyTemplate uses the given template as the value of the option
+// KeyTemplate. Then return to the option instance itself.
+func (o *ArchiveUploaderOption) UseKeyTemplate(template string) *ArchiveUploaderOption {
+	o.KeyTemplate = template
+	return o
+}
+
+// UseSettleTime uses the given duration as the value of the option
+// SettleTime. Then return to the option instance itself.
+func (o *ArchiveUploaderOption) UseSettleTime(settle time.Duration) *ArchiveUploaderOption {
+	o.SettleTime = settle
+	return o
+}
+
+// UseRetries uses the given number of retries and delay as the values of
+// the options MaxRetries and Backoff. Then return to the option instance
+// itself.
+func (o *ArchiveUploaderOption) UseRetries(retries int, backoff time.Duration) *ArchiveUploaderOption {
+	o.MaxRetries = retries
+	o.Backoff = backoff
+	return o
+}
+
+// UseKeepLocal enables the option KeepLocal. Then return to the option
+// instance itself.
+func (o *ArchiveUploaderOption) UseKeepLocal() *ArchiveUploaderOption {
+	o.KeepLocal = true
+	return o
+}
+
+// UseInterval uses the given interval as the value of the option
+// Interval. Then return to the option instance itself.
+func (o *ArchiveUploaderOption) UseInterval(interval time.Duration) *ArchiveUploaderOption {
+	o.Interval = interval
+	return o
+}
+
+// UseErrorHandler uses the given function as the value of the option
+// ErrorHandler. Then return to the option instance itself.
+func (o *ArchiveUploaderOption) UseErrorHandler(handler func(err error)) *ArchiveUploaderOption {
+	o.ErrorHandler = handler
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *ArchiveUploaderOption) Validate() error {
+	if o.Store == nil {
+		return newOptionError("Store", "must not be nil", nil)
+	}
+	if len(o.Directory) == 0 {
+		return newOptionError("Directory", "must not be empty", nil)
+	}
+	if len(o.Pattern) == 0 {
+		return newOptionError("Pattern", "must not be empty", nil)
+	}
+	if _, err := filepath.Match(o.Pattern, ""); err != nil {
+		return newOptionError("Pattern", "must be a valid glob pattern",
+			err)
+	}
+	if len(o.KeyTemplate) == 0 {
+		return newOptionError("KeyTemplate", "must not be empty", nil)
+	}
+	if o.SettleTime < 0 {
+		return newOptionError("SettleTime", "must not be negative", nil)
+	}
+	if o.MaxRetries < 0 {
+		return newOptionError("MaxRetries", "must not be negative", nil)
+	}
+	if o.Backoff < 0 {
+		return newOptionError("Backoff", "must not be negative", nil)
+	}
+	if o.Interval < 0 {
+		return newOptionError("Interval", "must not be negative", nil)
+	}
+	return nil
+}
+
+// Build builds and returns an archive uploader instance, which scans the
+// log directory on the schedule until it is closed.
+func (o *ArchiveUploaderOption) Build() (*ArchiveUploader, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	host, err := os.Hostname()
+	if err != nil {
+		host = "localhost"
+	}
+	context, contextCancel := context.WithCancel(context.Background())
+	instance := &ArchiveUploader {
+		store: o.Store,
+		directory: o.Directory,
+		pattern: o.Pattern,
+		template: o.KeyTemplate,
+		host: host,
+		settle: o.SettleTime,
+		retries: o.MaxRetries,
+		backoff: o.Backoff,
+		remove: !o.KeepLocal,
+		handler: o.ErrorHandler,
+		uploaded: make(map[string]time.Time),
+		context: context,
+		contextCancel: contextCancel,
+		contextWaitGroup: &sync.WaitGroup { },
+	}
+	if o.Interval > 0 {
+		instance.contextWaitGroup.Add(1)
+		go instance.run(o.Interval)
+	}
+	return instance, nil
+}
+
+// NewArchiveUploaderOption creates and returns an archive uploader option
+// instance with default option values.
+func NewArchiveUploaderOption() *ArchiveUploaderOption {
+	return &ArchiveUploaderOption {
+		Pattern: "*.log.*",
+		KeyTemplate: "{host}/{year}/{month}/{day}/{name}",
+		SettleTime: time.Minute,
+		MaxRetries: 3,
+		Backoff: time.Second,
+		Interval: time.Minute,
+	}
+}
@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testArchiveStore struct {
+	mutex sync.Mutex
+	objects map[string][]byte
+	failures int
+	corrupt bool
+}
+
+func (s *testArchiveStore) Upload(ctx context.Context, object ArchiveObject,
+	body io.Reader) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("test upload failure")
+	}
+	if s.corrupt {
+		data = append(data, '!')
+	}
+	s.objects[object.Key] = data
+	return nil
+}
+
+func (s *testArchiveStore) Checksum(ctx context.Context, key string) ([]byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	checksum := sha256.Sum256(s.objects[key])
+	return checksum[ : ], nil
+}
+
+func TestArchiveUploader(t *testing.T) {
+	directory := t.TempDir()
+	name := filepath.Join(directory, "app.log.1")
+	assert.NoError(t, os.WriteFile(name, []byte("Hello Test!"), 0644),
+		"Unexpected write error")
+	modified := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	assert.NoError(t, os.Chtimes(name, modified, modified),
+		"Unexpected chtimes error")
+
+	store := &testArchiveStore {
+		objects: make(map[string][]byte),
+		failures: 2,
+	}
+	uploader, err := NewArchiveUploaderOption().UseStore(store).
+		UseDirectory(directory, "app.log.*").UseInterval(0).
+		UseKeyTemplate("logs/{base}/{year}-{month}-{day}/{name}").
+		UseRetries(2, time.Millisecond).Build()
+	assert.NoError(t, err, "Unexpected build error")
+	defer uploader.Close()
+
+	assert.NoError(t, uploader.Scan(), "Unexpected scan error")
+	assert.Equal(t, "Hello Test!",
+		string(store.objects["logs/app/2020-01-02/app.log.1"]),
+		"Unexpected uploaded object")
+	_, err = os.Stat(name)
+	assert.True(t, os.IsNotExist(err), "Unexpected local copy")
+
+	assert.NoError(t, os.WriteFile(name, []byte("Hello Test!"), 0644),
+		"Unexpected write error")
+	assert.NoError(t, os.Chtimes(name, modified, modified),
+		"Unexpected chtimes error")
+	store.corrupt = true
+	err = uploader.Scan()
+	assert.True(t, errors.Is(err, ErrChecksumMismatch), "Unexpected scan error")
+	_, err = os.Stat(name)
+	assert.NoError(t, err, "Unexpected deletion of the local copy")
+
+	store.corrupt = false
+	hook := uploader.Hook()
+	file := LogFile { Path: name, End: modified }
+	assert.NoError(t, hook(file), "Unexpected hook error")
+	_, err = os.Stat(name)
+	assert.NoError(t, err, "Unexpected deletion of the local copy")
+
+	_, err = NewArchiveUploaderOption().Build()
+	assert.Error(t, err, "Unexpected build result")
+}
@@ -0,0 +1,600 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// errQueueFull represents that the queue of an asynchronous exporter is
+// full, so the log entry is dropped.
+var errQueueFull = errors.New("queue is full")
+
+// asyncSlot is a structure that contains a slot of the ring buffer of an
+// asynchronous exporter.
+type asyncSlot struct {
+	// sequence represents the sequence number of the record stored in the
+	// slot plus 1, which is published after the data has been written.
+	sequence uint64
+	data []byte
+
+	// ctx represents the context of the log entry of the record, and time
+	// represents the time of the log entry in nanoseconds. They are only
+	// stored if stale log entries are dropped.
+	ctx context.Context
+	time int64
+}
+
+// asyncQueue is the synchronizer that the wrapped exporter of an
+// asynchronous exporter writes its encoded records to.
+//
+// The queue is a lock-free multi-producer single-consumer ring buffer.
+// Producers claim a sequence number by advancing the head cursor, copy
+// the record into the slot of the sequence number, and then publish the
+// slot. The consumer goroutine collects the published slots in order into
+// batches, advances the tail cursor, and writes each batch to the
+// synchronizer with a single call.
+type asyncQueue struct {
+	head uint64
+	_ [56]byte
+	tail uint64
+	_ [56]byte
+	highWatermark uint64
+	dropped uint64
+	stale uint64
+	batches uint64
+	sleeping int32
+	closed int32
+
+	slots []asyncSlot
+	mask uint64
+	capacity uint64
+	batchSize int
+	blocking bool
+	dropStale bool
+	staleThreshold int64
+	batch []byte
+	syncer Syncer
+
+	wake chan struct { }
+	stop chan struct { }
+	done chan struct { }
+	mutex sync.Mutex
+	err error
+}
+
+// wakeup wakes up the consumer goroutine if it is sleeping.
+func (q *asyncQueue) wakeup() {
+	if atomic.CompareAndSwapInt32(&q.sleeping, 1, 0) {
+		select {
+		case q.wake <- struct { } { }:
+		default:
+		}
+	}
+}
+
+// Write copies the given encoded record into the ring buffer. If the ring
+// buffer is full, errQueueFull is returned, or the write waits for a free
+// slot if the queue is blocking.
+func (q *asyncQueue) Write(buffer []byte) (int, error) {
+	return q.write(buffer, nil, 0)
+}
+
+// write copies the given encoded record into the ring buffer with the
+// context and the time of its log entry. For details, please refer to the
+// comment section of the Write function.
+func (q *asyncQueue) write(buffer []byte, ctx context.Context,
+	clock int64) (int, error) {
+	for {
+		if atomic.LoadInt32(&q.closed) == 1 {
+			return 0, ErrClosed
+		}
+		head := atomic.LoadUint64(&q.head)
+		occupancy := head - atomic.LoadUint64(&q.tail)
+		if occupancy >= q.capacity {
+			if !q.blocking {
+				return 0, errQueueFull
+			}
+			q.wakeup()
+			runtime.Gosched()
+			continue
+		}
+		if !atomic.CompareAndSwapUint64(&q.head, head, head + 1) {
+			continue
+		}
+		slot := &q.slots[head & q.mask]
+		slot.data = append(slot.data[ : 0], buffer...)
+		slot.ctx = ctx
+		slot.time = clock
+		atomic.StoreUint64(&slot.sequence, head + 1)
+		for {
+			highest := atomic.LoadUint64(&q.highWatermark)
+			if occupancy + 1 <= highest || atomic.CompareAndSwapUint64(
+				&q.highWatermark, highest, occupancy + 1) {
+				break
+			}
+		}
+		if atomic.LoadInt32(&q.sleeping) == 1 {
+			q.wakeup()
+		}
+		return len(buffer), nil
+	}
+}
+
+// consume writes a batch of the published records to the synchronizer,
+// and then returns false if no record has been published.
+func (q *asyncQueue) consume() bool {
+	tail := atomic.LoadUint64(&q.tail)
+	batch := q.batch[ : 0]
+	count := uint64(0)
+	var clock int64
+	if q.dropStale {
+		clock = time.Now().UnixNano()
+	}
+	for count < uint64(q.batchSize) {
+		slot := &q.slots[(tail + count) & q.mask]
+		if atomic.LoadUint64(&slot.sequence) != tail + count + 1 {
+			break
+		}
+		count++
+		if slot.ctx != nil {
+			stale := q.expired(slot.ctx, slot.time, clock)
+			slot.ctx = nil
+			if stale {
+				atomic.AddUint64(&q.stale, 1)
+				continue
+			}
+		}
+		batch = append(batch, slot.data...)
+	}
+	if count == 0 {
+		return false
+	}
+	// The records have been copied into the batch, so the slots are
+	// released before the batch is written.
+	atomic.StoreUint64(&q.tail, tail + count)
+	q.batch = batch
+	if len(batch) == 0 {
+		// All the records of the batch are stale.
+		return true
+	}
+	atomic.AddUint64(&q.batches, 1)
+	if _, err := q.syncer.Write(batch); err != nil {
+		q.mutex.Lock()
+		q.err = err
+		q.mutex.Unlock()
+	}
+	return true
+}
+
+// expired checks whether a log entry with the given context and time (in
+// nanoseconds) is stale at the given clock (in nanoseconds). For details,
+// please refer to the comment section of the DropStale option of the
+// AsyncExporterOption structure.
+func (q *asyncQueue) expired(ctx context.Context, clock int64,
+	now int64) bool {
+	err := ctx.Err()
+	if err == nil {
+		return false
+	}
+	if deadline, ok := ctx.Deadline(); ok &&
+		errors.Is(err, context.DeadlineExceeded) {
+		clock = deadline.UnixNano()
+	}
+	return now - clock >= q.staleThreshold
+}
+
+// asyncContextSyncer is the synchronizer that the wrapped exporter of an
+// asynchronous exporter writes the encoded record of a log entry with a
+// cancelable context to, so that the context is queued with the record.
+type asyncContextSyncer struct {
+	*asyncQueue
+	ctx context.Context
+	time int64
+}
+
+// Write copies the given encoded record into the ring buffer with the
+// context of its log entry.
+func (s *asyncContextSyncer) Write(buffer []byte) (int, error) {
+	return s.write(buffer, s.ctx, s.time)
+}
+
+// run consumes the published records until the queue is stopped, and
+// sleeps while there are no published records.
+func (q *asyncQueue) run() {
+	defer close(q.done)
+	for {
+		if q.consume() {
+			continue
+		}
+		atomic.StoreInt32(&q.sleeping, 1)
+		// A record may have been published before the consumer goroutine
+		// started sleeping, without waking it up.
+		if q.consume() {
+			atomic.StoreInt32(&q.sleeping, 0)
+			continue
+		}
+		select {
+		case <-q.wake:
+		case <-q.stop:
+			atomic.StoreInt32(&q.sleeping, 0)
+			for q.consume() {
+			}
+			return
+		}
+	}
+}
+
+// drain waits until all records claimed so far have been written to the
+// synchronizer, and then returns the first error encountered by the
+// consumer goroutine since the previous call.
+func (q *asyncQueue) drain() error {
+	target := atomic.LoadUint64(&q.head)
+	for atomic.LoadUint64(&q.tail) < target {
+		select {
+		case <-q.done:
+			// The consumer goroutine has stopped, so the remaining
+			// records can never be written.
+			target = 0
+			continue
+		default:
+		}
+		q.wakeup()
+		time.Sleep(10 * time.Microsecond)
+	}
+	q.mutex.Lock()
+	err := q.err
+	q.err = nil
+	q.mutex.Unlock()
+	return err
+}
+
+// Flush writes all queued records, and then flushes the synchronizer. If
+// the synchronizer does not implement the Flusher interface, it is
+// synchronized instead.
+func (q *asyncQueue) Flush() error {
+	if err := q.drain(); err != nil {
+		return err
+	}
+	if flusher, ok := q.syncer.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return q.syncer.Sync()
+}
+
+// Sync writes all queued records, and then synchronizes the synchronizer.
+func (q *asyncQueue) Sync() error {
+	if err := q.drain(); err != nil {
+		return err
+	}
+	return q.syncer.Sync()
+}
+
+// Close writes all queued records, stops the consumer goroutine, and then
+// closes the synchronizer.
+func (q *asyncQueue) Close() error {
+	if !atomic.CompareAndSwapInt32(&q.closed, 0, 1) {
+		return ErrClosed
+	}
+	err := q.drain()
+	close(q.stop)
+	<-q.done
+	var errs MultiError
+	return errs.Append(err).Append(q.syncer.Close()).ErrorOrNil()
+}
+
+// AsyncStats is a structure that contains the occupancy metrics of an
+// asynchronous exporter.
+type AsyncStats struct {
+	// Capacity represents the number of slots of the ring buffer.
+	Capacity int
+
+	// Occupancy represents the number of records that are queued.
+	Occupancy int
+
+	// HighWatermark represents the highest occupancy observed so far.
+	HighWatermark int
+
+	// Enqueued represents the number of records that have been queued.
+	Enqueued uint64
+
+	// Dropped represents the number of log entries that were dropped
+	// because the ring buffer was full.
+	Dropped uint64
+
+	// Stale represents the number of log entries that were dropped because
+	// the contexts of their requests were canceled or expired. For details,
+	// please refer to the comment section of the DropStale option of the
+	// AsyncExporterOption structure.
+	Stale uint64
+
+	// Batches represents the number of batches written to the
+	// synchronizer.
+	Batches uint64
+}
+
+// AsyncExporter is the structure of the asynchronous exporter instance.
+//
+// The asynchronous exporter wraps a standard exporter, whose log level
+// span, label selector, name patterns, encoder and framing are used to
+// encode the log entries in the goroutine that outputs them. The encoded
+// log entries are then copied into a lock-free ring buffer, and a dedicated
+// goroutine writes them to the synchronizer of the wrapped exporter in
+// batches, so outputting log entries never waits for the storage device
+// and the cost of queuing is a few atomic operations instead of a channel
+// operation.
+//
+// If the ring buffer is full, the log entry is dropped and reported to the
+// drop observer with the DropOverflow reason, unless the asynchronous
+// exporter is blocking. The Sync, Flush and Close functions wait for all
+// queued log entries to be written first. Errors encountered by the
+// dedicated goroutine are returned by the next call of these functions.
+type AsyncExporter struct {
+	exporter StandardExporter
+	queue *asyncQueue
+	observer *DropObserver
+}
+
+// Export encodes a given log entry using the wrapped exporter, and then
+// queues it to be written to the synchronizer.
+//
+// Finally, any errors encountered are returned.
+func (e *AsyncExporter) Export(entry *Entry) error {
+	var err error
+	if e.queue.dropStale && entry.Context != nil &&
+		entry.Context.Done() != nil {
+		clock := entry.clock()
+		if e.queue.expired(entry.Context, clock, time.Now().UnixNano()) {
+			atomic.AddUint64(&e.queue.stale, 1)
+			e.observer.observe(entry, DropStale)
+			return nil
+		}
+		// The context is queued with the record, so that the log entry
+		// can still be dropped if it becomes stale while it is queued.
+		exporter := e.exporter
+		exporter.syncer = &asyncContextSyncer {
+			asyncQueue: e.queue,
+			ctx: entry.Context,
+			time: clock,
+		}
+		err = exporter.Export(entry)
+	} else {
+		err = e.exporter.Export(entry)
+	}
+	if err == errQueueFull {
+		atomic.AddUint64(&e.queue.dropped, 1)
+		e.observer.observe(entry, DropOverflow)
+		return nil
+	}
+	return err
+}
+
+// Match checks whether the given log entry matches the conditions of the
+// wrapped exporter. For details, please refer to the comment section of
+// the MatchingExporter interface.
+func (e *AsyncExporter) Match(entry *Entry) bool {
+	return e.exporter.Match(entry)
+}
+
+// Stats returns the occupancy metrics of the asynchronous exporter.
+func (e *AsyncExporter) Stats() AsyncStats {
+	head := atomic.LoadUint64(&e.queue.head)
+	tail := atomic.LoadUint64(&e.queue.tail)
+	return AsyncStats {
+		Capacity: int(e.queue.capacity),
+		Occupancy: int(head - tail),
+		HighWatermark: int(atomic.LoadUint64(&e.queue.highWatermark)),
+		Enqueued: head,
+		Dropped: atomic.LoadUint64(&e.queue.dropped),
+		Stale: atomic.LoadUint64(&e.queue.stale),
+		Batches: atomic.LoadUint64(&e.queue.batches),
+	}
+}
+
+// Flush writes all queued log entries, and then flushes the synchronizer
+// of the wrapped exporter.
+//
+// Finally, any errors encountered are returned.
+func (e *AsyncExporter) Flush() error {
+	return e.queue.Flush()
+}
+
+// Sync writes all queued log entries, and then synchronizes the
+// synchronizer of the wrapped exporter.
+//
+// Finally, any errors encountered are returned.
+func (e *AsyncExporter) Sync() error {
+	return e.queue.Sync()
+}
+
+// Close writes all queued log entries, stops the dedicated goroutine, and
+// then closes the synchronizer of the wrapped exporter.
+//
+// Finally, any errors encountered are returned.
+func (e *AsyncExporter) Close() error {
+	return e.queue.Close()
+}
+
+// AsyncExporterOption is a structure that contains asynchronous exporter
+// options.
+type AsyncExporterOption struct {
+	// Exporter represents the wrapped standard exporter. This option is
+	// required.
+	Exporter *StandardExporter
+
+	// Capacity represents the number of slots of the ring buffer, which
+	// is rounded up to a power of 2. If not provided, the default value is
+	// 8192.
+	Capacity int
+
+	// BatchSize represents the maximum number of log entries written to
+	// the synchronizer with a single call. If not provided, the default
+	// value is 256.
+	BatchSize int
+
+	// Blocking represents whether outputting a log entry waits for a free
+	// slot if the ring buffer is full, instead of dropping the log entry.
+	// If not provided, the default value is false.
+	Blocking bool
+
+	// Observer represents the drop observer that the dropped log entries
+	// are reported to. If not provided, the dropped log entries are only
+	// counted.
+	Observer *DropObserver
+
+	// DropStale represents whether log entries are dropped if the context
+	// of the request that output them (see the Context field of the Entry
+	// structure) has been canceled or has expired for at least the
+	// StaleThreshold, which reduces the useless log volume of abandoned
+	// requests during overload. Log entries are checked when they are
+	// output and again before they are written, so log entries that become
+	// stale while they are queued are also dropped. If not provided, the
+	// default value is false.
+	//
+	// The expiration time of an expired context is its deadline. The time
+	// when a context is canceled is not available from the context API, so
+	// the time of the log entry is used instead.
+	//
+	// Please note that only the log entries dropped when they are output
+	// are reported to the drop observer, with the DropStale reason.
+	DropStale bool
+
+	// StaleThreshold represents the minimum duration since the context of
+	// a log entry has been canceled or has expired before the log entry is
+	// dropped. It is only used if the DropStale option is enabled. If not
+	// provided, the default value is 0, and log entries are dropped as
+	// soon as their contexts are canceled or expire.
+	StaleThreshold time.Duration
+}
+
+// UseExporter uses the given standard exporter as the value of the option
+// Exporter. Then return to the option instance itself.
+func (o *AsyncExporterOption) UseExporter(exporter *StandardExporter) *AsyncExporterOption {
+	o.Exporter = exporter
+	return o
+}
+
+// UseCapacity uses the given number of slots as the value of the option
+// Capacity. Then return to the option instance itself.
+func (o *AsyncExporterOption) UseCapacity(capacity int) *AsyncExporterOption {
+	o.Capacity = capacity
+	return o
+}
+
+// UseBatchSize uses the given number of log entries as the value of the
+// option BatchSize. Then return to the option instance itself.
+func (o *AsyncExporterOption) UseBatchSize(size int) *AsyncExporterOption {
+	o.BatchSize = size
+	return o
+}
+
+// UseBlocking enables the option Blocking. Then return to the option
+// instance itself.
+func (o *AsyncExporterOption) UseBlocking() *AsyncExporterOption {
+	o.Blocking = true
+	return o
+}
+
+// UseObserver uses the given drop observer as the value of the option
+// Observer. Then return to the option instance itself.
+func (o *AsyncExporterOption) UseObserver(observer *DropObserver) *AsyncExporterOption {
+	o.Observer = observer
+	return o
+}
+
+// UseDropStale enables the option DropStale, and then uses the given
+// duration as the value of the option StaleThreshold. Then return to the
+// option instance itself.
+func (o *AsyncExporterOption) UseDropStale(threshold time.Duration) *AsyncExporterOption {
+	o.DropStale = true
+	o.StaleThreshold = threshold
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *AsyncExporterOption) Validate() error {
+	if o.Exporter == nil {
+		return newOptionError("Exporter", "must not be nil", nil)
+	}
+	if o.Capacity <= 0 || o.Capacity > 1 << 30 {
+		return newOptionError("Capacity", "must be between 1 and 2^30",
+			nil)
+	}
+	if o.BatchSize <= 0 {
+		return newOptionError("BatchSize", "must be greater than 0", nil)
+	}
+	if o.StaleThreshold < 0 {
+		return newOptionError("StaleThreshold", "must not be negative", nil)
+	}
+	return nil
+}
+
+// Build builds and returns an asynchronous exporter instance, whose
+// goroutine is started.
+func (o *AsyncExporterOption) Build() (*AsyncExporter, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	capacity := uint64(1)
+	for capacity < uint64(o.Capacity) {
+		capacity <<= 1
+	}
+	queue := &asyncQueue {
+		slots: make([]asyncSlot, capacity),
+		mask: capacity - 1,
+		capacity: capacity,
+		batchSize: o.BatchSize,
+		blocking: o.Blocking,
+		dropStale: o.DropStale,
+		staleThreshold: int64(o.StaleThreshold),
+		syncer: o.Exporter.syncer,
+		wake: make(chan struct { }, 1),
+		stop: make(chan struct { }),
+		done: make(chan struct { }),
+	}
+	instance := &AsyncExporter {
+		exporter: *o.Exporter,
+		queue: queue,
+		observer: o.Observer,
+	}
+	instance.exporter.syncer = queue
+	go queue.run()
+	return instance, nil
+}
+
+// NewAsyncExporterOption creates and returns an asynchronous exporter
+// option instance with default option values.
+func NewAsyncExporterOption() *AsyncExporterOption {
+	return &AsyncExporterOption {
+		Capacity: 8192,
+		BatchSize: 256,
+	}
+}
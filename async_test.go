@@ -0,0 +1,191 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testBlockedWriter struct {
+	testLockedWriter
+	release chan struct { }
+}
+
+func (w *testBlockedWriter) Write(data []byte) (int, error) {
+	<-w.release
+	return w.testLockedWriter.Write(data)
+}
+
+func newTestAsyncExporter(t *testing.T, writer interface {
+	Write(data []byte) (int, error) }) *StandardExporter {
+	encoder, err := NewStandardEncoderOption().UseEncoderOption(
+		EncoderOption { }).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	syncer, err := NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	exporter, err := NewStandardExporterOption().UseEncoder(encoder).
+		UseSyncer(syncer).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	return exporter
+}
+
+func TestAsyncExporter(t *testing.T) {
+	writer := &testLockedWriter { }
+	exporter, err := NewAsyncExporterOption().UseExporter(
+		newTestAsyncExporter(t, writer)).UseCapacity(100).UseBatchSize(8).
+		UseBlocking().Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.Equal(t, 128, exporter.Stats().Capacity, "Unexpected capacity")
+
+	var group sync.WaitGroup
+	for producer := 0; producer < 8; producer++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			for index := 0; index < 1000; index++ {
+				assert.NoError(t, exporter.Export(&Entry { Level: LevelInfo,
+					Message: StringMessage("queued") }),
+					"Unexpected export error")
+			}
+		}()
+	}
+	group.Wait()
+	assert.NoError(t, exporter.Sync(), "Unexpected sync error")
+	assert.Equal(t, 8000, strings.Count(writer.String(), "\"queued\"\n"),
+		"Unexpected output")
+	stats := exporter.Stats()
+	assert.Equal(t, uint64(8000), stats.Enqueued, "Unexpected stats")
+	assert.Equal(t, 0, stats.Occupancy, "Unexpected stats")
+	assert.Equal(t, uint64(0), stats.Dropped, "Unexpected stats")
+	assert.True(t, stats.HighWatermark > 0 && stats.HighWatermark <= 128,
+		"Unexpected stats")
+	assert.True(t, stats.Batches > 0 && stats.Batches <= 8000,
+		"Unexpected stats")
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+	assert.True(t, errors.Is(exporter.Export(&Entry { Level: LevelInfo,
+		Message: StringMessage("closed") }), ErrClosed),
+		"Unexpected export error")
+
+	_, err = NewAsyncExporterOption().Build()
+	assert.True(t, errors.Is(err, ErrInvalidOption), "Unexpected create error")
+}
+
+func TestAsyncExporterOverflow(t *testing.T) {
+	var mutex sync.Mutex
+	var records []DropRecord
+	observer, err := NewDropObserverOption().UseCallback(
+		func(record DropRecord) {
+			mutex.Lock()
+			records = append(records, record)
+			mutex.Unlock()
+		}).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	writer := &testBlockedWriter { release: make(chan struct { }) }
+	exporter, err := NewAsyncExporterOption().UseExporter(
+		newTestAsyncExporter(t, writer)).UseCapacity(4).UseBatchSize(1).
+		UseObserver(observer).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	for index := 0; index < 20; index++ {
+		assert.NoError(t, exporter.Export(&Entry { Name: "overflowed",
+			Level: LevelInfo, Message: StringMessage("queued") }),
+			"Unexpected export error")
+	}
+	// At most one log entry is being written and four are queued.
+	stats := exporter.Stats()
+	assert.True(t, stats.Dropped >= 15, "Unexpected stats")
+	assert.Equal(t, uint64(20), stats.Enqueued + stats.Dropped,
+		"Unexpected stats")
+
+	close(writer.release)
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+	assert.Equal(t, int(stats.Enqueued), strings.Count(writer.String(),
+		"\"queued\"\n"), "Unexpected output")
+	assert.NoError(t, observer.Close(), "Unexpected close error")
+	assert.Len(t, records, int(stats.Dropped), "Unexpected records")
+	assert.Equal(t, DropOverflow, records[0].Reason, "Unexpected record")
+	assert.Equal(t, "overflowed", records[0].Name, "Unexpected record")
+}
+
+func TestAsyncExporterDropStale(t *testing.T) {
+	var mutex sync.Mutex
+	var records []DropRecord
+	observer, err := NewDropObserverOption().UseCallback(
+		func(record DropRecord) {
+			mutex.Lock()
+			records = append(records, record)
+			mutex.Unlock()
+		}).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	writer := &testBlockedWriter { release: make(chan struct { }) }
+	exporter, err := NewAsyncExporterOption().UseExporter(
+		newTestAsyncExporter(t, writer)).UseCapacity(8).UseBatchSize(1).
+		UseObserver(observer).UseDropStale(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	live, liveCancel := context.WithCancel(context.Background())
+	defer liveCancel()
+	for _, entry := range []*Entry {
+		{ Level: LevelInfo, Message: StringMessage("first") },
+		{ Level: LevelInfo, Message: StringMessage("queued"), Context: ctx },
+		{ Level: LevelInfo, Message: StringMessage("live"), Context: live },
+	} {
+		entry.Time = time.Now()
+		assert.NoError(t, exporter.Export(entry), "Unexpected export error")
+	}
+	cancel()
+	assert.NoError(t, exporter.Export(&Entry { Name: "canceled",
+		Level: LevelInfo, Message: StringMessage("canceled"), Context: ctx,
+		Time: time.Now() }), "Unexpected export error")
+
+	close(writer.release)
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+	assert.Equal(t, "\"first\"\n\"live\"\n", writer.String(),
+		"Unexpected output")
+	assert.Equal(t, uint64(2), exporter.Stats().Stale, "Unexpected stats")
+	assert.NoError(t, observer.Close(), "Unexpected close error")
+	assert.Len(t, records, 1, "Unexpected records")
+	assert.Equal(t, DropStale, records[0].Reason, "Unexpected record")
+	assert.Equal(t, "canceled", records[0].Name, "Unexpected record")
+
+	expired, expiredCancel := context.WithDeadline(context.Background(),
+		time.Now().Add(-time.Second))
+	defer expiredCancel()
+	queue := &asyncQueue { staleThreshold: int64(time.Minute) }
+	assert.False(t, queue.expired(expired, 0, time.Now().UnixNano()),
+		"Unexpected expired context within the threshold")
+	assert.True(t, queue.expired(expired, 0,
+		time.Now().Add(time.Minute).UnixNano()),
+		"Unexpected expired context beyond the threshold")
+}
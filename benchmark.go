@@ -0,0 +1,171 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"errors"
+	"strconv"
+	"time"
+)
+
+// BenchmarkResult is a structure that contains the result of a
+// self-benchmark of a logger.
+type BenchmarkResult struct {
+	// Duration represents the measured duration, including the final
+	// flush of the exporters.
+	Duration time.Duration
+
+	// Entries represents the number of synthetic log entries exported.
+	Entries uint64
+
+	// Bytes represents the number of encoded bytes written to the
+	// synchronizers of the standard exporters.
+	Bytes uint64
+}
+
+// EntriesPerSecond returns the number of log entries exported per second.
+func (r BenchmarkResult) EntriesPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Entries) / r.Duration.Seconds()
+}
+
+// BytesPerSecond returns the number of encoded bytes written per second.
+func (r BenchmarkResult) BytesPerSecond() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) / r.Duration.Seconds()
+}
+
+// String returns the string representation of the benchmark result.
+func (r BenchmarkResult) String() string {
+	return strconv.FormatUint(r.Entries, 10) + " entries, " +
+		strconv.FormatUint(r.Bytes, 10) + " bytes in " +
+		r.Duration.String() + " (" +
+		strconv.FormatFloat(r.EntriesPerSecond(), 'f', 0, 64) +
+		" entries/s, " +
+		strconv.FormatFloat(r.BytesPerSecond(), 'f', 0, 64) + " bytes/s)"
+}
+
+// benchmarkMessages returns the representative synthetic messages used by
+// a self-benchmark, which are output in turn.
+func benchmarkMessages() []Message {
+	return []Message {
+		StringMessage("santa self-benchmark: request completed " +
+			"successfully without any retries"),
+		StructMessage {
+			Text: "santa self-benchmark: request completed",
+			Fields: ElementObject {
+				String("method", "GET"),
+				String("path", "/api/v1/benchmark"),
+				Int("status", 200),
+				Float64("latency", 0.0125),
+				Boolean("cached", false),
+				Time("started", time.Now()),
+			},
+		},
+		StructMessage {
+			Text: "santa self-benchmark: request failed",
+			Fields: ElementObject {
+				String("method", "POST"),
+				Int("status", 503),
+				Error("error", errors.New("upstream unavailable")),
+				Object("client", String("address", "192.0.2.1"),
+					Int("attempt", 3)),
+			},
+		},
+	}
+}
+
+// SelfBenchmark measures the encoding and synchronizing throughput of the
+// logger for the given duration, so capacity planning and regression
+// detection can be done in the target environment, usually at startup.
+//
+// Synthetic log entries with representative messages are encoded by the
+// encoder of each standard exporter and written to its synchronizer, and
+// passed to the Export function of any other exporter. The log level, the
+// sampler, the hooks and the filters of the exporters are bypassed, so the
+// measurement only depends on the encoders and synchronizers. The
+// exporters are flushed at the end, and the flush is included in the
+// measured duration.
+//
+// Please note that the synthetic log entries are actually written to the
+// storage devices of the exporters.
+//
+// If the logger instance has been closed, ErrClosed is returned.
+func (l *StandardLogger) SelfBenchmark(duration time.Duration) (BenchmarkResult, error) {
+	var result BenchmarkResult
+	if l.IsClosed() {
+		return result, ErrClosed
+	}
+	config := l.config()
+	messages := benchmarkMessages()
+	entry := &Entry {
+		Name: config.name,
+		Level: LevelInfo,
+		Labels: config.labels,
+	}
+	var buffer []byte
+	start := time.Now()
+	deadline := start.Add(duration)
+	for {
+		// The clock is read once every few log entries, so reading it does
+		// not dominate the measurement.
+		if result.Entries % 16 == 0 && !time.Now().Before(deadline) {
+			break
+		}
+		entry.Time = time.Now()
+		entry.Message = messages[result.Entries % uint64(len(messages))]
+		for index := 0; index < len(config.exporters); index++ {
+			exporter, ok := config.exporters[index].(*StandardExporter)
+			if !ok || exporter.encoder == nil || exporter.syncer == nil {
+				if err := config.exporters[index].Export(entry); err != nil {
+					return result, err
+				}
+				continue
+			}
+			buffer = exporter.framing.prefix(buffer[ : 0])
+			offset := len(buffer)
+			var err error
+			buffer, err = exporter.encoder.Encode(buffer, entry)
+			if err != nil {
+				return result, err
+			}
+			if buffer == nil {
+				continue
+			}
+			buffer = exporter.framing.frame(buffer, offset)
+			if _, err := exporter.syncer.Write(buffer); err != nil {
+				return result, err
+			}
+			result.Bytes += uint64(len(buffer))
+		}
+		result.Entries++
+	}
+	err := flushExporters(config.exporters)
+	result.Duration = time.Since(start)
+	return result, err
+}
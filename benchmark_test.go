@@ -0,0 +1,64 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelfBenchmark(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableSampling().DisableFlushing()
+	option.Outputting.UseStandard(writer)
+	option.ErrorOutputting.UseStandard(writer)
+	option.Encoding.UseJSON()
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	result, err := logger.SelfBenchmark(20 * time.Millisecond)
+	assert.NoError(t, err, "Unexpected benchmark error")
+	assert.Greater(t, result.Entries, uint64(0), "Unexpected entries")
+	assert.True(t, result.Duration >= 20 * time.Millisecond,
+		"Unexpected duration")
+	assert.Equal(t, uint64(len(writer.String())), result.Bytes,
+		"Unexpected bytes")
+	assert.Equal(t, int(result.Entries) * 2,
+		strings.Count(writer.String(), "\n"), "Unexpected output")
+	assert.Contains(t, writer.String(), "upstream unavailable",
+		"Unexpected output")
+	assert.Greater(t, result.EntriesPerSecond(), float64(0),
+		"Unexpected entries per second")
+	assert.Greater(t, result.BytesPerSecond(), float64(0),
+		"Unexpected bytes per second")
+	assert.Contains(t, result.String(), "entries/s", "Unexpected string")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+
+	_, err = logger.SelfBenchmark(time.Millisecond)
+	assert.True(t, errors.Is(err, ErrClosed), "Unexpected benchmark error")
+}
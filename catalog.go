@@ -0,0 +1,193 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"strings"
+	"sync"
+)
+
+// Catalog is the structure of the message catalog instance.
+//
+// The message catalog maps the key of each message to localized template
+// strings of one or more locales. Template strings use named placeholders,
+// which are replaced by the values of the fields with the same name. For
+// details, please refer to the comment section of the LocalizedMessage
+// structure.
+//
+// The API provided by the message catalog is thread-safe.
+type Catalog struct {
+	mutex sync.RWMutex
+	fallback string
+	templates map[string]map[string]string
+}
+
+// Add adds the given templates of the given locale to the catalog, and
+// then returns the catalog instance itself. The key of the given map is
+// the message key, and the value is the localized template string. The
+// existing templates with the same locale and key are replaced.
+func (c *Catalog) Add(locale string, templates map[string]string) *Catalog {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	current, ok := c.templates[locale]
+	if !ok {
+		current = make(map[string]string, len(templates))
+		c.templates[locale] = current
+	}
+	for key, template := range templates {
+		current[key] = template
+	}
+	return c
+}
+
+// lookup returns the template string with the given locale and key. It
+// returns false if not found.
+func (c *Catalog) lookup(locale, key string) (string, bool) {
+	template, ok := c.templates[locale][key]
+	return template, ok
+}
+
+// Lookup returns the localized template string with the given locale and
+// key. If the template is not found, the base language of the locale (for
+// example, "zh" for "zh-CN") and then the fallback locale of the catalog
+// are used in turn. It returns false if not found in any of them.
+func (c *Catalog) Lookup(locale, key string) (string, bool) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if template, ok := c.lookup(locale, key); ok {
+		return template, true
+	}
+	if index := strings.IndexAny(locale, "-_"); index > 0 {
+		if template, ok := c.lookup(locale[ : index], key); ok {
+			return template, true
+		}
+	}
+	return c.lookup(c.fallback, key)
+}
+
+// NewCatalog creates and returns a message catalog instance with the given
+// fallback locale. The fallback locale is used when the localized template
+// string of a message is not found.
+func NewCatalog(fallback string) *Catalog {
+	return &Catalog {
+		fallback: fallback,
+		templates: make(map[string]map[string]string),
+	}
+}
+
+// LocalizedMessage is a log entry message structure containing the key of
+// a message catalog entry, the localized template string and fields.
+//
+// The localized template string uses named placeholders, for example
+// "{user} logged in", and each placeholder is replaced by the value of
+// the field with the same name when the message is rendered. Unlike the
+// NamedTemplateMessage, placeholders are matched by name rather than by
+// position, because localized templates may order them differently.
+//
+// Regardless of the locale, the key, the locale and all fields are always
+// encoded as structured data for machine processing.
+type LocalizedMessage struct {
+	// Key represents the key of the message in the message catalog.
+	Key string
+
+	// Locale represents the locale of the localized template string.
+	Locale string
+
+	// Template represents the localized template string. If it is empty,
+	// the key is used as the template string.
+	Template string
+
+	// Fields represents the parameters of the message.
+	Fields ElementObject
+}
+
+// AppendText renders the localized template string with the fields and
+// appends it to the given buffer slice, and then returns the appended
+// buffer slice.
+func (m LocalizedMessage) AppendText(buffer []byte) []byte {
+	template := m.Template
+	if len(template) == 0 {
+		template = m.Key
+	}
+	scanner := namedTemplateScanner { template: template }
+	for {
+		text, placeholder, ok := scanner.next()
+		if !ok {
+			return buffer
+		}
+		if !placeholder {
+			buffer = append(buffer, text...)
+			continue
+		}
+		found := false
+		for index := 0; index < len(m.Fields); index++ {
+			if m.Fields[index].Name == text {
+				buffer = appendFieldText(buffer, m.Fields[index])
+				found = true
+				break
+			}
+		}
+		if !found {
+			buffer = append(buffer, '{')
+			buffer = append(buffer, text...)
+			buffer = append(buffer, '}')
+		}
+	}
+}
+
+// SerializeStandard serializes the message into a standard log string and
+// appends it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (m LocalizedMessage) SerializeStandard(buffer []byte) []byte {
+	buffer = append(buffer, '"')
+	buffer = m.AppendText(buffer)
+	if len(m.Fields) == 0 {
+		return append(buffer, '"')
+	}
+	buffer = append(buffer, `" `...)
+	return m.Fields.SerializeJSON(buffer)
+}
+
+// SerializeJSON serializes the message into a JSON string and appends it
+// to the given buffer slice, and then returns the appended buffer slice.
+func (m LocalizedMessage) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, `{"text": "`...)
+	buffer = m.AppendText(buffer)
+	buffer = append(buffer, `", "key": "`...)
+	buffer = append(buffer, m.Key...)
+	buffer = append(buffer, `", "locale": "`...)
+	buffer = append(buffer, m.Locale...)
+	if len(m.Fields) == 0 {
+		return append(buffer, `"}`...)
+	}
+	buffer = append(buffer, `", "payload": `...)
+	buffer = m.Fields.SerializeJSON(buffer)
+	return append(buffer, '}')
+}
+
+// SampleText returns the text sample string of the log entry message.
+func (m LocalizedMessage) SampleText() string {
+	return m.Key
+}
@@ -0,0 +1,113 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCatalog(t *testing.T) {
+	catalog := NewCatalog("en").
+		Add("en", map[string]string {
+			"login": "{user} logged in from {ip}",
+			"logout": "{user} logged out",
+		}).
+		Add("zh", map[string]string {
+			"login": "{user} 从 {ip} 登录",
+		})
+
+	for _, sample := range []struct {
+		locale string
+		key string
+		expected string
+		ok bool
+	} {
+		{ "en", "login", "{user} logged in from {ip}", true },
+		{ "zh", "login", "{user} 从 {ip} 登录", true },
+		{ "zh-CN", "login", "{user} 从 {ip} 登录", true },
+		{ "zh-CN", "logout", "{user} logged out", true },
+		{ "fr", "login", "{user} logged in from {ip}", true },
+		{ "en", "unknown", "", false },
+	} {
+		template, ok := catalog.Lookup(sample.locale, sample.key)
+		assert.Equal(t, sample.ok, ok, "Unexpected lookup result")
+		assert.Equal(t, sample.expected, template, "Unexpected template")
+	}
+}
+
+func TestLocalizedMessage(t *testing.T) {
+	buffer := make([]byte, 0, 256)
+
+	message := LocalizedMessage {
+		Key: "login",
+		Locale: "zh",
+		Template: "{ip} {user} {missing}",
+		Fields: []Field {
+			String("user", "test"),
+			String("ip", "127.0.0.1"),
+		},
+	}
+
+	buffer = message.AppendText(buffer)
+	assert.Equal(t, "127.0.0.1 test {missing}", string(buffer),
+		"Unexpected format result")
+
+	buffer = message.SerializeJSON(buffer[ : 0])
+	assert.JSONEq(t, `{
+		"text": "127.0.0.1 test {missing}",
+		"key": "login",
+		"locale": "zh",
+		"payload": {
+			"user": "test",
+			"ip": "127.0.0.1"
+		}
+	}`, string(buffer), "Unexpected format result")
+
+	message.Template = ""
+	buffer = message.AppendText(buffer[ : 0])
+	assert.Equal(t, "login", string(buffer), "Unexpected format result")
+	assert.Equal(t, "login", message.SampleText(), "Unexpected sample result")
+}
+
+func TestTemplateLoggerLocalized(t *testing.T) {
+	option := NewTemplateOption().UseCatalog(NewCatalog("en").
+		Add("en", map[string]string { "login": "{user} logged in" }), "en")
+	option.Outputting.UseDiscard()
+	option.ErrorOutputting.UseDiscard()
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	message := logger.localize("login", []Field { String("user", "test") })
+	assert.Equal(t, "{user} logged in", message.Template,
+		"Unexpected template")
+
+	logger.SetLocale("zh")
+	assert.NoError(t, logger.Infol("login", String("user", "test")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Printl(LevelError, "login"),
+		"Unexpected print error")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+}
@@ -0,0 +1,106 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TimeSource is the type of the function that returns the current time,
+// which is used as the time of the log entries of a logger. For details,
+// please refer to the comment section of the TimeSource option of the
+// Option structure.
+type TimeSource func() time.Time
+
+// CoarseClock is the structure of a coarse clock instance.
+//
+// The coarse clock caches the current time and refreshes it in the
+// background at a fixed interval, so that getting the current time is an
+// atomic load instead of a call to the time.Now function. At extreme
+// logging rates, using the Now function of the coarse clock as the time
+// source of a logger measurably reduces the overhead of each log entry,
+// at the cost of timestamps that are up to one interval old. Log entries
+// output within the same interval have the same time.
+//
+// Please note that the Close function must be called when the clock is
+// no longer used, to stop the background refreshing.
+type CoarseClock struct {
+	now atomic.Pointer[time.Time]
+	ticker *time.Ticker
+	done chan struct { }
+	stopped chan struct { }
+	once sync.Once
+}
+
+// NewCoarseClock creates and returns a coarse clock instance that refreshes
+// the current time at the given interval. If the given interval is less
+// than one millisecond, one millisecond is used.
+func NewCoarseClock(interval time.Duration) *CoarseClock {
+	if interval < time.Millisecond {
+		interval = time.Millisecond
+	}
+	clock := &CoarseClock {
+		ticker: time.NewTicker(interval),
+		done: make(chan struct { }),
+		stopped: make(chan struct { }),
+	}
+	clock.refresh(time.Now())
+	go clock.run()
+	return clock
+}
+
+// refresh caches the given time as the current time.
+func (c *CoarseClock) refresh(now time.Time) {
+	c.now.Store(&now)
+}
+
+// run refreshes the current time at each tick until the clock is closed.
+func (c *CoarseClock) run() {
+	defer close(c.stopped)
+	for {
+		select {
+		case now := <-c.ticker.C:
+			c.refresh(now)
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Now returns the cached current time. It can be used as a time source.
+func (c *CoarseClock) Now() time.Time {
+	return *c.now.Load()
+}
+
+// Close stops refreshing the current time. After the clock is closed, the
+// Now function returns the time of the last refresh.
+func (c *CoarseClock) Close() error {
+	c.once.Do(func() {
+		c.ticker.Stop()
+		close(c.done)
+	})
+	<-c.stopped
+	return nil
+}
@@ -0,0 +1,78 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoarseClock(t *testing.T) {
+	clock := NewCoarseClock(0)
+	first := clock.Now()
+	assert.WithinDuration(t, time.Now(), first, time.Second,
+		"Unexpected coarse time")
+	assert.Eventually(t, func() bool {
+		return clock.Now().After(first)
+	}, time.Second, time.Millisecond, "Unexpected refresh")
+
+	assert.NoError(t, clock.Close(), "Unexpected close error")
+	assert.NoError(t, clock.Close(), "Unexpected close error")
+	stopped := clock.Now()
+	time.Sleep(time.Millisecond * 5)
+	assert.Equal(t, stopped, clock.Now(), "Unexpected refresh after close")
+}
+
+func TestStandardLoggerTimeSource(t *testing.T) {
+	writer := &testLockedWriter { }
+	fixed := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	option := NewStandardOption().DisableSampling().DisableFlushing().
+		UseTimeSource(func() time.Time { return fixed })
+	option.Outputting.UseStandard(writer)
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, logger.Info(StringMessage("fixed")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+	assert.Contains(t, writer.String(), "2024-01-02T03:04:05Z",
+		"Unexpected entry time")
+}
+
+func BenchmarkCoarseClockNow(b *testing.B) {
+	clock := NewCoarseClock(time.Millisecond)
+	defer clock.Close()
+	b.ReportAllocs()
+	for index := 0; index < b.N; index++ {
+		clock.Now()
+	}
+}
+
+func BenchmarkTimeNow(b *testing.B) {
+	b.ReportAllocs()
+	for index := 0; index < b.N; index++ {
+		time.Now()
+	}
+}
@@ -0,0 +1,143 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+// CombinedLogger is the structure of a combined logger instance.
+//
+// The combined logger is a facade that forwards each log entry to all of
+// its underlying loggers, and each underlying logger outputs the log entry
+// with its own configuration (including but not limited to: minimum log
+// entry level, sampler, hooks and exporters). It is usually used during a
+// transitional period, when two logging destinations with different
+// configurations must both receive every log entry.
+//
+// The API provided by the combined logger is thread-safe if the API of
+// each underlying logger is thread-safe.
+type CombinedLogger struct {
+	loggers []*StandardLogger
+}
+
+// Output outputs the log entry of the given log level and message to each
+// underlying logger, even if outputting to one or more loggers fails.
+// Finally, all errors encountered are returned as a MultiError, or nil if
+// no error is encountered. For details, please refer to the comment section
+// of the Output function of the StandardLogger structure.
+//
+// Please note that this is a low-level API, and the high-level API
+// usually provided by the logger is used internally. Unless necessary,
+// applications should not use this API directly.
+func (l *CombinedLogger) Output(stacks int, level Level, message Message) error {
+	var errs MultiError
+	for index := 0; index < len(l.loggers); index++ {
+		errs = errs.Append(l.loggers[index].Output(stacks + 1, level,
+			message))
+	}
+	return errs.ErrorOrNil()
+}
+
+// Print outputs log entries for a given log level and message, and then
+// returns any errors encountered.
+func (l *CombinedLogger) Print(level Level, message Message) error {
+	return l.Output(2, level, message)
+}
+
+// Debug outputs a given log message with a log level of DEBUG, and then
+// returns any errors encountered.
+func (l *CombinedLogger) Debug(message Message) error {
+	return l.Output(2, LevelDebug, message)
+}
+
+// Info outputs a given log message with a log level of INFO, and then
+// returns any errors encountered.
+func (l *CombinedLogger) Info(message Message) error {
+	return l.Output(2, LevelInfo, message)
+}
+
+// Warning outputs a given log message with a log level of WARNING, and
+// then returns any errors encountered.
+func (l *CombinedLogger) Warning(message Message) error {
+	return l.Output(2, LevelWarning, message)
+}
+
+// Error outputs a given log message with a log level of ERROR, and then
+// returns any errors encountered.
+func (l *CombinedLogger) Error(message Message) error {
+	return l.Output(2, LevelError, message)
+}
+
+// Fatal outputs a given log message with a log level of FATAL, and then
+// returns any errors encountered.
+func (l *CombinedLogger) Fatal(message Message) error {
+	return l.Output(2, LevelFatal, message)
+}
+
+// Loggers returns the underlying loggers of the combined logger. The
+// returned slice is shared with the combined logger and must not be
+// modified.
+func (l *CombinedLogger) Loggers() []*StandardLogger {
+	return l.loggers
+}
+
+// Sync synchronizes each underlying logger, even if synchronizing one or
+// more loggers fails. Finally, all errors encountered are returned as a
+// MultiError, or nil if no error is encountered. For details, please refer
+// to the comment section of the Sync function of the StandardLogger
+// structure.
+func (l *CombinedLogger) Sync() error {
+	var errs MultiError
+	for index := 0; index < len(l.loggers); index++ {
+		errs = errs.Append(l.loggers[index].Sync())
+	}
+	return errs.ErrorOrNil()
+}
+
+// Close closes each underlying logger, even if closing one or more loggers
+// fails. Finally, all errors encountered are returned as a MultiError, or
+// nil if no error is encountered. For details, please refer to the comment
+// section of the Close function of the StandardLogger structure.
+func (l *CombinedLogger) Close() error {
+	var errs MultiError
+	for index := 0; index < len(l.loggers); index++ {
+		errs = errs.Append(l.loggers[index].Close())
+	}
+	return errs.ErrorOrNil()
+}
+
+// Combine creates and returns a combined logger that forwards each log
+// entry to the given loggers. Nil loggers are ignored. For details, please
+// refer to the comment section of the CombinedLogger structure.
+//
+// The combined logger takes over the given loggers, which means that
+// closing the combined logger closes each of them. The loggers of other
+// types can be combined by their embedded StandardLogger field.
+func Combine(loggers ...*StandardLogger) *CombinedLogger {
+	combined := make([]*StandardLogger, 0, len(loggers))
+	for index := 0; index < len(loggers); index++ {
+		if loggers[index] != nil {
+			combined = append(combined, loggers[index])
+		}
+	}
+	return &CombinedLogger {
+		loggers: combined,
+	}
+}
@@ -0,0 +1,71 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombine(t *testing.T) {
+	first := &testLockedWriter { }
+	structOption := NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling()
+	structOption.Outputting.UseStandard(first)
+	firstLogger, err := structOption.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	second := &testLockedWriter { }
+	option := NewStandardOption().DisableFlushing().DisableCache().
+		DisableSampling().UseLevel(LevelWarning).
+		UseEncoding(NewEncodingOption().UseJSON())
+	option.Outputting.UseStandard(second)
+	option.ErrorOutputting.UseStandard(second)
+	secondLogger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	logger := Combine(&firstLogger.StandardLogger, nil, secondLogger)
+	assert.Len(t, logger.Loggers(), 2, "Unexpected combined loggers")
+	assert.NoError(t, logger.Info(StringMessage("info")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Warning(StringMessage("warning")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Sync(), "Unexpected sync error")
+
+	assert.Contains(t, first.String(), "info", "Unexpected output data")
+	assert.Contains(t, first.String(), "warning", "Unexpected output data")
+	assert.Contains(t, first.String(), "combine_test.go",
+		"Unexpected source location")
+	assert.NotContains(t, second.String(), "info", "Unexpected output data")
+	assert.Contains(t, second.String(), `"message": "warning"`,
+		"Unexpected output data")
+
+	assert.NoError(t, firstLogger.Close(), "Unexpected close error")
+	err = logger.Print(LevelError, StringMessage("error"))
+	assert.True(t, errors.Is(err, ErrClosed), "Unexpected print error")
+	assert.Contains(t, second.String(), "error", "Unexpected output data")
+	assert.True(t, errors.Is(logger.Close(), ErrClosed),
+		"Unexpected close error")
+}
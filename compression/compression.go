@@ -0,0 +1,574 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+// Package compression provides an exporter that compresses batches of
+// encoded log entries with zstd and trained dictionaries, and a reader that
+// decompresses them (for example: in a relay server, see the Decompress
+// function).
+//
+// The package is a separate module, so that the zstd dependency is only
+// required by the users of the package.
+package compression
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+
+	"github.com/klauspost/compress/dict"
+	"github.com/klauspost/compress/zstd"
+	"github.com/nobody-night/santa"
+)
+
+const (
+	// compressionFrameDictionary represents a frame whose payload is a
+	// zstd dictionary used by the compressed batches that follow it.
+	compressionFrameDictionary byte = 'D'
+
+	// compressionFrameBatch represents a frame whose payload is a batch
+	// of framed records compressed into a single zstd frame.
+	compressionFrameBatch byte = 'B'
+
+	// compressionHeaderSize represents the size of a frame header, which
+	// is a frame kind byte followed by the payload length in bytes as a
+	// 4-byte big endian unsigned integer.
+	compressionHeaderSize = 5
+)
+
+var (
+	// ErrInvalidFrame represents that a frame read by a reader is
+	// malformed.
+	ErrInvalidFrame = errors.New("invalid compression frame")
+)
+
+// compressionSyncer is the synchronizer that the wrapped exporter of an
+// exporter writes its framed records to. It collects the
+// records into batches and samples, and writes each compressed batch to
+// the synchronizer of the wrapped exporter.
+type compressionSyncer struct {
+	mutex sync.Mutex
+	syncer santa.Syncer
+	level zstd.EncoderLevel
+	encoder *zstd.Encoder
+	batch []byte
+	entries int
+	batchSize int
+	batchBytes int
+	frame []byte
+	dictionarySize int
+	samples [][]byte
+	next int
+	trainingSamples int
+	retrainInterval int
+	batches int
+	trained bool
+	id uint32
+}
+
+// sample keeps a copy of the given record as a training sample, replacing
+// the oldest sample once the number of training samples is reached.
+func (s *compressionSyncer) sample(record []byte) {
+	if s.dictionarySize <= 0 || (s.trained && s.retrainInterval <= 0) {
+		return
+	}
+	if len(s.samples) < s.trainingSamples {
+		s.samples = append(s.samples, append([]byte(nil), record...))
+		return
+	}
+	s.samples[s.next] = append(s.samples[s.next][ : 0], record...)
+	s.next = (s.next + 1) % s.trainingSamples
+}
+
+// train trains a new dictionary from the recent samples if enough samples
+// have been collected and the dictionary is due, and then writes the new
+// dictionary to the synchronizer. If the samples are not suitable for a
+// dictionary, the current dictionary is kept.
+func (s *compressionSyncer) train() error {
+	if len(s.samples) < s.trainingSamples || s.trainingSamples <= 0 {
+		return nil
+	}
+	if s.trained && (s.retrainInterval <= 0 ||
+		s.batches < s.retrainInterval) {
+		return nil
+	}
+	samples := s.samples
+	s.samples = nil
+	s.next = 0
+	s.batches = 0
+	content, err := dict.BuildZstdDict(samples, dict.Options {
+		MaxDictSize: s.dictionarySize,
+		HashBytes: 6,
+		ZstdDictID: s.id + 1,
+		ZstdLevel: s.level,
+	})
+	if err != nil {
+		// The samples are usually too few or too uniform, so the batches
+		// are compressed with the current dictionary, if any.
+		return nil
+	}
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(s.level),
+		zstd.WithEncoderConcurrency(1), zstd.WithEncoderDict(content))
+	if err != nil {
+		return nil
+	}
+	s.encoder.Close()
+	s.encoder = encoder
+	s.trained = true
+	s.id++
+	return s.write(compressionFrameDictionary, content)
+}
+
+// write writes a frame with the given kind and payload to the synchronizer.
+func (s *compressionSyncer) write(kind byte, payload []byte) error {
+	s.frame = append(s.frame[ : 0], kind, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(s.frame[1 : compressionHeaderSize],
+		uint32(len(payload)))
+	s.frame = append(s.frame, payload...)
+	_, err := s.syncer.Write(s.frame)
+	return err
+}
+
+// flush compresses the current batch and writes it to the synchronizer.
+func (s *compressionSyncer) flush() error {
+	if s.entries == 0 {
+		return nil
+	}
+	if err := s.train(); err != nil {
+		return err
+	}
+	s.frame = append(s.frame[ : 0], compressionFrameBatch, 0, 0, 0, 0)
+	s.frame = s.encoder.EncodeAll(s.batch, s.frame)
+	binary.BigEndian.PutUint32(s.frame[1 : compressionHeaderSize],
+		uint32(len(s.frame) - compressionHeaderSize))
+	s.batch = s.batch[ : 0]
+	s.entries = 0
+	s.batches++
+	_, err := s.syncer.Write(s.frame)
+	return err
+}
+
+// Write appends the given framed record to the current batch, and then
+// writes the batch if it is full.
+func (s *compressionSyncer) Write(buffer []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.batch = append(s.batch, buffer...)
+	s.entries++
+	s.sample(buffer)
+	if s.entries < s.batchSize && len(s.batch) < s.batchBytes {
+		return len(buffer), nil
+	}
+	return len(buffer), s.flush()
+}
+
+// Flush writes the current batch, and then flushes the synchronizer. If
+// the synchronizer does not implement the Flusher interface, it is
+// synchronized instead.
+func (s *compressionSyncer) Flush() error {
+	s.mutex.Lock()
+	err := s.flush()
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	if flusher, ok := s.syncer.(santa.Flusher); ok {
+		return flusher.Flush()
+	}
+	return s.syncer.Sync()
+}
+
+// Sync writes the current batch, and then synchronizes the synchronizer.
+func (s *compressionSyncer) Sync() error {
+	s.mutex.Lock()
+	err := s.flush()
+	s.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.syncer.Sync()
+}
+
+// Close writes the current batch, and then closes the synchronizer.
+func (s *compressionSyncer) Close() error {
+	s.mutex.Lock()
+	err := s.flush()
+	s.encoder.Close()
+	s.mutex.Unlock()
+	var errs santa.MultiError
+	return errs.Append(err).Append(s.syncer.Close()).ErrorOrNil()
+}
+
+// Exporter is the structure of the compression exporter instance.
+//
+// The compression exporter wraps a standard exporter, whose log level
+// span, label selector, name patterns, encoder and framing are used to
+// encode the log entries. Instead of writing each encoded log entry to the
+// synchronizer of the wrapped exporter, the log entries are collected into
+// batches, and each batch is compressed with zstd into a single frame.
+//
+// Because highly repetitive log entries compress poorly one batch at a
+// time, the compression exporter samples the recent log entries and trains
+// a dictionary from them. The dictionary is written to the synchronizer
+// before the first batch compressed with it, so a reader only needs the
+// written data. This dramatically reduces the bandwidth of log entries
+// shipped over a network synchronizer.
+//
+// Each frame is a kind byte ('D' for a dictionary, 'B' for a batch),
+// followed by the payload length in bytes as a 4-byte big endian unsigned
+// integer, followed by the payload. Use a Reader to read the batches.
+type Exporter struct {
+	exporter *santa.StandardExporter
+	syncer *compressionSyncer
+}
+
+// Export encodes a given log entry using the wrapped exporter, and then
+// appends it to the current batch. If the batch is full, it is compressed
+// and written to the synchronizer.
+//
+// Finally, any errors encountered are returned.
+func (e *Exporter) Export(entry *santa.Entry) error {
+	return e.exporter.Export(entry)
+}
+
+// Match checks whether the given log entry matches the conditions of the
+// wrapped exporter. For details, please refer to the comment section of
+// the MatchingExporter interface.
+func (e *Exporter) Match(entry *santa.Entry) bool {
+	return e.exporter.Match(entry)
+}
+
+// Flush compresses and writes the current batch, and then flushes the
+// synchronizer of the wrapped exporter.
+//
+// Finally, any errors encountered are returned.
+func (e *Exporter) Flush() error {
+	return e.syncer.Flush()
+}
+
+// Sync compresses and writes the current batch, and then synchronizes the
+// synchronizer of the wrapped exporter.
+//
+// Finally, any errors encountered are returned.
+func (e *Exporter) Sync() error {
+	return e.syncer.Sync()
+}
+
+// Close compresses and writes the current batch, and then closes the
+// synchronizer of the wrapped exporter.
+//
+// Finally, any errors encountered are returned.
+func (e *Exporter) Close() error {
+	return e.syncer.Close()
+}
+
+// ExporterOption is a structure that contains compression exporter
+// options.
+type ExporterOption struct {
+	// Exporter represents the wrapped standard exporter. This option is
+	// required.
+	Exporter *santa.StandardExporter
+
+	// BatchSize represents the maximum number of log entries in a batch.
+	// If not provided, the default value is 64.
+	BatchSize int
+
+	// BatchBytes represents the size in bytes of the encoded log entries
+	// at which a batch is compressed, even if it is not full. If not
+	// provided, the default value is 64 KiB.
+	BatchBytes int
+
+	// Level represents the zstd compression level, from 1 (fastest) to
+	// 22 (best compression). If not provided, the default value is 3.
+	Level int
+
+	// DictionarySize represents the maximum size in bytes of a trained
+	// dictionary. If it is 0, no dictionary is trained. If not provided,
+	// the default value is 16 KiB.
+	DictionarySize int
+
+	// TrainingSamples represents the number of recent log entries that a
+	// dictionary is trained from. If not provided, the default value is
+	// 256.
+	TrainingSamples int
+
+	// RetrainInterval represents the number of batches after which the
+	// dictionary is trained again from the recent log entries, so that it
+	// follows changes in the traffic. If it is 0, the dictionary is only
+	// trained once. If not provided, the default value is 1024.
+	RetrainInterval int
+}
+
+// UseExporter uses the given standard exporter as the value of the option
+// Exporter. Then return to the option instance itself.
+func (o *ExporterOption) UseExporter(exporter *santa.StandardExporter) *ExporterOption {
+	o.Exporter = exporter
+	return o
+}
+
+// UseBatch uses the given number of log entries and size in bytes as the
+// values of the options BatchSize and BatchBytes. Then return to the option
+// instance itself.
+func (o *ExporterOption) UseBatch(size, bytes int) *ExporterOption {
+	o.BatchSize = size
+	o.BatchBytes = bytes
+	return o
+}
+
+// UseLevel uses the given zstd compression level as the value of the
+// option Level. Then return to the option instance itself.
+func (o *ExporterOption) UseLevel(level int) *ExporterOption {
+	o.Level = level
+	return o
+}
+
+// UseDictionary uses the given dictionary size, number of training samples
+// and retraining interval as the values of the options DictionarySize,
+// TrainingSamples and RetrainInterval. Then return to the option instance
+// itself.
+func (o *ExporterOption) UseDictionary(size, samples, interval int) *ExporterOption {
+	o.DictionarySize = size
+	o.TrainingSamples = samples
+	o.RetrainInterval = interval
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *ExporterOption) Validate() error {
+	if o.Exporter == nil {
+		return &santa.OptionError {
+			Option: "Exporter",
+			Reason: "must not be nil",
+		}
+	}
+	if o.BatchSize <= 0 {
+		return &santa.OptionError {
+			Option: "BatchSize",
+			Reason: "must be greater than 0",
+		}
+	}
+	if o.BatchBytes <= 0 {
+		return &santa.OptionError {
+			Option: "BatchBytes",
+			Reason: "must be greater than 0",
+		}
+	}
+	if o.Level < 1 || o.Level > 22 {
+		return &santa.OptionError {
+			Option: "Level",
+			Reason: "must be between 1 and 22",
+		}
+	}
+	if o.DictionarySize < 0 {
+		return &santa.OptionError {
+			Option: "DictionarySize",
+			Reason: "must not be negative",
+		}
+	}
+	if o.DictionarySize > 0 && o.TrainingSamples <= 0 {
+		return &santa.OptionError {
+			Option: "TrainingSamples",
+			Reason: "must be greater than 0",
+		}
+	}
+	if o.RetrainInterval < 0 {
+		return &santa.OptionError {
+			Option: "RetrainInterval",
+			Reason: "must not be negative",
+		}
+	}
+	return nil
+}
+
+// Build builds and returns a compression exporter instance.
+func (o *ExporterOption) Build() (*Exporter, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	level := zstd.EncoderLevelFromZstd(o.Level)
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(level),
+		zstd.WithEncoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	syncer := &compressionSyncer {
+		syncer: o.Exporter.Syncer(),
+		level: level,
+		encoder: encoder,
+		batchSize: o.BatchSize,
+		batchBytes: o.BatchBytes,
+		dictionarySize: o.DictionarySize,
+		trainingSamples: o.TrainingSamples,
+		retrainInterval: o.RetrainInterval,
+	}
+	return &Exporter {
+		exporter: o.Exporter.WithSyncer(syncer),
+		syncer: syncer,
+	}, nil
+}
+
+// NewExporterOption creates and returns a compression exporter option
+// instance with default option values.
+func NewExporterOption() *ExporterOption {
+	return &ExporterOption {
+		BatchSize: 64,
+		BatchBytes: 64 * 1024,
+		Level: 3,
+		DictionarySize: 16 * 1024,
+		TrainingSamples: 256,
+		RetrainInterval: 1024,
+	}
+}
+
+// Reader is the structure of the compression reader instance.
+//
+// The compression reader reads the frames written by a compression
+// exporter, and decompresses each batch with the most recent dictionary.
+type Reader struct {
+	reader io.Reader
+	decoder *zstd.Decoder
+	header [compressionHeaderSize]byte
+	payload []byte
+	batch []byte
+}
+
+// Next reads the next batch and returns its decompressed framed log
+// entries. The returned slice is only valid until the next call. If there
+// are no more batches, io.EOF is returned.
+func (r *Reader) Next() ([]byte, error) {
+	for {
+		if _, err := io.ReadFull(r.reader, r.header[ : ]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				return nil, ErrInvalidFrame
+			}
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(r.header[1 : ])
+		if cap(r.payload) < int(length) {
+			r.payload = make([]byte, length)
+		}
+		r.payload = r.payload[ : length]
+		if _, err := io.ReadFull(r.reader, r.payload); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				return nil, ErrInvalidFrame
+			}
+			return nil, err
+		}
+		switch r.header[0] {
+		case compressionFrameDictionary:
+			decoder, err := zstd.NewReader(nil,
+				zstd.WithDecoderConcurrency(1),
+				zstd.WithDecoderDicts(r.payload))
+			if err != nil {
+				return nil, ErrInvalidFrame
+			}
+			r.decoder.Close()
+			r.decoder = decoder
+		case compressionFrameBatch:
+			batch, err := r.decoder.DecodeAll(r.payload, r.batch[ : 0])
+			if err != nil {
+				return nil, ErrInvalidFrame
+			}
+			r.batch = batch
+			return batch, nil
+		default:
+			return nil, ErrInvalidFrame
+		}
+	}
+}
+
+// Close releases the resources of the compression reader. The underlying
+// reader is not closed.
+func (r *Reader) Close() {
+	r.decoder.Close()
+}
+
+// NewReader creates and returns a compression reader instance that reads
+// the frames from the given reader.
+func NewReader(reader io.Reader) (*Reader, error) {
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	return &Reader {
+		reader: reader,
+		decoder: decoder,
+	}, nil
+}
+
+// batchReader is a reader that reads the decompressed batches of a
+// compression reader one after another.
+type batchReader struct {
+	reader *Reader
+	batch []byte
+}
+
+// Read reads the decompressed batches into the given buffer slice.
+func (r *batchReader) Read(buffer []byte) (int, error) {
+	for len(r.batch) == 0 {
+		batch, err := r.reader.Next()
+		if err != nil {
+			return 0, err
+		}
+		r.batch = batch
+	}
+	size := copy(buffer, r.batch)
+	r.batch = r.batch[size : ]
+	return size, nil
+}
+
+// Close releases the resources of the compression reader.
+func (r *batchReader) Close() error {
+	r.reader.Close()
+	return nil
+}
+
+// Decompress returns a reader that reads the decompressed framed log
+// entries of the batches written by compression exporters to the given
+// reader, one batch after another. It can be used as the value of the
+// Decompressor option of a relay server (see the relay package).
+func Decompress(reader io.Reader) (io.ReadCloser, error) {
+	instance, err := NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	return &batchReader { reader: instance }, nil
+}
+
+// ExplainPipeline returns the description of the compression exporter and
+// the wrapped exporter.
+func (e *Exporter) ExplainPipeline() santa.PipelineNode {
+	return santa.PipelineNode { }.Append(santa.NewPipelineNode("exporter",
+		e.exporter))
+}
+
+// ExplainPipeline returns the description of the compression synchronizer
+// and the wrapped synchronizer.
+func (s *compressionSyncer) ExplainPipeline() santa.PipelineNode {
+	return santa.PipelineNode { Type: "zstd compression" }.
+		With("batch_size", strconv.Itoa(s.batchSize)).
+		Append(santa.NewPipelineNode("syncer", s.syncer))
+}
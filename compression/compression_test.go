@@ -0,0 +1,177 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package compression
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nobody-night/santa"
+	"github.com/nobody-night/santa/relay"
+	"github.com/stretchr/testify/assert"
+)
+
+type testLockedWriter struct {
+	mutex sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (w *testLockedWriter) Write(buffer []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.Write(buffer)
+}
+
+func (w *testLockedWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.String()
+}
+
+func TestExporter(t *testing.T) {
+	encoder, err := santa.NewStandardEncoderOption().UseEncoderOption(
+		santa.EncoderOption { }).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	writer := &testLockedWriter { }
+	syncer, err := santa.NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	exporter, err := santa.NewStandardExporterOption().UseEncoder(encoder).
+		UseSyncer(syncer).UseSpan(santa.LevelInfo, santa.LevelFatal).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	compression, err := NewExporterOption().UseExporter(exporter).
+		UseBatch(16, 1 << 20).UseDictionary(4096, 32, 0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	var expected bytes.Buffer
+	for index := 0; index < 100; index++ {
+		text := "request completed: method=GET path=/api/v1/users/" +
+			strconv.Itoa(index * 7919) + " status=200"
+		expected.WriteString(strconv.Quote(text) + "\n")
+		assert.NoError(t, compression.Export(&santa.Entry {
+			Level: santa.LevelInfo, Message: santa.StringMessage(text) }),
+			"Unexpected export error")
+		assert.NoError(t, compression.Export(&santa.Entry {
+			Level: santa.LevelDebug, Message: santa.StringMessage(text) }),
+			"Unexpected export error")
+	}
+	assert.NoError(t, compression.Flush(), "Unexpected flush error")
+	output := writer.String()
+	assert.Less(t, len(output), expected.Len() / 2, "Unexpected size")
+	dictionaries := 0
+	for offset := 0; offset + 5 <= len(output); {
+		if output[offset] == 'D' {
+			dictionaries++
+		}
+		offset += 5 + int(binary.BigEndian.Uint32([]byte(
+			output[offset + 1 : offset + 5])))
+	}
+	assert.Equal(t, 1, dictionaries, "Unexpected dictionary count")
+
+	reader, err := NewReader(bytes.NewReader([]byte(output)))
+	assert.NoError(t, err, "Unexpected create error")
+	var actual bytes.Buffer
+	batches := 0
+	for {
+		batch, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err, "Unexpected read error")
+		actual.Write(batch)
+		batches++
+	}
+	reader.Close()
+	assert.Equal(t, 7, batches, "Unexpected batch count")
+	assert.Equal(t, expected.String(), actual.String(), "Unexpected output")
+	assert.NoError(t, compression.Close(), "Unexpected close error")
+
+	reader, err = NewReader(bytes.NewReader([]byte("B\x00\x00")))
+	assert.NoError(t, err, "Unexpected create error")
+	_, err = reader.Next()
+	assert.True(t, errors.Is(err, ErrInvalidFrame), "Unexpected read error")
+	reader.Close()
+
+	_, err = NewExporterOption().Build()
+	assert.True(t, errors.Is(err, santa.ErrInvalidOption),
+		"Unexpected create error")
+	_, err = NewExporterOption().UseExporter(exporter).UseLevel(0).Build()
+	assert.True(t, errors.Is(err, santa.ErrInvalidOption),
+		"Unexpected create error")
+}
+
+func TestDecompressRelay(t *testing.T) {
+	writer := &testLockedWriter { }
+	syncer, err := santa.NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	encoder, err := santa.NewStandardEncoderOption().UseEncoderOption(
+		santa.EncoderOption { }).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	exporter, err := santa.NewStandardExporterOption().UseEncoder(encoder).
+		UseSyncer(syncer).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	server, err := relay.NewOption().UseListener(santa.ProtocolUnix,
+		filepath.Join(t.TempDir(), "relay.sock")).UseExporters(exporter).
+		UseFraming(santa.FramingLengthPrefixed).
+		UseDecompressor(Decompress).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	json, err := santa.NewJSONEncoder()
+	assert.NoError(t, err, "Unexpected create error")
+	client, err := santa.NewNetworkSyncerOption().UseCacheCapacity(0).
+		UseProtocol(santa.ProtocolUnix).
+		UseAddress(server.Address().String()).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	wrapped, err := santa.NewStandardExporterOption().UseEncoder(json).
+		UseSyncer(client).UseFraming(santa.FramingLengthPrefixed).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	compression, err := NewExporterOption().UseExporter(wrapped).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	for index := 0; index < 3; index++ {
+		assert.NoError(t, compression.Export(&santa.Entry {
+			Level: santa.LevelError, Message: santa.StringMessage("relayed") }),
+			"Unexpected export error")
+	}
+	assert.NoError(t, compression.Sync(), "Unexpected sync error")
+	assert.Eventually(t, func() bool {
+		return server.Exported() == 3
+	}, 5 * time.Second, 10 * time.Millisecond, "Unexpected exported")
+	assert.NoError(t, compression.Close(), "Unexpected close error")
+	assert.NoError(t, server.Close(), "Unexpected close error")
+
+	lines := strings.Split(strings.TrimSpace(writer.String()), "\n")
+	assert.Len(t, lines, 3, "Unexpected output")
+	for _, line := range lines {
+		assert.Contains(t, line, `"relayed"`, "Unexpected output")
+	}
+}
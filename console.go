@@ -0,0 +1,466 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"os"
+	"strconv"
+	"time"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ConsoleStyle represents the style of a log entry printed by a console
+// encoder, which is a sequence of ANSI SGR (Select Graphic Rendition)
+// parameters separated by semicolons, for example: "1;31" for bold red.
+// An empty style means that the log entry is not styled.
+type ConsoleStyle string
+
+const (
+	// ConsoleBold represents the bold style.
+	ConsoleBold ConsoleStyle = "1"
+
+	// ConsoleDim represents the dim (faint) style.
+	ConsoleDim ConsoleStyle = "2"
+
+	// ConsoleRed represents the red foreground style.
+	ConsoleRed ConsoleStyle = "31"
+
+	// ConsoleGreen represents the green foreground style.
+	ConsoleGreen ConsoleStyle = "32"
+
+	// ConsoleYellow represents the yellow foreground style.
+	ConsoleYellow ConsoleStyle = "33"
+
+	// ConsoleBlue represents the blue foreground style.
+	ConsoleBlue ConsoleStyle = "34"
+
+	// ConsoleMagenta represents the magenta foreground style.
+	ConsoleMagenta ConsoleStyle = "35"
+
+	// ConsoleCyan represents the cyan foreground style.
+	ConsoleCyan ConsoleStyle = "36"
+)
+
+// With returns the style combined with the given style.
+func (s ConsoleStyle) With(other ConsoleStyle) ConsoleStyle {
+	if len(s) == 0 {
+		return other
+	}
+	if len(other) == 0 {
+		return s
+	}
+	return s + ";" + other
+}
+
+// ConsoleProfile is a structure that contains the formatting options of
+// a console encoder.
+type ConsoleProfile struct {
+	// TimeLayout represents the layout used to format the time of the log
+	// entries. If the value is empty, the time is not printed.
+	TimeLayout string
+
+	// SourceLocation represents whether to print the source location of
+	// the log entries.
+	SourceLocation bool
+
+	// Labels represents whether to print the labels of the log entries.
+	Labels bool
+
+	// Name represents whether to print the name of the log entries.
+	Name bool
+
+	// ShortLevel represents whether to print the levels of the log entries
+	// as three letters abbreviations (for example: "WRN"), instead of
+	// their full names.
+	ShortLevel bool
+
+	// Styles represents the style of the log entries of each level. Log
+	// entries of levels without a style are not styled.
+	Styles map[Level]ConsoleStyle
+}
+
+const (
+	// ConsoleProfileCompact represents the compact profile, which prints
+	// the time of day, the abbreviated level, the name and the message.
+	ConsoleProfileCompact = "compact"
+
+	// ConsoleProfileVerbose represents the verbose profile, which prints
+	// the full time, the source location, the labels, the name, the full
+	// level and the message.
+	ConsoleProfileVerbose = "verbose"
+
+	// ConsoleProfileK9s represents the profile that is friendly to the log
+	// viewers of Kubernetes (for example: k9s and kubectl), which add their
+	// own timestamps, so only the abbreviated level, the name and the
+	// message are printed.
+	ConsoleProfileK9s = "k9s-friendly"
+)
+
+// consoleStyles returns the default styles of the levels.
+func consoleStyles() map[Level]ConsoleStyle {
+	return map[Level]ConsoleStyle {
+		LevelDebug: ConsoleDim,
+		LevelWarning: ConsoleYellow,
+		LevelError: ConsoleRed,
+		LevelFatal: ConsoleBold.With(ConsoleRed),
+	}
+}
+
+// LookupConsoleProfile returns the console profile with the given name,
+// and whether the profile exists. The names of the profiles are defined by
+// the constants beginning with ConsoleProfile...
+func LookupConsoleProfile(name string) (ConsoleProfile, bool) {
+	switch name {
+	case ConsoleProfileCompact:
+		return ConsoleProfile {
+			TimeLayout: "15:04:05.000",
+			Name: true,
+			ShortLevel: true,
+			Styles: consoleStyles(),
+		}, true
+	case ConsoleProfileVerbose:
+		return ConsoleProfile {
+			TimeLayout: time.RFC3339Nano,
+			SourceLocation: true,
+			Labels: true,
+			Name: true,
+			Styles: consoleStyles(),
+		}, true
+	case ConsoleProfileK9s:
+		return ConsoleProfile {
+			Name: true,
+			ShortLevel: true,
+			Styles: consoleStyles(),
+		}, true
+	}
+	return ConsoleProfile { }, false
+}
+
+// ColorMode represents when a console encoder styles the log entries.
+type ColorMode int
+
+const (
+	// ColorAuto represents that the log entries are styled if the standard
+	// output is a terminal. The NO_COLOR environment variable disables the
+	// styles, and the CLICOLOR_FORCE environment variable enables them
+	// even if the standard output is not a terminal.
+	ColorAuto ColorMode = iota
+
+	// ColorAlways represents that the log entries are always styled.
+	ColorAlways
+
+	// ColorNever represents that the log entries are never styled.
+	ColorNever
+)
+
+// colorTerminal checks whether the standard output is a terminal that
+// supports ANSI escape sequences. On Windows, the virtual terminal
+// processing of the console is enabled, and colors fall back to disabled
+// if it cannot be enabled. It is a variable so that the tests can replace
+// it.
+var colorTerminal = func() bool {
+	info, err := os.Stdout.Stat()
+	return err == nil && info.Mode() & os.ModeCharDevice != 0 &&
+		enableVirtualTerminal(os.Stdout)
+}
+
+// appendUTF16 converts the given UTF-8 data to UTF-16 and appends it to
+// the given buffer slice, and then returns the appended buffer slice and
+// the number of bytes converted. An incomplete UTF-8 sequence at the end of
+// the data is not converted, so that it can be completed by the next data.
+// Invalid UTF-8 sequences are converted to the replacement character.
+func appendUTF16(buffer []uint16, data []byte) ([]uint16, int) {
+	end := len(data)
+	for start := end - 1; start >= 0 && start >= end - utf8.UTFMax;
+		start-- {
+		if utf8.RuneStart(data[start]) {
+			if !utf8.FullRune(data[start : ]) {
+				end = start
+			}
+			break
+		}
+	}
+	for index := 0; index < end; {
+		value, size := utf8.DecodeRune(data[index : end])
+		buffer = utf16.AppendRune(buffer, value)
+		index += size
+	}
+	return buffer, end
+}
+
+// Enabled checks whether the log entries are styled with the color mode,
+// according to the environment variables and the standard output.
+func (m ColorMode) Enabled() bool {
+	switch m {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	}
+	// For details, please refer to https://no-color.org and
+	// https://bixense.com/clicolors.
+	if len(os.Getenv("NO_COLOR")) > 0 {
+		return false
+	}
+	if force := os.Getenv("CLICOLOR_FORCE"); len(force) > 0 && force != "0" {
+		return true
+	}
+	return colorTerminal()
+}
+
+// ConsoleEncoder is the structure of a console encoder instance.
+//
+// Console encoders encode log entries into human-readable strings like
+// standard encoders, but the formatting is selected by a named profile,
+// and the log entries of each level can be styled with ANSI escape
+// sequences (for example: bold red FATAL and dim DEBUG log entries).
+//
+// Please note that the message type of any log entry encoded with a
+// console encoder must implement the StandardSerializer interface.
+type ConsoleEncoder struct {
+	profile ConsoleProfile
+	color bool
+	indent int
+	option EncoderOption
+}
+
+// appendLevel appends the level of the log entry to the given buffer
+// slice, and then returns the appended buffer slice.
+func (e *ConsoleEncoder) appendLevel(buffer []byte, level Level) []byte {
+	if !e.profile.ShortLevel {
+		return level.AppendFormat(buffer)
+	}
+	switch level {
+	case LevelDebug:
+		return append(buffer, "DBG"...)
+	case LevelInfo:
+		return append(buffer, "INF"...)
+	case LevelWarning:
+		return append(buffer, "WRN"...)
+	case LevelError:
+		return append(buffer, "ERR"...)
+	case LevelFatal:
+		return append(buffer, "FTL"...)
+	default:
+		return strconv.AppendInt(append(buffer, "L"...), int64(level), 10)
+	}
+}
+
+// Encode encodes a given log entry into consecutive bytes in a specific
+// format, then appends to the given buffer slice, and finally returns
+// the appended buffer slice.
+func (e *ConsoleEncoder) Encode(buffer []byte, entry *Entry) ([]byte, error) {
+	message, ok := entry.Message.(StandardSerializer)
+	if !ok && entry.Message != nil {
+		return nil, ErrUnsupportedMessage
+	}
+	buffer = e.option.appendPriority(buffer, entry.Level)
+	style := e.profile.Styles[entry.Level]
+	styled := e.color && len(style) > 0
+	if styled {
+		buffer = append(buffer, "\x1b["...)
+		buffer = append(buffer, style...)
+		buffer = append(buffer, 'm')
+	}
+	if e.option.EncodeTime && len(e.profile.TimeLayout) > 0 {
+		buffer = e.option.truncateTime(entry.Time).AppendFormat(buffer,
+			e.profile.TimeLayout)
+		buffer = append(buffer, ' ')
+	}
+	if e.option.EncodeSourceLocation && e.profile.SourceLocation {
+		buffer = entry.SourceLocation.AppendString(buffer)
+		buffer = append(buffer, ' ')
+	}
+	if e.option.EncodeLabels && e.profile.Labels &&
+		entry.Labels.Count() > 0 {
+		buffer = entry.Labels.SerializeStandard(buffer)
+		buffer = append(buffer, ' ')
+	}
+	if e.option.EncodeLevel {
+		buffer = e.appendLevel(buffer, entry.Level)
+		buffer = append(buffer, ' ')
+	}
+	if e.option.EncodeName && e.profile.Name && len(entry.Name) > 0 {
+		for count := e.indent * e.option.nameDepth(entry.Name); count > 0;
+			count-- {
+			buffer = append(buffer, ' ')
+		}
+		buffer = append(buffer, e.option.entryName(entry.Name)...)
+		buffer = append(buffer, ": "...)
+	}
+	if message == nil {
+		buffer = append(buffer, "null"...)
+	} else {
+		option := e.option.fieldOption()
+		if err := option.checkFloats(message); err != nil {
+			return nil, err
+		}
+		serializer, ok := message.(FieldSerializer)
+		if ok && option != (FieldOption { }) {
+			buffer = serializer.SerializeStandardWith(buffer, option)
+		} else {
+			buffer = message.SerializeStandard(buffer)
+		}
+	}
+	if styled {
+		buffer = append(buffer, "\x1b[0m"...)
+	}
+	return append(buffer, '\n'), nil
+}
+
+// Option returns the value of the basic options of the encoder, and the
+// application can optimize the actual behavior by checking the values
+// of the options.
+func (e *ConsoleEncoder) Option() EncoderOption {
+	return e.option
+}
+
+// Colored returns true if the console encoder styles the log entries,
+// otherwise returns false.
+func (e *ConsoleEncoder) Colored() bool {
+	return e.color
+}
+
+// ConsoleEncoderOption is a structure that contains options for console
+// encoders.
+type ConsoleEncoderOption struct {
+	EncoderOption
+
+	// Profile represents the name of the console profile, which is defined
+	// by the constants beginning with ConsoleProfile... If not provided,
+	// the default value is the ConsoleProfileCompact constant.
+	Profile string
+
+	// Styles represents the styles of the log entries of each level that
+	// override the styles of the profile. An empty style disables the
+	// style of the level. If not provided, the default value is nil.
+	Styles map[Level]ConsoleStyle
+
+	// Color represents when the log entries are styled. For details,
+	// please refer to the comment section of the ColorMode constants. If
+	// not provided, the default value is the ColorAuto constant.
+	Color ColorMode
+
+	// NameIndent represents the number of spaces by which the name and the
+	// message of each log entry are indented for each ancestor of its
+	// hierarchical name (for example: "app.db" is indented once and
+	// "app.db.pool" twice), so that the log entries of nested subsystems
+	// are visually grouped. It is usually combined with the LeafName
+	// option. If not provided, the default value is 0, which means the log
+	// entries are not indented.
+	NameIndent int
+}
+
+// UseEncoderOption uses the given encoder option as part of the console
+// encoder option. For details, please refer to the comment section of
+// the EncoderOption structure. Then return to the option instance itself.
+func (o *ConsoleEncoderOption) UseEncoderOption(option EncoderOption) *ConsoleEncoderOption {
+	o.EncoderOption = option
+	return o
+}
+
+// UseProfile uses the given name as the value of the option Profile. For
+// details, please refer to the comment section of the Profile option. Then
+// return to the option instance itself.
+func (o *ConsoleEncoderOption) UseProfile(name string) *ConsoleEncoderOption {
+	o.Profile = name
+	return o
+}
+
+// UseStyle uses the given style for the log entries of the given level,
+// overriding the style of the profile. Then return to the option instance
+// itself.
+func (o *ConsoleEncoderOption) UseStyle(level Level, style ConsoleStyle) *ConsoleEncoderOption {
+	styles := make(map[Level]ConsoleStyle, len(o.Styles) + 1)
+	for key, value := range o.Styles {
+		styles[key] = value
+	}
+	styles[level] = style
+	o.Styles = styles
+	return o
+}
+
+// UseColor uses the given color mode as the value of the option Color.
+// Then return to the option instance itself.
+func (o *ConsoleEncoderOption) UseColor(mode ColorMode) *ConsoleEncoderOption {
+	o.Color = mode
+	return o
+}
+
+// UseNameIndent uses the given number of spaces as the value of the option
+// NameIndent. Then return to the option instance itself.
+func (o *ConsoleEncoderOption) UseNameIndent(spaces int) *ConsoleEncoderOption {
+	o.NameIndent = spaces
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *ConsoleEncoderOption) Validate() error {
+	if _, ok := LookupConsoleProfile(o.Profile); !ok {
+		return newOptionError("Profile", "unknown console profile \"" +
+			o.Profile + "\"", nil)
+	}
+	if o.Color < ColorAuto || o.Color > ColorNever {
+		return newOptionError("Color", "unknown color mode", nil)
+	}
+	if o.NameIndent < 0 {
+		return newOptionError("NameIndent", "must not be negative", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a console encoder instance. The color mode is
+// resolved when the console encoder is built.
+func (o *ConsoleEncoderOption) Build() (*ConsoleEncoder, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	profile, _ := LookupConsoleProfile(o.Profile)
+	for level, style := range o.Styles {
+		profile.Styles[level] = style
+	}
+	return &ConsoleEncoder {
+		profile: profile,
+		color: o.Color.Enabled(),
+		indent: o.NameIndent,
+		option: o.EncoderOption,
+	}, nil
+}
+
+// NewConsoleEncoderOption creates and returns a console encoder option
+// instance with default optional values.
+func NewConsoleEncoderOption() *ConsoleEncoderOption {
+	return &ConsoleEncoderOption {
+		EncoderOption: NewEncoderOption(),
+		Profile: ConsoleProfileCompact,
+	}
+}
+
+// NewConsoleEncoder creates and returns a console encoder instance using
+// the default optional values.
+func NewConsoleEncoder() (*ConsoleEncoder, error) {
+	return NewConsoleEncoderOption().Build()
+}
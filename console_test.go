@@ -0,0 +1,152 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsoleEncoderProfile(t *testing.T) {
+	option := NewConsoleEncoderOption().UseColor(ColorNever)
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected console encoder creation error")
+	assert.False(t, encoder.Colored(), "Unexpected color mode")
+
+	buffer, err := encoder.Encode(nil, entry)
+	assert.NoError(t, err, "Unexpected console encoder error")
+	assert.Equal(t, entry.Time.Format("15:04:05.000") +
+		" INF test: \"Hello Test!\"\n", string(buffer),
+		"Unexpected compact profile output")
+
+	encoder, err = option.UseProfile(ConsoleProfileK9s).Build()
+	assert.NoError(t, err, "Unexpected console encoder creation error")
+	buffer, err = encoder.Encode(nil, entry)
+	assert.NoError(t, err, "Unexpected console encoder error")
+	assert.Equal(t, "INF test: \"Hello Test!\"\n", string(buffer),
+		"Unexpected k9s-friendly profile output")
+
+	_, err = option.UseProfile("unknown").Build()
+	assert.Error(t, err, "Unexpected unknown profile acceptance")
+}
+
+func TestConsoleEncoderNameIndent(t *testing.T) {
+	option := NewConsoleEncoderOption().UseColor(ColorNever).
+		UseProfile(ConsoleProfileK9s).UseNameIndent(2)
+	option.EncoderOption.UseLeafName()
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected console encoder creation error")
+
+	var output []byte
+	for _, name := range []string { "app", "app.db", "app.db.pool" } {
+		output, err = encoder.Encode(output, &Entry {
+			Name: name,
+			Level: LevelInfo,
+			Message: StringMessage("Hello"),
+		})
+		assert.NoError(t, err, "Unexpected console encoder error")
+	}
+	assert.Equal(t, "INF app: \"Hello\"\nINF   db: \"Hello\"\n" +
+		"INF     pool: \"Hello\"\n", string(output),
+		"Unexpected indented output")
+
+	_, err = option.UseNameIndent(-1).Build()
+	assert.Error(t, err, "Unexpected negative indent acceptance")
+}
+
+func TestConsoleEncoderStyle(t *testing.T) {
+	option := NewConsoleEncoderOption().
+		UseProfile(ConsoleProfileK9s).
+		UseColor(ColorAlways).
+		UseStyle(LevelInfo, ConsoleGreen)
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected console encoder creation error")
+
+	buffer, err := encoder.Encode(nil, entry)
+	assert.NoError(t, err, "Unexpected console encoder error")
+	assert.Equal(t, "\x1b[32mINF test: \"Hello Test!\"\x1b[0m\n",
+		string(buffer), "Unexpected overridden style")
+
+	buffer, err = encoder.Encode(nil, &Entry {
+		Level: LevelFatal,
+		Message: StringMessage("Hello Test!"),
+	})
+	assert.NoError(t, err, "Unexpected console encoder error")
+	assert.Equal(t, "\x1b[1;31mFTL \"Hello Test!\"\x1b[0m\n",
+		string(buffer), "Unexpected profile style")
+
+	encoder, err = option.UseStyle(LevelInfo, "").Build()
+	assert.NoError(t, err, "Unexpected console encoder creation error")
+	buffer, err = encoder.Encode(nil, entry)
+	assert.NoError(t, err, "Unexpected console encoder error")
+	assert.Equal(t, "INF test: \"Hello Test!\"\n", string(buffer),
+		"Unexpected disabled style")
+}
+
+func TestColorModeEnabled(t *testing.T) {
+	terminal := colorTerminal
+	defer func() {
+		colorTerminal = terminal
+	}()
+	colorTerminal = func() bool { return true }
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("CLICOLOR_FORCE", "")
+	assert.True(t, ColorAuto.Enabled(), "Unexpected terminal color mode")
+	assert.False(t, ColorNever.Enabled(), "Unexpected never color mode")
+
+	t.Setenv("NO_COLOR", "1")
+	assert.False(t, ColorAuto.Enabled(), "Unexpected NO_COLOR handling")
+	assert.True(t, ColorAlways.Enabled(), "Unexpected always color mode")
+
+	t.Setenv("NO_COLOR", "")
+	colorTerminal = func() bool { return false }
+	assert.False(t, ColorAuto.Enabled(), "Unexpected pipe color mode")
+
+	t.Setenv("CLICOLOR_FORCE", "1")
+	assert.True(t, ColorAuto.Enabled(), "Unexpected CLICOLOR_FORCE handling")
+
+	t.Setenv("CLICOLOR_FORCE", "0")
+	assert.False(t, ColorAuto.Enabled(), "Unexpected CLICOLOR_FORCE handling")
+}
+
+func TestAppendUTF16(t *testing.T) {
+	data := []byte("héllo, 世界 😀")
+	buffer, size := appendUTF16(nil, data)
+	assert.Equal(t, len(data), size, "Unexpected converted size")
+	assert.Equal(t, utf16.Encode([]rune("héllo, 世界 😀")), buffer,
+		"Unexpected UTF-16 data")
+
+	// The incomplete sequence of the emoji is kept for the next data.
+	buffer, size = appendUTF16(buffer[ : 0], data[ : len(data) - 2])
+	assert.Equal(t, len(data) - 4, size, "Unexpected converted size")
+	assert.Equal(t, utf16.Encode([]rune("héllo, 世界 ")), buffer,
+		"Unexpected UTF-16 data")
+
+	buffer, size = appendUTF16(buffer[ : 0], []byte { 'a', 0xff, 'b' })
+	assert.Equal(t, 3, size, "Unexpected converted size")
+	assert.Equal(t, []uint16 { 'a', 0xfffd, 'b' }, buffer,
+		"Unexpected UTF-16 data")
+}
@@ -0,0 +1,130 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build windows
+
+package santa
+
+import (
+	"io"
+	"os"
+	"sync"
+	"syscall"
+)
+
+var (
+	kernel32 = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleMode = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleOutputCP = kernel32.NewProc("GetConsoleOutputCP")
+)
+
+const (
+	// enableVirtualTerminalProcessing is the console mode flag that enables
+	// the processing of ANSI escape sequences.
+	enableVirtualTerminalProcessing = 0x0004
+
+	// codePageUTF8 is the identifier of the UTF-8 code page.
+	codePageUTF8 = 65001
+
+	// consoleChunkSize is the maximum number of UTF-16 code units written
+	// to the console at once, because large writes can fail on older
+	// versions of Windows.
+	consoleChunkSize = 8192
+)
+
+// enableVirtualTerminal enables the virtual terminal processing of the
+// given console file, and then returns true if the console supports ANSI
+// escape sequences, otherwise it returns false.
+func enableVirtualTerminal(file *os.File) bool {
+	handle := syscall.Handle(file.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode & enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	result, _, _ := procSetConsoleMode.Call(uintptr(handle),
+		uintptr(mode | enableVirtualTerminalProcessing))
+	return result != 0
+}
+
+// consoleWriter returns a writer that writes UTF-16 data to the given
+// file, if it is a console whose output code page is not UTF-8, because
+// the UTF-8 data would be garbled. Otherwise, it returns the given file.
+func consoleWriter(file *os.File) io.Writer {
+	handle := syscall.Handle(file.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return file
+	}
+	if page, _, _ := procGetConsoleOutputCP.Call(); page == codePageUTF8 {
+		return file
+	}
+	return &consoleUTF16Writer {
+		handle: handle,
+	}
+}
+
+// consoleUTF16Writer is a writer that converts UTF-8 data to UTF-16, and
+// then writes it to a console with the WriteConsoleW function, which does
+// not depend on the output code page of the console.
+type consoleUTF16Writer struct {
+	handle syscall.Handle
+	mutex sync.Mutex
+	pending []byte
+	buffer []uint16
+}
+
+// Write converts the data of a given buffer slice to UTF-16, and then
+// writes it to the console. An incomplete UTF-8 sequence at the end of the
+// data is kept until the next write.
+//
+// Finally, it returns the number of bytes actually written and any
+// errors encountered.
+func (w *consoleUTF16Writer) Write(buffer []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	data := buffer
+	if len(w.pending) > 0 {
+		data = append(w.pending, buffer...)
+	}
+	var size int
+	w.buffer, size = appendUTF16(w.buffer[ : 0], data)
+	w.pending = append(w.pending[ : 0], data[size : ]...)
+	for written := 0; written < len(w.buffer); {
+		count := uint32(len(w.buffer) - written)
+		if count > consoleChunkSize {
+			count = consoleChunkSize
+		}
+		err := syscall.WriteConsole(w.handle, &w.buffer[written], count,
+			&count, nil)
+		if err != nil {
+			return 0, err
+		}
+		if count == 0 {
+			return 0, io.ErrShortWrite
+		}
+		written += int(count)
+	}
+	return len(buffer), nil
+}
@@ -0,0 +1,219 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"context"
+)
+
+// contextKey is the data type of the keys of the values stored in contexts
+// by this package, which avoids collisions with keys of other packages.
+type contextKey int
+
+const (
+	// contextKeyLogger is the key of the logger stored in a context.
+	contextKeyLogger contextKey = iota
+
+	// contextKeyFields is the key of the fields stored in a context.
+	contextKeyFields
+
+	// contextKeyRequestID is the key of the request ID stored in a context.
+	contextKeyRequestID
+)
+
+// contextFields is a node of an immutable persistent list of the fields
+// stored in a context. Each node references the node of its parent
+// context, so deriving a context with more fields only allocates a node
+// for the new fields, and the fields of the parent context are shared.
+type contextFields struct {
+	fields []Field
+	parent *contextFields
+	count int
+}
+
+// NewContext returns a context derived from the given context that carries
+// the given logger, which is used by the loggers returned by the
+// FromContext function.
+func NewContext(ctx context.Context, logger *StructLogger) context.Context {
+	return context.WithValue(ctx, contextKeyLogger, logger)
+}
+
+// ContextWith returns a context derived from the given context that
+// carries the given fields in addition to the fields carried by the given
+// context. The fields stack across nested calls, so each function of a
+// call chain can add the fields it knows about (for example: a request ID,
+// then a user ID), and the loggers returned by the FromContext function
+// add all of them to each structured log message.
+//
+// The fields are stored in an immutable persistent list, so the function
+// is thread-safe and only allocates memory for the given fields.
+func ContextWith(ctx context.Context, fields ...Field) context.Context {
+	if len(fields) == 0 {
+		return ctx
+	}
+	parent, _ := ctx.Value(contextKeyFields).(*contextFields)
+	node := &contextFields {
+		fields: append([]Field(nil), fields...),
+		parent: parent,
+		count: len(fields),
+	}
+	if parent != nil {
+		node.count += parent.count
+	}
+	return context.WithValue(ctx, contextKeyFields, node)
+}
+
+// ContextFields returns all fields carried by the given context, in the
+// order in which they were added. For details, please refer to the comment
+// section of the ContextWith function.
+func ContextFields(ctx context.Context) []Field {
+	node, _ := ctx.Value(contextKeyFields).(*contextFields)
+	if node == nil {
+		return nil
+	}
+	return node.appendTo(make([]Field, 0, node.count))
+}
+
+// appendTo appends the fields of the list to the given slice in the order
+// in which they were added, and then returns the appended slice.
+func (n *contextFields) appendTo(fields []Field) []Field {
+	offset := len(fields)
+	fields = fields[ : offset + n.count]
+	for node := n; node != nil; node = node.parent {
+		copy(fields[offset + node.count - len(node.fields) : ], node.fields)
+	}
+	return fields
+}
+
+// lookup returns the last field with the given name in the list, and
+// whether the list contains such a field. The list can be nil.
+func (n *contextFields) lookup(name string) (Field, bool) {
+	for node := n; node != nil; node = node.parent {
+		for index := len(node.fields) - 1; index >= 0; index-- {
+			if node.fields[index].Name == name {
+				return node.fields[index], true
+			}
+		}
+	}
+	return Field { }, false
+}
+
+// ContextLogger is the structure of a view of a structured logger that
+// adds the fields carried by a context to each structured log message.
+//
+// The context logger is a value that is cheap to create, and does not need
+// to be released. If the context does not carry a logger, the context
+// logger discards all log messages.
+type ContextLogger struct {
+	logger *StructLogger
+	ctx context.Context
+	fields *contextFields
+}
+
+// FromContext returns a view of the structured logger carried by the given
+// context (see the NewContext function), which adds the fields carried by
+// the given context (see the ContextWith function) before the fields of
+// each structured log message, and associates the given context with each
+// log entry. If the context does not carry a correlation ID, a new one is
+// added (see the EnableCorrelationIDInjection function).
+func FromContext(ctx context.Context) ContextLogger {
+	logger, _ := ctx.Value(contextKeyLogger).(*StructLogger)
+	fields, _ := ctx.Value(contextKeyFields).(*contextFields)
+	if logger != nil {
+		fields = injectCorrelationID(fields)
+	}
+	return ContextLogger {
+		logger: logger,
+		ctx: ctx,
+		fields: fields,
+	}
+}
+
+// Logger returns the structured logger of the view, or nil if the context
+// does not carry a logger.
+func (l ContextLogger) Logger() *StructLogger {
+	return l.logger
+}
+
+// output outputs a structured log message with the fields carried by the
+// context followed by the given fields, and then returns any errors
+// encountered.
+func (l ContextLogger) output(level Level, text string, fields []Field) error {
+	if l.logger == nil {
+		return nil
+	}
+	if l.fields == nil || !l.logger.Level().Enabled(level) {
+		return l.logger.output(l.ctx, 4, level, text, fields, false)
+	}
+	buffer := pool.Buffer.Field.New()
+	if cap(*buffer) < l.fields.count + len(fields) {
+		*buffer = make([]Field, 0, l.fields.count + len(fields))
+	}
+	*buffer = append(l.fields.appendTo((*buffer)[ : 0]), fields...)
+	err := l.logger.output(l.ctx, 4, level, text, *buffer, false)
+	pool.Buffer.Field.Free(buffer)
+	return err
+}
+
+// Prints outputs a structured log message with a given log level,
+// given description text and fields, and then returns any errors
+// encountered.
+func (l ContextLogger) Prints(level Level, text string, fields ...Field) error {
+	return l.output(level, text, fields)
+}
+
+// Debugs outputs a structured log message with a log level of DEBUG,
+// given description text and fields, and then returns any errors
+// encountered.
+func (l ContextLogger) Debugs(text string, fields ...Field) error {
+	return l.output(LevelDebug, text, fields)
+}
+
+// Infos outputs a structured log message with a log level of INFO,
+// given description text and fields, and then returns any errors
+// encountered.
+func (l ContextLogger) Infos(text string, fields ...Field) error {
+	return l.output(LevelInfo, text, fields)
+}
+
+// Warnings outputs a structured log message with a log level of WARNING,
+// given description text and fields, and then returns any errors
+// encountered.
+func (l ContextLogger) Warnings(text string, fields ...Field) error {
+	return l.output(LevelWarning, text, fields)
+}
+
+// Errors outputs a structured log message with a log level of ERROR,
+// given description text and fields, and then returns any errors
+// encountered.
+func (l ContextLogger) Errors(text string, fields ...Field) error {
+	return l.output(LevelError, text, fields)
+}
+
+// Fatals outputs a structured log message with a log level of FATAL,
+// given description text and fields, and then returns any errors
+// encountered.
+func (l ContextLogger) Fatals(text string, fields ...Field) error {
+	return l.output(LevelFatal, text, fields)
+}
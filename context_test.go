@@ -0,0 +1,114 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"strings"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextLogger(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling()
+	option.Encoding.UseStandard()
+	option.Outputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	defer logger.Close()
+
+	assert.NoError(t, FromContext(context.Background()).Infos("discarded"),
+		"Unexpected print error")
+	assert.Nil(t, FromContext(context.Background()).Logger(),
+		"Unexpected logger")
+
+	// The correlation ID is injected by default, which is tested by the
+	// TestCorrelationIDInjection function.
+	DisableCorrelationIDInjection()
+	defer EnableCorrelationIDInjection()
+
+	ctx := NewContext(context.Background(), logger)
+	ctx = ContextWith(ctx, String("request", "r1"))
+	first := ContextWith(ctx, String("user", "alice"), Int("attempt", 1))
+	second := ContextWith(ctx, String("user", "bob"))
+	assert.Equal(t, []Field { String("request", "r1"),
+		String("user", "alice"), Int("attempt", 1) }, ContextFields(first),
+		"Unexpected context fields")
+	assert.Nil(t, ContextFields(context.Background()),
+		"Unexpected context fields")
+
+	assert.NoError(t, FromContext(first).Infos("first", Int("step", 1)),
+		"Unexpected print error")
+	assert.NoError(t, FromContext(second).Infos("second"),
+		"Unexpected print error")
+	output := writer.String()
+	assert.Contains(t, output, `context_test.go:`, "Unexpected output data")
+	assert.Contains(t, output, `"first" {"request": "r1", "user": ` +
+		`"alice", "attempt": 1, "step": 1}`, "Unexpected output data")
+	assert.Contains(t, output, `"second" {"request": "r1", "user": "bob"}`,
+		"Unexpected output data")
+}
+
+func TestNewCorrelationID(t *testing.T) {
+	previous := NewCorrelationID()
+	for count := 0; count < 1000; count++ {
+		id := NewCorrelationID()
+		assert.Len(t, id, 26, "Unexpected correlation ID length")
+		assert.True(t, id > previous, "Unexpected correlation ID order")
+		previous = id
+	}
+}
+
+func TestCorrelationIDInjection(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling()
+	option.Encoding.UseStandard()
+	option.Outputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	defer logger.Close()
+
+	ctx := NewContext(context.Background(), logger)
+	assert.Empty(t, CorrelationIDFromContext(ctx), "Unexpected correlation ID")
+
+	view := FromContext(ctx)
+	assert.NoError(t, view.Infos("first"), "Unexpected print error")
+	assert.NoError(t, view.Infos("second"), "Unexpected print error")
+	lines := strings.Split(strings.TrimSpace(writer.String()), "\n")
+	assert.Len(t, lines, 2, "Unexpected output data")
+	id := lines[0][strings.Index(lines[0], `"correlation_id": "`) + 19 : ]
+	id = id[ : 26]
+	assert.Contains(t, lines[1], id, "Unexpected correlation ID")
+
+	ctx = ContextWithCorrelationID(ctx, "upstream")
+	assert.Equal(t, "upstream", CorrelationIDFromContext(ctx),
+		"Unexpected correlation ID")
+	assert.NoError(t, FromContext(ctx).Infos("third"), "Unexpected print error")
+	assert.Contains(t, writer.String(),
+		`"third" {"correlation_id": "upstream"}`, "Unexpected output data")
+}
@@ -0,0 +1,165 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"context"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// CorrelationIDName represents the name of the field that contains the
+// correlation ID of a log entry.
+const CorrelationIDName = "correlation_id"
+
+// correlationAlphabet is the Crockford's Base32 alphabet used to encode
+// correlation IDs, which excludes the letters I, L, O and U.
+const correlationAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// correlationGenerator is a structure that contains the state of the
+// correlation ID generator, which makes the correlation IDs generated
+// within the same millisecond monotonically increasing.
+type correlationGenerator struct {
+	mutex sync.Mutex
+	millisecond uint64
+	high uint64
+	low uint64
+}
+
+// next returns the 128 bits of the next correlation ID, as the high 64
+// bits and the low 64 bits.
+func (g *correlationGenerator) next() (uint64, uint64) {
+	millisecond := uint64(time.Now().UnixMilli())
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	if millisecond > g.millisecond {
+		g.millisecond = millisecond
+		g.high = rand.Uint64() & 0xffff
+		g.low = rand.Uint64()
+	} else {
+		// The clock has not advanced (or has gone backwards), so the
+		// random part of the previous ID is incremented to keep the IDs
+		// sortable.
+		g.low++
+		if g.low == 0 {
+			g.high = (g.high + 1) & 0xffff
+		}
+	}
+	return g.millisecond << 16 | g.high, g.low
+}
+
+// correlation is the correlation ID generator used by the
+// NewCorrelationID function.
+var correlation correlationGenerator
+
+// NewCorrelationID generates and returns a new correlation ID.
+//
+// The correlation ID is a 26 characters ULID-style string, encoded in
+// Crockford's Base32, which contains a 48 bits millisecond timestamp
+// followed by 80 random bits. The correlation IDs are lexicographically
+// sortable by their generation time, and the IDs generated by a process
+// within the same millisecond are monotonically increasing.
+//
+// Please note that the random bits are not cryptographically secure, so
+// correlation IDs must not be used as secrets.
+func NewCorrelationID() string {
+	high, low := correlation.next()
+	var buffer [26]byte
+	for index := len(buffer) - 1; index >= 0; index-- {
+		buffer[index] = correlationAlphabet[low & 31]
+		low = low >> 5 | high << 59
+		high >>= 5
+	}
+	return string(buffer[ : ])
+}
+
+// correlationInjection represents whether the context loggers inject a
+// correlation ID field when the context does not carry one. The value is
+// 0 if enabled, otherwise 1.
+var correlationInjection int32
+
+// EnableCorrelationIDInjection enables the injection of correlation IDs,
+// which is enabled by default.
+//
+// Once enabled, each context logger returned by the FromContext function
+// adds a field named CorrelationIDName with a new correlation ID to each
+// structured log message, if the context does not carry a field with the
+// same name (see the ContextWithCorrelationID function). All log messages
+// of the same context logger share the same correlation ID.
+func EnableCorrelationIDInjection() {
+	atomic.StoreInt32(&correlationInjection, 0)
+}
+
+// DisableCorrelationIDInjection disables the injection of correlation IDs.
+// For details, please refer to the comment section of the
+// EnableCorrelationIDInjection function.
+func DisableCorrelationIDInjection() {
+	atomic.StoreInt32(&correlationInjection, 1)
+}
+
+// ContextWithCorrelationID returns a context derived from the given context
+// that carries a field named CorrelationIDName with the given correlation
+// ID. If the given correlation ID is empty, a new correlation ID is used.
+//
+// It is usually called once per request, with the correlation ID received
+// from the upstream service, so that the log entries of all services that
+// handle the request share the same correlation ID.
+func ContextWithCorrelationID(ctx context.Context, id string) context.Context {
+	if len(id) == 0 {
+		id = NewCorrelationID()
+	}
+	return ContextWith(ctx, String(CorrelationIDName, id))
+}
+
+// CorrelationIDFromContext returns the correlation ID carried by the given
+// context, or an empty string if the context does not carry one.
+func CorrelationIDFromContext(ctx context.Context) string {
+	node, _ := ctx.Value(contextKeyFields).(*contextFields)
+	if field, ok := node.lookup(CorrelationIDName); ok {
+		return field.String
+	}
+	return ""
+}
+
+// injectCorrelationID returns the given list of fields with a field that
+// contains a new correlation ID added, unless the injection of correlation
+// IDs is disabled or the list already contains a correlation ID field.
+func injectCorrelationID(node *contextFields) *contextFields {
+	if atomic.LoadInt32(&correlationInjection) != 0 {
+		return node
+	}
+	if _, ok := node.lookup(CorrelationIDName); ok {
+		return node
+	}
+	injected := &contextFields {
+		fields: []Field { String(CorrelationIDName, NewCorrelationID()) },
+		parent: node,
+		count: 1,
+	}
+	if node != nil {
+		injected.count += node.count
+	}
+	return injected
+}
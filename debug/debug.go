@@ -0,0 +1,148 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package debug provides an HTTP handler and an expvar variable that expose
+// the state of the loggers registered in a logger registry, so operators
+// have a single place to inspect the logging state of a process.
+//
+// Importing this package has no side effects. Applications mount the
+// handler on a path of their choice (for example "/debug/santa") and
+// publish the expvar variable explicitly.
+package debug
+
+import (
+	"encoding/json"
+	"expvar"
+	"html/template"
+	"net/http"
+
+	"github.com/nobody-night/santa"
+)
+
+// levels are the log levels offered by the debug page.
+var levels = []santa.Level {
+	santa.LevelDebug,
+	santa.LevelInfo,
+	santa.LevelWarning,
+	santa.LevelError,
+	santa.LevelFatal,
+}
+
+// page is the template of the debug page.
+var page = template.Must(template.New("santa").Parse(`<!DOCTYPE html>
+<html>
+<head><title>santa loggers</title></head>
+<body>
+<table border="1">
+<tr><th>Key</th><th>Name</th><th>Level</th><th>Closed</th><th>Sampler</th>
+<th>Dropped</th><th>Exporters</th></tr>
+{{- range .States}}
+<tr>
+<td>{{.Key}}</td><td>{{.Name}}</td>
+<td>{{$state := .}}{{range $.Levels}}<form method="post" style="display:inline">
+<input type="hidden" name="key" value="{{$state.Key}}">
+<input type="hidden" name="level" value="{{.}}">
+{{if eq . $state.Level}}<b>{{.}}</b>{{else}}<button type="submit">{{.}}</button>{{end}}
+</form>{{end}}</td>
+<td>{{.Closed}}</td><td>{{.Sampler}}</td><td>{{.Dropped}}</td>
+<td>{{range .Exporters}}{{.Type}} {{.Syncer}} ({{.Buffered}} bytes buffered)<br>{{end}}</td>
+</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+// handler is the structure of the debug page handler.
+type handler struct {
+	registry *santa.Registry
+}
+
+// ServeHTTP serves the debug page. A GET request lists the state of all
+// registered loggers, as an HTML page or, if the "format" query parameter
+// is "json", as a JSON array. A POST request with the "key" and "level"
+// form values sets the level of the logger registered with the key, and
+// then redirects to the debug page.
+func (h handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodPost:
+		var level santa.Level
+		err := level.UnmarshalText([]byte(request.FormValue("level")))
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+		err = h.registry.SetLevel(request.FormValue("key"), level)
+		if err != nil {
+			http.Error(writer, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Redirect(writer, request, request.URL.Path, http.StatusSeeOther)
+		return
+	default:
+		writer.Header().Set("Allow", "GET, HEAD, POST")
+		http.Error(writer, http.StatusText(http.StatusMethodNotAllowed),
+			http.StatusMethodNotAllowed)
+		return
+	}
+
+	states := h.registry.States()
+	if request.URL.Query().Get("format") == "json" {
+		writer.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(writer).Encode(states)
+		return
+	}
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = page.Execute(writer, struct {
+		States []santa.LoggerState
+		Levels []santa.Level
+	} { states, levels })
+}
+
+// Handler returns an HTTP handler that serves the debug page of the given
+// logger registry. If the given registry is nil, the global logger
+// registry is used.
+//
+// Please note that the debug page can change the level of the registered
+// loggers, so it should only be served to trusted operators.
+func Handler(registry *santa.Registry) http.Handler {
+	if registry == nil {
+		registry = santa.GetGlobalRegistry()
+	}
+	return handler { registry: registry }
+}
+
+// Publish publishes the state of all loggers registered in the given logger
+// registry as an expvar variable with the given name. If the given registry
+// is nil, the global logger registry is used.
+//
+// Please note that, like the expvar.Publish function, it panics if the
+// name is already in use.
+func Publish(name string, registry *santa.Registry) {
+	if registry == nil {
+		registry = santa.GetGlobalRegistry()
+	}
+	expvar.Publish(name, expvar.Func(func() interface { } {
+		return registry.States()
+	}))
+}
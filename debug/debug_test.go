@@ -0,0 +1,84 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package debug
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/nobody-night/santa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	logger, err := santa.NewStandardOption().DisableFlushing().
+		UseName("testing").Build()
+	assert.NoError(t, err, "Unexpected create error")
+	registry := santa.NewRegistry()
+	assert.NoError(t, registry.Register("testing", logger),
+		"Unexpected register error")
+	handler := Handler(registry)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code, "Unexpected status")
+	assert.Contains(t, recorder.Body.String(), "testing",
+		"Unexpected page data")
+
+	form := url.Values { "key": { "testing" }, "level": { "error" } }
+	request := httptest.NewRequest(http.MethodPost, "/debug/santa",
+		strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusSeeOther, recorder.Code, "Unexpected status")
+	assert.Equal(t, santa.LevelError, logger.Level(), "Unexpected level")
+
+	form.Set("key", "unknown")
+	request = httptest.NewRequest(http.MethodPost, "/debug/santa",
+		strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusNotFound, recorder.Code, "Unexpected status")
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet,
+		"/?format=json", nil))
+	var states []santa.LoggerState
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &states),
+		"Unexpected decode error")
+	assert.Len(t, states, 1, "Unexpected states")
+	assert.Equal(t, santa.LevelError, states[0].Level, "Unexpected level")
+
+	Publish("santa_testing", registry)
+	assert.Contains(t, expvar.Get("santa_testing").String(), `"testing"`,
+		"Unexpected expvar value")
+
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+}
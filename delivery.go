@@ -0,0 +1,545 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DeliveryKeyName is the name of the field (or the label, if the
+	// message of a log entry has no fields) that contains the idempotency
+	// key of each log entry exported by a delivery exporter. For details,
+	// please refer to the comment section of the DeliveryExporter
+	// structure.
+	DeliveryKeyName = "delivery_key"
+
+	// deliveryJournalName is the name of the journal file of a delivery
+	// exporter in its directory.
+	deliveryJournalName = "journal"
+
+	// deliveryStateName is the name of the state file of a delivery
+	// exporter in its directory.
+	deliveryStateName = "state"
+
+	// deliveryHeaderSize represents the size of a journal record header,
+	// which is the sequence number of the record as an 8-byte big endian
+	// unsigned integer, followed by the length of the record in bytes as
+	// a 4-byte big endian unsigned integer.
+	deliveryHeaderSize = 12
+)
+
+var (
+	// ErrDeliveryBacklog represents that a delivery exporter could not
+	// export a log entry, because its pending log entries have reached
+	// the limit and could not be acknowledged.
+	ErrDeliveryBacklog = errors.New("delivery backlog is full")
+
+	// ErrInvalidDeliveryState represents that the state file of a delivery
+	// exporter is malformed.
+	ErrInvalidDeliveryState = errors.New("invalid delivery state")
+)
+
+// AcknowledgingSyncer is the public interface of synchronizers whose other
+// end acknowledges the data it has received (for example: a network
+// synchronizer speaking a protocol with acknowledgments).
+//
+// A delivery exporter considers the log entries written to a synchronizer
+// delivered once the synchronizer has been synchronized successfully. By
+// implementing this interface, a synchronizer makes the delivery exporter
+// additionally wait for the other end to acknowledge them.
+//
+// Please note that none of the synchronizers of this package implement
+// this interface, including the network synchronizer.
+type AcknowledgingSyncer interface {
+	Syncer
+
+	// Acknowledge waits until the other end has acknowledged all data
+	// written to the synchronizer so far.
+	//
+	// Finally, any errors encountered are returned.
+	Acknowledge() error
+}
+
+// deliverySyncer is the synchronizer that the wrapped exporter of a
+// delivery exporter writes its encoded log entries to. It appends each
+// encoded log entry to the journal before writing it to the synchronizer
+// of the wrapped exporter.
+type deliverySyncer struct {
+	syncer Syncer
+	journal *os.File
+	size int64
+	next uint64
+	failed bool
+}
+
+// Write appends the given encoded log entry to the journal with the next
+// sequence number, and then writes it to the synchronizer. If writing to
+// the synchronizer fails, the log entry is kept in the journal and written
+// again by the next synchronization.
+//
+// Finally, it returns the number of bytes of the given buffer slice and
+// any errors encountered.
+func (s *deliverySyncer) Write(buffer []byte) (int, error) {
+	var header [deliveryHeaderSize]byte
+	binary.BigEndian.PutUint64(header[ : 8], s.next)
+	binary.BigEndian.PutUint32(header[8 : ], uint32(len(buffer)))
+	if _, err := s.journal.Write(header[ : ]); err != nil {
+		return 0, err
+	}
+	if _, err := s.journal.Write(buffer); err != nil {
+		return 0, err
+	}
+	s.size += int64(deliveryHeaderSize + len(buffer))
+	s.next++
+	if s.failed {
+		// The earlier log entries will be written again first, so this
+		// log entry is written with them to keep them in order.
+		return len(buffer), nil
+	}
+	if _, err := s.syncer.Write(buffer); err != nil {
+		s.failed = true
+		return len(buffer), err
+	}
+	return len(buffer), nil
+}
+
+// Sync synchronizes the synchronizer.
+func (s *deliverySyncer) Sync() error {
+	return s.syncer.Sync()
+}
+
+// Close closes the synchronizer.
+func (s *deliverySyncer) Close() error {
+	return s.syncer.Close()
+}
+
+// DeliveryExporter is the structure of the delivery exporter instance.
+//
+// The delivery exporter wraps a standard exporter and delivers its log
+// entries at least once. Each log entry is given a sequence number and an
+// idempotency key, which is the stream ID of the delivery exporter and the
+// sequence number joined by '-' (for example: "01HV...-42"), encoded as a
+// field named DeliveryKeyName. The encoded log entry is appended to a
+// journal file before it is written to the synchronizer of the wrapped
+// exporter, and it is removed from the journal only after a later
+// synchronization has succeeded and, if the synchronizer implements the
+// AcknowledgingSyncer interface, has been acknowledged. The journal and
+// the stream ID are kept in a directory, so the log entries that were not
+// acknowledged before the application stopped or a synchronization failed
+// are written again by the next synchronization with the same idempotency
+// keys, and the sequence numbers continue across restarts. Receivers can
+// therefore deduplicate retried log entries by their idempotency keys.
+//
+// Delivery guarantees of the exporters:
+//
+//   - StandardExporter: at most once. Log entries cached by the
+//     synchronizer are lost if the application stops without closing the
+//     logger or if writing fails.
+//   - AsyncExporter: at most once. Log entries are also dropped when the
+//     queue is full (unless blocking) or their requests are stale.
+//   - Exporter of the compression package: at most once. A failed batch
+//     is lost.
+//   - ShardingExporter: the guarantee of the exporter of each shard.
+//   - DeliveryExporter: at least once, with duplicates identified by
+//     idempotency keys, up to the synchronizer of the wrapped exporter.
+//     Network sinks are not covered end to end: the network synchronizer
+//     does not implement the AcknowledgingSyncer interface, so a log entry
+//     is considered delivered once it has been written to the connection,
+//     and it is lost if the connection or the receiver fails before the
+//     receiver has processed it.
+//
+// The API provided by the delivery exporter is thread-safe.
+type DeliveryExporter struct {
+	exporter StandardExporter
+	syncer *deliverySyncer
+	directory string
+	stream string
+	acknowledged uint64
+	maxPending int64
+	mutex sync.Mutex
+}
+
+// Export adds the idempotency key to the given log entry, encodes it using
+// the wrapped exporter, and then appends it to the journal and writes it
+// to the synchronizer. If the pending log entries have reached the limit,
+// they are synchronized and acknowledged first, and ErrDeliveryBacklog is
+// returned if that fails. For details, please refer to the comment section
+// of the DeliveryExporter structure.
+//
+// Finally, any errors encountered are returned.
+func (e *DeliveryExporter) Export(entry *Entry) error {
+	if !e.exporter.Match(entry) {
+		return nil
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.maxPending > 0 && e.syncer.size >= e.maxPending {
+		if err := e.acknowledge(); err != nil {
+			return ErrDeliveryBacklog
+		}
+	}
+	key := e.stream + "-" + strconv.FormatUint(e.syncer.next, 10)
+	keyed := *entry
+	keyed.text = nil
+	keyed.labels = nil
+	if !keyed.AddFields(String(DeliveryKeyName, key)) {
+		keyed.AddLabel(DeliveryKeyName, key)
+	}
+	err := e.exporter.Export(&keyed)
+	keyed.release()
+	return err
+}
+
+// Match checks whether the given log entry matches the conditions of the
+// wrapped exporter. For details, please refer to the comment section of
+// the MatchingExporter interface.
+func (e *DeliveryExporter) Match(entry *Entry) bool {
+	return e.exporter.Match(entry)
+}
+
+// Stream returns the stream ID of the delivery exporter, which prefixes
+// the idempotency keys of its log entries.
+func (e *DeliveryExporter) Stream() string {
+	return e.stream
+}
+
+// Acknowledged returns the sequence number of the last log entry that has
+// been acknowledged.
+func (e *DeliveryExporter) Acknowledged() uint64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.acknowledged
+}
+
+// Pending returns the number of log entries that have been exported but
+// not yet acknowledged.
+func (e *DeliveryExporter) Pending() uint64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.syncer.next - 1 - e.acknowledged
+}
+
+// resend writes the log entries of the journal that have not been
+// acknowledged to the synchronizer again, and then returns any errors
+// encountered.
+func (e *DeliveryExporter) resend() error {
+	data := make([]byte, e.syncer.size)
+	if _, err := e.syncer.journal.ReadAt(data, 0); err != nil {
+		return err
+	}
+	for len(data) >= deliveryHeaderSize {
+		sequence := binary.BigEndian.Uint64(data[ : 8])
+		size := int(binary.BigEndian.Uint32(data[8 : deliveryHeaderSize]))
+		record := data[deliveryHeaderSize : deliveryHeaderSize + size]
+		data = data[deliveryHeaderSize + size : ]
+		if sequence <= e.acknowledged {
+			continue
+		}
+		if _, err := e.syncer.syncer.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acknowledge writes the log entries of the journal again if writing them
+// failed, synchronizes the synchronizer and waits for its acknowledgment,
+// and then records all exported log entries as acknowledged and empties
+// the journal. Finally, any errors encountered are returned.
+func (e *DeliveryExporter) acknowledge() error {
+	if e.syncer.failed {
+		if err := e.resend(); err != nil {
+			return err
+		}
+		e.syncer.failed = false
+	}
+	if err := e.syncer.journal.Sync(); err != nil {
+		return err
+	}
+	err := e.syncer.syncer.Sync()
+	if acknowledging, ok := e.syncer.syncer.(AcknowledgingSyncer); ok &&
+		err == nil {
+		err = acknowledging.Acknowledge()
+	}
+	if err != nil {
+		e.syncer.failed = true
+		return err
+	}
+	acknowledged := e.syncer.next - 1
+	if err := writeDeliveryState(e.directory, e.stream,
+		acknowledged); err != nil {
+		return err
+	}
+	e.acknowledged = acknowledged
+	if err := e.syncer.journal.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := e.syncer.journal.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	e.syncer.size = 0
+	return nil
+}
+
+// Flush writes the log entries of the journal again if writing them
+// failed, and then flushes the synchronizer of the wrapped exporter. The
+// log entries are not acknowledged.
+//
+// Finally, any errors encountered are returned.
+func (e *DeliveryExporter) Flush() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if e.syncer.failed {
+		if err := e.resend(); err != nil {
+			return err
+		}
+		e.syncer.failed = false
+	}
+	if flusher, ok := e.syncer.syncer.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Sync synchronizes the synchronizer of the wrapped exporter, and then
+// acknowledges the exported log entries. For details, please refer to the
+// comment section of the DeliveryExporter structure.
+//
+// Finally, any errors encountered are returned.
+func (e *DeliveryExporter) Sync() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.acknowledge()
+}
+
+// Close acknowledges the exported log entries, and then closes the
+// journal and the synchronizer of the wrapped exporter. The log entries
+// that could not be acknowledged are kept in the journal and written again
+// by the next delivery exporter using the same directory.
+//
+// Finally, any errors encountered are returned.
+func (e *DeliveryExporter) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	var errs MultiError
+	errs = errs.Append(e.acknowledge())
+	errs = errs.Append(e.syncer.journal.Close())
+	errs = errs.Append(e.syncer.syncer.Close())
+	return errs.ErrorOrNil()
+}
+
+// readDeliveryState reads the stream ID and the sequence number of the
+// last acknowledged log entry from the state file in the given directory.
+// If the state file does not exist, a new stream ID is returned.
+func readDeliveryState(directory string) (string, uint64, error) {
+	data, err := os.ReadFile(filepath.Join(directory, deliveryStateName))
+	if errors.Is(err, os.ErrNotExist) {
+		return NewCorrelationID(), 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	stream, sequence, ok := strings.Cut(strings.TrimSpace(string(data)), " ")
+	if !ok || len(stream) == 0 {
+		return "", 0, ErrInvalidDeliveryState
+	}
+	acknowledged, err := strconv.ParseUint(sequence, 10, 64)
+	if err != nil {
+		return "", 0, ErrInvalidDeliveryState
+	}
+	return stream, acknowledged, nil
+}
+
+// writeDeliveryState replaces the state file in the given directory with
+// the given stream ID and sequence number of the last acknowledged log
+// entry, and then returns any errors encountered.
+func writeDeliveryState(directory, stream string, acknowledged uint64) error {
+	name := filepath.Join(directory, deliveryStateName)
+	data := strconv.AppendUint([]byte(stream + " "), acknowledged, 10)
+	if err := os.WriteFile(name + ".tmp", append(data, '\n'),
+		0644); err != nil {
+		return err
+	}
+	return os.Rename(name + ".tmp", name)
+}
+
+// scanDeliveryJournal returns the size in bytes of the complete records of
+// the given journal data and the sequence number of the last record. A
+// record cut short by an interrupted write is not counted.
+func scanDeliveryJournal(data []byte) (int64, uint64) {
+	var size int64
+	var last uint64
+	for len(data) >= deliveryHeaderSize {
+		length := int(binary.BigEndian.Uint32(data[8 : deliveryHeaderSize]))
+		if len(data) < deliveryHeaderSize + length {
+			break
+		}
+		last = binary.BigEndian.Uint64(data[ : 8])
+		size += int64(deliveryHeaderSize + length)
+		data = data[deliveryHeaderSize + length : ]
+	}
+	return size, last
+}
+
+// DeliveryExporterOption is a structure that contains delivery exporter
+// options.
+type DeliveryExporterOption struct {
+	// Exporter represents the wrapped standard exporter. This option is
+	// required.
+	Exporter *StandardExporter
+
+	// Directory represents the directory that contains the journal and
+	// the state of the delivery exporter. It is created if it does not
+	// exist. Each delivery exporter must use its own directory. This
+	// option is required.
+	Directory string
+
+	// MaxPending represents the size in bytes of the journal at which the
+	// pending log entries are synchronized and acknowledged before the
+	// next log entry is exported. If it is 0, the journal is unlimited.
+	// If not provided, the default value is 64 MiB.
+	MaxPending int64
+}
+
+// UseExporter uses the given standard exporter as the value of the option
+// Exporter. Then return to the option instance itself.
+func (o *DeliveryExporterOption) UseExporter(exporter *StandardExporter) *DeliveryExporterOption {
+	o.Exporter = exporter
+	return o
+}
+
+// UseDirectory uses the given directory as the value of the option
+// Directory. Then return to the option instance itself.
+func (o *DeliveryExporterOption) UseDirectory(directory string) *DeliveryExporterOption {
+	o.Directory = directory
+	return o
+}
+
+// UseMaxPending uses the given size in bytes as the value of the option
+// MaxPending. Then return to the option instance itself.
+func (o *DeliveryExporterOption) UseMaxPending(size int64) *DeliveryExporterOption {
+	o.MaxPending = size
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *DeliveryExporterOption) Validate() error {
+	if o.Exporter == nil {
+		return newOptionError("Exporter", "must not be nil", nil)
+	}
+	if len(o.Directory) == 0 {
+		return newOptionError("Directory", "must not be empty", nil)
+	}
+	if o.MaxPending < 0 {
+		return newOptionError("MaxPending", "must not be negative", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a delivery exporter instance. The log entries
+// left in the journal by a previous delivery exporter using the same
+// directory are written again by the first synchronization.
+func (o *DeliveryExporterOption) Build() (*DeliveryExporter, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(o.Directory, 0755); err != nil {
+		return nil, err
+	}
+	stream, acknowledged, err := readDeliveryState(o.Directory)
+	if err != nil {
+		return nil, err
+	}
+	journal, err := os.OpenFile(filepath.Join(o.Directory,
+		deliveryJournalName), os.O_CREATE | os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(journal)
+	if err != nil {
+		_ = journal.Close()
+		return nil, err
+	}
+	size, last := scanDeliveryJournal(data)
+	if size < int64(len(data)) {
+		// The last record was cut short, so it was never written to the
+		// synchronizer and is discarded.
+		err = journal.Truncate(size)
+	}
+	if err == nil {
+		_, err = journal.Seek(size, io.SeekStart)
+	}
+	if err == nil {
+		// The state is written even if nothing has been acknowledged yet,
+		// so that the stream ID is kept across restarts.
+		err = writeDeliveryState(o.Directory, stream, acknowledged)
+	}
+	if err != nil {
+		_ = journal.Close()
+		return nil, err
+	}
+	if last < acknowledged {
+		last = acknowledged
+	}
+	syncer := &deliverySyncer {
+		syncer: o.Exporter.syncer,
+		journal: journal,
+		size: size,
+		next: last + 1,
+		failed: size > 0,
+	}
+	instance := &DeliveryExporter {
+		exporter: *o.Exporter,
+		syncer: syncer,
+		directory: o.Directory,
+		stream: stream,
+		acknowledged: acknowledged,
+		maxPending: o.MaxPending,
+	}
+	instance.exporter.syncer = syncer
+	return instance, nil
+}
+
+// NewDeliveryExporterOption creates and returns a delivery exporter option
+// instance with default option values.
+func NewDeliveryExporterOption() *DeliveryExporterOption {
+	return &DeliveryExporterOption {
+		MaxPending: 64 * 1024 * 1024,
+	}
+}
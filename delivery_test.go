@@ -0,0 +1,184 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDeliverySyncer struct {
+	mutex sync.Mutex
+	data []string
+	failing bool
+	acknowledged int
+}
+
+func (s *testDeliverySyncer) Write(buffer []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failing {
+		return 0, errors.New("unexpected delivery failure")
+	}
+	s.data = append(s.data, string(buffer))
+	return len(buffer), nil
+}
+
+func (s *testDeliverySyncer) Sync() error {
+	return nil
+}
+
+func (s *testDeliverySyncer) Acknowledge() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failing {
+		return errors.New("unexpected acknowledgment failure")
+	}
+	s.acknowledged = len(s.data)
+	return nil
+}
+
+func (s *testDeliverySyncer) Close() error {
+	return nil
+}
+
+func newTestDeliveryExporter(t *testing.T, directory string,
+	syncer Syncer) *DeliveryExporter {
+	encoder, err := NewStandardEncoderOption().Build()
+	assert.NoError(t, err, "Unexpected create error")
+	exporter, err := NewStandardExporterOption().UseEncoder(encoder).
+		UseSyncer(syncer).UseSpan(LevelInfo, LevelFatal).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	delivery, err := NewDeliveryExporterOption().UseExporter(exporter).
+		UseDirectory(directory).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	return delivery
+}
+
+func TestDeliveryExporter(t *testing.T) {
+	directory := t.TempDir()
+	syncer := &testDeliverySyncer { }
+	exporter := newTestDeliveryExporter(t, directory, syncer)
+	stream := exporter.Stream()
+	assert.NotEmpty(t, stream, "Unexpected empty stream")
+
+	for _, text := range []string { "first", "second" } {
+		assert.NoError(t, exporter.Export(&Entry { Level: LevelInfo,
+			Message: StringMessage(text) }), "Unexpected export error")
+	}
+	assert.NoError(t, exporter.Export(&Entry { Level: LevelDebug,
+		Message: StringMessage("ignored") }), "Unexpected export error")
+	assert.Len(t, syncer.data, 2, "Unexpected number of records")
+	assert.Contains(t, syncer.data[1],
+		`{"` + DeliveryKeyName + `": "` + stream + `-2"}`,
+		"Unexpected idempotency key")
+	assert.Equal(t, uint64(2), exporter.Pending(), "Unexpected pending")
+	assert.NoError(t, exporter.Sync(), "Unexpected sync error")
+	assert.Equal(t, uint64(0), exporter.Pending(), "Unexpected pending")
+	assert.Equal(t, uint64(2), exporter.Acknowledged(),
+		"Unexpected acknowledged")
+	assert.Equal(t, 2, syncer.acknowledged, "Unexpected acknowledged")
+
+	// The records that could not be written are written again with the
+	// same idempotency keys by the next synchronization.
+	syncer.failing = true
+	assert.Error(t, exporter.Export(&Entry { Level: LevelInfo,
+		Message: StringMessage("third") }), "Unexpected export success")
+	assert.NoError(t, exporter.Export(&Entry { Level: LevelInfo,
+		Message: StringMessage("fourth") }), "Unexpected export error")
+	assert.Error(t, exporter.Sync(), "Unexpected sync success")
+	syncer.failing = false
+	assert.NoError(t, exporter.Sync(), "Unexpected sync error")
+	assert.Len(t, syncer.data, 4, "Unexpected number of records")
+	assert.Contains(t, syncer.data[2], stream + "-3", "Unexpected record")
+	assert.Contains(t, syncer.data[3], stream + "-4", "Unexpected record")
+
+	// The records that were not acknowledged before closing are written
+	// again by the next exporter using the same directory.
+	assert.NoError(t, exporter.Export(&Entry { Level: LevelInfo,
+		Message: StringMessage("fifth") }), "Unexpected export error")
+	syncer.failing = true
+	assert.Error(t, exporter.Close(), "Unexpected close success")
+
+	syncer = &testDeliverySyncer { }
+	exporter = newTestDeliveryExporter(t, directory, syncer)
+	assert.Equal(t, stream, exporter.Stream(), "Unexpected stream")
+	assert.Equal(t, uint64(1), exporter.Pending(), "Unexpected pending")
+	assert.NoError(t, exporter.Export(&Entry { Level: LevelInfo,
+		Message: StringMessage("sixth") }), "Unexpected export error")
+	assert.Empty(t, syncer.data, "Unexpected records before resend")
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+	assert.Len(t, syncer.data, 2, "Unexpected number of records")
+	assert.True(t, strings.Contains(syncer.data[0], "fifth") &&
+		strings.Contains(syncer.data[0], stream + "-5"), "Unexpected record")
+	assert.Contains(t, syncer.data[1], stream + "-6", "Unexpected record")
+
+	_, err := NewDeliveryExporterOption().Build()
+	assert.True(t, errors.Is(err, ErrInvalidOption), "Unexpected create error")
+}
+
+func TestDeliveryExporterStructLogger(t *testing.T) {
+	syncer := &testDeliverySyncer { }
+	encoder, err := NewJSONEncoder()
+	assert.NoError(t, err, "Unexpected create error")
+	exporter, err := NewStandardExporterOption().UseEncoder(encoder).
+		UseSyncer(syncer).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	delivery, err := NewDeliveryExporterOption().UseExporter(exporter).
+		UseDirectory(t.TempDir()).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	// The hook exports the log entries of the structured logger, whose
+	// messages are pooled *StructMessage values, through the delivery
+	// exporter.
+	hook := NewSimpleHook(func(entry *Entry) error {
+		return delivery.Export(entry)
+	})
+	option := NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling().UseHooks(hook)
+	option.Outputting.UseDiscard()
+	option.ErrorOutputting.UseDiscard()
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, logger.Infos("Hello Test!", Int("age", 100)),
+		"Unexpected print error")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+	assert.NoError(t, delivery.Sync(), "Unexpected sync error")
+
+	assert.NoError(t, delivery.Close(), "Unexpected close error")
+
+	syncer.mutex.Lock()
+	defer syncer.mutex.Unlock()
+	assert.Len(t, syncer.data, 1, "Unexpected delivered log entries")
+	assert.Contains(t, syncer.data[0], `"payload": {"age": 100, ` +
+		`"delivery_key": "` + delivery.Stream() + `-1"}`,
+		"Unexpected idempotency key field")
+	assert.Contains(t, syncer.data[0], `"labels": null`,
+		"Unexpected idempotency key label")
+}
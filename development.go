@@ -0,0 +1,129 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrMisuse represents that the logger is misused. In development mode,
+	// the misuses of the logger panic with a MisuseError wrapping it. For
+	// details, please refer to the comment section of the Development
+	// option of the Option structure.
+	ErrMisuse = errors.New("misuse of the logger")
+)
+
+// MisuseError is a structure that contains the description of a misuse of
+// the logger, which is the value of the panics of the logger in development
+// mode.
+type MisuseError struct {
+	// Reason represents the description of the misuse.
+	Reason string
+}
+
+// Error returns the description string of the error.
+func (e *MisuseError) Error() string {
+	return ErrMisuse.Error() + ": " + e.Reason
+}
+
+// Unwrap returns the ErrMisuse error.
+func (e *MisuseError) Unwrap() error {
+	return ErrMisuse
+}
+
+// misuse panics with a MisuseError with the given reason.
+func misuse(reason string) {
+	panic(&MisuseError {
+		Reason: reason,
+	})
+}
+
+// unknown checks whether the value of the element cannot be serialized,
+// which is rendered as "???".
+func (e Element) unknown() bool {
+	switch e.Type {
+	case TypeInt, TypeUint, TypeFloat32, TypeFloat64, TypeBoolean,
+		TypeString, TypeBytes, TypeBigNumber:
+		return false
+	}
+	_, ok := e.Interface.(JSONSerializer)
+	return !ok
+}
+
+// checkFields panics if the given fields contain duplicate field names or
+// values that cannot be serialized. The fields of nested objects are also
+// checked.
+func checkFields(fields []Field) {
+	for index := range fields {
+		field := &fields[index]
+		for previous := 0; previous < index; previous++ {
+			if fields[previous].Name == field.Name {
+				misuse("duplicate field name \"" + field.Name + "\"")
+			}
+		}
+		if field.unknown() {
+			misuse("field \"" + field.Name + "\" has an unknown value type")
+		}
+		if object, ok := field.Interface.(ElementObject); ok {
+			checkFields(object)
+		}
+	}
+}
+
+// checkTemplate panics if the verbs of the given template string do not
+// match the given parameter values, which is rendered as "%!" markers by
+// the fmt package.
+func checkTemplate(template string, args []interface { }) {
+	parsed := lookupTemplate(template)
+	if !parsed.formatted && parsed.verbs != len(args) {
+		misuse("template \"" + template + "\" does not match the number " +
+			"of parameters")
+	}
+	if strings.Contains(string(appendTemplate(nil, template, args)),
+		"%!") {
+		misuse("template \"" + template + "\" does not match the " +
+			"parameters")
+	}
+}
+
+// checkMessage panics if the given log message is misused. For details,
+// please refer to the comment section of the Development option of the
+// Option structure.
+func checkMessage(message Message) {
+	switch message := message.(type) {
+	case StructMessage:
+		checkFields(message.Fields)
+	case *StructMessage:
+		if message != nil {
+			checkFields(message.Fields)
+		}
+	case TemplateMessage:
+		checkTemplate(message.Template, message.Args)
+	case *TemplateMessage:
+		if message != nil {
+			checkTemplate(message.Template, message.Args)
+		}
+	}
+}
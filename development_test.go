@@ -0,0 +1,87 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testMisuse calls the given function, and then returns the MisuseError
+// it panics with, or nil if it does not panic.
+func testMisuse(function func()) (err *MisuseError) {
+	defer func() {
+		if value := recover(); value != nil {
+			err, _ = value.(*MisuseError)
+		}
+	}()
+	function()
+	return nil
+}
+
+func TestDevelopmentMode(t *testing.T) {
+	option := NewStandardOption().DisableSampling().DisableFlushing().
+		EnableDevelopment()
+	option.Outputting.UseDiscard()
+	option.ErrorOutputting.UseDiscard()
+	logger, err := (&StructOption { StandardOption: *option }).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	assert.Nil(t, testMisuse(func() {
+		_ = logger.Infos("Hello Test!", String("first", "1"),
+			String("second", "2"))
+	}), "Unexpected misuse of distinct fields")
+	assert.NotNil(t, testMisuse(func() {
+		_ = logger.Infos("Hello Test!", String("name", "1"),
+			String("name", "2"))
+	}), "Unexpected duplicate fields accepted")
+	assert.NotNil(t, testMisuse(func() {
+		_ = logger.Infos("Hello Test!", Field {
+			Name: "unknown",
+			Element: Element { Type: TypeValue, Interface: struct { } { } },
+		})
+	}), "Unexpected unknown value type accepted")
+
+	templateOption := &TemplateOption { StandardOption: *option }
+	template, err := templateOption.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.Nil(t, testMisuse(func() {
+		_ = template.Infof("Hello %s, %d!", "Test", 1)
+	}), "Unexpected misuse of matching template")
+	assert.NotNil(t, testMisuse(func() {
+		_ = template.Infof("Hello %s, %d!", "Test")
+	}), "Unexpected missing parameter accepted")
+	assert.NotNil(t, testMisuse(func() {
+		_ = template.Infof("Hello %d!", "Test")
+	}), "Unexpected mismatched verb accepted")
+	assert.NoError(t, template.Close(), "Unexpected close error")
+
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+	misuse := testMisuse(func() {
+		_ = logger.Info(StringMessage("Hello Test!"))
+	})
+	assert.NotNil(t, misuse, "Unexpected output after close accepted")
+	assert.True(t, errors.Is(misuse, ErrMisuse), "Unexpected misuse error")
+}
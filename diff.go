@@ -0,0 +1,350 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RedactedMarker represents the value that replaces the redacted values of
+// a diff field.
+const RedactedMarker = "[REDACTED]"
+
+// DiffOption is a structure that contains options for computing diffs. For
+// details, please refer to the comment section of the DiffElement
+// structure.
+type DiffOption struct {
+	// MaxDepth represents the maximum nesting depth compared structurally.
+	// Values nested deeper are compared and reported as a whole. If not
+	// provided, the default value is 10.
+	MaxDepth int
+
+	// MaxChanges represents the maximum number of changes reported. The
+	// remaining changes are replaced by a marker. If not provided, the
+	// default value is 64.
+	MaxChanges int
+
+	// Value represents the dump option used to serialize the old and new
+	// values of each change. For details, please refer to the comment
+	// section of the DumpElement structure. If not provided, the default
+	// value is the default dump option.
+	Value DumpOption
+
+	// RedactPaths represents one or more patterns of the paths whose old
+	// and new values are replaced by the RedactedMarker constant, so that
+	// secrets are not logged while their changes are still reported. The
+	// pattern syntax is the same as the path.Match function (for example:
+	// "credentials.*" or "*.password"). If not provided, no values are
+	// redacted by path.
+	RedactPaths []string
+
+	// Redactor represents a function that returns true if the old and new
+	// values of the change of the given path should be redacted, which is
+	// called in addition to matching the RedactPaths option. If not
+	// provided, no values are redacted by the function.
+	Redactor func(path string) bool
+}
+
+// NewDiffOption creates and returns a diff option instance with default
+// optional values.
+func NewDiffOption() *DiffOption {
+	return &DiffOption {
+		MaxDepth: 10,
+		MaxChanges: 64,
+		Value: *NewDumpOption(),
+	}
+}
+
+// defaultDiffOption is the diff option used by the Diff function.
+var defaultDiffOption = *NewDiffOption()
+
+// DiffChange is a structure that contains a change reported by a diff.
+type DiffChange struct {
+	// Path represents the path of the changed value, for example:
+	// "spec.replicas", "items[2].name" or "labels.env". The path of the
+	// compared values themselves is empty.
+	Path string
+
+	// Operation represents the kind of the change, which is "added",
+	// "removed" or "changed".
+	Operation string
+
+	// Before represents the serialized old value, or nil if the value was
+	// added.
+	Before []byte
+
+	// After represents the serialized new value, or nil if the value was
+	// removed.
+	After []byte
+}
+
+// DiffElement is the data type of the value of a diff field.
+//
+// The diff element contains the structural differences between two values,
+// which is useful for audit logging of changes of configurations or
+// entities. Structures (including unexported fields), maps, slices and
+// arrays are compared element by element, and each difference is reported
+// as a change with its path and its old and new values serialized as JSON.
+// Other values (including time.Time values) are compared by their
+// serialized values.
+//
+// The diff is computed when the field is created, so later modifications
+// of the compared values do not affect the logged changes.
+type DiffElement struct {
+	// Changes represents the changes between the two values.
+	Changes []DiffChange
+
+	// Omitted represents the number of changes that exceeded the maximum
+	// number of changes and were not reported.
+	Omitted int
+}
+
+// differ is a structure that contains the state of a diff operation.
+type differ struct {
+	option *DiffOption
+	changes []DiffChange
+	omitted int
+}
+
+// serialize returns the JSON data of the given value.
+func (d *differ) serialize(value reflect.Value) []byte {
+	serialized := dumper {
+		option: d.option.Value,
+	}
+	serialized.dump(value, 0)
+	return serialized.result()
+}
+
+// redacted returns true if the values of the given path should be
+// redacted.
+func (d *differ) redacted(name string) bool {
+	for index := 0; index < len(d.option.RedactPaths); index++ {
+		if matched, _ := path.Match(d.option.RedactPaths[index],
+			name); matched {
+			return true
+		}
+	}
+	return d.option.Redactor != nil && d.option.Redactor(name)
+}
+
+// report reports a change of the given path with the given old and new
+// values. A nil value means that the value is absent.
+func (d *differ) report(name string, operation string, before,
+	after []byte) {
+	if len(d.changes) >= d.option.MaxChanges {
+		d.omitted++
+		return
+	}
+	if d.redacted(name) {
+		marker := strconv.AppendQuote(nil, RedactedMarker)
+		if before != nil {
+			before = marker
+		}
+		if after != nil {
+			after = marker
+		}
+	}
+	d.changes = append(d.changes, DiffChange {
+		Path: name,
+		Operation: operation,
+		Before: before,
+		After: after,
+	})
+}
+
+// indirect returns the value pointed to by the given pointers or contained
+// in the given interfaces.
+func indirect(value reflect.Value) reflect.Value {
+	for value.IsValid() && (value.Kind() == reflect.Ptr ||
+		value.Kind() == reflect.Interface) && !value.IsNil() {
+		value = value.Elem()
+	}
+	return value
+}
+
+// diffJoin returns the path of the given member of the given path.
+func diffJoin(parent, member string) string {
+	if len(parent) == 0 {
+		return member
+	}
+	return parent + "." + member
+}
+
+// leaf compares the given values as a whole, and reports a change of the
+// given path if their serialized values differ.
+func (d *differ) leaf(name string, before, after reflect.Value) {
+	serializedBefore := d.serialize(before)
+	serializedAfter := d.serialize(after)
+	if string(serializedBefore) != string(serializedAfter) {
+		d.report(name, "changed", serializedBefore, serializedAfter)
+	}
+}
+
+// diff compares the given values of the given path at the given depth,
+// and reports their differences.
+func (d *differ) diff(name string, before, after reflect.Value, depth int) {
+	before, after = indirect(before), indirect(after)
+	if !before.IsValid() || !after.IsValid() ||
+		before.Type() != after.Type() || depth >= d.option.MaxDepth {
+		d.leaf(name, before, after)
+		return
+	}
+	switch before.Kind() {
+	case reflect.Struct:
+		if before.Type() == reflect.TypeOf(time.Time { }) {
+			d.leaf(name, before, after)
+			return
+		}
+		for index := 0; index < before.NumField(); index++ {
+			d.diff(diffJoin(name, before.Type().Field(index).Name),
+				before.Field(index), after.Field(index), depth + 1)
+		}
+	case reflect.Map:
+		if before.IsNil() || after.IsNil() {
+			d.leaf(name, before, after)
+			return
+		}
+		keys := before.MapKeys()
+		for _, key := range after.MapKeys() {
+			if !before.MapIndex(key).IsValid() {
+				keys = append(keys, key)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return dumpKey(keys[i]) < dumpKey(keys[j])
+		})
+		for _, key := range keys {
+			member := diffJoin(name, dumpKey(key))
+			oldValue, newValue := before.MapIndex(key), after.MapIndex(key)
+			switch {
+			case !oldValue.IsValid():
+				d.report(member, "added", nil, d.serialize(newValue))
+			case !newValue.IsValid():
+				d.report(member, "removed", d.serialize(oldValue), nil)
+			default:
+				d.diff(member, oldValue, newValue, depth + 1)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if before.Kind() == reflect.Slice && (before.IsNil() ||
+			after.IsNil() || before.Type().Elem().Kind() == reflect.Uint8) {
+			d.leaf(name, before, after)
+			return
+		}
+		length := before.Len()
+		if after.Len() > length {
+			length = after.Len()
+		}
+		for index := 0; index < length; index++ {
+			member := name + "[" + strconv.Itoa(index) + "]"
+			switch {
+			case index >= before.Len():
+				d.report(member, "added", nil,
+					d.serialize(after.Index(index)))
+			case index >= after.Len():
+				d.report(member, "removed",
+					d.serialize(before.Index(index)), nil)
+			default:
+				d.diff(member, before.Index(index), after.Index(index),
+					depth + 1)
+			}
+		}
+	default:
+		d.leaf(name, before, after)
+	}
+}
+
+// SerializeJSON serializes the changes into a JSON array and appends it to
+// the given buffer slice, and then returns the appended buffer slice. Each
+// change is serialized as an object containing its path, its operation and
+// its old and/or new values.
+func (e DiffElement) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, '[')
+	for index := 0; index < len(e.Changes); index++ {
+		if index > 0 {
+			buffer = append(buffer, ", "...)
+		}
+		change := &e.Changes[index]
+		buffer = append(buffer, `{"path": `...)
+		buffer = strconv.AppendQuote(buffer, change.Path)
+		buffer = append(buffer, `, "op": `...)
+		buffer = strconv.AppendQuote(buffer, change.Operation)
+		if change.Before != nil {
+			buffer = append(buffer, `, "before": `...)
+			buffer = append(buffer, change.Before...)
+		}
+		if change.After != nil {
+			buffer = append(buffer, `, "after": `...)
+			buffer = append(buffer, change.After...)
+		}
+		buffer = append(buffer, '}')
+	}
+	if e.Omitted > 0 {
+		if len(e.Changes) > 0 {
+			buffer = append(buffer, ", "...)
+		}
+		buffer = append(buffer, `"...(`...)
+		buffer = strconv.AppendInt(buffer, int64(e.Omitted), 10)
+		buffer = append(buffer, ` more)"`...)
+	}
+	return append(buffer, ']')
+}
+
+// IsEmpty returns true if there are no changes, otherwise returns false.
+func (e DiffElement) IsEmpty() bool {
+	return len(e.Changes) == 0 && e.Omitted == 0
+}
+
+// DiffWith returns the value of a field with a given name, whose value is
+// the structural diff between the given old and new values computed with
+// the given diff option. For details, please refer to the comment section
+// of the DiffElement structure.
+func DiffWith(name string, before, after interface { }, option *DiffOption) Field {
+	d := differ {
+		option: option,
+	}
+	d.diff("", reflect.ValueOf(before), reflect.ValueOf(after), 0)
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: DiffElement {
+				Changes: d.changes,
+				Omitted: d.omitted,
+			},
+		},
+		Name: name,
+	}
+}
+
+// Diff returns the value of a field with a given name, whose value is the
+// structural diff between the given old and new values computed with the
+// default diff option. For details, please refer to the comment section
+// of the DiffElement structure.
+func Diff(name string, before, after interface { }) Field {
+	return DiffWith(name, before, after, &defaultDiffOption)
+}
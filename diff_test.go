@@ -0,0 +1,89 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDiffConfig struct {
+	Name string
+	Replicas int
+	Password string
+	Labels map[string]string
+	Ports []int
+	owner *string
+}
+
+func TestDiffElement(t *testing.T) {
+	owner, other := "alice", "bob"
+	before := &testDiffConfig {
+		Name: "api",
+		Replicas: 1,
+		Password: "old",
+		Labels: map[string]string { "env": "dev", "team": "core" },
+		Ports: []int { 80, 443 },
+		owner: &owner,
+	}
+	after := &testDiffConfig {
+		Name: "api",
+		Replicas: 3,
+		Password: "new",
+		Labels: map[string]string { "env": "prod", "tier": "web" },
+		Ports: []int { 80 },
+		owner: &other,
+	}
+
+	option := NewDiffOption()
+	option.RedactPaths = []string { "Password" }
+	field := DiffWith("changes", before, after, option)
+	after.Replicas = 5
+	assert.Equal(t, `[{"path": "Replicas", "op": "changed", "before": 1, ` +
+		`"after": 3}, {"path": "Password", "op": "changed", "before": ` +
+		`"[REDACTED]", "after": "[REDACTED]"}, {"path": "Labels.env", ` +
+		`"op": "changed", "before": "dev", "after": "prod"}, {"path": ` +
+		`"Labels.team", "op": "removed", "before": "core"}, {"path": ` +
+		`"Labels.tier", "op": "added", "after": "web"}, {"path": ` +
+		`"Ports[1]", "op": "removed", "before": 443}, {"path": "owner", ` +
+		`"op": "changed", "before": "alice", "after": "bob"}]`,
+		string(field.Element.SerializeJSON(nil)), "Unexpected diff data")
+
+	assert.True(t, Diff("changes", before, before).IsEmpty(),
+		"Unexpected diff data")
+	assert.Equal(t, `[{"path": "", "op": "changed", "before": 1, ` +
+		`"after": "1"}]`, string(Diff("changes", 1, "1").Element.
+		SerializeJSON(nil)), "Unexpected diff data")
+
+	option = NewDiffOption()
+	option.MaxChanges = 1
+	option.Redactor = func(path string) bool {
+		return strings.HasPrefix(path, "Labels")
+	}
+	assert.Equal(t, `[{"path": "Replicas", "op": "changed", "before": 1, ` +
+		`"after": 5}, "...(6 more)"]`, string(DiffWith("changes", before,
+		after, option).Element.SerializeJSON(nil)), "Unexpected diff data")
+}
@@ -0,0 +1,287 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrProbeLost represents that the probe log entry of the Doctor
+	// function was written without error, but it did not arrive at the
+	// specific storage device.
+	ErrProbeLost = errors.New("probe entry did not arrive")
+)
+
+// DoctorStatus represents the result status of a diagnostic check.
+type DoctorStatus int
+
+const (
+	// DoctorPassed represents that the diagnostic check passed.
+	DoctorPassed DoctorStatus = iota
+
+	// DoctorUnverified represents that the probe log entry was written
+	// without error, but whether it arrived at the specific storage device
+	// cannot be verified (for example: the standard output device).
+	DoctorUnverified
+
+	// DoctorFailed represents that the diagnostic check failed.
+	DoctorFailed
+)
+
+// String returns the string representation of the diagnostic status.
+func (s DoctorStatus) String() string {
+	switch s {
+	case DoctorPassed:
+		return "PASSED"
+	case DoctorUnverified:
+		return "UNVERIFIED"
+	case DoctorFailed:
+		return "FAILED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// DoctorCheck is a structure that contains the result of a diagnostic
+// check.
+type DoctorCheck struct {
+	// Name represents the name of the diagnostic check, usually the name
+	// of the option being checked (for example: "Outputting").
+	Name string
+
+	// Status represents the result status of the diagnostic check.
+	Status DoctorStatus
+
+	// Detail represents a human-readable description of the result.
+	Detail string
+
+	// Err represents the error encountered by the diagnostic check, or nil
+	// if the diagnostic check did not fail.
+	Err error
+}
+
+// String returns the string representation of the diagnostic check.
+func (c DoctorCheck) String() string {
+	var builder strings.Builder
+	builder.WriteString("[" + c.Status.String() + "] " + c.Name)
+	if len(c.Detail) > 0 {
+		builder.WriteString(": " + c.Detail)
+	}
+	if c.Err != nil {
+		builder.WriteString(" (" + c.Err.Error() + ")")
+	}
+	return builder.String()
+}
+
+// DoctorReport is a structure that contains the diagnostic report of
+// the Doctor function.
+type DoctorReport struct {
+	// Checks represents the results of each diagnostic check in the
+	// order in which they were performed.
+	Checks []DoctorCheck
+}
+
+// add appends a diagnostic check with the given values to the report.
+func (r *DoctorReport) add(name string, status DoctorStatus, detail string,
+	err error) {
+	r.Checks = append(r.Checks, DoctorCheck {
+		Name: name,
+		Status: status,
+		Detail: detail,
+		Err: err,
+	})
+}
+
+// Failed checks whether any diagnostic check in the report failed.
+func (r *DoctorReport) Failed() bool {
+	for index := 0; index < len(r.Checks); index++ {
+		if r.Checks[index].Status == DoctorFailed {
+			return true
+		}
+	}
+	return false
+}
+
+// Err returns a MultiError containing the errors of each failed
+// diagnostic check, or nil if no diagnostic check failed.
+func (r *DoctorReport) Err() error {
+	var errs MultiError
+	for index := 0; index < len(r.Checks); index++ {
+		if r.Checks[index].Status == DoctorFailed {
+			errs = errs.Append(r.Checks[index].Err)
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// String returns the string representation of the report, one diagnostic
+// check per line.
+func (r *DoctorReport) String() string {
+	lines := make([]string, len(r.Checks))
+	for index := 0; index < len(r.Checks); index++ {
+		lines[index] = r.Checks[index].String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// doctorProbe describes an outputting option to be probed by the Doctor
+// function.
+type doctorProbe struct {
+	name string
+	level Level
+	option *OutputtingOption
+	size int64
+}
+
+// fileSize returns the size of the given file, or -1 if the file does
+// not exist or cannot be accessed.
+func fileSize(name string) int64 {
+	info, err := os.Stat(name)
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+// probeFileName returns the file name used by the given outputting option
+// if its arrival can be verified, otherwise returns an empty string.
+func probeFileName(option *OutputtingOption) string {
+	if option.Type != SyncerFile {
+		return ""
+	}
+	name := option.Option.(*FileSyncerOption).FileName
+	if len(name) == 0 || name == os.DevNull {
+		return ""
+	}
+	return name
+}
+
+// verify checks whether the probe log entry arrived at the specific
+// storage device of the outputting option, and then adds the result to
+// the given report.
+func (p *doctorProbe) verify(report *DoctorReport) {
+	switch p.option.Type {
+	case SyncerFile:
+		name := probeFileName(p.option)
+		if len(name) == 0 {
+			report.add(p.name, DoctorUnverified,
+				"probe written to the null device", nil)
+			return
+		}
+		if size := fileSize(name); size <= p.size {
+			report.add(p.name, DoctorFailed, "file \"" + name +
+				"\" did not grow", ErrProbeLost)
+			return
+		}
+		report.add(p.name, DoctorPassed, "probe arrived at file \"" +
+			name + "\"", nil)
+	case SyncerNetwork:
+		option := p.option.Option.(*NetworkSyncerOption)
+		report.add(p.name, DoctorPassed, "probe accepted by " +
+			option.Protocol + " peer \"" + option.Address + "\"", nil)
+	case SyncerDiscard:
+		report.add(p.name, DoctorUnverified, "probe discarded", nil)
+	default:
+		report.add(p.name, DoctorUnverified, "probe written to the " +
+			"writer, arrival cannot be verified", nil)
+	}
+}
+
+// Doctor builds the pipeline configured by the given option, emits a probe
+// log entry through each exporter, verifies that the probe log entry
+// arrived where it is verifiable, and then returns a diagnostic report.
+//
+// The arrival of the probe log entry is verified as follows: a file
+// synchronizer passes if the file grows, and a network synchronizer passes
+// if the peer accepts the written data. Other synchronizers cannot be
+// verified and are reported as unverified if the probe log entry was
+// written without error.
+//
+// The probe log entry is exported directly to each exporter at the INFO
+// and ERROR levels, so it is not affected by the minimum log entry level,
+// sampling or hooks of the option. Automatic flushing is not started, and
+// the built pipeline is closed before returning.
+//
+// Please note that the probe log entry is actually written to each
+// specific storage device.
+func Doctor(option *StandardOption) *DoctorReport {
+	report := &DoctorReport { }
+	if err := option.Validate(); err != nil {
+		report.add("Validate", DoctorFailed, "invalid option", err)
+		return report
+	}
+	report.add("Validate", DoctorPassed, "", nil)
+
+	copied := *option
+	copied.Flushing.Interval = 0
+	probes := []*doctorProbe {
+		{ name: "Outputting", level: LevelInfo,
+			option: &copied.Outputting },
+		{ name: "ErrorOutputting", level: LevelError,
+			option: &copied.ErrorOutputting },
+	}
+	for _, probe := range probes {
+		if name := probeFileName(probe.option); len(name) > 0 {
+			probe.size = fileSize(name)
+		}
+	}
+
+	logger, err := copied.build()
+	if err != nil {
+		report.add("Build", DoctorFailed, "cannot build pipeline", err)
+		return report
+	}
+	report.add("Build", DoctorPassed, "", nil)
+
+	config := logger.config()
+	for index, probe := range probes {
+		entry := pool.Entry.New()
+		entry.Name = config.name
+		entry.Level = probe.level
+		entry.Time = time.Now()
+		entry.Message = StringMessage("santa doctor probe")
+		entry.Labels = config.labels
+		exporter := config.exporters[index]
+		err = exporter.Export(entry)
+		pool.Entry.Free(entry)
+		if err == nil {
+			err = exporter.Sync()
+		}
+		if err != nil {
+			report.add(probe.name, DoctorFailed, "cannot write probe", err)
+			continue
+		}
+		probe.verify(report)
+	}
+
+	if err = logger.Close(); err != nil {
+		report.add("Close", DoctorFailed, "cannot close pipeline", err)
+	} else {
+		report.add("Close", DoctorPassed, "", nil)
+	}
+	return report
+}
@@ -0,0 +1,70 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoctor(t *testing.T) {
+	directory := t.TempDir()
+	name := filepath.Join(directory, "doctor.log")
+
+	option := NewStandardOption()
+	option.Outputting.UseFile(name)
+	option.ErrorOutputting.UseDiscard()
+	report := Doctor(option)
+	assert.False(t, report.Failed(), "Unexpected failed report: %s", report)
+	assert.NoError(t, report.Err(), "Unexpected report error")
+	assert.Len(t, report.Checks, 5, "Unexpected number of checks")
+	assert.Equal(t, DoctorPassed, report.Checks[2].Status,
+		"Unexpected outputting status")
+	assert.Equal(t, DoctorUnverified, report.Checks[3].Status,
+		"Unexpected error outputting status")
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err, "Unexpected read error")
+	assert.True(t, strings.Contains(string(data), "santa doctor probe"),
+		"Unexpected file content")
+
+	option = NewStandardOption()
+	option.Outputting.UseFile(filepath.Join(directory, "missing", "doctor.log"))
+	report = Doctor(option)
+	assert.True(t, report.Failed(), "Unexpected passed report")
+	assert.Equal(t, "Build", report.Checks[len(report.Checks) - 1].Name,
+		"Unexpected failed check")
+
+	option = NewStandardOption()
+	option.Outputting.Type = "unknown"
+	report = Doctor(option)
+	assert.True(t, report.Failed(), "Unexpected passed report")
+	assert.True(t, errors.Is(report.Err(), ErrInvalidType),
+		"Unexpected report error")
+	assert.Contains(t, report.String(), "[FAILED] Validate",
+		"Unexpected report string")
+}
@@ -0,0 +1,351 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DumpOption is a structure that contains options for dumping values. For
+// details, please refer to the comment section of the DumpElement
+// structure.
+type DumpOption struct {
+	// MaxDepth represents the maximum nesting depth of the dumped value.
+	// Values nested deeper are replaced by a marker. If not provided, the
+	// default value is 5.
+	MaxDepth int
+
+	// MaxSize represents the maximum number of bytes of the dumped value.
+	// If the dumped value exceeds it, the dumping is stopped and the
+	// truncated data is dumped as a string with a marker instead. If not
+	// provided, the default value is 4096.
+	MaxSize int
+
+	// MaxElements represents the maximum number of elements dumped for
+	// each array, slice, map and structure. The remaining elements are
+	// replaced by a marker. If not provided, the default value is 64.
+	MaxElements int
+}
+
+// NewDumpOption creates and returns a dump option instance with default
+// optional values.
+func NewDumpOption() *DumpOption {
+	return &DumpOption {
+		MaxDepth: 5,
+		MaxSize: 4096,
+		MaxElements: 64,
+	}
+}
+
+// defaultDumpOption is the dump option used by the Dump function.
+var defaultDumpOption = *NewDumpOption()
+
+// DumpElement is the data type of the value of a dump field.
+//
+// The dump element serializes an arbitrary value, including nested
+// pointers, structures (including unexported fields), maps, slices and
+// arrays, into JSON using reflection when the log entry is encoded. The
+// serialization is limited by the depth, size and number of elements of
+// the dump option, and pointer cycles are replaced by a marker, so that
+// dumping a large or cyclic object cannot stall the application.
+type DumpElement struct {
+	// Value represents the value to be dumped.
+	Value interface { }
+
+	// Option represents the dump option.
+	Option DumpOption
+}
+
+// dumper is a structure that contains the state of a dump operation.
+type dumper struct {
+	buffer []byte
+	start int
+	option DumpOption
+	visited []uintptr
+	truncated bool
+}
+
+// full returns true if the dumped data has exceeded the maximum size, and
+// marks the dump operation as truncated.
+func (d *dumper) full() bool {
+	if len(d.buffer) - d.start > d.option.MaxSize {
+		d.truncated = true
+	}
+	return d.truncated
+}
+
+// marker appends a string marker with the given text to the buffer.
+func (d *dumper) marker(text string) {
+	d.buffer = append(d.buffer, `"...(`...)
+	d.buffer = append(d.buffer, text...)
+	d.buffer = append(d.buffer, `)"`...)
+}
+
+// enter returns false if the given pointer is already being dumped, which
+// means that there is a cycle, otherwise it marks the pointer as being
+// dumped and returns true.
+func (d *dumper) enter(pointer uintptr) bool {
+	for index := 0; index < len(d.visited); index++ {
+		if d.visited[index] == pointer {
+			return false
+		}
+	}
+	d.visited = append(d.visited, pointer)
+	return true
+}
+
+// leave marks the last pointer as no longer being dumped.
+func (d *dumper) leave() {
+	d.visited = d.visited[ : len(d.visited) - 1]
+}
+
+// dumpKey returns the text of the given map key.
+func dumpKey(key reflect.Value) string {
+	switch key.Kind() {
+	case reflect.String:
+		return key.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		return strconv.FormatInt(key.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(key.Uint(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(key.Bool())
+	default:
+		return key.Type().String()
+	}
+}
+
+// dump appends the JSON data of the given value at the given depth to the
+// buffer.
+func (d *dumper) dump(value reflect.Value, depth int) {
+	if d.full() {
+		return
+	}
+	if !value.IsValid() {
+		d.buffer = append(d.buffer, "null"...)
+		return
+	}
+	if value.CanInterface() {
+		switch v := value.Interface().(type) {
+		case time.Time:
+			d.buffer = append(d.buffer, '"')
+			d.buffer = v.AppendFormat(d.buffer, time.RFC3339Nano)
+			d.buffer = append(d.buffer, '"')
+			return
+		case error:
+			if value.Kind() != reflect.Ptr || !value.IsNil() {
+				d.buffer = strconv.AppendQuote(d.buffer, v.Error())
+				return
+			}
+		}
+	}
+
+	switch value.Kind() {
+	case reflect.Bool:
+		d.buffer = strconv.AppendBool(d.buffer, value.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64:
+		d.buffer = strconv.AppendInt(d.buffer, value.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32,
+		reflect.Uint64, reflect.Uintptr:
+		d.buffer = strconv.AppendUint(d.buffer, value.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		number := value.Float()
+		if math.IsNaN(number) || math.IsInf(number, 0) {
+			d.buffer = append(d.buffer, '"')
+			d.buffer = strconv.AppendFloat(d.buffer, number, 'g', -1, 64)
+			d.buffer = append(d.buffer, '"')
+		} else {
+			d.buffer = strconv.AppendFloat(d.buffer, number, 'g', -1, 64)
+		}
+	case reflect.String:
+		d.buffer = strconv.AppendQuote(d.buffer, value.String())
+	case reflect.Ptr, reflect.Interface:
+		if value.IsNil() {
+			d.buffer = append(d.buffer, "null"...)
+			return
+		}
+		if value.Kind() == reflect.Interface {
+			d.dump(value.Elem(), depth)
+			return
+		}
+		if !d.enter(value.Pointer()) {
+			d.marker("cycle")
+			return
+		}
+		d.dump(value.Elem(), depth)
+		d.leave()
+	case reflect.Map:
+		if value.IsNil() {
+			d.buffer = append(d.buffer, "null"...)
+			return
+		}
+		if depth >= d.option.MaxDepth {
+			d.marker("max depth")
+			return
+		}
+		if !d.enter(value.Pointer()) {
+			d.marker("cycle")
+			return
+		}
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return dumpKey(keys[i]) < dumpKey(keys[j])
+		})
+		d.buffer = append(d.buffer, '{')
+		for index, key := range keys {
+			if index > 0 {
+				d.buffer = append(d.buffer, ", "...)
+			}
+			if index >= d.option.MaxElements {
+				d.buffer = append(d.buffer, `"...": `...)
+				d.marker(strconv.Itoa(len(keys) - index) + " more")
+				break
+			}
+			d.buffer = strconv.AppendQuote(d.buffer, dumpKey(key))
+			d.buffer = append(d.buffer, ": "...)
+			d.dump(value.MapIndex(key), depth + 1)
+			if d.full() {
+				break
+			}
+		}
+		d.buffer = append(d.buffer, '}')
+		d.leave()
+	case reflect.Struct:
+		if depth >= d.option.MaxDepth {
+			d.marker("max depth")
+			return
+		}
+		d.buffer = append(d.buffer, '{')
+		for index := 0; index < value.NumField(); index++ {
+			if index > 0 {
+				d.buffer = append(d.buffer, ", "...)
+			}
+			if index >= d.option.MaxElements {
+				d.buffer = append(d.buffer, `"...": `...)
+				d.marker(strconv.Itoa(value.NumField() - index) + " more")
+				break
+			}
+			d.buffer = strconv.AppendQuote(d.buffer,
+				value.Type().Field(index).Name)
+			d.buffer = append(d.buffer, ": "...)
+			d.dump(value.Field(index), depth + 1)
+			if d.full() {
+				break
+			}
+		}
+		d.buffer = append(d.buffer, '}')
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			d.buffer = append(d.buffer, "null"...)
+			return
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 &&
+			value.Kind() == reflect.Slice {
+			d.buffer = strconv.AppendQuote(d.buffer, string(value.Bytes()))
+			return
+		}
+		if depth >= d.option.MaxDepth {
+			d.marker("max depth")
+			return
+		}
+		d.buffer = append(d.buffer, '[')
+		for index := 0; index < value.Len(); index++ {
+			if index > 0 {
+				d.buffer = append(d.buffer, ", "...)
+			}
+			if index >= d.option.MaxElements {
+				d.marker(strconv.Itoa(value.Len() - index) + " more")
+				break
+			}
+			d.dump(value.Index(index), depth + 1)
+			if d.full() {
+				break
+			}
+		}
+		d.buffer = append(d.buffer, ']')
+	default:
+		// Channels, functions and unsafe pointers cannot be dumped, so
+		// their types are dumped instead.
+		d.buffer = strconv.AppendQuote(d.buffer, value.Type().String())
+	}
+}
+
+// SerializeJSON serializes the dumped value into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer slice.
+// If the dumped value exceeds the maximum size, the truncated data is
+// serialized as a JSON string with a marker instead.
+func (e DumpElement) SerializeJSON(buffer []byte) []byte {
+	d := dumper {
+		buffer: buffer,
+		start: len(buffer),
+		option: e.Option,
+	}
+	d.dump(reflect.ValueOf(e.Value), 0)
+	return d.result()
+}
+
+// result returns the buffer containing the dumped data. If the dumped data
+// exceeds the maximum size, the truncated data is serialized as a JSON
+// string with a marker instead.
+func (d *dumper) result() []byte {
+	if !d.full() {
+		return d.buffer
+	}
+	size := d.option.MaxSize
+	if size > len(d.buffer) - d.start {
+		size = len(d.buffer) - d.start
+	}
+	truncated := string(d.buffer[d.start : d.start + size])
+	return strconv.AppendQuote(d.buffer[ : d.start], truncated +
+		"...(truncated at " + strconv.Itoa(size) + " bytes)")
+}
+
+// DumpWith returns the value of a field with a given name, whose value is
+// the given value dumped with the given dump option. For details, please
+// refer to the comment section of the DumpElement structure.
+func DumpWith(name string, value interface { }, option *DumpOption) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: DumpElement {
+				Value: value,
+				Option: *option,
+			},
+		},
+		Name: name,
+	}
+}
+
+// Dump returns the value of a field with a given name, whose value is the
+// given value dumped with the default dump option. For details, please
+// refer to the comment section of the DumpElement structure.
+func Dump(name string, value interface { }) Field {
+	return DumpWith(name, value, &defaultDumpOption)
+}
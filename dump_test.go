@@ -0,0 +1,92 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testDumpNode struct {
+	Name string
+	next *testDumpNode
+	Tags map[string]int
+}
+
+func TestDumpElement(t *testing.T) {
+	node := &testDumpNode { Name: "head", Tags: map[string]int { "b": 2,
+		"a": 1 } }
+	node.next = node
+	assert.Equal(t, `{"Name": "head", "next": "...(cycle)", ` +
+		`"Tags": {"a": 1, "b": 2}}`, string(Dump("node", node).Element.
+		SerializeJSON(nil)), "Unexpected dump data")
+
+	values := []interface { } {
+		nil, true, -1, uint8(2), 1.5, math.NaN(), "text", []byte("raw"),
+		errors.New("failed"), []int(nil), make(chan int),
+	}
+	assert.Equal(t, `[null, true, -1, 2, 1.5, "NaN", "text", "raw", ` +
+		`"failed", null, "chan int"]`, string(Dump("values", values).
+		Element.SerializeJSON(nil)), "Unexpected dump data")
+
+	option := NewDumpOption()
+	option.MaxDepth = 1
+	option.MaxElements = 2
+	assert.Equal(t, `[1, "...(max depth)", "...(1 more)"]`,
+		string(DumpWith("nested", []interface { } { 1, []int { 2 }, 3 },
+		option).Element.SerializeJSON(nil)), "Unexpected dump data")
+
+	option = NewDumpOption()
+	option.MaxSize = 8
+	data := string(DumpWith("large", strings.Repeat("x", 64), option).
+		Element.SerializeJSON(nil))
+	assert.Equal(t, `"\"xxxxxxx...(truncated at 8 bytes)"`, data,
+		"Unexpected dump data")
+}
+
+func TestStructLoggerDump(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling()
+	option.Outputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	assert.NoError(t, logger.Dump("config", map[string]int { "size": 1 }),
+		"Unexpected print error")
+	assert.Contains(t, writer.String(), `"config": {"size": 1}`,
+		"Unexpected output data")
+	assert.Contains(t, writer.String(), "dump_test.go",
+		"Unexpected source location")
+
+	logger.SetLevel(LevelInfo)
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = logger.Dump("config", logger)
+	})
+	assert.Zero(t, allocs, "Unexpected allocations")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+}
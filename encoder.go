@@ -24,10 +24,17 @@ package santa
 
 import (
 	"errors"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// DefaultNameSeparator represents the default separator between the
+// segments of hierarchical names of log entries.
+const DefaultNameSeparator = "."
+
 var (
 	// ErrUnsupportedMessage represents that the message type of the
 	// given log entry is not supported, usually because the message
@@ -61,10 +68,239 @@ type EncoderOption struct {
 	// value is true.
 	EncodeName bool
 
+	// NameSeparator represents the separator between the segments of the
+	// hierarchical names of log entries (for example: "app.db.pool"). If
+	// not provided, the default value is the DefaultNameSeparator
+	// constant.
+	NameSeparator string
+
+	// LeafName represents whether to encode only the last segment of the
+	// hierarchical name of each log entry (for example: "pool" of
+	// "app.db.pool"), instead of the full name. If not provided, the
+	// default value is false.
+	LeafName bool
+
 	// EncodeLevel represents whether to encode the level of the log entry
 	// and append it to the encoding result. If not provided, the default
 	// value is true.
 	EncodeLevel bool
+
+	// OmitEmpty represents whether to skip fields whose values are empty,
+	// such as zero values, empty strings, nil values, empty slices and
+	// empty nested objects, so as to reduce the size of sparse structured
+	// log entries. Fields returned by the Keep function are always encoded.
+	// It only takes effect when the log entry message implements the
+	// FieldSerializer interface. If not provided, the default value is
+	// false.
+	OmitEmpty bool
+
+	// MaxFieldLength represents the maximum number of bytes of the value
+	// of each string or bytes field, so that a single huge field does not
+	// dominate the size of the log entry. The value that exceeds the
+	// maximum length is truncated and appended with a marker containing
+	// the number of truncated bytes. Fields returned by the Truncate
+	// function use their own maximum length. It only takes effect when
+	// the log entry message implements the FieldSerializer interface. If
+	// not provided, the default value is 0, which means unlimited.
+	MaxFieldLength int
+
+	// UnsafeStringConversion represents whether the values of bytes fields
+	// originating from strings (see the StringBytes function), and of
+	// string fields originating from []byte (see the ByteString function),
+	// are converted without copying when they are encoded, which avoids
+	// the last allocations of high-frequency logging.
+	//
+	// Please note that the converted values share memory with the original
+	// values. The application must not modify a []byte value referenced by
+	// a field until the log entry has been encoded, including log entries
+	// retained by a sampler or queued by an asynchronous exporter. It only
+	// takes effect when the log entry message implements the FieldSerializer
+	// interface. If not provided, the default value is false.
+	UnsafeStringConversion bool
+
+	// JournalPriority represents whether to prefix each encoded log entry
+	// with the SD-DAEMON priority marker of its level (for example: "<3>"
+	// for ERROR, see the Priority function of the Level type) when the
+	// process runs under systemd with its output connected to the journal,
+	// which is detected by the JOURNAL_STREAM environment variable. The
+	// journal then records the correct priority for each line written to
+	// the standard output. If the process does not run under systemd, it
+	// has no effect. If not provided, the default value is false.
+	JournalPriority bool
+
+	// TimePrecision represents the precision to which the time of each
+	// log entry is truncated when it is encoded (for example: time.Second
+	// or time.Millisecond), so that the encoded timestamps are shorter and
+	// do not pretend to be more precise than the time source of the logger
+	// (see the TimeSource option of the Option structure). If not provided,
+	// the default value is 0, which means the time is not truncated.
+	TimePrecision time.Duration
+
+	// Encryptor represents the field encryptor that encrypts the values
+	// of encrypted fields when they are encoded. For details, please refer
+	// to the comment section of the EncryptedString function. It only
+	// takes effect when the log entry message implements the
+	// FieldSerializer interface. If not provided, the values of encrypted
+	// fields are masked.
+	Encryptor *FieldEncryptor
+
+	// FloatDecimals represents the number of digits after the decimal
+	// point of the values of float fields (for example: 2 encodes 1.005
+	// as 1.00). It only takes effect when the log entry message implements
+	// the FieldSerializer interface. If not provided, the default value is
+	// 0, which means the shortest representation that parses back to the
+	// same value is used.
+	FloatDecimals int
+
+	// FloatScientificThreshold represents the magnitude at or above which,
+	// or below whose reciprocal, the non-zero values of float fields are
+	// encoded in scientific notation (for example: with 1e6, 2500000 is
+	// encoded as 2.5e+06 and 0.0000025 as 2.5e-06). It only takes effect
+	// when the log entry message implements the FieldSerializer interface.
+	// If not provided, the default value is 0, which means scientific
+	// notation is never used.
+	FloatScientificThreshold float64
+
+	// NonFiniteFloats represents how the NaN and infinite values of float
+	// fields are encoded, since they are not valid JSON numbers, and its
+	// optional options are constants starting with NonFinite... If not
+	// provided, the default value is the NonFiniteNull constant.
+	NonFiniteFloats NonFiniteFloat
+}
+
+// truncateTime returns the given time truncated to the precision of the
+// TimePrecision option.
+func (o EncoderOption) truncateTime(value time.Time) time.Time {
+	if o.TimePrecision > 0 {
+		return value.Truncate(o.TimePrecision)
+	}
+	return value
+}
+
+// entryName returns the name of a log entry with the given name to be
+// encoded, which is the last segment of the name if the LeafName option is
+// enabled.
+func (o EncoderOption) entryName(name string) string {
+	if o.LeafName && len(o.NameSeparator) > 0 {
+		if index := strings.LastIndex(name, o.NameSeparator); index >= 0 {
+			return name[index + len(o.NameSeparator) : ]
+		}
+	}
+	return name
+}
+
+// nameDepth returns the number of ancestors of the given hierarchical
+// name, which is 0 for a name without separators.
+func (o EncoderOption) nameDepth(name string) int {
+	if len(o.NameSeparator) == 0 {
+		return 0
+	}
+	return strings.Count(name, o.NameSeparator)
+}
+
+// UseNameSeparator uses the given separator as the value of the option
+// NameSeparator. Then return to the option instance itself.
+func (o *EncoderOption) UseNameSeparator(separator string) *EncoderOption {
+	o.NameSeparator = separator
+	return o
+}
+
+// UseLeafName enables the LeafName option. For details, please refer to
+// the comment section of the option. Then return to the option instance
+// itself.
+func (o *EncoderOption) UseLeafName() *EncoderOption {
+	o.LeafName = true
+	return o
+}
+
+// UseTimePrecision uses the given precision as the value of the option
+// TimePrecision. For details, please refer to the comment section of the
+// TimePrecision option. Then return to the option instance itself.
+func (o *EncoderOption) UseTimePrecision(precision time.Duration) *EncoderOption {
+	o.TimePrecision = precision
+	return o
+}
+
+// journalStream represents whether the process runs under systemd with
+// its output connected to the journal. It is detected once.
+var journalStream = sync.OnceValue(func() bool {
+	return len(os.Getenv("JOURNAL_STREAM")) > 0
+})
+
+// appendPriority appends the SD-DAEMON priority marker of the given log
+// level to the given buffer slice if the JournalPriority option is enabled
+// and the process runs under systemd, and then returns the appended buffer
+// slice.
+func (o EncoderOption) appendPriority(buffer []byte, level Level) []byte {
+	if !o.JournalPriority || !journalStream() {
+		return buffer
+	}
+	buffer = append(buffer, '<')
+	buffer = strconv.AppendInt(buffer, int64(level.Priority()), 10)
+	return append(buffer, '>')
+}
+
+// UseJournalPriority enables the JournalPriority option. For details,
+// please refer to the comment section of the option. Then return to the
+// option instance itself.
+func (o *EncoderOption) UseJournalPriority() *EncoderOption {
+	o.JournalPriority = true
+	return o
+}
+
+// UseEncryptor uses the given field encryptor as the value of the option
+// Encryptor. For details, please refer to the comment section of the
+// Encryptor option. Then return to the option instance itself.
+func (o *EncoderOption) UseEncryptor(encryptor *FieldEncryptor) *EncoderOption {
+	o.Encryptor = encryptor
+	return o
+}
+
+// UseFloatDecimals uses the given number of digits as the value of the
+// option FloatDecimals. For details, please refer to the comment section
+// of the FloatDecimals option. Then return to the option instance itself.
+func (o *EncoderOption) UseFloatDecimals(decimals int) *EncoderOption {
+	o.FloatDecimals = decimals
+	return o
+}
+
+// UseFloatScientificThreshold uses the given magnitude as the value of the
+// option FloatScientificThreshold. For details, please refer to the
+// comment section of the FloatScientificThreshold option. Then return to
+// the option instance itself.
+func (o *EncoderOption) UseFloatScientificThreshold(threshold float64) *EncoderOption {
+	o.FloatScientificThreshold = threshold
+	return o
+}
+
+// UseNonFiniteFloats uses the given handling as the value of the option
+// NonFiniteFloats. For details, please refer to the comment section of
+// the NonFiniteFloats option. Then return to the option instance itself.
+func (o *EncoderOption) UseNonFiniteFloats(handling NonFiniteFloat) *EncoderOption {
+	o.NonFiniteFloats = handling
+	return o
+}
+
+// UseUnsafeStringConversion enables the UnsafeStringConversion option.
+// For details, please refer to the comment section of the option. Then
+// return to the option instance itself.
+func (o *EncoderOption) UseUnsafeStringConversion() *EncoderOption {
+	o.UnsafeStringConversion = true
+	return o
+}
+
+// fieldOption returns the field option value used to serialize the fields
+// of log entry messages.
+func (o EncoderOption) fieldOption() FieldOption {
+	return FieldOption {
+		OmitEmpty: o.OmitEmpty,
+		MaxLength: o.MaxFieldLength,
+		UnsafeStringConversion: o.UnsafeStringConversion,
+		Encryptor: o.Encryptor,
+		FloatDecimals: o.FloatDecimals,
+		FloatScientificThreshold: o.FloatScientificThreshold,
+		NonFiniteFloats: o.NonFiniteFloats,
+	}
 }
 
 // NewEncoderOption returns an encoder option value with default optional
@@ -75,6 +311,7 @@ func NewEncoderOption() EncoderOption {
 		EncodeSourceLocation: true,
 		EncodeLabels: true,
 		EncodeName: true,
+		NameSeparator: DefaultNameSeparator,
 		EncodeLevel: true,
 	}
 }
@@ -166,6 +403,25 @@ type StandardSerializer interface {
 	SerializeStandard(buffer []byte) []byte
 }
 
+// FieldSerializer is the public interface of the field serializer.
+//
+// Any message type of a log entry that contains fields can implement this
+// interface, so that encoders can apply field options such as OmitEmpty
+// and MaxFieldLength when encoding the message part of a log entry. For
+// details, please refer to the comment section of the FieldOption
+// structure.
+type FieldSerializer interface {
+	// SerializeStandardWith serializes the message into a standard log
+	// string using the given field option and appends to the given buffer
+	// slice, and then returns the appended buffer slice.
+	SerializeStandardWith(buffer []byte, option FieldOption) []byte
+
+	// SerializeJSONWith serializes the message into a JSON string using
+	// the given field option and appends to the given buffer slice, and
+	// then returns the appended buffer slice.
+	SerializeJSONWith(buffer []byte, option FieldOption) []byte
+}
+
 // StandardEncoder is the structure of a standard encoder instance.
 // 
 // Standard encoders encode log entries into human-readable strings,
@@ -186,11 +442,14 @@ type StandardEncoder struct {
 // format, then appends to the given buffer slice, and finally returns
 // the appended buffer slice.
 func (e *StandardEncoder) Encode(buffer []byte, entry *Entry) ([]byte, error) {
+	buffer = e.option.appendPriority(buffer, entry.Level)
 	if e.option.EncodeTime {
 		if len(e.layout) == 0 {
-			buffer = strconv.AppendInt(buffer, entry.Time.UnixNano(), 10)
+			buffer = strconv.AppendInt(buffer,
+				e.option.truncateTime(entry.Time).UnixNano(), 10)
 		} else {
-			buffer = entry.Time.AppendFormat(buffer, e.layout)
+			buffer = e.option.truncateTime(entry.Time).AppendFormat(buffer,
+				e.layout)
 		}
 		buffer = append(buffer, ' ')
 	}
@@ -203,7 +462,7 @@ func (e *StandardEncoder) Encode(buffer []byte, entry *Entry) ([]byte, error) {
 		buffer = append(buffer, ' ')
 	}
 	if e.option.EncodeName && len(entry.Name) > 0 {
-		buffer = append(buffer, entry.Name...)
+		buffer = append(buffer, e.option.entryName(entry.Name)...)
 		buffer = append(buffer, ' ')
 	}
 	if e.option.EncodeLevel {
@@ -215,7 +474,16 @@ func (e *StandardEncoder) Encode(buffer []byte, entry *Entry) ([]byte, error) {
 	case nil:
 		buffer = append(buffer, "null"...)
 	case StandardSerializer:
-		buffer = message.SerializeStandard(buffer)
+		option := e.option.fieldOption()
+		if err := option.checkFloats(message); err != nil {
+			return nil, err
+		}
+		serializer, ok := message.(FieldSerializer)
+		if ok && option != (FieldOption { }) {
+			buffer = serializer.SerializeStandardWith(buffer, option)
+		} else {
+			buffer = message.SerializeStandard(buffer)
+		}
 	default:
 		return nil, ErrUnsupportedMessage
 	}
@@ -310,6 +578,29 @@ type JSONEncoder struct {
 	layout string
 	keys EncoderKeys
 	option EncoderOption
+	sequence bool
+}
+
+const (
+	// ContentTypeNDJSON represents the media type of the data encoded by
+	// the JSON encoder, which is newline delimited JSON.
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	// ContentTypeJSONSequence represents the media type of the data
+	// encoded by the JSON encoder with the Sequence option enabled, which
+	// is a JSON text sequence of RFC 7464.
+	ContentTypeJSONSequence = "application/json-seq"
+)
+
+// ContentType returns the media type of the data encoded by the encoder,
+// which is one of the constants beginning with ContentType..., so that it
+// can be announced to consumers of the data (for example: in the
+// Content-Type header of an HTTP request).
+func (e *JSONEncoder) ContentType() string {
+	if e.sequence {
+		return ContentTypeJSONSequence
+	}
+	return ContentTypeNDJSON
 }
 
 // Encode encodes a given log entry into consecutive bytes in a specific
@@ -320,6 +611,12 @@ func (e *JSONEncoder) Encode(buffer []byte, entry *Entry) ([]byte, error) {
 	if !ok {
 		return nil, ErrUnsupportedMessage
 	}
+	buffer = e.option.appendPriority(buffer, entry.Level)
+	if e.sequence {
+		// Each record of a JSON text sequence starts with a record
+		// separator.
+		buffer = append(buffer, 0x1e)
+	}
 	buffer = append(buffer, '{')
 	if e.option.EncodeTime {
 		buffer = append(buffer, '"')
@@ -327,11 +624,13 @@ func (e *JSONEncoder) Encode(buffer []byte, entry *Entry) ([]byte, error) {
 
 		if len(e.layout) == 0 {
 			buffer = append(buffer, "\": "...)
-			buffer = strconv.AppendInt(buffer, entry.Time.UnixNano(), 10)
+			buffer = strconv.AppendInt(buffer,
+				e.option.truncateTime(entry.Time).UnixNano(), 10)
 			buffer = append(buffer, ", "...)
 		} else {
 			buffer = append(buffer, "\": \""...)
-			buffer = entry.Time.AppendFormat(buffer, e.layout)
+			buffer = e.option.truncateTime(entry.Time).AppendFormat(buffer,
+				e.layout)
 			buffer = append(buffer, "\", "...)
 		}
 	}
@@ -342,7 +641,8 @@ func (e *JSONEncoder) Encode(buffer []byte, entry *Entry) ([]byte, error) {
 		buffer = entry.SourceLocation.SerializeJSON(buffer)
 		buffer = append(buffer, ", "...)
 	}
-	if e.option.EncodeLabels {
+	if e.option.EncodeLabels && (!e.option.OmitEmpty ||
+		entry.Labels.Count() > 0) {
 		buffer = append(buffer, '"')
 		buffer = append(buffer, e.keys.LabelsKey...)
 		buffer = append(buffer, `": `...)
@@ -354,13 +654,13 @@ func (e *JSONEncoder) Encode(buffer []byte, entry *Entry) ([]byte, error) {
 		}
 		buffer = append(buffer, ", "...)
 	}
-	if e.option.EncodeName {
+	if e.option.EncodeName && (!e.option.OmitEmpty || len(entry.Name) > 0) {
 		buffer = append(buffer, '"')
 		buffer = append(buffer, e.keys.NameKey...)
 
 		if len(entry.Name) > 0 {
 			buffer = append(buffer, "\": \""...)
-			buffer = append(buffer, entry.Name...)
+			buffer = append(buffer, e.option.entryName(entry.Name)...)
 			buffer = append(buffer, "\", "...)
 		} else {
 			buffer = append(buffer, "\": "...)
@@ -378,7 +678,16 @@ func (e *JSONEncoder) Encode(buffer []byte, entry *Entry) ([]byte, error) {
 	buffer = append(buffer, '"')
 	buffer = append(buffer, e.keys.MessageKey...)
 	buffer = append(buffer, "\": "...)
-	buffer = message.SerializeJSON(buffer)
+	option := e.option.fieldOption()
+	if err := option.checkFloats(message); err != nil {
+		return nil, err
+	}
+	serializer, ok := message.(FieldSerializer)
+	if ok && option != (FieldOption { }) {
+		buffer = serializer.SerializeJSONWith(buffer, option)
+	} else {
+		buffer = message.SerializeJSON(buffer)
+	}
 	return append(buffer, "}\n"...), nil
 }
 
@@ -393,6 +702,26 @@ func (e *JSONEncoder) Option() EncoderOption {
 type JSONEncoderOption struct {
 	StandardEncoderOption
 	EncoderKeys
+
+	// Sequence represents whether log entries are encoded as a JSON text
+	// sequence of RFC 7464 (application/json-seq), in which each log entry
+	// is prefixed by a record separator ("\x1e") and terminated by a line
+	// feed, instead of newline delimited JSON. Stream parsers can then
+	// resynchronize at the next record separator after a corrupted or
+	// truncated log entry. If not provided, the default value is false.
+	//
+	// Please note that the record framing of the exporter must not be
+	// FramingJSONSequence at the same time, otherwise each log entry is
+	// prefixed by two record separators.
+	Sequence bool
+}
+
+// UseSequence enables the option Sequence. For details, please refer to
+// the comment section of the Sequence option. Then return to the option
+// instance itself.
+func (o *JSONEncoderOption) UseSequence() *JSONEncoderOption {
+	o.Sequence = true
+	return o
 }
 
 // UseEncoderOption uses the given encoder option as part of the JSON
@@ -417,6 +746,7 @@ func (o *JSONEncoderOption) Build() (*JSONEncoder, error) {
 		layout: o.TimeLayout,
 		keys: o.EncoderKeys,
 		option: o.EncoderOption,
+		sequence: o.Sequence,
 	}, nil
 }
 
@@ -24,6 +24,8 @@ package santa
 
 import (
 	"fmt"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
@@ -112,6 +114,153 @@ func TestJSONEncoderEncode(t *testing.T) {
 		"Unexpected JSON encoder output")
 }
 
+func TestJSONEncoderOmitEmpty(t *testing.T) {
+	buffer := make([]byte, 0, 1024)
+
+	option := NewJSONEncoderOption()
+	option.EncodeTime = false
+	option.EncodeSourceLocation = false
+	option.OmitEmpty = true
+
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+
+	buffer, err = encoder.Encode(buffer, &Entry {
+		Level: LevelInfo,
+		Message: StructMessage {
+			Text: "Hello Test!",
+			Fields: []Field {
+				String("name", "test"),
+				String("empty", ""),
+				Int("zero", 0),
+				Keep(Int("kept", 0)),
+				Ints("ints", nil),
+				Object("object", String("empty", "")),
+				Object("nested", Int("zero", 0), Boolean("ok", true)),
+			},
+		},
+	})
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+
+	const expected = `{
+		"level": "INFO",
+		"message": {
+			"text": "Hello Test!",
+			"payload": {
+				"name": "test",
+				"kept": 0,
+				"nested": {
+					"ok": true
+				}
+			}
+		}
+	}`
+
+	assert.JSONEq(t, expected, string(buffer),
+		"Unexpected JSON encoder output")
+}
+
+func TestJSONEncoderMaxFieldLength(t *testing.T) {
+	buffer := make([]byte, 0, 1024)
+
+	option := NewJSONEncoderOption()
+	option.EncodeTime = false
+	option.EncodeSourceLocation = false
+	option.EncodeLabels = false
+	option.EncodeName = false
+	option.EncodeLevel = false
+	option.MaxFieldLength = 5
+
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+
+	buffer, err = encoder.Encode(buffer, &Entry {
+		Message: StructMessage {
+			Text: "Hello Test!",
+			Fields: []Field {
+				String("short", "Hello"),
+				String("long", "Hello World"),
+				String("unicode", "Hellö World"),
+				Bytes("bytes", []byte("Hello World")),
+				Truncate(String("custom", "Hello World"), 8),
+			},
+		},
+	})
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+
+	const expected = `{
+		"message": {
+			"text": "Hello Test!",
+			"payload": {
+				"short": "Hello",
+				"long": "Hello...(truncated 6 bytes)",
+				"unicode": "Hell...(truncated 8 bytes)",
+				"bytes": "SGVsbG8=...(truncated 6 bytes)",
+				"custom": "Hello Wo...(truncated 3 bytes)"
+			}
+		}
+	}`
+
+	assert.JSONEq(t, expected, string(buffer),
+		"Unexpected JSON encoder output")
+}
+
+func TestJSONEncoderFloatFormatting(t *testing.T) {
+	option := NewJSONEncoderOption()
+	option.EncodeTime = false
+	option.EncodeSourceLocation = false
+	option.EncodeLabels = false
+	option.EncodeName = false
+	option.EncodeLevel = false
+	message := StructMessage {
+		Text: "Hello Test!",
+		Fields: []Field {
+			Float64("fixed", 1.5),
+			Float64("large", 2500000),
+			Float64("small", 0.00000025),
+			Float64("zero", 0),
+			Float64("nan", math.NaN()),
+			Float64s("infs", []float64 { math.Inf(1), math.Inf(-1) }),
+			Keep(Float32("kept", float32(math.NaN()))),
+		},
+	}
+
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	buffer, err := encoder.Encode(nil, &Entry { Message: message })
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+	assert.JSONEq(t, `{"message": {"text": "Hello Test!", "payload": {
+		"fixed": 1.5, "large": 2500000, "small": 0.00000025, "zero": 0,
+		"nan": null, "infs": [null, null], "kept": null}}}`,
+		string(buffer), "Unexpected JSON encoder output")
+
+	option.UseFloatDecimals(2).UseFloatScientificThreshold(1e6).
+		UseNonFiniteFloats(NonFiniteString)
+	encoder, err = option.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	buffer, err = encoder.Encode(nil, &Entry { Message: message })
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+	assert.Contains(t, string(buffer), `{"fixed": 1.50, "large": 2.50e+06, ` +
+		`"small": 2.50e-07, "zero": 0.00, "nan": "NaN", ` +
+		`"infs": ["+Inf", "-Inf"], "kept": "NaN"}`,
+		"Unexpected JSON encoder output")
+
+	option.UseNonFiniteFloats(NonFiniteError)
+	encoder, err = option.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	_, err = encoder.Encode(nil, &Entry { Message: message })
+	assert.Equal(t, ErrNonFiniteFloat, err, "Unexpected JSON encoder error")
+	_, err = encoder.Encode(nil, &Entry { Message: StructMessage {
+		Fields: []Field { Object("object", Floats("floats",
+			[]float32 { float32(math.Inf(1)) })) },
+	} })
+	assert.Equal(t, ErrNonFiniteFloat, err, "Unexpected JSON encoder error")
+	_, err = encoder.Encode(nil, &Entry { Message: StructMessage {
+		Fields: []Field { Float64("finite", 1) },
+	} })
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+}
+
 func TestStandardEncoderOption(t *testing.T) {
 	option := NewStandardEncoderOption()
 
@@ -151,3 +300,173 @@ func TestJSONEncoderOption(t *testing.T) {
 	_, err := option.Build()
 	assert.NoError(t, err, "Unexpected build error")
 }
+
+func TestJSONEncoderUnsafeStringConversion(t *testing.T) {
+	entry := &Entry {
+		Message: StructMessage {
+			Text: "Hello Test!",
+			Fields: []Field {
+				ByteString("text", []byte("Hellö World")),
+				StringBytes("base64", strings.Repeat("Hello World", 10),
+					BytesBase64),
+				StringBytes("hex", "Hi", BytesHex),
+				ByteString("empty", nil),
+			},
+		},
+	}
+	const expected = `{
+		"message": {
+			"text": "Hello Test!",
+			"payload": {
+				"text": "Hell...(truncated 8 bytes)",
+				"base64": "SGVsbG8=...(truncated 105 bytes)",
+				"hex": "4869"
+			}
+		}
+	}`
+
+	for _, unsafe := range []bool { false, true } {
+		option := NewJSONEncoderOption()
+		option.EncoderOption = EncoderOption {
+			OmitEmpty: true,
+			MaxFieldLength: 5,
+		}
+		if unsafe {
+			option.EncoderOption.UseUnsafeStringConversion()
+		}
+		encoder, err := option.Build()
+		assert.NoError(t, err, "Unexpected JSON encoder creation error")
+		buffer, err := encoder.Encode(nil, entry)
+		assert.NoError(t, err, "Unexpected JSON encoder error")
+		assert.JSONEq(t, expected, string(buffer),
+			"Unexpected JSON encoder output")
+		if unsafe {
+			buffer = make([]byte, 0, 1024)
+			assert.Equal(t, float64(0), testing.AllocsPerRun(100, func() {
+				_, _ = encoder.Encode(buffer[ : 0], entry)
+			}), "Unexpected allocations")
+		}
+	}
+}
+
+func TestJSONEncoderSequence(t *testing.T) {
+	encoder, err := NewJSONEncoder()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	assert.Equal(t, ContentTypeNDJSON, encoder.ContentType(),
+		"Unexpected content type")
+
+	encoder, err = NewJSONEncoderOption().UseSequence().Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	assert.Equal(t, ContentTypeJSONSequence, encoder.ContentType(),
+		"Unexpected content type")
+
+	buffer, err := encoder.Encode(nil, entry)
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+	assert.Equal(t, byte(0x1e), buffer[0], "Unexpected record separator")
+	assert.Equal(t, byte('\n'), buffer[len(buffer) - 1],
+		"Unexpected record terminator")
+	assert.JSONEq(t, `{"timestamp": 1597326990071993900, "sourceLocation": ` +
+		`{"file": "main.go", "line": 100, "function": ""}, "labels": ` +
+		`{"instanceId": "d325ef24327c"}, "name": "test", "level": "INFO", ` +
+		`"message": "Hello Test!"}`, string(buffer[1 : ]),
+		"Unexpected JSON encoder output")
+
+	option := NewEncodingOption().UseJSONSequence()
+	built, err := option.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	assert.Equal(t, ContentTypeJSONSequence, built.(*JSONEncoder).
+		ContentType(), "Unexpected content type")
+}
+
+func TestEncoderJournalPriority(t *testing.T) {
+	option := NewStandardEncoderOption()
+	option.EncoderOption.UseJournalPriority()
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected standard encoder creation error")
+
+	detected := journalStream
+	defer func() {
+		journalStream = detected
+	}()
+
+	journalStream = func() bool { return false }
+	buffer, err := encoder.Encode(nil, entry)
+	assert.NoError(t, err, "Unexpected standard encoder error")
+	assert.NotEqual(t, byte('<'), buffer[0], "Unexpected priority prefix")
+
+	journalStream = func() bool { return true }
+	buffer, err = encoder.Encode(nil, entry)
+	assert.NoError(t, err, "Unexpected standard encoder error")
+	assert.True(t, strings.HasPrefix(string(buffer), "<6>"),
+		"Unexpected priority prefix")
+
+	jsonOption := NewJSONEncoderOption()
+	jsonOption.EncoderOption.UseJournalPriority()
+	jsonEncoder, err := jsonOption.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	buffer, err = jsonEncoder.Encode(nil, &Entry {
+		Level: LevelError,
+		Message: StringMessage("Hello Test!"),
+	})
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+	assert.True(t, strings.HasPrefix(string(buffer), "<3>{"),
+		"Unexpected priority prefix")
+}
+
+func TestEncoderTimePrecision(t *testing.T) {
+	timed := &Entry {
+		Level: LevelInfo,
+		Time: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+		Message: StringMessage("Hello Test!"),
+	}
+	option := NewStandardEncoderOption()
+	option.EncoderOption.UseTimePrecision(time.Millisecond)
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected standard encoder creation error")
+	buffer, err := encoder.Encode(nil, timed)
+	assert.NoError(t, err, "Unexpected standard encoder error")
+	assert.True(t, strings.HasPrefix(string(buffer),
+		"2024-01-02T03:04:05.123Z "), "Unexpected truncated time")
+
+	jsonOption := NewJSONEncoderOption()
+	jsonOption.EncoderOption.UseTimePrecision(time.Second)
+	jsonEncoder, err := jsonOption.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	buffer, err = jsonEncoder.Encode(nil, timed)
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+	assert.Contains(t, string(buffer), fmt.Sprintf(": %d,",
+		timed.Time.Unix() * int64(time.Second)), "Unexpected truncated time")
+}
+
+func TestEncoderLeafName(t *testing.T) {
+	named := &Entry {
+		Name: "app::db::pool",
+		Level: LevelInfo,
+		Message: StringMessage("Hello Test!"),
+	}
+	option := NewJSONEncoderOption()
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	buffer, err := encoder.Encode(nil, named)
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+	assert.Contains(t, string(buffer), `"name": "app::db::pool"`,
+		"Unexpected full name")
+
+	option.EncoderOption.UseNameSeparator("::").UseLeafName()
+	encoder, err = option.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	buffer, err = encoder.Encode(nil, named)
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+	assert.Contains(t, string(buffer), `"name": "pool"`,
+		"Unexpected leaf name")
+
+	standardOption := NewStandardEncoderOption()
+	standardOption.EncoderOption.UseLeafName()
+	standardEncoder, err := standardOption.Build()
+	assert.NoError(t, err, "Unexpected standard encoder creation error")
+	named.Name = "app.db"
+	buffer, err = standardEncoder.Encode(nil, named)
+	assert.NoError(t, err, "Unexpected standard encoder error")
+	assert.Contains(t, string(buffer), " db [INFO] ",
+		"Unexpected leaf name")
+}
@@ -0,0 +1,409 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// EncryptedPrefix represents the prefix of the ciphertexts of encrypted
+// fields. For details, please refer to the comment section of the
+// EncryptedString function.
+const EncryptedPrefix = "enc:v1:"
+
+const (
+	// encryptedMask represents the value of encrypted fields encoded by
+	// encoders without a field encryptor.
+	encryptedMask = "[ENCRYPTED]"
+
+	// encryptionFailedMask represents the value of encrypted fields that
+	// failed to be encrypted.
+	encryptionFailedMask = "[ENCRYPTION FAILED]"
+)
+
+// ErrInvalidCiphertext represents that the ciphertext of an encrypted
+// field is malformed, or cannot be authenticated.
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// KeyWrapper is the public interface of the key wrapper.
+//
+// The key wrapper encrypts the data keys generated by the field encryptor
+// with a key that only the authorized consumers of the log entries can
+// use, such as a public key or a key managed by a key management service
+// (KMS). The wrapped data keys are embedded in the ciphertexts, so that
+// the consumers can decrypt them later.
+type KeyWrapper interface {
+	// KeyID returns the identifier of the wrapping key, which is embedded
+	// in the ciphertexts to select the unwrapping key. It must not be
+	// empty.
+	KeyID() string
+
+	// WrapKey encrypts the given data key, and then returns the wrapped
+	// data key and any errors encountered.
+	WrapKey(key []byte) ([]byte, error)
+}
+
+// KeyUnwrapper is the public interface of the key unwrapper, which is the
+// counterpart of the KeyWrapper interface used by the consumers of the log
+// entries.
+type KeyUnwrapper interface {
+	// UnwrapKey decrypts the given data key wrapped by the wrapping key
+	// with the given identifier, and then returns the data key and any
+	// errors encountered.
+	UnwrapKey(keyID string, wrapped []byte) ([]byte, error)
+}
+
+// RSAKeyWrapper is the structure of the RSA key wrapper instance, which
+// wraps data keys with an RSA public key using RSA-OAEP with SHA-256.
+type RSAKeyWrapper struct {
+	id string
+	key *rsa.PublicKey
+}
+
+// KeyID returns the identifier of the public key.
+func (w *RSAKeyWrapper) KeyID() string {
+	return w.id
+}
+
+// WrapKey encrypts the given data key with the public key, and then
+// returns the wrapped data key and any errors encountered.
+func (w *RSAKeyWrapper) WrapKey(key []byte) ([]byte, error) {
+	return rsa.EncryptOAEP(sha256.New(), rand.Reader, w.key, key, nil)
+}
+
+// NewRSAKeyWrapper creates and returns an RSA key wrapper instance using
+// the given key identifier and public key.
+func NewRSAKeyWrapper(id string, key *rsa.PublicKey) *RSAKeyWrapper {
+	return &RSAKeyWrapper {
+		id: id,
+		key: key,
+	}
+}
+
+// RSAKeyUnwrapper is the structure of the RSA key unwrapper instance,
+// which unwraps data keys wrapped by the RSA key wrapper with the same
+// key identifier.
+type RSAKeyUnwrapper struct {
+	id string
+	key *rsa.PrivateKey
+}
+
+// UnwrapKey decrypts the given wrapped data key with the private key, and
+// then returns the data key and any errors encountered. If the given key
+// identifier is not the identifier of the private key, it returns the
+// ErrInvalidCiphertext error.
+func (u *RSAKeyUnwrapper) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	if keyID != u.id {
+		return nil, ErrInvalidCiphertext
+	}
+	return rsa.DecryptOAEP(sha256.New(), nil, u.key, wrapped, nil)
+}
+
+// NewRSAKeyUnwrapper creates and returns an RSA key unwrapper instance
+// using the given key identifier and private key.
+func NewRSAKeyUnwrapper(id string, key *rsa.PrivateKey) *RSAKeyUnwrapper {
+	return &RSAKeyUnwrapper {
+		id: id,
+		key: key,
+	}
+}
+
+// fieldDataKey is a structure that contains a data key of the field
+// encryptor.
+type fieldDataKey struct {
+	aead cipher.AEAD
+	header string
+	usage uint64
+}
+
+// FieldEncryptor is the structure of the field encryptor instance.
+//
+// The field encryptor encrypts the values of encrypted fields (see the
+// EncryptedString function) when they are encoded, using envelope
+// encryption: the values are encrypted with AES-GCM using a random data
+// key, and the data key is wrapped by the key wrapper and embedded in each
+// ciphertext. The field name is authenticated as additional data, so the
+// ciphertext cannot be moved to another field unnoticed. A new data key is
+// generated after it has encrypted the maximum number of values.
+//
+// The field encryptor is safe to share between goroutines and encoders.
+type FieldEncryptor struct {
+	wrapper KeyWrapper
+	maxKeyUsage uint64
+	current *fieldDataKey
+	mutex sync.Mutex
+}
+
+// rotate replaces the data key with a new random data key, and then
+// returns any errors encountered. The caller must hold the mutex.
+func (e *FieldEncryptor) rotate() error {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return err
+	}
+	aead, err := newAEAD(AlgorithmAESGCM, key)
+	if err != nil {
+		return err
+	}
+	wrapped, err := e.wrapper.WrapKey(key)
+	if err != nil {
+		return err
+	}
+	e.current = &fieldDataKey {
+		aead: aead,
+		header: EncryptedPrefix + e.wrapper.KeyID() + ":" +
+			base64.RawURLEncoding.EncodeToString(wrapped) + ":",
+	}
+	return nil
+}
+
+// dataKey returns the data key used to encrypt the next value, rotating
+// it if it has encrypted the maximum number of values, and then returns
+// any errors encountered.
+func (e *FieldEncryptor) dataKey() (*fieldDataKey, error) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	if e.current == nil || e.current.usage >= e.maxKeyUsage {
+		if err := e.rotate(); err != nil {
+			return nil, err
+		}
+	}
+	e.current.usage++
+	return e.current, nil
+}
+
+// Encrypt encrypts the given value of the field with the given name, and
+// then returns the ciphertext and any errors encountered. For details
+// about the format of the ciphertext, please refer to the comment section
+// of the EncryptedString function.
+func (e *FieldEncryptor) Encrypt(name, value string) (string, error) {
+	key, err := e.dataKey()
+	if err != nil {
+		return "", err
+	}
+	size := key.aead.NonceSize()
+	nonce := make([]byte, size, size + len(value) + 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := key.aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return key.header + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// FieldEncryptorOption is a structure that contains options for the field
+// encryptor.
+type FieldEncryptorOption struct {
+	// Wrapper represents the key wrapper that wraps the data keys. It must
+	// be provided.
+	Wrapper KeyWrapper
+
+	// MaxKeyUsage represents the maximum number of values encrypted with
+	// each data key, after which a new data key is generated and wrapped.
+	// If not provided, the default value is 1 << 24.
+	MaxKeyUsage uint64
+}
+
+// UseWrapper uses the given key wrapper as the value of the option
+// Wrapper. Then return to the option instance itself.
+func (o *FieldEncryptorOption) UseWrapper(wrapper KeyWrapper) *FieldEncryptorOption {
+	o.Wrapper = wrapper
+	return o
+}
+
+// UseMaxKeyUsage uses the given number as the value of the option
+// MaxKeyUsage. For details, please refer to the comment section of the
+// MaxKeyUsage option. Then return to the option instance itself.
+func (o *FieldEncryptorOption) UseMaxKeyUsage(usage uint64) *FieldEncryptorOption {
+	o.MaxKeyUsage = usage
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *FieldEncryptorOption) Validate() error {
+	if o.Wrapper == nil {
+		return newOptionError("Wrapper", "must not be nil", nil)
+	}
+	if len(o.Wrapper.KeyID()) == 0 {
+		return newOptionError("Wrapper", "key identifier must not be empty",
+			nil)
+	}
+	if o.MaxKeyUsage == 0 {
+		return newOptionError("MaxKeyUsage", "must be greater than 0", nil)
+	}
+	if err := CheckAlgorithm(AlgorithmAESGCM); err != nil {
+		return newOptionError("Wrapper", "encryption unavailable", err)
+	}
+	return nil
+}
+
+// Build builds and returns a field encryptor instance, which wraps its
+// first data key immediately, so that a misconfigured key wrapper is
+// reported here instead of when log entries are encoded.
+func (o *FieldEncryptorOption) Build() (*FieldEncryptor, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	encryptor := &FieldEncryptor {
+		wrapper: o.Wrapper,
+		maxKeyUsage: o.MaxKeyUsage,
+	}
+	if err := encryptor.rotate(); err != nil {
+		return nil, err
+	}
+	return encryptor, nil
+}
+
+// NewFieldEncryptorOption creates and returns a field encryptor option
+// instance with default option values.
+func NewFieldEncryptorOption() *FieldEncryptorOption {
+	return &FieldEncryptorOption {
+		MaxKeyUsage: 1 << 24,
+	}
+}
+
+// NewFieldEncryptor creates and returns a field encryptor instance using
+// the given key wrapper and default option values.
+func NewFieldEncryptor(wrapper KeyWrapper) (*FieldEncryptor, error) {
+	return NewFieldEncryptorOption().UseWrapper(wrapper).Build()
+}
+
+// DecryptField decrypts the given ciphertext of the field with the given
+// name using the given key unwrapper, and then returns the value and any
+// errors encountered. If the ciphertext is malformed or cannot be
+// authenticated, it returns the ErrInvalidCiphertext error.
+func DecryptField(name, ciphertext string, unwrapper KeyUnwrapper) (string, error) {
+	if !strings.HasPrefix(ciphertext, EncryptedPrefix) {
+		return "", ErrInvalidCiphertext
+	}
+	text := ciphertext[len(EncryptedPrefix) : ]
+	// The key identifier may contain colons, but the base64 parts never do.
+	sealedIndex := strings.LastIndexByte(text, ':')
+	if sealedIndex < 0 {
+		return "", ErrInvalidCiphertext
+	}
+	keyIndex := strings.LastIndexByte(text[ : sealedIndex], ':')
+	if keyIndex < 1 {
+		return "", ErrInvalidCiphertext
+	}
+	wrapped, err := base64.RawURLEncoding.DecodeString(
+		text[keyIndex + 1 : sealedIndex])
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	sealed, err := base64.RawURLEncoding.DecodeString(text[sealedIndex + 1 : ])
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	key, err := unwrapper.UnwrapKey(text[ : keyIndex], wrapped)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(AlgorithmAESGCM, key)
+	if err != nil {
+		return "", err
+	}
+	size := aead.NonceSize()
+	if len(sealed) < size {
+		return "", ErrInvalidCiphertext
+	}
+	value, err := aead.Open(nil, sealed[ : size], sealed[size : ], []byte(name))
+	if err != nil {
+		return "", ErrInvalidCiphertext
+	}
+	return string(value), nil
+}
+
+// elementEncrypted represents the value of an encrypted field. For
+// details, please refer to the comment section of the EncryptedString
+// function.
+type elementEncrypted struct {
+	name string
+	value string
+}
+
+// SerializeJSON serializes the element into a JSON string and appends it
+// to the given buffer slice, and then returns the appended buffer slice.
+// Without a field encryptor, the value is never serialized in plaintext.
+func (e elementEncrypted) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, '"')
+	buffer = append(buffer, encryptedMask...)
+	return append(buffer, '"')
+}
+
+// serializeJSONWith serializes the element into a JSON string using the
+// field encryptor of the given field option and appends it to the given
+// buffer slice, and then returns the appended buffer slice.
+func (e elementEncrypted) serializeJSONWith(buffer []byte, option FieldOption) []byte {
+	if option.Encryptor == nil {
+		return e.SerializeJSON(buffer)
+	}
+	ciphertext, err := option.Encryptor.Encrypt(e.name, e.value)
+	if err != nil {
+		ciphertext = encryptionFailedMask
+	}
+	buffer = append(buffer, '"')
+	buffer = append(buffer, ciphertext...)
+	return append(buffer, '"')
+}
+
+// IsEmpty always returns false, so that whether the value is empty is not
+// revealed by the OmitEmpty option of the encoder.
+func (elementEncrypted) IsEmpty() bool {
+	return false
+}
+
+// EncryptedString returns the value of a field with a given name and a
+// given sensitive string value, which is encrypted by the field encryptor
+// of the encoder (see the Encryptor option of the EncoderOption structure)
+// when the field is encoded, so that operators only see opaque ciphertext
+// while authorized consumers can decrypt it (see the DecryptField
+// function).
+//
+// The ciphertext is a string of the form "enc:v1:<key id>:<wrapped data
+// key>:<nonce and sealed value>", where the last two parts are encoded in
+// unpadded URL-safe base64. If the encoder has no field encryptor, the
+// value is encoded as "[ENCRYPTED]", and if the encryption fails, it is
+// encoded as "[ENCRYPTION FAILED]"; the value is never encoded in
+// plaintext.
+func EncryptedString(name string, value string) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: elementEncrypted {
+				name: name,
+				value: value,
+			},
+		},
+		Name: name,
+	}
+}
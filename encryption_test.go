@@ -0,0 +1,100 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptedString(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err, "Unexpected key generation error")
+	encryptor, err := NewFieldEncryptorOption().UseMaxKeyUsage(1).
+		UseWrapper(NewRSAKeyWrapper("audit:2024", &key.PublicKey)).Build()
+	assert.NoError(t, err, "Unexpected encryptor creation error")
+
+	option := NewJSONEncoderOption()
+	option.EncodeTime = false
+	option.EncodeSourceLocation = false
+	option.UseEncryptor(encryptor)
+	encoder, err := option.Build()
+	assert.NoError(t, err, "Unexpected JSON encoder creation error")
+	message := StructMessage {
+		Text: "Hello Test!",
+		Fields: []Field {
+			EncryptedString("ssn", "123-45-6789"),
+			Object("card", EncryptedString("number", "4111111111111111")),
+		},
+	}
+	buffer, err := encoder.Encode(nil, &Entry {
+		Level: LevelInfo,
+		Message: message,
+	})
+	assert.NoError(t, err, "Unexpected JSON encoder error")
+	assert.NotContains(t, string(buffer), "123-45-6789",
+		"Unexpected plaintext")
+
+	var decoded struct {
+		Message struct {
+			Payload struct {
+				SSN string `json:"ssn"`
+				Card struct {
+					Number string `json:"number"`
+				} `json:"card"`
+			} `json:"payload"`
+		} `json:"message"`
+	}
+	assert.NoError(t, json.Unmarshal(buffer, &decoded),
+		"Unexpected JSON decode error")
+	payload := decoded.Message.Payload
+	assert.True(t, strings.HasPrefix(payload.SSN, EncryptedPrefix +
+		"audit:2024:"), "Unexpected ciphertext")
+	assert.NotEqual(t, payload.SSN[ : 300], payload.Card.Number[ : 300],
+		"Unexpected data key reuse")
+
+	unwrapper := NewRSAKeyUnwrapper("audit:2024", key)
+	value, err := DecryptField("ssn", payload.SSN, unwrapper)
+	assert.NoError(t, err, "Unexpected decrypt error")
+	assert.Equal(t, "123-45-6789", value, "Unexpected decrypted value")
+	value, err = DecryptField("number", payload.Card.Number, unwrapper)
+	assert.NoError(t, err, "Unexpected decrypt error")
+	assert.Equal(t, "4111111111111111", value, "Unexpected decrypted value")
+
+	_, err = DecryptField("name", payload.SSN, unwrapper)
+	assert.Equal(t, ErrInvalidCiphertext, err, "Unexpected decrypt result")
+	_, err = DecryptField("ssn", "123-45-6789", unwrapper)
+	assert.Equal(t, ErrInvalidCiphertext, err, "Unexpected decrypt result")
+
+	assert.Equal(t, `"Hello Test!" {"ssn": "[ENCRYPTED]", "card": ` +
+		`{"number": "[ENCRYPTED]"}}`, string(message.SerializeStandard(nil)),
+		"Unexpected masked value")
+	assert.Error(t, NewFieldEncryptorOption().Validate(),
+		"Unexpected validate result")
+}
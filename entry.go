@@ -23,6 +23,7 @@
 package santa
 
 import (
+	"context"
 	"path/filepath"
 	"runtime"
 	"strconv"
@@ -95,11 +96,25 @@ func newEntrySourceLocation(p uintptr, f string, l int, ok bool) EntrySourceLoca
 }
 
 // Entry is the structure of the log entry instance.
+//
+// Hooks can change the log entry with the AddFields, ReplaceMessage,
+// OverrideLevel and AddLabel functions, which never modify the data
+// shared with the logger or its caller. Log entries are reused after they
+// have been output, so hooks must not keep references to them.
 type Entry struct {
 	// Time represents the generation time of the log entry, usually
 	// the time when the log entry is printed out.
 	Time time.Time
 
+	// TimeOverridden represents whether the time of the log entry was
+	// given by the application (for example: by the PrintsAt function of
+	// the StructLogger structure) instead of being read from the time
+	// source of the logger, usually to backfill or import log entries
+	// with their original times. Encoders and exporters preserve such
+	// times as they are, and the stages that measure elapsed time (for
+	// example: samplers) use the current time instead.
+	TimeOverridden bool
+
 	// Level represents the severity level of the log entry.
 	Level Level
 
@@ -121,4 +136,166 @@ type Entry struct {
 	// details, please refer to the annotation section of the
 	// SerializedLabels structure.
 	Labels SerializedLabels
+
+	// Context represents the context associated with the log entry by a
+	// logger derived with the WithContext function, usually the context
+	// of the request that produced the log entry.
+	//
+	// The value can be nil.
+	Context context.Context
+
+	// text is the pooled string message owned by the log entry, which is
+	// used by the ReplaceText function.
+	text *StringMessage
+
+	// labels is the pooled labels buffer owned by the log entry, which is
+	// used by the AddLabel function.
+	labels *LabelsBuffer
+}
+
+// AddFields appends the given fields to the fields of the message of the
+// log entry, and then returns true. If the message is a StringMessage, a
+// CompiledMessage, nil or a typed nil pointer, it is replaced by a
+// StructMessage with the same text and all fields. A pointer to a
+// StructMessage or a LocalizedMessage is replaced by a copy of the message
+// it points to. If the message has no fields (for example: a
+// TemplateMessage), the message is kept and false is returned.
+//
+// The fields of the message may be shared with the caller of the logger
+// (or pooled by the structured logger), so they are never modified in
+// place, and the message is replaced by a copy containing all fields
+// instead.
+func (e *Entry) AddFields(fields ...Field) bool {
+	switch message := e.Message.(type) {
+	case nil:
+		e.Message = StructMessage { Fields: appendEntryFields(nil, fields) }
+	case StringMessage:
+		e.Message = StructMessage {
+			Text: string(message),
+			Fields: appendEntryFields(nil, fields),
+		}
+	case *StringMessage:
+		var text string
+		if message != nil {
+			text = string(*message)
+		}
+		e.Message = StructMessage {
+			Text: text,
+			Fields: appendEntryFields(nil, fields),
+		}
+	case StructMessage:
+		message.Fields = appendEntryFields(message.Fields, fields)
+		e.Message = message
+	case *StructMessage:
+		var copied StructMessage
+		if message != nil {
+			copied = *message
+		}
+		copied.Fields = appendEntryFields(copied.Fields, fields)
+		e.Message = copied
+	case LocalizedMessage:
+		message.Fields = appendEntryFields(message.Fields, fields)
+		e.Message = message
+	case *LocalizedMessage:
+		if message == nil {
+			e.Message = StructMessage { Fields: appendEntryFields(nil, fields) }
+			break
+		}
+		copied := *message
+		copied.Fields = appendEntryFields(copied.Fields, fields)
+		e.Message = copied
+	case *CompiledMessage:
+		if message == nil {
+			e.Message = StructMessage { Fields: appendEntryFields(nil, fields) }
+			break
+		}
+		e.Message = StructMessage {
+			Text: message.text,
+			Fields: appendEntryFields(message.message.Fields, fields),
+		}
+	default:
+		return false
+	}
+	return true
+}
+
+// appendEntryFields returns a new slice containing the given fields
+// followed by the given other fields.
+func appendEntryFields(fields ElementObject, others []Field) ElementObject {
+	result := make(ElementObject, 0, len(fields) + len(others))
+	return append(append(result, fields...), others...)
+}
+
+// ReplaceMessage replaces the message of the log entry with the given
+// message.
+func (e *Entry) ReplaceMessage(message Message) {
+	e.Message = message
+}
+
+// ReplaceText replaces the message of the log entry with a string message
+// with the given text. The string message is taken from the pool and owned
+// by the log entry, so replacing the message of each log entry (for
+// example: by a masking hook) does not allocate memory.
+func (e *Entry) ReplaceText(text string) {
+	if e.text == nil {
+		e.text = pool.Message.String.New(text)
+	} else {
+		*e.text = StringMessage(text)
+	}
+	e.Message = e.text
+}
+
+// OverrideLevel changes the level of the log entry to the given level.
+// The exporters select the log entry by the new level, but the logger
+// has already checked its lowest level and sampled the log entry with the
+// original level.
+func (e *Entry) OverrideLevel(level Level) {
+	e.Level = level
+}
+
+// AddLabel appends a label with the given key and value to the labels of
+// the log entry. The pre-serialized labels of the logger are shared by all
+// of its log entries, so they are not modified, and the log entry uses a
+// copy of them instead. For details, please refer to the comment section
+// of the With function of the SerializedLabels structure.
+//
+// The copy is built in a labels buffer taken from the pool and owned by
+// the log entry, so adding labels to each log entry does not allocate
+// memory once the pool is warmed up.
+func (e *Entry) AddLabel(key, value string) {
+	if e.labels == nil {
+		e.labels = pool.Buffer.Labels.New()
+	}
+	e.Labels = e.labels.With(e.Labels, NewLabel(key, value))
+}
+
+// clock returns the time of the log entry in nanoseconds for measuring
+// elapsed time, which is the current time if the time of the log entry
+// was overridden. For details, please refer to the comment section of the
+// TimeOverridden field.
+func (e *Entry) clock() int64 {
+	if e.TimeOverridden {
+		return time.Now().UnixNano()
+	}
+	return e.Time.UnixNano()
+}
+
+// release returns the objects owned by the log entry to their pools, and
+// resets the state that is not set by every logger.
+func (e *Entry) release() {
+	e.TimeOverridden = false
+	if e.text != nil {
+		if e.Message == Message(e.text) {
+			e.Message = nil
+		}
+		pool.Message.String.Free(e.text)
+		e.text = nil
+	}
+	if e.labels != nil {
+		if e.labels.owns(e.Labels) {
+			e.Labels = SerializedLabels { }
+		}
+		pool.Buffer.Labels.Free(e.labels)
+		e.labels = nil
+	}
 }
@@ -53,3 +53,135 @@ func TestEntrySourceLocation(t *testing.T) {
 	assert.JSONEq(t, expected, string(buffer),
 		"Unexpected append result")
 }
+
+func TestEntryMutation(t *testing.T) {
+	labels := NewSerializedLabels(NewLabel("app", "test"))
+	fields := make(ElementObject, 1, 4)
+	fields[0] = String("name", "santa")
+	entry := &Entry {
+		Level: LevelInfo,
+		Message: StructMessage { Text: "Hello", Fields: fields },
+		Labels: labels,
+	}
+
+	assert.True(t, entry.AddFields(Int("age", 100)), "Unexpected result")
+	assert.Equal(t, StructMessage { Text: "Hello", Fields: ElementObject {
+		String("name", "santa"), Int("age", 100) } }, entry.Message,
+		"Unexpected message")
+	assert.Len(t, fields, 1, "Unexpected shared fields")
+	assert.Equal(t, ElementObject { String("name", "santa"), { } },
+		fields[ : 2], "Unexpected shared fields")
+
+	entry.AddLabel("region", "east")
+	assert.Equal(t, 2, entry.Labels.Count(), "Unexpected label count")
+	assert.Equal(t, `{"app": "test", "region": "east"}`,
+		string(entry.Labels.SerializeJSON(nil)), "Unexpected labels")
+	assert.Equal(t, `{"app": "test"}`, string(labels.SerializeJSON(nil)),
+		"Unexpected shared labels")
+
+	entry.ReplaceMessage(StringMessage("Hello"))
+	assert.True(t, entry.AddFields(Int("age", 100)), "Unexpected result")
+	assert.Equal(t, StructMessage { Text: "Hello", Fields: ElementObject {
+		Int("age", 100) } }, entry.Message, "Unexpected message")
+	pointer := &StructMessage { Text: "Hello", Fields: fields[ : 1] }
+	entry.ReplaceMessage(pointer)
+	assert.True(t, entry.AddFields(Int("age", 100)), "Unexpected result")
+	assert.Equal(t, StructMessage { Text: "Hello", Fields: ElementObject {
+		String("name", "santa"), Int("age", 100) } }, entry.Message,
+		"Unexpected message")
+	assert.Len(t, pointer.Fields, 1, "Unexpected shared fields")
+	entry.ReplaceMessage(&LocalizedMessage { Key: "hello" })
+	assert.True(t, entry.AddFields(Int("age", 100)), "Unexpected result")
+	assert.Equal(t, LocalizedMessage { Key: "hello", Fields: ElementObject {
+		Int("age", 100) } }, entry.Message, "Unexpected message")
+	entry.ReplaceMessage((*StructMessage)(nil))
+	assert.True(t, entry.AddFields(Int("age", 100)), "Unexpected result")
+	assert.Equal(t, StructMessage { Fields: ElementObject {
+		Int("age", 100) } }, entry.Message, "Unexpected message")
+	entry.ReplaceMessage(TemplateMessage { Template: "Hello" })
+	assert.False(t, entry.AddFields(Int("age", 100)), "Unexpected result")
+
+	entry.OverrideLevel(LevelError)
+	assert.Equal(t, LevelError, entry.Level, "Unexpected level")
+
+	entry.Labels = NewSerializedLabels()
+	entry.AddLabel("app", "test")
+	assert.Equal(t, `{"app": "test"}`,
+		string(entry.Labels.SerializeJSON(nil)), "Unexpected labels")
+}
+
+func TestEntryAddFieldsStructLogger(t *testing.T) {
+	writer := &testLockedWriter { }
+	added := make(chan bool, 1)
+	hook := NewSimpleHook(func(entry *Entry) error {
+		added <- entry.AddFields(String("trace", "t1"))
+		return nil
+	})
+	option := NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling().UseHooks(hook)
+	option.Outputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	assert.NoError(t, logger.Infos("Hello Test!", Int("age", 100)),
+		"Unexpected print error")
+	assert.True(t, <-added, "Unexpected result")
+	assert.Contains(t, writer.String(), `"age": 100, "trace": "t1"`,
+		"Unexpected output data")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+}
+
+func TestEntryPooling(t *testing.T) {
+	labels := NewSerializedLabels(NewLabel("app", "test"))
+	entry := pool.Entry.New()
+	entry.Labels = labels
+	entry.AddLabel("region", "east")
+	entry.AddLabel("zone", "a")
+	entry.ReplaceText("Hello")
+	assert.Equal(t, `{"app": "test", "region": "east", "zone": "a"}`,
+		string(entry.Labels.SerializeJSON(nil)), "Unexpected labels")
+	assert.Equal(t, `"Hello"`, string(entry.Message.(JSONSerializer).
+		SerializeJSON(nil)), "Unexpected message")
+	assert.True(t, entry.AddFields(Int("age", 100)), "Unexpected result")
+	assert.Equal(t, StructMessage { Text: "Hello", Fields: ElementObject {
+		Int("age", 100) } }, entry.Message, "Unexpected message")
+
+	retained := retainEntry(entry)
+	pool.Entry.Free(entry)
+	assert.Equal(t, `{"app": "test", "region": "east", "zone": "a"}`,
+		string(retained.Labels.SerializeJSON(nil)), "Unexpected labels")
+	assert.Equal(t, `{"app": "test"}`, string(labels.SerializeJSON(nil)),
+		"Unexpected shared labels")
+
+	if raceEnabled {
+		t.Skip("Skipping allocation test with the race detector")
+	}
+	allocs := testing.AllocsPerRun(100, func() {
+		entry := pool.Entry.New()
+		entry.Labels = labels
+		entry.AddLabel("region", "east")
+		entry.ReplaceText("Hello")
+		pool.Entry.Free(entry)
+	})
+	assert.Zero(t, allocs, "Unexpected allocations")
+}
+
+func BenchmarkEntryAddLabel(b *testing.B) {
+	labels := NewSerializedLabels(NewLabel("app", "test"))
+	b.ReportAllocs()
+	for index := 0; index < b.N; index++ {
+		entry := pool.Entry.New()
+		entry.Labels = labels
+		entry.AddLabel("region", "east")
+		pool.Entry.Free(entry)
+	}
+}
+
+func BenchmarkEntryReplaceText(b *testing.B) {
+	b.ReportAllocs()
+	for index := 0; index < b.N; index++ {
+		entry := pool.Entry.New()
+		entry.ReplaceText("Hello")
+		pool.Entry.Free(entry)
+	}
+}
@@ -0,0 +1,160 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"strings"
+)
+
+// MultiError is a data type that contains one or more errors.
+//
+// Some operations try to complete every step even if one or more steps
+// fail, such as closing all exporters of a logger. These operations use
+// MultiError to return all the errors encountered, instead of stopping
+// at the first error and leaving the remaining steps incomplete.
+//
+// The errors.Is and errors.As functions check each contained error in
+// turn, so applications can still match the sentinel errors defined by
+// this package.
+type MultiError []error
+
+// Error returns the description string of all contained errors, and the
+// descriptions are separated by semicolons.
+func (e MultiError) Error() string {
+	var builder strings.Builder
+	for index := 0; index < len(e); index++ {
+		if index > 0 {
+			builder.WriteString("; ")
+		}
+		builder.WriteString(e[index].Error())
+	}
+	return builder.String()
+}
+
+// Unwrap returns all contained errors.
+func (e MultiError) Unwrap() []error {
+	return e
+}
+
+// Is checks whether any contained error matches the given target error.
+func (e MultiError) Is(target error) bool {
+	for index := 0; index < len(e); index++ {
+		if errors.Is(e[index], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first contained error that matches the given target, and
+// if found, sets the target to that error value and returns true.
+func (e MultiError) As(target interface { }) bool {
+	for index := 0; index < len(e); index++ {
+		if errors.As(e[index], target) {
+			return true
+		}
+	}
+	return false
+}
+
+// Append appends the given error to the multi-error and returns the
+// appended multi-error. If the given error is nil, the multi-error is
+// returned unchanged. If the given error is a multi-error, its errors are
+// appended instead, so that the multi-error is never nested.
+func (e MultiError) Append(err error) MultiError {
+	if err == nil {
+		return e
+	}
+	if errs, ok := err.(MultiError); ok {
+		return append(e, errs...)
+	}
+	return append(e, err)
+}
+
+// ErrorOrNil returns nil if the multi-error does not contain any error,
+// otherwise it returns the multi-error itself.
+func (e MultiError) ErrorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+var (
+	// ErrInvalidOption represents that the value of an option is invalid.
+	// This is usually because the value of the given option does not meet
+	// the constraints described in the comment section of the option.
+	ErrInvalidOption = errors.New("invalid option")
+)
+
+// OptionError is a structure that contains an error of an invalid option
+// value, and identifies which option is invalid.
+//
+// The Validate function of each option type returns an OptionError to
+// describe the first invalid option value found.
+type OptionError struct {
+	// Option represents the path name of the invalid option, such as
+	// "Outputting.Option.CacheCapacity".
+	Option string
+
+	// Reason represents the description of why the option value is
+	// invalid.
+	Reason string
+
+	// Err represents the underlying error of the invalid option value. If
+	// not provided, the default value is ErrInvalidOption.
+	Err error
+}
+
+// Error returns the description string of the error.
+func (e *OptionError) Error() string {
+	return "invalid option " + e.Option + ": " + e.Reason
+}
+
+// Unwrap returns the underlying error of the invalid option value.
+func (e *OptionError) Unwrap() error {
+	if e.Err == nil {
+		return ErrInvalidOption
+	}
+	return e.Err
+}
+
+// newOptionError creates and returns an OptionError with the given option
+// name, reason and underlying error.
+func newOptionError(option, reason string, err error) *OptionError {
+	return &OptionError {
+		Option: option,
+		Reason: reason,
+		Err: err,
+	}
+}
+
+// prefixOptionError adds the given prefix to the option path name of the
+// given error if it is an OptionError, and then returns the error.
+func prefixOptionError(prefix string, err error) error {
+	if e, ok := err.(*OptionError); ok {
+		e.Option = prefix + "." + e.Option
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMultiError(t *testing.T) {
+	var errs MultiError
+
+	assert.NoError(t, errs.ErrorOrNil(), "Unexpected return value")
+
+	errs = errs.Append(nil)
+	assert.Len(t, errs, 0, "Unexpected append result")
+
+	errs = errs.Append(ErrClosed)
+	errs = errs.Append(errors.New("Error"))
+	assert.Len(t, errs, 2, "Unexpected append result")
+
+	err := errs.ErrorOrNil()
+	assert.Error(t, err, "Unexpected return value")
+	assert.Equal(t, "instance has been closed; Error", err.Error(),
+		"Unexpected error string")
+
+	assert.True(t, errors.Is(err, ErrClosed), "Unexpected match result")
+	assert.False(t, errors.Is(err, ErrInvalidType),
+		"Unexpected match result")
+
+	errs = MultiError { ErrInvalidType }.Append(err)
+	assert.Len(t, errs, 3, "Unexpected append result")
+	assert.Equal(t, ErrClosed, errs[1], "Unexpected append result")
+}
@@ -0,0 +1,241 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"time"
+)
+
+// Event is the structure of a structured event instance.
+//
+// The structured event is a fluent alternative to the Prints function of
+// the structured logger, which adds the fields of a structured log message
+// one by one and then outputs it through the same pipeline, for example:
+//
+//   logger.Event(LevelInfo).Str("address", "1.1.1.1").Int("port", 54321).
+//       Msg("Connection accepted.")
+//
+// Events are taken from a pool, and returned to it after they are output,
+// so an event must not be used after its Msg, Msgf or Send function is
+// called. If the level of an event is not enabled, the Event function
+// returns nil, and all functions of a nil event do nothing, so that the
+// fields of disabled log entries cost nothing.
+//
+// The API provided by the event is not thread-safe.
+type Event struct {
+	logger *StructLogger
+	level Level
+	fields []Field
+}
+
+// Event creates and returns a structured event with the given level. If the
+// given level is not enabled, it returns nil. For details, please refer to
+// the comment section of the Event structure.
+func (l *StructLogger) Event(level Level) *Event {
+	if !l.Level().Enabled(level) {
+		return nil
+	}
+	event := pool.Event.New()
+	event.logger = l
+	event.level = level
+	return event
+}
+
+// Enabled returns true if the event is output, otherwise it returns false.
+func (e *Event) Enabled() bool {
+	return e != nil
+}
+
+// Fields adds the given fields to the event, and then returns the event
+// itself.
+func (e *Event) Fields(fields ...Field) *Event {
+	if e != nil {
+		e.fields = append(e.fields, fields...)
+	}
+	return e
+}
+
+// Str adds a field with the given name and string value to the event, and
+// then returns the event itself.
+func (e *Event) Str(name, value string) *Event {
+	if e != nil {
+		e.fields = append(e.fields, String(name, value))
+	}
+	return e
+}
+
+// Strs adds a field with the given name and string slice value to the
+// event, and then returns the event itself.
+func (e *Event) Strs(name string, values []string) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Strings(name, values))
+	}
+	return e
+}
+
+// Int adds a field with the given name and integer value to the event, and
+// then returns the event itself.
+func (e *Event) Int(name string, value int) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Int(name, int64(value)))
+	}
+	return e
+}
+
+// Int64 adds a field with the given name and 64-bit integer value to the
+// event, and then returns the event itself.
+func (e *Event) Int64(name string, value int64) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Int(name, value))
+	}
+	return e
+}
+
+// Uint64 adds a field with the given name and 64-bit unsigned integer value
+// to the event, and then returns the event itself.
+func (e *Event) Uint64(name string, value uint64) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Uint(name, value))
+	}
+	return e
+}
+
+// Float64 adds a field with the given name and 64-bit floating point value
+// to the event, and then returns the event itself.
+func (e *Event) Float64(name string, value float64) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Float64(name, value))
+	}
+	return e
+}
+
+// Bool adds a field with the given name and boolean value to the event,
+// and then returns the event itself.
+func (e *Event) Bool(name string, value bool) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Boolean(name, value))
+	}
+	return e
+}
+
+// Dur adds a field with the given name and duration value to the event,
+// and then returns the event itself.
+func (e *Event) Dur(name string, value time.Duration) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Duration(name, value))
+	}
+	return e
+}
+
+// Time adds a field with the given name and time value to the event, and
+// then returns the event itself.
+func (e *Event) Time(name string, value time.Time) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Time(name, value))
+	}
+	return e
+}
+
+// Bytes adds a field with the given name and []byte value to the event,
+// and then returns the event itself.
+func (e *Event) Bytes(name string, value []byte) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Bytes(name, value))
+	}
+	return e
+}
+
+// Err adds a field named "error" with the given error value to the event,
+// and then returns the event itself. If the given error is nil, no field
+// is added.
+func (e *Event) Err(err error) *Event {
+	return e.AnErr("error", err)
+}
+
+// AnErr adds a field with the given name and error value to the event, and
+// then returns the event itself. If the given error is nil, no field is
+// added.
+func (e *Event) AnErr(name string, err error) *Event {
+	if e != nil && err != nil {
+		e.fields = append(e.fields, Error(name, err))
+	}
+	return e
+}
+
+// Any adds a field with the given name and value of any type to the event,
+// and then returns the event itself. For details, please refer to the
+// comment section of the Value function.
+func (e *Event) Any(name string, value interface { }) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Value(name, value))
+	}
+	return e
+}
+
+// Object adds a nested object field with the given name and fields to the
+// event, and then returns the event itself.
+func (e *Event) Object(name string, fields ...Field) *Event {
+	if e != nil {
+		e.fields = append(e.fields, Object(name, fields...))
+	}
+	return e
+}
+
+// output outputs the event with the given description text, returns the
+// event to the pool, and then returns any errors encountered.
+func (e *Event) output(text string) error {
+	err := e.logger.output(e.logger.ctx, 4, e.level, text, e.fields, false)
+	pool.Event.Free(e)
+	return err
+}
+
+// Msg outputs the event as a structured log message with the given
+// description text, and then returns any errors encountered. After it is
+// called, the event is not allowed to be used again.
+func (e *Event) Msg(text string) error {
+	if e == nil {
+		return nil
+	}
+	return e.output(text)
+}
+
+// Msgf outputs the event as a structured log message whose description
+// text is the given template formatted with the given arguments (see the
+// TemplateMessage structure), and then returns any errors encountered.
+// After it is called, the event is not allowed to be used again.
+func (e *Event) Msgf(template string, args ...interface { }) error {
+	if e == nil {
+		return nil
+	}
+	return e.output(string(appendTemplate(nil, template, args)))
+}
+
+// Send outputs the event as a structured log message without description
+// text, and then returns any errors encountered. After it is called, the
+// event is not allowed to be used again.
+func (e *Event) Send() error {
+	if e == nil {
+		return nil
+	}
+	return e.output("")
+}
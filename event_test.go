@@ -0,0 +1,74 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructLoggerEvent(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling().UseLevel(LevelInfo)
+	option.Outputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	err = logger.Event(LevelInfo).Str("name", "santa").Int("age", 100).
+		Bool("admin", true).Dur("elapsed", time.Second).Err(nil).
+		AnErr("cause", errors.New("boom")).Object("geo",
+		String("country", "NZ")).Msg("Hello Test!")
+	assert.NoError(t, err, "Unexpected print error")
+	assert.Contains(t, writer.String(), `Hello Test!`, "Unexpected output")
+	assert.Contains(t, writer.String(), `"name": "santa", "age": 100, ` +
+		`"admin": true, "elapsed": 1000000000, "cause": "boom", ` +
+		`"geo": {"country": "NZ"}`, "Unexpected output data")
+	assert.Contains(t, writer.String(), "event_test.go",
+		"Unexpected source location")
+
+	derived := logger.With(String("request", "r1"))
+	assert.NoError(t, derived.Event(LevelWarning).Uint64("count", 3).
+		Msgf("Retry %d", 2), "Unexpected print error")
+	assert.Contains(t, writer.String(), `Retry 2`, "Unexpected output")
+	assert.Contains(t, writer.String(), `"request": "r1", "count": 3`,
+		"Unexpected output data")
+	assert.NoError(t, derived.Release(), "Unexpected release error")
+
+	disabled := logger.Event(LevelDebug)
+	assert.Nil(t, disabled, "Unexpected disabled event")
+	assert.False(t, disabled.Enabled(), "Unexpected disabled event")
+	assert.NoError(t, disabled.Str("name", "santa").Msg("Hidden"),
+		"Unexpected print error")
+	assert.NotContains(t, writer.String(), "Hidden", "Unexpected output")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = logger.Event(LevelDebug).Str("name", "santa").Int("age", 100).
+			Msg("Hidden")
+	})
+	assert.Zero(t, allocs, "Unexpected allocations")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+}
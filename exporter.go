@@ -22,6 +22,89 @@
 
 package santa
 
+import (
+	"encoding/binary"
+	"errors"
+	"path"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// RecordFraming represents how the encoded log entries are delimited when
+// they are written to a synchronizer. Its optional options are constants
+// starting with Framing...
+type RecordFraming int
+
+const (
+	// FramingNewline represents that each encoded log entry is terminated
+	// by a line feed ("\n"), as produced by the encoders. This is the
+	// default.
+	FramingNewline RecordFraming = iota
+
+	// FramingCRLF represents that each encoded log entry is terminated by
+	// a carriage return and a line feed ("\r\n").
+	FramingCRLF
+
+	// FramingJSONSequence represents that each encoded log entry is
+	// prefixed by a record separator ("\x1e") and terminated by a line
+	// feed, which is the JSON text sequence format of RFC 7464.
+	FramingJSONSequence
+
+	// FramingNone represents that the encoded log entries are not
+	// delimited.
+	FramingNone
+
+	// FramingLengthPrefixed represents that each encoded log entry is not
+	// terminated, but prefixed by its length in bytes as a 4-byte big
+	// endian unsigned integer.
+	FramingLengthPrefixed
+)
+
+// prefix appends the prefix of a record with the framing to the given
+// buffer slice, and then returns the appended buffer slice.
+func (f RecordFraming) prefix(buffer []byte) []byte {
+	switch f {
+	case FramingJSONSequence:
+		return append(buffer, 0x1e)
+	case FramingLengthPrefixed:
+		return append(buffer, 0, 0, 0, 0)
+	}
+	return buffer
+}
+
+// frame replaces the line feed terminating the given encoded record, which
+// starts at the given offset of the given buffer slice, with the delimiter
+// of the framing, and then returns the framed buffer slice.
+func (f RecordFraming) frame(buffer []byte, offset int) []byte {
+	if f == FramingNewline {
+		return buffer
+	}
+	if length := len(buffer); length > offset && buffer[length - 1] == '\n' {
+		buffer = buffer[ : length - 1]
+	}
+	switch f {
+	case FramingCRLF:
+		return append(buffer, '\r', '\n')
+	case FramingJSONSequence:
+		return append(buffer, '\n')
+	case FramingLengthPrefixed:
+		binary.BigEndian.PutUint32(buffer[offset - 4 : offset],
+			uint32(len(buffer) - offset))
+	}
+	return buffer
+}
+
+// validateFraming returns an OptionError if the given framing is not
+// supported, otherwise it returns nil.
+func validateFraming(framing RecordFraming) error {
+	if framing < FramingNewline || framing > FramingLengthPrefixed {
+		return newOptionError("Framing", "unsupported framing " +
+			strconv.Itoa(int(framing)), ErrInvalidType)
+	}
+	return nil
+}
+
 // Exporter is a public interface for exporters.
 //
 // The exporter uses a specific encoder to encode log entries into
@@ -65,6 +148,262 @@ type Exporter interface {
 	Close() error
 }
 
+var (
+	// ErrCyclicDependency represents that the dependencies declared by
+	// one or more exporters form a cycle, so there is no valid order to
+	// close them.
+	ErrCyclicDependency = errors.New("cyclic exporter dependency")
+)
+
+// DependentExporter is the public interface of exporters that depend on
+// other exporters.
+//
+// Some exporters must be closed before other exporters, for example, an
+// exporter that buffers log entries and hands them to another exporter
+// must flush its buffer before the other exporter closes its network
+// synchronizer. By implementing this interface, an exporter declares the
+// exporters it depends on, and the logger closes the exporter before any
+// of its dependencies.
+//
+// Dependencies that are not exporters of the same logger are ignored.
+type DependentExporter interface {
+	Exporter
+
+	// Dependencies returns one or more exporters that the exporter depends
+	// on. These exporters will be closed after the exporter is closed.
+	Dependencies() []Exporter
+}
+
+// MatchingExporter is the public interface of exporters that export only
+// the log entries that match their conditions (for example: level spans,
+// names or label selectors).
+//
+// By implementing this interface, an exporter reports whether it would
+// export a log entry without exporting it, so that the log entries it
+// ignores can be told apart from the log entries it exports. For details,
+// please refer to the comment section of the TracePipeline function of the
+// StandardLogger structure.
+type MatchingExporter interface {
+	Exporter
+
+	// Match checks whether the given log entry matches the conditions of
+	// the exporter, in which case the exporter exports it.
+	Match(entry *Entry) bool
+}
+
+// ExporterError is a structure that contains an error encountered by an
+// exporter of a logger, and identifies which exporter failed.
+type ExporterError struct {
+	// Index represents the index of the exporter in the exporters of the
+	// logger, starting from 0.
+	Index int
+
+	// Exporter represents the exporter instance that failed.
+	Exporter Exporter
+
+	// Err represents the error encountered by the exporter.
+	Err error
+}
+
+// Error returns the description string of the error, which contains the
+// index of the exporter that failed.
+func (e *ExporterError) Error() string {
+	return "exporter " + strconv.Itoa(e.Index) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error encountered by the exporter.
+func (e *ExporterError) Unwrap() error {
+	return e.Err
+}
+
+// newExporterError returns an ExporterError for the exporter at the given
+// index if the given error is not nil, otherwise it returns nil.
+func newExporterError(exporters []Exporter, index int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExporterError {
+		Index: index,
+		Exporter: exporters[index],
+		Err: err,
+	}
+}
+
+// syncExporters synchronizes all the given exporters. Every exporter is
+// synchronized even if synchronizing one or more exporters fails. Finally,
+// all errors encountered are returned as a MultiError, or nil if no error
+// is encountered.
+func syncExporters(exporters []Exporter) error {
+	var errs MultiError
+	for index := 0; index < len(exporters); index++ {
+		errs = errs.Append(newExporterError(exporters, index,
+			exporters[index].Sync()))
+	}
+	return errs.ErrorOrNil()
+}
+
+// SyncResult is a structure that contains the result of synchronizing an
+// exporter of a logger. For details, please refer to the comment section
+// of the SyncDetailed function of the StandardLogger structure.
+type SyncResult struct {
+	// Index represents the index of the exporter in the exporters of the
+	// logger, starting from 0.
+	Index int
+
+	// Exporter represents the exporter instance that was synchronized.
+	Exporter Exporter
+
+	// Bytes represents the number of bytes of internally cached data that
+	// were written to a specific storage device by the synchronization. If
+	// the size of the internal cache of the exporter is unknown, the value
+	// is -1.
+	Bytes int
+
+	// Duration represents the duration of the synchronization.
+	Duration time.Duration
+
+	// Err represents the error encountered by the synchronization, or nil
+	// if the log entry data of the exporter is durable.
+	Err error
+}
+
+// exporterBuffered returns the number of bytes of the internal cache of
+// the given exporter, or -1 if it is unknown.
+func exporterBuffered(exporter Exporter) int {
+	if buffered, ok := exporter.(BufferedSyncer); ok {
+		return buffered.Buffered()
+	}
+	if standard, ok := exporter.(*StandardExporter); ok {
+		if buffered, ok := standard.Syncer().(BufferedSyncer); ok {
+			return buffered.Buffered()
+		}
+	}
+	return -1
+}
+
+// syncExportersDetailed synchronizes all the given exporters, and then
+// returns the result of each exporter. Every exporter is synchronized
+// even if synchronizing one or more exporters fails.
+func syncExportersDetailed(exporters []Exporter) []SyncResult {
+	results := make([]SyncResult, len(exporters))
+	for index := 0; index < len(exporters); index++ {
+		before := exporterBuffered(exporters[index])
+		start := time.Now()
+		err := exporters[index].Sync()
+		result := &results[index]
+		result.Index = index
+		result.Exporter = exporters[index]
+		result.Duration = time.Since(start)
+		result.Err = newExporterError(exporters, index, err)
+		result.Bytes = before
+		if err != nil && before > 0 {
+			// Only the part of the internal cache that is no longer
+			// cached has been written.
+			result.Bytes = before - exporterBuffered(exporters[index])
+			if result.Bytes < 0 {
+				result.Bytes = 0
+			}
+		}
+	}
+	return results
+}
+
+// flushExporters flushes all the given exporters. Exporters that implement
+// the Flusher interface are flushed, and the others are synchronized. Every
+// exporter is flushed even if flushing one or more exporters fails.
+// Finally, all errors encountered are returned as a MultiError, or nil if
+// no error is encountered.
+func flushExporters(exporters []Exporter) error {
+	var errs MultiError
+	for index := 0; index < len(exporters); index++ {
+		var err error
+		if flusher, ok := exporters[index].(Flusher); ok {
+			err = flusher.Flush()
+		} else {
+			err = exporters[index].Sync()
+		}
+		errs = errs.Append(newExporterError(exporters, index, err))
+	}
+	return errs.ErrorOrNil()
+}
+
+// sameExporter checks whether the given exporters are the same exporter.
+// Exporters of types that are not comparable are never the same, instead
+// of panicking when they are compared.
+func sameExporter(a, b Exporter) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	dynamic := reflect.TypeOf(a)
+	return dynamic == reflect.TypeOf(b) && dynamic.Comparable() && a == b
+}
+
+// closeExporters closes the given exporters in dependency order, which
+// means that each exporter is closed before the exporters it depends on.
+// Exporters without dependency constraints are closed in the given order.
+// Dependencies on exporters of types that are not comparable cannot be
+// identified, so they are ignored.
+//
+// Every exporter is closed even if closing one or more exporters fails.
+// If the dependencies form a cycle, the remaining exporters are closed in
+// the given order. Finally, all errors encountered are returned as a
+// MultiError, or nil if no error is encountered. Each error of an exporter
+// is wrapped by ExporterError.
+func closeExporters(exporters []Exporter) error {
+	var errs MultiError
+	closed := make([]bool, len(exporters))
+
+	// required checks whether any exporter that has not been closed
+	// depends on the exporter at the given index.
+	required := func(target int) bool {
+		for index := 0; index < len(exporters); index++ {
+			if closed[index] || index == target {
+				continue
+			}
+			dependent, ok := exporters[index].(DependentExporter)
+			if !ok {
+				continue
+			}
+			dependencies := dependent.Dependencies()
+			for offset := 0; offset < len(dependencies); offset++ {
+				if sameExporter(dependencies[offset], exporters[target]) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	for remaining := len(exporters); remaining > 0; remaining-- {
+		next := -1
+		for index := 0; index < len(exporters); index++ {
+			if !closed[index] && !required(index) {
+				next = index
+				break
+			}
+		}
+		if next < 0 {
+			// There is no exporter that can be closed safely, so the
+			// dependencies form a cycle. Close the remaining exporters
+			// in the given order to avoid leaking them.
+			errs = errs.Append(ErrCyclicDependency)
+			for index := 0; index < len(exporters); index++ {
+				if !closed[index] {
+					closed[index] = true
+					errs = errs.Append(newExporterError(exporters,
+						index, exporters[index].Close()))
+				}
+			}
+			break
+		}
+		closed[next] = true
+		errs = errs.Append(newExporterError(exporters, next,
+			exporters[next].Close()))
+	}
+
+	return errs.ErrorOrNil()
+}
+
 // StandardExporter is the structure of the standard exporter instance.
 // 
 // The standard exporter checks whether the level of each log entry is
@@ -74,24 +413,59 @@ type Exporter interface {
 // storage device.
 type StandardExporter struct {
 	span LevelSpan
+	names []string
+	selector LabelSelector
+	filter *Filter
 	encoder Encoder
 	syncer Syncer
+	framing RecordFraming
+}
+
+// matchName returns true if the given log entry name matches any of the
+// name patterns of the exporter, otherwise it returns false.
+func (e *StandardExporter) matchName(name string) bool {
+	for index := 0; index < len(e.names); index++ {
+		// The patterns have been checked when the exporter was built, so
+		// no error can occur.
+		if ok, _ := path.Match(e.names[index], name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Match checks whether the given log entry matches the level span, the
+// names, the label selector and the filter of the exporter.
+func (e *StandardExporter) Match(entry *Entry) bool {
+	if !e.span.Contains(entry.Level) {
+		return false
+	}
+	if len(e.names) > 0 && !e.matchName(entry.Name) {
+		return false
+	}
+	if len(e.selector) > 0 && !e.selector.Matches(entry.Labels) {
+		return false
+	}
+	return e.filter == nil || e.filter.Match(entry)
 }
 
 // Export encodes a given log entry into specific data using a specific
 // encoder, then uses a specific synchronizer to write the encoded log
-// entry data to a specific storage device.
+// entry data to a specific storage device. If the synchronizer implements
+// the LevelSyncer interface, it is then notified of the log entry level.
 //
 // Finally, any errors encountered are returned.
 func (e *StandardExporter) Export(entry *Entry) error {
-	if !e.span.Contains(entry.Level) {
+	if !e.Match(entry) {
 		return nil
 	}
 	if e.encoder == nil {
 		return nil
 	}
 	pointer := pool.Buffer.Exporter.New()
-	buffer, err := e.encoder.Encode((*pointer)[ : 0], entry)
+	buffer := e.framing.prefix((*pointer)[ : 0])
+	offset := len(buffer)
+	buffer, err := e.encoder.Encode(buffer, entry)
 	if err != nil {
 		pool.Buffer.Exporter.Free(pointer)
 		return err
@@ -104,9 +478,16 @@ func (e *StandardExporter) Export(entry *Entry) error {
 		pool.Buffer.Exporter.Free(pointer)
 		return nil
 	}
+	buffer = e.framing.frame(buffer, offset)
 	_, err = e.syncer.Write(buffer)
 	pool.Buffer.Exporter.Free(pointer)
-	return err
+	if err != nil {
+		return err
+	}
+	if syncer, ok := e.syncer.(LevelSyncer); ok {
+		return syncer.SyncLevel(entry.Level)
+	}
+	return nil
 }
 
 // Sync writes the internal cache data of a specific synchronizer to a
@@ -120,6 +501,34 @@ func (e *StandardExporter) Sync() error {
 	return e.syncer.Sync()
 }
 
+// Flush writes the internal cache data of a specific synchronizer to a
+// specific storage device, without writing the data cached by the file
+// system to the persistent storage device. If the synchronizer does not
+// implement the Flusher interface, it is synchronized instead.
+//
+// Finally, any errors encountered are returned.
+func (e *StandardExporter) Flush() error {
+	if flusher, ok := e.syncer.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return e.syncer.Sync()
+}
+
+// Syncer returns the synchronizer used by the exporter.
+func (e *StandardExporter) Syncer() Syncer {
+	return e.syncer
+}
+
+// WithSyncer returns a copy of the exporter that writes the encoded log
+// entries to the given synchronizer instead, so that exporters of other
+// packages can wrap the synchronizer of a standard exporter (for example:
+// to compress the encoded log entries).
+func (e *StandardExporter) WithSyncer(syncer Syncer) *StandardExporter {
+	copied := *e
+	copied.syncer = syncer
+	return &copied
+}
+
 // Close close a specific synchronizer. For details, please participate
 // in the Close function of the Syncer interface.
 //
@@ -144,6 +553,43 @@ type StandardExporterOption struct {
 	// data to a specific storage device. If not provided, the default
 	// value is the standard synchronizer.
 	Syncer Syncer
+
+	// LabelSelector represents the label selector that the labels of a
+	// log entry must match, in addition to the log level span, for the
+	// log entry to be processed. For details, please refer to the comment
+	// section of the ParseLabelSelector function. If not provided, log
+	// entries are not selected by their labels.
+	LabelSelector string
+
+	// NamePatterns represents the glob patterns (see the path.Match
+	// function), one of which the name of a log entry must match, in
+	// addition to the log level span, for the log entry to be processed.
+	// For example, "db.*" matches the log entries of the "db.pool" and
+	// "db.query" loggers, so subsystem log entries can be routed to a
+	// dedicated storage device. If not provided, log entries are not
+	// selected by their names.
+	NamePatterns []string
+
+	// Filter represents the filter expression that a log entry must
+	// match, in addition to the log level span, for the log entry to be
+	// processed. The filter expression is compiled when the exporter is
+	// built. For details, please refer to the comment section of the
+	// CompileFilter function. If not provided, log entries are not
+	// filtered.
+	Filter string
+
+	// Framing represents how the encoded log entries are delimited when
+	// they are written to the synchronizer, and its optional options are
+	// constants starting with Framing... If not provided, the default
+	// value is the FramingNewline constant.
+	Framing RecordFraming
+}
+
+// UseFraming uses the given record framing as the value of the Framing
+// option. Then return to the option instance itself.
+func (o *StandardExporterOption) UseFraming(framing RecordFraming) *StandardExporterOption {
+	o.Framing = framing
+	return o
 }
 
 // UseSpan uses the given start and end log levels as the value of the
@@ -173,12 +619,62 @@ func (o *StandardExporterOption) UseSyncer(syncer Syncer) *StandardExporterOptio
 	return o
 }
 
-// Build builds and returns a standard exporter instance.
+// UseLabelSelector uses the given label selector as the value of the
+// LabelSelector option. For details, please refer to the comment section
+// of the LabelSelector option. Then return to the option instance itself.
+func (o *StandardExporterOption) UseLabelSelector(selector string) *StandardExporterOption {
+	o.LabelSelector = selector
+	return o
+}
+
+// UseNamePatterns uses the given glob patterns as the value of the
+// NamePatterns option. For details, please refer to the comment section
+// of the NamePatterns option. Then return to the option instance itself.
+func (o *StandardExporterOption) UseNamePatterns(patterns ...string) *StandardExporterOption {
+	o.NamePatterns = patterns
+	return o
+}
+
+// UseFilter uses the given filter expression as the value of the Filter
+// option. For details, please refer to the comment section of the Filter
+// option. Then return to the option instance itself.
+func (o *StandardExporterOption) UseFilter(expression string) *StandardExporterOption {
+	o.Filter = expression
+	return o
+}
+
+// Build builds and returns a standard exporter instance. If the value of
+// the LabelSelector, NamePatterns or Filter option cannot be parsed, or
+// the value of the Framing option is not supported, an OptionError is
+// returned.
 func (o *StandardExporterOption) Build() (*StandardExporter, error) {
+	if err := validateFraming(o.Framing); err != nil {
+		return nil, err
+	}
+	selector, err := ParseLabelSelector(o.LabelSelector)
+	if err != nil {
+		return nil, newOptionError("LabelSelector", "invalid selector", err)
+	}
+	for index, pattern := range o.NamePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, newOptionError("NamePatterns[" +
+				strconv.Itoa(index) + "]", "invalid pattern", err)
+		}
+	}
+	var filter *Filter
+	if len(o.Filter) > 0 {
+		if filter, err = CompileFilter(o.Filter); err != nil {
+			return nil, newOptionError("Filter", "invalid filter", err)
+		}
+	}
 	return &StandardExporter {
 		span: o.Span,
+		names: append([]string(nil), o.NamePatterns...),
+		selector: selector,
+		filter: filter,
 		encoder: o.Encoder,
 		syncer: o.Syncer,
+		framing: o.Framing,
 	}, nil
 }
 
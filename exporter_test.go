@@ -23,6 +23,8 @@
 package santa
 
 import (
+	"errors"
+	"path"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -78,3 +80,237 @@ func TestStandardExporterOption(t *testing.T) {
 	assert.Equal(t, span, exporter.span,
 		"Unexpected instance error")
 }
+
+type testDependentExporter struct {
+	testExporter
+
+	name string
+	closed *[]string
+	dependencies []Exporter
+	err error
+}
+
+func (e *testDependentExporter) Close() error {
+	*e.closed = append(*e.closed, e.name)
+	return e.err
+}
+
+func (e *testDependentExporter) Dependencies() []Exporter {
+	return e.dependencies
+}
+
+type testUncomparableExporter struct {
+	*testDependentExporter
+
+	labels []string
+}
+
+func TestCloseExporters(t *testing.T) {
+	var closed []string
+
+	network := &testDependentExporter {
+		name: "network",
+		closed: &closed,
+		err: errors.New("Network error"),
+	}
+	file := &testDependentExporter {
+		name: "file",
+		closed: &closed,
+	}
+	buffered := &testDependentExporter {
+		name: "buffered",
+		closed: &closed,
+		dependencies: []Exporter {
+			network,
+		},
+		err: errors.New("Buffered error"),
+	}
+
+	err := closeExporters([]Exporter {
+		network,
+		file,
+		buffered,
+	})
+
+	assert.Equal(t, []string { "file", "buffered", "network" }, closed,
+		"Unexpected close order")
+	assert.Len(t, err, 2, "Unexpected close error")
+	assert.Equal(t, "exporter 2: Buffered error; exporter 0: Network error",
+		err.Error(), "Unexpected close error")
+
+	var exporterErr *ExporterError
+	assert.True(t, errors.As(err, &exporterErr), "Unexpected close error")
+	assert.Equal(t, buffered, exporterErr.Exporter, "Unexpected close error")
+
+	closed = nil
+	network.err = nil
+	network.dependencies = []Exporter {
+		buffered,
+	}
+	buffered.err = nil
+
+	err = closeExporters([]Exporter {
+		network,
+		file,
+		buffered,
+	})
+
+	assert.Equal(t, []string { "file", "network", "buffered" }, closed,
+		"Unexpected close order")
+	assert.True(t, errors.Is(err, ErrCyclicDependency),
+		"Unexpected close error")
+
+	closed = nil
+	first := testUncomparableExporter {
+		testDependentExporter: &testDependentExporter {
+			name: "first",
+			closed: &closed,
+		},
+	}
+	second := testUncomparableExporter {
+		testDependentExporter: &testDependentExporter {
+			name: "second",
+			closed: &closed,
+			dependencies: []Exporter {
+				first,
+			},
+		},
+	}
+
+	assert.NotPanics(t, func() {
+		err = closeExporters([]Exporter {
+			first,
+			second,
+		})
+	}, "Unexpected close panic")
+	assert.NoError(t, err, "Unexpected close error")
+	assert.Equal(t, []string { "first", "second" }, closed,
+		"Unexpected close order")
+}
+
+type testFailedExporter struct {
+	testExporter
+
+	synced bool
+	err error
+}
+
+func (e *testFailedExporter) Sync() error {
+	e.synced = true
+	return e.err
+}
+
+func TestSyncExporters(t *testing.T) {
+	first := &testFailedExporter {
+		err: errors.New("Sync error"),
+	}
+	second := &testFailedExporter { }
+
+	err := syncExporters([]Exporter {
+		first,
+		second,
+	})
+
+	assert.True(t, first.synced, "Exporter is not synchronized")
+	assert.True(t, second.synced, "Exporter is not synchronized")
+	assert.Equal(t, "exporter 0: Sync error", err.Error(),
+		"Unexpected sync error")
+
+	var exporterErr *ExporterError
+	assert.True(t, errors.As(err, &exporterErr), "Unexpected sync error")
+	assert.Equal(t, 0, exporterErr.Index, "Unexpected sync error")
+
+	err = syncExporters([]Exporter {
+		second,
+	})
+	assert.NoError(t, err, "Unexpected sync error")
+}
+
+func TestStandardExporterLabelSelector(t *testing.T) {
+	writer := &testLockedWriter { }
+	syncer, err := NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	exporter, err := NewStandardExporterOption().UseSyncer(syncer).
+		UseLabelSelector("env=prod,team!=infra").Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	for _, labels := range []Labels {
+		{ NewLabel("env", "prod"), NewLabel("team", "web") },
+		{ NewLabel("env", "prod"), NewLabel("team", "infra") },
+		{ NewLabel("env", "dev") },
+	} {
+		entry := &Entry {
+			Level: LevelInfo,
+			Message: StringMessage(labels.String()),
+			Labels: NewSerializedLabels(labels...),
+		}
+		assert.NoError(t, exporter.Export(entry), "Unexpected export error")
+	}
+	assert.Contains(t, writer.String(), "team=web", "Unexpected output")
+	assert.NotContains(t, writer.String(), "team=infra", "Unexpected output")
+	assert.NotContains(t, writer.String(), "env=dev", "Unexpected output")
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+
+	_, err = NewStandardExporterOption().UseLabelSelector("env").Build()
+	assert.True(t, errors.Is(err, ErrInvalidLabel), "Unexpected create error")
+}
+
+func TestStandardExporterNamePatterns(t *testing.T) {
+	writer := &testLockedWriter { }
+	syncer, err := NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	exporter, err := NewStandardExporterOption().UseSyncer(syncer).
+		UseNamePatterns("db.*", "cache").Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	for _, name := range []string { "db.pool", "cache", "http", "dbx" } {
+		entry := &Entry {
+			Level: LevelInfo,
+			Message: StringMessage("from " + name),
+			Name: name,
+		}
+		assert.NoError(t, exporter.Export(entry), "Unexpected export error")
+	}
+	assert.Contains(t, writer.String(), "from db.pool", "Unexpected output")
+	assert.Contains(t, writer.String(), "from cache", "Unexpected output")
+	assert.NotContains(t, writer.String(), "from http", "Unexpected output")
+	assert.NotContains(t, writer.String(), "from dbx", "Unexpected output")
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+
+	_, err = NewStandardExporterOption().UseNamePatterns("[").Build()
+	assert.True(t, errors.Is(err, path.ErrBadPattern),
+		"Unexpected create error")
+}
+
+func TestStandardExporterFraming(t *testing.T) {
+	encoder, err := NewStandardEncoderOption().UseEncoderOption(
+		EncoderOption { }).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	for framing, expected := range map[RecordFraming]string {
+		FramingNewline: "\"a\"\n\"b\"\n",
+		FramingCRLF: "\"a\"\r\n\"b\"\r\n",
+		FramingJSONSequence: "\x1e\"a\"\n\x1e\"b\"\n",
+		FramingNone: "\"a\"\"b\"",
+		FramingLengthPrefixed: "\x00\x00\x00\x03\"a\"\x00\x00\x00\x03\"b\"",
+	} {
+		writer := &testLockedWriter { }
+		syncer, err := NewStandardSyncerOption().UseWriter(writer).
+			UseCacheCapacity(0).Build()
+		assert.NoError(t, err, "Unexpected create error")
+		exporter, err := NewStandardExporterOption().UseEncoder(encoder).
+			UseSyncer(syncer).UseFraming(framing).Build()
+		assert.NoError(t, err, "Unexpected create error")
+		for _, text := range []string { "a", "b" } {
+			assert.NoError(t, exporter.Export(&Entry { Level: LevelInfo,
+				Message: StringMessage(text) }), "Unexpected export error")
+		}
+		assert.Equal(t, expected, writer.String(), "Unexpected output")
+		assert.NoError(t, exporter.Close(), "Unexpected close error")
+	}
+
+	_, err = NewStandardExporterOption().UseFraming(-1).Build()
+	assert.True(t, errors.Is(err, ErrInvalidType), "Unexpected create error")
+}
@@ -23,9 +23,15 @@
 package santa
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"math"
+	"math/big"
+	"reflect"
 	"strconv"
 	"time"
+	"unicode/utf8"
+	"unsafe"
 )
 
 // ElementType represents the native data type of an element. The
@@ -75,6 +81,12 @@ const (
 	// For details, please refer to the comment section of the Element
 	// structure.
 	TypeValue
+
+	// TypeBigNumber represents the native data type of the element is
+	// *big.Int, which is stored in the interface container and encoded as
+	// a JSON number with all of its digits. For details, please refer to
+	// the comment section of the BigInt function.
+	TypeBigNumber
 )
 
 // Element is a structure that contains a value of native data type.
@@ -103,7 +115,9 @@ type Element struct {
 
 	// Number represents a number container, and all values of
 	// native data types that represent numbers are stored in this
-	// container.
+	// container. Uint64 values are stored bit for bit, so values above
+	// math.MaxInt64 are negative in the container; use the Uint64
+	// function to read them back.
 	Number int64
 
 	// String represents a string container, and all values of
@@ -118,6 +132,13 @@ type Element struct {
 	Interface interface { }
 }
 
+// Uint64 returns the value of an element whose native data type is
+// Uint64, including values above math.MaxInt64. For details, please refer
+// to the comment section of the Number option.
+func (e Element) Uint64() uint64 {
+	return uint64(e.Number)
+}
+
 // SerializeJSON serializes the element into a JSON value string and
 // appends it to the given buffer slice, and then returns the appended
 // buffer slice.
@@ -126,13 +147,19 @@ func (e Element) SerializeJSON(buffer []byte) []byte {
 	case TypeInt:
 		return strconv.AppendInt(buffer, e.Number, 10)
 	case TypeUint:
-		return strconv.AppendUint(buffer, uint64(e.Number), 10)
+		return strconv.AppendUint(buffer, e.Uint64(), 10)
+	case TypeBigNumber:
+		value, _ := e.Interface.(*big.Int)
+		if value == nil {
+			return append(buffer, "null"...)
+		}
+		return value.Append(buffer, 10)
 	case TypeFloat32:
-		return strconv.AppendFloat(buffer, float64(math.Float32frombits(
-			uint32(e.Number))), 'f', -1, 32)
+		return FieldOption { }.appendFloat(buffer, float64(
+			math.Float32frombits(uint32(e.Number))), 32)
 	case TypeFloat64:
-		return strconv.AppendFloat(buffer, math.Float64frombits(
-			uint64(e.Number)), 'f', -1, 64)
+		return FieldOption { }.appendFloat(buffer, math.Float64frombits(
+			uint64(e.Number)), 64)
 	case TypeBoolean:
 		if e.Number > 0 {
 			return append(buffer, "true"...)
@@ -144,7 +171,7 @@ func (e Element) SerializeJSON(buffer []byte) []byte {
 		return append(buffer, '"')
 	case TypeBytes:
 		buffer = append(buffer, '"')
-		buffer = append(buffer, e.Interface.([]byte)...)
+		buffer = appendBytes(buffer, e.bytes(false), BytesEncoding(e.Number))
 		return append(buffer, '"')
 	default:
 		element, ok := e.Interface.(JSONSerializer)
@@ -155,6 +182,219 @@ func (e Element) SerializeJSON(buffer []byte) []byte {
 	}
 }
 
+// FieldOption is a structure that contains options for serializing
+// fields, which are usually provided by the encoder.
+type FieldOption struct {
+	// OmitEmpty represents whether to skip fields whose values are empty.
+	// For details, please refer to the comment section of the IsEmpty
+	// function of the Element structure.
+	OmitEmpty bool
+
+	// MaxLength represents the maximum number of bytes of the value of
+	// each string or bytes field. The value that exceeds the maximum
+	// length is truncated and appended with a marker containing the
+	// number of truncated bytes. If the value is 0, it means unlimited.
+	MaxLength int
+
+	// UnsafeStringConversion represents whether the values of bytes fields
+	// originating from strings, and of string fields originating from
+	// []byte (see the StringBytes and ByteString functions), are converted
+	// without copying. For details, please refer to the comment section of
+	// the UnsafeStringConversion option of the EncoderOption structure.
+	UnsafeStringConversion bool
+
+	// Encryptor represents the field encryptor that encrypts the values
+	// of encrypted fields. For details, please refer to the comment
+	// section of the EncryptedString function.
+	Encryptor *FieldEncryptor
+
+	// FloatDecimals represents the number of digits after the decimal
+	// point of the values of float fields. If the value is 0, the
+	// shortest representation that parses back to the same value is used.
+	FloatDecimals int
+
+	// FloatScientificThreshold represents the magnitude at or above which,
+	// or below whose reciprocal, the non-zero values of float fields are
+	// encoded in scientific notation (for example: 1e+21). If the value is
+	// 0, scientific notation is never used.
+	FloatScientificThreshold float64
+
+	// NonFiniteFloats represents how the NaN and infinite values of float
+	// fields are encoded, and its optional options are constants starting
+	// with NonFinite...
+	NonFiniteFloats NonFiniteFloat
+}
+
+// bytesToString returns the given bytes value as a string. If the given
+// unsafe is true, the string shares the memory of the bytes value instead
+// of copying it, so the bytes value must not be modified while the string
+// is used.
+func bytesToString(value []byte, unsafeConversion bool) string {
+	if !unsafeConversion || len(value) == 0 {
+		return string(value)
+	}
+	return unsafe.String(unsafe.SliceData(value), len(value))
+}
+
+// stringToBytes returns the given string value as a bytes value. If the
+// given unsafe is true, the bytes value shares the memory of the string
+// instead of copying it, so the bytes value must never be modified.
+func stringToBytes(value string, unsafeConversion bool) []byte {
+	if !unsafeConversion || len(value) == 0 {
+		return []byte(value)
+	}
+	return unsafe.Slice(unsafe.StringData(value), len(value))
+}
+
+// bytes returns the value of a bytes element, which is stored in the
+// interface container, or in the string container if the element was
+// created by the StringBytes function. For details about the given unsafe,
+// please refer to the comment section of the stringToBytes function.
+func (e Element) bytes(unsafeConversion bool) []byte {
+	if value, ok := e.Interface.([]byte); ok {
+		return value
+	}
+	return stringToBytes(e.String, unsafeConversion)
+}
+
+// truncatedMarker represents the marker appended to truncated values.
+const truncatedMarker = "...(truncated "
+
+// truncateLength returns the length of the given string value after it
+// is truncated to at most the given number of bytes without splitting
+// a UTF-8 encoded character.
+func truncateLength(value string, length int) int {
+	for length > 0 && !utf8.RuneStart(value[length]) {
+		length--
+	}
+	return length
+}
+
+// appendTruncatedMarker appends the truncated marker containing the given
+// number of truncated bytes to the given buffer slice, and then returns
+// the appended buffer slice.
+func appendTruncatedMarker(buffer []byte, truncated int) []byte {
+	buffer = append(buffer, truncatedMarker...)
+	buffer = strconv.AppendInt(buffer, int64(truncated), 10)
+	return append(buffer, " bytes)"...)
+}
+
+// serializeJSONWith serializes the element into a JSON value string using
+// the given field option and appends it to the given buffer slice, and
+// then returns the appended buffer slice.
+func (e Element) serializeJSONWith(buffer []byte, option FieldOption) []byte {
+	switch e.Type {
+	case TypeString:
+		if option.MaxLength > 0 && len(e.String) > option.MaxLength {
+			length := truncateLength(e.String, option.MaxLength)
+			buffer = append(buffer, '"')
+			buffer = append(buffer, e.String[ : length]...)
+			buffer = appendTruncatedMarker(buffer, len(e.String) - length)
+			return append(buffer, '"')
+		}
+	case TypeBytes:
+		value := e.bytes(option.UnsafeStringConversion)
+		encoding := BytesEncoding(e.Number)
+		if option.MaxLength > 0 && len(value) > option.MaxLength {
+			// The bytes are truncated before being encoded, so the number
+			// of truncated bytes always refers to the original data.
+			length := option.MaxLength
+			if encoding == BytesString {
+				length = truncateLength(bytesToString(value,
+					option.UnsafeStringConversion), length)
+			}
+			buffer = append(buffer, '"')
+			buffer = appendBytes(buffer, value[ : length], encoding)
+			buffer = appendTruncatedMarker(buffer, len(value) - length)
+			return append(buffer, '"')
+		}
+		buffer = append(buffer, '"')
+		buffer = appendBytes(buffer, value, encoding)
+		return append(buffer, '"')
+	case TypeFloat32:
+		return option.appendFloat(buffer, float64(math.Float32frombits(
+			uint32(e.Number))), 32)
+	case TypeFloat64:
+		return option.appendFloat(buffer, math.Float64frombits(
+			uint64(e.Number)), 64)
+	case TypeValue:
+		switch value := e.Interface.(type) {
+		case ElementObject:
+			return value.SerializeJSONWith(buffer, option)
+		case elementTruncated:
+			// The element uses its own maximum length.
+			return value.SerializeJSON(buffer)
+		case optionSerializer:
+			return value.serializeJSONWith(buffer, option)
+		}
+	}
+	return e.SerializeJSON(buffer)
+}
+
+// optionSerializer is the interface of the built-in element values that
+// are serialized using the field option, such as float slices, encrypted
+// values and the elements returned by the Keep function.
+type optionSerializer interface {
+	serializeJSONWith(buffer []byte, option FieldOption) []byte
+}
+
+// EmptyChecker is the public interface of the empty checker.
+//
+// Any value stored in an element can implement this interface to tell
+// encoders with the OmitEmpty option enabled whether the value is empty.
+// Values that do not implement this interface are checked by their
+// native data type, that is, nil values and empty slices, maps and
+// arrays are considered empty.
+type EmptyChecker interface {
+	// IsEmpty returns true if the value is empty, otherwise returns
+	// false.
+	IsEmpty() bool
+}
+
+// IsEmpty returns true if the value of the element is a zero value, an
+// empty string, a nil value, an empty slice or an empty object, otherwise
+// returns false.
+func (e Element) IsEmpty() bool {
+	switch e.Type {
+	case TypeInt, TypeUint, TypeFloat32, TypeFloat64, TypeBoolean:
+		return e.Number == 0
+	case TypeString:
+		return len(e.String) == 0
+	case TypeBytes:
+		value, _ := e.Interface.([]byte)
+		return len(value) == 0 && len(e.String) == 0
+	case TypeBigNumber:
+		value, _ := e.Interface.(*big.Int)
+		return value == nil || value.Sign() == 0
+	}
+	if e.Interface == nil {
+		return true
+	}
+	if checker, ok := e.Interface.(EmptyChecker); ok {
+		return checker.IsEmpty()
+	}
+	value := reflect.ValueOf(e.Interface)
+	switch value.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return value.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// elementKept represents an element that is never considered empty. For
+// details, please refer to the comment section of the Keep function.
+type elementKept struct {
+	Element
+}
+
+// IsEmpty always returns false, so that the element is emitted even if
+// the OmitEmpty option of the encoder is enabled.
+func (elementKept) IsEmpty() bool {
+	return false
+}
+
 // Field is a structure that contains the name and value of a field.
 //
 // Fields use elements to store the value of a field's native data type.
@@ -197,6 +437,23 @@ func Uint(name string, value uint64) Field {
 	}
 }
 
+// BigInt returns the value of a field with a given name and a given
+// *big.Int value, which is encoded as a JSON number with all of its digits,
+// so that integers that do not fit in 64 bits (for example: token amounts
+// or 128-bit identifiers) are not rounded. A nil value is encoded as null.
+// Please note that consumers that parse JSON numbers as float64 may still
+// lose precision. For details, see the comments section of the Field
+// structure.
+func BigInt(name string, value *big.Int) Field {
+	return Field {
+		Element: Element {
+			Type: TypeBigNumber,
+			Interface: value,
+		},
+		Name: name,
+	}
+}
+
 // Float32 returns the value of a field with a given name and a given
 // float32 value. For details, see the comments section of the Field
 // structure.
@@ -267,39 +524,251 @@ func Time(name string, value time.Time) Field {
 }
 
 // Error returns the value of a field with a given name and a given
-// error value. For details, see the comments section of the Field
-// structure.
+// error value. The original error value is kept in the Interface
+// container, so that hooks can inspect it. For details, see the
+// comments section of the Field structure. If the given error value is
+// nil, the value of the field is null.
 func Error(name string, value error) Field {
+	if value == nil {
+		return Null(name)
+	}
 	return Field {
 		Element: Element {
 			Type: TypeString,
 			String: value.Error(),
+			Interface: value,
 		},
 		Name: name,
 	}
 }
 
-// Bytes returns the value of a field with a given name and a given
-// []byte value. For details, see the comments section of the Field
+// Keep returns a copy of the given field that is always emitted, even if
+// the value of the field is empty and the OmitEmpty option of the encoder
+// is enabled. For details, see the comments section of the EncoderOption
 // structure.
+func Keep(field Field) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: elementKept { field.Element },
+		},
+		Name: field.Name,
+	}
+}
+
+// elementTruncated represents an element with its own maximum length.
+// For details, please refer to the comment section of the Truncate
+// function.
+type elementTruncated struct {
+	Element
+	length int
+}
+
+// SerializeJSON serializes the element into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (e elementTruncated) SerializeJSON(buffer []byte) []byte {
+	return e.Element.serializeJSONWith(buffer, FieldOption {
+		MaxLength: e.length,
+	})
+}
+
+// Truncate returns a copy of the given string or bytes field whose value
+// is truncated to at most the given number of bytes, regardless of the
+// MaxFieldLength option of the encoder. If the given field is not a
+// string or bytes field, or the given length is not greater than 0, the
+// given field is returned unchanged.
+func Truncate(field Field, length int) Field {
+	if length <= 0 || (field.Type != TypeString && field.Type != TypeBytes) {
+		return field
+	}
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: elementTruncated { field.Element, length },
+		},
+		Name: field.Name,
+	}
+}
+
+// BytesEncoding represents the encoding used when serializing the value
+// of a bytes field into a JSON string. Its optional options are constants
+// starting with Bytes...
+type BytesEncoding int64
+
+const (
+	// BytesBase64 represents that the value of a bytes field is encoded
+	// using standard base64 encoding. This is the default encoding.
+	BytesBase64 BytesEncoding = iota
+
+	// BytesHex represents that the value of a bytes field is encoded
+	// using lowercase hexadecimal encoding.
+	BytesHex
+
+	// BytesRaw represents that the value of a bytes field is appended
+	// without any encoding. Please note that the application must make
+	// sure that the data is a valid JSON string content, otherwise the
+	// encoding result is not a valid JSON.
+	BytesRaw
+
+	// BytesString represents that the value of a bytes field is appended
+	// as the content of a string, just like the value of a string field,
+	// and is only truncated at the boundaries of UTF-8 encoded characters.
+	// It is used by the ByteString function.
+	BytesString
+)
+
+// appendBytes appends the given bytes value encoded with the given bytes
+// encoding to the given buffer slice without quotation marks, and then
+// returns the appended buffer slice.
+func appendBytes(buffer []byte, value []byte, encoding BytesEncoding) []byte {
+	var length int
+	switch encoding {
+	case BytesHex:
+		length = hex.EncodedLen(len(value))
+	case BytesRaw, BytesString:
+		return append(buffer, value...)
+	default:
+		length = base64.StdEncoding.EncodedLen(len(value))
+	}
+	offset := len(buffer)
+	if cap(buffer) - offset < length {
+		expanded := make([]byte, offset, offset + length)
+		copy(expanded, buffer)
+		buffer = expanded
+	}
+	buffer = buffer[ : offset + length]
+	if encoding == BytesHex {
+		hex.Encode(buffer[offset : ], value)
+	} else {
+		base64.StdEncoding.Encode(buffer[offset : ], value)
+	}
+	return buffer
+}
+
+// Bytes returns the value of a field with a given name and a given
+// []byte value. The value is encoded using base64 encoding. For details,
+// see the comments section of the Field structure.
 func Bytes(name string, value []byte) Field {
+	return BytesWith(name, value, BytesBase64)
+}
+
+// HexBytes returns the value of a field with a given name and a given
+// []byte value. The value is encoded using hexadecimal encoding. For
+// details, see the comments section of the Field structure.
+func HexBytes(name string, value []byte) Field {
+	return BytesWith(name, value, BytesHex)
+}
+
+// RawBytes returns the value of a field with a given name and a given
+// []byte value. The value is appended without any encoding, so it must
+// be pre-sanitized data. For details, see the comments section of the
+// Field structure.
+func RawBytes(name string, value []byte) Field {
+	return BytesWith(name, value, BytesRaw)
+}
+
+// BytesWith returns the value of a field with a given name, a given
+// []byte value and a given bytes encoding. For details, see the comments
+// section of the Field structure and the BytesEncoding type.
+func BytesWith(name string, value []byte, encoding BytesEncoding) Field {
 	return Field {
 		Element: Element {
 			Type: TypeBytes,
+			Number: int64(encoding),
 			Interface: value,
 		},
 		Name: name,
 	}
 }
 
+// ByteString returns the value of a string field with a given name and a
+// given []byte value, which is serialized just like the value of a field
+// returned by the String function, without converting the value to a
+// string when the field is created. For details, see the comments section
+// of the Field structure.
+//
+// Please note that the value is referenced by the field, so it must not be
+// modified until the log entry has been encoded.
+func ByteString(name string, value []byte) Field {
+	return BytesWith(name, value, BytesString)
+}
+
+// StringBytes returns the value of a bytes field with a given name, a
+// given string value and a given bytes encoding, without converting the
+// value to []byte when the field is created. For details, see the comments
+// section of the Field structure and the BytesEncoding type.
+func StringBytes(name string, value string, encoding BytesEncoding) Field {
+	return Field {
+		Element: Element {
+			Type: TypeBytes,
+			Number: int64(encoding),
+			String: value,
+		},
+		Name: name,
+	}
+}
+
+// elementNull represents the null value. For details, please refer to the
+// comment section of the Null function.
+type elementNull struct { }
+
+// SerializeJSON appends null to the given buffer slice, and then returns
+// the appended buffer slice.
+func (elementNull) SerializeJSON(buffer []byte) []byte {
+	return append(buffer, "null"...)
+}
+
+// IsEmpty always returns true.
+func (elementNull) IsEmpty() bool {
+	return true
+}
+
+// Null returns the value of a field with a given name whose value is
+// null. For details, see the comments section of the Field structure.
+func Null(name string) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: elementNull { },
+		},
+		Name: name,
+	}
+}
+
+// isNil returns true if the given value is nil or a typed nil value, such
+// as a nil pointer, map, slice, channel or function, otherwise it returns
+// false.
+func isNil(value interface { }) bool {
+	if value == nil {
+		return true
+	}
+	reflected := reflect.ValueOf(value)
+	switch reflected.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan,
+		reflect.Func, reflect.Interface, reflect.UnsafePointer:
+		return reflected.IsNil()
+	}
+	return false
+}
+
 // Value returns the value of a field with a given name and a given
 // value. The given value must have implemented the relevant formatter
 // interface. Please refer to the comments section of the Field
 // structure for details.
+//
+// If the given value is nil, or a typed nil value such as a nil pointer,
+// map or slice (including a nil []byte value and a nil pointer whose type
+// implements the error interface), the value of the field is null.
 func Value(name string, value interface { }) Field {
+	if value == nil {
+		return Null(name)
+	}
 	switch v := value.(type) {
 	case int:
 		return Int(name, int64(v))
+	case int8:
+		return Int(name, int64(v))
 	case int16:
 		return Int(name, int64(v))
 	case int32:
@@ -316,6 +785,10 @@ func Value(name string, value interface { }) Field {
 		return Uint(name, uint64(v))
 	case uint64:
 		return Uint(name, uint64(v))
+	case uintptr:
+		return Uint(name, uint64(v))
+	case *big.Int:
+		return BigInt(name, v)
 	case float32:
 		return Float32(name, v)
 	case float64:
@@ -327,10 +800,19 @@ func Value(name string, value interface { }) Field {
 	case time.Time:
 		return Time(name, v)
 	case error:
+		if isNil(v) {
+			return Null(name)
+		}
 		return Error(name, v)
 	case []byte:
+		if v == nil {
+			return Null(name)
+		}
 		return Bytes(name, v)
 	}
+	if isNil(value) {
+		return Null(name)
+	}
 
 	return Field {
 		Element: Element {
@@ -364,6 +846,51 @@ func (e ElementObject) SerializeJSON(buffer []byte) []byte {
 	return append(buffer, '}')
 }
 
+// IsEmpty returns true if the object does not contain any field or all
+// fields of the object are empty, otherwise returns false.
+func (e ElementObject) IsEmpty() bool {
+	for index := 0; index < len(e); index++ {
+		if !e[index].IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// SerializeJSONWith serializes the element into a JSON string using the
+// given field option and appends it to the given buffer slice, and then
+// returns the appended buffer slice. Nested objects are serialized with
+// the same field option. For details, please refer to the comment section
+// of the FieldOption structure.
+func (e ElementObject) SerializeJSONWith(buffer []byte, option FieldOption) []byte {
+	buffer = append(buffer, '{')
+	buffer = e.appendJSONWith(buffer, option, false)
+	return append(buffer, '}')
+}
+
+// appendJSONWith serializes the fields of the object separated by commas
+// using the given field option without the enclosing braces, and appends
+// them to the given buffer slice, and then returns the appended buffer
+// slice. If the given separator is true, a comma is appended before the
+// first serialized field.
+func (e ElementObject) appendJSONWith(buffer []byte, option FieldOption,
+	separator bool) []byte {
+	for index := 0; index < len(e); index++ {
+		if option.OmitEmpty && e[index].IsEmpty() {
+			continue
+		}
+		if separator {
+			buffer = append(buffer, ", "...)
+		}
+		separator = true
+		buffer = append(buffer, '"')
+		buffer = append(buffer, e[index].Name...)
+		buffer = append(buffer, "\": "...)
+		buffer = e[index].serializeJSONWith(buffer, option)
+	}
+	return buffer
+}
+
 // Object returns the value of a field with a given name and a given
 // []Field value. For details, see the comments section of the Field
 // structure.
@@ -397,6 +924,55 @@ func (e ElementObjects) SerializeJSON(buffer []byte) []byte {
 	return append(buffer, ']')
 }
 
+// ObjectFormatter is the public interface of object formatters.
+//
+// An object formatter describes a value as a structured object containing
+// one or more fields, so that a slice of application values (for example,
+// a list of query results) can be encoded as an array of objects without
+// manually assembling fields. The ElementObject data type implements this
+// interface.
+type ObjectFormatter interface {
+	// FormatObject appends the fields of the object to the given field
+	// slice, and then returns the appended field slice.
+	FormatObject(fields []Field) []Field
+}
+
+// FormatObject appends the fields of the object to the given field slice,
+// and then returns the appended field slice.
+func (e ElementObject) FormatObject(fields []Field) []Field {
+	return append(fields, e...)
+}
+
+// ElementFormatters represents an element data type whose native data
+// type is []ObjectFormatter. Each object formatter is encoded as a JSON
+// object, and a nil or typed nil object formatter (such as a nil pointer)
+// is encoded as null. For details, please refer to the comment section of
+// the Element structure.
+type ElementFormatters []ObjectFormatter
+
+// SerializeJSON serializes the element into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (e ElementFormatters) SerializeJSON(buffer []byte) []byte {
+	// The field slice is reused by all objects to avoid allocating heap
+	// memory for each object.
+	var fields []Field
+	buffer = append(buffer, '[')
+	tail := len(e) - 1
+	for index := 0; index < len(e); index++ {
+		if isNil(e[index]) {
+			buffer = append(buffer, "null"...)
+		} else {
+			fields = e[index].FormatObject(fields[ : 0])
+			buffer = ElementObject(fields).SerializeJSON(buffer)
+		}
+		if index < tail {
+			buffer = append(buffer, ", "...)
+		}
+	}
+	return append(buffer, ']')
+}
+
 // Objects returns the value of a field with a given name and a given
 // []ElementObject value. For details, see the comments section of the
 // Field structure.
@@ -410,6 +986,21 @@ func Objects(name string, values ...ElementObject) Field {
 	}
 }
 
+// Formatters returns the value of a field with a given name and a given
+// []ObjectFormatter value. Each application value that implements the
+// ObjectFormatter interface is encoded as an object, and a nil or typed
+// nil value is encoded as null. For details, see the comments section of
+// the Field structure.
+func Formatters(name string, values ...ObjectFormatter) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: ElementFormatters(values),
+		},
+		Name: name,
+	}
+}
+
 // ElementInts represents an element data type whose native data type
 // is []int64. For details, please refer to the comment section of the
 // Element structure.
@@ -485,11 +1076,17 @@ type ElementFloat32s []float32
 // it to the given buffer slice, and then returns the appended buffer
 // slice.
 func (e ElementFloat32s) SerializeJSON(buffer []byte) []byte {
+	return e.serializeJSONWith(buffer, FieldOption { })
+}
+
+// serializeJSONWith serializes the element into a JSON string using the
+// float options of the given field option and appends it to the given
+// buffer slice, and then returns the appended buffer slice.
+func (e ElementFloat32s) serializeJSONWith(buffer []byte, option FieldOption) []byte {
 	buffer = append(buffer, '[')
 	tail := len(e) - 1
 	for index := 0; index < len(e); index++ {
-		buffer = strconv.AppendFloat(buffer, float64(e[index]),
-			'f', -1, 32)
+		buffer = option.appendFloat(buffer, float64(e[index]), 32)
 		if index < tail {
 			buffer = append(buffer, ", "...)
 		}
@@ -519,11 +1116,17 @@ type ElementFloat64s []float64
 // it to the given buffer slice, and then returns the appended buffer
 // slice.
 func (e ElementFloat64s) SerializeJSON(buffer []byte) []byte {
+	return e.serializeJSONWith(buffer, FieldOption { })
+}
+
+// serializeJSONWith serializes the element into a JSON string using the
+// float options of the given field option and appends it to the given
+// buffer slice, and then returns the appended buffer slice.
+func (e ElementFloat64s) serializeJSONWith(buffer []byte, option FieldOption) []byte {
 	buffer = append(buffer, '[')
 	tail := len(e) - 1
 	for index := 0; index < len(e); index++ {
-		buffer = strconv.AppendFloat(buffer, float64(e[index]),
-			'f', -1, 64)
+		buffer = option.appendFloat(buffer, e[index], 64)
 		if index < tail {
 			buffer = append(buffer, ", "...)
 		}
@@ -644,3 +1247,152 @@ func Times(name string, values []time.Time) Field {
 		Name: name,
 	}
 }
+
+// Duration returns the value of a field with a given name and a given
+// time.Duration value. The duration is encoded as the number of
+// nanoseconds. For details, see the comments section of the Field
+// structure.
+func Duration(name string, value time.Duration) Field {
+	return Int(name, int64(value))
+}
+
+// ElementDurations represents an element data type whose native data
+// type is []time.Duration. Each duration is encoded as the number of
+// nanoseconds. For details, please refer to the comment section of the
+// Element structure.
+type ElementDurations []time.Duration
+
+// SerializeJSON serializes the element into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (e ElementDurations) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, '[')
+	tail := len(e) - 1
+	for index := 0; index < len(e); index++ {
+		buffer = strconv.AppendInt(buffer, int64(e[index]), 10)
+		if index < tail {
+			buffer = append(buffer, ", "...)
+		}
+	}
+	return append(buffer, ']')
+}
+
+// Durations returns the value of a field with a given name and a given
+// []time.Duration value. For details, see the comments section of the
+// Field structure.
+func Durations(name string, values []time.Duration) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: ElementDurations(values),
+		},
+		Name: name,
+	}
+}
+
+// ElementErrors represents an element data type whose native data type
+// is []error. Each error is encoded as its description string, and a nil
+// error is encoded as null. For details, please refer to the comment
+// section of the Element structure.
+type ElementErrors []error
+
+// SerializeJSON serializes the element into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (e ElementErrors) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, '[')
+	tail := len(e) - 1
+	for index := 0; index < len(e); index++ {
+		if e[index] == nil {
+			buffer = append(buffer, "null"...)
+		} else {
+			buffer = append(buffer, '"')
+			buffer = append(buffer, e[index].Error()...)
+			buffer = append(buffer, '"')
+		}
+		if index < tail {
+			buffer = append(buffer, ", "...)
+		}
+	}
+	return append(buffer, ']')
+}
+
+// Errors returns the value of a field with a given name and a given
+// []error value. For details, see the comments section of the Field
+// structure.
+func Errors(name string, values []error) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: ElementErrors(values),
+		},
+		Name: name,
+	}
+}
+
+// FieldBuilder is the structure of the field builder instance.
+//
+// The field builder collects fields into a field buffer obtained from the
+// global pool, so that call sites that assemble fields incrementally (for
+// example: generated code) do not allocate a new field slice for each log
+// entry. The zero value is ready to use, and the field builder can be
+// reused after calling the Reset function. Once it is no longer needed,
+// the Release function returns the field buffer to the pool.
+//
+// Please note that the field builder is not thread-safe.
+type FieldBuilder struct {
+	buffer *[]Field
+}
+
+// Add appends the given fields to the field builder, and then returns the
+// field builder instance itself.
+func (b *FieldBuilder) Add(fields ...Field) *FieldBuilder {
+	if b.buffer == nil {
+		b.buffer = pool.Buffer.Field.New()
+	}
+	*b.buffer = append(*b.buffer, fields...)
+	return b
+}
+
+// Fields returns the fields that have been added to the field builder. The
+// returned slice is shared with the field builder, and it is only valid
+// until the next call to the Add, Reset or Release function.
+func (b *FieldBuilder) Fields() []Field {
+	if b.buffer == nil {
+		return nil
+	}
+	return *b.buffer
+}
+
+// Len returns the number of fields that have been added to the field
+// builder.
+func (b *FieldBuilder) Len() int {
+	if b.buffer == nil {
+		return 0
+	}
+	return len(*b.buffer)
+}
+
+// Reset removes all fields from the field builder, keeping the field
+// buffer for reuse.
+func (b *FieldBuilder) Reset() {
+	if b.buffer == nil {
+		return
+	}
+	fields := *b.buffer
+	for index := 0; index < len(fields); index++ {
+		fields[index] = Field { }
+	}
+	*b.buffer = fields[ : 0]
+}
+
+// Release returns the field buffer to the global pool and resets the field
+// builder to its zero value. The fields previously returned by the Fields
+// function must not be used after calling it.
+func (b *FieldBuilder) Release() {
+	if b.buffer == nil {
+		return
+	}
+	pool.Buffer.Field.Free(b.buffer)
+	b.buffer = nil
+}
@@ -24,12 +24,23 @@ package santa
 
 import (
 	"errors"
+	"math"
+	"math/big"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+type testUser struct {
+	name string
+	age int64
+}
+
+func (u *testUser) FormatObject(fields []Field) []Field {
+	return append(fields, String("name", u.name), Int("age", u.age))
+}
+
 func TestElementSerializeJSON(t *testing.T) {
 	timestamp, _ := time.Parse(time.RFC3339Nano,
 		"2020-08-13T21:56:30.0719939+08:00")
@@ -74,6 +85,16 @@ func TestElementSerializeJSON(t *testing.T) {
 		{
 			name: "bytes",
 			field: Bytes("bytes", []byte("Hello")),
+			expected: "\"SGVsbG8=\"",
+		},
+		{
+			name: "hex",
+			field: HexBytes("hex", []byte("Hello")),
+			expected: "\"48656c6c6f\"",
+		},
+		{
+			name: "raw",
+			field: RawBytes("raw", []byte("Hello")),
 			expected: "\"Hello\"",
 		},
 		{
@@ -136,6 +157,22 @@ func TestElementSerializeJSON(t *testing.T) {
 				}
 			]`,
 		},
+		{
+			name: "formatters",
+			field: Formatters("formatters",
+				&testUser { name: "test", age: 100 },
+				nil,
+				(*testUser)(nil),
+			),
+			expected: `[
+				{
+					"name": "test",
+					"age": 100
+				},
+				null,
+				null
+			]`,
+		},
 		{
 			name: "ints",
 			field: Ints("ints", []int64 { 10, 20, 30 }),
@@ -173,6 +210,18 @@ func TestElementSerializeJSON(t *testing.T) {
 				timestamp }),
 			expected: `[1597326990071993900, 1597326990071993900]`,
 		},
+		{
+			name: "durations",
+			field: Durations("durations", []time.Duration {
+				time.Second, time.Millisecond }),
+			expected: `[1000000000, 1000000]`,
+		},
+		{
+			name: "errors",
+			field: Errors("errors", []error { errors.New("Error"),
+				nil }),
+			expected: `["Error", null]`,
+		},
 	} {
 		assert.Equal(t, sample.name, sample.field.Name,
 			"Unexpected field name")
@@ -183,3 +232,82 @@ func TestElementSerializeJSON(t *testing.T) {
 		)
 	}
 }
+
+func TestUintBoundaries(t *testing.T) {
+	large, _ := new(big.Int).SetString("340282366920938463463374607431768211457",
+		10)
+	for _, sample := range []struct {
+		field Field
+		expected string
+	} {
+		{ Uint("max", math.MaxUint64), "18446744073709551615" },
+		{ Uint("overflow", math.MaxInt64 + 1), "9223372036854775808" },
+		{ Uint("int", math.MaxInt64), "9223372036854775807" },
+		{ Value("value", uint64(math.MaxUint64)), "18446744073709551615" },
+		{ Value("uintptr", uintptr(7)), "7" },
+		{ Value("int8", int8(-8)), "-8" },
+		{ F("generic", uint64(math.MaxUint64)), "18446744073709551615" },
+		{ Uints("uints", []uint64 { math.MaxUint64 }),
+			"[18446744073709551615]" },
+		{ BigInt("big", large), "340282366920938463463374607431768211457" },
+		{ BigInt("negative", big.NewInt(-1)), "-1" },
+		{ BigInt("nil", nil), "null" },
+		{ Value("value", large), "340282366920938463463374607431768211457" },
+	} {
+		assert.Equal(t, sample.expected, string(sample.field.SerializeJSON(
+			nil)), "Unexpected JSON formatted append result")
+	}
+	assert.Equal(t, uint64(math.MaxUint64), Uint("max",
+		math.MaxUint64).Uint64(), "Unexpected uint value")
+	assert.True(t, BigInt("zero", new(big.Int)).IsEmpty(),
+		"Unexpected empty result")
+	assert.False(t, BigInt("big", large).IsEmpty(), "Unexpected empty result")
+
+	filter, err := CompileFilter("field.max > 1e19")
+	assert.NoError(t, err, "Unexpected compile error")
+	assert.True(t, filter.Match(&Entry { Message: StructMessage {
+		Fields: []Field { Uint("max", math.MaxUint64) },
+	} }), "Unexpected filter result")
+	filter, err = CompileFilter("field.big > 1e30")
+	assert.NoError(t, err, "Unexpected compile error")
+	assert.True(t, filter.Match(&Entry { Message: StructMessage {
+		Fields: []Field { BigInt("big", large) },
+	} }), "Unexpected filter result")
+}
+
+type testNilError struct { }
+
+func (e *testNilError) Error() string {
+	return "nil error"
+}
+
+func TestValueNil(t *testing.T) {
+	var user *testUser
+	var serializer JSONSerializer
+	var err error
+	for _, sample := range []Field {
+		Value("nil", nil),
+		Value("interface", serializer),
+		Value("pointer", user),
+		Value("map", map[string]int(nil)),
+		Value("slice", []string(nil)),
+		Value("bytes", []byte(nil)),
+		Value("error", err),
+		Value("typed_error", (*testNilError)(nil)),
+		Value("function", (func())(nil)),
+		Error("error", nil),
+	} {
+		assert.Equal(t, "null", string(sample.SerializeJSON(nil)),
+			"Unexpected JSON formatted append result")
+		assert.True(t, sample.IsEmpty(), "Unexpected empty result")
+		assert.False(t, sample.unknown(), "Unexpected unknown value")
+	}
+	assert.Equal(t, `""`, string(Value("bytes", []byte { }).SerializeJSON(
+		nil)), "Unexpected JSON formatted append result")
+	assert.Equal(t, `{"name": "test", "age": 100}`, string(Value("user",
+		ElementObject { String("name", "test"), Int("age", 100) }).
+		SerializeJSON(nil)), "Unexpected JSON formatted append result")
+	assert.Equal(t, `{"value": null}`, string(StructMessage {
+		Fields: []Field { Value("value", nil) },
+	}.Fields.SerializeJSON(nil)), "Unexpected JSON formatted append result")
+}
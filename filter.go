@@ -0,0 +1,551 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidFilter represents that a filter expression is invalid. This
+	// is usually because the filter expression cannot be parsed.
+	ErrInvalidFilter = errors.New("invalid filter")
+)
+
+// FilterError is a structure that contains an error of a filter expression
+// that cannot be compiled, and identifies where the error is.
+type FilterError struct {
+	// Expression represents the filter expression.
+	Expression string
+
+	// Offset represents the offset in bytes of the error in the filter
+	// expression.
+	Offset int
+
+	// Reason represents the description of why the filter expression
+	// cannot be compiled.
+	Reason string
+}
+
+// Error returns the description string of the error.
+func (e *FilterError) Error() string {
+	return ErrInvalidFilter.Error() + " " + strconv.Quote(e.Expression) +
+		" at offset " + strconv.Itoa(e.Offset) + ": " + e.Reason
+}
+
+// Unwrap returns ErrInvalidFilter, so that the filter error can be checked
+// with the errors.Is function.
+func (e *FilterError) Unwrap() error {
+	return ErrInvalidFilter
+}
+
+// filterFunc is the type of the compiled functions of filter expressions.
+type filterFunc func(entry *Entry) bool
+
+// Filter is the structure of a compiled filter expression.
+//
+// The filter expression is compiled into a chain of closures when the
+// filter is created, so matching a log entry neither parses the filter
+// expression nor allocates memory. For details of the filter expressions,
+// please refer to the comment section of the CompileFilter function.
+//
+// The API provided by the filter is thread-safe.
+type Filter struct {
+	expression string
+	match filterFunc
+}
+
+// Match returns true if the given log entry matches the filter expression,
+// otherwise it returns false.
+func (f *Filter) Match(entry *Entry) bool {
+	return f.match(entry)
+}
+
+// String returns the filter expression of the filter.
+func (f *Filter) String() string {
+	return f.expression
+}
+
+// CompileFilter compiles the given filter expression, and then returns the
+// compiled filter and any errors encountered. If the filter expression
+// cannot be compiled, a FilterError is returned.
+//
+// A filter expression consists of comparisons combined with the "&&",
+// "||" and "!" operators and parentheses, for example:
+//
+//   level >= WARNING && (label.env == prod || field.status >= 500)
+//
+// Each comparison compares an operand of the log entry with a literal
+// using one of the "==", "!=", "<", "<=", ">", ">=" and "~=" (contains)
+// operators. The operands are:
+//
+//   level           the level, compared with a level name or number
+//   name            the name of the logger
+//   message         the text of the message (see TextSampleParser)
+//   label.KEY       the value of the label with the key
+//   field.NAME      the value of the field of a structured message
+//
+// Literals are quoted strings (for example: "hello world"), numbers (for
+// example: 1.5), or bare words (for example: prod and true). Labels and
+// fields that do not exist only satisfy the "!=" operator, and a label or
+// field operand without an operator checks whether it exists.
+func CompileFilter(expression string) (*Filter, error) {
+	tokens, err := lexFilter(expression)
+	if err != nil {
+		return nil, err
+	}
+	parser := &filterParser {
+		expression: expression,
+		tokens: tokens,
+	}
+	match, err := parser.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if token := parser.peek(); token.kind != filterEnd {
+		return nil, parser.fail(token, "unexpected " + strconv.Quote(
+			token.text))
+	}
+	return &Filter {
+		expression: expression,
+		match: match,
+	}, nil
+}
+
+// filterTokenKind represents the kind of a token of a filter expression.
+type filterTokenKind int
+
+const (
+	filterEnd filterTokenKind = iota
+	filterWord
+	filterString
+	filterNumber
+	filterOperator
+	filterAnd
+	filterOr
+	filterNot
+	filterOpen
+	filterClose
+)
+
+// filterToken is a structure that contains a token of a filter expression.
+type filterToken struct {
+	kind filterTokenKind
+	text string
+	offset int
+}
+
+// filterOperators contains the comparison operators of filter expressions,
+// two-character operators first.
+var filterOperators = []string { "==", "!=", "<=", ">=", "~=", "<", ">" }
+
+// isFilterWord checks whether the given character can be part of a bare
+// word of a filter expression.
+func isFilterWord(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9' || c == '_' || c == '.' || c == '-'
+}
+
+// lexFilter splits the given filter expression into tokens, and then
+// returns the tokens and any errors encountered.
+func lexFilter(expression string) ([]filterToken, error) {
+	var tokens []filterToken
+	fail := func(offset int, reason string) error {
+		return &FilterError {
+			Expression: expression,
+			Offset: offset,
+			Reason: reason,
+		}
+	}
+	for offset := 0; offset < len(expression); {
+		c := expression[offset]
+		rest := expression[offset : ]
+		token := filterToken {
+			offset: offset,
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			offset++
+			continue
+		case c == '(':
+			token.kind, token.text = filterOpen, "("
+		case c == ')':
+			token.kind, token.text = filterClose, ")"
+		case strings.HasPrefix(rest, "&&"):
+			token.kind, token.text = filterAnd, "&&"
+		case strings.HasPrefix(rest, "||"):
+			token.kind, token.text = filterOr, "||"
+		case c == '"':
+			end := 1
+			for end < len(rest) && rest[end] != '"' {
+				if rest[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(rest) {
+				return nil, fail(offset, "unterminated string")
+			}
+			value, err := strconv.Unquote(rest[ : end + 1])
+			if err != nil {
+				return nil, fail(offset, "invalid string")
+			}
+			tokens = append(tokens, filterToken {
+				kind: filterString,
+				text: value,
+				offset: offset,
+			})
+			offset += end + 1
+			continue
+		case isFilterWord(c):
+			end := 1
+			for end < len(rest) && isFilterWord(rest[end]) {
+				end++
+			}
+			token.kind, token.text = filterWord, rest[ : end]
+			if _, err := strconv.ParseFloat(token.text, 64); err == nil {
+				token.kind = filterNumber
+			}
+		default:
+			for _, operator := range filterOperators {
+				if strings.HasPrefix(rest, operator) {
+					token.kind, token.text = filterOperator, operator
+					break
+				}
+			}
+			if len(token.text) == 0 && c == '!' {
+				token.kind, token.text = filterNot, "!"
+			}
+			if len(token.text) == 0 {
+				return nil, fail(offset, "unexpected character " +
+					strconv.QuoteRune(rune(c)))
+			}
+		}
+		tokens = append(tokens, token)
+		offset += len(token.text)
+	}
+	return append(tokens, filterToken {
+		kind: filterEnd,
+		offset: len(expression),
+	}), nil
+}
+
+// filterParser is a structure that parses the tokens of a filter
+// expression into compiled functions.
+type filterParser struct {
+	expression string
+	tokens []filterToken
+	index int
+}
+
+// peek returns the next token without consuming it.
+func (p *filterParser) peek() filterToken {
+	return p.tokens[p.index]
+}
+
+// next consumes and returns the next token.
+func (p *filterParser) next() filterToken {
+	token := p.tokens[p.index]
+	if token.kind != filterEnd {
+		p.index++
+	}
+	return token
+}
+
+// fail returns a FilterError at the given token with the given reason.
+func (p *filterParser) fail(token filterToken, reason string) error {
+	return &FilterError {
+		Expression: p.expression,
+		Offset: token.offset,
+		Reason: reason,
+	}
+}
+
+// parseOr parses the operands of "||" operators.
+func (p *filterParser) parseOr() (filterFunc, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == filterOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		first, second := left, right
+		left = func(entry *Entry) bool {
+			return first(entry) || second(entry)
+		}
+	}
+	return left, nil
+}
+
+// parseAnd parses the operands of "&&" operators.
+func (p *filterParser) parseAnd() (filterFunc, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == filterAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		first, second := left, right
+		left = func(entry *Entry) bool {
+			return first(entry) && second(entry)
+		}
+	}
+	return left, nil
+}
+
+// parseUnary parses a "!" operator, a parenthesized expression, or a
+// comparison.
+func (p *filterParser) parseUnary() (filterFunc, error) {
+	token := p.next()
+	switch token.kind {
+	case filterNot:
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return func(entry *Entry) bool {
+			return !operand(entry)
+		}, nil
+	case filterOpen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != filterClose {
+			return nil, p.fail(closing, "expected \")\"")
+		}
+		return inner, nil
+	case filterWord:
+		return p.parseComparison(token)
+	case filterEnd:
+		return nil, p.fail(token, "unexpected end of expression")
+	}
+	return nil, p.fail(token, "unexpected " + strconv.Quote(token.text))
+}
+
+// parseComparison parses the comparison of the given operand.
+func (p *filterParser) parseComparison(operand filterToken) (filterFunc,
+	error) {
+	if p.peek().kind != filterOperator {
+		// An operand without an operator checks whether the label or the
+		// field exists.
+		switch {
+		case strings.HasPrefix(operand.text, "label."):
+			key := operand.text[len("label.") : ]
+			return func(entry *Entry) bool {
+				_, ok := entry.Labels.Lookup(key)
+				return ok
+			}, nil
+		case strings.HasPrefix(operand.text, "field."):
+			name := operand.text[len("field.") : ]
+			return func(entry *Entry) bool {
+				_, ok := lookupMessageField(entry.Message, name)
+				return ok
+			}, nil
+		}
+		return nil, p.fail(p.peek(), "expected an operator")
+	}
+	operator := p.next()
+	literal := p.next()
+	switch literal.kind {
+	case filterWord, filterString, filterNumber:
+	default:
+		return nil, p.fail(literal, "expected a literal")
+	}
+	op := operator.text
+	value := literal.text
+
+	switch {
+	case operand.text == "level":
+		if op == "~=" {
+			return nil, p.fail(operator, "unsupported operator for level")
+		}
+		level, err := ParseLevel(value)
+		if literal.kind == filterNumber {
+			var number uint64
+			number, err = strconv.ParseUint(value, 10, 8)
+			level = Level(number)
+		}
+		if err != nil {
+			return nil, p.fail(literal, "invalid level " +
+				strconv.Quote(value))
+		}
+		return func(entry *Entry) bool {
+			return compareFilterNumber(op, float64(entry.Level),
+				float64(level))
+		}, nil
+	case operand.text == "name":
+		return func(entry *Entry) bool {
+			return compareFilterString(op, entry.Name, value)
+		}, nil
+	case operand.text == "message":
+		return func(entry *Entry) bool {
+			var text string
+			if parser, ok := entry.Message.(TextSampleParser); ok {
+				text = parser.SampleText()
+			}
+			return compareFilterString(op, text, value)
+		}, nil
+	case strings.HasPrefix(operand.text, "label."):
+		key := operand.text[len("label.") : ]
+		return func(entry *Entry) bool {
+			label, ok := entry.Labels.Lookup(key)
+			if !ok {
+				return op == "!="
+			}
+			return compareFilterString(op, label, value)
+		}, nil
+	case strings.HasPrefix(operand.text, "field."):
+		return compileFilterField(operand.text[len("field.") : ], op,
+			literal), nil
+	}
+	return nil, p.fail(operand, "unknown operand " +
+		strconv.Quote(operand.text))
+}
+
+// compileFilterField returns the compiled function of the comparison of
+// the field with the given name.
+func compileFilterField(name, op string, literal filterToken) filterFunc {
+	number, numeric := 0.0, literal.kind == filterNumber
+	if numeric {
+		number, _ = strconv.ParseFloat(literal.text, 64)
+	}
+	boolean := literal.kind == filterWord &&
+		(literal.text == "true" || literal.text == "false")
+	value := literal.text
+	return func(entry *Entry) bool {
+		field, ok := lookupMessageField(entry.Message, name)
+		if !ok {
+			return op == "!="
+		}
+		switch field.Type {
+		case TypeInt:
+			if numeric {
+				return compareFilterNumber(op, float64(field.Number), number)
+			}
+		case TypeUint:
+			if numeric {
+				return compareFilterNumber(op, float64(field.Uint64()),
+					number)
+			}
+		case TypeBigNumber:
+			if value, ok := field.Interface.(*big.Int); ok && numeric {
+				actual, _ := new(big.Float).SetInt(value).Float64()
+				return compareFilterNumber(op, actual, number)
+			}
+		case TypeFloat32:
+			if numeric {
+				return compareFilterNumber(op, float64(math.Float32frombits(
+					uint32(field.Number))), number)
+			}
+		case TypeFloat64:
+			if numeric {
+				return compareFilterNumber(op, math.Float64frombits(
+					uint64(field.Number)), number)
+			}
+		case TypeBoolean:
+			if boolean {
+				actual := "false"
+				if field.Number > 0 {
+					actual = "true"
+				}
+				return compareFilterString(op, actual, value)
+			}
+		case TypeString:
+			return compareFilterString(op, field.String, value)
+		}
+		return op == "!="
+	}
+}
+
+// lookupMessageField returns the last field with the given name of the
+// given structured message, and whether it exists.
+func lookupMessageField(message Message, name string) (*Field, bool) {
+	var fields []Field
+	switch message := message.(type) {
+	case *StructMessage:
+		if message != nil {
+			fields = message.Fields
+		}
+	case StructMessage:
+		fields = message.Fields
+	}
+	for index := len(fields) - 1; index >= 0; index-- {
+		if fields[index].Name == name {
+			return &fields[index], true
+		}
+	}
+	return nil, false
+}
+
+// compareFilterNumber compares the given numbers with the given operator.
+func compareFilterNumber(op string, left, right float64) bool {
+	switch op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	}
+	return false
+}
+
+// compareFilterString compares the given strings with the given operator.
+func compareFilterString(op string, left, right string) bool {
+	switch op {
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	case "~=":
+		return strings.Contains(left, right)
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestFilterEntry() *Entry {
+	return &Entry {
+		Level: LevelWarning,
+		Name: "db.pool",
+		Message: &StructMessage {
+			Text: "connection refused",
+			Fields: []Field {
+				Int("status", 503),
+				Float64("ratio", 0.25),
+				Boolean("retry", true),
+				String("host", "db-1"),
+			},
+		},
+		Labels: NewSerializedLabels(NewLabel("env", "prod")),
+	}
+}
+
+func TestCompileFilter(t *testing.T) {
+	entry := newTestFilterEntry()
+	for expression, expected := range map[string]bool {
+		"level >= WARNING": true,
+		"level < warning": false,
+		"level == 3": entry.Level == 3,
+		"name == db.pool": true,
+		"name ~= \"db.\" && !(name == cache)": true,
+		"message ~= refused": true,
+		"message == \"connection refused\"": true,
+		"label.env == prod": true,
+		"label.env != prod || label.team == web": false,
+		"label.team != web": true,
+		"label.env && !label.team": true,
+		"field.status >= 500 && field.status < 600": true,
+		"field.ratio > 0.5": false,
+		"field.retry == true": true,
+		"field.host == \"db-1\"": true,
+		"field.host == 1": false,
+		"field.missing != 1": true,
+		"field.missing": false,
+		"field.status": true,
+		"level >= ERROR || field.status == 503 && label.env == prod": true,
+		"(level >= ERROR || field.status == 503) && label.env == dev": false,
+	} {
+		filter, err := CompileFilter(expression)
+		assert.NoError(t, err, "Unexpected compile error")
+		assert.Equal(t, expected, filter.Match(entry), expression)
+		assert.Equal(t, expression, filter.String(), "Unexpected string")
+	}
+
+	for _, expression := range []string {
+		"", "level", "level >=", "level ~= INFO", "level == LOUD",
+		"(name == a", "name == a)", "unknown == a", "name == \"a",
+		"name = a", "name == a &&", "label.env == (",
+	} {
+		_, err := CompileFilter(expression)
+		assert.True(t, errors.Is(err, ErrInvalidFilter),
+			"Unexpected compile error: " + expression)
+		var filterErr *FilterError
+		assert.True(t, errors.As(err, &filterErr), "Unexpected error type")
+	}
+}
+
+func TestFilterAllocations(t *testing.T) {
+	filter, err := CompileFilter("level >= WARNING && label.env == prod " +
+		"&& (field.status >= 500 || name ~= db.)")
+	assert.NoError(t, err, "Unexpected compile error")
+	entry := newTestFilterEntry()
+	allocations := testing.AllocsPerRun(100, func() {
+		filter.Match(entry)
+	})
+	assert.Zero(t, allocations, "Unexpected allocations")
+}
+
+func TestStandardExporterFilter(t *testing.T) {
+	writer := &testLockedWriter { }
+	syncer, err := NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	exporter, err := NewStandardExporterOption().UseSyncer(syncer).
+		UseFilter("name ~= db. && level >= WARNING").Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	for _, name := range []string { "db.pool", "http" } {
+		entry := &Entry {
+			Level: LevelWarning,
+			Message: StringMessage("from " + name),
+			Name: name,
+		}
+		assert.NoError(t, exporter.Export(entry), "Unexpected export error")
+	}
+	assert.Contains(t, writer.String(), "from db.pool", "Unexpected output")
+	assert.NotContains(t, writer.String(), "from http", "Unexpected output")
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+
+	_, err = NewStandardExporterOption().UseFilter("name ==").Build()
+	assert.True(t, errors.Is(err, ErrInvalidFilter), "Unexpected create error")
+}
+
+func BenchmarkFilterMatch(b *testing.B) {
+	filter, err := CompileFilter("level >= WARNING && label.env == prod " +
+		"&& (field.status >= 500 || name ~= db.)")
+	if err != nil {
+		b.Fatal(err)
+	}
+	entry := newTestFilterEntry()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		filter.Match(entry)
+	}
+}
+
+func BenchmarkStandardExporterFilter(b *testing.B) {
+	exporter, err := NewStandardExporterOption().
+		UseFilter("level >= ERROR && label.env == prod").Build()
+	if err != nil {
+		b.Fatal(err)
+	}
+	entry := newTestFilterEntry()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		exporter.Export(entry)
+	}
+}
@@ -0,0 +1,166 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"hash/crc32"
+)
+
+var (
+	// ErrUnapprovedAlgorithm represents that an algorithm is not approved
+	// by FIPS 140, and cannot be used because FIPS mode is enabled.
+	ErrUnapprovedAlgorithm = errors.New("algorithm not approved in FIPS mode")
+
+	// ErrUnknownAlgorithm represents that an algorithm is not supported.
+	ErrUnknownAlgorithm = errors.New("unknown algorithm")
+)
+
+// Algorithm represents the name of a cryptographic or checksum algorithm
+// used by the features of the package, such as checksums, encryption and
+// HMAC anonymization.
+type Algorithm string
+
+const (
+	// AlgorithmSHA256 represents the SHA-256 hash algorithm, which is
+	// approved by FIPS 140.
+	AlgorithmSHA256 Algorithm = "sha256"
+
+	// AlgorithmSHA384 represents the SHA-384 hash algorithm, which is
+	// approved by FIPS 140.
+	AlgorithmSHA384 Algorithm = "sha384"
+
+	// AlgorithmSHA512 represents the SHA-512 hash algorithm, which is
+	// approved by FIPS 140.
+	AlgorithmSHA512 Algorithm = "sha512"
+
+	// AlgorithmAESGCM represents the AES-GCM authenticated encryption
+	// algorithm, which is approved by FIPS 140.
+	AlgorithmAESGCM Algorithm = "aes-gcm"
+
+	// AlgorithmCRC32C represents the CRC-32 checksum algorithm with the
+	// Castagnoli polynomial, which is not approved by FIPS 140.
+	AlgorithmCRC32C Algorithm = "crc32c"
+)
+
+// Approved returns true if the algorithm is approved by FIPS 140,
+// otherwise it returns false.
+func (a Algorithm) Approved() bool {
+	switch a {
+	case AlgorithmSHA256, AlgorithmSHA384, AlgorithmSHA512,
+		AlgorithmAESGCM:
+		return true
+	}
+	return false
+}
+
+// FIPSEnabled returns true if FIPS mode is enabled, otherwise it returns
+// false.
+//
+// FIPS mode is enabled if the package is built with the "santafips" build
+// tag (see the FIPSMode constant), or if the Go FIPS 140-3 module is
+// enabled at run time (see the crypto/fips140 package, which requires Go
+// 1.24 or later). In FIPS mode, algorithms that are not approved by FIPS
+// 140 are disabled, and features that use them by default switch to
+// approved algorithms. The approved algorithms are implemented by the
+// standard library, which uses the validated module when it is enabled.
+func FIPSEnabled() bool {
+	return FIPSMode || fipsModuleEnabled()
+}
+
+// CheckAlgorithm checks whether the given algorithm can be used, and then
+// returns ErrUnknownAlgorithm if it is not supported, ErrUnapprovedAlgorithm
+// if it is not approved by FIPS 140 and FIPS mode is enabled, or nil.
+func CheckAlgorithm(algorithm Algorithm) error {
+	switch algorithm {
+	case AlgorithmSHA256, AlgorithmSHA384, AlgorithmSHA512,
+		AlgorithmAESGCM, AlgorithmCRC32C:
+	default:
+		return ErrUnknownAlgorithm
+	}
+	if FIPSEnabled() && !algorithm.Approved() {
+		return ErrUnapprovedAlgorithm
+	}
+	return nil
+}
+
+// checksumAlgorithm returns the default algorithm of checksums, which is
+// CRC-32C unless FIPS mode is enabled.
+func checksumAlgorithm() Algorithm {
+	if FIPSEnabled() {
+		return AlgorithmSHA256
+	}
+	return AlgorithmCRC32C
+}
+
+// newHash creates and returns a hash of the given algorithm, which is an
+// HMAC if the given key is not nil, and then returns any errors
+// encountered.
+func newHash(algorithm Algorithm, key []byte) (hash.Hash, error) {
+	if err := CheckAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	var constructor func() hash.Hash
+	switch algorithm {
+	case AlgorithmSHA256:
+		constructor = sha256.New
+	case AlgorithmSHA384:
+		constructor = sha512.New384
+	case AlgorithmSHA512:
+		constructor = sha512.New
+	case AlgorithmCRC32C:
+		if key != nil {
+			return nil, ErrUnknownAlgorithm
+		}
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli)), nil
+	default:
+		return nil, ErrUnknownAlgorithm
+	}
+	if key != nil {
+		return hmac.New(constructor, key), nil
+	}
+	return constructor(), nil
+}
+
+// newAEAD creates and returns an authenticated encryption cipher of the
+// given algorithm with the given key, and then returns any errors
+// encountered.
+func newAEAD(algorithm Algorithm, key []byte) (cipher.AEAD, error) {
+	if err := CheckAlgorithm(algorithm); err != nil {
+		return nil, err
+	}
+	if algorithm != AlgorithmAESGCM {
+		return nil, ErrUnknownAlgorithm
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,75 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckAlgorithm(t *testing.T) {
+	for _, algorithm := range []Algorithm {
+		AlgorithmSHA256, AlgorithmSHA384, AlgorithmSHA512, AlgorithmAESGCM,
+	} {
+		assert.True(t, algorithm.Approved(), "Unexpected approval")
+		assert.NoError(t, CheckAlgorithm(algorithm), "Unexpected check error")
+	}
+	assert.False(t, AlgorithmCRC32C.Approved(), "Unexpected approval")
+	assert.True(t, errors.Is(CheckAlgorithm("md5"), ErrUnknownAlgorithm),
+		"Unexpected check error")
+
+	if FIPSEnabled() {
+		assert.True(t, errors.Is(CheckAlgorithm(AlgorithmCRC32C),
+			ErrUnapprovedAlgorithm), "Unexpected check error")
+		assert.Equal(t, AlgorithmSHA256, checksumAlgorithm(),
+			"Unexpected checksum algorithm")
+	} else {
+		assert.NoError(t, CheckAlgorithm(AlgorithmCRC32C),
+			"Unexpected check error")
+		assert.Equal(t, AlgorithmCRC32C, checksumAlgorithm(),
+			"Unexpected checksum algorithm")
+	}
+	assert.True(t, FIPSEnabled() || !FIPSMode, "Unexpected FIPS mode")
+}
+
+func TestNewHash(t *testing.T) {
+	hash, err := newHash(AlgorithmSHA256, nil)
+	assert.NoError(t, err, "Unexpected hash error")
+	assert.Equal(t, 32, hash.Size(), "Unexpected hash size")
+	mac, err := newHash(AlgorithmSHA512, []byte("key"))
+	assert.NoError(t, err, "Unexpected hash error")
+	mac.Write([]byte("data"))
+	other, _ := newHash(AlgorithmSHA512, []byte("other"))
+	other.Write([]byte("data"))
+	assert.NotEqual(t, mac.Sum(nil), other.Sum(nil), "Unexpected HMAC")
+	_, err = newHash(AlgorithmAESGCM, nil)
+	assert.True(t, errors.Is(err, ErrUnknownAlgorithm), "Unexpected hash error")
+
+	aead, err := newAEAD(AlgorithmAESGCM, make([]byte, 32))
+	assert.NoError(t, err, "Unexpected cipher error")
+	assert.Equal(t, 12, aead.NonceSize(), "Unexpected nonce size")
+	_, err = newAEAD(AlgorithmAESGCM, make([]byte, 7))
+	assert.Error(t, err, "Unexpected cipher result")
+}
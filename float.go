@@ -0,0 +1,172 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+// ErrNonFiniteFloat represents that a float field of a log entry message
+// is NaN or infinite, and the encoder is configured to reject it. For
+// details, please refer to the NonFiniteError constant.
+var ErrNonFiniteFloat = errors.New("non-finite float value")
+
+// NonFiniteFloat represents how float fields whose values are NaN or
+// infinite are encoded, which are not valid JSON numbers.
+type NonFiniteFloat uint8
+
+const (
+	// NonFiniteNull represents that NaN and infinite values are encoded
+	// as null.
+	NonFiniteNull NonFiniteFloat = iota
+
+	// NonFiniteString represents that NaN and infinite values are encoded
+	// as the strings "NaN", "+Inf" and "-Inf".
+	NonFiniteString
+
+	// NonFiniteError represents that the encoder returns the
+	// ErrNonFiniteFloat error when it encodes a log entry whose message
+	// contains NaN or infinite values. The fields of StructMessage,
+	// LocalizedMessage and CompiledMessage messages are checked, and the
+	// values of other messages are encoded as null.
+	NonFiniteError
+)
+
+// appendFloat appends the given float value of the given bit size to the
+// given buffer slice as a JSON value using the float options of the field
+// option, and then returns the appended buffer slice.
+func (o FieldOption) appendFloat(buffer []byte, value float64, bitSize int) []byte {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		if o.NonFiniteFloats != NonFiniteString {
+			return append(buffer, "null"...)
+		}
+		buffer = append(buffer, '"')
+		buffer = strconv.AppendFloat(buffer, value, 'g', -1, bitSize)
+		return append(buffer, '"')
+	}
+	precision := -1
+	if o.FloatDecimals > 0 {
+		precision = o.FloatDecimals
+	}
+	if threshold := o.FloatScientificThreshold; threshold > 0 &&
+		value != 0 {
+		magnitude := math.Abs(value)
+		if magnitude >= threshold || magnitude < 1 / threshold {
+			return strconv.AppendFloat(buffer, value, 'e', precision,
+				bitSize)
+		}
+	}
+	return strconv.AppendFloat(buffer, value, 'f', precision, bitSize)
+}
+
+// nonFinite returns true if the given value is NaN or infinite, otherwise
+// it returns false.
+func nonFinite(value float64) bool {
+	return math.IsNaN(value) || math.IsInf(value, 0)
+}
+
+// containsNonFinite returns true if any of the values of the element is
+// NaN or infinite, otherwise it returns false.
+func (e ElementFloat32s) containsNonFinite() bool {
+	for _, value := range e {
+		if nonFinite(float64(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsNonFinite returns true if any of the values of the element is
+// NaN or infinite, otherwise it returns false.
+func (e ElementFloat64s) containsNonFinite() bool {
+	for _, value := range e {
+		if nonFinite(value) {
+			return true
+		}
+	}
+	return false
+}
+
+// nonFiniteFields returns true if any of the given fields, including the
+// fields of nested objects, contains NaN or infinite values, otherwise it
+// returns false.
+func nonFiniteFields(fields []Field) bool {
+	for index := range fields {
+		element := fields[index].Element
+		if kept, ok := element.Interface.(elementKept); ok {
+			element = kept.Element
+		}
+		switch element.Type {
+		case TypeFloat32:
+			if nonFinite(float64(math.Float32frombits(uint32(
+				element.Number)))) {
+				return true
+			}
+		case TypeFloat64:
+			if nonFinite(math.Float64frombits(uint64(element.Number))) {
+				return true
+			}
+		case TypeValue:
+			switch value := element.Interface.(type) {
+			case ElementObject:
+				if nonFiniteFields(value) {
+					return true
+				}
+			case interface { containsNonFinite() bool }:
+				if value.containsNonFinite() {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// checkFloats returns the ErrNonFiniteFloat error if the NonFiniteFloats
+// option is the NonFiniteError constant and the fields of the given log
+// entry message contain NaN or infinite values, otherwise it returns nil.
+func (o FieldOption) checkFloats(message Message) error {
+	if o.NonFiniteFloats != NonFiniteError {
+		return nil
+	}
+	var fields []Field
+	switch message := message.(type) {
+	case StructMessage:
+		fields = message.Fields
+	case *StructMessage:
+		if message != nil {
+			fields = message.Fields
+		}
+	case LocalizedMessage:
+		fields = message.Fields
+	case *CompiledMessage:
+		fields = message.message.Fields
+	}
+	if nonFiniteFields(fields) {
+		return ErrNonFiniteFloat
+	}
+	return nil
+}
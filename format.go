@@ -0,0 +1,224 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// templateCacheCapacity represents the maximum number of parsed template
+// strings that are cached. Template strings parsed after the cache is
+// full are not cached, so templates built at runtime cannot grow the
+// cache without bound.
+const templateCacheCapacity = 4096
+
+var (
+	// templateCache caches the parsed template strings, keyed by the
+	// template string.
+	templateCache sync.Map
+
+	// templateCacheSize represents the number of cached template strings.
+	templateCacheSize int64
+)
+
+// templateSegment is a structure that contains a segment of a parsed
+// template string, which is either literal text or a verb.
+type templateSegment struct {
+	text string
+	verb byte
+}
+
+// parsedTemplate is a structure that contains a parsed template string.
+type parsedTemplate struct {
+	segments []templateSegment
+	verbs int
+
+	// formatted represents that the template string uses verbs other than
+	// %s, %d and %v, or verbs with flags, widths or precisions, so it is
+	// formatted by the fmt package.
+	formatted bool
+}
+
+// parseTemplate parses the given template string into segments.
+func parseTemplate(template string) *parsedTemplate {
+	parsed := &parsedTemplate { }
+	start := 0
+	for index := 0; index < len(template); index++ {
+		if template[index] != '%' {
+			continue
+		}
+		if index + 1 >= len(template) {
+			parsed.formatted = true
+			return parsed
+		}
+		if index > start {
+			parsed.segments = append(parsed.segments, templateSegment {
+				text: template[start : index],
+			})
+		}
+		switch verb := template[index + 1]; verb {
+		case 's', 'd', 'v':
+			parsed.segments = append(parsed.segments, templateSegment {
+				verb: verb,
+			})
+			parsed.verbs++
+		case '%':
+			parsed.segments = append(parsed.segments, templateSegment {
+				text: "%",
+			})
+		default:
+			parsed.formatted = true
+			return parsed
+		}
+		index++
+		start = index + 1
+	}
+	if start < len(template) {
+		parsed.segments = append(parsed.segments, templateSegment {
+			text: template[start : ],
+		})
+	}
+	return parsed
+}
+
+// lookupTemplate returns the parsed template string of the given template
+// string, parsing and caching it if it is used for the first time.
+func lookupTemplate(template string) *parsedTemplate {
+	if cached, ok := templateCache.Load(template); ok {
+		return cached.(*parsedTemplate)
+	}
+	parsed := parseTemplate(template)
+	if atomic.LoadInt64(&templateCacheSize) >= templateCacheCapacity {
+		return parsed
+	}
+	cached, loaded := templateCache.LoadOrStore(template, parsed)
+	if !loaded {
+		atomic.AddInt64(&templateCacheSize, 1)
+	}
+	return cached.(*parsedTemplate)
+}
+
+// appendTemplateArg formats the given parameter value with the given verb
+// and appends it to the given buffer slice, and then returns the appended
+// buffer slice. Strings, integers, booleans and floating-point numbers are
+// appended directly, and any other value is formatted by the fmt package,
+// so the result is always the same as fmt.Sprintf.
+func appendTemplateArg(buffer []byte, verb byte, arg interface { }) []byte {
+	switch value := arg.(type) {
+	case string:
+		if verb != 'd' {
+			return append(buffer, value...)
+		}
+	case []byte:
+		if verb == 's' {
+			return append(buffer, value...)
+		}
+	case int:
+		if verb != 's' {
+			return strconv.AppendInt(buffer, int64(value), 10)
+		}
+	case int8:
+		if verb != 's' {
+			return strconv.AppendInt(buffer, int64(value), 10)
+		}
+	case int16:
+		if verb != 's' {
+			return strconv.AppendInt(buffer, int64(value), 10)
+		}
+	case int32:
+		if verb != 's' {
+			return strconv.AppendInt(buffer, int64(value), 10)
+		}
+	case int64:
+		if verb != 's' {
+			return strconv.AppendInt(buffer, value, 10)
+		}
+	case uint:
+		if verb != 's' {
+			return strconv.AppendUint(buffer, uint64(value), 10)
+		}
+	case uint8:
+		if verb != 's' {
+			return strconv.AppendUint(buffer, uint64(value), 10)
+		}
+	case uint16:
+		if verb != 's' {
+			return strconv.AppendUint(buffer, uint64(value), 10)
+		}
+	case uint32:
+		if verb != 's' {
+			return strconv.AppendUint(buffer, uint64(value), 10)
+		}
+	case uint64:
+		if verb != 's' {
+			return strconv.AppendUint(buffer, value, 10)
+		}
+	case bool:
+		if verb == 'v' {
+			return strconv.AppendBool(buffer, value)
+		}
+	case float64:
+		if verb == 'v' {
+			return strconv.AppendFloat(buffer, value, 'g', -1, 64)
+		}
+	case float32:
+		if verb == 'v' {
+			return strconv.AppendFloat(buffer, float64(value), 'g', -1, 32)
+		}
+	}
+	switch verb {
+	case 's':
+		return fmt.Appendf(buffer, "%s", arg)
+	case 'd':
+		return fmt.Appendf(buffer, "%d", arg)
+	default:
+		return fmt.Appendf(buffer, "%v", arg)
+	}
+}
+
+// appendTemplate formats the given template string with the given
+// parameter values and appends it to the given buffer slice, and then
+// returns the appended buffer slice. The result is the same as
+// fmt.Sprintf, but the parsed template string is cached, and %s, %d and
+// %v are appended without the fmt package whenever possible.
+func appendTemplate(buffer []byte, template string,
+	args []interface { }) []byte {
+	parsed := lookupTemplate(template)
+	if parsed.formatted || parsed.verbs != len(args) {
+		return fmt.Appendf(buffer, template, args...)
+	}
+	index := 0
+	for _, segment := range parsed.segments {
+		if segment.verb == 0 {
+			buffer = append(buffer, segment.text...)
+			continue
+		}
+		buffer = appendTemplateArg(buffer, segment.verb, args[index])
+		index++
+	}
+	return buffer
+}
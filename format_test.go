@@ -0,0 +1,77 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testFormatStringer int
+
+func (s testFormatStringer) String() string {
+	return "stringer"
+}
+
+func TestAppendTemplate(t *testing.T) {
+	values := []interface { } { "text", []byte("bytes"), 42, int8(-8),
+		int64(math.MinInt64), uint(7), uint64(math.MaxUint64), true, 3.25,
+		float32(0.1), math.Inf(-1), nil, errors.New("failed"),
+		testFormatStringer(1), time.Second, []int { 1, 2 },
+		struct { A int } { 1 } }
+	for _, template := range []string { "%s", "%d", "%v", "a %v b",
+		"100%% %v", "%5d", "%q", "%v%", "" } {
+		for _, value := range values {
+			assert.Equal(t, fmt.Sprintf(template, value),
+				string(appendTemplate(nil, template, []interface { } {
+					value })), "Unexpected format of %q with %#v",
+				template, value)
+		}
+	}
+	for template, args := range map[string][]interface { } {
+		"%s %s": { "a" },
+		"%s": { "a", "b" },
+	} {
+		assert.Equal(t, fmt.Sprintf(template, args...),
+			string(appendTemplate(nil, template, args)),
+			"Unexpected format of %q", template)
+	}
+
+	cached, ok := templateCache.Load("a %v b")
+	assert.True(t, ok, "Unexpected cache miss")
+	assert.Len(t, cached.(*parsedTemplate).segments, 3,
+		"Unexpected parsed template")
+	assert.True(t, lookupTemplate("%5d").formatted,
+		"Unexpected parsed template")
+
+	message := TemplateMessage { Template: "user %s logged in %d times",
+		Args: []interface { } { "alice", 3 } }
+	assert.Equal(t, `"user alice logged in 3 times"`,
+		string(message.SerializeJSON(nil)), "Unexpected serialization")
+}
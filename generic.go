@@ -0,0 +1,295 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"reflect"
+	"strconv"
+	"unsafe"
+)
+
+// Signed is a constraint that permits any signed integer type.
+type Signed interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64
+}
+
+// Unsigned is a constraint that permits any unsigned integer type.
+type Unsigned interface {
+	~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~uintptr
+}
+
+// Float is a constraint that permits any floating-point type.
+type Float interface {
+	~float32 | ~float64
+}
+
+// Primitive is a constraint that permits any data type that can be
+// stored in an element without using the interface container, including
+// all integer types, floating-point types, boolean types and string types.
+type Primitive interface {
+	Signed | Unsigned | Float | ~bool | ~string
+}
+
+// F returns the value of a field with a given name and a given value of
+// any primitive data type. It is a generic alternative to the Int, Uint,
+// Float32, Float64, Boolean and String functions, and the data type of the
+// element is determined by the data type of the given value.
+//
+// Unlike the Value function, the given value is never stored in the
+// interface container, so no heap memory is allocated for it. For details,
+// see the comments section of the Field structure.
+func F[T Primitive](name string, value T) Field {
+	switch v := any(value).(type) {
+	case int:
+		return Int(name, int64(v))
+	case int8:
+		return Int(name, int64(v))
+	case int16:
+		return Int(name, int64(v))
+	case int32:
+		return Int(name, int64(v))
+	case int64:
+		return Int(name, v)
+	case uint:
+		return Uint(name, uint64(v))
+	case uint8:
+		return Uint(name, uint64(v))
+	case uint16:
+		return Uint(name, uint64(v))
+	case uint32:
+		return Uint(name, uint64(v))
+	case uint64:
+		return Uint(name, v)
+	case uintptr:
+		return Uint(name, uint64(v))
+	case float32:
+		return Float32(name, v)
+	case float64:
+		return Float64(name, v)
+	case bool:
+		return Boolean(name, v)
+	case string:
+		return String(name, v)
+	}
+
+	// The given value is of a defined type whose underlying type is a
+	// primitive data type, such as time.Duration. The kind is read from the
+	// type instead of the value, and the value is read through a pointer,
+	// so that the value is not stored in the interface container.
+	pointer := unsafe.Pointer(&value)
+	switch reflect.TypeOf((*T)(nil)).Elem().Kind() {
+	case reflect.Int:
+		return Int(name, int64(*(*int)(pointer)))
+	case reflect.Int8:
+		return Int(name, int64(*(*int8)(pointer)))
+	case reflect.Int16:
+		return Int(name, int64(*(*int16)(pointer)))
+	case reflect.Int32:
+		return Int(name, int64(*(*int32)(pointer)))
+	case reflect.Int64:
+		return Int(name, *(*int64)(pointer))
+	case reflect.Uint:
+		return Uint(name, uint64(*(*uint)(pointer)))
+	case reflect.Uint8:
+		return Uint(name, uint64(*(*uint8)(pointer)))
+	case reflect.Uint16:
+		return Uint(name, uint64(*(*uint16)(pointer)))
+	case reflect.Uint32:
+		return Uint(name, uint64(*(*uint32)(pointer)))
+	case reflect.Uint64:
+		return Uint(name, *(*uint64)(pointer))
+	case reflect.Uintptr:
+		return Uint(name, uint64(*(*uintptr)(pointer)))
+	case reflect.Float32:
+		return Float32(name, *(*float32)(pointer))
+	case reflect.Float64:
+		return Float64(name, *(*float64)(pointer))
+	case reflect.Bool:
+		return Boolean(name, *(*bool)(pointer))
+	default:
+		return String(name, *(*string)(pointer))
+	}
+}
+
+// ElementSigneds represents an element data type whose native data type
+// is a slice of any signed integer type. For details, please refer to the
+// comment section of the Element structure.
+type ElementSigneds[T Signed] []T
+
+// SerializeJSON serializes the element into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (e ElementSigneds[T]) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, '[')
+	tail := len(e) - 1
+	for index := 0; index < len(e); index++ {
+		buffer = strconv.AppendInt(buffer, int64(e[index]), 10)
+		if index < tail {
+			buffer = append(buffer, ", "...)
+		}
+	}
+	return append(buffer, ']')
+}
+
+// Signeds returns the value of a field with a given name and a given
+// slice of any signed integer type. Unlike the Ints function, the given
+// slice does not need to be converted to []int64. For details, see the
+// comments section of the Field structure.
+func Signeds[T Signed](name string, values []T) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: ElementSigneds[T](values),
+		},
+		Name: name,
+	}
+}
+
+// ElementUnsigneds represents an element data type whose native data
+// type is a slice of any unsigned integer type. For details, please refer
+// to the comment section of the Element structure.
+type ElementUnsigneds[T Unsigned] []T
+
+// SerializeJSON serializes the element into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (e ElementUnsigneds[T]) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, '[')
+	tail := len(e) - 1
+	for index := 0; index < len(e); index++ {
+		buffer = strconv.AppendUint(buffer, uint64(e[index]), 10)
+		if index < tail {
+			buffer = append(buffer, ", "...)
+		}
+	}
+	return append(buffer, ']')
+}
+
+// Unsigneds returns the value of a field with a given name and a given
+// slice of any unsigned integer type. Unlike the Uints function, the given
+// slice does not need to be converted to []uint64. For details, see the
+// comments section of the Field structure.
+func Unsigneds[T Unsigned](name string, values []T) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: ElementUnsigneds[T](values),
+		},
+		Name: name,
+	}
+}
+
+// ElementFloats represents an element data type whose native data type
+// is a slice of any floating-point type. For details, please refer to the
+// comment section of the Element structure.
+type ElementFloats[T Float] []T
+
+// SerializeJSON serializes the element into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (e ElementFloats[T]) SerializeJSON(buffer []byte) []byte {
+	return e.serializeJSONWith(buffer, FieldOption { })
+}
+
+// serializeJSONWith serializes the element into a JSON string using the
+// float options of the given field option and appends it to the given
+// buffer slice, and then returns the appended buffer slice.
+func (e ElementFloats[T]) serializeJSONWith(buffer []byte, option FieldOption) []byte {
+	var zero T
+	size := int(unsafe.Sizeof(zero)) * 8
+	buffer = append(buffer, '[')
+	tail := len(e) - 1
+	for index := 0; index < len(e); index++ {
+		buffer = option.appendFloat(buffer, float64(e[index]), size)
+		if index < tail {
+			buffer = append(buffer, ", "...)
+		}
+	}
+	return append(buffer, ']')
+}
+
+// containsNonFinite returns true if any of the values of the element is
+// NaN or infinite, otherwise it returns false.
+func (e ElementFloats[T]) containsNonFinite() bool {
+	for _, value := range e {
+		if nonFinite(float64(value)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Floats returns the value of a field with a given name and a given
+// slice of any floating-point type. For details, see the comments section
+// of the Field structure.
+func Floats[T Float](name string, values []T) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: ElementFloats[T](values),
+		},
+		Name: name,
+	}
+}
+
+// Slice returns the value of a field with a given name and a given slice
+// of any primitive data type. Each member of the slice is converted to an
+// element using the F function. For details, see the comments section of
+// the Field structure.
+//
+// Please note that this function allocates a new element slice. If the
+// data type of the members is known, using the Signeds, Unsigneds, Floats,
+// Booleans or Strings function is more efficient.
+func Slice[T Primitive](name string, values []T) Field {
+	elements := make(ElementSlice, len(values))
+	for index := 0; index < len(values); index++ {
+		elements[index] = F("", values[index]).Element
+	}
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: elements,
+		},
+		Name: name,
+	}
+}
+
+// ElementSlice represents an element data type whose native data type
+// is []Element. For details, please refer to the comment section of the
+// Element structure.
+type ElementSlice []Element
+
+// SerializeJSON serializes the element into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (e ElementSlice) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, '[')
+	tail := len(e) - 1
+	for index := 0; index < len(e); index++ {
+		buffer = e[index].SerializeJSON(buffer)
+		if index < tail {
+			buffer = append(buffer, ", "...)
+		}
+	}
+	return append(buffer, ']')
+}
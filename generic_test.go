@@ -0,0 +1,148 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testName string
+
+func TestGenericField(t *testing.T) {
+	buffer := make([]byte, 0, 256)
+
+	for _, sample := range []struct {
+		name string
+		field Field
+		expected string
+		elementType ElementType
+	} {
+		{
+			name: "int",
+			field: F("int", 10),
+			expected: "10",
+			elementType: TypeInt,
+		},
+		{
+			name: "int8",
+			field: F("int8", int8(-8)),
+			expected: "-8",
+			elementType: TypeInt,
+		},
+		{
+			name: "uint16",
+			field: F("uint16", uint16(16)),
+			expected: "16",
+			elementType: TypeUint,
+		},
+		{
+			name: "float32",
+			field: F("float32", float32(3.14)),
+			expected: "3.14",
+			elementType: TypeFloat32,
+		},
+		{
+			name: "float64",
+			field: F("float64", 3.1415),
+			expected: "3.1415",
+			elementType: TypeFloat64,
+		},
+		{
+			name: "boolean",
+			field: F("boolean", true),
+			expected: "true",
+			elementType: TypeBoolean,
+		},
+		{
+			name: "string",
+			field: F("string", "Hello"),
+			expected: "\"Hello\"",
+			elementType: TypeString,
+		},
+		{
+			name: "duration",
+			field: F("duration", time.Second),
+			expected: "1000000000",
+			elementType: TypeInt,
+		},
+		{
+			name: "defined",
+			field: F("defined", testName("Hello")),
+			expected: "\"Hello\"",
+			elementType: TypeString,
+		},
+	} {
+		assert.Equal(t, sample.name, sample.field.Name,
+			"Unexpected field name")
+		assert.Equal(t, sample.elementType, sample.field.Type,
+			"Unexpected element type")
+		assert.Equal(t, sample.expected, string(
+			sample.field.SerializeJSON(buffer[ : 0])),
+			"Unexpected JSON formatted append result")
+	}
+
+	var field Field
+	allocs := testing.AllocsPerRun(100, func() {
+		field = F("duration", time.Second)
+		field = F("defined", testName("Hello"))
+	})
+	assert.Zero(t, allocs, "Unexpected allocations")
+	assert.Equal(t, "defined", field.Name, "Unexpected field name")
+}
+
+func TestGenericSliceField(t *testing.T) {
+	buffer := make([]byte, 0, 256)
+
+	for _, sample := range []struct {
+		field Field
+		expected string
+	} {
+		{
+			field: Signeds("signeds", []int32 { -1, 2 }),
+			expected: "[-1, 2]",
+		},
+		{
+			field: Unsigneds("unsigneds", []uint8 { 1, 2 }),
+			expected: "[1, 2]",
+		},
+		{
+			field: Floats("floats", []float32 { 3.14, 1.5 }),
+			expected: "[3.14, 1.5]",
+		},
+		{
+			field: Slice("slice", []testName { "Hello", "Test" }),
+			expected: "[\"Hello\", \"Test\"]",
+		},
+		{
+			field: Signeds("empty", []int(nil)),
+			expected: "[]",
+		},
+	} {
+		assert.Equal(t, sample.expected, string(
+			sample.field.SerializeJSON(buffer[ : 0])),
+			"Unexpected JSON formatted append result")
+	}
+}
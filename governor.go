@@ -0,0 +1,275 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// VolumeGovernor is the structure of a volume governor instance.
+//
+// The volume governor counts the bytes of log entry data written by the
+// loggers that use it during each period (for example: each hour or each
+// day), and keeps the volume within a budget, which is useful when the
+// ingestion of log entry data is billed by volume. Each time the bytes
+// written during the current period exceed another multiple of the budget,
+// the effective minimum level of the loggers is raised by one level (for
+// example: DEBUG to INFO, then INFO to WARNING), up to the ceiling level.
+// The minimum level is restored at the start of the next period. Periods
+// are aligned to multiples of the period since the zero time (for
+// example: to the hour or to midnight UTC).
+//
+// Each time the minimum level is raised or restored, the logger outputs a
+// governance log entry with a log level of WARNING, whose fields describe
+// the minimum level, the bytes written and the budget.
+//
+// The API provided by the volume governor is thread-safe. A volume governor
+// can be shared by multiple loggers, in which case they share the budget.
+type VolumeGovernor struct {
+	emitted uint64
+	start int64
+	announced int32
+
+	period int64
+	budget uint64
+	ceiling Level
+}
+
+// governorNotice is a structure containing a change of the minimum level
+// imposed by the volume governor, which is announced by a governance log
+// entry.
+type governorNotice struct {
+	raised bool
+	steps int32
+	emitted uint64
+}
+
+// Record adds the given number of bytes to the bytes written during the
+// current period.
+func (g *VolumeGovernor) Record(size int) {
+	if size > 0 {
+		atomic.AddUint64(&g.emitted, uint64(size))
+	}
+}
+
+// Emitted returns the number of bytes written during the current period.
+func (g *VolumeGovernor) Emitted() uint64 {
+	return atomic.LoadUint64(&g.emitted)
+}
+
+// steps returns the number of levels by which the minimum level is raised
+// for the given number of bytes written.
+func (g *VolumeGovernor) steps(emitted uint64) int32 {
+	steps := emitted / g.budget
+	if steps > uint64(LevelFatal) {
+		steps = uint64(LevelFatal)
+	}
+	return int32(steps)
+}
+
+// Level returns the effective minimum level for the given minimum level of
+// a logger.
+func (g *VolumeGovernor) Level(level Level) Level {
+	if level >= g.ceiling {
+		return level
+	}
+	raised := level + Level(atomic.LoadInt32(&g.announced))
+	if raised > g.ceiling {
+		return g.ceiling
+	}
+	return raised
+}
+
+// evaluate starts a new period if the current period has ended at the
+// given time, and then checks whether the minimum level has changed since
+// it was last announced. If so, it returns the change to be announced,
+// otherwise it returns nil.
+func (g *VolumeGovernor) evaluate(now time.Time) *governorNotice {
+	start := atomic.LoadInt64(&g.start)
+	if current := now.Truncate(time.Duration(g.period)).UnixNano();
+		current > start && atomic.CompareAndSwapInt64(&g.start, start,
+		current) {
+		// Using subtraction to reset the counter avoids losing the bytes
+		// written concurrently.
+		emitted := atomic.LoadUint64(&g.emitted)
+		atomic.AddUint64(&g.emitted, -emitted)
+		if atomic.SwapInt32(&g.announced, 0) > 0 {
+			return &governorNotice {
+				emitted: emitted,
+			}
+		}
+		return nil
+	}
+	emitted := atomic.LoadUint64(&g.emitted)
+	steps := g.steps(emitted)
+	announced := atomic.LoadInt32(&g.announced)
+	if steps > announced &&
+		atomic.CompareAndSwapInt32(&g.announced, announced, steps) {
+		return &governorNotice {
+			raised: true,
+			steps: steps,
+			emitted: emitted,
+		}
+	}
+	return nil
+}
+
+// message returns the message of the governance log entry announcing the
+// given change for a logger with the given minimum level.
+func (g *VolumeGovernor) message(notice *governorNotice, level Level) Message {
+	text := "Log volume budget period renewed, minimum level restored."
+	if notice.raised {
+		text = "Log volume budget exceeded, minimum level raised."
+	}
+	return StructMessage {
+		Text: text,
+		Fields: ElementObject {
+			String("level", g.Level(level).String()),
+			Uint("emitted", notice.emitted),
+			Uint("budget", g.budget),
+			String("period", time.Duration(g.period).String()),
+		},
+	}
+}
+
+// Wrap returns a synchronizer that writes to the given synchronizer and
+// records the number of bytes written to it into the volume governor.
+func (g *VolumeGovernor) Wrap(syncer Syncer) Syncer {
+	return &governedSyncer {
+		Syncer: syncer,
+		governor: g,
+	}
+}
+
+// governedSyncer is the structure of a synchronizer that records the
+// number of bytes written into a volume governor.
+type governedSyncer struct {
+	Syncer
+
+	governor *VolumeGovernor
+}
+
+// Write writes the given buffer to the underlying synchronizer, and then
+// records the number of bytes written.
+func (s *governedSyncer) Write(buffer []byte) (int, error) {
+	size, err := s.Syncer.Write(buffer)
+	s.governor.Record(size)
+	return size, err
+}
+
+// Flush flushes the internal cache of the underlying synchronizer, if it
+// implements the Flusher interface.
+func (s *governedSyncer) Flush() error {
+	if flusher, ok := s.Syncer.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Buffered returns the size of the internal cache of the underlying
+// synchronizer, if it implements the BufferedSyncer interface.
+func (s *governedSyncer) Buffered() int {
+	if buffered, ok := s.Syncer.(BufferedSyncer); ok {
+		return buffered.Buffered()
+	}
+	return 0
+}
+
+// VolumeGovernorOption is a structure that contains options for the volume
+// governor.
+type VolumeGovernorOption struct {
+	// Period represents the length of each budget period. If not provided,
+	// the default value is 1 hour.
+	Period time.Duration
+
+	// Budget represents the number of bytes of log entry data allowed to
+	// be written during each period before the minimum level is raised.
+	// This option is required.
+	Budget uint64
+
+	// Ceiling represents the highest minimum level the volume governor
+	// raises the minimum level to, so that log entries of higher levels
+	// are always output. If not provided, the default value is WARNING.
+	Ceiling Level
+}
+
+// UsePeriod uses the given period as the value of the option Period. Then
+// return to the option instance itself.
+func (o *VolumeGovernorOption) UsePeriod(period time.Duration) *VolumeGovernorOption {
+	o.Period = period
+	return o
+}
+
+// UseBudget uses the given number of bytes as the value of the option
+// Budget. Then return to the option instance itself.
+func (o *VolumeGovernorOption) UseBudget(budget uint64) *VolumeGovernorOption {
+	o.Budget = budget
+	return o
+}
+
+// UseCeiling uses the given log level as the value of the option Ceiling.
+// Then return to the option instance itself.
+func (o *VolumeGovernorOption) UseCeiling(level Level) *VolumeGovernorOption {
+	o.Ceiling = level
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *VolumeGovernorOption) Validate() error {
+	if o.Period <= 0 {
+		return newOptionError("Period", "must be greater than 0", nil)
+	}
+	if o.Budget == 0 {
+		return newOptionError("Budget", "must be greater than 0", nil)
+	}
+	if o.Ceiling > LevelFatal {
+		return newOptionError("Ceiling", "invalid level", ErrInvalidLevel)
+	}
+	return nil
+}
+
+// Build builds and returns a volume governor instance.
+func (o *VolumeGovernorOption) Build() (*VolumeGovernor, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &VolumeGovernor {
+		start: time.Now().Truncate(o.Period).UnixNano(),
+		period: int64(o.Period),
+		budget: o.Budget,
+		ceiling: o.Ceiling,
+	}, nil
+}
+
+// NewVolumeGovernorOption creates and returns a volume governor option
+// instance with default option values.
+func NewVolumeGovernorOption() *VolumeGovernorOption {
+	return &VolumeGovernorOption {
+		Period: time.Hour,
+		Ceiling: LevelWarning,
+	}
+}
@@ -0,0 +1,119 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVolumeGovernor(t *testing.T) {
+	_, err := NewVolumeGovernorOption().Build()
+	assert.Error(t, err, "Unexpected create error")
+
+	governor, err := NewVolumeGovernorOption().UseBudget(1000).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableFlushing().DisableCache().
+		DisableSampling().UseGovernor(governor)
+	option.Outputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	defer logger.Close()
+
+	for index := 0; index < 20; index++ {
+		assert.NoError(t, logger.Debug(StringMessage("debug entry")),
+			"Unexpected print error")
+	}
+	assert.Equal(t, LevelInfo, governor.Level(LevelDebug),
+		"Unexpected effective level")
+	output := writer.String()
+	assert.Contains(t, output, "Log volume budget exceeded",
+		"Unexpected output data")
+	assert.Less(t, strings.Count(output, "debug entry"), 20,
+		"Unexpected output data")
+
+	count := strings.Count(output, "debug entry")
+	assert.NoError(t, logger.Debug(StringMessage("debug entry")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Info(StringMessage("info entry")),
+		"Unexpected print error")
+	output = writer.String()
+	assert.Equal(t, count, strings.Count(output, "debug entry"),
+		"Unexpected output data")
+	assert.Contains(t, output, "info entry", "Unexpected output data")
+
+	// Simulate the start of the next period.
+	atomic.StoreInt64(&governor.start, 0)
+	assert.NoError(t, logger.Debug(StringMessage("debug entry")),
+		"Unexpected print error")
+	output = writer.String()
+	assert.Contains(t, output, "minimum level restored",
+		"Unexpected output data")
+	assert.Equal(t, count + 1, strings.Count(output, "debug entry"),
+		"Unexpected output data")
+	assert.Equal(t, LevelDebug, governor.Level(LevelDebug),
+		"Unexpected effective level")
+	assert.Equal(t, LevelError, governor.Level(LevelError),
+		"Unexpected effective level")
+}
+
+func TestVolumeGovernorTimeSource(t *testing.T) {
+	governor, err := NewVolumeGovernorOption().UseBudget(1000).
+		UsePeriod(time.Hour).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	now := time.Now().UnixNano()
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableFlushing().DisableCache().
+		DisableSampling().UseGovernor(governor).
+		UseTimeSource(func() time.Time {
+			return time.Unix(0, atomic.LoadInt64(&now))
+		})
+	option.Outputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	defer logger.Close()
+
+	for index := 0; index < 20; index++ {
+		assert.NoError(t, logger.Debug(StringMessage("debug entry")),
+			"Unexpected print error")
+	}
+	assert.Equal(t, LevelInfo, governor.Level(LevelDebug),
+		"Unexpected effective level")
+
+	// The next period starts by the time source of the logger.
+	atomic.AddInt64(&now, int64(time.Hour))
+	assert.NoError(t, logger.Debug(StringMessage("debug entry")),
+		"Unexpected print error")
+	assert.Contains(t, writer.String(), "minimum level restored",
+		"Unexpected output data")
+	assert.Equal(t, LevelDebug, governor.Level(LevelDebug),
+		"Unexpected effective level")
+}
@@ -0,0 +1,178 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// histogramSubBits is the number of bits of precision of each bucket
+	// of the latency histogram, which bounds the relative error of the
+	// recorded values to 1 / 64.
+	histogramSubBits = 6
+
+	// histogramSubBuckets is the number of sub buckets in each power of
+	// two range of the latency histogram.
+	histogramSubBuckets = 1 << histogramSubBits
+
+	// histogramBuckets is the total number of buckets of the latency
+	// histogram, which covers all values of uint64.
+	histogramBuckets = (64 - histogramSubBits + 1) * histogramSubBuckets
+)
+
+// LatencyHistogram is the structure of a latency histogram instance.
+//
+// The latency histogram records durations into HDR-style log-linear
+// buckets: durations less than 64 nanoseconds are recorded exactly, and
+// larger durations are recorded with a relative error of at most 1 / 64.
+// This allows the tail latency (for example: p99.9) of a large number of
+// recorded durations to be calculated with a fixed amount of memory.
+//
+// The API provided by the latency histogram is thread-safe. Values that
+// are recorded concurrently with a query may or may not be included in
+// the query result.
+type LatencyHistogram struct {
+	count uint64
+	sum uint64
+	max uint64
+	buckets [histogramBuckets]uint64
+}
+
+// histogramIndex returns the index of the bucket of the given value.
+func histogramIndex(value uint64) int {
+	if value < histogramSubBuckets {
+		return int(value)
+	}
+	shift := bits.Len64(value) - histogramSubBits - 1
+	mantissa := value >> uint(shift)
+	return (shift + 1) * histogramSubBuckets + int(mantissa) -
+		histogramSubBuckets
+}
+
+// histogramUpperBound returns the largest value recorded into the bucket
+// of the given index.
+func histogramUpperBound(index int) uint64 {
+	if index < histogramSubBuckets {
+		return uint64(index)
+	}
+	shift := uint(index / histogramSubBuckets - 1)
+	mantissa := uint64(index % histogramSubBuckets + histogramSubBuckets)
+	return ((mantissa + 1) << shift) - 1
+}
+
+// Record records the given duration. Negative durations are recorded as
+// zero.
+func (h *LatencyHistogram) Record(duration time.Duration) {
+	var value uint64
+	if duration > 0 {
+		value = uint64(duration)
+	}
+	atomic.AddUint64(&h.buckets[histogramIndex(value)], 1)
+	atomic.AddUint64(&h.count, 1)
+	atomic.AddUint64(&h.sum, value)
+	for {
+		max := atomic.LoadUint64(&h.max)
+		if value <= max || atomic.CompareAndSwapUint64(&h.max, max, value) {
+			return
+		}
+	}
+}
+
+// Count returns the number of recorded durations.
+func (h *LatencyHistogram) Count() uint64 {
+	return atomic.LoadUint64(&h.count)
+}
+
+// Sum returns the sum of the recorded durations.
+func (h *LatencyHistogram) Sum() time.Duration {
+	return time.Duration(atomic.LoadUint64(&h.sum))
+}
+
+// Max returns the largest recorded duration.
+func (h *LatencyHistogram) Max() time.Duration {
+	return time.Duration(atomic.LoadUint64(&h.max))
+}
+
+// Mean returns the mean of the recorded durations, or 0 if no duration
+// has been recorded.
+func (h *LatencyHistogram) Mean() time.Duration {
+	count := atomic.LoadUint64(&h.count)
+	if count == 0 {
+		return 0
+	}
+	return time.Duration(atomic.LoadUint64(&h.sum) / count)
+}
+
+// Quantile returns the duration below which the given quantile (from 0 to
+// 1, for example: 0.999 for p99.9) of the recorded durations fall, or 0 if
+// no duration has been recorded. The returned duration is the upper bound
+// of its bucket, and is never greater than the largest recorded duration.
+func (h *LatencyHistogram) Quantile(quantile float64) time.Duration {
+	count := atomic.LoadUint64(&h.count)
+	if count == 0 {
+		return 0
+	}
+	if quantile < 0 {
+		quantile = 0
+	}
+	if quantile > 1 {
+		quantile = 1
+	}
+	target := uint64(math.Ceil(quantile * float64(count)))
+	if target == 0 {
+		target = 1
+	}
+	max := atomic.LoadUint64(&h.max)
+	var total uint64
+	for index := 0; index < histogramBuckets; index++ {
+		total += atomic.LoadUint64(&h.buckets[index])
+		if total >= target {
+			if bound := histogramUpperBound(index); bound < max {
+				return time.Duration(bound)
+			}
+			break
+		}
+	}
+	return time.Duration(max)
+}
+
+// Reset discards all recorded durations. Values that are recorded
+// concurrently with the reset may be partially discarded.
+func (h *LatencyHistogram) Reset() {
+	for index := 0; index < histogramBuckets; index++ {
+		atomic.StoreUint64(&h.buckets[index], 0)
+	}
+	atomic.StoreUint64(&h.count, 0)
+	atomic.StoreUint64(&h.sum, 0)
+	atomic.StoreUint64(&h.max, 0)
+}
+
+// NewLatencyHistogram creates and returns an empty latency histogram
+// instance.
+func NewLatencyHistogram() *LatencyHistogram {
+	return &LatencyHistogram { }
+}
@@ -0,0 +1,80 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatencyHistogram(t *testing.T) {
+	histogram := NewLatencyHistogram()
+	assert.Equal(t, time.Duration(0), histogram.Quantile(0.99),
+		"Unexpected quantile")
+	assert.Equal(t, time.Duration(0), histogram.Mean(), "Unexpected mean")
+
+	for value := 1; value <= 1000; value++ {
+		histogram.Record(time.Duration(value) * time.Microsecond)
+	}
+	histogram.Record(-time.Second)
+	assert.Equal(t, uint64(1001), histogram.Count(), "Unexpected count")
+	assert.Equal(t, time.Millisecond, histogram.Max(), "Unexpected max")
+	assert.Equal(t, time.Millisecond, histogram.Quantile(1),
+		"Unexpected quantile")
+	assert.Equal(t, time.Duration(0), histogram.Quantile(0),
+		"Unexpected quantile")
+	assert.InEpsilon(t, float64(500 * time.Microsecond),
+		float64(histogram.Quantile(0.5)), 1.0 / 64, "Unexpected quantile")
+	assert.InEpsilon(t, float64(990 * time.Microsecond),
+		float64(histogram.Quantile(0.99)), 1.0 / 64, "Unexpected quantile")
+
+	for value := uint64(0); value < 1 << 20; value = value * 3 + 1 {
+		index := histogramIndex(value)
+		assert.True(t, value <= histogramUpperBound(index),
+			"Unexpected bucket of %d", value)
+		if index > 0 {
+			assert.True(t, value > histogramUpperBound(index - 1),
+				"Unexpected bucket of %d", value)
+		}
+	}
+	assert.Less(t, histogramIndex(^uint64(0)), histogramBuckets,
+		"Unexpected bucket index")
+
+	histogram.Reset()
+	assert.Equal(t, uint64(0), histogram.Count(), "Unexpected count")
+
+	logger, err := NewStructBenchmark(false, EncoderJSON, histogram)
+	assert.NoError(t, err, "Unexpected create error")
+	for count := 0; count < 100; count++ {
+		assert.NoError(t, logger.Infos("Hello Test!"),
+			"Unexpected print error")
+	}
+	copied := logger.Duplicate()
+	assert.NoError(t, copied.Infos("Hello Test!"), "Unexpected print error")
+	assert.Equal(t, uint64(101), histogram.Count(), "Unexpected count")
+	assert.True(t, histogram.Quantile(0.999) > 0, "Unexpected quantile")
+	assert.NoError(t, copied.Close(), "Unexpected close error")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+}
@@ -22,6 +22,16 @@
 
 package santa
 
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
 // Hook is the public interface of Hook.
 //
 // Hook is an event callback mechanism. Any Hook type instance that
@@ -72,3 +82,911 @@ func NewSimpleHook(handler SimpleHookHandler) *SimpleHook {
 func (h *SimpleHook) Print(entry *Entry) error {
 	return h.handler(entry)
 }
+
+// FingerprintHook is the structure of the fingerprint Hook instance.
+//
+// The fingerprint Hook computes a stable fingerprint for each log entry
+// within the level span from the types of the errors in the message, the
+// trimmed call stack and the message template, and then attaches it to the
+// log entry message as a field. Downstream systems can use the fingerprint
+// to group identical errors across hosts.
+//
+// The fingerprint does not depend on the values of the message arguments,
+// the source file paths or the line numbers, so it stays the same across
+// hosts and minor code changes. Messages that are not structured messages
+// are converted to structured messages that contain the formatted text.
+type FingerprintHook struct {
+	span LevelSpan
+	depth int
+	name string
+}
+
+// fingerprintPrefix represents the package path prefix of the functions
+// that are trimmed from the call stack.
+const fingerprintPrefix = "github.com/nobody-night/santa"
+
+// fingerprintHash is a FNV64-A hash state used to compute fingerprints.
+type fingerprintHash uint64
+
+// write writes the given text to the hash state, and then returns the new
+// hash state. A zero byte is written after the text to separate it from
+// the next text.
+func (h fingerprintHash) write(text string) fingerprintHash {
+	for index := 0; index < len(text); index++ {
+		h ^= fingerprintHash(text[index])
+		h *= 1099511628211
+	}
+	h *= 1099511628211
+	return h
+}
+
+// writeError writes the type of the given error to the hash state, and
+// then returns the new hash state.
+func (h fingerprintHash) writeError(err error) fingerprintHash {
+	if err == nil {
+		return h
+	}
+	return h.write(reflect.TypeOf(err).String())
+}
+
+// Fingerprint computes and returns the fingerprint of the given log entry
+// as a hexadecimal string.
+func (h *FingerprintHook) Fingerprint(entry *Entry) string {
+	hash := fingerprintHash(14695981039346656037)
+
+	switch message := entry.Message.(type) {
+	case *StructMessage:
+		hash = h.writeFields(hash, message.Fields)
+	case StructMessage:
+		hash = h.writeFields(hash, message.Fields)
+	case *TemplateMessage:
+		hash = h.writeArgs(hash, message.Args)
+	case TemplateMessage:
+		hash = h.writeArgs(hash, message.Args)
+	}
+	if parser, ok := entry.Message.(TextSampleParser); ok {
+		hash = hash.write(parser.SampleText())
+	}
+
+	var callers [32]uintptr
+	count := runtime.Callers(2, callers[ : ])
+	frames := runtime.CallersFrames(callers[ : count])
+	for depth := 0; depth < h.depth; {
+		frame, more := frames.Next()
+		if !h.trimmed(frame.Function) {
+			hash = hash.write(frame.Function)
+			depth++
+		}
+		if !more {
+			break
+		}
+	}
+
+	return strconv.FormatUint(uint64(hash), 16)
+}
+
+// trimmed checks whether the given function needs to be trimmed from the
+// call stack. It returns true if needed, otherwise it returns false.
+func (*FingerprintHook) trimmed(function string) bool {
+	if strings.HasPrefix(function, "runtime.") {
+		return true
+	}
+	if !strings.HasPrefix(function, fingerprintPrefix) {
+		return false
+	}
+	function = function[len(fingerprintPrefix) : ]
+	return len(function) > 0 && (function[0] == '.' || function[0] == '/')
+}
+
+// writeFields writes the types of the errors in the given fields to the
+// hash state, and then returns the new hash state.
+func (*FingerprintHook) writeFields(hash fingerprintHash, fields []Field) fingerprintHash {
+	for index := 0; index < len(fields); index++ {
+		switch value := fields[index].Interface.(type) {
+		case error:
+			hash = hash.writeError(value)
+		case ElementErrors:
+			for _, err := range value {
+				hash = hash.writeError(err)
+			}
+		}
+	}
+	return hash
+}
+
+// writeArgs writes the types of the errors in the given template arguments
+// to the hash state, and then returns the new hash state.
+func (*FingerprintHook) writeArgs(hash fingerprintHash, args []interface { }) fingerprintHash {
+	for index := 0; index < len(args); index++ {
+		if err, ok := args[index].(error); ok {
+			hash = hash.writeError(err)
+		}
+	}
+	return hash
+}
+
+// Print computes the fingerprint of the given log entry and attaches it
+// to the log entry message as a field. Log entries whose level is not
+// within the level span are ignored.
+func (h *FingerprintHook) Print(entry *Entry) error {
+	if !h.span.Contains(entry.Level) {
+		return nil
+	}
+	field := String(h.name, h.Fingerprint(entry))
+
+	// Template messages cannot carry fields, so they are rendered to
+	// the text of the log entry first.
+	switch value := entry.Message.(type) {
+	case *TemplateMessage:
+		if value != nil {
+			entry.ReplaceText(string(appendTemplate(nil, value.Template,
+				value.Args)))
+		}
+	case TemplateMessage:
+		entry.ReplaceText(string(appendTemplate(nil, value.Template,
+			value.Args)))
+	}
+	entry.AddFields(field)
+	return nil
+}
+
+// FingerprintHookOption is a structure that contains options for the
+// fingerprint Hook.
+type FingerprintHookOption struct {
+	// Span represents the log level span of log entries that need to be
+	// fingerprinted. If not provided, the default is ERROR to FATAL.
+	Span LevelSpan
+
+	// Depth represents the maximum number of call stack frames, outside
+	// of this package and the Go runtime, used to compute the fingerprint.
+	// If not provided, the default value is 8.
+	Depth int
+
+	// Name represents the name of the field that contains the fingerprint.
+	// If not provided, the default value is "fingerprint".
+	Name string
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *FingerprintHookOption) Validate() error {
+	if o.Span.Start > o.Span.End {
+		return newOptionError("Span",
+			"start level must not be greater than end level", nil)
+	}
+	if o.Depth < 0 {
+		return newOptionError("Depth", "must not be negative", nil)
+	}
+	if len(o.Name) == 0 {
+		return newOptionError("Name", "must not be empty", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a fingerprint Hook instance.
+func (o *FingerprintHookOption) Build() (*FingerprintHook, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &FingerprintHook {
+		span: o.Span,
+		depth: o.Depth,
+		name: o.Name,
+	}, nil
+}
+
+// NewFingerprintHookOption creates and returns a fingerprint Hook option
+// instance with default option values.
+func NewFingerprintHookOption() *FingerprintHookOption {
+	return &FingerprintHookOption {
+		Span: LevelSpan {
+			Start: LevelError,
+			End: LevelFatal,
+		},
+		Depth: 8,
+		Name: "fingerprint",
+	}
+}
+
+// NewFingerprintHook creates and returns a fingerprint Hook instance using
+// default option values.
+func NewFingerprintHook() (*FingerprintHook, error) {
+	return NewFingerprintHookOption().Build()
+}
+
+// SlowOpHook is the structure of the slow operation Hook instance.
+//
+// The slow operation Hook watches paired begin and end log entries, which
+// are structured messages matched by an operation ID field and marked by a
+// phase field, and then prints a WARNING log entry to the given logger if
+// the end log entry of an operation does not arrive within its deadline.
+// It helps to detect hung operations directly from the logs.
+//
+// The deadline of an operation is the configured deadline, unless the begin
+// log entry contains a deadline field created by the Duration function.
+//
+// Please note that the Close function must be called when the Hook is no
+// longer used, to stop the timers of the pending operations.
+type SlowOpHook struct {
+	logger *StandardLogger
+	idName string
+	phaseName string
+	deadlineName string
+	begin string
+	end string
+	deadline time.Duration
+
+	mutex sync.Mutex
+	pending map[string]*slowOp
+	closed bool
+}
+
+// slowOp is a structure that contains the begin log entry of a pending
+// operation.
+type slowOp struct {
+	id string
+	text string
+	time time.Time
+	deadline time.Duration
+	timer *time.Timer
+}
+
+// fields returns the fields of the given log entry, and whether the log
+// entry contains a structured message.
+func (*SlowOpHook) fields(entry *Entry) (string, []Field, bool) {
+	switch message := entry.Message.(type) {
+	case *StructMessage:
+		return message.Text, message.Fields, true
+	case StructMessage:
+		return message.Text, message.Fields, true
+	}
+	return "", nil, false
+}
+
+// Print checks whether the given log entry begins or ends an operation,
+// and then starts or stops the deadline timer of the operation. Other log
+// entries are ignored.
+func (h *SlowOpHook) Print(entry *Entry) error {
+	text, fields, ok := h.fields(entry)
+	if !ok {
+		return nil
+	}
+	operation := slowOp {
+		text: text,
+		time: entry.Time,
+		deadline: h.deadline,
+	}
+	var phase string
+	var found bool
+	for index := 0; index < len(fields); index++ {
+		field := &fields[index]
+		switch field.Name {
+		case h.idName:
+			switch field.Type {
+			case TypeString:
+				operation.id, found = field.String, true
+			case TypeInt:
+				operation.id = strconv.FormatInt(field.Number, 10)
+				found = true
+			case TypeUint:
+				operation.id = strconv.FormatUint(field.Uint64(), 10)
+				found = true
+			}
+		case h.phaseName:
+			if field.Type == TypeString {
+				phase = field.String
+			}
+		case h.deadlineName:
+			if field.Type == TypeInt && field.Number > 0 {
+				operation.deadline = time.Duration(field.Number)
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	switch phase {
+	case h.begin:
+		h.start(operation)
+	case h.end:
+		h.stop(operation.id)
+	}
+	return nil
+}
+
+// start starts the deadline timer of the given operation. The timer of a
+// pending operation with the same ID is replaced.
+func (h *SlowOpHook) start(operation slowOp) {
+	if operation.time.IsZero() {
+		operation.time = time.Now()
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		return
+	}
+	if pending, ok := h.pending[operation.id]; ok {
+		pending.timer.Stop()
+	}
+	pending := &operation
+	pending.timer = time.AfterFunc(operation.deadline, func() {
+		h.expire(pending)
+	})
+	h.pending[operation.id] = pending
+}
+
+// stop stops the deadline timer of the operation with the given ID.
+func (h *SlowOpHook) stop(id string) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if pending, ok := h.pending[id]; ok {
+		pending.timer.Stop()
+		delete(h.pending, id)
+	}
+}
+
+// expire prints a WARNING log entry for the given operation whose deadline
+// has been exceeded, unless the operation has ended or been replaced.
+func (h *SlowOpHook) expire(operation *slowOp) {
+	h.mutex.Lock()
+	if h.closed || h.pending[operation.id] != operation {
+		h.mutex.Unlock()
+		return
+	}
+	delete(h.pending, operation.id)
+	h.mutex.Unlock()
+	_ = h.logger.Warning(&StructMessage {
+		Text: "Operation exceeded its deadline",
+		Fields: []Field {
+			String(h.idName, operation.id),
+			String("operation", operation.text),
+			Time("began", operation.time),
+			Duration(h.deadlineName, operation.deadline),
+		},
+	})
+}
+
+// Pending returns the number of operations that have begun but have not
+// yet ended or exceeded their deadline.
+func (h *SlowOpHook) Pending() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.pending)
+}
+
+// Close stops the deadline timers of all pending operations. After the
+// Hook is closed, operations are no longer watched.
+func (h *SlowOpHook) Close() error {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		return ErrClosed
+	}
+	h.closed = true
+	for id, pending := range h.pending {
+		pending.timer.Stop()
+		delete(h.pending, id)
+	}
+	return nil
+}
+
+// SlowOpHookOption is a structure that contains options for the slow
+// operation Hook.
+type SlowOpHookOption struct {
+	// Logger represents the logger instance that prints the WARNING log
+	// entries of the operations that exceed their deadline. This option
+	// is required.
+	Logger *StandardLogger
+
+	// IDName represents the name of the field that contains the operation
+	// ID. The field can be a string or an integer. If not provided, the
+	// default value is "operation_id".
+	IDName string
+
+	// PhaseName represents the name of the string field that marks the
+	// begin and end log entries of an operation. If not provided, the
+	// default value is "phase".
+	PhaseName string
+
+	// BeginPhase represents the value of the phase field of the begin log
+	// entry. If not provided, the default value is "begin".
+	BeginPhase string
+
+	// EndPhase represents the value of the phase field of the end log
+	// entry. If not provided, the default value is "end".
+	EndPhase string
+
+	// DeadlineName represents the name of the field of the begin log entry
+	// that overrides the deadline of the operation. If not provided, the
+	// default value is "deadline".
+	DeadlineName string
+
+	// Deadline represents the maximum duration between the begin and end
+	// log entries of an operation. If not provided, the default value is
+	// 30 seconds.
+	Deadline time.Duration
+}
+
+// UseLogger uses the given logger as the value of the option Logger. For
+// details, please refer to the comment section of the Logger option. Then
+// return to the option instance itself.
+func (o *SlowOpHookOption) UseLogger(logger *StandardLogger) *SlowOpHookOption {
+	o.Logger = logger
+	return o
+}
+
+// UseNames uses the given names as the values of the options IDName and
+// PhaseName. For details, please refer to the comment section of these
+// options. Then return to the option instance itself.
+func (o *SlowOpHookOption) UseNames(id, phase string) *SlowOpHookOption {
+	o.IDName = id
+	o.PhaseName = phase
+	return o
+}
+
+// UsePhases uses the given values as the values of the options BeginPhase
+// and EndPhase. For details, please refer to the comment section of these
+// options. Then return to the option instance itself.
+func (o *SlowOpHookOption) UsePhases(begin, end string) *SlowOpHookOption {
+	o.BeginPhase = begin
+	o.EndPhase = end
+	return o
+}
+
+// UseDeadline uses the given duration as the value of the option Deadline.
+// For details, please refer to the comment section of the Deadline option.
+// Then return to the option instance itself.
+func (o *SlowOpHookOption) UseDeadline(deadline time.Duration) *SlowOpHookOption {
+	o.Deadline = deadline
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *SlowOpHookOption) Validate() error {
+	if o.Logger == nil {
+		return newOptionError("Logger", "must not be nil", nil)
+	}
+	if len(o.IDName) == 0 {
+		return newOptionError("IDName", "must not be empty", nil)
+	}
+	if len(o.PhaseName) == 0 {
+		return newOptionError("PhaseName", "must not be empty", nil)
+	}
+	if len(o.BeginPhase) == 0 || o.BeginPhase == o.EndPhase {
+		return newOptionError("BeginPhase",
+			"must not be empty or equal to the end phase", nil)
+	}
+	if len(o.EndPhase) == 0 {
+		return newOptionError("EndPhase", "must not be empty", nil)
+	}
+	if len(o.DeadlineName) == 0 {
+		return newOptionError("DeadlineName", "must not be empty", nil)
+	}
+	if o.Deadline <= 0 {
+		return newOptionError("Deadline", "must be greater than 0", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a slow operation Hook instance.
+func (o *SlowOpHookOption) Build() (*SlowOpHook, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &SlowOpHook {
+		logger: o.Logger,
+		idName: o.IDName,
+		phaseName: o.PhaseName,
+		deadlineName: o.DeadlineName,
+		begin: o.BeginPhase,
+		end: o.EndPhase,
+		deadline: o.Deadline,
+		pending: make(map[string]*slowOp),
+	}, nil
+}
+
+// NewSlowOpHookOption creates and returns a slow operation Hook option
+// instance with default option values.
+func NewSlowOpHookOption() *SlowOpHookOption {
+	return &SlowOpHookOption {
+		IDName: "operation_id",
+		PhaseName: "phase",
+		BeginPhase: "begin",
+		EndPhase: "end",
+		DeadlineName: "deadline",
+		Deadline: time.Second * 30,
+	}
+}
+
+// NewSlowOpHook creates and returns a slow operation Hook instance that
+// prints to the given logger using default option values.
+func NewSlowOpHook(logger *StandardLogger) (*SlowOpHook, error) {
+	return NewSlowOpHookOption().UseLogger(logger).Build()
+}
+
+// EnrichmentResolver is the type of the function that resolves the value
+// of a field into the fields attached by the enrichment Hook, for example
+// the geographic location of an IP address or the plan of a user ID.
+//
+// The given context is canceled when the timeout of the resolution is
+// exceeded or the Hook is closed.
+type EnrichmentResolver func(ctx context.Context, value string) ([]Field, error)
+
+// EnrichmentHook is the structure of the enrichment Hook instance.
+//
+// The enrichment Hook looks up a field of each structured log entry,
+// resolves its value using the given resolver, and then attaches the
+// resolved fields to the log entry message as a nested object field. The
+// resolved fields are cached in memory until their TTL expires, so the
+// resolver is called once per value and TTL.
+//
+// Resolutions run asynchronously with bounded concurrency, and the Hook
+// waits at most the configured duration for a pending resolution before
+// the log entry is printed without the resolved fields, so that a slow
+// resolver never blocks the logger for long. If the concurrency limit is
+// reached, the value is not resolved for the log entry. Failed resolutions
+// are cached as well, and their log entries are not enriched.
+//
+// Please note that the Close function must be called when the Hook is no
+// longer used, to cancel and wait for the pending resolutions.
+type EnrichmentHook struct {
+	span LevelSpan
+	field string
+	name string
+	resolver EnrichmentResolver
+	ttl time.Duration
+	timeout time.Duration
+	wait time.Duration
+	capacity int
+	slots chan struct { }
+	ctx context.Context
+	cancel context.CancelFunc
+	group sync.WaitGroup
+
+	mutex sync.Mutex
+	cache map[string]*enrichment
+	closed bool
+}
+
+// enrichment is a structure that contains a cached or pending resolution
+// of the enrichment Hook.
+type enrichment struct {
+	done chan struct { }
+	fields []Field
+	err error
+	expires time.Time
+}
+
+// key returns the value of the field to resolve of the given log entry,
+// and whether the log entry contains the field.
+func (h *EnrichmentHook) key(entry *Entry) (string, bool) {
+	field, ok := lookupMessageField(entry.Message, h.field)
+	if !ok {
+		return "", false
+	}
+	switch field.Type {
+	case TypeString:
+		return field.String, true
+	case TypeInt:
+		return strconv.FormatInt(field.Number, 10), true
+	case TypeUint:
+		return strconv.FormatUint(field.Uint64(), 10), true
+	case TypeBigNumber:
+		return string(field.SerializeJSON(nil)), true
+	}
+	return "", false
+}
+
+// lookup returns the cached or pending resolution of the given value. If
+// there is none, or it has expired, a new resolution is started, unless the
+// concurrency limit is reached or the Hook is closed.
+func (h *EnrichmentHook) lookup(value string, now time.Time) *enrichment {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if h.closed {
+		return nil
+	}
+	if cached, ok := h.cache[value]; ok {
+		select {
+		case <-cached.done:
+			if now.Before(cached.expires) {
+				return cached
+			}
+		default:
+			return cached
+		}
+	}
+	select {
+	case h.slots <- struct { } { }:
+	default:
+		return nil
+	}
+	h.evict(now)
+	pending := &enrichment {
+		done: make(chan struct { }),
+	}
+	h.cache[value] = pending
+	h.group.Add(1)
+	go h.resolve(value, pending)
+	return pending
+}
+
+// evict removes the expired resolutions from the cache if the cache is
+// full, and then removes any completed resolution if it is still full.
+// The caller must hold the mutex.
+func (h *EnrichmentHook) evict(now time.Time) {
+	if len(h.cache) < h.capacity {
+		return
+	}
+	for value, cached := range h.cache {
+		select {
+		case <-cached.done:
+			if !now.Before(cached.expires) {
+				delete(h.cache, value)
+			}
+		default:
+		}
+	}
+	for value, cached := range h.cache {
+		if len(h.cache) < h.capacity {
+			return
+		}
+		select {
+		case <-cached.done:
+			delete(h.cache, value)
+		default:
+		}
+	}
+}
+
+// resolve resolves the given value using the resolver, and then completes
+// the given pending resolution.
+func (h *EnrichmentHook) resolve(value string, pending *enrichment) {
+	defer h.group.Done()
+	defer func() {
+		<-h.slots
+	}()
+	ctx, cancel := context.WithTimeout(h.ctx, h.timeout)
+	defer cancel()
+	fields, err := h.resolver(ctx, value)
+	h.mutex.Lock()
+	pending.fields, pending.err = fields, err
+	pending.expires = time.Now().Add(h.ttl)
+	h.mutex.Unlock()
+	close(pending.done)
+}
+
+// Print resolves the value of the field of the given log entry, and then
+// attaches the resolved fields to the log entry message as an object
+// field. Log entries whose level is not within the level span, or that
+// do not contain the field, are ignored.
+func (h *EnrichmentHook) Print(entry *Entry) error {
+	if !h.span.Contains(entry.Level) {
+		return nil
+	}
+	value, ok := h.key(entry)
+	if !ok {
+		return nil
+	}
+	resolution := h.lookup(value, time.Now())
+	if resolution == nil {
+		return nil
+	}
+	select {
+	case <-resolution.done:
+	default:
+		if h.wait <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(h.wait)
+		select {
+		case <-resolution.done:
+			timer.Stop()
+		case <-timer.C:
+			return nil
+		}
+	}
+	h.mutex.Lock()
+	fields, err := resolution.fields, resolution.err
+	h.mutex.Unlock()
+	if err != nil || len(fields) == 0 {
+		return nil
+	}
+
+	entry.AddFields(Object(h.name, fields...))
+	return nil
+}
+
+// Cached returns the number of cached and pending resolutions.
+func (h *EnrichmentHook) Cached() int {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return len(h.cache)
+}
+
+// Close cancels the pending resolutions and waits for them to complete.
+// After the Hook is closed, log entries are no longer enriched.
+func (h *EnrichmentHook) Close() error {
+	h.mutex.Lock()
+	if h.closed {
+		h.mutex.Unlock()
+		return ErrClosed
+	}
+	h.closed = true
+	h.cache = make(map[string]*enrichment)
+	h.mutex.Unlock()
+	h.cancel()
+	h.group.Wait()
+	return nil
+}
+
+// EnrichmentHookOption is a structure that contains options for the
+// enrichment Hook.
+type EnrichmentHookOption struct {
+	// Span represents the log level span of log entries that need to be
+	// enriched. If not provided, the default is DEBUG to FATAL.
+	Span LevelSpan
+
+	// Field represents the name of the field whose value is resolved. The
+	// field can be a string or an integer. This option is required.
+	Field string
+
+	// Name represents the name of the object field that contains the
+	// resolved fields. If not provided, the default value is the value of
+	// the Field option followed by "_info".
+	Name string
+
+	// Resolver represents the function that resolves the value of the
+	// field. This option is required.
+	Resolver EnrichmentResolver
+
+	// TTL represents how long the resolved fields of a value are cached.
+	// If not provided, the default value is 5 minutes.
+	TTL time.Duration
+
+	// Timeout represents the maximum duration of a resolution. If not
+	// provided, the default value is 5 seconds.
+	Timeout time.Duration
+
+	// Wait represents the maximum duration that printing a log entry waits
+	// for a pending resolution. If it is 0, log entries are only enriched
+	// from the cache. If not provided, the default value is 0.
+	//
+	// Any non-zero value blocks the goroutine that prints the log entry,
+	// so the latency of the resolver is added to the caller of the
+	// logger until the resolution completes or the duration elapses.
+	Wait time.Duration
+
+	// Concurrency represents the maximum number of pending resolutions.
+	// If not provided, the default value is 4.
+	Concurrency int
+
+	// Capacity represents the maximum number of cached resolutions. If not
+	// provided, the default value is 1024.
+	Capacity int
+}
+
+// UseField uses the given names as the values of the options Field and
+// Name. For details, please refer to the comment section of these options.
+// Then return to the option instance itself.
+func (o *EnrichmentHookOption) UseField(field, name string) *EnrichmentHookOption {
+	o.Field = field
+	o.Name = name
+	return o
+}
+
+// UseResolver uses the given resolver as the value of the option Resolver.
+// For details, please refer to the comment section of the Resolver option.
+// Then return to the option instance itself.
+func (o *EnrichmentHookOption) UseResolver(resolver EnrichmentResolver) *EnrichmentHookOption {
+	o.Resolver = resolver
+	return o
+}
+
+// UseTTL uses the given duration as the value of the option TTL. For
+// details, please refer to the comment section of the TTL option. Then
+// return to the option instance itself.
+func (o *EnrichmentHookOption) UseTTL(ttl time.Duration) *EnrichmentHookOption {
+	o.TTL = ttl
+	return o
+}
+
+// UseWait uses the given duration as the value of the option Wait. For
+// details, please refer to the comment section of the Wait option. Then
+// return to the option instance itself.
+func (o *EnrichmentHookOption) UseWait(wait time.Duration) *EnrichmentHookOption {
+	o.Wait = wait
+	return o
+}
+
+// UseConcurrency uses the given number as the value of the option
+// Concurrency. For details, please refer to the comment section of the
+// Concurrency option. Then return to the option instance itself.
+func (o *EnrichmentHookOption) UseConcurrency(concurrency int) *EnrichmentHookOption {
+	o.Concurrency = concurrency
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *EnrichmentHookOption) Validate() error {
+	if o.Span.Start > o.Span.End {
+		return newOptionError("Span",
+			"start level must not be greater than end level", nil)
+	}
+	if len(o.Field) == 0 {
+		return newOptionError("Field", "must not be empty", nil)
+	}
+	if o.Resolver == nil {
+		return newOptionError("Resolver", "must not be nil", nil)
+	}
+	if o.TTL <= 0 {
+		return newOptionError("TTL", "must be greater than 0", nil)
+	}
+	if o.Timeout <= 0 {
+		return newOptionError("Timeout", "must be greater than 0", nil)
+	}
+	if o.Wait < 0 {
+		return newOptionError("Wait", "must not be negative", nil)
+	}
+	if o.Concurrency <= 0 {
+		return newOptionError("Concurrency", "must be greater than 0", nil)
+	}
+	if o.Capacity <= 0 {
+		return newOptionError("Capacity", "must be greater than 0", nil)
+	}
+	return nil
+}
+
+// Build builds and returns an enrichment Hook instance.
+func (o *EnrichmentHookOption) Build() (*EnrichmentHook, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	name := o.Name
+	if len(name) == 0 {
+		name = o.Field + "_info"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EnrichmentHook {
+		span: o.Span,
+		field: o.Field,
+		name: name,
+		resolver: o.Resolver,
+		ttl: o.TTL,
+		timeout: o.Timeout,
+		wait: o.Wait,
+		capacity: o.Capacity,
+		slots: make(chan struct { }, o.Concurrency),
+		ctx: ctx,
+		cancel: cancel,
+		cache: make(map[string]*enrichment),
+	}, nil
+}
+
+// NewEnrichmentHookOption creates and returns an enrichment Hook option
+// instance with default option values.
+func NewEnrichmentHookOption() *EnrichmentHookOption {
+	return &EnrichmentHookOption {
+		Span: LevelSpan {
+			Start: LevelDebug,
+			End: LevelFatal,
+		},
+		TTL: time.Minute * 5,
+		Timeout: time.Second * 5,
+		Concurrency: 4,
+		Capacity: 1024,
+	}
+}
+
+// NewEnrichmentHook creates and returns an enrichment Hook instance that
+// resolves the given field using the given resolver and default option
+// values.
+func NewEnrichmentHook(field string, resolver EnrichmentResolver) (*EnrichmentHook, error) {
+	return NewEnrichmentHookOption().UseField(field, "").
+		UseResolver(resolver).Build()
+}
@@ -23,8 +23,12 @@
 package santa
 
 import (
+	"context"
 	"errors"
+	"os"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -43,3 +47,191 @@ func TestSimpleHook(t *testing.T) {
 	assert.Equal(t, "Error", err.Error(), "Unexpected return value")
 	assert.Equal(t, true, succeed, "Hook handler is not called")
 }
+
+func TestFingerprintHook(t *testing.T) {
+	hook, err := NewFingerprintHook()
+	assert.NoError(t, err, "Unexpected fingerprint hook creation error")
+
+	print := func(level Level, message Message) *Entry {
+		entry := &Entry {
+			Level: level,
+			Message: message,
+		}
+		assert.NoError(t, hook.Print(entry), "Unexpected hook error")
+		return entry
+	}
+
+	fingerprint := func(entry *Entry) string {
+		message, ok := entry.Message.(StructMessage)
+		if !assert.True(t, ok, "Unexpected message type") {
+			return ""
+		}
+		field := message.Fields[len(message.Fields) - 1]
+		assert.Equal(t, "fingerprint", field.Name, "Unexpected field name")
+		return field.String
+	}
+
+	fields := []Field { Error("error", errors.New("Error 1")) }
+	first := print(LevelError, &StructMessage { Text: "Failed", Fields: fields })
+	assert.Len(t, fields, 1, "Unexpected modification of fields")
+
+	var results []string
+	for index := 0; index < 2; index++ {
+		results = append(results, fingerprint(print(LevelError,
+			&StructMessage {
+				Text: "Failed",
+				Fields: []Field {
+					Error("error", errors.New("Error 2")),
+				},
+			})))
+	}
+	assert.Equal(t, fingerprint(first), results[0],
+		"Unexpected fingerprint of identical errors")
+	assert.Equal(t, results[0], results[1],
+		"Unexpected fingerprint of identical errors")
+
+	other := print(LevelError, &StructMessage {
+		Text: "Failed",
+		Fields: []Field {
+			Error("error", &os.PathError { Op: "open", Err: os.ErrNotExist }),
+		},
+	})
+	assert.NotEqual(t, results[0], fingerprint(other),
+		"Unexpected fingerprint of different error types")
+
+	template := print(LevelFatal, TemplateMessage {
+		Template: "Failed: %v",
+		Args: []interface { } { errors.New("Error") },
+	})
+	assert.Equal(t, "Failed: Error", template.Message.(StructMessage).Text,
+		"Unexpected message text")
+	assert.NotEmpty(t, fingerprint(template), "Unexpected fingerprint")
+
+	ignored := print(LevelInfo, StringMessage("Hello"))
+	assert.Equal(t, StringMessage("Hello"), ignored.Message,
+		"Unexpected message of entry outside of level span")
+}
+
+func TestSlowOpHook(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableSampling().DisableFlushing()
+	option.Outputting.UseStandard(writer)
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	_, err = NewSlowOpHook(nil)
+	assert.Error(t, err, "Unexpected slow operation hook creation result")
+
+	hook, err := NewSlowOpHookOption().UseLogger(logger).
+		UseDeadline(time.Hour).Build()
+	assert.NoError(t, err, "Unexpected slow operation hook creation error")
+
+	print := func(text string, fields ...Field) {
+		entry := &Entry {
+			Level: LevelInfo,
+			Message: &StructMessage { Text: text, Fields: fields },
+		}
+		assert.NoError(t, hook.Print(entry), "Unexpected hook error")
+	}
+
+	print("Query", String("operation_id", "1"), String("phase", "begin"))
+	print("Upload", Int("operation_id", 2), String("phase", "begin"),
+		Duration("deadline", time.Millisecond * 10))
+	print("Ignored", String("phase", "begin"))
+	assert.Equal(t, 2, hook.Pending(), "Unexpected pending operations")
+
+	print("Query", String("operation_id", "1"), String("phase", "end"))
+	assert.Eventually(t, func() bool {
+		return hook.Pending() == 0
+	}, time.Second, time.Millisecond, "Unexpected pending operations")
+
+	assert.NoError(t, logger.Sync(), "Unexpected sync error")
+	output := writer.String()
+	assert.Contains(t, output, "Operation exceeded its deadline",
+		"Unexpected warning")
+	assert.Contains(t, output, "Upload", "Unexpected warning")
+	assert.NotContains(t, output, "Query", "Unexpected warning")
+
+	print("Query", String("operation_id", "3"), String("phase", "begin"))
+	assert.NoError(t, hook.Close(), "Unexpected close error")
+	assert.Equal(t, 0, hook.Pending(), "Unexpected pending operations")
+	assert.True(t, errors.Is(hook.Close(), ErrClosed), "Unexpected close error")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+}
+
+func TestEnrichmentHook(t *testing.T) {
+	var calls int32
+	release := make(chan struct { })
+	resolver := func(ctx context.Context, value string) ([]Field, error) {
+		atomic.AddInt32(&calls, 1)
+		switch value {
+		case "slow":
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		case "bad":
+			return nil, errors.New("lookup failed")
+		}
+		return []Field { String("country", "NZ:" + value) }, nil
+	}
+	_, err := NewEnrichmentHook("ip", nil)
+	assert.Error(t, err, "Unexpected enrichment hook creation result")
+
+	hook, err := NewEnrichmentHookOption().UseField("ip", "geo").
+		UseResolver(resolver).UseWait(time.Second).UseConcurrency(1).Build()
+	assert.NoError(t, err, "Unexpected enrichment hook creation error")
+
+	print := func(fields ...Field) *Entry {
+		entry := &Entry {
+			Level: LevelInfo,
+			Message: &StructMessage { Text: "Request", Fields: fields },
+		}
+		assert.NoError(t, hook.Print(entry), "Unexpected hook error")
+		return entry
+	}
+	fieldsOf := func(entry *Entry) []Field {
+		message, ok := entry.Message.(StructMessage)
+		if !ok {
+			return nil
+		}
+		return message.Fields
+	}
+
+	original := []Field { String("ip", "10.0.0.1") }
+	entry := print(original...)
+	assert.Equal(t, []Field { String("ip", "10.0.0.1"), Object("geo",
+		String("country", "NZ:10.0.0.1")) }, fieldsOf(entry),
+		"Unexpected enriched fields")
+	assert.Len(t, original, 1, "Unexpected modified fields")
+	print(String("ip", "10.0.0.1"))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "Unexpected calls")
+
+	assert.Nil(t, fieldsOf(print(String("ip", "bad"))),
+		"Unexpected enriched fields")
+	assert.Nil(t, fieldsOf(print(String("user", "1"))),
+		"Unexpected enriched fields")
+
+	// The slow resolution occupies the only concurrency slot, so the
+	// other values are not resolved until it completes.
+	hook.wait = time.Millisecond
+	assert.Nil(t, fieldsOf(print(String("ip", "slow"))),
+		"Unexpected enriched fields")
+	assert.Nil(t, fieldsOf(print(String("ip", "10.0.0.2"))),
+		"Unexpected enriched fields")
+	close(release)
+	hook.wait = time.Second
+	assert.Eventually(t, func() bool {
+		return len(fieldsOf(print(String("ip", "slow")))) == 2
+	}, time.Second, time.Millisecond, "Unexpected enriched fields")
+	assert.Len(t, fieldsOf(print(Int("ip", 7))), 2,
+		"Unexpected enriched fields")
+	assert.Equal(t, 4, hook.Cached(), "Unexpected cached resolutions")
+
+	assert.NoError(t, hook.Close(), "Unexpected close error")
+	assert.Nil(t, fieldsOf(print(String("ip", "10.0.0.1"))),
+		"Unexpected enriched fields")
+	assert.True(t, errors.Is(hook.Close(), ErrClosed), "Unexpected close error")
+}
@@ -0,0 +1,272 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// HTTPOption is a structure that contains options for the fields of HTTP
+// requests and responses. For details, please refer to the comment section
+// of the Request and Response functions.
+type HTTPOption struct {
+	// Headers represents the names of the headers to be included. If not
+	// provided, all headers are included, except the denied headers.
+	Headers []string
+
+	// DeniedHeaders represents the names of the headers whose values are
+	// replaced by the RedactedMarker constant, because they usually contain
+	// credentials. If not provided, the default value is Authorization,
+	// Proxy-Authorization, Cookie, Set-Cookie and X-Api-Key.
+	DeniedHeaders []string
+
+	// MaxBody represents the maximum number of bytes of the body included.
+	// The body that exceeds it is truncated and appended with a marker. If
+	// the value is 0, the body is not included. If not provided, the
+	// default value is 1024.
+	MaxBody int
+}
+
+// UseHeaders uses the given header names as the value of the option
+// Headers. Then return to the option instance itself.
+func (o *HTTPOption) UseHeaders(headers ...string) *HTTPOption {
+	o.Headers = headers
+	return o
+}
+
+// UseDeniedHeaders appends the given header names to the option
+// DeniedHeaders. Then return to the option instance itself.
+func (o *HTTPOption) UseDeniedHeaders(headers ...string) *HTTPOption {
+	o.DeniedHeaders = append(o.DeniedHeaders, headers...)
+	return o
+}
+
+// UseMaxBody uses the given number of bytes as the value of the option
+// MaxBody. Then return to the option instance itself.
+func (o *HTTPOption) UseMaxBody(size int) *HTTPOption {
+	o.MaxBody = size
+	return o
+}
+
+// NewHTTPOption creates and returns an HTTP option instance with default
+// optional values.
+func NewHTTPOption() *HTTPOption {
+	return &HTTPOption {
+		DeniedHeaders: []string {
+			"Authorization",
+			"Proxy-Authorization",
+			"Cookie",
+			"Set-Cookie",
+			"X-Api-Key",
+		},
+		MaxBody: 1024,
+	}
+}
+
+// defaultHTTPOption is the HTTP option used by the Request and Response
+// functions.
+var defaultHTTPOption = *NewHTTPOption()
+
+// elementQuoted represents an element whose native data type is string,
+// which is quoted and escaped when serialized, because its value is not
+// controlled by the application (for example: an HTTP header value).
+type elementQuoted string
+
+// SerializeJSON serializes the element into a JSON string and appends
+// it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (e elementQuoted) SerializeJSON(buffer []byte) []byte {
+	return strconv.AppendQuote(buffer, string(e))
+}
+
+// quoted returns the value of a field with a given name and a given string
+// value that is quoted and escaped when serialized.
+func quoted(name string, value string) Field {
+	return Field {
+		Element: Element {
+			Type: TypeValue,
+			Interface: elementQuoted(value),
+		},
+		Name: name,
+	}
+}
+
+// headers returns a field with a given name containing the given headers
+// selected by the option. The headers are sorted by name, and the values
+// of the denied headers are redacted.
+func (o *HTTPOption) headers(name string, header http.Header) Field {
+	names := make([]string, 0, len(header))
+	if len(o.Headers) > 0 {
+		for index := 0; index < len(o.Headers); index++ {
+			key := http.CanonicalHeaderKey(o.Headers[index])
+			if _, ok := header[key]; ok {
+				names = append(names, key)
+			}
+		}
+	} else {
+		for key := range header {
+			names = append(names, key)
+		}
+	}
+	sort.Strings(names)
+
+	fields := make(ElementObject, 0, len(names))
+	for _, key := range names {
+		value := RedactedMarker
+		if !o.denied(key) {
+			value = strings.Join(header[key], ", ")
+		}
+		fields = append(fields, quoted(key, value))
+	}
+	return Object(name, fields...)
+}
+
+// denied returns true if the given header name is denied.
+func (o *HTTPOption) denied(name string) bool {
+	for index := 0; index < len(o.DeniedHeaders); index++ {
+		if strings.EqualFold(o.DeniedHeaders[index], name) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpBody is the structure of a body that has been partially read, which
+// replays the read data before the remaining data.
+type httpBody struct {
+	io.Reader
+	io.Closer
+}
+
+// body reads at most the maximum number of bytes of the given body with
+// the given content length, and returns a field containing the read data
+// and a body that replays the read data followed by the remaining data,
+// which replaces the given body. If the body is not included, the given
+// body is returned as is.
+func (o *HTTPOption) body(body io.ReadCloser, size int64) (Field, io.ReadCloser, bool) {
+	if o.MaxBody <= 0 || body == nil || body == http.NoBody {
+		return Field { }, body, false
+	}
+	data, err := io.ReadAll(io.LimitReader(body, int64(o.MaxBody) + 1))
+	replay := &httpBody {
+		Reader: io.MultiReader(bytes.NewReader(data), body),
+		Closer: body,
+	}
+	if err != nil {
+		return Error("body", err), replay, true
+	}
+	if len(data) > o.MaxBody {
+		length := truncateLength(string(data), o.MaxBody)
+		truncated := data[ : length : length]
+		if size >= int64(len(data)) {
+			truncated = appendTruncatedMarker(truncated, int(size) - length)
+		} else {
+			// The length of the remaining data is unknown.
+			truncated = append(truncated, "...(truncated at " +
+				strconv.Itoa(length) + " bytes)"...)
+		}
+		return quoted("body", string(truncated)), replay, true
+	}
+	return quoted("body", string(data)), replay, true
+}
+
+// RequestWith returns the value of a field with a given name and the given
+// HTTP request encoded as an object with the given HTTP option, which
+// contains the method, the URL (with the password redacted), the protocol,
+// the host, the selected headers, the content length and the truncated
+// body. If the given request is nil, the object is empty.
+//
+// The body is read in advance and replaced with a body that replays the
+// read data, so the request can still be handled or sent after the field
+// has been created.
+func RequestWith(name string, request *http.Request, option *HTTPOption) Field {
+	if request == nil {
+		return Object(name)
+	}
+	fields := ElementObject {
+		quoted("method", request.Method),
+		quoted("url", request.URL.Redacted()),
+		quoted("proto", request.Proto),
+		quoted("host", request.Host),
+		option.headers("headers", request.Header),
+		Int("content_length", request.ContentLength),
+	}
+	body, replay, ok := option.body(request.Body,
+		request.ContentLength)
+	if ok {
+		request.Body = replay
+		fields = append(fields, body)
+	}
+	return Object(name, fields...)
+}
+
+// Request returns the value of a field with a given name and the given
+// HTTP request encoded as an object with the default HTTP option. For
+// details, please refer to the comment section of the RequestWith
+// function.
+func Request(name string, request *http.Request) Field {
+	return RequestWith(name, request, &defaultHTTPOption)
+}
+
+// ResponseWith returns the value of a field with a given name and the
+// given HTTP response encoded as an object with the given HTTP option,
+// which contains the status code, the status, the protocol, the selected
+// headers, the content length and the truncated body. If the given
+// response is nil, the object is empty.
+//
+// The body is read in advance and replaced with a body that replays the
+// read data, so the response can still be read after the field has been
+// created.
+func ResponseWith(name string, response *http.Response, option *HTTPOption) Field {
+	if response == nil {
+		return Object(name)
+	}
+	fields := ElementObject {
+		Int("status_code", int64(response.StatusCode)),
+		quoted("status", response.Status),
+		quoted("proto", response.Proto),
+		option.headers("headers", response.Header),
+		Int("content_length", response.ContentLength),
+	}
+	body, replay, ok := option.body(response.Body,
+		response.ContentLength)
+	if ok {
+		response.Body = replay
+		fields = append(fields, body)
+	}
+	return Object(name, fields...)
+}
+
+// Response returns the value of a field with a given name and the given
+// HTTP response encoded as an object with the default HTTP option. For
+// details, please refer to the comment section of the ResponseWith
+// function.
+func Response(name string, response *http.Response) Field {
+	return ResponseWith(name, response, &defaultHTTPOption)
+}
@@ -0,0 +1,107 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package interceptor provides gRPC server interceptors that propagate the
+// request ID of each call into its context, so that the log entries of the
+// gRPC services are correlated with their requests like the HTTP handlers
+// of the RequestIDMiddleware of santa.
+//
+// The package is a separate module, so that the gRPC dependency is only
+// required by the users of the package.
+package interceptor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/nobody-night/santa"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// requestID returns the request ID of the call with the given incoming
+// context resolved by the given middleware, and the metadata key of the
+// request ID.
+func requestID(ctx context.Context, middleware *santa.RequestIDMiddleware) (string, string) {
+	key := strings.ToLower(middleware.Header())
+	var incoming string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			incoming = values[0]
+		}
+	}
+	return middleware.Resolve(incoming), key
+}
+
+// UnaryServerInterceptor returns a gRPC unary server interceptor that reads
+// the request ID from the incoming metadata with the header name of the
+// given middleware, or generates a new one, and then derives the context of
+// the call with the request ID. If the echo is enabled, the request ID is
+// sent in the response header metadata. For details, please refer to the
+// comment section of the RequestIDMiddleware structure of santa.
+func UnaryServerInterceptor(middleware *santa.RequestIDMiddleware) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, request interface { },
+		info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface { }, error) {
+		id, key := requestID(ctx, middleware)
+		if middleware.Echo() {
+			// The header metadata can only be set once the call is served
+			// by a gRPC server, and failing to echo the request ID must
+			// not fail the call.
+			_ = grpc.SetHeader(ctx, metadata.Pairs(key, id))
+		}
+		return handler(middleware.WithContext(ctx, id), request)
+	}
+}
+
+// serverStream is the structure of a gRPC server stream whose context is
+// replaced with the derived context.
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+// Context returns the derived context of the stream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// StreamServerInterceptor returns a gRPC stream server interceptor that
+// derives the context of each stream with the request ID. For details,
+// please refer to the comment section of the UnaryServerInterceptor
+// function.
+func StreamServerInterceptor(middleware *santa.RequestIDMiddleware) grpc.StreamServerInterceptor {
+	return func(server interface { }, stream grpc.ServerStream,
+		info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		id, key := requestID(ctx, middleware)
+		if middleware.Echo() {
+			if err := stream.SetHeader(metadata.Pairs(key, id)); err != nil {
+				return err
+			}
+		}
+		return handler(server, &serverStream {
+			ServerStream: stream,
+			ctx: middleware.WithContext(ctx, id),
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nobody-night/santa"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+type testServerStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+	header metadata.MD
+}
+
+func (s *testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	middleware, err := santa.NewRequestIDMiddleware()
+	assert.NoError(t, err, "Unexpected create error")
+	interceptor := UnaryServerInterceptor(middleware)
+
+	ctx := metadata.NewIncomingContext(context.Background(),
+		metadata.Pairs("x-request-id", "upstream-1"))
+	response, err := interceptor(ctx, "request", &grpc.UnaryServerInfo { },
+		func(ctx context.Context, request interface { }) (interface { }, error) {
+			return santa.RequestIDFromContext(ctx), nil
+		})
+	assert.NoError(t, err, "Unexpected call error")
+	assert.Equal(t, "upstream-1", response, "Unexpected request ID")
+
+	response, err = interceptor(context.Background(), "request",
+		&grpc.UnaryServerInfo { }, func(ctx context.Context,
+		request interface { }) (interface { }, error) {
+			return santa.ContextFields(ctx), nil
+		})
+	assert.NoError(t, err, "Unexpected call error")
+	fields, _ := response.([]santa.Field)
+	assert.Len(t, fields, 1, "Unexpected context fields")
+	assert.Equal(t, santa.RequestIDName, fields[0].Name,
+		"Unexpected context field")
+	assert.Len(t, fields[0].String, 26, "Unexpected generated request ID")
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	middleware, err := santa.NewRequestIDMiddleware()
+	assert.NoError(t, err, "Unexpected create error")
+	interceptor := StreamServerInterceptor(middleware)
+
+	stream := &testServerStream {
+		ctx: metadata.NewIncomingContext(context.Background(),
+			metadata.Pairs("x-request-id", "upstream-2")),
+	}
+	var id string
+	err = interceptor(nil, stream, &grpc.StreamServerInfo { },
+		func(server interface { }, stream grpc.ServerStream) error {
+			id = santa.RequestIDFromContext(stream.Context())
+			return nil
+		})
+	assert.NoError(t, err, "Unexpected call error")
+	assert.Equal(t, "upstream-2", id, "Unexpected request ID")
+	assert.Equal(t, []string { "upstream-2" }, stream.header.Get(
+		"x-request-id"), "Unexpected echoed request ID")
+}
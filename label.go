@@ -22,6 +22,38 @@
 
 package santa
 
+import (
+	"errors"
+	"os"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrInvalidLabel represents that a label is invalid. This is usually
+	// because the text of a label to be parsed is not in the "key=value"
+	// format or its key is empty.
+	ErrInvalidLabel = errors.New("invalid label")
+)
+
+// LabelError is a structure that contains an error of a label that cannot
+// be parsed, and identifies the text of the label.
+type LabelError struct {
+	// Text represents the text of the label that cannot be parsed.
+	Text string
+}
+
+// Error returns the error message of the label error.
+func (e *LabelError) Error() string {
+	return ErrInvalidLabel.Error() + " \"" + e.Text + "\""
+}
+
+// Unwrap returns ErrInvalidLabel, so that the label error can be checked
+// with the errors.Is function.
+func (e *LabelError) Unwrap() error {
+	return ErrInvalidLabel
+}
+
 // Label is a structure that contains the label name and value.
 //
 // Label is a pair of custom static key-value data used to identify the
@@ -96,6 +128,80 @@ func (l Labels) SerializeStandard(buffer []byte) []byte {
 	return l.SerializeJSON(buffer)
 }
 
+// String returns the labels in the "key=value,key2=value2" format. For
+// details, please refer to the comment section of the LabelsFromString
+// function.
+func (l Labels) String() string {
+	var builder strings.Builder
+	for index := 0; index < len(l); index++ {
+		if index > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(l[index].Key)
+		builder.WriteByte('=')
+		builder.WriteString(l[index].Value)
+	}
+	return builder.String()
+}
+
+// Set implements the flag.Value interface. It parses the given text and
+// appends the parsed labels to the labels, so that labels can be given by
+// one or more command line flags. For details, please refer to the comment
+// section of the LabelsFromString function.
+func (l *Labels) Set(text string) error {
+	labels, err := LabelsFromString(text)
+	if err != nil {
+		return err
+	}
+	*l = append(*l, labels...)
+	return nil
+}
+
+// LabelsFromMap returns the labels of the given map. The labels are sorted
+// by key, so that the serialized labels are stable.
+func LabelsFromMap(values map[string]string) Labels {
+	labels := make(Labels, 0, len(values))
+	for key, value := range values {
+		labels = append(labels, NewLabel(key, value))
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Key < labels[j].Key
+	})
+	return labels
+}
+
+// LabelsFromString parses and returns the labels of the given text in the
+// "key=value,key2=value2" format and any errors encountered. White space
+// around keys and values is ignored, and empty items are skipped. A value
+// may contain "=", but not ",".
+//
+// If a label cannot be parsed, a LabelError is returned.
+func LabelsFromString(text string) (Labels, error) {
+	var labels Labels
+	for _, item := range strings.Split(text, ",") {
+		if len(strings.TrimSpace(item)) == 0 {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !ok || len(key) == 0 {
+			return nil, &LabelError {
+				Text: item,
+			}
+		}
+		labels = append(labels, NewLabel(key, strings.TrimSpace(value)))
+	}
+	return labels, nil
+}
+
+// LabelsFromEnv parses and returns the labels of the value of the given
+// environment variable and any errors encountered. If the environment
+// variable is not set, no labels are returned. For details, please refer
+// to the comment section of the LabelsFromString function.
+func LabelsFromEnv(name string) (Labels, error) {
+	return LabelsFromString(os.Getenv(name))
+}
+
 // SerializedLabels is a structure that contains data of one or more
 // labels that have been serialized using multiple encoding formats to
 // avoid repeatedly serializing a set of the same labels.
@@ -103,6 +209,7 @@ func (l Labels) SerializeStandard(buffer []byte) []byte {
 // For details, please refer to the notes section of the Labels structure.
 type SerializedLabels struct {
 	count int
+	labels Labels
 	jsonBuffer []byte
 }
 
@@ -111,6 +218,23 @@ func (l SerializedLabels) Count() int {
 	return l.count
 }
 
+// Labels returns the labels. The returned slice is shared by log entries
+// and loggers, so it must not be modified.
+func (l SerializedLabels) Labels() Labels {
+	return l.labels
+}
+
+// Lookup returns the value of the last label with the given key. It
+// returns false if there is no label with the key.
+func (l SerializedLabels) Lookup(key string) (string, bool) {
+	for index := len(l.labels) - 1; index >= 0; index-- {
+		if l.labels[index].Key == key {
+			return l.labels[index].Value, true
+		}
+	}
+	return "", false
+}
+
 // SerializeJSON appends a set of serialized label JSON strings to the
 // given buffer slice, and then returns the appended buffer slice.
 func (l SerializedLabels) SerializeJSON(buffer []byte) []byte {
@@ -124,11 +248,230 @@ func (l SerializedLabels) SerializeStandard(buffer []byte) []byte {
 	return l.SerializeJSON(buffer)
 }
 
+// With returns serialized labels containing the labels followed by the
+// given labels. The serialized data of the labels is shared by log entries
+// and loggers, so it is never modified and the returned value uses a new
+// buffer.
+func (l SerializedLabels) With(labels ...Label) SerializedLabels {
+	if len(labels) == 0 {
+		return l
+	}
+	if l.count == 0 {
+		return NewSerializedLabels(labels...)
+	}
+	size := len(l.jsonBuffer)
+	for index := 0; index < len(labels); index++ {
+		size += len(labels[index].Key) + len(labels[index].Value) + 8
+	}
+	// Remove the closing brace of the existing labels, and then append
+	// the given labels separated by ", ".
+	buffer := append(make([]byte, 0, size),
+		l.jsonBuffer[ : len(l.jsonBuffer) - 1]...)
+	for index := 0; index < len(labels); index++ {
+		buffer = append(buffer, ", "...)
+		buffer = labels[index].SerializeJSON(buffer)
+	}
+	merged := make(Labels, 0, len(l.labels) + len(labels))
+	return SerializedLabels {
+		count: l.count + len(labels),
+		labels: append(append(merged, l.labels...), labels...),
+		jsonBuffer: append(buffer, '}'),
+	}
+}
+
+// emptyLabelsJSON is the serialized data shared by all empty serialized
+// labels. It must not be modified.
+var emptyLabelsJSON = []byte("{}")
+
 // NewSerializedLabels pre-serializes a given set of labels, and then
 // returns a SerializedLabels value.
+//
+// The buffer is allocated with the exact serialized size, so rebuilding
+// the serialized labels after the labels change only allocates once.
 func NewSerializedLabels(labels ...Label) SerializedLabels {
+	if len(labels) == 0 {
+		return SerializedLabels {
+			jsonBuffer: emptyLabelsJSON,
+		}
+	}
+	// Each label is serialized as `"key": "value"`, separated by ", " and
+	// enclosed in braces.
+	size := 2 + (len(labels) - 1) * 2
+	for index := 0; index < len(labels); index++ {
+		size += len(labels[index].Key) + len(labels[index].Value) + 6
+	}
 	return SerializedLabels {
 		count: len(labels),
-		jsonBuffer: Labels(labels).SerializeJSON(make([]byte, 0, 256)),
+		labels: append(Labels(nil), labels...),
+		jsonBuffer: Labels(labels).SerializeJSON(make([]byte, 0, size)),
+	}
+}
+
+// LabelsBuffer is a structure that contains a reusable buffer of serialized
+// labels, which is used to derive the labels of a single log entry (see
+// the AddLabel function of the Entry structure) without allocating memory
+// for each log entry.
+//
+// Please note that the API provided by LabelsBuffer is not thread-safe.
+type LabelsBuffer struct {
+	labels Labels
+	jsonBuffer []byte
+}
+
+// owns returns true if the given serialized labels were built in the
+// buffer.
+func (b *LabelsBuffer) owns(labels SerializedLabels) bool {
+	return cap(b.jsonBuffer) > 0 && len(labels.jsonBuffer) > 0 &&
+		&labels.jsonBuffer[0] == &b.jsonBuffer[ : 1][0]
+}
+
+// With returns serialized labels containing the given serialized labels
+// followed by the given labels, which are built in the buffer. If the given
+// serialized labels were built in the buffer, they are extended in place.
+//
+// The returned serialized labels share the buffer, so they are only valid
+// until the buffer is used to build other serialized labels, reset or
+// returned to the pool. For details, please refer to the comment section
+// of the With function of the SerializedLabels structure.
+func (b *LabelsBuffer) With(serialized SerializedLabels,
+	labels ...Label) SerializedLabels {
+	if len(labels) == 0 {
+		return serialized
+	}
+	var buffer []byte
+	var merged Labels
+	switch {
+	case serialized.count == 0:
+		buffer = append(b.jsonBuffer[ : 0], '{')
+		merged = b.labels[ : 0]
+	case b.owns(serialized):
+		buffer = serialized.jsonBuffer[ : len(serialized.jsonBuffer) - 1]
+		merged = serialized.labels
+	default:
+		buffer = append(b.jsonBuffer[ : 0], serialized.jsonBuffer[ :
+			len(serialized.jsonBuffer) - 1]...)
+		merged = append(b.labels[ : 0], serialized.labels...)
+	}
+	for index := 0; index < len(labels); index++ {
+		if serialized.count > 0 || index > 0 {
+			buffer = append(buffer, ", "...)
+		}
+		buffer = labels[index].SerializeJSON(buffer)
+	}
+	b.jsonBuffer = append(buffer, '}')
+	b.labels = append(merged, labels...)
+	return SerializedLabels {
+		count: serialized.count + len(labels),
+		labels: b.labels,
+		jsonBuffer: b.jsonBuffer,
+	}
+}
+
+// Reset clears the buffer, so that it does not keep the values of the
+// labels alive.
+func (b *LabelsBuffer) Reset() {
+	for index := 0; index < len(b.labels); index++ {
+		b.labels[index] = Label { }
+	}
+	b.labels = b.labels[ : 0]
+	b.jsonBuffer = b.jsonBuffer[ : 0]
+}
+
+// LabelRequirement is a structure that contains a requirement of a label
+// selector on the value of the label with a key.
+type LabelRequirement struct {
+	// Key represents the key of the label.
+	Key string
+
+	// Value represents the value that the label is compared with.
+	Value string
+
+	// NotEqual represents whether the value of the label must differ from
+	// the Value option. A label that does not exist differs from any
+	// value.
+	NotEqual bool
+}
+
+// Matches returns true if the given labels satisfy the requirement,
+// otherwise it returns false.
+func (r LabelRequirement) Matches(labels SerializedLabels) bool {
+	value, ok := labels.Lookup(r.Key)
+	if r.NotEqual {
+		return !ok || value != r.Value
+	}
+	return ok && value == r.Value
+}
+
+// LabelSelector is a data type that contains a set of label requirements,
+// all of which must be satisfied by the labels of a log entry. For
+// details, please refer to the comment section of the ParseLabelSelector
+// function.
+type LabelSelector []LabelRequirement
+
+// Matches returns true if the given labels satisfy all the requirements
+// of the label selector, otherwise it returns false. An empty label
+// selector matches any labels.
+func (s LabelSelector) Matches(labels SerializedLabels) bool {
+	for index := 0; index < len(s); index++ {
+		if !s[index].Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// String returns the label selector in the format accepted by the
+// ParseLabelSelector function.
+func (s LabelSelector) String() string {
+	var builder strings.Builder
+	for index := 0; index < len(s); index++ {
+		if index > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(s[index].Key)
+		if s[index].NotEqual {
+			builder.WriteString("!=")
+		} else {
+			builder.WriteByte('=')
+		}
+		builder.WriteString(s[index].Value)
+	}
+	return builder.String()
+}
+
+// ParseLabelSelector parses and returns the label selector of the given
+// text and any errors encountered. The text contains requirements
+// separated by commas, and each requirement is either "key=value" (the
+// label must exist and have the value) or "key!=value" (the label must
+// not exist or have a different value), for example
+// "env=prod,team!=infra". Surrounding whitespace is ignored.
+//
+// If a requirement cannot be parsed, a LabelError is returned.
+func ParseLabelSelector(text string) (LabelSelector, error) {
+	var selector LabelSelector
+	for _, item := range strings.Split(text, ",") {
+		if len(strings.TrimSpace(item)) == 0 {
+			continue
+		}
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, &LabelError {
+				Text: item,
+			}
+		}
+		requirement := LabelRequirement { }
+		if strings.HasSuffix(key, "!") {
+			key = key[ : len(key) - 1]
+			requirement.NotEqual = true
+		}
+		requirement.Key = strings.TrimSpace(key)
+		requirement.Value = strings.TrimSpace(value)
+		if len(requirement.Key) == 0 {
+			return nil, &LabelError {
+				Text: item,
+			}
+		}
+		selector = append(selector, requirement)
 	}
+	return selector, nil
 }
@@ -23,6 +23,8 @@
 package santa
 
 import (
+	"errors"
+	"flag"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -61,3 +63,74 @@ func TestSerializedLabels(t *testing.T) {
 		"instanceId": "d325ef24327c"
 	}`, string(buffer), "Unexpected JSON serialization result")
 }
+
+func TestLabelsParse(t *testing.T) {
+	labels := LabelsFromMap(map[string]string {
+		"zoneId": "ap-shanghai-1",
+		"projectId": "santa-project",
+	})
+	assert.Equal(t, "projectId=santa-project,zoneId=ap-shanghai-1",
+		labels.String(), "Unexpected labels")
+
+	labels, err := LabelsFromString(" projectId = santa-project ,, " +
+		"query=a=b ")
+	assert.NoError(t, err, "Unexpected parse error")
+	assert.Equal(t, Labels {
+		NewLabel("projectId", "santa-project"),
+		NewLabel("query", "a=b"),
+	}, labels, "Unexpected labels")
+
+	_, err = LabelsFromString("projectId")
+	assert.True(t, errors.Is(err, ErrInvalidLabel), "Unexpected parse error")
+	assert.Equal(t, `invalid label "projectId"`, err.Error(),
+		"Unexpected parse error")
+	_, err = LabelsFromString("=value")
+	assert.Error(t, err, "Unexpected parse result")
+
+	t.Setenv("SANTA_TEST_LABELS", "zoneId=ap-shanghai-1")
+	labels, err = LabelsFromEnv("SANTA_TEST_LABELS")
+	assert.NoError(t, err, "Unexpected parse error")
+	assert.Equal(t, Labels { NewLabel("zoneId", "ap-shanghai-1") }, labels,
+		"Unexpected labels")
+
+	labels = nil
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(&labels, "label", "log labels")
+	assert.NoError(t, flags.Parse([]string { "-label", "a=1,b=2",
+		"-label", "c=3" }), "Unexpected parse error")
+	assert.Equal(t, "a=1,b=2,c=3", labels.String(), "Unexpected labels")
+
+	serialized := NewSerializedLabels(labels...)
+	buffer := serialized.SerializeJSON(nil)
+	assert.Equal(t, len(buffer), cap(serialized.jsonBuffer),
+		"Unexpected serialized capacity")
+	assert.Equal(t, "{}", string(NewSerializedLabels().SerializeJSON(nil)),
+		"Unexpected empty labels")
+}
+
+func TestLabelSelector(t *testing.T) {
+	selector, err := ParseLabelSelector(" env = prod , team!=infra,, ")
+	assert.NoError(t, err, "Unexpected parse error")
+	assert.Equal(t, LabelSelector {
+		{ Key: "env", Value: "prod" },
+		{ Key: "team", Value: "infra", NotEqual: true },
+	}, selector, "Unexpected selector")
+	assert.Equal(t, "env=prod,team!=infra", selector.String(),
+		"Unexpected selector string")
+
+	labels := NewSerializedLabels(NewLabel("env", "prod"))
+	assert.True(t, selector.Matches(labels), "Unexpected match result")
+	assert.True(t, selector.Matches(labels.With(NewLabel("team", "web"))),
+		"Unexpected match result")
+	assert.False(t, selector.Matches(labels.With(NewLabel("team", "infra"))),
+		"Unexpected match result")
+	assert.False(t, selector.Matches(NewSerializedLabels()),
+		"Unexpected match result")
+	assert.True(t, LabelSelector(nil).Matches(NewSerializedLabels()),
+		"Unexpected match result")
+
+	for _, text := range []string { "env", "=prod", "!=infra" } {
+		_, err := ParseLabelSelector(text)
+		assert.True(t, errors.Is(err, ErrInvalidLabel), "Unexpected error")
+	}
+}
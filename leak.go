@@ -0,0 +1,116 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sync/atomic"
+)
+
+// Leak is a structure that contains information about an instance that
+// has been garbage collected without being closed.
+type Leak struct {
+	// Type represents the type name of the leaked instance, for example
+	// "*santa.StandardLogger".
+	Type string
+
+	// Stack represents the call stack of the goroutine that created the
+	// leaked instance.
+	Stack string
+}
+
+// String returns a human-readable description of the leak.
+func (l Leak) String() string {
+	return "santa: " + l.Type + " garbage collected without Close, " +
+		"created at:\n" + l.Stack
+}
+
+// LeakHandler is the type of handler function of leak detection. For
+// details, please refer to the comment section of the EnableLeakDetection
+// function.
+type LeakHandler func(leak Leak)
+
+// leakDetector is a structure that contains the leak handler. A nil leak
+// handler means that leak detection is disabled.
+type leakDetector struct {
+	handler LeakHandler
+}
+
+// leakDetection contains the current *leakDetector value.
+var leakDetection atomic.Value
+
+// EnableLeakDetection enables leak detection with the given leak handler.
+//
+// Leak detection is a debug mode. Once enabled, the creation call stack
+// of each logger (including copies created by the Duplicate function),
+// file synchronizer and network synchronizer created afterwards is
+// recorded, and the given handler is called for each of them that is
+// garbage collected without being closed. If the given handler is nil,
+// leaks are reported to the standard error device (os.Stderr).
+//
+// Please note that leak detection records a call stack for each instance
+// and relies on runtime finalizers, which may run long after an instance
+// becomes unreachable or not at all. It should not be enabled in production
+// environments. Instances created before leak detection is enabled are not
+// tracked.
+func EnableLeakDetection(handler LeakHandler) {
+	if handler == nil {
+		handler = func(leak Leak) {
+			_, _ = fmt.Fprintln(os.Stderr, leak.String())
+		}
+	}
+	leakDetection.Store(&leakDetector {
+		handler: handler,
+	})
+}
+
+// DisableLeakDetection disables leak detection. Instances that have been
+// tracked are still reported if they are garbage collected without being
+// closed.
+func DisableLeakDetection() {
+	leakDetection.Store(&leakDetector { })
+}
+
+// trackLeak records the creation call stack of the given instance if leak
+// detection is enabled, and reports the instance if it is garbage collected
+// while the given closed function returns false.
+func trackLeak[T any](instance *T, closed func(instance *T) bool) {
+	detector, _ := leakDetection.Load().(*leakDetector)
+	if detector == nil || detector.handler == nil {
+		return
+	}
+	buffer := make([]byte, 4096)
+	buffer = buffer[ : runtime.Stack(buffer, false)]
+	leak := Leak {
+		Type: fmt.Sprintf("%T", instance),
+		Stack: string(buffer),
+	}
+	handler := detector.handler
+	runtime.SetFinalizer(instance, func(instance *T) {
+		if !closed(instance) {
+			handler(leak)
+		}
+	})
+}
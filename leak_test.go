@@ -0,0 +1,72 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLeakDetection(t *testing.T) {
+	leaks := make(chan Leak, 16)
+	EnableLeakDetection(func(leak Leak) {
+		leaks <- leak
+	})
+	defer DisableLeakDetection()
+
+	func() {
+		option := NewStructOption().DisableFlushing()
+		option.Outputting.UseDiscard()
+		option.ErrorOutputting.UseDiscard()
+
+		leaked, err := option.Build()
+		assert.NoError(t, err, "Unexpected create error")
+		assert.NoError(t, leaked.Infos("Hello Test!"), "Unexpected print error")
+
+		closed, err := option.Build()
+		assert.NoError(t, err, "Unexpected create error")
+		assert.NoError(t, closed.Close(), "Unexpected close error")
+	}()
+
+	var reported []Leak
+	for attempt := 0; attempt < 20; attempt++ {
+		runtime.GC()
+		select {
+		case leak := <-leaks:
+			reported = append(reported, leak)
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	if assert.Len(t, reported, 1, "Unexpected number of leaks") {
+		assert.Equal(t, "*santa.StructLogger", reported[0].Type,
+			"Unexpected leak type")
+		assert.Contains(t, reported[0].Stack, "TestLeakDetection",
+			"Unexpected leak stack")
+		assert.Contains(t, reported[0].String(), "without Close",
+			"Unexpected leak description")
+	}
+}
@@ -25,6 +25,7 @@ package santa
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -100,6 +101,26 @@ func (l Level) Format() string {
 	}
 }
 
+// Priority returns the syslog priority (severity) of the log level, as
+// defined by RFC 5424 and used by the SD-DAEMON priority prefixes of
+// journald. Unknown log levels are mapped to the notice priority.
+func (l Level) Priority() int {
+	switch l {
+	case LevelDebug:
+		return 7
+	case LevelInfo:
+		return 6
+	case LevelWarning:
+		return 4
+	case LevelError:
+		return 3
+	case LevelFatal:
+		return 2
+	default:
+		return 5
+	}
+}
+
 // AppendFormat appends the format string of the log level to the
 // given buffer slice, and then returns the appended buffer slice.
 func (l Level) AppendFormat(buffer []byte) []byte {
@@ -120,14 +141,16 @@ func (l Level) AppendFormat(buffer []byte) []byte {
 }
 
 // ParseLevel parses and returns the log level value of the given log
-// level name and any errors encountered.
+// level name and any errors encountered. The name is case-insensitive and
+// surrounding white space is ignored, and "warn" is accepted as an alias
+// of "warning".
 func ParseLevel(name string) (Level, error) {
-	switch strings.ToLower(name) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
 	case "debug":
 		return LevelDebug, nil
 	case "info":
 		return LevelInfo, nil
-	case "warning":
+	case "warning", "warn":
 		return LevelWarning, nil
 	case "error":
 		return LevelError, nil
@@ -138,6 +161,173 @@ func ParseLevel(name string) (Level, error) {
 	}
 }
 
+// LevelMapper is the type of the functions that translate the level of an
+// external level scheme (for example: syslog severities, OpenTelemetry
+// severity numbers or HTTP status codes) into a log level. It returns the
+// log level and true if the given value is recognized, otherwise it
+// returns false.
+//
+// Level mappers are used by ingest bridges (for example: the relay server)
+// to read the levels of log entries produced by other systems.
+type LevelMapper func(value string) (Level, bool)
+
+// MapLevelName is a level mapper that translates the names of log levels.
+// For details, please refer to the comment section of the ParseLevel
+// function.
+func MapLevelName(value string) (Level, bool) {
+	level, err := ParseLevel(value)
+	return level, err == nil
+}
+
+// MapSyslogSeverity is a level mapper that translates the syslog severities
+// defined by RFC 5424, which are either numbers from 0 (emergency) to 7
+// (debug) or their keywords (for example: "crit" and "notice"). The
+// emergency, alert and critical severities are translated into FATAL, and
+// the notice severity into INFO.
+func MapSyslogSeverity(value string) (Level, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	switch value {
+	case "0", "1", "2", "emerg", "panic", "alert", "crit":
+		return LevelFatal, true
+	case "3", "err", "error":
+		return LevelError, true
+	case "4", "warning", "warn":
+		return LevelWarning, true
+	case "5", "6", "notice", "info":
+		return LevelInfo, true
+	case "7", "debug":
+		return LevelDebug, true
+	}
+	return 0, false
+}
+
+// MapOTelSeverity is a level mapper that translates the severity numbers
+// (1 to 24) and the short names (for example: "TRACE" and "WARN2") of the
+// OpenTelemetry log data model. The TRACE severities are translated into
+// DEBUG.
+func MapOTelSeverity(value string) (Level, bool) {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		// The short names are optionally followed by a digit from 2 to 4
+		// that represents a more severe event of the same range.
+		name := strings.TrimRight(value, "234")
+		offset := 0
+		if len(name) < len(value) {
+			if len(value) - len(name) != 1 {
+				return 0, false
+			}
+			offset = int(value[len(value) - 1] - '1')
+		}
+		switch name {
+		case "TRACE":
+			number = 1
+		case "DEBUG":
+			number = 5
+		case "INFO":
+			number = 9
+		case "WARN":
+			number = 13
+		case "ERROR":
+			number = 17
+		case "FATAL":
+			number = 21
+		default:
+			return 0, false
+		}
+		number += offset
+	}
+	switch {
+	case number < 1 || number > 24:
+		return 0, false
+	case number <= 8:
+		return LevelDebug, true
+	case number <= 12:
+		return LevelInfo, true
+	case number <= 16:
+		return LevelWarning, true
+	case number <= 20:
+		return LevelError, true
+	default:
+		return LevelFatal, true
+	}
+}
+
+// MapHTTPStatus is a level mapper that translates HTTP status codes (for
+// example: "404") and status classes (for example: "5xx"). The
+// informational, successful and redirection classes are translated into
+// INFO, the client error class into WARNING, and the server error class
+// into ERROR.
+func MapHTTPStatus(value string) (Level, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) != 3 {
+		return 0, false
+	}
+	if value[1 : ] != "xx" {
+		if _, err := strconv.Atoi(value); err != nil {
+			return 0, false
+		}
+	}
+	switch value[0] {
+	case '1', '2', '3':
+		return LevelInfo, true
+	case '4':
+		return LevelWarning, true
+	case '5':
+		return LevelError, true
+	}
+	return 0, false
+}
+
+// ChainLevelMappers returns a level mapper that tries each of the given
+// level mappers in order, and returns the log level of the first level
+// mapper that recognizes the value.
+func ChainLevelMappers(mappers ...LevelMapper) LevelMapper {
+	return func(value string) (Level, bool) {
+		for index := 0; index < len(mappers); index++ {
+			if level, ok := mappers[index](value); ok {
+				return level, true
+			}
+		}
+		return 0, false
+	}
+}
+
+// MarshalText implements the encoding.TextMarshaler interface. It returns
+// the name of the log level, or ErrInvalidLevel if the log level is
+// unknown.
+func (l Level) MarshalText() ([]byte, error) {
+	if l > LevelFatal {
+		return nil, ErrInvalidLevel
+	}
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, so that
+// log levels can be parsed from configuration files (for example: JSON)
+// and environment variables. For details, please refer to the comment
+// section of the ParseLevel function.
+func (l *Level) UnmarshalText(text []byte) error {
+	level, err := ParseLevel(string(text))
+	if err != nil {
+		return err
+	}
+	*l = level
+	return nil
+}
+
+// Set implements the flag.Value interface, so that a log level can be used
+// as a command line flag with the flag.Var function. For details, please
+// refer to the comment section of the ParseLevel function.
+func (l *Level) Set(name string) error {
+	return l.UnmarshalText([]byte(name))
+}
+
+// Get implements the flag.Getter interface, and returns the log level.
+func (l *Level) Get() interface { } {
+	return *l
+}
+
 // LevelSpan is a structure that contains the log level span.
 type LevelSpan struct {
 	// Start represents the starting level of the log.
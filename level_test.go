@@ -23,6 +23,8 @@
 package santa
 
 import (
+	"encoding/json"
+	"flag"
 	"strings"
 	"testing"
 
@@ -75,6 +77,35 @@ func TestLevelFormatAndParse(t *testing.T) {
 	}
 }
 
+func TestLevelMarshalText(t *testing.T) {
+	var config struct {
+		Level Level `json:"level"`
+	}
+	err := json.Unmarshal([]byte(`{"level": " WARN "}`), &config)
+	assert.NoError(t, err, "Unexpected unmarshal error")
+	assert.Equal(t, LevelWarning, config.Level, "Unexpected level")
+
+	data, err := json.Marshal(config)
+	assert.NoError(t, err, "Unexpected marshal error")
+	assert.Equal(t, `{"level":"warning"}`, string(data), "Unexpected data")
+
+	err = json.Unmarshal([]byte(`{"level": "verbose"}`), &config)
+	assert.Error(t, err, "Unexpected unmarshal result")
+	_, err = Level(10).MarshalText()
+	assert.Equal(t, ErrInvalidLevel, err, "Unexpected marshal error")
+
+	level := LevelInfo
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.Var(&level, "level", "log level")
+	assert.NoError(t, flags.Parse([]string { "-level", "error" }),
+		"Unexpected parse error")
+	assert.Equal(t, LevelError, level, "Unexpected level")
+	assert.Equal(t, LevelError, flags.Lookup("level").Value.(flag.Getter).
+		Get(), "Unexpected level")
+	assert.Equal(t, "info", flags.Lookup("level").DefValue,
+		"Unexpected default level")
+}
+
 func TestLevelEnabled(t *testing.T) {
 	for _, sample := range []struct {
 		enable Level
@@ -150,3 +181,45 @@ func TestLevelSpanContains(t *testing.T) {
 			sample.actual), "Unexpected result")
 	}
 }
+
+func TestLevelMappers(t *testing.T) {
+	cases := []struct {
+		mapper LevelMapper
+		value string
+		level Level
+		ok bool
+	} {
+		{ MapLevelName, "WARN", LevelWarning, true },
+		{ MapLevelName, "notice", 0, false },
+		{ MapSyslogSeverity, "0", LevelFatal, true },
+		{ MapSyslogSeverity, "notice", LevelInfo, true },
+		{ MapSyslogSeverity, "7", LevelDebug, true },
+		{ MapSyslogSeverity, "8", 0, false },
+		{ MapOTelSeverity, "1", LevelDebug, true },
+		{ MapOTelSeverity, "9", LevelInfo, true },
+		{ MapOTelSeverity, "16", LevelWarning, true },
+		{ MapOTelSeverity, "ERROR3", LevelError, true },
+		{ MapOTelSeverity, "fatal", LevelFatal, true },
+		{ MapOTelSeverity, "25", 0, false },
+		{ MapOTelSeverity, "WARN22", 0, false },
+		{ MapHTTPStatus, "200", LevelInfo, true },
+		{ MapHTTPStatus, "404", LevelWarning, true },
+		{ MapHTTPStatus, "5xx", LevelError, true },
+		{ MapHTTPStatus, "600", 0, false },
+		{ MapHTTPStatus, "ok", 0, false },
+	}
+	for _, c := range cases {
+		level, ok := c.mapper(c.value)
+		assert.Equal(t, c.ok, ok, "Unexpected mapping of " + c.value)
+		if c.ok {
+			assert.Equal(t, c.level, level, "Unexpected level of " + c.value)
+		}
+	}
+
+	mapper := ChainLevelMappers(MapLevelName, MapHTTPStatus)
+	level, ok := mapper("503")
+	assert.True(t, ok, "Unexpected chained mapping")
+	assert.Equal(t, LevelError, level, "Unexpected chained level")
+	_, ok = mapper("unknown")
+	assert.False(t, ok, "Unexpected chained mapping")
+}
@@ -0,0 +1,405 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LifecycleState is a structure that describes the data written to a
+// lifecycle synchronizer since it was opened or last rotated. It is passed
+// to the lifecycle callbacks to render header and footer records.
+type LifecycleState struct {
+	// Opened represents the time when the synchronizer was opened or last
+	// rotated.
+	Opened time.Time
+
+	// Entries represents the number of log entries written.
+	Entries uint64
+
+	// Bytes represents the number of bytes of log entry data written,
+	// excluding the header record.
+	Bytes uint64
+
+	// Checksum represents the hexadecimal SHA-256 checksum of the log
+	// entry data written, excluding the header record.
+	Checksum string
+}
+
+// LifecycleCallback is the type of the callbacks of a lifecycle
+// synchronizer. It returns the record written to the synchronizer, or nil
+// if no record is written.
+//
+// Please note that the returned record is written as is, so it usually
+// ends with a newline character.
+type LifecycleCallback func(state LifecycleState) []byte
+
+// StaticRecord returns a lifecycle callback that always returns the given
+// record (for example: a CSV header or a W3C "#Version: 1.0" directive).
+func StaticRecord(record string) LifecycleCallback {
+	return func(LifecycleState) []byte {
+		return []byte(record)
+	}
+}
+
+// LifecycleSyncer is the structure of a lifecycle synchronizer instance.
+//
+// The lifecycle synchronizer writes log entry data to a synchronizer, and
+// emits header and footer records around it, which some formats and audit
+// requirements need (for example: a process start banner, a schema or
+// version line, a CSV header, W3C directives, and the entry counts and
+// checksums of the written data).
+//
+// The OnOpen callback is called when the synchronizer is built and after
+// it is rotated, and its record is written before any log entry data. The
+// OnRotate callback is called before the synchronizer is rotated, and the
+// OnClose callback is called before the synchronizer is closed, and their
+// records are written after all log entry data.
+//
+// Please note that each call of the Write function is counted as one log
+// entry, which is how exporters write the encoded log entries.
+type LifecycleSyncer struct {
+	mutex sync.Mutex
+	syncer Syncer
+	onOpen LifecycleCallback
+	onRotate LifecycleCallback
+	onClose LifecycleCallback
+
+	opened time.Time
+	entries uint64
+	bytes uint64
+	hash hash.Hash
+	closed bool
+}
+
+// state returns the state of the data written since the synchronizer was
+// opened or last rotated.
+func (s *LifecycleSyncer) state() LifecycleState {
+	return LifecycleState {
+		Opened: s.opened,
+		Entries: s.entries,
+		Bytes: s.bytes,
+		Checksum: hex.EncodeToString(s.hash.Sum(nil)),
+	}
+}
+
+// emit writes the record returned by the given callback, and then returns
+// any errors encountered.
+func (s *LifecycleSyncer) emit(callback LifecycleCallback) error {
+	if callback == nil {
+		return nil
+	}
+	record := callback(s.state())
+	if len(record) == 0 {
+		return nil
+	}
+	_, err := s.syncer.Write(record)
+	return err
+}
+
+// open resets the state of the synchronizer, writes the header record,
+// and then returns any errors encountered.
+func (s *LifecycleSyncer) open() error {
+	s.opened = time.Now()
+	s.entries = 0
+	s.bytes = 0
+	s.hash.Reset()
+	return s.emit(s.onOpen)
+}
+
+// Write writes the data of a given buffer slice to the synchronizer.
+//
+// Finally, it returns the number of bytes actually written and any
+// errors encountered.
+func (s *LifecycleSyncer) Write(buffer []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return 0, ErrClosed
+	}
+	size, err := s.syncer.Write(buffer)
+	if size > 0 {
+		s.entries++
+		s.bytes += uint64(size)
+		s.hash.Write(buffer[:size])
+	}
+	return size, err
+}
+
+// Rotate writes the footer record returned by the OnRotate callback, calls
+// the given function to rotate the synchronizer (for example: to rename
+// the underlying file), and then writes the header record returned by the
+// OnOpen callback. The given function can be nil.
+//
+// Finally, any errors encountered are returned.
+func (s *LifecycleSyncer) Rotate(rotate func() error) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	if err := s.emit(s.onRotate); err != nil {
+		return err
+	}
+	if err := flushSyncer(s.syncer); err != nil {
+		return err
+	}
+	if rotate != nil {
+		if err := rotate(); err != nil {
+			return err
+		}
+	}
+	return s.open()
+}
+
+// State returns the state of the data written since the synchronizer was
+// opened or last rotated.
+func (s *LifecycleSyncer) State() LifecycleState {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.state()
+}
+
+// Flush writes the internally cached data of the synchronizer to a
+// specific storage device. If the synchronizer does not implement the
+// Flusher interface, it is synchronized instead.
+//
+// Finally, any errors encountered are returned.
+func (s *LifecycleSyncer) Flush() error {
+	return flushSyncer(s.syncer)
+}
+
+// Sync synchronizes the synchronizer. For details, please refer to the
+// Sync function of the Syncer interface.
+//
+// Finally, any errors encountered are returned.
+func (s *LifecycleSyncer) Sync() error {
+	return s.syncer.Sync()
+}
+
+// SyncLevel forwards the given level to the synchronizer if it implements
+// the LevelSyncer interface, and then returns any errors encountered.
+func (s *LifecycleSyncer) SyncLevel(level Level) error {
+	if syncer, ok := s.syncer.(LevelSyncer); ok {
+		return syncer.SyncLevel(level)
+	}
+	return nil
+}
+
+// Close writes the footer record returned by the OnClose callback, and
+// then closes the synchronizer. Calling it more than once returns the
+// ErrClosed error.
+//
+// Finally, any errors encountered are returned.
+func (s *LifecycleSyncer) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+	s.closed = true
+	var errs MultiError
+	errs = errs.Append(s.emit(s.onClose))
+	errs = errs.Append(s.syncer.Close())
+	return errs.ErrorOrNil()
+}
+
+// flushSyncer flushes the given synchronizer if it implements the Flusher
+// interface, otherwise synchronizes it, and then returns any errors
+// encountered.
+func flushSyncer(syncer Syncer) error {
+	if flusher, ok := syncer.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return syncer.Sync()
+}
+
+// LifecycleSyncerOption is a structure that contains options for lifecycle
+// synchronizers.
+type LifecycleSyncerOption struct {
+	// Syncer represents the synchronizer to which the log entry data and
+	// the records are written. This option is required.
+	Syncer Syncer
+
+	// OnOpen represents the callback that returns the header record. If
+	// not provided, the default value is nil, and no header record is
+	// written.
+	OnOpen LifecycleCallback
+
+	// OnRotate represents the callback that returns the footer record
+	// written before the synchronizer is rotated. If not provided, the
+	// OnClose callback is used instead.
+	OnRotate LifecycleCallback
+
+	// OnClose represents the callback that returns the footer record
+	// written before the synchronizer is closed. If not provided, the
+	// default value is nil, and no footer record is written.
+	OnClose LifecycleCallback
+}
+
+// UseSyncer uses the given synchronizer as the value of the option Syncer.
+// Then return to the option instance itself.
+func (o *LifecycleSyncerOption) UseSyncer(syncer Syncer) *LifecycleSyncerOption {
+	o.Syncer = syncer
+	return o
+}
+
+// UseOnOpen uses the given callback as the value of the option OnOpen.
+// Then return to the option instance itself.
+func (o *LifecycleSyncerOption) UseOnOpen(callback LifecycleCallback) *LifecycleSyncerOption {
+	o.OnOpen = callback
+	return o
+}
+
+// UseOnRotate uses the given callback as the value of the option OnRotate.
+// Then return to the option instance itself.
+func (o *LifecycleSyncerOption) UseOnRotate(callback LifecycleCallback) *LifecycleSyncerOption {
+	o.OnRotate = callback
+	return o
+}
+
+// UseOnClose uses the given callback as the value of the option OnClose.
+// Then return to the option instance itself.
+func (o *LifecycleSyncerOption) UseOnClose(callback LifecycleCallback) *LifecycleSyncerOption {
+	o.OnClose = callback
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *LifecycleSyncerOption) Validate() error {
+	if o.Syncer == nil {
+		return newOptionError("Syncer", "must not be nil", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a lifecycle synchronizer instance, and then
+// writes the header record returned by the OnOpen callback.
+func (o *LifecycleSyncerOption) Build() (*LifecycleSyncer, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	onRotate := o.OnRotate
+	if onRotate == nil {
+		onRotate = o.OnClose
+	}
+	syncer := &LifecycleSyncer {
+		syncer: o.Syncer,
+		onOpen: o.OnOpen,
+		onRotate: onRotate,
+		onClose: o.OnClose,
+		hash: sha256.New(),
+	}
+	if err := syncer.open(); err != nil {
+		return nil, err
+	}
+	return syncer, nil
+}
+
+// NewLifecycleSyncerOption creates and returns a lifecycle synchronizer
+// option instance with default optional values.
+func NewLifecycleSyncerOption() *LifecycleSyncerOption {
+	return &LifecycleSyncerOption { }
+}
+
+// lifecycleCounters is a structure that counts the log entries emitted and
+// dropped by a logger and its copies, which are reported by the lifecycle
+// log entries. For details, please refer to the comment section of the
+// EmitLifecycleEvents option of the Option structure.
+//
+// The functions of a nil counter do nothing, so that loggers without
+// lifecycle log entries do not count log entries.
+type lifecycleCounters struct {
+	emitted uint64
+	dropped uint64
+}
+
+// emit counts a log entry emitted to the exporters.
+func (c *lifecycleCounters) emit() {
+	if c != nil {
+		atomic.AddUint64(&c.emitted, 1)
+	}
+}
+
+// drop counts a log entry dropped by the sampler or the volume governor.
+func (c *lifecycleCounters) drop() {
+	if c != nil {
+		atomic.AddUint64(&c.dropped, 1)
+	}
+}
+
+// applicationVersion returns the version of the main module of the
+// application, or "(unknown)" if the build information is not available.
+func applicationVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || len(info.Main.Version) == 0 {
+		return "(unknown)"
+	}
+	return info.Main.Version
+}
+
+// startedMessage returns the message of the log entry output when a
+// logger with the given configuration is built.
+func startedMessage(config *loggerConfig) *StructMessage {
+	sampler := "none"
+	if config.sampler != nil {
+		sampler = fmt.Sprintf("%T", config.sampler)
+	}
+	return &StructMessage {
+		Text: "Logger started",
+		Fields: []Field {
+			Int("pid", int64(os.Getpid())),
+			String("version", applicationVersion()),
+			String("go_version", runtime.Version()),
+			String("level", config.level.String()),
+			String("sampler", sampler),
+			Int("hooks", int64(len(config.hooks))),
+			Int("exporters", int64(len(config.exporters))),
+			Boolean("source_location", config.addSource),
+		},
+	}
+}
+
+// stoppingMessage returns the message of the log entry output when a
+// logger with the given configuration is closed.
+func stoppingMessage(config *loggerConfig) *StructMessage {
+	return &StructMessage {
+		Text: "Logger stopping",
+		Fields: []Field {
+			Int("pid", int64(os.Getpid())),
+			Uint("emitted", atomic.LoadUint64(&config.counters.emitted)),
+			Uint("dropped", atomic.LoadUint64(&config.counters.dropped)),
+		},
+	}
+}
@@ -0,0 +1,89 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycleSyncer(t *testing.T) {
+	writer := &testLockedWriter { }
+	syncer, err := NewStandardSyncerOption().
+		UseWriter(writer).
+		UseCacheCapacity(0).
+		Build()
+	assert.NoError(t, err, "Unexpected synchronizer creation error")
+
+	footer := func(state LifecycleState) []byte {
+		return []byte(fmt.Sprintf("#Entries: %d %s\n", state.Entries,
+			state.Checksum))
+	}
+	lifecycle, err := NewLifecycleSyncerOption().
+		UseSyncer(syncer).
+		UseOnOpen(StaticRecord("#Version: 1.0\n")).
+		UseOnClose(footer).
+		Build()
+	assert.NoError(t, err, "Unexpected lifecycle synchronizer creation error")
+	assert.Equal(t, "#Version: 1.0\n", writer.String(),
+		"Unexpected header record")
+
+	_, err = lifecycle.Write([]byte("first\n"))
+	assert.NoError(t, err, "Unexpected write error")
+	_, err = lifecycle.Write([]byte("second\n"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.Equal(t, uint64(2), lifecycle.State().Entries,
+		"Unexpected entry count")
+
+	checksum := sha256.Sum256([]byte("first\nsecond\n"))
+	rotated := false
+	err = lifecycle.Rotate(func() error {
+		rotated = true
+		return nil
+	})
+	assert.NoError(t, err, "Unexpected rotation error")
+	assert.True(t, rotated, "Unexpected rotation function skipped")
+	assert.Equal(t, "#Version: 1.0\nfirst\nsecond\n#Entries: 2 " +
+		hex.EncodeToString(checksum[:]) + "\n#Version: 1.0\n",
+		writer.String(), "Unexpected rotation records")
+	assert.Equal(t, uint64(0), lifecycle.State().Entries,
+		"Unexpected entry count after rotation")
+
+	_, err = lifecycle.Write([]byte("third\n"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.NoError(t, lifecycle.Close(), "Unexpected close error")
+
+	checksum = sha256.Sum256([]byte("third\n"))
+	assert.Contains(t, writer.String(), "third\n#Entries: 1 " +
+		hex.EncodeToString(checksum[:]) + "\n", "Unexpected footer record")
+
+	_, err = lifecycle.Write([]byte("fourth\n"))
+	assert.True(t, errors.Is(err, ErrClosed), "Unexpected write error")
+	assert.True(t, errors.Is(lifecycle.Close(), ErrClosed),
+		"Unexpected close error")
+}
@@ -91,6 +91,15 @@ func Sync() error {
 	return logger.Sync()
 }
 
+// TriggerFlush asks the automatic flushing to flush the internal cache
+// and file system cache immediately. For details, please refer to the
+// TriggerFlush function of the StandardLogger structure.
+//
+// Finally, any errors encountered are returned.
+func TriggerFlush() error {
+	return logger.TriggerFlush()
+}
+
 // Duplicate creates and returns a copy of the logger. If the logger is
 // closed, it returns nil.
 //
@@ -104,7 +113,8 @@ func Duplicate() *santa.StandardLogger {
 // refer to the comment section of the Name field of the StandardOption
 // structure.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries.
 func SetName(name string) {
 	logger.SetName(name)
 }
@@ -113,7 +123,8 @@ func SetName(name string) {
 // For details, please refer to the comment section of the Level field of
 // the StandardOption structure.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries.
 func SetLevel(level santa.Level) {
 	logger.SetLevel(level)
 }
@@ -122,7 +133,8 @@ func SetLevel(level santa.Level) {
 // refer to the comment section of the Sampler field of the Option
 // structure.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries.
 func SetSampler(sampler santa.Sampler) {
 	logger.SetSampler(sampler)
 }
@@ -134,7 +146,8 @@ func SetSampler(sampler santa.Sampler) {
 // It is worth noting that one or more labels previously set by the
 // logger will be discarded because labels need to be pre-serialized.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries.
 func SetLabels(labels ...santa.Label) {
 	logger.SetLabels(labels...)
 }
@@ -143,7 +156,8 @@ func SetLabels(labels ...santa.Label) {
 // please refer to the comment section of the Hooks field of the Option
 // option.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries.
 func AddHooks(hooks ...santa.Hook) {
 	logger.AddHooks(hooks...)
 }
@@ -152,7 +166,8 @@ func AddHooks(hooks ...santa.Hook) {
 // will be removed. For details, please refer to the comment section of
 // the Hooks field of the Option option.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries.
 func ResetHooks() {
 	logger.ResetHooks()
 }
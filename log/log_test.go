@@ -72,6 +72,9 @@ func TestStructured(t *testing.T) {
 	err = Sync()
 	assert.NoError(t, err, "Unexpected sync error")
 
+	err = TriggerFlush()
+	assert.NoError(t, err, "Unexpected flush error")
+
 	err = Close()
 	assert.NoError(t, err, "Unexpected close error")
 }
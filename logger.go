@@ -28,6 +28,8 @@ import (
 	"io"
 	"os"
 	"runtime"
+	"runtime/pprof"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -43,6 +45,17 @@ var (
 	ErrClosed = errors.New("instance has been closed")
 )
 
+const (
+	// ProfileLabelName is the key of the runtime/pprof label whose value
+	// is the name of the logger. For details, please refer to the comment
+	// section of the Profiling option of the StandardOption structure.
+	ProfileLabelName = "santa.name"
+
+	// ProfileLabelLevel is the key of the runtime/pprof label whose value
+	// is the level of the log entry being output.
+	ProfileLabelLevel = "santa.level"
+)
+
 // Logger is the structure of the logger instance.
 //
 // The logger is the foundation of all logger types. It provides simple
@@ -56,14 +69,57 @@ var (
 //
 // The API provided by the logger is thread-safe.
 type Logger struct {
+	snapshot atomic.Value
+}
+
+// loggerConfig is a structure that contains the configuration of the
+// logger. The configuration is immutable once it has been published to
+// a logger, and every change creates a new copy of the configuration that
+// is swapped atomically, so that each log entry is output using a single
+// consistent configuration.
+type loggerConfig struct {
 	name string
+	nameSeparator string
 	level Level
 	sampler Sampler
 	hooks []Hook
 	exporters []Exporter
 	labels SerializedLabels
+	observer *DropObserver
+	tracer *pipelineTracer
+	counters *lifecycleCounters
+	now TimeSource
 
 	addSource bool
+	development bool
+}
+
+// emptyLoggerConfig is the configuration of a logger that has not been
+// built, which does not output any log entries.
+var emptyLoggerConfig = &loggerConfig { }
+
+// config returns the current configuration of the logger.
+func (l *Logger) config() *loggerConfig {
+	config, _ := l.snapshot.Load().(*loggerConfig)
+	if config == nil {
+		return emptyLoggerConfig
+	}
+	return config
+}
+
+// update calls the given handler with a copy of the current configuration
+// of the logger, and then atomically replaces the current configuration
+// with the copy. If the configuration is changed concurrently, the handler
+// is called again with a copy of the new configuration.
+func (l *Logger) update(handler func(config *loggerConfig)) {
+	for {
+		current := l.snapshot.Load()
+		config := *l.config()
+		handler(&config)
+		if l.snapshot.CompareAndSwap(current, &config) {
+			return
+		}
+	}
 }
 
 // Output checks whether the log level is lower than the minimum log
@@ -74,60 +130,183 @@ type Logger struct {
 // entry exporters for processing, and any errors encountered are
 // returned.
 //
+// The configuration of the logger is read once for each log entry, so
+// concurrent changes to the logger do not affect log entries being output.
+//
 // Please note that this is a low-level API, and the high-level API
 // usually provided by the logger is used internally. Unless necessary,
 // applications should not use this API directly.
 func (l *Logger) Output(stacks int, level Level, message Message) error {
-	if !l.level.Enabled(level) {
+	return l.outputAt(nil, stacks + 1, time.Time { }, level, message)
+}
+
+// OutputAt outputs a log entry like the Output function, but the time of
+// the log entry is the given time instead of the time read from the time
+// source of the logger. For details, please refer to the comment section
+// of the TimeOverridden field of the Entry structure.
+//
+// Please note that this is a low-level API, and the high-level API
+// usually provided by the logger is used internally. Unless necessary,
+// applications should not use this API directly.
+func (l *Logger) OutputAt(stacks int, at time.Time, level Level,
+	message Message) error {
+	return l.outputAt(nil, stacks + 1, at, level, message)
+}
+
+// outputContext outputs a log entry associated with the given context.
+// For details, please refer to the comment section of the Output function.
+func (l *Logger) outputContext(ctx context.Context, stacks int, level Level,
+	message Message) error {
+	return l.outputAt(ctx, stacks + 1, time.Time { }, level, message)
+}
+
+// outputAt outputs a log entry associated with the given context with the
+// given time. If the given time is zero, the time is read from the time
+// source of the logger. For details, please refer to the comment section
+// of the OutputAt function.
+func (l *Logger) outputAt(ctx context.Context, stacks int, at time.Time,
+	level Level, message Message) error {
+	config := l.config()
+	if !config.level.Enabled(level) || IsSilenced() {
 		return nil
 	}
-	if len(l.exporters) == 0 {
+	if config.development {
+		checkMessage(message)
+	}
+	if len(config.exporters) == 0 {
 		return nil
 	}
 
 	entry := pool.Entry.New()
-	entry.Name = l.name
+	entry.Name = config.name
 	entry.Level = level
-	entry.Time = time.Now()
+	if entry.TimeOverridden = !at.IsZero(); entry.TimeOverridden {
+		entry.Time = at
+	} else {
+		entry.Time = config.now()
+	}
 	entry.Message = message
-	entry.Labels = l.labels
-
-	if l.sampler != nil && !l.sampler.Sample(entry) {
+	entry.Labels = config.labels
+	entry.Context = ctx
+	trace := config.tracer.start(entry)
+
+	retaining, _ := config.sampler.(RetainingSampler)
+	if config.addSource && retaining != nil {
+		// The log entry may be retained by the sampler, so its source
+		// location is captured before it is sampled.
+		entry.SourceLocation = newEntrySourceLocation(
+			runtime.Caller(stacks))
+	}
+	if config.sampler != nil && !config.sampler.Sample(entry) {
+		config.observer.observe(entry, DropSampled)
+		config.counters.drop()
+		trace.decide("sampler", 0, config.sampler, PipelineDropped, nil)
+		config.tracer.report(trace)
 		pool.Entry.Free(entry)
-		return nil
+		return releaseRetained(config, retaining, false)
+	}
+	if config.sampler != nil {
+		trace.decide("sampler", 0, config.sampler, PipelineAccepted, nil)
 	}
-	if l.addSource {
+	if config.addSource && retaining == nil {
 		entry.SourceLocation = newEntrySourceLocation(
 			runtime.Caller(stacks))
 	}
 
-	for index := 0; index < len(l.hooks); index++ {
-		err := l.hooks[index].Print(entry)
+	// The retained log entries were discarded before the log entry, so
+	// they are output first.
+	released := releaseRetained(config, retaining, false)
+	err := exportEntry(config, entry, trace)
+	pool.Entry.Free(entry)
+	if err != nil {
+		return err
+	}
+	return released
+}
 
-		if err != nil {
-			pool.Entry.Free(entry)
+// exportEntry passes the given log entry to each hook and then to each
+// exporter of the given configuration, and then returns the first error
+// encountered. If the given trace is not nil, the decisions of the hooks
+// and the exporters are recorded to it and it is reported.
+func exportEntry(config *loggerConfig, entry *Entry,
+	trace *PipelineTrace) error {
+	if trace != nil {
+		return traceEntry(config, entry, trace)
+	}
+	for index := 0; index < len(config.hooks); index++ {
+		if err := config.hooks[index].Print(entry); err != nil {
 			return err
 		}
 	}
-	for index := 0; index < len(l.exporters); index++ {
-		err := l.exporters[index].Export(entry)
-
-		if err != nil {
-			pool.Entry.Free(entry)
+	for index := 0; index < len(config.exporters); index++ {
+		if err := config.exporters[index].Export(entry); err != nil {
 			return err
 		}
 	}
-
-	pool.Entry.Free(entry)
+	config.counters.emit()
 	return nil
 }
 
+// releaseRetained outputs the log entries released by the given retaining
+// sampler with the given configuration, and then returns all errors
+// encountered as a MultiError. If the given force is true, all retained
+// log entries are released. For details, please refer to the comment
+// section of the RetainingSampler interface.
+func releaseRetained(config *loggerConfig, sampler RetainingSampler,
+	force bool) error {
+	if sampler == nil {
+		return nil
+	}
+	var errs MultiError
+	sampler.Release(func(entry *Entry) {
+		trace := config.tracer.start(entry)
+		trace.decide("sampler", 0, sampler, PipelineReleased, nil)
+		errs = errs.Append(exportEntry(config, entry, trace))
+	}, force)
+	return errs.ErrorOrNil()
+}
+
 // Print outputs log entries for a given log level and message, and then
 // returns any errors encountered.
 func (l *Logger) Print(level Level, message Message) error {
 	return l.Output(2, level, message)
 }
 
+// Name returns the name of log entries of the logger.
+func (l *Logger) Name() string {
+	return l.config().name
+}
+
+// NameSeparator returns the separator between the segments of the
+// hierarchical names of the logger. For details, please refer to the
+// comment section of the NameSeparator option of the Option structure.
+func (l *Logger) NameSeparator() string {
+	return l.config().nameSeparator
+}
+
+// Level returns the lowest level of log entries of the logger.
+func (l *Logger) Level() Level {
+	return l.config().level
+}
+
+// Hooks returns the hook chain of the logger. The returned slice is shared
+// with the logger and must not be modified.
+func (l *Logger) Hooks() []Hook {
+	return l.config().hooks
+}
+
+// Sampler returns the sampler of the logger. If sampling is disabled, it
+// returns nil.
+func (l *Logger) Sampler() Sampler {
+	return l.config().sampler
+}
+
+// Exporters returns the exporters of the logger. The returned slice is
+// shared with the logger and must not be modified.
+func (l *Logger) Exporters() []Exporter {
+	return l.config().exporters
+}
+
 // Option is a structure that contains options for the logger.
 //
 // Normally, all the logger option types of all logger types rely on the
@@ -140,6 +319,13 @@ type Option struct {
 	// value is empty.
 	Name string
 
+	// NameSeparator represents the separator between the segments of
+	// hierarchical names (for example: "app.db.pool"), which is used to
+	// join the names of the loggers derived by the Named function of the
+	// structured logger. If not provided, the default value is the
+	// DefaultNameSeparator constant.
+	NameSeparator string
+
 	// Level represents the lowest level of log entries, and log entries
 	// below the lowest level will be discarded. If not provided, the
 	// default lowest level is DEBUG.
@@ -206,19 +392,102 @@ type Option struct {
 	// expensive performance overhead. If not provided, the default value
 	// is false.
 	DisableSourceLocation bool
+
+	// DropObserver represents a drop observer that reports the log entries
+	// dropped by the logger. For details, please refer to the comment
+	// section of the DropObserver structure. If not provided, dropped log
+	// entries are not reported.
+	DropObserver *DropObserver
+
+	// EmitLifecycleEvents represents whether the logger outputs a "Logger
+	// started" log entry with a summary of its configuration, the process
+	// ID and the version of the application when it is built, and a
+	// "Logger stopping" log entry with the number of log entries emitted
+	// and dropped when it is closed, which helps to build incident
+	// timelines. If not provided, the default value is false.
+	//
+	// Please note that only loggers that can be closed (for example: the
+	// standard logger) output the "Logger stopping" log entry.
+	EmitLifecycleEvents bool
+
+	// Development represents whether the logger is in the strict development
+	// mode, in which the misuses of the logger panic with a MisuseError
+	// immediately instead of silently degrading, so that they are caught by
+	// tests. The misuses include: outputting log entries after the logger
+	// is closed, duplicate field names, field values of unknown types
+	// (rendered as "???"), and template verbs that do not match the
+	// parameters (rendered as "%!" markers). If not provided, the default
+	// value is false.
+	//
+	// Please note that the log messages are checked before they are
+	// sampled, which is expensive, so it should not be enabled in
+	// production.
+	Development bool
+
+	// TimeSource represents the function that returns the time of each
+	// log entry. For example, the Now function of a coarse clock (see the
+	// CoarseClock structure) trades the precision of the timestamps for
+	// fewer calls to the time.Now function at extreme logging rates. If
+	// not provided, the default value is the time.Now function.
+	TimeSource TimeSource
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *Option) Validate() error {
+	if o.Level > LevelFatal {
+		return newOptionError("Level", "unknown level " + o.Level.String(),
+			ErrInvalidLevel)
+	}
+	for index := 0; index < len(o.Hooks); index++ {
+		if o.Hooks[index] == nil {
+			return newOptionError("Hooks[" + strconv.Itoa(index) + "]",
+				"must not be nil", nil)
+		}
+	}
+	for index := 0; index < len(o.Exporters); index++ {
+		if o.Exporters[index] == nil {
+			return newOptionError("Exporters[" + strconv.Itoa(index) + "]",
+				"must not be nil", nil)
+		}
+	}
+	return nil
 }
 
 // Build builds and returns an instance of the logger.
 func (o *Option) Build() (*Logger, error) {
-	return &Logger {
+	instance := &Logger { }
+	config := &loggerConfig {
 		name: o.Name,
+		nameSeparator: o.NameSeparator,
 		level: o.Level,
 		sampler: o.Sampler,
 		hooks: o.Hooks,
 		exporters: o.Exporters,
 		labels: NewSerializedLabels(o.Labels...),
+		observer: o.DropObserver,
+		now: o.TimeSource,
 		addSource: !o.DisableSourceLocation,
-	}, nil
+		development: o.Development,
+	}
+	if config.now == nil {
+		config.now = time.Now
+	}
+	if len(config.nameSeparator) == 0 {
+		config.nameSeparator = DefaultNameSeparator
+	}
+	if o.EmitLifecycleEvents {
+		config.counters = &lifecycleCounters { }
+	}
+	instance.snapshot.Store(config)
+	if o.EmitLifecycleEvents {
+		if err := instance.outputContext(nil, 1, LevelInfo,
+			startedMessage(config)); err != nil {
+			return nil, err
+		}
+	}
+	return instance, nil
 }
 
 // NewOption creates and returns a logger option instance with default
@@ -263,12 +532,12 @@ func New() (*Logger, error) {
 // interface.
 //
 // Unless explicitly stated, the API provided by the logger is
-// thread-safe. It’s worth noting that APIs that allow post-build
-// changes to logger instances are generally not thread-safe. If you
-// need to change the logger instance (including but not limited to:
-// minimum log entry level, etc.), use the Duplicate function to create
-// a copy of the logger instance, and then make changes to the copy of
-// the logger instance.
+// thread-safe, including the APIs that allow post-build changes to
+// logger instances (including but not limited to: minimum log entry
+// level, etc.). Changes only take effect for subsequent log entries of
+// the changed logger instance. If you need a differently configured
+// logger, use the Duplicate function to create a copy of the logger
+// instance, and then make changes to the copy of the logger instance.
 type StandardLogger struct {
 	Logger
 
@@ -276,6 +545,16 @@ type StandardLogger struct {
 	contextCancel context.CancelFunc
 	contextWaitGroup *sync.WaitGroup
 	contextReferences *int32
+	contextTrigger chan struct { }
+
+	flushOnLevel bool
+	flushLevel Level
+
+	samplerIsolated bool
+	latency *LatencyHistogram
+	profiling bool
+	reentrancy *reentrancyGuard
+	governor *VolumeGovernor
 
 	closed int32
 }
@@ -310,46 +589,116 @@ func (l *StandardLogger) Fatal(message Message) error {
 	return l.Output(2, LevelFatal, message)
 }
 
+// acquire increases the reference count of the logger. It returns false
+// if the logger has been closed, in which case the reference count is not
+// changed.
+func (l *StandardLogger) acquire() bool {
+	if l.IsClosed() {
+		return false
+	}
+	for {
+		references := atomic.LoadInt32(l.contextReferences)
+		if references <= 0 {
+			// The logger has been shut down, and using the created copy
+			// may cause panic.
+			return false
+		}
+		if atomic.CompareAndSwapInt32(l.contextReferences, references,
+			references + 1) {
+			return true
+		}
+	}
+}
+
+// duplicate creates and returns a copy of the logger without changing the
+// reference count of the logger. If the sampler state is isolated, the
+// copy uses a clone of the sampler.
+func (l *StandardLogger) duplicate() *StandardLogger {
+	instance := &StandardLogger { }
+	l.duplicateTo(instance)
+	return instance
+}
+
+// duplicateTo initializes the given logger instance as a copy of the
+// logger without changing the reference count of the logger. The copy
+// shares the current configuration with the logger until either of them
+// is changed. The given instance may be a reused instance, which avoids
+// allocating a new one.
+func (l *StandardLogger) duplicateTo(instance *StandardLogger) {
+	instance.snapshot.Store(l.config())
+	instance.context = l.context
+	instance.contextCancel = l.contextCancel
+	instance.contextWaitGroup = l.contextWaitGroup
+	instance.contextReferences = l.contextReferences
+	instance.contextTrigger = l.contextTrigger
+	instance.flushOnLevel = l.flushOnLevel
+	instance.flushLevel = l.flushLevel
+	instance.samplerIsolated = l.samplerIsolated
+	instance.latency = l.latency
+	instance.profiling = l.profiling
+	instance.reentrancy = l.reentrancy
+	instance.governor = l.governor
+	atomic.StoreInt32(&instance.closed, 0)
+	if l.samplerIsolated {
+		instance.update(func(config *loggerConfig) {
+			if cloner, ok := config.sampler.(SamplerCloner); ok {
+				config.sampler = cloner.CloneSampler()
+			}
+		})
+	}
+}
+
 // Duplicate creates and returns a copy of the logger. If the logger is
 // closed, it returns nil.
 //
+// This function can be called concurrently with other copies of the logger
+// being closed.
+//
 // Please note that the application must explicitly close each copy of
 // the logger, otherwise the logger may be leaked.
 func (l *StandardLogger) Duplicate() *StandardLogger {
-	if atomic.AddInt32(l.contextReferences, 1) == 1 {
-		// The logger has been shut down, and using the created copy
-		// may cause panic.
+	if !l.acquire() {
 		return nil
 	}
-	instance := *l
-	return &instance
+	instance := l.duplicate()
+	trackLeak(instance, (*StandardLogger).closedForLeak)
+	return instance
 }
 
 // SetName sets the log entry name to the given name. For details, please
 // refer to the comment section of the Name field of the StandardOption
 // structure.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries. Other copies of the logger are not affected.
 func (l *StandardLogger) SetName(name string) {
-	l.name = name
+	l.update(func(config *loggerConfig) {
+		config.name = name
+	})
 }
 
 // SetLevel sets the lowest level of the log entry to the given level.
 // For details, please refer to the comment section of the Level field of
 // the StandardOption structure.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries. Other copies of the logger are not affected.
 func (l *StandardLogger) SetLevel(level Level) {
-	l.level = level
+	l.update(func(config *loggerConfig) {
+		config.level = level
+	})
 }
 
 // SetSampler sets the sampler to the given sampler. For details, please
 // refer to the comment section of the Sampler field of the Option
 // structure.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries. Other copies of the logger are not affected.
 func (l *StandardLogger) SetSampler(sampler Sampler) {
-	l.sampler = sampler
+	l.update(func(config *loggerConfig) {
+		config.sampler = sampler
+	})
 }
 
 // SetLabels sets the label to one or more given labels. For details,
@@ -359,27 +708,41 @@ func (l *StandardLogger) SetSampler(sampler Sampler) {
 // It is worth noting that one or more labels previously set by the
 // logger will be discarded because labels need to be pre-serialized.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries. Other copies of the logger are not affected.
 func (l *StandardLogger) SetLabels(labels ...Label) {
-	l.labels = NewSerializedLabels(labels...)
+	serialized := NewSerializedLabels(labels...)
+	l.update(func(config *loggerConfig) {
+		config.labels = serialized
+	})
 }
 
 // AddHooks adds one or more hooks to the hook chain. For details,
 // please refer to the comment section of the Hooks field of the Option
 // option.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries. Other copies of the logger are not affected.
 func (l *StandardLogger) AddHooks(hooks ...Hook) {
-	l.hooks = append(l.hooks, hooks...)
+	l.update(func(config *loggerConfig) {
+		// The hook chain is copied, because the current hook chain may
+		// be used by log entries being output.
+		chain := make([]Hook, 0, len(config.hooks) + len(hooks))
+		chain = append(chain, config.hooks...)
+		config.hooks = append(chain, hooks...)
+	})
 }
 
 // ResetHooks resets the hook chain, and the hooks that have been added
 // will be removed. For details, please refer to the comment section of
 // the Hooks field of the Option option.
 //
-// Please note that this API is not thread-safe.
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries. Other copies of the logger are not affected.
 func (l *StandardLogger) ResetHooks() {
-	l.hooks = []Hook { }
+	l.update(func(config *loggerConfig) {
+		config.hooks = []Hook { }
+	})
 }
 
 // Sync writes the internal cache data of a specific synchronizer to a
@@ -388,14 +751,82 @@ func (l *StandardLogger) ResetHooks() {
 // persistent storage device. For details, please refer to the Sync
 // function of the Syncer interface.
 //
-// Finally, any errors encountered are returned.
+// Every exporter is synchronized even if synchronizing one or more
+// exporters fails. Finally, all errors encountered are returned as a
+// MultiError, and each error is wrapped by ExporterError to identify
+// which exporter failed.
+//
+// If the logger instance has been closed, ErrClosed is returned.
 func (l *StandardLogger) Sync() error {
-	for index := 0; index < len(l.exporters); index++ {
-		err := l.exporters[index].Sync()
+	if l.IsClosed() {
+		return ErrClosed
+	}
+	config := l.config()
+	retaining, _ := config.sampler.(RetainingSampler)
+	if err := releaseRetained(config, retaining, true); err != nil {
+		return MultiError { err }.Append(syncExporters(config.exporters))
+	}
+	return syncExporters(config.exporters)
+}
 
-		if err != nil {
-			return err
+// SyncDetailed synchronizes each exporter like the Sync function, and then
+// returns the result of each exporter, including the number of bytes
+// written and the duration of the synchronization, so that applications
+// (for example: checkpointing batch jobs) can assert that the log entry
+// data of specific exporters is durable before proceeding. For details,
+// please refer to the comment section of the SyncResult structure.
+//
+// If releasing the log entries retained by the sampler fails, the error is
+// reported by the result of every exporter, because it is unknown which
+// exporters have not written them. If the logger instance has been closed,
+// the result of every exporter reports ErrClosed.
+func (l *StandardLogger) SyncDetailed() []SyncResult {
+	config := l.config()
+	if l.IsClosed() {
+		results := make([]SyncResult, len(config.exporters))
+		for index := range results {
+			results[index] = SyncResult {
+				Index: index,
+				Exporter: config.exporters[index],
+				Bytes: -1,
+				Err: ErrClosed,
+			}
 		}
+		return results
+	}
+	retaining, _ := config.sampler.(RetainingSampler)
+	released := releaseRetained(config, retaining, true)
+	results := syncExportersDetailed(config.exporters)
+	if released != nil {
+		for index := range results {
+			var errs MultiError
+			errs = errs.Append(released).Append(results[index].Err)
+			results[index].Err = errs
+		}
+	}
+	return results
+}
+
+// TriggerFlush asks the automatic flushing to flush the internal cache
+// and file system cache immediately instead of waiting for the next
+// interval, and then returns without waiting for the flush to complete.
+// If automatic flushing is disabled, the logger is synchronized directly
+// and any errors encountered are returned. For details, please refer to
+// the comment section of the FlushingOption structure.
+//
+// If the logger instance has been closed, ErrClosed is returned.
+func (l *StandardLogger) TriggerFlush() error {
+	if l.IsClosed() {
+		return ErrClosed
+	}
+	if l.contextTrigger == nil {
+		return syncExporters(l.config().exporters)
+	}
+	select {
+	case l.contextTrigger <- struct { } { }:
+	default:
+		// A flush has already been triggered and not yet performed, so
+		// the log entry data written so far will be flushed by it.
 	}
 	return nil
 }
@@ -404,6 +835,13 @@ func (l *StandardLogger) Sync() error {
 // encountered. For details, please refer to the comment section of the
 // Close function of the Exporter interface.
 //
+// Exporters are closed in dependency order, each exporter is closed before
+// the exporters it depends on. For details, please refer to the comment
+// section of the DependentExporter interface. Every exporter is closed even
+// if closing one or more exporters fails, and all errors encountered are
+// returned as a MultiError. Each error is wrapped by ExporterError to
+// identify which exporter failed.
+//
 // If there are multiple copies of the logger, this function only reduces
 // the reference count of the logger. If the logger's reference count is 0,
 // it will actually be closed.
@@ -428,38 +866,251 @@ func (l *StandardLogger) Close() error {
 	}
 	l.contextCancel()
 	l.contextWaitGroup.Wait()
-	for index := 0; index < len(l.exporters); index++ {
-		err := l.exporters[index].Close()
-		if err != nil {
-			return err
-		}
+	config := l.config()
+	retaining, _ := config.sampler.(RetainingSampler)
+	var errs MultiError
+	errs = errs.Append(releaseRetained(config, retaining, true))
+	if config.counters != nil {
+		errs = errs.Append(l.Logger.outputContext(nil, 1, LevelInfo,
+			stoppingMessage(config)))
 	}
-	return nil
+	if len(errs) > 0 {
+		return errs.Append(closeExporters(config.exporters))
+	}
+	return closeExporters(config.exporters)
 }
 
 // IsClosed checks whether the logger instance has been closed.
+//
+// The logger instance is closed if its Close function has been called, or
+// if all copies of the logger have been closed. Closing a copy of the
+// logger does not close the other copies.
 func (l *StandardLogger) IsClosed() bool {
+	if atomic.LoadInt32(&l.closed) == 1 {
+		return true
+	}
+	return l.contextReferences == nil ||
+		atomic.LoadInt32(l.contextReferences) <= 0
+}
+
+// Output checks whether the logger instance has been closed. If it has
+// been closed, ErrClosed is returned. Otherwise, the log entry is output.
+// For details, please refer to the comment section of the Output function
+// of the Logger structure.
+//
+// If the level of the log entry is greater than or equal to the flush
+// level of the FlushingOption structure, the logger is synchronized
+// before returning.
+//
+// Please note that this is a low-level API, and the high-level API
+// usually provided by the logger is used internally. Unless necessary,
+// applications should not use this API directly.
+func (l *StandardLogger) Output(stacks int, level Level, message Message) error {
+	return l.outputAt(nil, stacks + 1, time.Time { }, level, message)
+}
+
+// OutputAt checks whether the logger instance has been closed. If it has
+// been closed, ErrClosed is returned. Otherwise, the log entry is output
+// with the given time. For details, please refer to the comment section of
+// the OutputAt function of the Logger structure.
+//
+// Please note that this is a low-level API, and the high-level API
+// usually provided by the logger is used internally. Unless necessary,
+// applications should not use this API directly.
+func (l *StandardLogger) OutputAt(stacks int, at time.Time, level Level,
+	message Message) error {
+	return l.outputAt(nil, stacks + 1, at, level, message)
+}
+
+// outputContext outputs a log entry associated with the given context.
+// For details, please refer to the comment section of the Output function.
+func (l *StandardLogger) outputContext(ctx context.Context, stacks int,
+	level Level, message Message) error {
+	return l.outputAt(ctx, stacks + 1, time.Time { }, level, message)
+}
+
+// outputAt outputs a log entry associated with the given context with the
+// given time. If the given time is zero, the time is read from the time
+// source of the logger. For details, please refer to the comment section
+// of the OutputAt function.
+func (l *StandardLogger) outputAt(ctx context.Context, stacks int,
+	at time.Time, level Level, message Message) error {
+	if l.latency == nil {
+		return l.outputProfiled(ctx, stacks + 1, at, level, message)
+	}
+	start := time.Now()
+	err := l.outputProfiled(ctx, stacks + 1, at, level, message)
+	l.latency.Record(time.Since(start))
+	return err
+}
+
+// outputProfiled outputs a log entry associated with the given context. If
+// profiling is enabled, the output operation is performed with the profiler
+// labels of the logger. For details, please refer to the comment section
+// of the Profiling option of the StandardOption structure.
+func (l *StandardLogger) outputProfiled(ctx context.Context, stacks int,
+	at time.Time, level Level, message Message) error {
+	if !l.profiling {
+		return l.outputEntry(ctx, stacks + 1, at, level, message)
+	}
+	parent := ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	var err error
+	pprof.Do(parent, pprof.Labels(ProfileLabelName, l.Name(),
+		ProfileLabelLevel, level.String()), func(labeled context.Context) {
+		// The closure and the pprof.Do function are two more frames
+		// between this function and the output operation.
+		err = l.outputEntry(labeled, stacks + 3, at, level, message)
+	})
+	return err
+}
+
+// outputEntry outputs a log entry associated with the given context
+// without recording its latency. For details, please refer to the comment
+// section of the Output function.
+func (l *StandardLogger) outputEntry(ctx context.Context, stacks int,
+	at time.Time, level Level, message Message) error {
+	if atomic.LoadInt32(&l.closed) == 1 {
+		if l.config().development {
+			misuse("log entry output after the logger is closed")
+		}
+		return ErrClosed
+	}
+	if l.reentrancy != nil {
+		id, ok := l.reentrancy.enter()
+		if !ok {
+			if !l.Level().Enabled(level) {
+				return nil
+			}
+			return l.reentrancy.divert(l.Name(), level, message)
+		}
+		defer l.reentrancy.leave(id)
+	}
+	if l.governor != nil {
+		config, minimum := l.config(), l.Level()
+		if notice := l.governor.evaluate(config.now()); notice != nil {
+			// Any errors encountered by the governance log entry are
+			// discarded, so that it does not fail the log entry.
+			_ = l.Logger.outputContext(ctx, stacks + 1, LevelWarning,
+				l.governor.message(notice, minimum))
+		}
+		if !l.governor.Level(minimum).Enabled(level) {
+			if minimum.Enabled(level) {
+				config.counters.drop()
+				if observer := config.observer; observer != nil {
+					record := DropRecord {
+						Name: l.Name(),
+						Level: level,
+						Time: config.now(),
+						Reason: DropGoverned,
+					}
+					if parser, ok := message.(TextSampleParser); ok {
+						record.Text = parser.SampleText()
+					}
+					observer.Observe(record)
+				}
+			}
+			return nil
+		}
+	}
+	err := l.Logger.outputAt(ctx, stacks + 1, at, level, message)
+	if err != nil || !l.flushOnLevel || level < l.flushLevel ||
+		!l.Level().Enabled(level) {
+		return err
+	}
+	return syncExporters(l.config().exporters)
+}
+
+// Print outputs log entries for a given log level and message, and then
+// returns any errors encountered. If the logger instance has been closed,
+// ErrClosed is returned.
+func (l *StandardLogger) Print(level Level, message Message) error {
+	return l.Output(2, level, message)
+}
+
+// PrintAt outputs log entries for a given time, log level and message, and
+// then returns any errors encountered. For details, please refer to the
+// comment section of the OutputAt function.
+func (l *StandardLogger) PrintAt(at time.Time, level Level,
+	message Message) error {
+	return l.OutputAt(2, at, level, message)
+}
+
+// OutputSync outputs the log entry, and then returns only after the
+// internal cache of each exporter has been handed to the operating system.
+// If durable is true, the data cached by the file system is also written
+// to the persistent storage device. For details, please refer to the
+// comment section of the Output function.
+//
+// Please note that the internal cache is flushed as a whole, so other log
+// entries cached before the log entry are also flushed.
+func (l *StandardLogger) OutputSync(stacks int, level Level, message Message,
+	durable bool) error {
+	return l.outputSync(nil, stacks + 1, time.Time { }, level, message,
+		durable)
+}
+
+// outputSync outputs a log entry associated with the given context with
+// the given time, and then flushes the exporters. For details, please
+// refer to the comment section of the OutputSync function.
+func (l *StandardLogger) outputSync(ctx context.Context, stacks int,
+	at time.Time, level Level, message Message, durable bool) error {
+	if err := l.outputAt(ctx, stacks + 1, at, level, message); err != nil {
+		return err
+	}
+	if !l.Level().Enabled(level) {
+		return nil
+	}
+	if durable {
+		return syncExporters(l.config().exporters)
+	}
+	return flushExporters(l.config().exporters)
+}
+
+// PrintSync outputs log entries for a given log level and message, and
+// then returns any errors encountered after the log entry has been handed
+// to the operating system. It is intended for single audit-critical log
+// entries of an otherwise buffered logger. For details, please refer to
+// the comment section of the OutputSync function.
+func (l *StandardLogger) PrintSync(level Level, message Message) error {
+	return l.OutputSync(2, level, message, false)
+}
+
+// closedForLeak checks whether the logger instance has been closed for
+// leak detection.
+func (l *StandardLogger) closedForLeak() bool {
 	return atomic.LoadInt32(&l.closed) == 1
 }
 
-// flushHandler calls the Sync function at a given time interval to
+// flushHandler synchronizes the given exporters at a given time interval,
+// or immediately when the given trigger channel receives a value, to
 // automatically refresh the internal cache and file system cache until
-// the context has been marked as complete and returns.
+// the given context has been marked as complete and returns.
+//
+// The handler does not reference the logger instance, so that a logger
+// that is no longer used can be garbage collected and detected as leaked.
 //
 // This function should run in an independent coroutine context.
-func (l *StandardLogger) flushHandler(interval time.Duration) {
+func flushHandler(ctx context.Context, group *sync.WaitGroup,
+	trigger <-chan struct { }, exporters []Exporter,
+	interval time.Duration) {
 	if interval < (time.Microsecond * 100) {
 		// The interval must not be less than 100 milliseconds.
 		interval = (time.Microsecond * 100)
 	}
-	defer l.contextWaitGroup.Done()
+	defer group.Done()
 	for {
 		select {
-		case <-l.context.Done():
+		case <-ctx.Done():
 			return
+		case <-trigger:
+			// Discard any errors encountered.
+			_ = syncExporters(exporters)
 		case <-time.After(interval):
 			// Discard any errors encountered.
-			_ = l.Sync()
+			_ = syncExporters(exporters)
 		}
 	}
 }
@@ -469,6 +1120,21 @@ const (
 	// details, please refer to the comment section of the TextSampler
 	// structure.
 	SamplerText = "text"
+
+	// SamplerHeadTail represents the type of sampler as head and tail
+	// sampler. For details, please refer to the comment section of the
+	// HeadTailSampler structure.
+	SamplerHeadTail = "head-tail"
+
+	// SamplerAdaptive represents the type of sampler as adaptive sampler.
+	// For details, please refer to the comment section of the
+	// AdaptiveSampler structure.
+	SamplerAdaptive = "adaptive"
+
+	// SamplerRate represents the type of sampler as rate sampler. For
+	// details, please refer to the comment section of the RateSampler
+	// structure.
+	SamplerRate = "rate"
 )
 
 // SamplingOption is a structure that contains options for sampling log
@@ -490,6 +1156,58 @@ type SamplingOption struct {
 	// different. If not provided, the default value is the default optional
 	// value for the specific sampler type.
 	Option interface { }
+
+	// Isolated represents whether each copy of the logger created by the
+	// Duplicate function uses its own sampler state. If false, all copies
+	// share the sampler state (for example: rate counters), so that a rate
+	// budget is respected across request-scoped copies of the logger. If
+	// the sampler does not implement the SamplerCloner interface, its state
+	// is always shared. If not provided, the default value is false.
+	Isolated bool
+
+	// Levels represents the sampling options of specific log levels, which
+	// replace the sampler of the Type option for log entries of those
+	// levels (for example: DEBUG 1:100, INFO 1:10). A log level mapped to
+	// nil or to an option whose Type is empty is never sampled. Log entries
+	// of other levels are sampled by the sampler of the Type option. The
+	// Isolated and Levels options of the mapped options are ignored. If not
+	// provided, all log entries are sampled by the sampler of the Type
+	// option.
+	Levels map[Level]*SamplingOption
+}
+
+// UseLevelOption uses the given sampling option as the sampling option of
+// the given log level. If the given option is nil, log entries of the
+// given level are never sampled. For details, please refer to the comment
+// section of the Levels option. Then return to the option instance itself.
+func (o *SamplingOption) UseLevelOption(level Level, option *SamplingOption) *SamplingOption {
+	if o.Levels == nil {
+		o.Levels = make(map[Level]*SamplingOption)
+	}
+	o.Levels[level] = option
+	return o
+}
+
+// UseRateOption uses the rate sampler (SamplerRate constant) as the value
+// of the option Type, and then uses the value of the given option as the
+// value of the option. If the value of the given option is nil, the default
+// option is used. For details, please refer to the comment section of the
+// SamplerRate constant. Then return to the option instance itself.
+func (o *SamplingOption) UseRateOption(option *RateSamplerOption) *SamplingOption {
+	o.Type = SamplerRate
+	if option == nil {
+		option = NewRateSamplerOption()
+	}
+	o.Option = option
+	return o
+}
+
+// UseIsolated uses the given value as the value of the option Isolated.
+// For details, please refer to the comment section of the Isolated option.
+// Then return to the option instance itself.
+func (o *SamplingOption) UseIsolated(isolated bool) *SamplingOption {
+	o.Isolated = isolated
+	return o
 }
 
 // UseText uses the text sampler (SamplerText constant) as the value of the
@@ -515,14 +1233,133 @@ func (o *SamplingOption) UseTextOption(option *TextSamplerOption) *SamplingOptio
 	return o
 }
 
+// UseHeadTailOption uses the head and tail sampler (SamplerHeadTail
+// constant) as the value of the option Type, and then uses the value of
+// the given option as the value of the option. If the value of the given
+// option is nil, the default option is used. For details, please refer to
+// the comment section of the SamplerHeadTail constant. Then return to the
+// option instance itself.
+func (o *SamplingOption) UseHeadTailOption(option *HeadTailSamplerOption) *SamplingOption {
+	o.Type = SamplerHeadTail
+	if option == nil {
+		option = NewHeadTailSamplerOption()
+	}
+	o.Option = option
+	return o
+}
+
+// UseAdaptiveOption uses the adaptive sampler (SamplerAdaptive constant)
+// as the value of the option Type, and then uses the value of the given
+// option as the value of the option. The adaptive sampler has no default
+// option, because its pressure reporters are required. For details, please
+// refer to the comment section of the SamplerAdaptive constant. Then return
+// to the option instance itself.
+func (o *SamplingOption) UseAdaptiveOption(option *AdaptiveSamplerOption) *SamplingOption {
+	o.Type = SamplerAdaptive
+	o.Option = option
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *SamplingOption) Validate() error {
+	for level, option := range o.Levels {
+		if level > LevelFatal {
+			return newOptionError("Levels", "invalid level " +
+				strconv.Itoa(int(level)), ErrInvalidLevel)
+		}
+		if option == nil {
+			continue
+		}
+		if len(option.Levels) > 0 {
+			return newOptionError("Levels[" + level.String() + "].Levels",
+				"must be empty", nil)
+		}
+		if err := option.Validate(); err != nil {
+			return prefixOptionError("Levels[" + level.String() + "]", err)
+		}
+	}
+	switch o.Type {
+	case "":
+		return nil
+	case SamplerText:
+		option, ok := o.Option.(*TextSamplerOption)
+		if !ok || option == nil {
+			return newOptionError("Option", "must be a *TextSamplerOption " +
+				"for the text sampler", ErrInvalidType)
+		}
+		return prefixOptionError("Option", option.Validate())
+	case SamplerHeadTail:
+		option, ok := o.Option.(*HeadTailSamplerOption)
+		if !ok || option == nil {
+			return newOptionError("Option", "must be a " +
+				"*HeadTailSamplerOption for the head and tail sampler",
+				ErrInvalidType)
+		}
+		return prefixOptionError("Option", option.Validate())
+	case SamplerAdaptive:
+		option, ok := o.Option.(*AdaptiveSamplerOption)
+		if !ok || option == nil {
+			return newOptionError("Option", "must be an " +
+				"*AdaptiveSamplerOption for the adaptive sampler",
+				ErrInvalidType)
+		}
+		return prefixOptionError("Option", option.Validate())
+	case SamplerRate:
+		option, ok := o.Option.(*RateSamplerOption)
+		if !ok || option == nil {
+			return newOptionError("Option", "must be a *RateSamplerOption " +
+				"for the rate sampler", ErrInvalidType)
+		}
+		return prefixOptionError("Option", option.Validate())
+	default:
+		return newOptionError("Type", "unsupported sampler type \"" +
+			o.Type + "\"", ErrInvalidType)
+	}
+}
+
 // Build builds and returns a sampler instance.
 func (o *SamplingOption) Build() (Sampler, error) {
-	if len(o.Type) == 0 {
-		return nil, nil
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	if len(o.Levels) == 0 {
+		return o.build()
+	}
+	sampler, err := o.build()
+	if err != nil {
+		return nil, err
+	}
+	var samplers [LevelFatal + 1]Sampler
+	for index := 0; index < len(samplers); index++ {
+		option, ok := o.Levels[Level(index)]
+		switch {
+		case !ok:
+			samplers[index] = sampler
+		case option != nil:
+			if samplers[index], err = option.build(); err != nil {
+				return nil, err
+			}
+		}
 	}
+	return newLevelSampler(samplers), nil
+}
+
+// build builds and returns the sampler of the Type option, or nil if the
+// Type option is empty. The options must be valid.
+func (o *SamplingOption) build() (Sampler, error) {
 	switch o.Type {
+	case "":
+		return nil, nil
 	case SamplerText:
 		return o.Option.(*TextSamplerOption).Build()
+	case SamplerHeadTail:
+		return o.Option.(*HeadTailSamplerOption).Build()
+	case SamplerAdaptive:
+		return o.Option.(*AdaptiveSamplerOption).Build()
+	case SamplerRate:
+		return o.Option.(*RateSamplerOption).Build()
 	default:
 		return nil, ErrInvalidType
 	}
@@ -547,6 +1384,11 @@ const (
 	// encoder. For details, please refer to the comment section of the
 	// JSONEncoder structure.
 	EncoderJSON = "json"
+
+	// EncoderConsole represents that the type of encoder is a console
+	// encoder. For details, please refer to the comment section of the
+	// ConsoleEncoder structure.
+	EncoderConsole = "console"
 )
 
 // EncodingOption is a structure that contains options for encoding log
@@ -610,6 +1452,17 @@ func (o *EncodingOption) UseJSON() *EncodingOption {
 	return o
 }
 
+// UseJSONSequence uses the JSON encoder (EncoderJSON constant) with the
+// Sequence option enabled as the value of option Type, so that log entries
+// are encoded as an RFC 7464 JSON text sequence. For details, please refer
+// to the comment section of the Sequence option of the JSONEncoderOption
+// structure. Then return to the option instance itself.
+func (o *EncodingOption) UseJSONSequence() *EncodingOption {
+	o.Type = EncoderJSON
+	o.Option = NewJSONEncoderOption().UseSequence()
+	return o
+}
+
 // UseJSONOption uses the standard encoder (EncoderJSON constant)
 // as the value of the option Type, and then uses the value of the given
 // option as the value of the option. If the value of the given option is
@@ -625,8 +1478,70 @@ func (o *EncodingOption) UseJSONOption(option *JSONEncoderOption) *EncodingOptio
 	return o
 }
 
+// UseConsole uses the console encoder (EncoderConsole constant) with the
+// given profile as the value of option Type. For details, please refer to
+// the comment section of the EncoderConsole constant. Then return to the
+// option instance itself.
+func (o *EncodingOption) UseConsole(profile string) *EncodingOption {
+	o.Type = EncoderConsole
+	o.Option = NewConsoleEncoderOption().UseProfile(profile)
+	return o
+}
+
+// UseConsoleOption uses the console encoder (EncoderConsole constant) as
+// the value of the option Type, and then uses the value of the given
+// option as the value of the option. If the value of the given option is
+// nil, the default option is used. Then return to the option instance
+// itself.
+func (o *EncodingOption) UseConsoleOption(option *ConsoleEncoderOption) *EncodingOption {
+	o.Type = EncoderConsole
+	if option == nil {
+		option = NewConsoleEncoderOption()
+	}
+	o.Option = option
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *EncodingOption) Validate() error {
+	switch o.Type {
+	case EncoderStandard:
+		option, ok := o.Option.(*StandardEncoderOption)
+		if !ok || option == nil {
+			return newOptionError("Option", "must be a " +
+				"*StandardEncoderOption for the standard encoder",
+				ErrInvalidType)
+		}
+	case EncoderJSON:
+		option, ok := o.Option.(*JSONEncoderOption)
+		if !ok || option == nil {
+			return newOptionError("Option", "must be a " +
+				"*JSONEncoderOption for the JSON encoder", ErrInvalidType)
+		}
+	case EncoderConsole:
+		option, ok := o.Option.(*ConsoleEncoderOption)
+		if !ok || option == nil {
+			return newOptionError("Option", "must be a " +
+				"*ConsoleEncoderOption for the console encoder",
+				ErrInvalidType)
+		}
+		if err := option.Validate(); err != nil {
+			return prefixOptionError("Option", err)
+		}
+	default:
+		return newOptionError("Type", "unsupported encoder type \"" +
+			o.Type + "\"", ErrInvalidType)
+	}
+	return nil
+}
+
 // Build builds and returns a encoder instance.
 func (o *EncodingOption) Build() (Encoder, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
 	switch o.Type {
 	case EncoderStandard:
 		option := o.Option.(*StandardEncoderOption)
@@ -636,6 +1551,10 @@ func (o *EncodingOption) Build() (Encoder, error) {
 		option := o.Option.(*JSONEncoderOption)
 		option.EncodeSourceLocation = !o.DisableSourceLocation
 		return option.Build()
+	case EncoderConsole:
+		option := o.Option.(*ConsoleEncoderOption)
+		option.EncodeSourceLocation = !o.DisableSourceLocation
+		return option.Build()
 	default:
 		return nil, ErrInvalidType
 	}
@@ -693,6 +1612,21 @@ type OutputtingOption struct {
 	// some side effects. For details, please refer to the notes section of
 	// the Syncer interface. If not provided, the default value is false.
 	DisableCache bool
+
+	// Framing represents how the encoded log entries are delimited when
+	// they are written to the synchronizer (for example: "\r\n" or RFC
+	// 7464 JSON text sequences), and its optional options are constants
+	// starting with Framing... If not provided, the default value is the
+	// FramingNewline constant, which is a line feed.
+	Framing RecordFraming
+}
+
+// UseFraming uses the given record framing as the value of the option
+// Framing. For details, please refer to the comment section of the
+// Framing option. Then return to the option instance itself.
+func (o *OutputtingOption) UseFraming(framing RecordFraming) *OutputtingOption {
+	o.Framing = framing
+	return o
 }
 
 // UseStandard uses the standard synchronizer (SyncerFile constant) as
@@ -738,8 +1672,85 @@ func (o *OutputtingOption) UseDiscard() *OutputtingOption {
 	return o
 }
 
+// validateType checks whether the value of the option Type is supported
+// and whether the data type of the value of the option Option matches it.
+// Then returns an OptionError describing the invalid option found, or nil
+// if both options are valid.
+func (o *OutputtingOption) validateType() error {
+	var ok bool
+	switch o.Type {
+	case SyncerStandard:
+		option, _ := o.Option.(*StandardSyncerOption)
+		ok = option != nil
+	case SyncerFile:
+		option, _ := o.Option.(*FileSyncerOption)
+		ok = option != nil
+	case SyncerNetwork:
+		option, _ := o.Option.(*NetworkSyncerOption)
+		ok = option != nil
+	case SyncerDiscard:
+		return nil
+	default:
+		return newOptionError("Type", "unsupported synchronizer type \"" +
+			o.Type + "\"", ErrInvalidType)
+	}
+	if !ok {
+		return newOptionError("Option", "data type does not match the " +
+			"synchronizer type \"" + o.Type + "\"", ErrInvalidType)
+	}
+	return nil
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *OutputtingOption) Validate() error {
+	if err := o.validateType(); err != nil {
+		return err
+	}
+	if err := validateFraming(o.Framing); err != nil {
+		return err
+	}
+	var err error
+	switch option := o.Option.(type) {
+	case *StandardSyncerOption:
+		err = option.Validate()
+	case *FileSyncerOption:
+		err = option.Validate()
+	case *NetworkSyncerOption:
+		err = option.Validate()
+	}
+	if err != nil && o.DisableCache {
+		// The cache capacity is set to 0 when the cache is disabled, so
+		// an invalid cache capacity does not matter.
+		if e, ok := err.(*OptionError); ok && e.Option == "CacheCapacity" {
+			err = nil
+		}
+	}
+	return prefixOptionError("Option", err)
+}
+
+// adjust adjusts a cache capacity of the synchronizer option less than
+// 1,024 bytes to 1,024 bytes, as the Build function of the synchronizer
+// option does.
+func (o *OutputtingOption) adjust() {
+	switch option := o.Option.(type) {
+	case *StandardSyncerOption:
+		if !option.DisableMutex {
+			option.adjust()
+		}
+	case *FileSyncerOption:
+		option.adjust()
+	case *NetworkSyncerOption:
+		option.adjust()
+	}
+}
+
 // Build builds and returns a syncer instance.
 func (o *OutputtingOption) Build() (Syncer, error) {
+	if err := o.validateType(); err != nil {
+		return nil, err
+	}
 	switch o.Type {
 	case SyncerStandard:
 		if o.DisableCache {
@@ -799,6 +1810,19 @@ type FlushingOption struct {
 	// automatic flushing is performed, all log entry output operations
 	// on the same log will be blocked.
 	Interval time.Duration
+
+	// FlushOnLevelEnabled represents whether log entries with a level
+	// greater than or equal to the option FlushLevel synchronize the
+	// logger immediately after they are output. This bounds the loss of
+	// the most important log entries while keeping the internal cache for
+	// the others. If not provided, the default value is false.
+	FlushOnLevelEnabled bool
+
+	// FlushLevel represents the lowest level of log entries that
+	// synchronize the logger immediately after they are output. It is
+	// only used if the option FlushOnLevelEnabled is true. If not
+	// provided, the default value is DEBUG.
+	FlushLevel Level
 }
 
 // UseInterval uses the given interval as the value of the Interval option.
@@ -809,6 +1833,34 @@ func (o *FlushingOption) UseInterval(interval time.Duration) *FlushingOption {
 	return o
 }
 
+// FlushOnLevel enables immediate flushing of log entries with a level
+// greater than or equal to the given level. For details, please refer to
+// the comment section of the FlushOnLevelEnabled option. Then return to
+// the option instance itself.
+func (o *FlushingOption) FlushOnLevel(level Level) *FlushingOption {
+	o.FlushOnLevelEnabled = true
+	o.FlushLevel = level
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *FlushingOption) Validate() error {
+	if o.Interval < 0 {
+		return newOptionError("Interval", "must not be negative", nil)
+	}
+	if o.Interval > 0 && o.Interval < (time.Millisecond * 100) {
+		return newOptionError("Interval",
+			"must be 0 or greater than or equal to 100ms", nil)
+	}
+	if o.FlushOnLevelEnabled && o.FlushLevel > LevelFatal {
+		return newOptionError("FlushLevel", "unknown level " +
+			o.FlushLevel.String(), ErrInvalidLevel)
+	}
+	return nil
+}
+
 // NewFlushingOption creates and returns an instance of a flushing option
 // with default optional values.
 func NewFlushingOption() *FlushingOption {
@@ -825,6 +1877,12 @@ type StandardOption struct {
 	// value is empty.
 	Name string
 
+	// NameSeparator represents the separator between the segments of
+	// hierarchical names. For details, please refer to the comment section
+	// of the NameSeparator option of the Option structure. If not
+	// provided, the default value is the DefaultNameSeparator constant.
+	NameSeparator string
+
 	// Level represents the lowest level of log entries, and log entries
 	// below the lowest level will be discarded. If not provided, the
 	// default lowest level is DEBUG.
@@ -858,6 +1916,29 @@ type StandardOption struct {
 	// to the standard error device (os.Stderr).
 	ErrorOutputting OutputtingOption
 
+	// FallbackOutputting represents the value of the log entry output
+	// option used when the synchronizer of the Outputting or the
+	// ErrorOutputting option fails persistently (for example: the network
+	// is down or the disk is full). If provided, each of them is wrapped
+	// in a fallback synchronizer that routes log entry data to a separate
+	// synchronizer built from this option. For details, please refer to
+	// the comment section of the FallbackSyncer structure. If the Type
+	// option is not provided, no fallback is used by default.
+	FallbackOutputting OutputtingOption
+
+	// FallbackThreshold represents the number of consecutive failed writes
+	// after which log entry data is routed to the fallback synchronizer.
+	// It is only used if the FallbackOutputting option is provided. If not
+	// provided, the default value of the FallbackSyncerOption structure
+	// is used.
+	FallbackThreshold int
+
+	// FallbackProbeInterval represents the minimum interval between two
+	// probes of a failed synchronizer. It is only used if the
+	// FallbackOutputting option is provided. If not provided, the default
+	// value of the FallbackSyncerOption structure is used.
+	FallbackProbeInterval time.Duration
+
 	// Flushing represents the value of an option for automatic flushing
 	// of log entry data. Automatic flushing can periodically flush the
 	// internal cache (if enabled) and the data in the file system cache
@@ -877,6 +1958,7 @@ type StandardOption struct {
 	//
 	// Please note that this option slice will be reused during the build
 	// process, and any side effects of external modifications are undefined.
+	// Use the Clone function to build multiple loggers from one option.
 	Hooks []Hook
 
 	// Labels represents one or more labels related to the logger. Each label
@@ -889,6 +1971,87 @@ type StandardOption struct {
 	// For details, please refer to the annotation section of the Label
 	// structure.
 	Labels Labels
+
+	// Latency represents a latency histogram that records the latency of
+	// each log entry output operation of the logger and its copies, so that
+	// the tail latency of logging can be measured. For details, please
+	// refer to the comment section of the LatencyHistogram structure. If
+	// not provided, the latency is not recorded.
+	Latency *LatencyHistogram
+
+	// Profiling represents whether each log entry output operation is
+	// performed with the runtime/pprof labels of the logger, so that CPU
+	// profiles of logging-heavy paths can be sliced by logger name and log
+	// level (see the ProfileLabel... constants). The profiler labels of the
+	// context of the log entry (see the WithContext function of the
+	// StructLogger structure) are kept, so request attributes labeled by
+	// the application are also available. The context of the log entry
+	// passed to hooks carries the labels (see the runtime/pprof.Label
+	// function). If not provided, the default value is false.
+	//
+	// Please note that after each output operation, the profiler labels of
+	// the goroutine are set to the labels of the context of the log entry,
+	// or cleared if the log entry has no context. Applications that label
+	// goroutines should output log entries with a logger derived from the
+	// labeled context.
+	Profiling bool
+
+	// GuardReentrancy represents whether the logger detects log entries
+	// that are output by a hook, an exporter or a synchronizer of the
+	// logger (directly or indirectly, through the logger or any of its
+	// copies) while the same goroutine is already outputting a log entry.
+	// Such log entries are diverted to the ReentrancyOutput writer with a
+	// warning, instead of deadlocking or recursing infinitely. If not
+	// provided, the default value is false.
+	//
+	// Please note that Go does not expose goroutine identifiers, so the
+	// detection identifies the calling goroutine by formatting and parsing
+	// the header of its stack trace (see the runtime.Stack function) for
+	// every output operation, including the log entries below the log
+	// level. This costs about one to two microseconds and a heap allocation
+	// per output operation, so it is recommended to enable this option only
+	// if hooks, exporters or synchronizers may output log entries through
+	// the logger.
+	GuardReentrancy bool
+
+	// ReentrancyOutput represents the writer to which reentrant log entries
+	// are diverted. It is only used if the GuardReentrancy option is
+	// enabled. If not provided, the default value is the standard error
+	// output.
+	ReentrancyOutput io.Writer
+
+	// Governor represents a volume governor that counts the bytes of log
+	// entry data written by the logger and its copies, and raises their
+	// effective minimum level when the budget of the current period is
+	// exceeded. For details, please refer to the comment section of the
+	// VolumeGovernor structure. If not provided, the volume is not
+	// governed.
+	Governor *VolumeGovernor
+
+	// DropObserver represents a drop observer that reports the log entries
+	// dropped by the logger and its copies (for example: by the sampler or
+	// the volume governor). For details, please refer to the comment
+	// section of the DropObserver structure. If not provided, dropped log
+	// entries are not reported.
+	DropObserver *DropObserver
+
+	// EmitLifecycleEvents represents whether the logger outputs a log entry
+	// when it is built and when it is closed. For details, please refer to
+	// the comment section of the EmitLifecycleEvents option of the Option
+	// structure. If not provided, the default value is false.
+	EmitLifecycleEvents bool
+
+	// Development represents whether the logger is in the strict development
+	// mode. For details, please refer to the comment section of the
+	// Development option of the Option structure. If not provided, the
+	// default value is false.
+	Development bool
+
+	// TimeSource represents the function that returns the time of each
+	// log entry. For details, please refer to the comment section of the
+	// TimeSource option of the Option structure. If not provided, the
+	// default value is the time.Now function.
+	TimeSource TimeSource
 }
 
 // UseName uses the given name as the value of the option Name. For details,
@@ -899,6 +2062,14 @@ func (o *StandardOption) UseName(name string) *StandardOption {
 	return o
 }
 
+// UseNameSeparator uses the given separator as the value of the option
+// NameSeparator. For details, please refer to the comment section of the
+// NameSeparator option. Then return to the option instance itself.
+func (o *StandardOption) UseNameSeparator(separator string) *StandardOption {
+	o.NameSeparator = separator
+	return o
+}
+
 // UseLevel uses the given log level as the value of the option Level. For
 // details, please refer to the comment section of the Level option. Then
 // return to the option instance itself.
@@ -955,6 +2126,15 @@ func (o *StandardOption) UseErrorOutputting(option *OutputtingOption) *StandardO
 	return o
 }
 
+// UseFallbackOutputting uses the given output option as the value of the
+// option FallbackOutputting. For details, please refer to the comment
+// section of the FallbackOutputting option. Then return to the option
+// instance itself.
+func (o *StandardOption) UseFallbackOutputting(option *OutputtingOption) *StandardOption {
+	o.FallbackOutputting = *option
+	return o
+}
+
 // UseFlushing Use the given flushing option as the value of the Flushing
 // option. For details, see the comment section of the Flushing option. Then
 // return to the option instance itself.
@@ -963,12 +2143,79 @@ func (o *StandardOption) UseFlushing(option *FlushingOption) *StandardOption {
 	return o
 }
 
-// DisableCache disable the internal cache of output and error output. For
-// details, please refer to the DisableCache option of the OutputtingOption
-// structure. Then return to the option instance itself.
+// UseLatency uses the given latency histogram as the value of the option
+// Latency. For details, please refer to the comment section of the Latency
+// option. Then return to the option instance itself.
+func (o *StandardOption) UseLatency(histogram *LatencyHistogram) *StandardOption {
+	o.Latency = histogram
+	return o
+}
+
+// UseGovernor uses the given volume governor as the value of the option
+// Governor. For details, please refer to the comment section of the
+// Governor option. Then return to the option instance itself.
+func (o *StandardOption) UseGovernor(governor *VolumeGovernor) *StandardOption {
+	o.Governor = governor
+	return o
+}
+
+// UseDropObserver uses the given drop observer as the value of the option
+// DropObserver. For details, please refer to the comment section of the
+// DropObserver option. Then return to the option instance itself.
+func (o *StandardOption) UseDropObserver(observer *DropObserver) *StandardOption {
+	o.DropObserver = observer
+	return o
+}
+
+// EnableProfiling enables the option Profiling. For details, please refer
+// to the comment section of the Profiling option. Then return to the option
+// instance itself.
+func (o *StandardOption) EnableProfiling() *StandardOption {
+	o.Profiling = true
+	return o
+}
+
+// EnableLifecycleEvents enables the lifecycle log entries of the logger.
+// For details, please refer to the comment section of the
+// EmitLifecycleEvents option. Then return to the option instance itself.
+func (o *StandardOption) EnableLifecycleEvents() *StandardOption {
+	o.EmitLifecycleEvents = true
+	return o
+}
+
+// UseTimeSource uses the given function as the value of the option
+// TimeSource. For details, please refer to the comment section of the
+// TimeSource option. Then return to the option instance itself.
+func (o *StandardOption) UseTimeSource(source TimeSource) *StandardOption {
+	o.TimeSource = source
+	return o
+}
+
+// EnableDevelopment enables the strict development mode of the logger. For
+// details, please refer to the comment section of the Development option.
+// Then return to the option instance itself.
+func (o *StandardOption) EnableDevelopment() *StandardOption {
+	o.Development = true
+	return o
+}
+
+// UseReentrancyGuard enables the option GuardReentrancy and uses the given
+// writer as the value of the option ReentrancyOutput. For details, please
+// refer to the comment section of the GuardReentrancy option. Then return
+// to the option instance itself.
+func (o *StandardOption) UseReentrancyGuard(output io.Writer) *StandardOption {
+	o.GuardReentrancy = true
+	o.ReentrancyOutput = output
+	return o
+}
+
+// DisableCache disable the internal cache of output, error output and
+// fallback output. For details, please refer to the DisableCache option of
+// the OutputtingOption structure. Then return to the option instance itself.
 func (o *StandardOption) DisableCache() *StandardOption {
 	o.Outputting.DisableCache = true
 	o.ErrorOutputting.DisableCache = true
+	o.FallbackOutputting.DisableCache = true
 	return o
 }
 
@@ -988,8 +2235,144 @@ func (o *StandardOption) DisableFlushing() *StandardOption {
 	return o
 }
 
-// Build builds and returns a standard logger instance.
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid. The Build function calls this function,
+// and returns the OptionError instead of building the logger.
+//
+// Please note that the Build function adjusts a cache capacity of the
+// synchronizer options less than 1,024 bytes to 1,024 bytes before
+// validating the options, but this function reports it as invalid.
+func (o *StandardOption) Validate() error {
+	if o.Level > LevelFatal {
+		return newOptionError("Level", "unknown level " + o.Level.String(),
+			ErrInvalidLevel)
+	}
+	if err := o.Sampling.Validate(); err != nil {
+		return prefixOptionError("Sampling", err)
+	}
+	if err := o.Encoding.Validate(); err != nil {
+		return prefixOptionError("Encoding", err)
+	}
+	if err := o.Outputting.Validate(); err != nil {
+		return prefixOptionError("Outputting", err)
+	}
+	if err := o.ErrorOutputting.Validate(); err != nil {
+		return prefixOptionError("ErrorOutputting", err)
+	}
+	if len(o.FallbackOutputting.Type) > 0 {
+		if err := o.FallbackOutputting.Validate(); err != nil {
+			return prefixOptionError("FallbackOutputting", err)
+		}
+	}
+	if o.FallbackThreshold < 0 {
+		return newOptionError("FallbackThreshold", "must not be negative",
+			nil)
+	}
+	if o.FallbackProbeInterval < 0 {
+		return newOptionError("FallbackProbeInterval",
+			"must not be negative", nil)
+	}
+	if err := o.Flushing.Validate(); err != nil {
+		return prefixOptionError("Flushing", err)
+	}
+	for index := 0; index < len(o.Hooks); index++ {
+		if o.Hooks[index] == nil {
+			return newOptionError("Hooks[" + strconv.Itoa(index) + "]",
+				"must not be nil", nil)
+		}
+	}
+	return nil
+}
+
+// Preview encodes a sample log entry with the given message using the
+// encoder configured by the option Encoding, and then returns the encoded
+// data and any errors encountered. No synchronizer is built, so nothing
+// is written to any storage device.
+//
+// The sample log entry has the configured name and labels, the INFO level
+// and the current time. If the encoder encodes the source location, the
+// source location is the caller of this function.
+func (o *StandardOption) Preview(message Message) ([]byte, error) {
+	encoder, err := o.Encoding.Build()
+	if err != nil {
+		return nil, err
+	}
+	entry := &Entry {
+		Time: time.Now(),
+		Level: LevelInfo,
+		Message: message,
+		Name: o.Name,
+		Labels: NewSerializedLabels(o.Labels...),
+	}
+	if encoder.Option().EncodeSourceLocation {
+		entry.SourceLocation = newEntrySourceLocation(runtime.Caller(1))
+	}
+	return encoder.Encode(nil, entry)
+}
+
+// buildOutputting builds and returns the synchronizer of the given output
+// option. If the option FallbackOutputting is provided, the synchronizer
+// is wrapped in a fallback synchronizer. If the option Governor is
+// provided, the synchronizer is wrapped by the volume governor.
+func (o *StandardOption) buildOutputting(outputting *OutputtingOption) (Syncer, error) {
+	syncer, err := outputting.Build()
+	if err != nil || len(o.FallbackOutputting.Type) == 0 {
+		return o.governed(syncer), err
+	}
+	fallback, err := o.FallbackOutputting.Build()
+	if err != nil {
+		_ = syncer.Close()
+		return nil, err
+	}
+	option := NewFallbackSyncerOption().UsePrimary(syncer).
+		UseFallback(fallback)
+	if o.FallbackThreshold > 0 {
+		option.UseThreshold(o.FallbackThreshold)
+	}
+	if o.FallbackProbeInterval > 0 {
+		option.UseProbeInterval(o.FallbackProbeInterval)
+	}
+	wrapped, err := option.Build()
+	if err != nil {
+		_ = syncer.Close()
+		_ = fallback.Close()
+		return nil, err
+	}
+	return o.governed(wrapped), nil
+}
+
+// governed returns the given synchronizer wrapped by the volume governor
+// of the option Governor, if it is provided and the synchronizer is not
+// nil. Otherwise it returns the given synchronizer.
+func (o *StandardOption) governed(syncer Syncer) Syncer {
+	if o.Governor == nil || syncer == nil {
+		return syncer
+	}
+	return o.Governor.Wrap(syncer)
+}
+
+// Build builds and returns a standard logger instance. If the values of the
+// options are invalid, an OptionError is returned. For details, please
+// refer to the comment section of the Validate function.
 func (o *StandardOption) Build() (*StandardLogger, error) {
+	logger, err := o.build()
+	if err != nil {
+		return nil, err
+	}
+	trackLeak(logger, (*StandardLogger).closedForLeak)
+	return logger, nil
+}
+
+// build builds and returns a standard logger instance without leak
+// detection, so that it can be embedded in other logger types.
+func (o *StandardOption) build() (*StandardLogger, error) {
+	o.Outputting.adjust()
+	o.ErrorOutputting.adjust()
+	o.FallbackOutputting.adjust()
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
 	sampler, err := o.Sampling.Build()
 	if err != nil {
 		return nil, err
@@ -998,19 +2381,20 @@ func (o *StandardOption) Build() (*StandardLogger, error) {
 	if err != nil {
 		return nil, err
 	}
-	syncer, err := o.Outputting.Build()
+	syncer, err := o.buildOutputting(&o.Outputting)
 	if err != nil {
 		return nil, err
 	}
 	exporter, err := NewStandardExporterOption().
 		UseSpan(LevelDebug, LevelWarning).
 		UseEncoder(encoder).
-		UseSyncer(syncer).Build()
+		UseSyncer(syncer).
+		UseFraming(o.Outputting.Framing).Build()
 	if err != nil {
 		_ = syncer.Close()
 		return nil, err
 	}
-	errorSyncer, err := o.ErrorOutputting.Build()
+	errorSyncer, err := o.buildOutputting(&o.ErrorOutputting)
 	if err != nil {
 		_ = exporter.Close()
 		return nil, err
@@ -1018,7 +2402,8 @@ func (o *StandardOption) Build() (*StandardLogger, error) {
 	errorExporter, err := NewStandardExporterOption().
 		UseSpan(LevelError, LevelFatal).
 		UseEncoder(encoder).
-		UseSyncer(errorSyncer).Build()
+		UseSyncer(errorSyncer).
+		UseFraming(o.ErrorOutputting.Framing).Build()
 	if err != nil {
 		_ = exporter.Close()
 		_ = errorSyncer.Close()
@@ -1027,6 +2412,7 @@ func (o *StandardOption) Build() (*StandardLogger, error) {
 
 	logger, err := (&Option {
 		Name: o.Name,
+		NameSeparator: o.NameSeparator,
 		Level: o.Level,
 		Sampler: sampler,
 		Hooks: o.Hooks,
@@ -1037,6 +2423,10 @@ func (o *StandardOption) Build() (*StandardLogger, error) {
 		Labels: o.Labels,
 		DisableSourceLocation: (!encoder.Option().
 			EncodeSourceLocation),
+		DropObserver: o.DropObserver,
+		EmitLifecycleEvents: o.EmitLifecycleEvents,
+		Development: o.Development,
+		TimeSource: o.TimeSource,
 	}).Build()
 
 	if err != nil {
@@ -1054,6 +2444,17 @@ func (o *StandardOption) Build() (*StandardLogger, error) {
 		contextCancel: contextCancel,
 		contextWaitGroup: &sync.WaitGroup { },
 		contextReferences: new(int32),
+
+		flushOnLevel: o.Flushing.FlushOnLevelEnabled,
+		flushLevel: o.Flushing.FlushLevel,
+
+		samplerIsolated: o.Sampling.Isolated,
+		latency: o.Latency,
+		profiling: o.Profiling,
+		governor: o.Governor,
+	}
+	if o.GuardReentrancy {
+		instance.reentrancy = newReentrancyGuard(o.ReentrancyOutput)
 	}
 
 	// Initialize the logger reference count to 1 to avoid
@@ -1061,8 +2462,11 @@ func (o *StandardOption) Build() (*StandardLogger, error) {
 	atomic.AddInt32(instance.contextReferences, 1)
 
 	if o.Flushing.Interval > 0 {
+		instance.contextTrigger = make(chan struct { }, 1)
 		instance.contextWaitGroup.Add(1)
-		go instance.flushHandler(o.Flushing.Interval)
+		go flushHandler(instance.context, instance.contextWaitGroup,
+			instance.contextTrigger, instance.config().exporters,
+			o.Flushing.Interval)
 	}
 	return instance, nil
 }
@@ -1089,7 +2493,13 @@ func NewStandard() (*StandardLogger, error) {
 // NewStandardBenchmark creates and returns an instance of a standard
 // logger suitable for benchmark performance testing and any errors
 // encountered.
-func NewStandardBenchmark(sampling bool, encoder string) (*StandardLogger, error) {
+//
+// If a latency histogram is given, the latency of each log entry output
+// operation is recorded into it, so that the tail latency can be read after
+// the benchmark. For details, please refer to the Latency option of the
+// StandardOption structure.
+func NewStandardBenchmark(sampling bool, encoder string,
+	latency ...*LatencyHistogram) (*StandardLogger, error) {
 	option := NewStandardOption()
 	switch encoder {
 	case EncoderStandard:
@@ -1104,6 +2514,9 @@ func NewStandardBenchmark(sampling bool, encoder string) (*StandardLogger, error
 	option.Outputting.UseDiscard()
 	option.ErrorOutputting.UseDiscard()
 	option.UseLevel(LevelDebug)
+	if len(latency) > 0 {
+		option.UseLatency(latency[0])
+	}
 	if !sampling {
 		option.DisableSampling()
 	}
@@ -23,9 +23,13 @@
 package santa
 
 import (
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"net"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -47,12 +51,12 @@ func TestLoggerOption(t *testing.T) {
 	logger, err := option.Build()
 	assert.NoError(t, err, "Unexpected build error")
 
-	assert.Equal(t, 1, logger.labels.Count(), "Unexpected instance error")
-	assert.Len(t, logger.exporters, 1, "Unexpected instance error")
-	assert.Equal(t, exporter, logger.exporters[0], "Unexpected instance error")
-	assert.Equal(t, option.Sampler, logger.sampler, "Unexpected instance error")
-	assert.Equal(t, option.Level, logger.level, "Unexpected instance error")
-	assert.Equal(t, option.Name, logger.name, "Unexpected instance error")
+	assert.Equal(t, 1, logger.config().labels.Count(), "Unexpected instance error")
+	assert.Len(t, logger.config().exporters, 1, "Unexpected instance error")
+	assert.Equal(t, exporter, logger.config().exporters[0], "Unexpected instance error")
+	assert.Equal(t, option.Sampler, logger.config().sampler, "Unexpected instance error")
+	assert.Equal(t, option.Level, logger.Level(), "Unexpected instance error")
+	assert.Equal(t, option.Name, logger.Name(), "Unexpected instance error")
 }
 
 type testExporter struct {
@@ -290,13 +294,13 @@ func TestStandardLoggerOption(t *testing.T) {
 	logger, err := option.Build()
 	assert.NoError(t, err, "Unexpected build error")
 
-	assert.NotNil(t, logger.sampler, "Unexpected instance error")
-	assert.Len(t, logger.exporters, 2, "Unexpected instance error")
-	assert.NotNil(t, logger.exporters[0], "Unexpected instance error")
-	assert.NotNil(t, logger.exporters[1], "Unexpected instance error")
+	assert.NotNil(t, logger.config().sampler, "Unexpected instance error")
+	assert.Len(t, logger.config().exporters, 2, "Unexpected instance error")
+	assert.NotNil(t, logger.config().exporters[0], "Unexpected instance error")
+	assert.NotNil(t, logger.config().exporters[1], "Unexpected instance error")
 
-	assert.Equal(t, option.Level, logger.level, "Unexpected instance error")
-	assert.Equal(t, option.Name, logger.name, "Unexpected instance error")
+	assert.Equal(t, option.Level, logger.Level(), "Unexpected instance error")
+	assert.Equal(t, option.Name, logger.Name(), "Unexpected instance error")
 
 	option.DisableCache()
 	option.DisableFlushing()
@@ -370,16 +374,16 @@ func TestStandardLoggerSet(t *testing.T) {
 	assert.NotNil(t, logger, "Unexpected nil value")
 
 	logger.SetName("testing")
-	assert.Equal(t, "testing", logger.name, "Unexpected instance error")
+	assert.Equal(t, "testing", logger.Name(), "Unexpected instance error")
 
 	logger.SetLevel(LevelFatal)
-	assert.Equal(t, LevelFatal, logger.level, "Unexpected instance error")
+	assert.Equal(t, LevelFatal, logger.Level(), "Unexpected instance error")
 
 	logger.SetSampler(nil)
-	assert.Equal(t, nil, logger.sampler, "Unexpected instance error")
+	assert.Equal(t, nil, logger.config().sampler, "Unexpected instance error")
 
 	logger.SetLabels(NewLabel("name", "testing"))
-	assert.Equal(t, 1, logger.labels.count, "Unexpected instance error")
+	assert.Equal(t, 1, logger.config().labels.count, "Unexpected instance error")
 
 	assert.NoError(t, logger.Close(), "Unexpected close error")
 }
@@ -393,8 +397,8 @@ func TestStandardLoggerDuplicate(t *testing.T) {
 	assert.NotNil(t, instance, "Unexpected nil value")
 
 	instance.SetName("testing")
-	assert.Equal(t, "testing", instance.name, "Unexpected instance error")
-	assert.Equal(t, "", logger.name, "Unexpected instance error")
+	assert.Equal(t, "testing", instance.Name(), "Unexpected instance error")
+	assert.Equal(t, "", logger.Name(), "Unexpected instance error")
 
 	assert.NoError(t, instance.Close(), "Unexpected close error")
 	assert.NoError(t, logger.Close(), "Unexpected close error")
@@ -414,3 +418,361 @@ func TestStandardLoggerClosed(t *testing.T) {
 	closed = logger.IsClosed()
 	assert.Equal(t, true, closed, "Unexpected return value")
 }
+
+func TestStandardLoggerCloseInterleaving(t *testing.T) {
+	newLogger := func() *StandardLogger {
+		option := NewStandardOption()
+		option.Outputting.UseDiscard()
+		option.ErrorOutputting.UseDiscard()
+		logger, err := option.Build()
+		assert.NoError(t, err, "Unexpected create error")
+		return logger
+	}
+
+	for _, order := range [][]int {
+		{ 0, 1, 2 },
+		{ 0, 2, 1 },
+		{ 1, 0, 2 },
+		{ 1, 2, 0 },
+		{ 2, 0, 1 },
+		{ 2, 1, 0 },
+	} {
+		logger := newLogger()
+		instance := logger.Duplicate()
+		instances := []*StandardLogger {
+			logger,
+			instance,
+			instance.Duplicate(),
+		}
+
+		for step, index := range order {
+			closing := instances[index]
+			assert.NoError(t, closing.Close(), "Unexpected close error")
+			assert.Equal(t, ErrClosed, closing.Close(),
+				"Unexpected repeated close result")
+			assert.True(t, closing.IsClosed(), "Unexpected closed state")
+			assert.Nil(t, closing.Duplicate(),
+				"Unexpected duplicate of closed logger")
+			assert.Equal(t, ErrClosed, closing.Print(LevelInfo,
+				StringMessage("Hello Test!")), "Unexpected print result")
+			assert.Equal(t, ErrClosed, closing.Sync(),
+				"Unexpected sync result")
+
+			for _, other := range order[step + 1 : ] {
+				remain := instances[other]
+				assert.False(t, remain.IsClosed(), "Unexpected closed state")
+				assert.NoError(t, remain.Print(LevelInfo,
+					StringMessage("Hello Test!")), "Unexpected print error")
+				assert.NoError(t, remain.Sync(), "Unexpected sync error")
+			}
+		}
+
+		assert.Equal(t, int32(0), *logger.contextReferences,
+			"Unexpected reference count")
+		for _, closed := range instances {
+			assert.True(t, closed.IsClosed(), "Unexpected closed state")
+			assert.Nil(t, closed.Duplicate(),
+				"Unexpected duplicate of closed logger")
+		}
+		assert.Equal(t, int32(0), *logger.contextReferences,
+			"Unexpected reference count")
+	}
+}
+
+func TestStandardOptionValidate(t *testing.T) {
+	option := NewStandardOption()
+	assert.NoError(t, option.Validate(), "Unexpected validate error")
+
+	option.Outputting.UseFile("")
+	err := option.Validate()
+
+	var optionErr *OptionError
+	assert.True(t, errors.As(err, &optionErr), "Unexpected validate error")
+	assert.Equal(t, "Outputting.Option.FileName", optionErr.Option,
+		"Unexpected invalid option")
+	assert.True(t, errors.Is(err, ErrInvalidOption),
+		"Unexpected validate error")
+
+	option = NewStandardOption()
+	option.Encoding.Type = "xml"
+	err = option.Validate()
+	assert.True(t, errors.Is(err, ErrInvalidType), "Unexpected validate error")
+	assert.Equal(t, `invalid option Encoding.Type: unsupported encoder ` +
+		`type "xml"`, err.Error(), "Unexpected validate error")
+
+	_, err = option.Build()
+	assert.True(t, errors.Is(err, ErrInvalidType), "Unexpected build error")
+
+	option = NewStandardOption()
+	option.Outputting.UseStandard(ioutil.Discard)
+	option.Outputting.Option.(*StandardSyncerOption).UseCacheCapacity(256)
+	err = option.Validate()
+	assert.True(t, errors.As(err, &optionErr), "Unexpected validate error")
+	assert.Equal(t, "Outputting.Option.CacheCapacity", optionErr.Option,
+		"Unexpected invalid option")
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected build error")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+
+	option = NewStandardOption()
+	option.Flushing.UseInterval(time.Millisecond)
+	assert.Error(t, option.Validate(), "Unexpected validate result")
+
+	option = NewStandardOption()
+	option.Sampling.Option.(*TextSamplerOption).UseFirst(100, 0)
+	assert.Error(t, option.Validate(), "Unexpected validate result")
+
+	option = NewStandardOption()
+	option.Outputting.Option = NewFileSyncerOption()
+	_, err = option.Outputting.Build()
+	assert.True(t, errors.Is(err, ErrInvalidType), "Unexpected build error")
+
+	option = NewStandardOption()
+	option.UseLevel(Level(10))
+	assert.True(t, errors.Is(option.Validate(), ErrInvalidLevel),
+		"Unexpected validate error")
+}
+
+func TestStandardOptionPreview(t *testing.T) {
+	encoder := NewJSONEncoderOption()
+	encoder.EncodeTime = false
+
+	option := NewStandardOption().
+		UseName("preview").
+		UseLabels(NewLabel("region", "cn")).
+		UseEncoding(NewEncodingOption().UseJSONOption(encoder))
+	option.Encoding.DisableSourceLocation = true
+	option.Outputting.UseFile("")
+
+	data, err := option.Preview(&StructMessage { Text: "Hello Test!",
+		Fields: []Field { Int("id", 1) } })
+	assert.NoError(t, err, "Unexpected preview error")
+	preview, err := option.Preview(&StructMessage { Text: "Hello Test!",
+		Fields: []Field { Int("id", 1) } })
+	assert.NoError(t, err, "Unexpected preview error")
+	assert.Equal(t, string(data), string(preview), "Unexpected preview data")
+	assert.Contains(t, string(data), `"preview"`, "Unexpected preview data")
+	assert.Contains(t, string(data), `"region": "cn"`, "Unexpected preview data")
+	assert.Contains(t, string(data), `"id": 1`, "Unexpected preview data")
+
+	option.Encoding.Type = "xml"
+	_, err = option.Preview(StringMessage("Hello Test!"))
+	assert.True(t, errors.Is(err, ErrInvalidType), "Unexpected preview error")
+}
+
+type testLockedWriter struct {
+	mutex sync.Mutex
+	builder strings.Builder
+}
+
+func (w *testLockedWriter) Write(data []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.builder.Write(data)
+}
+
+func (w *testLockedWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.builder.String()
+}
+
+func TestStandardLoggerFlushTrigger(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableSampling().DisableFlushing()
+	option.Outputting.UseStandard(writer)
+	option.ErrorOutputting.UseStandard(writer)
+	option.Flushing.FlushOnLevel(LevelError)
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, logger.Info(StringMessage("first")),
+		"Unexpected print error")
+	assert.Empty(t, writer.String(), "Unexpected flushed data")
+	assert.NoError(t, logger.Error(StringMessage("second")),
+		"Unexpected print error")
+	assert.Contains(t, writer.String(), "second", "Unexpected flushed data")
+
+	assert.NoError(t, logger.Info(StringMessage("third")),
+		"Unexpected print error")
+	assert.NoError(t, logger.TriggerFlush(), "Unexpected flush error")
+	assert.Contains(t, writer.String(), "third", "Unexpected flushed data")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+	assert.True(t, errors.Is(logger.TriggerFlush(), ErrClosed),
+		"Unexpected flush error")
+
+	writer = &testLockedWriter { }
+	option = NewStandardOption().DisableSampling().
+		UseFlushing(NewFlushingOption().UseInterval(time.Hour))
+	option.Outputting.UseStandard(writer)
+
+	logger, err = option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, logger.Info(StringMessage("fourth")),
+		"Unexpected print error")
+	assert.NoError(t, logger.TriggerFlush(), "Unexpected flush error")
+	for attempt := 0; attempt < 100; attempt++ {
+		if strings.Contains(writer.String(), "fourth") {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	assert.Contains(t, writer.String(), "fourth", "Unexpected flushed data")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+
+	option.Flushing.FlushOnLevel(Level(10))
+	assert.True(t, errors.Is(option.Validate(), ErrInvalidLevel),
+		"Unexpected validate error")
+}
+
+func TestStandardLoggerPrintSync(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableSampling().DisableFlushing()
+	option.Outputting.UseStandard(writer)
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, logger.Info(StringMessage("first")),
+		"Unexpected print error")
+	assert.Empty(t, writer.String(), "Unexpected flushed data")
+	assert.NoError(t, logger.PrintSync(LevelInfo, StringMessage("second")),
+		"Unexpected print error")
+	assert.Contains(t, writer.String(), "first", "Unexpected flushed data")
+	assert.Contains(t, writer.String(), "second", "Unexpected flushed data")
+	assert.NoError(t, logger.OutputSync(1, LevelWarning,
+		StringMessage("third"), true), "Unexpected print error")
+	assert.Contains(t, writer.String(), "third", "Unexpected flushed data")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+	assert.True(t, errors.Is(logger.PrintSync(LevelInfo,
+		StringMessage("fourth")), ErrClosed), "Unexpected print error")
+}
+
+func TestStandardLoggerSharedSampler(t *testing.T) {
+	for _, isolated := range []bool { false, true } {
+		writer := &testLockedWriter { }
+		option := NewStandardOption().DisableFlushing().DisableCache().
+			UseSampling(NewSamplingOption().UseTextOption(
+				NewTextSamplerOption().UseTick(time.Hour).UseFirst(1, 1000)).
+				UseIsolated(isolated))
+		option.Outputting.UseStandard(writer)
+
+		logger, err := option.Build()
+		assert.NoError(t, err, "Unexpected create error")
+		copied := logger.Duplicate()
+		for count := 0; count < 2; count++ {
+			assert.NoError(t, logger.Info(StringMessage("Hello Test!")),
+				"Unexpected print error")
+			assert.NoError(t, copied.Info(StringMessage("Hello Test!")),
+				"Unexpected print error")
+		}
+		expected := 2
+		if isolated {
+			expected = 4
+		}
+		assert.Equal(t, expected, strings.Count(writer.String(),
+			"Hello Test!"), "Unexpected sampled entries")
+		assert.NoError(t, copied.Close(), "Unexpected close error")
+		assert.NoError(t, logger.Close(), "Unexpected close error")
+	}
+}
+
+func TestStandardLoggerReentrancyGuard(t *testing.T) {
+	writer := &testLockedWriter { }
+	fallback := &testLockedWriter { }
+	var logger *StandardLogger
+	option := NewStandardOption().DisableFlushing().DisableCache().
+		DisableSampling().UseName("testing").UseReentrancyGuard(fallback).
+		UseHooks(NewSimpleHook(func(entry *Entry) error {
+			if entry.Message == StringMessage("outer") {
+				return logger.Warning(StringMessage("inner"))
+			}
+			return nil
+		}))
+	option.Outputting.UseStandard(writer)
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, logger.Info(StringMessage("outer")),
+		"Unexpected print error")
+	assert.Contains(t, writer.String(), "outer", "Unexpected output data")
+	assert.NotContains(t, writer.String(), "inner", "Unexpected output data")
+	assert.Equal(t, "santa: reentrant log entry diverted: testing " +
+		"warning \"inner\"\n", fallback.String(), "Unexpected diverted data")
+
+	var group sync.WaitGroup
+	for count := 0; count < 8; count++ {
+		group.Add(1)
+		go func() {
+			defer group.Done()
+			assert.NoError(t, logger.Info(StringMessage("concurrent")),
+				"Unexpected print error")
+		}()
+	}
+	group.Wait()
+	assert.Equal(t, 8, strings.Count(writer.String(), "concurrent"),
+		"Unexpected output data")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+}
+
+type testDropSampler struct { }
+
+func (s testDropSampler) Sample(entry *Entry) bool {
+	return entry.Message != StringMessage("drop")
+}
+
+func TestStandardLoggerLifecycleEvents(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableSampling().DisableFlushing().
+		EnableLifecycleEvents()
+	option.Encoding.UseJSON()
+	option.Outputting.UseStandard(writer)
+	option.ErrorOutputting.UseStandard(writer)
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, logger.Sync(), "Unexpected sync error")
+	assert.Contains(t, writer.String(), `"Logger started"`,
+		"Unexpected started entry")
+	assert.Contains(t, writer.String(), fmt.Sprintf(`"pid": %d`, os.Getpid()),
+		"Unexpected started entry")
+
+	logger.SetSampler(testDropSampler { })
+	assert.NoError(t, logger.Info(StringMessage("keep")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Info(StringMessage("drop")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+
+	assert.Contains(t, writer.String(), `"Logger stopping"`,
+		"Unexpected stopping entry")
+	assert.Contains(t, writer.String(), `"emitted": 2, "dropped": 1`,
+		"Unexpected stopping entry counts")
+}
+
+func TestStandardLoggerSyncDetailed(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableSampling().DisableFlushing()
+	option.Outputting.UseStandard(writer)
+	option.ErrorOutputting.UseDiscard()
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, logger.Info(StringMessage("Hello Test!")),
+		"Unexpected print error")
+	assert.Empty(t, writer.String(), "Unexpected flushed data")
+
+	results := logger.SyncDetailed()
+	assert.Len(t, results, 2, "Unexpected results")
+	assert.NoError(t, results[0].Err, "Unexpected sync error")
+	assert.Equal(t, len(writer.String()), results[0].Bytes,
+		"Unexpected flushed bytes")
+	assert.Equal(t, 1, results[1].Index, "Unexpected result index")
+	assert.NoError(t, results[1].Err, "Unexpected sync error")
+
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+	results = logger.SyncDetailed()
+	assert.Len(t, results, 2, "Unexpected results")
+	assert.True(t, errors.Is(results[0].Err, ErrClosed),
+		"Unexpected sync error")
+}
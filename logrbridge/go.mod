@@ -0,0 +1,17 @@
+module github.com/nobody-night/santa/logrbridge
+
+go 1.22.0
+
+require (
+	github.com/go-logr/logr v1.4.2
+	github.com/nobody-night/santa v0.0.0
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/nobody-night/santa => ../
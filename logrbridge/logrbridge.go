@@ -0,0 +1,212 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package logrbridge provides a logr LogSink backed by a santa structured
+// logger, which allows components that log through logr (for example:
+// controller-runtime and client-go by klog) to output log entries through
+// santa.
+//
+// The package is a separate module, so that the logr dependency is only
+// required by the users of the package.
+package logrbridge
+
+import (
+	"fmt"
+
+	"github.com/go-logr/logr"
+	"github.com/nobody-night/santa"
+)
+
+const (
+	// NameField is the name of the field that contains the name of the
+	// logr logger, which is the names given to the WithName function
+	// joined by NameSeparator.
+	NameField = "logger"
+
+	// NameSeparator is the separator between the names given to successive
+	// calls to the WithName function.
+	NameSeparator = "."
+
+	// ErrorField is the name of the field that contains the error given
+	// to the Error function.
+	ErrorField = "error"
+)
+
+// santaLevel returns the santa log level of the given logr verbosity level.
+// The verbosity level 0 is mapped to the INFO level, and the greater
+// verbosity levels are mapped to the DEBUG level.
+func santaLevel(level int) santa.Level {
+	if level <= 0 {
+		return santa.LevelInfo
+	}
+	return santa.LevelDebug
+}
+
+// appendValues converts the given logr key/value pairs to santa fields,
+// and then appends them to the given santa fields. Keys that are not
+// strings are formatted, a missing value of the last key is replaced with
+// "<no-value>", and values that are not supported by the Value function of
+// santa are dumped (see the Dump function of santa).
+func appendValues(fields []santa.Field, values []interface { }) []santa.Field {
+	for index := 0; index < len(values); index += 2 {
+		name, ok := values[index].(string)
+		if !ok {
+			name = fmt.Sprint(values[index])
+		}
+		if index + 1 == len(values) {
+			fields = append(fields, santa.String(name, "<no-value>"))
+			break
+		}
+		value := values[index + 1]
+		if marshaler, ok := value.(logr.Marshaler); ok {
+			value = marshaler.MarshalLog()
+		}
+		field := santa.Value(name, value)
+		if field.Type == santa.TypeValue {
+			field = santa.Dump(name, value)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// LogSink is the structure of a logr LogSink instance backed by a santa
+// structured logger.
+//
+// The LogSink outputs each logr log entry as a structured log message
+// whose text is the message of the logr log entry and whose fields are, in
+// order, the name of the logr logger (see NameField), the key/value pairs
+// bound by the WithValues function and the key/value pairs of the log
+// entry. The Error function outputs the log entry with the ERROR level
+// and the error as the first field after the name (see ErrorField).
+//
+// The verbosity level 0 of logr is mapped to the INFO level and the
+// greater verbosity levels are mapped to the DEBUG level. Like the -v flag
+// of klog, log entries with a verbosity level greater than the maximum
+// verbosity level of the LogSink are discarded.
+//
+// The source location of the santa log entries is the caller of the logr
+// logger, including the call depth added by the WithCallDepth function.
+type LogSink struct {
+	logger *santa.StructLogger
+	verbosity int
+	name string
+	fields []santa.Field
+	depth int
+}
+
+// NewLogSink creates and returns a logr LogSink instance that outputs log
+// entries to the given structured logger, with the given maximum verbosity
+// level.
+func NewLogSink(logger *santa.StructLogger, verbosity int) *LogSink {
+	return &LogSink {
+		logger: logger,
+		verbosity: verbosity,
+	}
+}
+
+// New creates and returns a logr logger backed by a LogSink instance. For
+// details, please refer to the comment section of the NewLogSink function.
+func New(logger *santa.StructLogger, verbosity int) logr.Logger {
+	return logr.New(NewLogSink(logger, verbosity))
+}
+
+// Init receives the runtime information of the logr logger.
+func (s *LogSink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+}
+
+// Enabled checks whether log entries with the given verbosity level are
+// output.
+func (s *LogSink) Enabled(level int) bool {
+	return level <= s.verbosity && s.logger.Level().Enabled(santaLevel(level))
+}
+
+// output outputs a structured log message with the given log level, the
+// given description text, the given leading fields and the given key/value
+// pairs, and then discards any errors encountered, because logr does not
+// report them.
+func (s *LogSink) output(level santa.Level, text string, leading []santa.Field,
+	values []interface { }) {
+	fields := make([]santa.Field, 0, 1 + len(leading) + len(s.fields) +
+		len(values) / 2 + 1)
+	if s.name != "" {
+		fields = append(fields, santa.String(NameField, s.name))
+	}
+	fields = append(append(fields, leading...), s.fields...)
+	fields = appendValues(fields, values)
+	// Skips the output function, the function of the LogSink and the
+	// frames of logr.
+	_ = s.logger.OutputFields(3 + s.depth, level, text, fields)
+}
+
+// Info outputs a log entry with the given verbosity level, the given
+// message and the given key/value pairs.
+func (s *LogSink) Info(level int, text string, values ...interface { }) {
+	s.output(santaLevel(level), text, nil, values)
+}
+
+// Error outputs a log entry with the ERROR level, the given error, the
+// given message and the given key/value pairs.
+func (s *LogSink) Error(err error, text string, values ...interface { }) {
+	s.output(santa.LevelError, text, []santa.Field {
+		santa.Error(ErrorField, err),
+	}, values)
+}
+
+// derive creates and returns a copy of the LogSink.
+func (s *LogSink) derive() *LogSink {
+	instance := *s
+	// Limits the capacity so that appending to the fields of the copy does
+	// not modify the fields of the LogSink.
+	instance.fields = s.fields[ : len(s.fields) : len(s.fields)]
+	return &instance
+}
+
+// WithValues creates and returns a LogSink derived from the LogSink, which
+// adds the given key/value pairs to each log entry it outputs.
+func (s *LogSink) WithValues(values ...interface { }) logr.LogSink {
+	instance := s.derive()
+	instance.fields = appendValues(instance.fields, values)
+	return instance
+}
+
+// WithName creates and returns a LogSink derived from the LogSink, whose
+// name is the name of the LogSink followed by the given name.
+func (s *LogSink) WithName(name string) logr.LogSink {
+	instance := s.derive()
+	if instance.name == "" {
+		instance.name = name
+	} else {
+		instance.name += NameSeparator + name
+	}
+	return instance
+}
+
+// WithCallDepth creates and returns a LogSink derived from the LogSink,
+// which skips the given number of additional stack frames to find the
+// caller of the logr logger.
+func (s *LogSink) WithCallDepth(depth int) logr.LogSink {
+	instance := s.derive()
+	instance.depth += depth
+	return instance
+}
@@ -0,0 +1,103 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package logrbridge
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/nobody-night/santa"
+	"github.com/stretchr/testify/assert"
+)
+
+type testWriter struct {
+	mutex sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (w *testWriter) Write(data []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.Write(data)
+}
+
+func (w *testWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.String()
+}
+
+func TestLogSink(t *testing.T) {
+	writer := &testWriter { }
+	option := santa.NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling().UseLevel(santa.LevelDebug)
+	option.Encoding.UseJSON()
+	option.Outputting.UseStandard(writer)
+	option.ErrorOutputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	defer logger.Close()
+
+	controller := New(logger, 1).WithName("controller").WithName("pod").
+		WithValues("namespace", "default")
+	controller.V(2).Info("hidden")
+	assert.False(t, controller.V(2).Enabled(), "Unexpected enabled level")
+	assert.True(t, controller.V(1).Enabled(), "Unexpected disabled level")
+
+	controller.Info("Reconciled", "attempt", 2, "dangling")
+	output := writer.String()
+	assert.NotContains(t, output, "hidden", "Unexpected disabled entry")
+	assert.Contains(t, output, `"level": "INFO"`, "Unexpected level")
+	assert.Contains(t, output, `"text": "Reconciled"`, "Unexpected text")
+	assert.Contains(t, output, `"logger": "controller.pod", ` +
+		`"namespace": "default", "attempt": 2, "dangling": "<no-value>"`,
+		"Unexpected fields")
+	assert.Contains(t, output, "logrbridge_test.go",
+		"Unexpected source location")
+
+	controller.V(1).Info("Requeued")
+	controller.Error(errors.New("conflict"), "Update failed", "retry", true)
+	lines := strings.Split(strings.TrimSpace(writer.String()), "\n")
+	assert.Len(t, lines, 3, "Unexpected entry count")
+	assert.Contains(t, lines[1], `"level": "DEBUG"`, "Unexpected level")
+	assert.Contains(t, lines[2], `"level": "ERROR"`, "Unexpected level")
+	assert.Contains(t, lines[2], `"logger": "controller.pod", ` +
+		`"error": "conflict", "namespace": "default", "retry": true`,
+		"Unexpected fields")
+}
+
+func TestLogSinkLevel(t *testing.T) {
+	option := santa.NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling().UseLevel(santa.LevelInfo)
+	option.Outputting.UseStandard(&testWriter { })
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	defer logger.Close()
+
+	sink := NewLogSink(logger, 5)
+	assert.True(t, sink.Enabled(0), "Unexpected disabled level")
+	assert.False(t, sink.Enabled(1), "Unexpected enabled level")
+}
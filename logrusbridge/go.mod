@@ -0,0 +1,18 @@
+module github.com/nobody-night/santa/logrusbridge
+
+go 1.22.0
+
+require (
+	github.com/nobody-night/santa v0.0.0
+	github.com/sirupsen/logrus v1.9.3
+	github.com/stretchr/testify v1.10.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	golang.org/x/sys v0.24.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/nobody-night/santa => ../
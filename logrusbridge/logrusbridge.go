@@ -0,0 +1,189 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package logrusbridge provides a logrus Hook that forwards logrus log
+// entries to a santa structured logger, and a logrus Formatter backed by
+// santa encoders, which ease the migration of legacy services from logrus
+// to santa.
+//
+// The package is a separate module, so that the logrus dependency is only
+// required by the users of the package.
+package logrusbridge
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/nobody-night/santa"
+	"github.com/sirupsen/logrus"
+)
+
+// santaLevel returns the santa log level of the given logrus log level. The
+// PANIC and FATAL levels are mapped to the FATAL level, and the TRACE level
+// is mapped to the DEBUG level.
+func santaLevel(level logrus.Level) santa.Level {
+	switch level {
+	case logrus.PanicLevel, logrus.FatalLevel:
+		return santa.LevelFatal
+	case logrus.ErrorLevel:
+		return santa.LevelError
+	case logrus.WarnLevel:
+		return santa.LevelWarning
+	case logrus.InfoLevel:
+		return santa.LevelInfo
+	}
+	return santa.LevelDebug
+}
+
+// convertFields converts the given logrus fields to santa fields sorted by
+// their names, and then appends them to the given santa fields. Values
+// that are not supported by the Value function of santa are dumped (see
+// the Dump function of santa).
+func convertFields(fields []santa.Field, data logrus.Fields) []santa.Field {
+	names := make([]string, 0, len(data))
+	for name := range data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		field := santa.Value(name, data[name])
+		if field.Type == santa.TypeValue {
+			field = santa.Dump(name, data[name])
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// Hook is the structure of a logrus Hook instance that forwards logrus log
+// entries to a santa structured logger.
+//
+// The Hook outputs each logrus log entry as a structured log message whose
+// text is the message of the logrus log entry and whose fields are the
+// fields of the logrus log entry sorted by their names. The PANIC and FATAL
+// levels are output synchronously (see the PrintsSync function of the
+// structured logger), because logrus exits the process or panics after the
+// Hooks are fired.
+//
+// The source location of the santa log entries is the caller of the Hook
+// inside logrus. If the ReportCaller option of the logrus logger is
+// enabled, the caller of logrus is added as the "caller" field.
+//
+// Usually, the output of the logrus logger is discarded (for example:
+// io.Discard) after the Hook is added, so that each log entry is only
+// written by santa.
+type Hook struct {
+	logger *santa.StructLogger
+	levels []logrus.Level
+}
+
+// NewHook creates and returns a logrus Hook instance that forwards the
+// logrus log entries of the given levels to the given structured logger.
+// If no levels are given, the log entries of all levels are forwarded.
+func NewHook(logger *santa.StructLogger, levels ...logrus.Level) *Hook {
+	if len(levels) == 0 {
+		levels = logrus.AllLevels
+	}
+	return &Hook {
+		logger: logger,
+		levels: levels,
+	}
+}
+
+// Levels returns the logrus log levels forwarded by the Hook.
+func (h *Hook) Levels() []logrus.Level {
+	return h.levels
+}
+
+// Fire forwards the given logrus log entry to the structured logger, and
+// then returns any errors encountered.
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	fields := convertFields(nil, entry.Data)
+	if entry.Caller != nil {
+		fields = append(fields, santa.String("caller", entry.Caller.File +
+			":" + strconv.Itoa(entry.Caller.Line)))
+	}
+	level := santaLevel(entry.Level)
+	if level == santa.LevelFatal {
+		return h.logger.PrintsSync(level, entry.Message, fields...)
+	}
+	return h.logger.PrintsFields(level, entry.Message, fields)
+}
+
+// Formatter is the structure of a logrus Formatter instance backed by a
+// santa encoder.
+//
+// The Formatter converts each logrus log entry into a santa log entry
+// with a structured message, whose text is the message of the logrus log
+// entry and whose fields are the fields of the logrus log entry sorted by
+// their names, and then encodes it with the santa encoder terminated by a
+// newline, so that logrus writes the same format as santa loggers.
+type Formatter struct {
+	encoder santa.Encoder
+	labels santa.SerializedLabels
+}
+
+// NewFormatter creates and returns a logrus Formatter instance that encodes
+// logrus log entries with the given encoder and adds the given labels to
+// each log entry.
+func NewFormatter(encoder santa.Encoder, labels ...santa.Label) *Formatter {
+	return &Formatter {
+		encoder: encoder,
+		labels: santa.NewSerializedLabels(labels...),
+	}
+}
+
+// Format encodes the given logrus log entry, and then returns the encoded
+// data and any errors encountered.
+func (f *Formatter) Format(entry *logrus.Entry) ([]byte, error) {
+	record := &santa.Entry {
+		Level: santaLevel(entry.Level),
+		Time: entry.Time,
+		Message: &santa.StructMessage {
+			Text: entry.Message,
+			Fields: convertFields(nil, entry.Data),
+		},
+		Labels: f.labels,
+		Context: entry.Context,
+	}
+	if entry.Caller != nil {
+		record.SourceLocation = santa.EntrySourceLocation {
+			Proc: entry.Caller.PC,
+			File: entry.Caller.File,
+			Line: entry.Caller.Line,
+			Parsed: true,
+		}
+	}
+	var buffer []byte
+	if entry.Buffer != nil {
+		buffer = entry.Buffer.Bytes()[ : 0]
+	}
+	buffer, err := f.encoder.Encode(buffer, record)
+	if err != nil {
+		return nil, err
+	}
+	// Some encoders terminate the encoded log entry with a newline.
+	if length := len(buffer); length > 0 && buffer[length - 1] == '\n' {
+		return buffer, nil
+	}
+	return append(buffer, '\n'), nil
+}
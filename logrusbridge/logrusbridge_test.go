@@ -0,0 +1,111 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package logrusbridge
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nobody-night/santa"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+type testWriter struct {
+	mutex sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (w *testWriter) Write(data []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.Write(data)
+}
+
+func (w *testWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.String()
+}
+
+func TestHook(t *testing.T) {
+	writer := &testWriter { }
+	option := santa.NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling().UseLevel(santa.LevelInfo)
+	option.Encoding.UseJSON()
+	option.Outputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	legacy := logrus.New()
+	legacy.SetOutput(io.Discard)
+	legacy.SetLevel(logrus.TraceLevel)
+	legacy.AddHook(NewHook(logger))
+	legacy.Trace("hidden")
+	legacy.WithFields(logrus.Fields {
+		"user": "alice",
+		"attempt": 2,
+	}).WithError(errors.New("denied")).Warn("Login failed")
+
+	output := writer.String()
+	assert.NotContains(t, output, "hidden", "Unexpected disabled entry")
+	assert.Contains(t, output, `"level": "WARNING"`, "Unexpected level")
+	assert.Contains(t, output, `"text": "Login failed"`, "Unexpected text")
+	assert.Contains(t, output, `"attempt": 2, "error": "denied", ` +
+		`"user": "alice"`, "Unexpected fields")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+
+	assert.Equal(t, []logrus.Level { logrus.ErrorLevel },
+		NewHook(logger, logrus.ErrorLevel).Levels(), "Unexpected levels")
+	assert.Equal(t, logrus.AllLevels, NewHook(logger).Levels(),
+		"Unexpected levels")
+}
+
+func TestFormatter(t *testing.T) {
+	encoder, err := santa.NewJSONEncoderOption().Build()
+	assert.NoError(t, err, "Unexpected encoder creation error")
+	formatter := NewFormatter(encoder, santa.NewLabel("env", "test"))
+
+	data, err := formatter.Format(&logrus.Entry {
+		Level: logrus.PanicLevel,
+		Time: time.Unix(1, 0),
+		Message: "Out of memory",
+		Data: logrus.Fields {
+			"size": uint64(1024),
+			"tags": []string { "a" },
+		},
+	})
+	assert.NoError(t, err, "Unexpected format error")
+	output := string(data)
+	assert.True(t, strings.HasSuffix(output, "}\n"), "Unexpected newline")
+	assert.Contains(t, output, `"timestamp": 1000000000`, "Unexpected time")
+	assert.Contains(t, output, `"env": "test"`, "Unexpected labels")
+	assert.Contains(t, output, `"level": "FATAL"`, "Unexpected level")
+	assert.Contains(t, output, `"text": "Out of memory", "payload": ` +
+		`{"size": 1024, "tags": ["a"]}`, "Unexpected message")
+}
@@ -24,6 +24,9 @@ package santa
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 )
 
 // Message is the public interface for messages.
@@ -73,7 +76,7 @@ type TemplateMessage struct {
 // slice.
 func (m TemplateMessage) SerializeStandard(buffer []byte) []byte {
 	buffer = append(buffer, '"')
-	buffer = append(buffer, fmt.Sprintf(m.Template, m.Args...)...)
+	buffer = appendTemplate(buffer, m.Template, m.Args)
 	return append(buffer, '"')
 }
 
@@ -81,7 +84,7 @@ func (m TemplateMessage) SerializeStandard(buffer []byte) []byte {
 // to the given buffer slice, and then returns the appended buffer slice.
 func (m TemplateMessage) SerializeJSON(buffer []byte) []byte {
 	buffer = append(buffer, '"')
-	buffer = append(buffer, fmt.Sprintf(m.Template, m.Args...)...)
+	buffer = appendTemplate(buffer, m.Template, m.Args)
 	return append(buffer, '"')
 }
 
@@ -101,6 +104,42 @@ type StructMessage struct {
 	// field message, and these fields will be encoded as
 	// structured log entries.
 	Fields ElementObject
+
+	// prefix represents the serialized fields bound to the structured
+	// logger that output the message, and owner represents the first
+	// field of the fields the message was output with. For details,
+	// please refer to the comment section of the fieldPrefix structure.
+	prefix *fieldPrefix
+	owner *Field
+}
+
+// prefixed returns the number of leading fields of the message whose
+// serialized data is cached by the field prefix, which is 0 if the message
+// has no field prefix or its fields have been replaced (for example: by a
+// Hook) since it was output.
+func (m StructMessage) prefixed() int {
+	if m.prefix == nil || len(m.Fields) < len(m.prefix.fields) ||
+		&m.Fields[0] != m.owner {
+		return 0
+	}
+	return len(m.prefix.fields)
+}
+
+// serializeFields serializes the fields of the message into a JSON object
+// using the given field option, appending the cached data of the field
+// prefix if any, and appends it to the given buffer slice, and then
+// returns the appended buffer slice.
+func (m StructMessage) serializeFields(buffer []byte, option FieldOption) []byte {
+	count := m.prefixed()
+	if count == 0 {
+		return m.Fields.SerializeJSONWith(buffer, option)
+	}
+	serialized := m.prefix.serialized(option)
+	buffer = append(buffer, '{')
+	buffer = append(buffer, serialized...)
+	buffer = m.Fields[count : ].appendJSONWith(buffer, option,
+		len(serialized) > 0)
+	return append(buffer, '}')
 }
 
 // SerializeStandard serializes the message into a standard log string and
@@ -110,6 +149,9 @@ func (m StructMessage) SerializeStandard(buffer []byte) []byte {
 	buffer = append(buffer, '"')
 	buffer = append(buffer, m.Text...)
 	buffer = append(buffer, `" `...)
+	if m.prefix != nil {
+		return m.serializeFields(buffer, FieldOption { })
+	}
 	return m.Fields.SerializeJSON(buffer)
 }
 
@@ -122,7 +164,36 @@ func (m StructMessage) SerializeJSON(buffer []byte) []byte {
 		return append(buffer, `"}`...)
 	}
 	buffer = append(buffer, `", "payload": `...)
-	buffer = m.Fields.SerializeJSON(buffer)
+	if m.prefix != nil {
+		buffer = m.serializeFields(buffer, FieldOption { })
+	} else {
+		buffer = m.Fields.SerializeJSON(buffer)
+	}
+	return append(buffer, '}')
+}
+
+// SerializeStandardWith serializes the message into a standard log string
+// using the given field option and appends it to the given buffer slice,
+// and then returns the appended buffer slice.
+func (m StructMessage) SerializeStandardWith(buffer []byte, option FieldOption) []byte {
+	buffer = append(buffer, '"')
+	buffer = append(buffer, m.Text...)
+	buffer = append(buffer, `" `...)
+	return m.serializeFields(buffer, option)
+}
+
+// SerializeJSONWith serializes the message into a JSON string using the
+// given field option and appends it to the given buffer slice, and then
+// returns the appended buffer slice. If the OmitEmpty option is enabled
+// and all fields are empty, the payload is omitted.
+func (m StructMessage) SerializeJSONWith(buffer []byte, option FieldOption) []byte {
+	buffer = append(buffer, `{"text": "`...)
+	buffer = append(buffer, m.Text...)
+	if len(m.Fields) == 0 || (option.OmitEmpty && m.Fields.IsEmpty()) {
+		return append(buffer, `"}`...)
+	}
+	buffer = append(buffer, `", "payload": `...)
+	buffer = m.serializeFields(buffer, option)
 	return append(buffer, '}')
 }
 
@@ -130,3 +201,353 @@ func (m StructMessage) SerializeJSON(buffer []byte) []byte {
 func (m StructMessage) SampleText() string {
 	return m.Text
 }
+
+// namedTemplateScanner is a structure that scans the literal texts and
+// placeholders of a named template string.
+type namedTemplateScanner struct {
+	template string
+	offset int
+}
+
+// next returns the next literal text or placeholder name of the template.
+// The placeholder is true if a placeholder name is returned, and the ok is
+// false if the template has been fully scanned. The escaped braces "{{"
+// and "}}" are returned as literal texts.
+func (s *namedTemplateScanner) next() (text string, placeholder bool, ok bool) {
+	if s.offset >= len(s.template) {
+		return "", false, false
+	}
+	remain := s.template[s.offset : ]
+	if strings.HasPrefix(remain, "{{") || strings.HasPrefix(remain, "}}") {
+		s.offset += 2
+		return remain[ : 1], false, true
+	}
+	if remain[0] == '{' {
+		end := strings.IndexByte(remain, '}')
+		if end > 1 {
+			s.offset += end + 1
+			return remain[1 : end], true, true
+		}
+	}
+	end := strings.IndexAny(remain[1 : ], "{}")
+	if end < 0 {
+		end = len(remain)
+	} else {
+		end++
+	}
+	s.offset += end
+	return remain[ : end], false, true
+}
+
+// NamedTemplateMessage is a message structure containing a template
+// string with named placeholders and parameter values.
+//
+// Each placeholder is a name enclosed in braces, for example "user
+// {userId} logged in from {ip}", and is replaced by the parameter value
+// at the same position when the message is rendered. At the same time,
+// each parameter value is captured as a field named by the placeholder,
+// so the message is both human-readable text and a structured log entry.
+// Use "{{" and "}}" to output literal braces.
+//
+// Placeholders without parameter values are kept as they are in the text,
+// and parameter values without placeholders are ignored.
+type NamedTemplateMessage struct {
+	// Template represents the template string with named placeholders.
+	Template string
+
+	// Args represents the parameter values of the placeholders. The
+	// number and position of the parameters correspond to the
+	// placeholders of the template string.
+	Args []interface { }
+}
+
+// namedField returns the field with the given name and the given parameter
+// value. Values that can not be serialized as JSON are formatted as text.
+func namedField(name string, arg interface { }) Field {
+	field := Value(name, arg)
+	if field.Type == TypeValue {
+		if _, ok := arg.(JSONSerializer); !ok {
+			return String(name, fmt.Sprint(arg))
+		}
+	}
+	return field
+}
+
+// appendFieldText appends the value of the given field as text to the
+// given buffer slice, and then returns the appended buffer slice. Unlike
+// JSON serialization, strings are appended without quotation marks.
+func appendFieldText(buffer []byte, field Field) []byte {
+	switch field.Type {
+	case TypeString:
+		return append(buffer, field.String...)
+	case TypeBytes:
+		return appendBytes(buffer, field.bytes(false),
+			BytesEncoding(field.Number))
+	}
+	return field.SerializeJSON(buffer)
+}
+
+// AppendText renders the template string with the parameter values and
+// appends it to the given buffer slice, and then returns the appended
+// buffer slice.
+func (m NamedTemplateMessage) AppendText(buffer []byte) []byte {
+	scanner := namedTemplateScanner { template: m.Template }
+	index := 0
+	for {
+		text, placeholder, ok := scanner.next()
+		if !ok {
+			return buffer
+		}
+		if !placeholder {
+			buffer = append(buffer, text...)
+			continue
+		}
+		if index >= len(m.Args) {
+			buffer = append(buffer, '{')
+			buffer = append(buffer, text...)
+			buffer = append(buffer, '}')
+			continue
+		}
+		buffer = appendFieldText(buffer, namedField(text, m.Args[index]))
+		index++
+	}
+}
+
+// Fields returns the fields captured from the placeholders and their
+// parameter values.
+func (m NamedTemplateMessage) Fields() []Field {
+	var fields []Field
+	scanner := namedTemplateScanner { template: m.Template }
+	for len(fields) < len(m.Args) {
+		text, placeholder, ok := scanner.next()
+		if !ok {
+			break
+		}
+		if placeholder {
+			fields = append(fields, namedField(text, m.Args[len(fields)]))
+		}
+	}
+	return fields
+}
+
+// appendPayload serializes the captured fields into a JSON object string
+// and appends it to the given buffer slice, and then returns the appended
+// buffer slice.
+func (m NamedTemplateMessage) appendPayload(buffer []byte) []byte {
+	scanner := namedTemplateScanner { template: m.Template }
+	buffer = append(buffer, '{')
+	for index := 0; index < len(m.Args); {
+		text, placeholder, ok := scanner.next()
+		if !ok {
+			break
+		}
+		if !placeholder {
+			continue
+		}
+		if index > 0 {
+			buffer = append(buffer, ", "...)
+		}
+		buffer = append(buffer, '"')
+		buffer = append(buffer, text...)
+		buffer = append(buffer, "\": "...)
+		buffer = namedField(text, m.Args[index]).SerializeJSON(buffer)
+		index++
+	}
+	return append(buffer, '}')
+}
+
+// SerializeStandard serializes the message into a standard log string and
+// appends it to the given buffer slice, and then returns the appended buffer
+// slice.
+func (m NamedTemplateMessage) SerializeStandard(buffer []byte) []byte {
+	buffer = append(buffer, '"')
+	buffer = m.AppendText(buffer)
+	if len(m.Args) == 0 {
+		return append(buffer, '"')
+	}
+	buffer = append(buffer, `" `...)
+	return m.appendPayload(buffer)
+}
+
+// SerializeJSON serializes the message into a JSON string and appends it
+// to the given buffer slice, and then returns the appended buffer slice.
+func (m NamedTemplateMessage) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, `{"text": "`...)
+	buffer = m.AppendText(buffer)
+	buffer = append(buffer, `", "template": "`...)
+	buffer = append(buffer, m.Template...)
+	if len(m.Args) == 0 {
+		return append(buffer, `"}`...)
+	}
+	buffer = append(buffer, `", "payload": `...)
+	buffer = m.appendPayload(buffer)
+	return append(buffer, '}')
+}
+
+// SampleText returns the text sample string of the log entry message.
+func (m NamedTemplateMessage) SampleText() string {
+	return m.Template
+}
+
+// fieldPrefix is a structure that contains the fields bound to a structured
+// logger by the With function, which are serialized once for each field
+// option (see the FieldOption structure) the first time they are encoded,
+// and then the cached data is appended to each log entry output by the
+// logger.
+//
+// Each message output by the logger refers to the field prefix and to its
+// first field, so that if a Hook replaces the fields of the message, the
+// fields are serialized again instead of using the cached data. Hooks must
+// never modify the fields of a message in place.
+//
+// The field prefix is embedded in the logger, so that deriving a pooled
+// logger does not allocate, and it is reset when the logger is released.
+type fieldPrefix struct {
+	fields []Field
+	variants atomic.Pointer[[]prefixVariant]
+	mutex sync.Mutex
+}
+
+// prefixVariant is a structure that contains the serialized data of a
+// field prefix for a field option.
+type prefixVariant struct {
+	option FieldOption
+	data []byte
+}
+
+// reset resets the field prefix to the given fields, and discards the
+// cached data.
+func (p *fieldPrefix) reset(fields []Field) {
+	p.fields = fields
+	p.variants.Store(nil)
+}
+
+// lookup returns the cached data of the field prefix for the given field
+// option, and whether it is cached.
+func (p *fieldPrefix) lookup(option FieldOption) ([]byte, bool) {
+	if variants := p.variants.Load(); variants != nil {
+		for index := range *variants {
+			if (*variants)[index].option == option {
+				return (*variants)[index].data, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// serialized returns the serialized data of the fields for the given field
+// option, which are separated by commas without the enclosing braces,
+// serializing and caching them if they are used for the first time.
+func (p *fieldPrefix) serialized(option FieldOption) []byte {
+	if data, ok := p.lookup(option); ok {
+		return data
+	}
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if data, ok := p.lookup(option); ok {
+		return data
+	}
+	var variants []prefixVariant
+	if previous := p.variants.Load(); previous != nil {
+		variants = append(variants, *previous...)
+	}
+	data := ElementObject(p.fields).appendJSONWith(nil, option, false)
+	variants = append(variants, prefixVariant {
+		option: option,
+		data: data,
+	})
+	p.variants.Store(&variants)
+	return data
+}
+
+// compiledSerialization is a structure that contains the serialized data
+// of a compiled message for a field option.
+type compiledSerialization struct {
+	standard []byte
+	json []byte
+}
+
+// CompiledMessage is an immutable log entry message structure containing
+// a text and static fields that have been serialized in advance.
+//
+// The compiled message serializes its text and fields once, when it is
+// compiled, and then appends the cached data each time it is encoded, so
+// extremely hot and mostly static messages (for example: "heartbeat ok")
+// cost almost nothing to encode. The data serialized with the field option
+// of each encoder (see the FieldOption structure) is cached the first time
+// it is used.
+//
+// The compiled message is safe to share between goroutines and loggers.
+// Please note that since the fields are serialized in advance, changes to
+// the values referenced by the fields after compiling are not reflected.
+type CompiledMessage struct {
+	text string
+	serialization compiledSerialization
+	message StructMessage
+	variants sync.Map
+}
+
+// SerializeStandard appends the serialized standard log string of the
+// message to the given buffer slice, and then returns the appended buffer
+// slice.
+func (m *CompiledMessage) SerializeStandard(buffer []byte) []byte {
+	return append(buffer, m.serialization.standard...)
+}
+
+// SerializeJSON appends the serialized JSON string of the message to the
+// given buffer slice, and then returns the appended buffer slice.
+func (m *CompiledMessage) SerializeJSON(buffer []byte) []byte {
+	return append(buffer, m.serialization.json...)
+}
+
+// variant returns the serialized data of the message for the given field
+// option, serializing and caching it if it is used for the first time.
+func (m *CompiledMessage) variant(option FieldOption) *compiledSerialization {
+	if cached, ok := m.variants.Load(option); ok {
+		return cached.(*compiledSerialization)
+	}
+	serialization := &compiledSerialization {
+		standard: m.message.SerializeStandardWith(nil, option),
+		json: m.message.SerializeJSONWith(nil, option),
+	}
+	cached, _ := m.variants.LoadOrStore(option, serialization)
+	return cached.(*compiledSerialization)
+}
+
+// SerializeStandardWith appends the serialized standard log string of the
+// message for the given field option to the given buffer slice, and then
+// returns the appended buffer slice.
+func (m *CompiledMessage) SerializeStandardWith(buffer []byte, option FieldOption) []byte {
+	return append(buffer, m.variant(option).standard...)
+}
+
+// SerializeJSONWith appends the serialized JSON string of the message for
+// the given field option to the given buffer slice, and then returns the
+// appended buffer slice.
+func (m *CompiledMessage) SerializeJSONWith(buffer []byte, option FieldOption) []byte {
+	return append(buffer, m.variant(option).json...)
+}
+
+// SampleText returns the text sample string of the log entry message.
+func (m *CompiledMessage) SampleText() string {
+	return m.text
+}
+
+// CompileMessage creates and returns a compiled message with the given
+// text and static fields. The message is serialized like a StructMessage
+// with the same text and fields. For details, please refer to the comment
+// section of the CompiledMessage structure.
+func CompileMessage(text string, fields ...Field) *CompiledMessage {
+	message := StructMessage {
+		Text: text,
+		Fields: append(ElementObject(nil), fields...),
+	}
+	return &CompiledMessage {
+		text: text,
+		serialization: compiledSerialization {
+			standard: message.SerializeStandard(nil),
+			json: message.SerializeJSON(nil),
+		},
+		message: message,
+	}
+}
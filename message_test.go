@@ -99,3 +99,77 @@ func TestStructMessage(t *testing.T) {
 	assert.Equal(t, "Hello Test!", message.SampleText(),
 		"Unexpected sample result")
 }
+
+func TestNamedTemplateMessage(t *testing.T) {
+	buffer := make([]byte, 0, 256)
+
+	message := NamedTemplateMessage {
+		Template: "User {userId} logged in from {ip} {{{missing}}}",
+		Args: []interface { } {
+			100,
+			"127.0.0.1",
+		},
+	}
+
+	buffer = message.AppendText(buffer)
+
+	assert.Equal(t, "User 100 logged in from 127.0.0.1 {{missing}}",
+		string(buffer), "Unexpected format result")
+
+	buffer = message.SerializeStandard(buffer[ : 0])
+
+	assert.Equal(t, `"User 100 logged in from 127.0.0.1 {{missing}}" ` +
+		`{"userId": 100, "ip": "127.0.0.1"}`, string(buffer),
+		"Unexpected format result")
+
+	buffer = message.SerializeJSON(buffer[ : 0])
+
+	assert.JSONEq(t, `{
+		"text": "User 100 logged in from 127.0.0.1 {{missing}}",
+		"template": "User {userId} logged in from {ip} {{{missing}}}",
+		"payload": {
+			"userId": 100,
+			"ip": "127.0.0.1"
+		}
+	}`, string(buffer), "Unexpected format result")
+
+	assert.Equal(t, []Field {
+		Int("userId", 100),
+		String("ip", "127.0.0.1"),
+	}, message.Fields(), "Unexpected fields")
+
+	assert.Equal(t, message.Template, message.SampleText(),
+		"Unexpected sample result")
+}
+
+func TestCompiledMessage(t *testing.T) {
+	fields := []Field { String("status", "ok"), Int("code", 200) }
+	message := CompileMessage("heartbeat ok", fields...)
+	fields[0] = String("status", "changed")
+	expected := StructMessage {
+		Text: "heartbeat ok",
+		Fields: ElementObject { String("status", "ok"), Int("code", 200) },
+	}
+
+	assert.Equal(t, string(expected.SerializeStandard(nil)),
+		string(message.SerializeStandard(nil)), "Unexpected standard data")
+	assert.Equal(t, string(expected.SerializeJSON(nil)),
+		string(message.SerializeJSON(nil)), "Unexpected JSON data")
+	assert.Equal(t, "heartbeat ok", message.SampleText(),
+		"Unexpected sample text")
+
+	option := FieldOption { MaxLength: 1 }
+	assert.Equal(t, string(expected.SerializeJSONWith(nil, option)),
+		string(message.SerializeJSONWith(nil, option)),
+		"Unexpected JSON data")
+	assert.Equal(t, string(expected.SerializeStandardWith(nil, option)),
+		string(message.SerializeStandardWith(nil, option)),
+		"Unexpected standard data")
+
+	buffer := make([]byte, 0, 256)
+	allocs := testing.AllocsPerRun(100, func() {
+		buffer = message.SerializeJSON(buffer[ : 0])
+		buffer = message.SerializeStandardWith(buffer[ : 0], option)
+	})
+	assert.Zero(t, allocs, "Unexpected allocations")
+}
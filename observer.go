@@ -0,0 +1,260 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DropSampled represents that a log entry was dropped by the sampler
+	// of the logger.
+	DropSampled = "sampled"
+
+	// DropGoverned represents that a log entry was dropped because its
+	// level was below the minimum level raised by the volume governor.
+	DropGoverned = "governed"
+
+	// DropOverflow represents that a log entry was dropped because the
+	// queue of an asynchronous exporter was full.
+	DropOverflow = "overflow"
+
+	// DropStale represents that a log entry was dropped because the context
+	// of the request that output it was canceled or expired beyond the
+	// stale threshold of an asynchronous exporter.
+	DropStale = "stale"
+)
+
+// DropRecord is a structure that contains the metadata of a dropped log
+// entry, which is reported to the callback of a drop observer.
+type DropRecord struct {
+	// Name represents the name of the logger of the dropped log entry.
+	Name string
+
+	// Level represents the level of the dropped log entry.
+	Level Level
+
+	// Time represents the time when the log entry was dropped.
+	Time time.Time
+
+	// Reason represents why the log entry was dropped, which is one of the
+	// constants beginning with Drop...
+	Reason string
+
+	// Text represents the text sample of the message of the dropped log
+	// entry, or an empty string if the message does not implement the
+	// TextSampleParser interface.
+	Text string
+
+	// Suppressed represents the number of dropped log entries that were
+	// not reported since the previous record, because the rate limit was
+	// reached or the queue was full.
+	Suppressed uint64
+}
+
+// DropObserver is the structure of a drop observer instance.
+//
+// The drop observer reports the metadata of the log entries dropped by the
+// loggers that use it (for example: by samplers or by volume governors),
+// so that applications can quantify and tune what they lose. The records
+// are queued without blocking the output of log entries, and the callback
+// is called with each record by a dedicated goroutine, at most Limit times
+// per Interval. The records that exceed the rate limit or the capacity of
+// the queue are counted and reported by the Suppressed field of the next
+// record. When the drop observer is closed, the records that are still
+// suppressed are reported by a final record with an empty reason.
+//
+// The API provided by the drop observer is thread-safe. A drop observer
+// can be shared by multiple loggers, and must be closed after it is no
+// longer used.
+type DropObserver struct {
+	missed uint64
+	queue chan DropRecord
+	callback func(record DropRecord)
+	limit int
+	interval time.Duration
+	done chan struct { }
+	mutex sync.RWMutex
+	closed bool
+}
+
+// Observe queues the given record to be reported without blocking. If the
+// queue is full, the record is counted as suppressed.
+func (o *DropObserver) Observe(record DropRecord) {
+	o.mutex.RLock()
+	defer o.mutex.RUnlock()
+	if o.closed {
+		return
+	}
+	select {
+	case o.queue <- record:
+	default:
+		atomic.AddUint64(&o.missed, 1)
+	}
+}
+
+// observe queues a record of the given log entry dropped for the given
+// reason. It does nothing if the drop observer is nil.
+func (o *DropObserver) observe(entry *Entry, reason string) {
+	if o == nil {
+		return
+	}
+	record := DropRecord {
+		Name: entry.Name,
+		Level: entry.Level,
+		Time: entry.Time,
+		Reason: reason,
+	}
+	if parser, ok := entry.Message.(TextSampleParser); ok {
+		record.Text = parser.SampleText()
+	}
+	o.Observe(record)
+}
+
+// run calls the callback with the queued records until the drop observer
+// is closed, and then reports the remaining queued records and the number
+// of records that are still suppressed.
+func (o *DropObserver) run() {
+	defer close(o.done)
+	var suppressed uint64
+	var start time.Time
+	count := 0
+	for record := range o.queue {
+		if now := time.Now(); now.Sub(start) >= o.interval {
+			start, count = now, 0
+		}
+		if count >= o.limit {
+			suppressed++
+			continue
+		}
+		count++
+		record.Suppressed = suppressed + atomic.SwapUint64(&o.missed, 0)
+		suppressed = 0
+		o.callback(record)
+	}
+	if suppressed += atomic.SwapUint64(&o.missed, 0); suppressed > 0 {
+		o.callback(DropRecord {
+			Time: time.Now(),
+			Suppressed: suppressed,
+		})
+	}
+}
+
+// Close stops queuing records, waits until the queued records have been
+// reported, and then returns. Records observed after the drop observer is
+// closed are discarded.
+func (o *DropObserver) Close() error {
+	o.mutex.Lock()
+	if !o.closed {
+		o.closed = true
+		close(o.queue)
+	}
+	o.mutex.Unlock()
+	<-o.done
+	return nil
+}
+
+// DropObserverOption is a structure that contains options for the drop
+// observer.
+type DropObserverOption struct {
+	// Callback represents the function called with each reported record.
+	// This option is required.
+	Callback func(record DropRecord)
+
+	// Limit represents the maximum number of records reported per
+	// interval. If not provided, the default value is 100.
+	Limit int
+
+	// Interval represents the interval of the rate limit. If not provided,
+	// the default value is 1 second.
+	Interval time.Duration
+
+	// Capacity represents the number of records that can be queued. If not
+	// provided, the default value is 1024.
+	Capacity int
+}
+
+// UseCallback uses the given function as the value of the option Callback.
+// Then return to the option instance itself.
+func (o *DropObserverOption) UseCallback(callback func(record DropRecord)) *DropObserverOption {
+	o.Callback = callback
+	return o
+}
+
+// UseLimit uses the given number of records and interval as the values of
+// the options Limit and Interval. Then return to the option instance
+// itself.
+func (o *DropObserverOption) UseLimit(limit int, interval time.Duration) *DropObserverOption {
+	o.Limit = limit
+	o.Interval = interval
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *DropObserverOption) Validate() error {
+	if o.Callback == nil {
+		return newOptionError("Callback", "must not be nil", nil)
+	}
+	if o.Limit <= 0 {
+		return newOptionError("Limit", "must be greater than 0", nil)
+	}
+	if o.Interval <= 0 {
+		return newOptionError("Interval", "must be greater than 0", nil)
+	}
+	if o.Capacity <= 0 {
+		return newOptionError("Capacity", "must be greater than 0", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a drop observer instance, whose goroutine is
+// started.
+func (o *DropObserverOption) Build() (*DropObserver, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	instance := &DropObserver {
+		queue: make(chan DropRecord, o.Capacity),
+		callback: o.Callback,
+		limit: o.Limit,
+		interval: o.Interval,
+		done: make(chan struct { }),
+	}
+	go instance.run()
+	return instance, nil
+}
+
+// NewDropObserverOption creates and returns a drop observer option
+// instance with default option values.
+func NewDropObserverOption() *DropObserverOption {
+	return &DropObserverOption {
+		Limit: 100,
+		Interval: time.Second,
+		Capacity: 1024,
+	}
+}
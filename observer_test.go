@@ -0,0 +1,74 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDropObserver(t *testing.T) {
+	_, err := NewDropObserverOption().Build()
+	assert.Error(t, err, "Unexpected create error")
+
+	var mutex sync.Mutex
+	var records []DropRecord
+	observer, err := NewDropObserverOption().UseLimit(2, time.Hour).
+		UseCallback(func(record DropRecord) {
+			mutex.Lock()
+			records = append(records, record)
+			mutex.Unlock()
+		}).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	option := NewStandardOption().DisableFlushing().DisableCache().
+		UseDropObserver(observer).UseSampling(NewSamplingOption().
+		UseRateOption(NewRateSamplerOption().UseEvery(10)))
+	option.UseName("observed")
+	option.Outputting.UseDiscard()
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	for index := 0; index < 10; index++ {
+		assert.NoError(t, logger.Info(StringMessage("noisy")),
+			"Unexpected print error")
+	}
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+	assert.NoError(t, observer.Close(), "Unexpected close error")
+	observer.Observe(DropRecord { })
+
+	assert.Len(t, records, 3, "Unexpected records")
+	assert.Equal(t, "observed", records[0].Name, "Unexpected record name")
+	assert.Equal(t, LevelInfo, records[0].Level, "Unexpected record level")
+	assert.Equal(t, DropSampled, records[0].Reason, "Unexpected reason")
+	assert.Equal(t, "noisy", records[0].Text, "Unexpected record text")
+	assert.Equal(t, uint64(0), records[1].Suppressed,
+		"Unexpected suppressed count")
+	assert.Equal(t, "", records[2].Reason, "Unexpected reason")
+	assert.Equal(t, uint64(7), records[2].Suppressed,
+		"Unexpected suppressed count")
+}
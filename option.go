@@ -0,0 +1,525 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"io"
+	"time"
+)
+
+// OptionFunc is the type of a functional option of the logger.
+//
+// Functional options are an alternative to the option structures. Each
+// functional option changes one or more values of a standard logger option
+// by calling the corresponding Use... or Disable... function of the
+// StandardOption structure, so the behavior of functional options always
+// stays the same as the option structures. Because the structured logger
+// option and template logger option are based on the standard logger
+// option, functional options can be used to build all logger types.
+//
+// For example, the following creates a structured logger that outputs
+// JSON log entries with a level of INFO or higher to a local file:
+//
+//     logger, err := santa.NewStructLogger(
+//         santa.WithLevel(santa.LevelInfo),
+//         santa.WithJSON(),
+//         santa.WithFile("app.log"))
+type OptionFunc func(option *StandardOption)
+
+// Apply calls the given functional options in order to change the values
+// of the options, and then returns the option instance itself.
+func (o *StandardOption) Apply(options ...OptionFunc) *StandardOption {
+	for index := 0; index < len(options); index++ {
+		if options[index] != nil {
+			options[index](o)
+		}
+	}
+	return o
+}
+
+// WithName returns a functional option that uses the given name as the
+// value of the option Name. For details, please refer to the comment
+// section of the UseName function of the StandardOption structure.
+func WithName(name string) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseName(name)
+	}
+}
+
+// WithLevel returns a functional option that uses the given log level as
+// the value of the option Level. For details, please refer to the comment
+// section of the UseLevel function of the StandardOption structure.
+func WithLevel(level Level) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseLevel(level)
+	}
+}
+
+// WithHooks returns a functional option that appends the given one or
+// more hooks to the option Hooks. For details, please refer to the comment
+// section of the UseHooks function of the StandardOption structure.
+func WithHooks(hooks ...Hook) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseHooks(hooks...)
+	}
+}
+
+// WithLabels returns a functional option that appends the given one or
+// more labels to the option Labels. For details, please refer to the
+// comment section of the UseLabels function of the StandardOption
+// structure.
+func WithLabels(labels ...Label) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseLabels(labels...)
+	}
+}
+
+// WithSampling returns a functional option that uses the given sampling
+// option as the value of the option Sampling. For details, please refer
+// to the comment section of the UseSampling function of the StandardOption
+// structure.
+func WithSampling(sampling *SamplingOption) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseSampling(sampling)
+	}
+}
+
+// WithoutSampling returns a functional option that disables sampling of
+// log entries. For details, please refer to the comment section of the
+// DisableSampling function of the StandardOption structure.
+func WithoutSampling() OptionFunc {
+	return func(option *StandardOption) {
+		option.DisableSampling()
+	}
+}
+
+// WithEncoding returns a functional option that uses the given encoding
+// option as the value of the option Encoding. For details, please refer
+// to the comment section of the UseEncoding function of the StandardOption
+// structure.
+func WithEncoding(encoding *EncodingOption) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseEncoding(encoding)
+	}
+}
+
+// WithStandardEncoder returns a functional option that uses the standard
+// encoder with default optional values to encode log entries. For details,
+// please refer to the comment section of the EncoderStandard constant.
+func WithStandardEncoder() OptionFunc {
+	return func(option *StandardOption) {
+		option.Encoding.UseStandard()
+	}
+}
+
+// WithJSON returns a functional option that uses the JSON encoder with
+// default optional values to encode log entries. For details, please
+// refer to the comment section of the EncoderJSON constant.
+func WithJSON() OptionFunc {
+	return func(option *StandardOption) {
+		option.Encoding.UseJSON()
+	}
+}
+
+// WithoutSourceLocation returns a functional option that disables getting
+// and encoding the source location of log entries. For details, please
+// refer to the comment section of the DisableSourceLocation option of the
+// EncodingOption structure.
+func WithoutSourceLocation() OptionFunc {
+	return func(option *StandardOption) {
+		option.Encoding.DisableSourceLocation = true
+	}
+}
+
+// WithOutputting returns a functional option that uses the given output
+// option as the value of the option Outputting. For details, please refer
+// to the comment section of the UseOutputting function of the
+// StandardOption structure.
+func WithOutputting(outputting *OutputtingOption) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseOutputting(outputting)
+	}
+}
+
+// WithErrorOutputting returns a functional option that uses the given
+// output option as the value of the option ErrorOutputting. For details,
+// please refer to the comment section of the UseErrorOutputting function
+// of the StandardOption structure.
+func WithErrorOutputting(outputting *OutputtingOption) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseErrorOutputting(outputting)
+	}
+}
+
+// WithFallbackOutputting returns a functional option that uses the given
+// output option as the value of the option FallbackOutputting. For details,
+// please refer to the comment section of the UseFallbackOutputting function
+// of the StandardOption structure.
+func WithFallbackOutputting(outputting *OutputtingOption) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseFallbackOutputting(outputting)
+	}
+}
+
+// WithWriter returns a functional option that outputs log entries of all
+// levels to the given writer using the standard synchronizer. For details,
+// please refer to the comment section of the SyncerStandard constant.
+func WithWriter(writer io.Writer) OptionFunc {
+	return func(option *StandardOption) {
+		option.Outputting.UseStandard(writer)
+		option.ErrorOutputting.UseStandard(writer)
+	}
+}
+
+// WithFile returns a functional option that outputs log entries of all
+// levels to the local file with the given name using the file synchronizer.
+// For details, please refer to the comment section of the SyncerFile
+// constant.
+//
+// Please note that the file is opened twice in append mode, once for log
+// entries from DEBUG to WARNING and once for log entries from ERROR to
+// FATAL, and each handle has its own internal cache.
+func WithFile(name string) OptionFunc {
+	return func(option *StandardOption) {
+		option.Outputting.UseFile(name)
+		option.ErrorOutputting.UseFile(name)
+	}
+}
+
+// WithRotation returns a functional option that rotates the local files
+// used by the file synchronizer of output and error output. For details,
+// please refer to the comment section of the UseRotation function of the
+// FileSyncerOption structure.
+//
+// Please note that if output and error output use the same file (for
+// example: the WithFile functional option is used), both handles rotate the
+// file together: when either of them rotates it, the other opens the new
+// file before its next write.
+func WithRotation(maxSize int64, maxBackups int, maxAge time.Duration) OptionFunc {
+	return func(option *StandardOption) {
+		output, ok := option.Outputting.Option.(*FileSyncerOption)
+		if ok && option.Outputting.Type == SyncerFile {
+			output.UseRotation(maxSize, maxBackups, maxAge)
+		}
+		errorOutput, ok := option.ErrorOutputting.Option.(*FileSyncerOption)
+		if !ok || option.ErrorOutputting.Type != SyncerFile {
+			return
+		}
+		errorOutput.UseRotation(maxSize, maxBackups, maxAge)
+	}
+}
+
+// WithoutCache returns a functional option that disables the internal
+// cache of output, error output and fallback output. For details, please
+// refer to the comment section of the DisableCache function of the
+// StandardOption structure.
+func WithoutCache() OptionFunc {
+	return func(option *StandardOption) {
+		option.DisableCache()
+	}
+}
+
+// WithFlushing returns a functional option that automatically flushes
+// cached log entry data at the given interval. For details, please refer
+// to the comment section of the FlushingOption structure.
+func WithFlushing(interval time.Duration) OptionFunc {
+	return func(option *StandardOption) {
+		option.UseFlushing(NewFlushingOption().UseInterval(interval))
+	}
+}
+
+// WithoutFlushing returns a functional option that disables automatic
+// flushing of cached log entry data. For details, please refer to the
+// comment section of the DisableFlushing function of the StandardOption
+// structure.
+func WithoutFlushing() OptionFunc {
+	return func(option *StandardOption) {
+		option.DisableFlushing()
+	}
+}
+
+// NewStandardLogger creates and returns a standard logger instance using
+// the default optional values changed by the given functional options.
+func NewStandardLogger(options ...OptionFunc) (*StandardLogger, error) {
+	return NewStandardOption().Apply(options...).Build()
+}
+
+// NewStructLogger creates and returns a structured logger instance using
+// the default optional values changed by the given functional options.
+func NewStructLogger(options ...OptionFunc) (*StructLogger, error) {
+	option := NewStructOption()
+	option.Apply(options...)
+	return option.Build()
+}
+
+// NewTemplateLogger creates and returns a template logger instance using
+// the default optional values changed by the given functional options.
+func NewTemplateLogger(options ...OptionFunc) (*TemplateLogger, error) {
+	option := NewTemplateOption()
+	option.Apply(options...)
+	return option.Build()
+}
+
+// cloneOptionValue returns a copy of the given value of an Option field of
+// the sampling, encoding or outputting options. The known option types are
+// copied, and other values (including nil) are returned as is.
+func cloneOptionValue(value interface { }) interface { } {
+	switch option := value.(type) {
+	case *TextSamplerOption:
+		if option != nil {
+			copied := *option
+			return &copied
+		}
+	case *HeadTailSamplerOption:
+		if option != nil {
+			copied := *option
+			return &copied
+		}
+	case *RateSamplerOption:
+		if option != nil {
+			copied := *option
+			return &copied
+		}
+	case *AdaptiveSamplerOption:
+		if option != nil {
+			copied := *option
+			copied.Reporters = append([]PressureReporter(nil),
+				option.Reporters...)
+			return &copied
+		}
+	case *StandardEncoderOption:
+		if option != nil {
+			copied := *option
+			return &copied
+		}
+	case *JSONEncoderOption:
+		if option != nil {
+			copied := *option
+			return &copied
+		}
+	case *ConsoleEncoderOption:
+		if option != nil {
+			copied := *option
+			copied.Styles = make(map[Level]ConsoleStyle,
+				len(option.Styles))
+			for level, style := range option.Styles {
+				copied.Styles[level] = style
+			}
+			return &copied
+		}
+	case *StandardSyncerOption:
+		if option != nil {
+			copied := *option
+			return &copied
+		}
+	case *FileSyncerOption:
+		if option != nil {
+			copied := *option
+			return &copied
+		}
+	case *NetworkSyncerOption:
+		if option != nil {
+			copied := *option
+			return &copied
+		}
+	}
+	return value
+}
+
+// Clone returns a deep copy of the sampling option.
+func (o *SamplingOption) Clone() *SamplingOption {
+	copied := *o
+	copied.Option = cloneOptionValue(o.Option)
+	if o.Levels != nil {
+		copied.Levels = make(map[Level]*SamplingOption, len(o.Levels))
+		for level, option := range o.Levels {
+			if option != nil {
+				option = option.Clone()
+			}
+			copied.Levels[level] = option
+		}
+	}
+	return &copied
+}
+
+// Clone returns a deep copy of the encoding option.
+func (o *EncodingOption) Clone() *EncodingOption {
+	copied := *o
+	copied.Option = cloneOptionValue(o.Option)
+	return &copied
+}
+
+// Clone returns a deep copy of the outputting option. The writer of the
+// standard synchronizer option is shared, because it cannot be copied.
+func (o *OutputtingOption) Clone() *OutputtingOption {
+	copied := *o
+	copied.Option = cloneOptionValue(o.Option)
+	return &copied
+}
+
+// Clone returns a deep copy of the option, so that a base option can be
+// defined once and customized for each component without changing the
+// base option. The option slices and the options of the sampler, encoder
+// and synchronizers are copied, but the hooks, writers and other instances
+// referenced by the option are shared.
+func (o *StandardOption) Clone() *StandardOption {
+	copied := *o
+	copied.Sampling = *o.Sampling.Clone()
+	copied.Encoding = *o.Encoding.Clone()
+	copied.Outputting = *o.Outputting.Clone()
+	copied.ErrorOutputting = *o.ErrorOutputting.Clone()
+	copied.FallbackOutputting = *o.FallbackOutputting.Clone()
+	copied.Hooks = append([]Hook(nil), o.Hooks...)
+	copied.Labels = append(Labels(nil), o.Labels...)
+	return &copied
+}
+
+// Merge changes the values of the option to the values of the given other
+// option that are provided, and then returns the option instance itself.
+// The values of the other option are copied, so later changes to either
+// option do not affect the other.
+//
+// The values of the other option are merged as follows: the Name,
+// NameSeparator, Level, Sampling, Encoding, Outputting, ErrorOutputting,
+// FallbackOutputting, FallbackThreshold, FallbackProbeInterval, Flushing,
+// Latency, Profiling, Governor, DropObserver, GuardReentrancy (with
+// ReentrancyOutput), EmitLifecycleEvents, Development and TimeSource
+// options are replaced if they are not the zero value (for example: the
+// Type or Levels option of the Sampling option is not empty), and the Hooks
+// and Labels options are appended. Please note that a zero value cannot be
+// merged, for example the DEBUG level or disabled sampling, use the Use...
+// or Disable... functions instead.
+func (o *StandardOption) Merge(other *StandardOption) *StandardOption {
+	if len(other.Name) > 0 {
+		o.Name = other.Name
+	}
+	if len(other.NameSeparator) > 0 {
+		o.NameSeparator = other.NameSeparator
+	}
+	if other.Level != LevelDebug {
+		o.Level = other.Level
+	}
+	if len(other.Sampling.Type) > 0 || len(other.Sampling.Levels) > 0 {
+		o.Sampling = *other.Sampling.Clone()
+	}
+	if len(other.Encoding.Type) > 0 {
+		o.Encoding = *other.Encoding.Clone()
+	}
+	if len(other.Outputting.Type) > 0 {
+		o.Outputting = *other.Outputting.Clone()
+	}
+	if len(other.ErrorOutputting.Type) > 0 {
+		o.ErrorOutputting = *other.ErrorOutputting.Clone()
+	}
+	if len(other.FallbackOutputting.Type) > 0 {
+		o.FallbackOutputting = *other.FallbackOutputting.Clone()
+	}
+	if other.FallbackThreshold > 0 {
+		o.FallbackThreshold = other.FallbackThreshold
+	}
+	if other.FallbackProbeInterval > 0 {
+		o.FallbackProbeInterval = other.FallbackProbeInterval
+	}
+	if other.Flushing != (FlushingOption { }) {
+		o.Flushing = other.Flushing
+	}
+	if other.Latency != nil {
+		o.Latency = other.Latency
+	}
+	if other.Profiling {
+		o.Profiling = true
+	}
+	if other.Governor != nil {
+		o.Governor = other.Governor
+	}
+	if other.DropObserver != nil {
+		o.DropObserver = other.DropObserver
+	}
+	if other.GuardReentrancy {
+		o.GuardReentrancy = true
+		o.ReentrancyOutput = other.ReentrancyOutput
+	}
+	if other.EmitLifecycleEvents {
+		o.EmitLifecycleEvents = true
+	}
+	if other.Development {
+		o.Development = true
+	}
+	if other.TimeSource != nil {
+		o.TimeSource = other.TimeSource
+	}
+	if len(other.Hooks) > 0 {
+		hooks := make([]Hook, 0, len(o.Hooks) + len(other.Hooks))
+		o.Hooks = append(append(hooks, o.Hooks...), other.Hooks...)
+	}
+	if len(other.Labels) > 0 {
+		labels := make(Labels, 0, len(o.Labels) + len(other.Labels))
+		o.Labels = append(append(labels, o.Labels...), other.Labels...)
+	}
+	return o
+}
+
+// Clone returns a deep copy of the option. For details, please refer to
+// the comment section of the Clone function of the StandardOption
+// structure.
+func (o *StructOption) Clone() *StructOption {
+	return &StructOption {
+		StandardOption: *o.StandardOption.Clone(),
+	}
+}
+
+// Merge changes the values of the option to the values of the given other
+// option that are provided, and then returns the option instance itself.
+// For details, please refer to the comment section of the Merge function
+// of the StandardOption structure.
+func (o *StructOption) Merge(other *StructOption) *StructOption {
+	o.StandardOption.Merge(&other.StandardOption)
+	return o
+}
+
+// Clone returns a deep copy of the option. The catalog is shared. For
+// details, please refer to the comment section of the Clone function of
+// the StandardOption structure.
+func (o *TemplateOption) Clone() *TemplateOption {
+	return &TemplateOption {
+		StandardOption: *o.StandardOption.Clone(),
+		Catalog: o.Catalog,
+		Locale: o.Locale,
+	}
+}
+
+// Merge changes the values of the option to the values of the given other
+// option that are provided, and then returns the option instance itself.
+// The Catalog and Locale options are replaced if they are provided. For
+// details, please refer to the comment section of the Merge function of
+// the StandardOption structure.
+func (o *TemplateOption) Merge(other *TemplateOption) *TemplateOption {
+	o.StandardOption.Merge(&other.StandardOption)
+	if other.Catalog != nil {
+		o.Catalog = other.Catalog
+	}
+	if len(other.Locale) > 0 {
+		o.Locale = other.Locale
+	}
+	return o
+}
@@ -0,0 +1,200 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStandardOptionApply(t *testing.T) {
+	hook := NewSimpleHook(func(entry *Entry) error {
+		return nil
+	})
+	option := NewStandardOption().Apply(
+		WithName("testing"),
+		WithLevel(LevelWarning),
+		WithHooks(hook),
+		WithLabels(NewLabel("name", "testing")),
+		WithoutSampling(),
+		WithJSON(),
+		WithoutSourceLocation(),
+		WithFile(os.DevNull),
+		WithRotation(1024, 3, time.Hour),
+		WithoutCache(),
+		WithFlushing(time.Minute),
+		nil,
+	)
+
+	assert.Equal(t, "testing", option.Name, "Unexpected option value")
+	assert.Equal(t, LevelWarning, option.Level, "Unexpected option value")
+	assert.Len(t, option.Hooks, 1, "Unexpected option value")
+	assert.Len(t, option.Labels, 1, "Unexpected option value")
+	assert.Equal(t, "", option.Sampling.Type, "Unexpected option value")
+	assert.Equal(t, EncoderJSON, option.Encoding.Type,
+		"Unexpected option value")
+	assert.True(t, option.Encoding.DisableSourceLocation,
+		"Unexpected option value")
+	assert.Equal(t, SyncerFile, option.Outputting.Type,
+		"Unexpected option value")
+	assert.Equal(t, SyncerFile, option.ErrorOutputting.Type,
+		"Unexpected option value")
+	assert.True(t, option.Outputting.DisableCache, "Unexpected option value")
+	assert.Equal(t, int64(1024),
+		option.Outputting.Option.(*FileSyncerOption).MaxSize,
+		"Unexpected option value")
+	assert.Equal(t, int64(1024),
+		option.ErrorOutputting.Option.(*FileSyncerOption).MaxSize,
+		"Unexpected option value")
+	assert.Equal(t, time.Minute, option.Flushing.Interval,
+		"Unexpected option value")
+
+	option.Apply(WithoutFlushing(), WithStandardEncoder(),
+		WithWriter(ioutil.Discard))
+
+	assert.Equal(t, time.Duration(0), option.Flushing.Interval,
+		"Unexpected option value")
+	assert.Equal(t, EncoderStandard, option.Encoding.Type,
+		"Unexpected option value")
+	assert.Equal(t, SyncerStandard, option.Outputting.Type,
+		"Unexpected option value")
+}
+
+func TestNewLoggerWithOptions(t *testing.T) {
+	options := []OptionFunc {
+		WithLevel(LevelInfo),
+		WithWriter(ioutil.Discard),
+		WithoutFlushing(),
+	}
+
+	standard, err := NewStandardLogger(options...)
+	assert.NoError(t, err, "Unexpected create error")
+	assert.Equal(t, LevelInfo, standard.Level(), "Unexpected instance error")
+	assert.NoError(t, standard.Close(), "Unexpected close error")
+
+	structure, err := NewStructLogger(options...)
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, structure.Infos("Hello Test!", Int("count", 1)),
+		"Unexpected print error")
+	assert.NoError(t, structure.Close(), "Unexpected close error")
+
+	template, err := NewTemplateLogger(options...)
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, template.Infof("Hello %s!", "Test"),
+		"Unexpected print error")
+	assert.NoError(t, template.Close(), "Unexpected close error")
+}
+
+func TestWithRotationSharedFile(t *testing.T) {
+	directory := t.TempDir()
+	name := filepath.Join(directory, "app.log")
+
+	logger, err := NewStructLogger(WithFile(name), WithRotation(200, 1, 0),
+		WithoutSampling(), WithoutCache(), WithoutFlushing())
+	assert.NoError(t, err, "Unexpected create error")
+	for count := 0; count < 20; count++ {
+		assert.NoError(t, logger.Infos("Hello Test!", Int("count", int64(count))),
+			"Unexpected print error")
+	}
+	assert.NoError(t, logger.Errors("IMPORTANT ERROR"),
+		"Unexpected print error")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+
+	matches, err := filepath.Glob(filepath.Join(directory, "app*.log"))
+	assert.NoError(t, err, "Unexpected glob error")
+	assert.Len(t, matches, 2, "Unexpected files")
+	found := false
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		assert.NoError(t, err, "Unexpected read error")
+		found = found || strings.Contains(string(data), "IMPORTANT ERROR")
+	}
+	assert.True(t, found, "Unexpected lost log entry")
+}
+
+func TestStandardOptionCloneAndMerge(t *testing.T) {
+	base := NewStandardOption().UseName("base").
+		UseLabels(NewLabel("zone", "ap-shanghai-1"))
+	base.Outputting.UseFile("base.log")
+
+	option := base.Clone()
+	option.UseLabels(NewLabel("component", "api"))
+	option.Outputting.Option.(*FileSyncerOption).UseName("api.log")
+	option.Sampling.Option.(*TextSamplerOption).UseFirst(1, 1)
+	option.Encoding.Option.(*StandardEncoderOption).EncodeTime = false
+
+	assert.Len(t, base.Labels, 1, "Unexpected base option value")
+	assert.Equal(t, "base.log", base.Outputting.Option.(*FileSyncerOption).
+		FileName, "Unexpected base option value")
+	assert.Equal(t, uint64(100), base.Sampling.Option.(*TextSamplerOption).
+		First, "Unexpected base option value")
+	assert.True(t, base.Encoding.Option.(*StandardEncoderOption).EncodeTime,
+		"Unexpected base option value")
+
+	other := &StandardOption {
+		Name: "api",
+		NameSeparator: "/",
+		Level: LevelError,
+		Labels: Labels { NewLabel("version", "1") },
+		EmitLifecycleEvents: true,
+		Development: true,
+		TimeSource: func() time.Time { return time.Unix(1600000000, 0) },
+	}
+	other.Encoding.UseJSON()
+	merged := base.Clone().Merge(other)
+	assert.Equal(t, "api", merged.Name, "Unexpected merged option value")
+	assert.Equal(t, "/", merged.NameSeparator,
+		"Unexpected merged option value")
+	assert.Equal(t, LevelError, merged.Level, "Unexpected merged option value")
+	assert.Equal(t, EncoderJSON, merged.Encoding.Type,
+		"Unexpected merged option value")
+	assert.Equal(t, SyncerFile, merged.Outputting.Type,
+		"Unexpected merged option value")
+	assert.Equal(t, SamplerText, merged.Sampling.Type,
+		"Unexpected merged option value")
+	assert.Len(t, merged.Labels, 2, "Unexpected merged option value")
+	assert.True(t, merged.EmitLifecycleEvents,
+		"Unexpected merged option value")
+	assert.True(t, merged.Development, "Unexpected merged option value")
+	assert.Equal(t, int64(1600000000), merged.TimeSource().Unix(),
+		"Unexpected merged option value")
+	assert.NotSame(t, other.Encoding.Option, merged.Encoding.Option,
+		"Unexpected shared option value")
+
+	template := NewTemplateOption().Merge(&TemplateOption {
+		Locale: "zh-CN",
+	})
+	assert.Equal(t, "zh-CN", template.Clone().Locale,
+		"Unexpected merged option value")
+	structure := NewStructOption().Merge(&StructOption {
+		StandardOption: StandardOption { Name: "api" },
+	})
+	assert.Equal(t, "api", structure.Clone().Name,
+		"Unexpected merged option value")
+}
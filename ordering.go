@@ -0,0 +1,341 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"container/heap"
+	"math"
+	"sync"
+	"time"
+)
+
+// orderingRecord is a structure that contains an encoded log entry held
+// by an ordering exporter, and the time and arrival order it is sorted by.
+type orderingRecord struct {
+	time int64
+	sequence uint64
+	data []byte
+}
+
+// orderingHeap is a min-heap of held records ordered by their times, and
+// then by their arrival order. It implements the heap.Interface interface.
+type orderingHeap []orderingRecord
+
+func (h orderingHeap) Len() int {
+	return len(h)
+}
+
+func (h orderingHeap) Less(i, j int) bool {
+	if h[i].time != h[j].time {
+		return h[i].time < h[j].time
+	}
+	return h[i].sequence < h[j].sequence
+}
+
+func (h orderingHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *orderingHeap) Push(value interface { }) {
+	*h = append(*h, value.(orderingRecord))
+}
+
+func (h *orderingHeap) Pop() interface { } {
+	old := *h
+	record := old[len(old) - 1]
+	old[len(old) - 1] = orderingRecord { }
+	*h = old[ : len(old) - 1]
+	return record
+}
+
+// orderingSyncer is the synchronizer that the wrapped exporter of an
+// ordering exporter writes its encoded log entries to. It holds each
+// encoded log entry until it is released by the ordering exporter in the
+// order of their times.
+type orderingSyncer struct {
+	syncer Syncer
+	records orderingHeap
+	time int64
+	sequence uint64
+	released int64
+	late uint64
+}
+
+// Write holds a copy of the given encoded log entry with the time of the
+// log entry being exported. If the time is earlier than the time of a log
+// entry already released, the log entry can no longer be ordered, so it is
+// written to the synchronizer immediately and counted as late.
+//
+// Finally, it returns the number of bytes of the given buffer slice and
+// any errors encountered.
+func (s *orderingSyncer) Write(buffer []byte) (int, error) {
+	if s.time < s.released {
+		s.late++
+		return s.syncer.Write(buffer)
+	}
+	s.sequence++
+	heap.Push(&s.records, orderingRecord {
+		time: s.time,
+		sequence: s.sequence,
+		data: append([]byte(nil), buffer...),
+	})
+	return len(buffer), nil
+}
+
+// release writes the held log entries whose times are earlier than or
+// equal to the given watermark to the synchronizer in the order of their
+// times, and then, if more than the given capacity of log entries are
+// still held, the earliest of them as well. Finally, the first error
+// encountered is returned.
+func (s *orderingSyncer) release(watermark int64, capacity int) error {
+	var first error
+	for len(s.records) > 0 && (s.records[0].time <= watermark ||
+		len(s.records) > capacity) {
+		record := heap.Pop(&s.records).(orderingRecord)
+		if record.time > s.released {
+			s.released = record.time
+		}
+		if _, err := s.syncer.Write(record.data); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Sync releases all held log entries, and then synchronizes the
+// synchronizer.
+func (s *orderingSyncer) Sync() error {
+	if err := s.release(math.MaxInt64, 0); err != nil {
+		return err
+	}
+	return s.syncer.Sync()
+}
+
+// Close releases all held log entries, and then closes the synchronizer.
+func (s *orderingSyncer) Close() error {
+	err := s.release(math.MaxInt64, 0)
+	if closeErr := s.syncer.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// OrderingExporter is the structure of the ordering exporter instance.
+//
+// The ordering exporter wraps a standard exporter, and holds its encoded
+// log entries for a short window before writing them to the synchronizer
+// of the wrapped exporter sorted by the times of the log entries. It is
+// intended for multi-source setups (for example: a relay server, or the
+// loggers of a combined logger sharing a synchronizer) whose merged log
+// entries arrive slightly out of order, so that downstream consumers that
+// assume monotonic time see a roughly ordered stream.
+//
+// A log entry is released once a log entry whose time is later than its
+// time by at least the window has been exported, once the Flush function
+// is called at least the window after its time, or once more than the
+// capacity of log entries are held. The Sync and Close functions release
+// all held log entries. A log entry whose time is earlier than the time of
+// a log entry already released is written immediately and counted as
+// late, which can be reduced by widening the window.
+//
+// The API provided by the ordering exporter is thread-safe.
+type OrderingExporter struct {
+	exporter StandardExporter
+	syncer *orderingSyncer
+	window int64
+	capacity int
+	newest int64
+	mutex sync.Mutex
+}
+
+// Export encodes the given log entry using the wrapped exporter and holds
+// it, and then releases the held log entries that are earlier than the
+// latest exported log entry by at least the window. For details, please
+// refer to the comment section of the OrderingExporter structure.
+//
+// Finally, any errors encountered are returned.
+func (e *OrderingExporter) Export(entry *Entry) error {
+	if !e.exporter.Match(entry) {
+		return nil
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	clock := entry.Time.UnixNano()
+	e.syncer.time = clock
+	if err := e.exporter.Export(entry); err != nil {
+		return err
+	}
+	if clock > e.newest {
+		e.newest = clock
+	}
+	return e.syncer.release(e.newest - e.window, e.capacity)
+}
+
+// Match checks whether the given log entry matches the conditions of the
+// wrapped exporter. For details, please refer to the comment section of
+// the MatchingExporter interface.
+func (e *OrderingExporter) Match(entry *Entry) bool {
+	return e.exporter.Match(entry)
+}
+
+// Pending returns the number of log entries held by the ordering exporter.
+func (e *OrderingExporter) Pending() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return len(e.syncer.records)
+}
+
+// Late returns the number of log entries that were written out of order,
+// because they arrived after a later log entry had been released.
+func (e *OrderingExporter) Late() uint64 {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.syncer.late
+}
+
+// Flush releases the held log entries that are earlier than the current
+// time by at least the window, and then flushes the synchronizer of the
+// wrapped exporter.
+//
+// Finally, any errors encountered are returned.
+func (e *OrderingExporter) Flush() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	watermark := time.Now().UnixNano() - e.window
+	if err := e.syncer.release(watermark, e.capacity); err != nil {
+		return err
+	}
+	if flusher, ok := e.syncer.syncer.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+// Sync releases all held log entries, and then synchronizes the
+// synchronizer of the wrapped exporter.
+//
+// Finally, any errors encountered are returned.
+func (e *OrderingExporter) Sync() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.syncer.Sync()
+}
+
+// Close releases all held log entries, and then closes the synchronizer of
+// the wrapped exporter.
+//
+// Finally, any errors encountered are returned.
+func (e *OrderingExporter) Close() error {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	return e.syncer.Close()
+}
+
+// OrderingExporterOption is a structure that contains ordering exporter
+// options.
+type OrderingExporterOption struct {
+	// Exporter represents the wrapped standard exporter. This option is
+	// required.
+	Exporter *StandardExporter
+
+	// Window represents how long log entries are held to be sorted by
+	// their times. If not provided, the default value is 500 milliseconds.
+	Window time.Duration
+
+	// Capacity represents the maximum number of log entries held. If more
+	// log entries are held, the earliest of them are released even if the
+	// window has not passed. If not provided, the default value is 4096.
+	Capacity int
+}
+
+// UseExporter uses the given standard exporter as the value of the option
+// Exporter. Then return to the option instance itself.
+func (o *OrderingExporterOption) UseExporter(exporter *StandardExporter) *OrderingExporterOption {
+	o.Exporter = exporter
+	return o
+}
+
+// UseWindow uses the given duration as the value of the option Window.
+// Then return to the option instance itself.
+func (o *OrderingExporterOption) UseWindow(window time.Duration) *OrderingExporterOption {
+	o.Window = window
+	return o
+}
+
+// UseCapacity uses the given number of log entries as the value of the
+// option Capacity. Then return to the option instance itself.
+func (o *OrderingExporterOption) UseCapacity(capacity int) *OrderingExporterOption {
+	o.Capacity = capacity
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *OrderingExporterOption) Validate() error {
+	if o.Exporter == nil {
+		return newOptionError("Exporter", "must not be nil", nil)
+	}
+	if o.Window <= 0 {
+		return newOptionError("Window", "must be greater than 0", nil)
+	}
+	if o.Capacity <= 0 {
+		return newOptionError("Capacity", "must be greater than 0", nil)
+	}
+	return nil
+}
+
+// Build builds and returns an ordering exporter instance.
+func (o *OrderingExporterOption) Build() (*OrderingExporter, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	syncer := &orderingSyncer {
+		syncer: o.Exporter.syncer,
+		released: math.MinInt64,
+	}
+	instance := &OrderingExporter {
+		exporter: *o.Exporter,
+		syncer: syncer,
+		window: int64(o.Window),
+		capacity: o.Capacity,
+		newest: math.MinInt64,
+	}
+	instance.exporter.syncer = syncer
+	return instance, nil
+}
+
+// NewOrderingExporterOption creates and returns an ordering exporter
+// option instance with default option values.
+func NewOrderingExporterOption() *OrderingExporterOption {
+	return &OrderingExporterOption {
+		Window: 500 * time.Millisecond,
+		Capacity: 4096,
+	}
+}
@@ -0,0 +1,82 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrderingExporter(t *testing.T) {
+	encoder, err := NewStandardEncoderOption().UseEncoderOption(
+		EncoderOption { }).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	writer := &testLockedWriter { }
+	syncer, err := NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	exporter, err := NewStandardExporterOption().UseEncoder(encoder).
+		UseSyncer(syncer).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	ordering, err := NewOrderingExporterOption().UseExporter(exporter).
+		UseWindow(time.Second).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	start := time.Now()
+	export := func(text string, offset time.Duration) {
+		assert.NoError(t, ordering.Export(&Entry {
+			Time: start.Add(offset),
+			Level: LevelInfo,
+			Message: StringMessage(text),
+		}), "Unexpected export error")
+	}
+	export("c", time.Second)
+	export("a", 200 * time.Millisecond)
+	export("b", 500 * time.Millisecond)
+	assert.Empty(t, writer.String(), "Unexpected released entries")
+	assert.Equal(t, 3, ordering.Pending(), "Unexpected pending entries")
+
+	export("d", 2800 * time.Millisecond)
+	assert.Equal(t, "\"a\"\n\"b\"\n\"c\"\n", writer.String(),
+		"Unexpected released entries")
+	export("late", 400 * time.Millisecond)
+	assert.Equal(t, uint64(1), ordering.Late(), "Unexpected late entries")
+	assert.True(t, strings.HasSuffix(writer.String(), "\"late\"\n"),
+		"Unexpected late entry")
+
+	assert.NoError(t, ordering.Sync(), "Unexpected sync error")
+	assert.Equal(t, 0, ordering.Pending(), "Unexpected pending entries")
+	assert.True(t, strings.HasSuffix(writer.String(), "\"d\"\n"),
+		"Unexpected released entries")
+	assert.NoError(t, ordering.Close(), "Unexpected close error")
+
+	_, err = NewOrderingExporterOption().Build()
+	assert.True(t, errors.Is(err, ErrInvalidOption), "Unexpected create error")
+	_, err = NewOrderingExporterOption().UseExporter(exporter).
+		UseCapacity(0).Build()
+	assert.True(t, errors.Is(err, ErrInvalidOption), "Unexpected create error")
+}
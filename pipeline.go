@@ -0,0 +1,566 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PipelineAttribute is a structure that contains an attribute of a stage
+// of the pipeline of a logger, such as the level span of an exporter.
+type PipelineAttribute struct {
+	// Key represents the name of the attribute.
+	Key string
+
+	// Value represents the value of the attribute.
+	Value string
+}
+
+// PipelineNode is a structure that contains the description of a stage of
+// the pipeline of a logger, and the stages it passes log entries to. For
+// details, please refer to the comment section of the ExplainPipeline
+// function of the Logger structure.
+type PipelineNode struct {
+	// Kind represents the role of the stage in the pipeline, such as
+	// "logger", "sampler", "hook", "exporter", "encoder" or "syncer".
+	Kind string
+
+	// Type represents the type of the stage, such as
+	// "*santa.StandardExporter".
+	Type string
+
+	// Attributes represents the attributes of the stage in the order in
+	// which they were added, such as the level span and the matchers of an
+	// exporter.
+	Attributes []PipelineAttribute
+
+	// Children represents the stages that the stage passes log entries to,
+	// in the order in which they are called.
+	Children []PipelineNode
+}
+
+// PipelineExplainer is the interface implemented by the stages of the
+// pipeline (for example: samplers, hooks, exporters, encoders and syncers)
+// that describe their configuration and the stages they wrap. Stages that
+// do not implement it are described by their types only.
+type PipelineExplainer interface {
+	// ExplainPipeline returns the description of the stage. The Kind of
+	// the description can be empty, in which case it is set to the role
+	// of the stage in the pipeline.
+	ExplainPipeline() PipelineNode
+}
+
+// NewPipelineNode returns the description of the given stage with the
+// given role in the pipeline. If the stage implements the PipelineExplainer
+// interface, its own description is used.
+func NewPipelineNode(kind string, stage interface { }) PipelineNode {
+	var node PipelineNode
+	if explainer, ok := stage.(PipelineExplainer); ok {
+		node = explainer.ExplainPipeline()
+	}
+	if len(node.Kind) == 0 {
+		node.Kind = kind
+	}
+	if len(node.Type) == 0 {
+		node.Type = fmt.Sprintf("%T", stage)
+	}
+	return node
+}
+
+// With appends an attribute with the given key and value to the node, and
+// then returns the node.
+func (n PipelineNode) With(key, value string) PipelineNode {
+	n.Attributes = append(n.Attributes, PipelineAttribute {
+		Key: key,
+		Value: value,
+	})
+	return n
+}
+
+// Append appends the given child nodes to the node, and then returns the
+// node.
+func (n PipelineNode) Append(children ...PipelineNode) PipelineNode {
+	n.Children = append(n.Children, children...)
+	return n
+}
+
+// Attribute returns the value of the attribute with the given key, and
+// whether the node has such an attribute.
+func (n PipelineNode) Attribute(key string) (string, bool) {
+	for _, attribute := range n.Attributes {
+		if attribute.Key == key {
+			return attribute.Value, true
+		}
+	}
+	return "", false
+}
+
+// appendSummary appends the kind, the type and the attributes of the node
+// separated by the given separator to the given buffer slice, and then
+// returns the appended buffer slice.
+func (n PipelineNode) appendSummary(buffer []byte, separator string) []byte {
+	buffer = append(buffer, n.Kind...)
+	if len(n.Type) > 0 {
+		buffer = append(buffer, separator...)
+		buffer = append(buffer, n.Type...)
+	}
+	for _, attribute := range n.Attributes {
+		buffer = append(buffer, separator...)
+		buffer = append(buffer, attribute.Key...)
+		buffer = append(buffer, '=')
+		buffer = append(buffer, attribute.Value...)
+	}
+	return buffer
+}
+
+// appendTree appends the node and its children indented by the given
+// depth to the given buffer slice, and then returns the appended buffer
+// slice.
+func (n PipelineNode) appendTree(buffer []byte, depth int) []byte {
+	for index := 0; index < depth; index++ {
+		buffer = append(buffer, "  "...)
+	}
+	buffer = append(n.appendSummary(buffer, " "), '\n')
+	for _, child := range n.Children {
+		buffer = child.appendTree(buffer, depth + 1)
+	}
+	return buffer
+}
+
+// String returns the description of the pipeline as an indented tree, one
+// stage per line.
+func (n PipelineNode) String() string {
+	return string(n.appendTree(nil, 0))
+}
+
+// appendDOT appends the DOT statements of the node with the given ID and
+// its children to the given buffer slice, and then returns the appended
+// buffer slice and the next available ID.
+func (n PipelineNode) appendDOT(buffer []byte, id int) ([]byte, int) {
+	buffer = append(buffer, "  n"...)
+	buffer = strconv.AppendInt(buffer, int64(id), 10)
+	buffer = append(buffer, " [label="...)
+	buffer = strconv.AppendQuote(buffer, string(n.appendSummary(nil, "\n")))
+	buffer = append(buffer, "];\n"...)
+	next := id + 1
+	for _, child := range n.Children {
+		buffer = append(buffer, "  n"...)
+		buffer = strconv.AppendInt(buffer, int64(id), 10)
+		buffer = append(buffer, " -> n"...)
+		buffer = strconv.AppendInt(buffer, int64(next), 10)
+		buffer = append(buffer, ";\n"...)
+		buffer, next = child.appendDOT(buffer, next)
+	}
+	return buffer, next
+}
+
+// DOT returns the description of the pipeline as a directed graph in the
+// DOT language of Graphviz (for example: rendered by "dot -Tsvg").
+func (n PipelineNode) DOT() string {
+	buffer := append([]byte(nil), "digraph pipeline {\n  node [shape=box];\n"...)
+	buffer, _ = n.appendDOT(buffer, 0)
+	return string(append(buffer, "}\n"...))
+}
+
+// ExplainPipeline returns the description of the current pipeline of the
+// logger: the sampler, the hooks and the exporters in the order in which
+// log entries pass through them, with the matchers, the encoders and the
+// synchronizers of the exporters. It is intended to answer why a log entry
+// did not show up without reading the code that built the logger. Use the
+// String or DOT function of the returned value to render it.
+func (l *Logger) ExplainPipeline() PipelineNode {
+	config := l.config()
+	node := PipelineNode { Kind: "logger" }
+	if len(config.name) > 0 {
+		node = node.With("name", config.name)
+	}
+	node = node.With("level", config.level.String())
+	if config.labels.Count() > 0 {
+		node = node.With("labels", config.labels.Labels().String())
+	}
+	if !config.addSource {
+		node = node.With("source_location", "disabled")
+	}
+	if config.sampler != nil {
+		node = node.Append(NewPipelineNode("sampler", config.sampler))
+	}
+	for _, hook := range config.hooks {
+		node = node.Append(NewPipelineNode("hook", hook))
+	}
+	for _, exporter := range config.exporters {
+		node = node.Append(NewPipelineNode("exporter", exporter))
+	}
+	return node
+}
+
+// ExplainPipeline returns the description of the level sampler and the
+// samplers of its levels.
+func (s *LevelSampler) ExplainPipeline() PipelineNode {
+	var node PipelineNode
+	for level, sampler := range s.samplers {
+		if sampler != nil {
+			node = node.Append(NewPipelineNode("sampler", sampler).
+				With("level", Level(level).String()))
+		}
+	}
+	return node
+}
+
+// ExplainPipeline returns the description of the standard exporter, its
+// matchers, its encoder and its synchronizer.
+func (e *StandardExporter) ExplainPipeline() PipelineNode {
+	node := PipelineNode { }.With("levels", e.span.Start.String() + ".." +
+		e.span.End.String())
+	if len(e.names) > 0 {
+		node = node.With("names", strings.Join(e.names, ","))
+	}
+	if len(e.selector) > 0 {
+		node = node.With("selector", e.selector.String())
+	}
+	if e.filter != nil {
+		node = node.With("filter", e.filter.String())
+	}
+	return node.Append(NewPipelineNode("encoder", e.encoder),
+		NewPipelineNode("syncer", e.syncer))
+}
+
+// ExplainPipeline returns the description of the asynchronous exporter and
+// the wrapped exporter.
+func (e *AsyncExporter) ExplainPipeline() PipelineNode {
+	return PipelineNode { }.Append(NewPipelineNode("exporter", &e.exporter))
+}
+
+// ExplainPipeline returns the description of the queue and its
+// synchronizer.
+func (q *asyncQueue) ExplainPipeline() PipelineNode {
+	node := PipelineNode { Type: "asynchronous queue" }.
+		With("capacity", strconv.FormatUint(q.capacity, 10)).
+		With("batch_size", strconv.Itoa(q.batchSize))
+	if q.blocking {
+		node = node.With("blocking", "true")
+	}
+	if q.dropStale {
+		node = node.With("drop_stale", "true")
+	}
+	return node.Append(NewPipelineNode("syncer", q.syncer))
+}
+
+// ExplainPipeline returns the description of the delivery exporter and
+// the wrapped exporter.
+func (e *DeliveryExporter) ExplainPipeline() PipelineNode {
+	return PipelineNode { }.With("directory", e.directory).
+		Append(NewPipelineNode("exporter", &e.exporter))
+}
+
+// ExplainPipeline returns the description of the journal and the wrapped
+// synchronizer.
+func (s *deliverySyncer) ExplainPipeline() PipelineNode {
+	return PipelineNode { Type: "delivery journal" }.
+		Append(NewPipelineNode("syncer", s.syncer))
+}
+
+// ExplainPipeline returns the description of the ordering exporter and
+// the wrapped exporter.
+func (e *OrderingExporter) ExplainPipeline() PipelineNode {
+	return PipelineNode { }.
+		With("window", time.Duration(e.window).String()).
+		With("capacity", strconv.Itoa(e.capacity)).
+		Append(NewPipelineNode("exporter", &e.exporter))
+}
+
+// ExplainPipeline returns the description of the ordering buffer and the
+// wrapped synchronizer.
+func (s *orderingSyncer) ExplainPipeline() PipelineNode {
+	return PipelineNode { Type: "ordering buffer" }.
+		Append(NewPipelineNode("syncer", s.syncer))
+}
+
+// ExplainPipeline returns the description of the sharding exporter and
+// its shards.
+func (e *ShardingExporter) ExplainPipeline() PipelineNode {
+	var node PipelineNode
+	if len(e.label) > 0 {
+		node = node.With("label", e.label)
+	}
+	if len(e.field) > 0 {
+		node = node.With("field", e.field)
+	}
+	for index, exporter := range e.exporters {
+		child := NewPipelineNode("exporter", exporter).
+			With("shard", strconv.Itoa(index))
+		if index == e.fallback {
+			child = child.With("default", "true")
+		}
+		node = node.Append(child)
+	}
+	return node
+}
+
+// ExplainPipeline returns the description of the file synchronizer.
+func (s *FileSyncer) ExplainPipeline() PipelineNode {
+	node := PipelineNode { }.With("file", s.rotator.name)
+	if s.rotator.maxSize > 0 {
+		node = node.With("max_size", strconv.FormatInt(s.rotator.maxSize,
+			10))
+	}
+	if s.rotator.maxBackups > 0 {
+		node = node.With("max_backups", strconv.Itoa(s.rotator.maxBackups))
+	}
+	if s.rotator.maxAge > 0 {
+		node = node.With("max_age", s.rotator.maxAge.String())
+	}
+	return node
+}
+
+// ExplainPipeline returns the description of the fallback synchronizer and
+// its primary and fallback synchronizers.
+func (s *FallbackSyncer) ExplainPipeline() PipelineNode {
+	return PipelineNode { }.
+		With("threshold", strconv.Itoa(int(s.threshold))).
+		Append(NewPipelineNode("syncer", s.primary).With("role", "primary"),
+			NewPipelineNode("syncer", s.fallback).With("role", "fallback"))
+}
+
+// ExplainPipeline returns the description of the lifecycle synchronizer
+// and the wrapped synchronizer.
+func (s *LifecycleSyncer) ExplainPipeline() PipelineNode {
+	return PipelineNode { }.Append(NewPipelineNode("syncer", s.syncer))
+}
+
+const (
+	// PipelineAccepted represents that a stage of the pipeline accepted a
+	// log entry and passed it on.
+	PipelineAccepted = "accepted"
+
+	// PipelineDropped represents that the sampler dropped a log entry, or
+	// retained it to be released later.
+	PipelineDropped = "dropped"
+
+	// PipelineReleased represents that a log entry previously retained by
+	// the sampler was released to be output.
+	PipelineReleased = "released"
+
+	// PipelineFiltered represents that a log entry did not match the
+	// conditions of an exporter, so the exporter ignored it.
+	PipelineFiltered = "filtered"
+
+	// PipelineFailed represents that a stage of the pipeline returned an
+	// error for a log entry.
+	PipelineFailed = "failed"
+)
+
+// PipelineDecision is a structure that contains the decision of a stage of
+// the pipeline of a logger about a log entry.
+type PipelineDecision struct {
+	// Stage represents the role of the stage in the pipeline, which is
+	// "sampler", "hook" or "exporter".
+	Stage string
+
+	// Index represents the index of the stage among the hooks or the
+	// exporters of the logger.
+	Index int
+
+	// Type represents the type of the stage, such as
+	// "*santa.StandardExporter".
+	Type string
+
+	// Outcome represents the decision of the stage, which is one of the
+	// constants beginning with Pipeline...
+	Outcome string
+
+	// Err represents the error returned by the stage, or nil if the stage
+	// did not fail.
+	Err error
+}
+
+// PipelineTrace is a structure that contains the decisions of the stages
+// of the pipeline of a logger about a log entry, in the order in which
+// they were made.
+type PipelineTrace struct {
+	// Name represents the name of the logger of the log entry.
+	Name string
+
+	// Level represents the level of the log entry.
+	Level Level
+
+	// Time represents the time of the log entry.
+	Time time.Time
+
+	// Text represents the text sample of the message of the log entry, or
+	// an empty string if the message does not implement the
+	// TextSampleParser interface.
+	Text string
+
+	// Decisions represents the decisions of the stages of the pipeline.
+	Decisions []PipelineDecision
+}
+
+// decide appends a decision of the given stage with the given role, index,
+// outcome and error to the trace. It does nothing if the trace is nil.
+func (t *PipelineTrace) decide(kind string, index int, stage interface { },
+	outcome string, err error) {
+	if t == nil {
+		return
+	}
+	t.Decisions = append(t.Decisions, PipelineDecision {
+		Stage: kind,
+		Index: index,
+		Type: fmt.Sprintf("%T", stage),
+		Outcome: outcome,
+		Err: err,
+	})
+}
+
+// String returns the trace as a single line of text, such as
+// "info app: sampler[0] accepted, exporter[0] filtered".
+func (t PipelineTrace) String() string {
+	buffer := append([]byte(nil), t.Level.String()...)
+	if len(t.Name) > 0 {
+		buffer = append(buffer, ' ')
+		buffer = append(buffer, t.Name...)
+	}
+	buffer = append(buffer, ':')
+	for index, decision := range t.Decisions {
+		if index > 0 {
+			buffer = append(buffer, ',')
+		}
+		buffer = append(buffer, ' ')
+		buffer = append(buffer, decision.Stage...)
+		buffer = append(buffer, '[')
+		buffer = strconv.AppendInt(buffer, int64(decision.Index), 10)
+		buffer = append(buffer, "] "...)
+		buffer = append(buffer, decision.Outcome...)
+		if decision.Err != nil {
+			buffer = append(buffer, " ("...)
+			buffer = append(buffer, decision.Err.Error()...)
+			buffer = append(buffer, ')')
+		}
+	}
+	return string(buffer)
+}
+
+// pipelineTracer is a structure that contains the callback that receives
+// the traces of a logger.
+type pipelineTracer struct {
+	callback func(trace PipelineTrace)
+}
+
+// start returns a new trace of the given log entry, or nil if the tracer
+// is nil.
+func (t *pipelineTracer) start(entry *Entry) *PipelineTrace {
+	if t == nil {
+		return nil
+	}
+	trace := &PipelineTrace {
+		Name: entry.Name,
+		Level: entry.Level,
+		Time: entry.Time,
+	}
+	if parser, ok := entry.Message.(TextSampleParser); ok {
+		trace.Text = parser.SampleText()
+	}
+	return trace
+}
+
+// report calls the callback with the given trace. It does nothing if the
+// tracer or the trace is nil.
+func (t *pipelineTracer) report(trace *PipelineTrace) {
+	if t == nil || trace == nil {
+		return
+	}
+	t.callback(*trace)
+}
+
+// traceEntry passes the given log entry to each hook and then to each
+// exporter of the given configuration like the exportEntry function,
+// records the decision of each of them to the given trace, and then
+// reports the trace.
+func traceEntry(config *loggerConfig, entry *Entry,
+	trace *PipelineTrace) error {
+	defer config.tracer.report(trace)
+	for index := 0; index < len(config.hooks); index++ {
+		hook := config.hooks[index]
+		if err := hook.Print(entry); err != nil {
+			trace.decide("hook", index, hook, PipelineFailed, err)
+			return err
+		}
+		trace.decide("hook", index, hook, PipelineAccepted, nil)
+	}
+	for index := 0; index < len(config.exporters); index++ {
+		exporter := config.exporters[index]
+		if matcher, ok := exporter.(MatchingExporter); ok &&
+			!matcher.Match(entry) {
+			trace.decide("exporter", index, exporter, PipelineFiltered, nil)
+			continue
+		}
+		if err := exporter.Export(entry); err != nil {
+			trace.decide("exporter", index, exporter, PipelineFailed, err)
+			return err
+		}
+		trace.decide("exporter", index, exporter, PipelineAccepted, nil)
+	}
+	config.counters.emit()
+	return nil
+}
+
+// TracePipeline starts recording the decisions of the sampler, the hooks
+// and the exporters of the logger about each log entry output afterwards,
+// and calls the given tracer with the trace of each log entry once it has
+// passed through the pipeline. It returns a function that stops the
+// recording. It is intended to be enabled temporarily at runtime (for
+// example: by an administrative endpoint) to diagnose why specific log
+// entries are missing, and it slows down the output of log entries while
+// it is enabled.
+//
+// The tracer is called synchronously by the goroutine that outputs the log
+// entry, so it must return quickly and must not output log entries using
+// the logger. Exporters that do not implement the MatchingExporter
+// interface are reported as accepting every log entry that they do not
+// fail. Log entries dropped after being accepted by an asynchronous
+// exporter are reported by the drop observer instead. For details, please
+// refer to the comment section of the DropObserver structure.
+//
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries. Other copies of the logger are not affected.
+func (l *StandardLogger) TracePipeline(tracer func(trace PipelineTrace)) func() {
+	current := &pipelineTracer { callback: tracer }
+	l.update(func(config *loggerConfig) {
+		config.tracer = current
+	})
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.update(func(config *loggerConfig) {
+				// The tracer may have been replaced by a later call,
+				// which must not be stopped.
+				if config.tracer == current {
+					config.tracer = nil
+				}
+			})
+		})
+	}
+}
@@ -0,0 +1,156 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoggerExplainPipeline(t *testing.T) {
+	option := NewStandardOption()
+	option.Outputting.UseStandard(&bytes.Buffer { })
+	option.ErrorOutputting.UseDiscard()
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected build error")
+	defer logger.Close()
+
+	node := logger.ExplainPipeline()
+	assert.Equal(t, "logger", node.Kind, "Unexpected root kind")
+	level, ok := node.Attribute("level")
+	assert.True(t, ok, "Unexpected missing level attribute")
+	assert.Equal(t, logger.Level().String(), level, "Unexpected level")
+	assert.Len(t, node.Children, 3, "Unexpected number of stages")
+	assert.Equal(t, "sampler", node.Children[0].Kind, "Unexpected sampler kind")
+
+	exporter := node.Children[1]
+	assert.Equal(t, "exporter", exporter.Kind, "Unexpected exporter kind")
+	assert.Equal(t, "*santa.StandardExporter", exporter.Type,
+		"Unexpected exporter type")
+	_, ok = exporter.Attribute("levels")
+	assert.True(t, ok, "Unexpected missing levels attribute")
+	assert.Len(t, exporter.Children, 2, "Unexpected number of children")
+	assert.Equal(t, "encoder", exporter.Children[0].Kind,
+		"Unexpected encoder kind")
+	assert.Equal(t, "syncer", exporter.Children[1].Kind,
+		"Unexpected syncer kind")
+
+	tree := node.String()
+	assert.True(t, strings.HasPrefix(tree, "logger "), "Unexpected tree: %s",
+		tree)
+	assert.Contains(t, tree, "\n    syncer ", "Unexpected tree: %s", tree)
+
+	graph := node.DOT()
+	assert.True(t, strings.HasPrefix(graph, "digraph pipeline {\n"),
+		"Unexpected graph: %s", graph)
+	assert.Contains(t, graph, "n0 -> n1;", "Unexpected graph: %s", graph)
+	assert.Equal(t, 2 + 2 * 3, strings.Count(graph, "[label="),
+		"Unexpected number of graph nodes")
+}
+
+type pipelineTestStage struct { }
+
+func TestNewPipelineNode(t *testing.T) {
+	node := NewPipelineNode("hook", &pipelineTestStage { })
+	assert.Equal(t, "hook", node.Kind, "Unexpected kind")
+	assert.Equal(t, "*santa.pipelineTestStage", node.Type, "Unexpected type")
+	assert.Empty(t, node.Children, "Unexpected children")
+
+	node = NewPipelineNode("syncer", &LifecycleSyncer {
+		syncer: &FallbackSyncer {
+			primary: &pipelineTestSyncer { },
+			fallback: &pipelineTestSyncer { },
+			threshold: 3,
+		},
+	})
+	assert.Equal(t, "*santa.LifecycleSyncer", node.Type, "Unexpected type")
+	assert.Len(t, node.Children, 1, "Unexpected number of children")
+	threshold, _ := node.Children[0].Attribute("threshold")
+	assert.Equal(t, "3", threshold, "Unexpected threshold")
+	assert.Len(t, node.Children[0].Children, 2,
+		"Unexpected number of children")
+	role, _ := node.Children[0].Children[1].Attribute("role")
+	assert.Equal(t, "fallback", role, "Unexpected role")
+}
+
+type pipelineTestSyncer struct { }
+
+func (s *pipelineTestSyncer) Write(buffer []byte) (int, error) {
+	return len(buffer), nil
+}
+
+func (s *pipelineTestSyncer) Sync() error {
+	return nil
+}
+
+func (s *pipelineTestSyncer) Close() error {
+	return nil
+}
+
+func TestStandardLoggerTracePipeline(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableSampling().DisableFlushing()
+	option.Outputting.UseStandard(writer)
+	option.ErrorOutputting.UseDiscard()
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected build error")
+	defer logger.Close()
+
+	var traces []PipelineTrace
+	stop := logger.TracePipeline(func(trace PipelineTrace) {
+		traces = append(traces, trace)
+	})
+	logger.SetSampler(testDropSampler { })
+	assert.NoError(t, logger.Info(StringMessage("keep")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Info(StringMessage("drop")),
+		"Unexpected print error")
+	stop()
+	stop()
+	assert.NoError(t, logger.Info(StringMessage("keep")),
+		"Unexpected print error")
+
+	assert.Len(t, traces, 2, "Unexpected number of traces")
+	assert.Equal(t, "keep", traces[0].Text, "Unexpected trace text")
+	assert.Equal(t, []string { "sampler", "exporter", "exporter" },
+		[]string {
+			traces[0].Decisions[0].Stage,
+			traces[0].Decisions[1].Stage,
+			traces[0].Decisions[2].Stage,
+		}, "Unexpected trace stages")
+	assert.Equal(t, PipelineAccepted, traces[0].Decisions[1].Outcome,
+		"Unexpected exporter outcome")
+	assert.Equal(t, PipelineFiltered, traces[0].Decisions[2].Outcome,
+		"Unexpected exporter outcome")
+	assert.Equal(t, "info: sampler[0] accepted, exporter[0] accepted, " +
+		"exporter[1] filtered", traces[0].String(), "Unexpected trace string")
+
+	assert.Len(t, traces[1].Decisions, 1, "Unexpected number of decisions")
+	assert.Equal(t, PipelineDropped, traces[1].Decisions[0].Outcome,
+		"Unexpected sampler outcome")
+	assert.Equal(t, "santa.testDropSampler", traces[1].Decisions[0].Type,
+		"Unexpected sampler type")
+}
@@ -22,7 +22,179 @@
 
 package santa
 
-import "sync"
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// PoolOption is a structure that contains the retention options of a
+// pool, so that applications with strict memory ceilings can bound what
+// the logger retains between bursts of log entries.
+type PoolOption struct {
+	// Disabled represents whether the pool is disabled. A disabled pool
+	// allocates a new object for each use and never retains objects. If
+	// not provided, the default value is false.
+	Disabled bool
+
+	// MaxObjects represents the maximum number of objects retained by the
+	// pool. If it is greater than 0, the objects are retained by a bounded
+	// free list instead of a sync.Pool, so they are never released by the
+	// garbage collector, but never exceed the maximum. If not provided,
+	// the default value is 0, which means the objects are retained by a
+	// sync.Pool and released by the garbage collector.
+	MaxObjects int
+
+	// MaxBufferSize represents the maximum capacity of a buffer retained
+	// by a buffer pool (for example: the exporter buffer pool), so that a
+	// buffer grown by a single huge log entry is released instead of being
+	// retained. It is ignored by the other pools. If not provided, the
+	// default value is 0, which means unlimited.
+	MaxBufferSize int
+}
+
+// PoolStats is a structure that contains the statistics of a pool. Only
+// the slow paths of the pool are counted, so that taking and returning
+// retained objects never contend on shared counters.
+type PoolStats struct {
+	// Allocations represents the number of objects allocated because the
+	// pool did not retain any object.
+	Allocations uint64
+
+	// Discarded represents the number of objects returned to the pool but
+	// not retained, because the pool is disabled, the bounded free list is
+	// full or the buffer is too large.
+	Discarded uint64
+
+	// Retained represents the number of objects retained by the bounded
+	// free list. If the objects are retained by a sync.Pool, the number
+	// cannot be known and it is -1.
+	Retained int
+}
+
+// objectPool is a structure that contains the retention logic shared by
+// all pools. The objects are retained by a sync.Pool, or by a bounded free
+// list if the MaxObjects option is greater than 0.
+type objectPool[T any] struct {
+	create func() T
+	reset func(object T)
+	size func(object T) int
+
+	option atomic.Pointer[PoolOption]
+	pool atomic.Pointer[sync.Pool]
+	mutex sync.Mutex
+	free []T
+
+	allocations uint64
+	discarded uint64
+}
+
+// init initializes the pool with the given function that allocates a new
+// object, the given function that clears an object before it is retained,
+// and the given function that returns the capacity of a buffer. The reset
+// and size functions can be nil.
+func (p *objectPool[T]) init(create func() T, reset func(object T),
+	size func(object T) int) {
+	p.create = create
+	p.reset = reset
+	p.size = size
+	p.option.Store(&PoolOption { })
+	p.pool.Store(&sync.Pool { })
+}
+
+// get takes an object from the pool, or allocates a new object if the pool
+// does not retain any object.
+func (p *objectPool[T]) get() T {
+	option := p.option.Load()
+	if option.Disabled {
+		atomic.AddUint64(&p.allocations, 1)
+		return p.create()
+	}
+	if option.MaxObjects > 0 {
+		p.mutex.Lock()
+		if length := len(p.free); length > 0 {
+			object := p.free[length - 1]
+			var zero T
+			p.free[length - 1] = zero
+			p.free = p.free[ : length - 1]
+			p.mutex.Unlock()
+			return object
+		}
+		p.mutex.Unlock()
+	} else if object := p.pool.Load().Get(); object != nil {
+		return object.(T)
+	}
+	atomic.AddUint64(&p.allocations, 1)
+	return p.create()
+}
+
+// put returns the given object to the pool, which retains it unless the
+// retention options do not allow it.
+func (p *objectPool[T]) put(object T) {
+	if p.reset != nil {
+		p.reset(object)
+	}
+	option := p.option.Load()
+	if option.Disabled || (p.size != nil && option.MaxBufferSize > 0 &&
+		p.size(object) > option.MaxBufferSize) {
+		atomic.AddUint64(&p.discarded, 1)
+		return
+	}
+	if option.MaxObjects <= 0 {
+		p.pool.Load().Put(object)
+		return
+	}
+	p.mutex.Lock()
+	if len(p.free) < option.MaxObjects {
+		p.free = append(p.free, object)
+		p.mutex.Unlock()
+		return
+	}
+	p.mutex.Unlock()
+	atomic.AddUint64(&p.discarded, 1)
+}
+
+// Configure replaces the retention options of the pool with the given
+// options, and then releases all retained objects, so that the new options
+// apply to all objects retained from now on.
+func (p *objectPool[T]) Configure(option PoolOption) {
+	p.option.Store(&option)
+	p.Clear()
+}
+
+// Option returns the retention options of the pool.
+func (p *objectPool[T]) Option() PoolOption {
+	return *p.option.Load()
+}
+
+// Clear releases all objects retained by the pool, including the objects
+// kept by the victim cache of the sync.Pool, so that they can be collected
+// by the next garbage collection. It is usually called after a burst of
+// log entries, or when the application is under memory pressure.
+func (p *objectPool[T]) Clear() {
+	p.pool.Store(&sync.Pool { })
+	p.mutex.Lock()
+	var zero T
+	for index := range p.free {
+		p.free[index] = zero
+	}
+	p.free = nil
+	p.mutex.Unlock()
+}
+
+// Stats returns the statistics of the pool.
+func (p *objectPool[T]) Stats() PoolStats {
+	stats := PoolStats {
+		Allocations: atomic.LoadUint64(&p.allocations),
+		Discarded: atomic.LoadUint64(&p.discarded),
+		Retained: -1,
+	}
+	if p.option.Load().MaxObjects > 0 {
+		p.mutex.Lock()
+		stats.Retained = len(p.free)
+		p.mutex.Unlock()
+	}
+	return stats
+}
 
 // StructMessagePool is a structure that contains instances of
 // cached structured messages.
@@ -32,15 +204,17 @@ import "sync"
 // hyper-threading contexts, which will significantly reduce the number
 // of heap memory allocations.
 type StructMessagePool struct {
-	pool *sync.Pool
+	objectPool[*StructMessage]
 }
 
 // New gets and returns a reusable message instance from the buffer pool.
 // If not, then allocate and return a new message instance.
 func (p *StructMessagePool) New(text string, fields []Field) *StructMessage {
-	message := p.pool.Get().(*StructMessage)
+	message := p.get()
 	message.Text = text
 	message.Fields = fields
+	message.prefix = nil
+	message.owner = nil
 	return message
 }
 
@@ -48,19 +222,57 @@ func (p *StructMessagePool) New(text string, fields []Field) *StructMessage {
 // refund, the message instance is not allowed to be used again, otherwise
 // the behavior is undefined.
 func (p *StructMessagePool) Free(message *StructMessage) {
-	p.pool.Put(message)
+	p.put(message)
 }
 
 // NewStructMessagePool creates and returns a structured message buffer
 // pool instance.
 func NewStructMessagePool() *StructMessagePool {
-	return &StructMessagePool {
-		pool: &sync.Pool {
-			New: func() interface { } {
-				return &StructMessage { }
-			},
-		},
-	}
+	instance := &StructMessagePool { }
+	instance.init(func() *StructMessage {
+		return &StructMessage { }
+	}, nil, nil)
+	return instance
+}
+
+// StringMessagePool is a structure that contains instances of cached
+// string message wrappers.
+//
+// A StringMessage stored in a Message interface is allocated on the heap
+// for each log entry, so the string message pool allows the wrappers to be
+// cached in the pool after use and reused in multiple hyper-threading
+// contexts, which will significantly reduce the number of heap memory
+// allocations.
+type StringMessagePool struct {
+	objectPool[*StringMessage]
+}
+
+// New gets and returns a reusable message instance from the buffer pool.
+// If not, then allocate and return a new message instance.
+func (p *StringMessagePool) New(text string) *StringMessage {
+	message := p.get()
+	*message = StringMessage(text)
+	return message
+}
+
+// Free returns the given message instance to the buffer pool. After the
+// refund, the message instance is not allowed to be used again, otherwise
+// the behavior is undefined.
+func (p *StringMessagePool) Free(message *StringMessage) {
+	p.put(message)
+}
+
+// NewStringMessagePool creates and returns a string message buffer pool
+// instance.
+func NewStringMessagePool() *StringMessagePool {
+	instance := &StringMessagePool { }
+	instance.init(func() *StringMessage {
+		return new(StringMessage)
+	}, func(message *StringMessage) {
+		// The text is cleared so that the pool does not keep it alive.
+		*message = ""
+	}, nil)
+	return instance
 }
 
 // TemplateMessagePool is a structure that contains instances of
@@ -71,13 +283,13 @@ func NewStructMessagePool() *StructMessagePool {
 // hyper-threading contexts, which will significantly reduce the number
 // of heap memory allocations.
 type TemplateMessagePool struct {
-	pool *sync.Pool
+	objectPool[*TemplateMessage]
 }
 
 // New gets and returns a reusable message instance from the buffer pool.
 // If not, then allocate and return a new message instance.
 func (p *TemplateMessagePool) New(template string, args []interface { }) *TemplateMessage {
-	message := p.pool.Get().(*TemplateMessage)
+	message := p.get()
 	message.Template = template
 	message.Args = args
 	return message
@@ -87,19 +299,54 @@ func (p *TemplateMessagePool) New(template string, args []interface { }) *Templa
 // refund, the message instance is not allowed to be used again, otherwise
 // the behavior is undefined.
 func (p *TemplateMessagePool) Free(message *TemplateMessage) {
-	p.pool.Put(message)
+	p.put(message)
 }
 
 // NewTemplateMessagePool creates and returns a template message buffer
 // pool instance.
 func NewTemplateMessagePool() *TemplateMessagePool {
-	return &TemplateMessagePool {
-		pool: &sync.Pool {
-			New: func() interface { } {
-				return &TemplateMessage { }
-			},
-		},
-	}
+	instance := &TemplateMessagePool { }
+	instance.init(func() *TemplateMessage {
+		return &TemplateMessage { }
+	}, nil, nil)
+	return instance
+}
+
+// NamedTemplateMessagePool is a structure that contains instances of
+// cached named template messages.
+//
+// The named template message pool allows the allocated named template
+// message instance to be cached in the pool after use and reused in
+// multiple hyper-threading contexts, which will significantly reduce the
+// number of heap memory allocations.
+type NamedTemplateMessagePool struct {
+	objectPool[*NamedTemplateMessage]
+}
+
+// New gets and returns a reusable message instance from the buffer pool.
+// If not, then allocate and return a new message instance.
+func (p *NamedTemplateMessagePool) New(template string, args []interface { }) *NamedTemplateMessage {
+	message := p.get()
+	message.Template = template
+	message.Args = args
+	return message
+}
+
+// Free returns the given message instance to the buffer pool. After the
+// refund, the message instance is not allowed to be used again, otherwise
+// the behavior is undefined.
+func (p *NamedTemplateMessagePool) Free(message *NamedTemplateMessage) {
+	p.put(message)
+}
+
+// NewNamedTemplateMessagePool creates and returns a named template message
+// buffer pool instance.
+func NewNamedTemplateMessagePool() *NamedTemplateMessagePool {
+	instance := &NamedTemplateMessagePool { }
+	instance.init(func() *NamedTemplateMessage {
+		return &NamedTemplateMessage { }
+	}, nil, nil)
+	return instance
 }
 
 // EntryPool is a structure that contains instances of cached log entries.
@@ -110,34 +357,35 @@ func NewTemplateMessagePool() *TemplateMessagePool {
 //
 // Note that any instance of log entry should use this pool allocation.
 type EntryPool struct {
-	pool *sync.Pool
+	objectPool[*Entry]
 }
 
 // New gets and returns a reusable log entry instance from the buffer pool.
 // If not, then allocate and return a new log entry instance.
 //
 // Please note that the log entry instance obtained and returned may be dirty,
-// and the pool is not responsible for cleaning it.
+// and the pool is not responsible for cleaning it, except that the objects
+// owned by the log entry (for example: the labels buffer used by the
+// AddLabel function) are returned to their pools when it is freed.
 func (p *EntryPool) New() *Entry {
-	return p.pool.Get().(*Entry)
+	return p.get()
 }
 
 // Free returns the given log entry instance to the buffer pool. After the
 // refund, the log entry instance is not allowed to be used again, otherwise
 // the behavior is undefined.
 func (p *EntryPool) Free(entry *Entry) {
-	p.pool.Put(entry)
+	entry.release()
+	p.put(entry)
 }
 
 // NewEntryPool creates and returns a log entry buffer pool instance.
 func NewEntryPool() *EntryPool {
-	return &EntryPool {
-		pool: &sync.Pool {
-			New: func() interface { } {
-				return &Entry { }
-			},
-		},
-	}
+	instance := &EntryPool { }
+	instance.init(func() *Entry {
+		return &Entry { }
+	}, nil, nil)
+	return instance
 }
 
 // ExporterBufferPool is a structure that contains instances of cached
@@ -151,7 +399,7 @@ func NewEntryPool() *EntryPool {
 // Note that any instance of exporter buffer should use this pool
 // allocation.
 type ExporterBufferPool struct {
-	pool *sync.Pool
+	objectPool[*[]byte]
 }
 
 // New gets and returns a reusable exporter buffer instance from the
@@ -161,27 +409,195 @@ type ExporterBufferPool struct {
 // Please note that the exporter buffer instance obtained and returned
 // may be dirty, and the pool is not responsible for cleaning it.
 func (p *ExporterBufferPool) New() *[]byte {
-	return p.pool.Get().(*[]byte)
+	return p.get()
 }
 
 // Free returns the given exporter buffer instance to the buffer pool.
 // After the refund, the exporter buffer instance is not allowed to be
 // used again, otherwise the behavior is undefined.
 func (p *ExporterBufferPool) Free(buffer *[]byte) {
-	p.pool.Put(buffer)
+	p.put(buffer)
 }
 
 // NewExporterBufferPool creates and returns a log entry buffer pool
 // instance.
 func NewExporterBufferPool(capacity int) *ExporterBufferPool {
-	return &ExporterBufferPool {
-		pool: &sync.Pool {
-			New: func() interface { } {
-				buffer := make([]byte, 0, capacity)
-				return &buffer
-			},
-		},
-	}
+	instance := &ExporterBufferPool { }
+	instance.init(func() *[]byte {
+		buffer := make([]byte, 0, capacity)
+		return &buffer
+	}, nil, func(buffer *[]byte) int {
+		return cap(*buffer)
+	})
+	return instance
+}
+
+// FieldBufferPool is a structure that contains instances of cached field
+// buffers.
+//
+// The field buffer pool allows the allocated and used field buffer
+// instances to be cached in the pool for use by other hyper-threading
+// contexts, which will significantly reduce the number of heap memory
+// allocations.
+type FieldBufferPool struct {
+	objectPool[*[]Field]
+}
+
+// New gets and returns a reusable field buffer instance from the buffer
+// pool. If not, then allocate and return a new field buffer instance.
+//
+// Please note that the field buffer instance obtained and returned may be
+// dirty, and the pool is not responsible for cleaning it.
+func (p *FieldBufferPool) New() *[]Field {
+	return p.get()
+}
+
+// Free clears the given field buffer instance and returns it to the buffer
+// pool. After the refund, the field buffer instance is not allowed to be
+// used again, otherwise the behavior is undefined.
+func (p *FieldBufferPool) Free(buffer *[]Field) {
+	p.put(buffer)
+}
+
+// NewFieldBufferPool creates and returns a field buffer pool instance.
+func NewFieldBufferPool(capacity int) *FieldBufferPool {
+	instance := &FieldBufferPool { }
+	instance.init(func() *[]Field {
+		buffer := make([]Field, 0, capacity)
+		return &buffer
+	}, func(buffer *[]Field) {
+		// The fields are cleared so that the pool does not keep the values
+		// of the fields alive.
+		fields := *buffer
+		for index := 0; index < len(fields); index++ {
+			fields[index] = Field { }
+		}
+		*buffer = fields[ : 0]
+	}, func(buffer *[]Field) int {
+		return cap(*buffer)
+	})
+	return instance
+}
+
+// LabelsBufferPool is a structure that contains instances of cached labels
+// buffers.
+//
+// The labels buffer pool allows the buffers of the labels derived for a
+// single log entry (for example: by the AddLabel function of the Entry
+// structure) to be cached in the pool after use and reused by other
+// hyper-threading contexts, which avoids allocating the labels and their
+// serialized data for each log entry.
+type LabelsBufferPool struct {
+	objectPool[*LabelsBuffer]
+}
+
+// New gets and returns a reusable labels buffer instance from the pool. If
+// not, then allocate and return a new labels buffer instance.
+func (p *LabelsBufferPool) New() *LabelsBuffer {
+	return p.get()
+}
+
+// Free clears the given labels buffer instance and returns it to the pool.
+// After the refund, the labels buffer instance and the serialized labels
+// built in it are not allowed to be used again, otherwise the behavior is
+// undefined.
+func (p *LabelsBufferPool) Free(buffer *LabelsBuffer) {
+	p.put(buffer)
+}
+
+// NewLabelsBufferPool creates and returns a labels buffer pool instance.
+func NewLabelsBufferPool() *LabelsBufferPool {
+	instance := &LabelsBufferPool { }
+	instance.init(func() *LabelsBuffer {
+		return &LabelsBuffer { }
+	}, func(buffer *LabelsBuffer) {
+		buffer.Reset()
+	}, func(buffer *LabelsBuffer) int {
+		return cap(buffer.jsonBuffer)
+	})
+	return instance
+}
+
+// StructLoggerPool is a structure that contains instances of cached
+// derived structured loggers.
+//
+// The structured logger pool allows the loggers derived by the With and
+// WithContext functions to be cached in the pool after they are released
+// and reused by other hyper-threading contexts, which avoids allocating a
+// logger for each request.
+type StructLoggerPool struct {
+	objectPool[*StructLogger]
+}
+
+// New gets and returns a reusable structured logger instance from the
+// pool. If not, then allocate and return a new structured logger instance.
+//
+// Please note that the logger instance obtained and returned may be dirty,
+// and the pool is not responsible for cleaning it.
+func (p *StructLoggerPool) New() *StructLogger {
+	return p.get()
+}
+
+// Free returns the given structured logger instance to the pool. After
+// the refund, the logger instance is not allowed to be used again,
+// otherwise the behavior is undefined.
+func (p *StructLoggerPool) Free(logger *StructLogger) {
+	p.put(logger)
+}
+
+// NewStructLoggerPool creates and returns a structured logger pool
+// instance.
+func NewStructLoggerPool() *StructLoggerPool {
+	instance := &StructLoggerPool { }
+	instance.init(func() *StructLogger {
+		return &StructLogger { }
+	}, nil, nil)
+	return instance
+}
+
+// EventPool is a structure that contains instances of cached structured
+// events.
+//
+// The event pool allows the events created by the Event function of the
+// structured logger to be cached in the pool after they are output and
+// reused by other hyper-threading contexts, which avoids allocating an
+// event and its fields for each log entry.
+type EventPool struct {
+	objectPool[*Event]
+}
+
+// New gets and returns a reusable event instance from the pool. If not,
+// then allocate and return a new event instance.
+func (p *EventPool) New() *Event {
+	return p.get()
+}
+
+// Free clears the given event instance and returns it to the pool. After
+// the refund, the event instance is not allowed to be used again,
+// otherwise the behavior is undefined.
+func (p *EventPool) Free(event *Event) {
+	p.put(event)
+}
+
+// NewEventPool creates and returns an event pool instance.
+func NewEventPool() *EventPool {
+	instance := &EventPool { }
+	instance.init(func() *Event {
+		return &Event {
+			fields: make([]Field, 0, 16),
+		}
+	}, func(event *Event) {
+		// The fields are cleared so that the pool does not keep the values
+		// of the fields alive.
+		for index := 0; index < len(event.fields); index++ {
+			event.fields[index] = Field { }
+		}
+		event.fields = event.fields[ : 0]
+		event.logger = nil
+	}, func(event *Event) int {
+		return cap(event.fields)
+	})
+	return instance
 }
 
 // GlobalPool is a structure that contains default instances of various
@@ -191,12 +607,20 @@ func NewExporterBufferPool(capacity int) *ExporterBufferPool {
 // allocations.
 type GlobalPool struct {
 	Entry *EntryPool
+	Event *EventPool
 	Message struct {
 		Structure *StructMessagePool
 		Template *TemplateMessagePool
+		NamedTemplate *NamedTemplateMessagePool
+		String *StringMessagePool
 	}
 	Buffer struct {
 		Exporter *ExporterBufferPool
+		Field *FieldBufferPool
+		Labels *LabelsBufferPool
+	}
+	Logger struct {
+		Structure *StructLoggerPool
 	}
 }
 
@@ -206,10 +630,16 @@ type GlobalPool struct {
 func NewGlobalPool() GlobalPool {
 	instance := GlobalPool {
 		Entry: NewEntryPool(),
+		Event: NewEventPool(),
 	}
 	instance.Message.Template = NewTemplateMessagePool()
+	instance.Message.NamedTemplate = NewNamedTemplateMessagePool()
 	instance.Message.Structure = NewStructMessagePool()
+	instance.Message.String = NewStringMessagePool()
 	instance.Buffer.Exporter = NewExporterBufferPool(2048)
+	instance.Buffer.Field = NewFieldBufferPool(16)
+	instance.Buffer.Labels = NewLabelsBufferPool()
+	instance.Logger.Structure = NewStructLoggerPool()
 	return instance
 }
 
@@ -222,3 +652,73 @@ var pool GlobalPool = NewGlobalPool()
 func GetGlobalPool() GlobalPool {
 	return pool
 }
+
+// GlobalPoolStats is a structure that contains the statistics of each
+// pool of a global pool.
+type GlobalPoolStats struct {
+	Entry PoolStats
+	Event PoolStats
+	Message struct {
+		Structure PoolStats
+		Template PoolStats
+		NamedTemplate PoolStats
+		String PoolStats
+	}
+	Buffer struct {
+		Exporter PoolStats
+		Field PoolStats
+		Labels PoolStats
+	}
+	Logger struct {
+		Structure PoolStats
+	}
+}
+
+// Stats returns the statistics of each pool of the global pool.
+func (p GlobalPool) Stats() GlobalPoolStats {
+	var stats GlobalPoolStats
+	stats.Entry = p.Entry.Stats()
+	stats.Event = p.Event.Stats()
+	stats.Message.Structure = p.Message.Structure.Stats()
+	stats.Message.Template = p.Message.Template.Stats()
+	stats.Message.NamedTemplate = p.Message.NamedTemplate.Stats()
+	stats.Message.String = p.Message.String.Stats()
+	stats.Buffer.Exporter = p.Buffer.Exporter.Stats()
+	stats.Buffer.Field = p.Buffer.Field.Stats()
+	stats.Buffer.Labels = p.Buffer.Labels.Stats()
+	stats.Logger.Structure = p.Logger.Structure.Stats()
+	return stats
+}
+
+// Configure replaces the retention options of each pool of the global
+// pool with the given options. For details, please refer to the comment
+// section of the PoolOption structure. Use the Configure function of a
+// specific pool to configure it individually.
+func (p GlobalPool) Configure(option PoolOption) {
+	p.Entry.Configure(option)
+	p.Event.Configure(option)
+	p.Message.Structure.Configure(option)
+	p.Message.Template.Configure(option)
+	p.Message.NamedTemplate.Configure(option)
+	p.Message.String.Configure(option)
+	p.Buffer.Exporter.Configure(option)
+	p.Buffer.Field.Configure(option)
+	p.Buffer.Labels.Configure(option)
+	p.Logger.Structure.Configure(option)
+}
+
+// Clear releases all objects retained by each pool of the global pool.
+// For details, please refer to the comment section of the Clear function
+// of the pools.
+func (p GlobalPool) Clear() {
+	p.Entry.Clear()
+	p.Event.Clear()
+	p.Message.Structure.Clear()
+	p.Message.Template.Clear()
+	p.Message.NamedTemplate.Clear()
+	p.Message.String.Clear()
+	p.Buffer.Exporter.Clear()
+	p.Buffer.Field.Clear()
+	p.Buffer.Labels.Clear()
+	p.Logger.Structure.Clear()
+}
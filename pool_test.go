@@ -65,6 +65,40 @@ func TestTemplateMessagePool(t *testing.T) {
 	pool.Free(message)
 }
 
+func TestStringMessagePool(t *testing.T) {
+	pool := NewStringMessagePool()
+
+	message := pool.New("Hello Test!")
+
+	assert.NotNil(t, message, "Unexpected new error")
+	assert.Equal(t, StringMessage("Hello Test!"), *message,
+		"Unexpected message value")
+
+	pool.Free(message)
+	assert.Empty(t, *message, "Unexpected retained text")
+}
+
+func TestLabelsBufferPool(t *testing.T) {
+	pool := NewLabelsBufferPool()
+
+	buffer := pool.New()
+	labels := NewSerializedLabels(NewLabel("app", "test"))
+	derived := buffer.With(labels, NewLabel("region", "east"))
+	derived = buffer.With(derived, NewLabel("zone", "a"))
+
+	assert.Equal(t, 3, derived.Count(), "Unexpected label count")
+	assert.Equal(t, `{"app": "test", "region": "east", "zone": "a"}`,
+		string(derived.SerializeJSON(nil)), "Unexpected labels")
+	assert.Equal(t, `{"app": "test"}`, string(labels.SerializeJSON(nil)),
+		"Unexpected shared labels")
+	assert.Equal(t, `{"zone": "a"}`, string(buffer.With(
+		NewSerializedLabels(), NewLabel("zone", "a")).SerializeJSON(nil)),
+		"Unexpected labels")
+
+	pool.Free(buffer)
+	assert.Empty(t, buffer.labels, "Unexpected retained labels")
+}
+
 func TestEntryPool(t *testing.T) {
 	pool := NewEntryPool()
 
@@ -88,3 +122,73 @@ func TestExporterBufferPool(t *testing.T) {
 
 	pool.Free(pointer)
 }
+
+func TestPoolOption(t *testing.T) {
+	pool := NewExporterBufferPool(16)
+
+	pool.Configure(PoolOption {
+		MaxObjects: 2,
+		MaxBufferSize: 64,
+	})
+
+	buffers := []*[]byte { pool.New(), pool.New(), pool.New() }
+	large := make([]byte, 0, 128)
+	buffers = append(buffers, &large)
+
+	for _, buffer := range buffers {
+		pool.Free(buffer)
+	}
+
+	stats := pool.Stats()
+
+	assert.Equal(t, uint64(3), stats.Allocations, "Unexpected allocations")
+	assert.Equal(t, uint64(2), stats.Discarded, "Unexpected discarded")
+	assert.Equal(t, 2, stats.Retained, "Unexpected retained")
+
+	assert.Equal(t, 16, cap(*pool.New()), "Unexpected buffer capacity")
+	assert.Equal(t, 1, pool.Stats().Retained, "Unexpected retained")
+
+	pool.Clear()
+
+	assert.Equal(t, 0, pool.Stats().Retained, "Unexpected retained")
+
+	pool.Configure(PoolOption {
+		Disabled: true,
+	})
+
+	pool.Free(pool.New())
+
+	stats = pool.Stats()
+
+	assert.Equal(t, uint64(4), stats.Allocations, "Unexpected allocations")
+	assert.Equal(t, uint64(3), stats.Discarded, "Unexpected discarded")
+	assert.Equal(t, -1, stats.Retained, "Unexpected retained")
+}
+
+func TestGlobalPoolStats(t *testing.T) {
+	pool := NewGlobalPool()
+
+	pool.Configure(PoolOption {
+		MaxObjects: 1,
+	})
+
+	pool.Entry.Free(pool.Entry.New())
+	pool.Message.String.Free(pool.Message.String.New("Hello"))
+	pool.Buffer.Labels.Free(pool.Buffer.Labels.New())
+
+	stats := pool.Stats()
+
+	assert.Equal(t, uint64(1), stats.Entry.Allocations,
+		"Unexpected allocations")
+	assert.Equal(t, 1, stats.Entry.Retained, "Unexpected retained")
+	assert.Equal(t, 0, stats.Buffer.Exporter.Retained,
+		"Unexpected retained")
+	assert.Equal(t, 1, stats.Message.String.Retained,
+		"Unexpected retained")
+	assert.Equal(t, 1, stats.Buffer.Labels.Retained,
+		"Unexpected retained")
+
+	pool.Clear()
+
+	assert.Equal(t, 0, pool.Stats().Entry.Retained, "Unexpected retained")
+}
@@ -0,0 +1,122 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// goroutineID returns the identifier of the calling goroutine, which is
+// parsed from the header of its stack trace (for example: "goroutine 18
+// [running]:"). It returns 0 if the identifier cannot be parsed.
+//
+// Please note that formatting the stack trace is expensive (about one to
+// two microseconds), and it is done for every output operation of a logger
+// with the GuardReentrancy option enabled.
+func goroutineID() uint64 {
+	var buffer [64]byte
+	stack := buffer[ : runtime.Stack(buffer[ : ], false)]
+	const prefix = "goroutine "
+	if len(stack) <= len(prefix) {
+		return 0
+	}
+	stack = stack[len(prefix) : ]
+	size := 0
+	for size < len(stack) && stack[size] >= '0' && stack[size] <= '9' {
+		size++
+	}
+	id, err := strconv.ParseUint(string(stack[ : size]), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return id
+}
+
+// reentrancyGuard is the structure of the reentrancy guard instance.
+//
+// The reentrancy guard keeps track of the goroutines that are outputting
+// log entries through a logger and all of its copies. If a hook, an
+// exporter or a synchronizer outputs a log entry through the same logger
+// (directly or indirectly) while the goroutine is already outputting one,
+// the log entry is diverted to the fallback writer instead, so that it
+// neither deadlocks on the locks held by the outer output operation nor
+// recurses infinitely.
+type reentrancyGuard struct {
+	goroutines sync.Map
+	writer io.Writer
+	mutex sync.Mutex
+}
+
+// enter marks the calling goroutine as outputting a log entry, and then
+// returns the identifier of the goroutine. It returns false if the calling
+// goroutine is already outputting a log entry.
+func (g *reentrancyGuard) enter() (uint64, bool) {
+	id := goroutineID()
+	_, loaded := g.goroutines.LoadOrStore(id, struct { } { })
+	return id, !loaded
+}
+
+// leave marks the goroutine with the given identifier as no longer
+// outputting a log entry.
+func (g *reentrancyGuard) leave(id uint64) {
+	g.goroutines.Delete(id)
+}
+
+// divert writes a warning containing the given log entry name, level and
+// message to the fallback writer, and then returns any errors encountered.
+func (g *reentrancyGuard) divert(name string, level Level,
+	message Message) error {
+	buffer := append([]byte(nil), "santa: reentrant log entry diverted: "...)
+	if len(name) > 0 {
+		buffer = append(buffer, name...)
+		buffer = append(buffer, ' ')
+	}
+	buffer = append(buffer, level.String()...)
+	buffer = append(buffer, ' ')
+	if serializer, ok := message.(StandardSerializer); ok {
+		buffer = serializer.SerializeStandard(buffer)
+	} else {
+		buffer = strconv.AppendQuote(buffer, fmt.Sprint(message))
+	}
+	buffer = append(buffer, '\n')
+
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	_, err := g.writer.Write(buffer)
+	return err
+}
+
+// newReentrancyGuard creates and returns a reentrancy guard instance that
+// diverts reentrant log entries to the given writer. If the given writer
+// is nil, the standard error output is used.
+func newReentrancyGuard(writer io.Writer) *reentrancyGuard {
+	if writer == nil {
+		writer = os.Stderr
+	}
+	return &reentrancyGuard { writer: writer }
+}
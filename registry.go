@@ -0,0 +1,215 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	// ErrRegistered represents that a logger has already been registered
+	// with the given key. This is usually because the application attempts
+	// to register multiple loggers with the same key.
+	ErrRegistered = errors.New("logger already registered")
+
+	// ErrNotRegistered represents that no logger has been registered with
+	// the given key.
+	ErrNotRegistered = errors.New("logger not registered")
+)
+
+// ExporterState is a structure that contains the state of an exporter of
+// a registered logger.
+type ExporterState struct {
+	// Type represents the type name of the exporter.
+	Type string `json:"type"`
+
+	// Syncer represents the type name of the synchronizer used by the
+	// exporter. If the exporter is not a standard exporter, it is empty.
+	Syncer string `json:"syncer,omitempty"`
+
+	// Buffered represents the number of bytes of log entry data cached
+	// by the synchronizer, which is the depth of the queue of log entry
+	// data that has not yet been written. If the synchronizer does not
+	// implement the BufferedSyncer interface, it is zero.
+	Buffered int `json:"buffered"`
+}
+
+// LoggerState is a structure that contains the state of a registered
+// logger at a point in time.
+type LoggerState struct {
+	// Key represents the key with which the logger is registered.
+	Key string `json:"key"`
+
+	// Name represents the name of log entries of the logger.
+	Name string `json:"name"`
+
+	// Level represents the lowest level of log entries of the logger.
+	Level Level `json:"level"`
+
+	// Closed represents whether the logger has been closed.
+	Closed bool `json:"closed"`
+
+	// Sampler represents the type name of the sampler of the logger. If
+	// sampling is disabled, it is empty.
+	Sampler string `json:"sampler,omitempty"`
+
+	// Dropped represents the number of log entries discarded by the
+	// sampler. If the sampler does not implement the DropCounter
+	// interface, it is zero.
+	Dropped uint64 `json:"dropped"`
+
+	// Exporters represents the state of each exporter of the logger.
+	Exporters []ExporterState `json:"exporters"`
+}
+
+// newLoggerState returns the current state of the given logger registered
+// with the given key.
+func newLoggerState(key string, logger *StandardLogger) LoggerState {
+	state := LoggerState {
+		Key: key,
+		Name: logger.Name(),
+		Level: logger.Level(),
+		Closed: logger.IsClosed(),
+	}
+	if sampler := logger.Sampler(); sampler != nil {
+		state.Sampler = fmt.Sprintf("%T", sampler)
+		if counter, ok := sampler.(DropCounter); ok {
+			state.Dropped = counter.Dropped()
+		}
+	}
+	exporters := logger.Exporters()
+	state.Exporters = make([]ExporterState, len(exporters))
+	for index, exporter := range exporters {
+		state.Exporters[index].Type = fmt.Sprintf("%T", exporter)
+		standard, ok := exporter.(*StandardExporter)
+		if !ok {
+			continue
+		}
+		state.Exporters[index].Syncer = fmt.Sprintf("%T", standard.Syncer())
+		if buffered, ok := standard.Syncer().(BufferedSyncer); ok {
+			state.Exporters[index].Buffered = buffered.Buffered()
+		}
+	}
+	return state
+}
+
+// Registry is the structure of the logger registry instance.
+//
+// The logger registry keeps track of the loggers of a process by key, so
+// that operators can inspect the logging state of the process in a single
+// place and change the level of each logger dynamically. The registry does
+// not own the registered loggers, which means that it neither duplicates
+// nor closes them.
+//
+// The API provided by the logger registry is thread-safe.
+type Registry struct {
+	mutex sync.RWMutex
+	loggers map[string]*StandardLogger
+}
+
+// Register registers the given logger with the given key. If a logger has
+// already been registered with the key, it returns ErrRegistered.
+func (r *Registry) Register(key string, logger *StandardLogger) error {
+	if logger == nil {
+		return ErrInvalidType
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.loggers[key]; ok {
+		return ErrRegistered
+	}
+	r.loggers[key] = logger
+	return nil
+}
+
+// Unregister removes the logger registered with the given key from the
+// registry. It returns false if no logger has been registered with the key.
+func (r *Registry) Unregister(key string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if _, ok := r.loggers[key]; !ok {
+		return false
+	}
+	delete(r.loggers, key)
+	return true
+}
+
+// Lookup returns the logger registered with the given key. It returns false
+// if no logger has been registered with the key.
+func (r *Registry) Lookup(key string) (*StandardLogger, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	logger, ok := r.loggers[key]
+	return logger, ok
+}
+
+// SetLevel sets the lowest level of log entries of the logger registered
+// with the given key. If no logger has been registered with the key, it
+// returns ErrNotRegistered.
+func (r *Registry) SetLevel(key string, level Level) error {
+	logger, ok := r.Lookup(key)
+	if !ok {
+		return ErrNotRegistered
+	}
+	logger.SetLevel(level)
+	return nil
+}
+
+// States returns the current state of all registered loggers, ordered by
+// their keys.
+func (r *Registry) States() []LoggerState {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	states := make([]LoggerState, 0, len(r.loggers))
+	for key, logger := range r.loggers {
+		states = append(states, newLoggerState(key, logger))
+	}
+	sort.Slice(states, func(i, j int) bool {
+		return states[i].Key < states[j].Key
+	})
+	return states
+}
+
+// NewRegistry creates and returns an empty logger registry instance.
+func NewRegistry() *Registry {
+	return &Registry {
+		loggers: make(map[string]*StandardLogger),
+	}
+}
+
+// globalRegistry is the default logger registry instance, which is
+// created when the application is initialized and shared globally.
+var globalRegistry = NewRegistry()
+
+// GetGlobalRegistry returns the default logger registry instance. For
+// details, please refer to the comment section of the Registry structure.
+func GetGlobalRegistry() *Registry {
+	return globalRegistry
+}
@@ -0,0 +1,81 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	option := NewStandardOption().DisableFlushing().UseName("testing").
+		UseSampling(NewSamplingOption().UseTextOption(
+			NewTextSamplerOption().UseTick(time.Hour).UseFirst(1, 1000)))
+	option.Outputting.UseStandard(&testLockedWriter { })
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	registry := NewRegistry()
+	assert.NoError(t, registry.Register("second", logger),
+		"Unexpected register error")
+	assert.NoError(t, registry.Register("first", logger),
+		"Unexpected register error")
+	assert.Equal(t, ErrRegistered, registry.Register("first", logger),
+		"Unexpected register error")
+	assert.Equal(t, ErrInvalidType, registry.Register("third", nil),
+		"Unexpected register error")
+
+	for count := 0; count < 4; count++ {
+		assert.NoError(t, logger.Info(StringMessage("Hello Test!")),
+			"Unexpected print error")
+	}
+	assert.NoError(t, registry.SetLevel("first", LevelWarning),
+		"Unexpected set error")
+	assert.Equal(t, ErrNotRegistered, registry.SetLevel("third",
+		LevelWarning), "Unexpected set error")
+	assert.Equal(t, LevelWarning, logger.Level(), "Unexpected level")
+
+	states := registry.States()
+	assert.Len(t, states, 2, "Unexpected states")
+	assert.Equal(t, "first", states[0].Key, "Unexpected state order")
+	assert.Equal(t, "testing", states[0].Name, "Unexpected state name")
+	assert.Equal(t, LevelWarning, states[0].Level, "Unexpected state level")
+	assert.Equal(t, "*santa.TextSampler", states[0].Sampler,
+		"Unexpected state sampler")
+	assert.Equal(t, uint64(2), states[0].Dropped, "Unexpected dropped count")
+	assert.NotEmpty(t, states[0].Exporters, "Unexpected state exporters")
+	assert.Equal(t, "*santa.StandardExporter", states[0].Exporters[0].Type,
+		"Unexpected exporter type")
+	assert.Greater(t, states[0].Exporters[0].Buffered, 0,
+		"Unexpected buffered size")
+
+	assert.True(t, registry.Unregister("second"), "Unexpected unregister")
+	assert.False(t, registry.Unregister("second"), "Unexpected unregister")
+	_, ok := registry.Lookup("second")
+	assert.False(t, ok, "Unexpected lookup")
+
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+	assert.True(t, registry.States()[0].Closed, "Unexpected closed state")
+}
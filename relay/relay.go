@@ -0,0 +1,626 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+// Package relay provides a lightweight log relay server, which accepts the
+// log entries written by network synchronizers of other processes over
+// TCP/IP or Unix streams, and exports them through its own exporters.
+//
+// The relay server is usually deployed as an aggregation sidecar: every
+// process on a host writes its log entries to the relay server, and only
+// the relay server writes to the final storage devices.
+package relay
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/nobody-night/santa"
+)
+
+// Record is the message type of the log entries exported by a relay
+// server, which contains an encoded log entry received from a client,
+// without its framing.
+type Record []byte
+
+// SerializeStandard serializes the record and appends to the given buffer
+// slice, and then returns the appended buffer slice.
+func (r Record) SerializeStandard(buffer []byte) []byte {
+	return append(buffer, r...)
+}
+
+// SerializeJSON serializes the record and appends to the given buffer
+// slice, and then returns the appended buffer slice. A record that is a
+// JSON value is appended as is, and any other record is appended as a JSON
+// string.
+func (r Record) SerializeJSON(buffer []byte) []byte {
+	if json.Valid(r) {
+		return append(buffer, r...)
+	}
+	return strconv.AppendQuote(buffer, string(r))
+}
+
+// received is a structure that contains a record received by a relay
+// server and the time it was received.
+type received struct {
+	time time.Time
+	record Record
+}
+
+// Server is the structure of the relay server instance.
+//
+// The relay server accepts connections from network synchronizers, splits
+// the data of each connection into records with the configured framing,
+// and queues the records. Records from all connections are fanned in to a
+// single goroutine that exports them through the exporters of the server,
+// so the exporters are never used concurrently. If the queue is full, the
+// connections are not read until there is room in the queue, which makes
+// the clients buffer the log entries instead of the relay server.
+//
+// Each record is exported as a log entry whose message is the Record type,
+// whose time is the time the record was received, and whose level is read
+// from the level key of the record if it is a JSON object (for example:
+// written by a JSON encoder), otherwise the default level is used. If the
+// time key is configured, the time is read from it instead, so that the
+// records of multiple clients can be ordered by an ordering exporter (see
+// the santa.OrderingExporter structure).
+type Server struct {
+	listener net.Listener
+	exporters []santa.Exporter
+	framing santa.RecordFraming
+	decompressor Decompressor
+	maxRecordSize int
+	flushInterval time.Duration
+	level santa.Level
+	levelKey string
+	levelMapper santa.LevelMapper
+	timeKey string
+	name string
+
+	queue chan received
+	mutex sync.Mutex
+	connections map[net.Conn]struct { }
+	readers sync.WaitGroup
+	done chan struct { }
+	closed int32
+	exported uint64
+	dropped uint64
+}
+
+// Address returns the network address that the relay server listens on.
+func (s *Server) Address() net.Addr {
+	return s.listener.Addr()
+}
+
+// Exported returns the number of records exported by the relay server.
+func (s *Server) Exported() uint64 {
+	return atomic.LoadUint64(&s.exported)
+}
+
+// Dropped returns the number of records dropped by the relay server
+// because they are larger than the maximum record size.
+func (s *Server) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// accept accepts connections until the listener is closed.
+func (s *Server) accept() {
+	defer s.readers.Done()
+	for {
+		connection, err := s.listener.Accept()
+		if err != nil {
+			var temporary interface { Temporary() bool }
+			if errors.As(err, &temporary) && temporary.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return
+		}
+		s.mutex.Lock()
+		if atomic.LoadInt32(&s.closed) == 1 {
+			s.mutex.Unlock()
+			_ = connection.Close()
+			return
+		}
+		s.connections[connection] = struct { } { }
+		s.readers.Add(1)
+		s.mutex.Unlock()
+		go s.read(connection)
+	}
+}
+
+// read reads the records of the given connection until it is closed.
+func (s *Server) read(connection net.Conn) {
+	defer s.readers.Done()
+	defer func() {
+		s.mutex.Lock()
+		delete(s.connections, connection)
+		s.mutex.Unlock()
+		_ = connection.Close()
+	}()
+	var source io.Reader = connection
+	if s.decompressor != nil {
+		reader, err := s.decompressor(connection)
+		if err != nil {
+			return
+		}
+		defer reader.Close()
+		source = reader
+	}
+	reader := bufio.NewReaderSize(source, s.maxRecordSize + 5)
+	for {
+		record, err := s.next(reader)
+		if err != nil {
+			return
+		}
+		if record == nil {
+			continue
+		}
+		s.queue <- received {
+			time: time.Now(),
+			record: append(Record(nil), record...),
+		}
+	}
+}
+
+// next reads the next record from the given reader. If the record is
+// larger than the maximum record size, it is discarded and nil is
+// returned.
+func (s *Server) next(reader *bufio.Reader) ([]byte, error) {
+	if s.framing == santa.FramingLengthPrefixed {
+		var prefix [4]byte
+		if _, err := io.ReadFull(reader, prefix[ : ]); err != nil {
+			return nil, err
+		}
+		length := int(binary.BigEndian.Uint32(prefix[ : ]))
+		if length > s.maxRecordSize {
+			atomic.AddUint64(&s.dropped, 1)
+			_, err := reader.Discard(length)
+			return nil, err
+		}
+		record, err := reader.Peek(length)
+		if err != nil {
+			return nil, err
+		}
+		_, _ = reader.Discard(length)
+		return record, nil
+	}
+
+	record, err := reader.ReadSlice('\n')
+	if err == bufio.ErrBufferFull {
+		// Discard the rest of the record.
+		atomic.AddUint64(&s.dropped, 1)
+		for err == bufio.ErrBufferFull {
+			_, err = reader.ReadSlice('\n')
+		}
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+	record = record[ : len(record) - 1]
+	switch s.framing {
+	case santa.FramingCRLF:
+		record = bytes.TrimSuffix(record, []byte { '\r' })
+	case santa.FramingJSONSequence:
+		record = bytes.TrimPrefix(record, []byte { 0x1e })
+	}
+	if len(record) == 0 {
+		return nil, nil
+	}
+	return record, nil
+}
+
+// levelOf returns the level of the given record, which is read from the
+// level key if the record is a JSON object, otherwise it is the default
+// level.
+func (s *Server) levelOf(record Record) santa.Level {
+	if len(record) == 0 || record[0] != '{' {
+		return s.level
+	}
+	var object map[string]json.RawMessage
+	if json.Unmarshal(record, &object) != nil {
+		return s.level
+	}
+	value, ok := object[s.levelKey]
+	if !ok {
+		return s.level
+	}
+	// The levels of some schemes are numbers (for example: syslog
+	// severities), which are passed to the level mapper as they are.
+	name := string(bytes.TrimSpace(value))
+	if len(name) > 0 && name[0] == '"' &&
+		json.Unmarshal(value, &name) != nil {
+		return s.level
+	}
+	level, ok := s.levelMapper(name)
+	if !ok {
+		return s.level
+	}
+	return level
+}
+
+// timeOf returns the time of the given record, which is read from the time
+// key if it is configured and the record is a JSON object. The time is
+// either a JSON number of Unix nanoseconds or a JSON string in the RFC
+// 3339 format. If the time cannot be read, it returns false.
+func (s *Server) timeOf(record Record) (time.Time, bool) {
+	if len(s.timeKey) == 0 || len(record) == 0 || record[0] != '{' {
+		return time.Time { }, false
+	}
+	var object map[string]json.RawMessage
+	if json.Unmarshal(record, &object) != nil {
+		return time.Time { }, false
+	}
+	value, ok := object[s.timeKey]
+	if !ok {
+		return time.Time { }, false
+	}
+	var nanoseconds int64
+	if json.Unmarshal(value, &nanoseconds) == nil {
+		return time.Unix(0, nanoseconds), true
+	}
+	var text string
+	if json.Unmarshal(value, &text) != nil {
+		return time.Time { }, false
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, text)
+	return parsed, err == nil
+}
+
+// export exports the queued records until the queue is closed, and flushes
+// the exporters once the queue has been empty for the flush interval.
+func (s *Server) export() {
+	defer close(s.done)
+	ticker := time.NewTicker(s.flushInterval)
+	defer ticker.Stop()
+	pending := false
+	for {
+		select {
+		case item, ok := <-s.queue:
+			if !ok {
+				return
+			}
+			entry := &santa.Entry {
+				Time: item.time,
+				Level: s.levelOf(item.record),
+				Message: item.record,
+				Name: s.name,
+			}
+			if at, ok := s.timeOf(item.record); ok {
+				entry.Time = at
+				entry.TimeOverridden = true
+			}
+			for index := 0; index < len(s.exporters); index++ {
+				_ = s.exporters[index].Export(entry)
+			}
+			atomic.AddUint64(&s.exported, 1)
+			pending = true
+		case <-ticker.C:
+			if pending {
+				_ = s.Flush()
+				pending = false
+			}
+		}
+	}
+}
+
+// Flush flushes all exporters of the relay server. Exporters that
+// implement the Flusher interface are flushed, and the others are
+// synchronized.
+//
+// Finally, all errors encountered are returned as a MultiError, or nil if
+// no error is encountered.
+func (s *Server) Flush() error {
+	var errs santa.MultiError
+	for index := 0; index < len(s.exporters); index++ {
+		if flusher, ok := s.exporters[index].(santa.Flusher); ok {
+			errs = errs.Append(flusher.Flush())
+		} else {
+			errs = errs.Append(s.exporters[index].Sync())
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+// Close stops accepting connections, closes all connections, exports the
+// queued records, and then closes all exporters of the relay server.
+//
+// Finally, all errors encountered are returned as a MultiError, or nil if
+// no error is encountered. If the relay server has been closed, ErrClosed
+// is returned.
+func (s *Server) Close() error {
+	if !atomic.CompareAndSwapInt32(&s.closed, 0, 1) {
+		return santa.ErrClosed
+	}
+	var errs santa.MultiError
+	errs = errs.Append(s.listener.Close())
+	s.mutex.Lock()
+	for connection := range s.connections {
+		_ = connection.Close()
+	}
+	s.mutex.Unlock()
+	s.readers.Wait()
+	close(s.queue)
+	<-s.done
+	for index := 0; index < len(s.exporters); index++ {
+		errs = errs.Append(s.exporters[index].Close())
+	}
+	return errs.ErrorOrNil()
+}
+
+// Decompressor is the function type that returns a reader of the
+// decompressed data of the given connection, and any errors encountered.
+// The returned reader is closed when the connection is closed.
+type Decompressor func(reader io.Reader) (io.ReadCloser, error)
+
+// Option is a structure that contains relay server options.
+type Option struct {
+	// Protocol represents the network protocol that the relay server
+	// listens on, which is santa.ProtocolTCP or santa.ProtocolUnix. If not
+	// provided, the default value is santa.ProtocolTCP.
+	Protocol string
+
+	// Address represents the network address that the relay server listens
+	// on. If not provided, the default value is "127.0.0.1:10000".
+	Address string
+
+	// Exporters represents the exporters that the received log entries are
+	// exported through. The exporters are closed when the relay server is
+	// closed. This option is required.
+	Exporters []santa.Exporter
+
+	// Framing represents how the log entries written by the clients are
+	// delimited, which must match the framing of the exporters of the
+	// clients. The santa.FramingNone constant is not supported. If not
+	// provided, the default value is the santa.FramingNewline constant.
+	Framing santa.RecordFraming
+
+	// Decompressor represents the decompressor of the data of each
+	// connection, which is used when the clients use compression exporters
+	// (for example: the Decompress function of the compression package),
+	// so the data is decompressed before it is split into records. If not
+	// provided, the default value is nil, which means that the data is not
+	// compressed.
+	Decompressor Decompressor
+
+	// Capacity represents the number of records that can be queued before
+	// the connections are no longer read. If not provided, the default
+	// value is 4096.
+	Capacity int
+
+	// MaxRecordSize represents the maximum size in bytes of a record.
+	// Larger records are dropped. If not provided, the default value is
+	// 1 MiB.
+	MaxRecordSize int
+
+	// FlushInterval represents the interval at which the exporters are
+	// flushed after records have been exported. If not provided, the
+	// default value is 1 second.
+	FlushInterval time.Duration
+
+	// Level represents the level of the exported log entries whose level
+	// cannot be read from the records. If not provided, the default value
+	// is INFO level.
+	Level santa.Level
+
+	// LevelKey represents the key that the level is read from if a record
+	// is a JSON object. If not provided, the default value is "level".
+	LevelKey string
+
+	// LevelMapper represents the level mapper that translates the levels
+	// read from the records, whose values are either JSON strings or JSON
+	// numbers, into log levels, so that records produced by other systems
+	// with their own level schemes (for example: santa.MapSyslogSeverity)
+	// can be relayed. Records whose levels are not recognized use the
+	// default level. If not provided, the default value is the
+	// santa.MapLevelName function.
+	LevelMapper santa.LevelMapper
+
+	// TimeKey represents the key that the time of the exported log entries
+	// is read from if a record is a JSON object, as a JSON number of Unix
+	// nanoseconds (for example: written by a JSON encoder with the default
+	// time layout) or a JSON string in the RFC 3339 format. Records whose
+	// times cannot be read use the time they were received. If not
+	// provided, the time every record was received is used.
+	TimeKey string
+
+	// Name represents the name of the exported log entries. If not
+	// provided, the default value is "relay".
+	Name string
+}
+
+// UseListener uses the given network protocol and address as the values
+// of the options Protocol and Address. Then return to the option instance
+// itself.
+func (o *Option) UseListener(protocol, address string) *Option {
+	o.Protocol = protocol
+	o.Address = address
+	return o
+}
+
+// UseExporters uses the given exporters as the value of the option
+// Exporters. Then return to the option instance itself.
+func (o *Option) UseExporters(exporters ...santa.Exporter) *Option {
+	o.Exporters = exporters
+	return o
+}
+
+// UseFraming uses the given record framing as the value of the option
+// Framing. Then return to the option instance itself.
+func (o *Option) UseFraming(framing santa.RecordFraming) *Option {
+	o.Framing = framing
+	return o
+}
+
+// UseDecompressor uses the given decompressor as the value of the option
+// Decompressor. Then return to the option instance itself.
+func (o *Option) UseDecompressor(decompressor Decompressor) *Option {
+	o.Decompressor = decompressor
+	return o
+}
+
+// UseCapacity uses the given number of records as the value of the option
+// Capacity. Then return to the option instance itself.
+func (o *Option) UseCapacity(capacity int) *Option {
+	o.Capacity = capacity
+	return o
+}
+
+// UseLevelMapper uses the given key and level mapper as the values of the
+// options LevelKey and LevelMapper. Then return to the option instance
+// itself.
+func (o *Option) UseLevelMapper(key string, mapper santa.LevelMapper) *Option {
+	o.LevelKey = key
+	o.LevelMapper = mapper
+	return o
+}
+
+// UseTimeKey uses the given key as the value of the option TimeKey. Then
+// return to the option instance itself.
+func (o *Option) UseTimeKey(key string) *Option {
+	o.TimeKey = key
+	return o
+}
+
+// UseName uses the given name as the value of the option Name. Then return
+// to the option instance itself.
+func (o *Option) UseName(name string) *Option {
+	o.Name = name
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *Option) Validate() error {
+	if o.Protocol != santa.ProtocolTCP && o.Protocol != santa.ProtocolUnix {
+		return &santa.OptionError {
+			Option: "Protocol",
+			Reason: "unsupported protocol " + strconv.Quote(o.Protocol),
+			Err: santa.ErrInvalidProtocol,
+		}
+	}
+	if len(o.Address) == 0 {
+		return &santa.OptionError {
+			Option: "Address",
+			Reason: "must not be empty",
+		}
+	}
+	if len(o.Exporters) == 0 {
+		return &santa.OptionError {
+			Option: "Exporters",
+			Reason: "must not be empty",
+		}
+	}
+	switch o.Framing {
+	case santa.FramingNewline, santa.FramingCRLF,
+		santa.FramingJSONSequence, santa.FramingLengthPrefixed:
+	default:
+		return &santa.OptionError {
+			Option: "Framing",
+			Reason: "unsupported framing " + strconv.Itoa(int(o.Framing)),
+			Err: santa.ErrInvalidType,
+		}
+	}
+	if o.Capacity <= 0 {
+		return &santa.OptionError {
+			Option: "Capacity",
+			Reason: "must be greater than 0",
+		}
+	}
+	if o.MaxRecordSize <= 0 {
+		return &santa.OptionError {
+			Option: "MaxRecordSize",
+			Reason: "must be greater than 0",
+		}
+	}
+	if o.FlushInterval <= 0 {
+		return &santa.OptionError {
+			Option: "FlushInterval",
+			Reason: "must be greater than 0",
+		}
+	}
+	return nil
+}
+
+// Build builds and returns a relay server instance, which listens on the
+// network address and accepts connections.
+func (o *Option) Build() (*Server, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen(o.Protocol, o.Address)
+	if err != nil {
+		return nil, err
+	}
+	mapper := o.LevelMapper
+	if mapper == nil {
+		mapper = santa.MapLevelName
+	}
+	instance := &Server {
+		listener: listener,
+		exporters: append([]santa.Exporter(nil), o.Exporters...),
+		framing: o.Framing,
+		decompressor: o.Decompressor,
+		maxRecordSize: o.MaxRecordSize,
+		flushInterval: o.FlushInterval,
+		level: o.Level,
+		levelKey: o.LevelKey,
+		levelMapper: mapper,
+		timeKey: o.TimeKey,
+		name: o.Name,
+		queue: make(chan received, o.Capacity),
+		connections: make(map[net.Conn]struct { }),
+		done: make(chan struct { }),
+	}
+	instance.readers.Add(1)
+	go instance.accept()
+	go instance.export()
+	return instance, nil
+}
+
+// NewOption creates and returns a relay server option instance with
+// default option values.
+func NewOption() *Option {
+	return &Option {
+		Protocol: santa.ProtocolTCP,
+		Address: "127.0.0.1:10000",
+		Framing: santa.FramingNewline,
+		Capacity: 4096,
+		MaxRecordSize: 1024 * 1024,
+		FlushInterval: time.Second,
+		Level: santa.LevelInfo,
+		LevelKey: "level",
+		LevelMapper: santa.MapLevelName,
+		Name: "relay",
+	}
+}
@@ -0,0 +1,182 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package relay
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nobody-night/santa"
+	"github.com/stretchr/testify/assert"
+)
+
+type testLockedWriter struct {
+	mutex sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (w *testLockedWriter) Write(buffer []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.Write(buffer)
+}
+
+func (w *testLockedWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.String()
+}
+
+type testDecompressor struct {
+	mutex sync.Mutex
+	readers int
+}
+
+func (d *testDecompressor) decompress(reader io.Reader) (io.ReadCloser, error) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.readers++
+	return io.NopCloser(reader), nil
+}
+
+func (d *testDecompressor) count() int {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	return d.readers
+}
+
+func TestServer(t *testing.T) {
+	for _, compressed := range []bool { false, true } {
+		decompressor := &testDecompressor { }
+		writer := &testLockedWriter { }
+		syncer, err := santa.NewStandardSyncerOption().UseWriter(writer).
+			UseCacheCapacity(0).Build()
+		assert.NoError(t, err, "Unexpected create error")
+		encoder, err := santa.NewStandardEncoderOption().UseEncoderOption(
+			santa.EncoderOption { }).Build()
+		assert.NoError(t, err, "Unexpected create error")
+		exporter, err := santa.NewStandardExporterOption().
+			UseEncoder(encoder).UseSyncer(syncer).
+			UseSpan(santa.LevelWarning, santa.LevelFatal).Build()
+		assert.NoError(t, err, "Unexpected create error")
+		option := NewOption().UseListener(santa.ProtocolUnix,
+			filepath.Join(t.TempDir(), "relay.sock")).
+			UseExporters(exporter).
+			UseFraming(santa.FramingLengthPrefixed)
+		if compressed {
+			option.UseDecompressor(decompressor.decompress)
+		}
+		server, err := option.Build()
+		assert.NoError(t, err, "Unexpected create error")
+
+		json, err := santa.NewJSONEncoder()
+		assert.NoError(t, err, "Unexpected create error")
+		var clients []santa.Exporter
+		for index := 0; index < 3; index++ {
+			client, err := santa.NewNetworkSyncerOption().UseCacheCapacity(0).
+				UseProtocol(santa.ProtocolUnix).
+				UseAddress(server.Address().String()).Build()
+			assert.NoError(t, err, "Unexpected create error")
+			exporter, err := santa.NewStandardExporterOption().
+				UseEncoder(json).UseSyncer(client).
+				UseFraming(santa.FramingLengthPrefixed).Build()
+			assert.NoError(t, err, "Unexpected create error")
+			clients = append(clients, exporter)
+		}
+		for _, client := range clients {
+			for _, level := range []santa.Level { santa.LevelInfo,
+				santa.LevelError } {
+				assert.NoError(t, client.Export(&santa.Entry { Level: level,
+					Message: santa.StringMessage("relayed") }),
+					"Unexpected export error")
+			}
+			assert.NoError(t, client.Sync(), "Unexpected sync error")
+		}
+		assert.Eventually(t, func() bool {
+			return server.Exported() == 6
+		}, 5 * time.Second, 10 * time.Millisecond, "Unexpected exported")
+		for _, client := range clients {
+			assert.NoError(t, client.Close(), "Unexpected close error")
+		}
+		assert.NoError(t, server.Close(), "Unexpected close error")
+		assert.True(t, errors.Is(server.Close(), santa.ErrClosed),
+			"Unexpected close error")
+
+		if compressed {
+			assert.Equal(t, 3, decompressor.count(),
+				"Unexpected decompressed connections")
+		}
+		lines := strings.Split(strings.TrimSpace(writer.String()), "\n")
+		assert.Len(t, lines, 3, "Unexpected output")
+		for _, line := range lines {
+			assert.Contains(t, line, `"level": "ERROR"`, "Unexpected output")
+			assert.Contains(t, line, `"relayed"`, "Unexpected output")
+		}
+	}
+
+	_, err := NewOption().Build()
+	assert.True(t, errors.Is(err, santa.ErrInvalidOption),
+		"Unexpected create error")
+	_, err = NewOption().UseExporters(&santa.StandardExporter { }).
+		UseFraming(santa.FramingNone).Build()
+	assert.True(t, errors.Is(err, santa.ErrInvalidType),
+		"Unexpected create error")
+}
+
+func TestServerLevelMapper(t *testing.T) {
+	server := &Server {
+		level: santa.LevelInfo,
+		levelKey: "severity",
+		levelMapper: santa.MapSyslogSeverity,
+	}
+	assert.Equal(t, santa.LevelError, server.levelOf(
+		Record(`{"severity": 3}`)), "Unexpected numeric level")
+	assert.Equal(t, santa.LevelFatal, server.levelOf(
+		Record(`{"severity": "crit"}`)), "Unexpected named level")
+	assert.Equal(t, santa.LevelInfo, server.levelOf(
+		Record(`{"severity": 9}`)), "Unexpected default level")
+	assert.Equal(t, santa.LevelInfo, server.levelOf(
+		Record(`{"level": "error"}`)), "Unexpected default level")
+}
+
+func TestServerTimeKey(t *testing.T) {
+	server := &Server { timeKey: "timestamp" }
+	at, ok := server.timeOf(Record(`{"timestamp": 1600000000000000001}`))
+	assert.True(t, ok, "Unexpected missing time")
+	assert.Equal(t, int64(1600000000000000001), at.UnixNano(),
+		"Unexpected numeric time")
+	at, ok = server.timeOf(Record(`{"timestamp": "2020-09-13T12:26:40Z"}`))
+	assert.True(t, ok, "Unexpected missing time")
+	assert.Equal(t, int64(1600000000), at.Unix(), "Unexpected text time")
+	_, ok = server.timeOf(Record(`{"time": 1}`))
+	assert.False(t, ok, "Unexpected time")
+	_, ok = server.timeOf(Record("plain text"))
+	assert.False(t, ok, "Unexpected time")
+}
@@ -0,0 +1,232 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// RequestIDName represents the default name of the field that contains
+	// the request ID of a log entry.
+	RequestIDName = "request_id"
+
+	// RequestIDHeader represents the default name of the header (or the
+	// gRPC metadata key) that carries the request ID of a request.
+	RequestIDHeader = "X-Request-ID"
+)
+
+// RequestIDMiddleware is the structure of a request ID middleware instance.
+//
+// For each request, the request ID middleware reads the request ID from
+// the incoming header, or generates a new one if the header is missing or
+// invalid, and then derives the context of the request, which carries the
+// request ID (see the RequestIDFromContext function) and a field with the
+// request ID (see the ContextWith function), so that the loggers returned
+// by the FromContext function add the request ID to each structured log
+// message of the request. Optionally, the request ID is echoed to the
+// response headers, so that clients can correlate their requests with the
+// log entries.
+//
+// The Handler function provides the middleware for HTTP servers, and the
+// interceptor package provides the interceptors for gRPC servers.
+type RequestIDMiddleware struct {
+	header string
+	field string
+	generator func() string
+	echo bool
+	maxLength int
+}
+
+// Header returns the name of the header that carries the request ID.
+func (m *RequestIDMiddleware) Header() string {
+	return m.header
+}
+
+// Echo returns true if the request ID is echoed to the response headers.
+func (m *RequestIDMiddleware) Echo() bool {
+	return m.echo
+}
+
+// Resolve returns the given incoming request ID if it is valid, otherwise
+// a new request ID. The incoming request ID is valid if it is not empty,
+// not longer than the maximum length and only contains printable ASCII
+// characters except spaces, which prevents clients from injecting
+// arbitrary data into the log entries.
+func (m *RequestIDMiddleware) Resolve(id string) string {
+	if len(id) == 0 || len(id) > m.maxLength {
+		return m.generator()
+	}
+	for index := 0; index < len(id); index++ {
+		if id[index] <= ' ' || id[index] > '~' {
+			return m.generator()
+		}
+	}
+	return id
+}
+
+// WithContext returns a context derived from the given context that
+// carries the given request ID and a field with the request ID.
+func (m *RequestIDMiddleware) WithContext(ctx context.Context,
+	id string) context.Context {
+	ctx = ContextWith(ctx, String(m.field, id))
+	return context.WithValue(ctx, contextKeyRequestID, id)
+}
+
+// Handler returns an HTTP handler that handles each request with the
+// given handler, after the context of the request has been derived with
+// the request ID of the request. If the echo is enabled, the request ID is
+// set to the response header before the given handler is called.
+func (m *RequestIDMiddleware) Handler(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter,
+		request *http.Request) {
+		id := m.Resolve(request.Header.Get(m.header))
+		if m.echo {
+			writer.Header().Set(m.header, id)
+		}
+		handler.ServeHTTP(writer, request.WithContext(
+			m.WithContext(request.Context(), id)))
+	})
+}
+
+// RequestIDFromContext returns the request ID carried by the given context,
+// or an empty string if the context does not carry one. For details,
+// please refer to the comment section of the RequestIDMiddleware
+// structure.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKeyRequestID).(string)
+	return id
+}
+
+// RequestIDOption is a structure that contains options for request ID
+// middlewares.
+type RequestIDOption struct {
+	// Header represents the name of the header (or the gRPC metadata key)
+	// that carries the request ID of a request. If not provided, the
+	// default value is RequestIDHeader.
+	Header string
+
+	// Field represents the name of the field that contains the request ID
+	// of each structured log message. If not provided, the default value
+	// is RequestIDName.
+	Field string
+
+	// Generator represents the function that generates a new request ID
+	// when the incoming request ID is missing or invalid. If not provided,
+	// the default value is the NewCorrelationID function.
+	Generator func() string
+
+	// Echo represents whether to echo the request ID to the response
+	// headers. If not provided, the default value is true.
+	Echo bool
+
+	// MaxLength represents the maximum length of a valid incoming request
+	// ID. If not provided, the default value is 128.
+	MaxLength int
+}
+
+// UseHeader uses the given header name as the value of the option Header.
+// Then return to the option instance itself.
+func (o *RequestIDOption) UseHeader(header string) *RequestIDOption {
+	o.Header = header
+	return o
+}
+
+// UseField uses the given field name as the value of the option Field.
+// Then return to the option instance itself.
+func (o *RequestIDOption) UseField(field string) *RequestIDOption {
+	o.Field = field
+	return o
+}
+
+// UseGenerator uses the given function as the value of the option
+// Generator. Then return to the option instance itself.
+func (o *RequestIDOption) UseGenerator(generator func() string) *RequestIDOption {
+	o.Generator = generator
+	return o
+}
+
+// DisableEcho disables the option Echo. Then return to the option instance
+// itself.
+func (o *RequestIDOption) DisableEcho() *RequestIDOption {
+	o.Echo = false
+	return o
+}
+
+// UseMaxLength uses the given length as the value of the option MaxLength.
+// Then return to the option instance itself.
+func (o *RequestIDOption) UseMaxLength(length int) *RequestIDOption {
+	o.MaxLength = length
+	return o
+}
+
+// Validate checks whether the option is valid, and then returns the first
+// error encountered.
+func (o *RequestIDOption) Validate() error {
+	if len(o.Header) == 0 {
+		return newOptionError("Header", "must not be empty", nil)
+	}
+	if len(o.Field) == 0 {
+		return newOptionError("Field", "must not be empty", nil)
+	}
+	if o.Generator == nil {
+		return newOptionError("Generator", "must not be nil", nil)
+	}
+	if o.MaxLength <= 0 {
+		return newOptionError("MaxLength", "must be greater than 0", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a request ID middleware instance.
+func (o *RequestIDOption) Build() (*RequestIDMiddleware, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &RequestIDMiddleware {
+		header: http.CanonicalHeaderKey(o.Header),
+		field: o.Field,
+		generator: o.Generator,
+		echo: o.Echo,
+		maxLength: o.MaxLength,
+	}, nil
+}
+
+// NewRequestIDOption creates and returns a request ID option instance with
+// default optional values.
+func NewRequestIDOption() *RequestIDOption {
+	return &RequestIDOption {
+		Header: RequestIDHeader,
+		Field: RequestIDName,
+		Generator: NewCorrelationID,
+		Echo: true,
+		MaxLength: 128,
+	}
+}
+
+// NewRequestIDMiddleware creates and returns a request ID middleware
+// instance with default optional values.
+func NewRequestIDMiddleware() (*RequestIDMiddleware, error) {
+	return NewRequestIDOption().Build()
+}
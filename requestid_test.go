@@ -0,0 +1,96 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDMiddleware(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling()
+	option.Encoding.UseStandard()
+	option.Outputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	defer logger.Close()
+
+	DisableCorrelationIDInjection()
+	defer EnableCorrelationIDInjection()
+
+	middleware, err := NewRequestIDMiddleware()
+	assert.NoError(t, err, "Unexpected create error")
+	var ids []string
+	handler := middleware.Handler(http.HandlerFunc(func(
+		writer http.ResponseWriter, request *http.Request) {
+		ids = append(ids, RequestIDFromContext(request.Context()))
+		assert.NoError(t, FromContext(request.Context()).Infos("handled"),
+			"Unexpected print error")
+	}))
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request = request.WithContext(NewContext(request.Context(), logger))
+	request.Header.Set("X-Request-Id", "upstream-1")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, []string { "upstream-1" }, ids, "Unexpected request ID")
+	assert.Equal(t, "upstream-1", recorder.Header().Get(RequestIDHeader),
+		"Unexpected echoed request ID")
+	assert.Contains(t, writer.String(),
+		`"handled" {"request_id": "upstream-1"}`, "Unexpected output data")
+
+	for _, id := range []string { "", "bad id", "bad\n", strings.Repeat("x", 129) } {
+		request.Header.Set(RequestIDHeader, id)
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		generated := ids[len(ids) - 1]
+		assert.Len(t, generated, 26, "Unexpected generated request ID")
+		assert.Equal(t, generated, recorder.Header().Get(RequestIDHeader),
+			"Unexpected echoed request ID")
+	}
+
+	middleware, err = NewRequestIDOption().UseHeader("x-trace").
+		UseField("trace").UseGenerator(func() string { return "generated" }).
+		DisableEcho().Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.Equal(t, "X-Trace", middleware.Header(), "Unexpected header")
+	assert.False(t, middleware.Echo(), "Unexpected echo")
+	assert.Equal(t, "generated", middleware.Resolve(""),
+		"Unexpected request ID")
+	ctx := middleware.WithContext(context.Background(), "t1")
+	assert.Equal(t, "t1", RequestIDFromContext(ctx), "Unexpected request ID")
+	assert.Equal(t, []Field { String("trace", "t1") }, ContextFields(ctx),
+		"Unexpected context fields")
+	assert.Empty(t, RequestIDFromContext(context.Background()),
+		"Unexpected request ID")
+
+	_, err = NewRequestIDOption().UseMaxLength(0).Build()
+	assert.ErrorIs(t, err, ErrInvalidOption, "Unexpected option error")
+}
@@ -0,0 +1,384 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// LogFile is a structure that contains the information of a rotated log
+// file found by a retention manager.
+type LogFile struct {
+	// Path represents the path of the log file.
+	Path string
+
+	// Size represents the number of bytes of the log file.
+	Size int64
+
+	// Start represents the start of the time window covered by the log
+	// file, which is the modification time of the previous log file. It is
+	// the zero time for the oldest log file.
+	Start time.Time
+
+	// End represents the end of the time window covered by the log file,
+	// which is the modification time of the log file.
+	End time.Time
+}
+
+// Overlaps returns true if the time window covered by the log file
+// overlaps the given time window, otherwise returns false.
+func (f LogFile) Overlaps(start, end time.Time) bool {
+	return !f.End.Before(start) && !f.Start.After(end)
+}
+
+// RetentionHook is the type of function called before a log file is
+// deleted by a retention manager (for example: to upload the log file to
+// an object storage). If the function returns an error, the log file is
+// not deleted, and it is tried again by the next run.
+type RetentionHook func(file LogFile) error
+
+// RetentionManager is the structure of a retention manager instance.
+//
+// The retention manager scans a log directory for the rotated log files
+// that match a pattern, indexes them by the time windows they cover, and
+// then deletes the oldest log files that exceed the age, total size or
+// count retention policies. The policies are applied on a schedule, and
+// can be applied manually by calling the Apply function.
+//
+// Please note that the pattern must not match the log file being written,
+// otherwise the log file may be deleted while it is used.
+type RetentionManager struct {
+	directory string
+	pattern string
+	maxAge time.Duration
+	maxSize int64
+	maxCount int
+	before RetentionHook
+	handler func(err error)
+
+	mutex sync.Mutex
+	context context.Context
+	contextCancel context.CancelFunc
+	contextWaitGroup *sync.WaitGroup
+}
+
+// Index scans the log directory, and then returns the log files that match
+// the pattern, sorted from the oldest to the newest, and any errors
+// encountered.
+func (m *RetentionManager) Index() ([]LogFile, error) {
+	paths, err := filepath.Glob(filepath.Join(m.directory, m.pattern))
+	if err != nil {
+		return nil, err
+	}
+	files := make([]LogFile, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		if !info.Mode().IsRegular() {
+			continue
+		}
+		files = append(files, LogFile {
+			Path: path,
+			Size: info.Size(),
+			End: info.ModTime(),
+		})
+	}
+	sort.SliceStable(files, func(i, j int) bool {
+		if files[i].End.Equal(files[j].End) {
+			return files[i].Path < files[j].Path
+		}
+		return files[i].End.Before(files[j].End)
+	})
+	for index := 1; index < len(files); index++ {
+		files[index].Start = files[index - 1].End
+	}
+	return files, nil
+}
+
+// Window returns the log files that cover the given time window, sorted
+// from the oldest to the newest, and any errors encountered. It is usually
+// used to find the log files of an incident.
+func (m *RetentionManager) Window(start, end time.Time) ([]LogFile, error) {
+	files, err := m.Index()
+	if err != nil {
+		return nil, err
+	}
+	matched := files[ : 0]
+	for _, file := range files {
+		if file.Overlaps(start, end) {
+			matched = append(matched, file)
+		}
+	}
+	return matched, nil
+}
+
+// expired returns the log files that exceed the retention policies, from
+// the oldest to the newest. The newest log files are retained first.
+func (m *RetentionManager) expired(files []LogFile, now time.Time) []LogFile {
+	var size int64
+	var count int
+	for index := len(files) - 1; index >= 0; index-- {
+		file := files[index]
+		if m.maxAge > 0 && now.Sub(file.End) > m.maxAge {
+			return files[ : index + 1]
+		}
+		if m.maxCount > 0 && count + 1 > m.maxCount {
+			return files[ : index + 1]
+		}
+		if m.maxSize > 0 && size + file.Size > m.maxSize {
+			return files[ : index + 1]
+		}
+		size += file.Size
+		count++
+	}
+	return nil
+}
+
+// Apply applies the retention policies once, and then returns the log
+// files that have been deleted and any errors encountered. Log files that
+// the retention hook fails for, or that can not be deleted, are retained
+// and their errors are returned as a MultiError.
+func (m *RetentionManager) Apply() ([]LogFile, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	files, err := m.Index()
+	if err != nil {
+		return nil, err
+	}
+	var deleted []LogFile
+	var errs MultiError
+	for _, file := range m.expired(files, time.Now()) {
+		if m.before != nil {
+			if err := m.before(file); err != nil {
+				errs = errs.Append(&os.PathError {
+					Op: "retain",
+					Path: file.Path,
+					Err: err,
+				})
+				continue
+			}
+		}
+		if err := os.Remove(file.Path); err != nil && !os.IsNotExist(err) {
+			errs = errs.Append(err)
+			continue
+		}
+		deleted = append(deleted, file)
+	}
+	return deleted, errs.ErrorOrNil()
+}
+
+// run applies the retention policies on the given interval until the
+// retention manager is closed.
+func (m *RetentionManager) run(interval time.Duration) {
+	defer m.contextWaitGroup.Done()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.context.Done():
+			return
+		case <-ticker.C:
+			if _, err := m.Apply(); err != nil && m.handler != nil {
+				m.handler(err)
+			}
+		}
+	}
+}
+
+// Close stops applying the retention policies on the schedule. It does
+// not delete any log files.
+func (m *RetentionManager) Close() error {
+	m.contextCancel()
+	m.contextWaitGroup.Wait()
+	return nil
+}
+
+// RetentionManagerOption is a structure that contains retention manager
+// options.
+type RetentionManagerOption struct {
+	// Directory represents the log directory that contains the rotated log
+	// files. This option is required.
+	Directory string
+
+	// Pattern represents the glob pattern (see the filepath.Match function)
+	// of the names of the rotated log files within the log directory. It
+	// must not match the log file being written. If not provided, the
+	// default value is "*.log.*".
+	Pattern string
+
+	// MaxAge represents the maximum age of the rotated log files, which is
+	// the duration since their modification time. If the value is 0, the
+	// age is unlimited. If not provided, the default value is 0.
+	MaxAge time.Duration
+
+	// MaxSize represents the maximum total number of bytes of the rotated
+	// log files. If the value is 0, the total size is unlimited. If not
+	// provided, the default value is 0.
+	MaxSize int64
+
+	// MaxCount represents the maximum number of the rotated log files. If
+	// the value is 0, the count is unlimited. If not provided, the default
+	// value is 0.
+	MaxCount int
+
+	// Interval represents the interval at which the retention policies are
+	// applied. If the value is 0, the retention policies are only applied
+	// by calling the Apply function. If not provided, the default value is
+	// 1 hour.
+	Interval time.Duration
+
+	// BeforeDelete represents the function called before a log file is
+	// deleted. For details, please refer to the comment section of the
+	// RetentionHook type. If not provided, the default value is nil.
+	BeforeDelete RetentionHook
+
+	// ErrorHandler represents the function called with the errors
+	// encountered by the scheduled runs. If not provided, the errors are
+	// discarded.
+	ErrorHandler func(err error)
+}
+
+// UseDirectory uses the given directory and pattern as the values of the
+// options Directory and Pattern. For details, please refer to the comment
+// section of these options. Then return to the option instance itself.
+func (o *RetentionManagerOption) UseDirectory(directory, pattern string) *RetentionManagerOption {
+	o.Directory = directory
+	o.Pattern = pattern
+	return o
+}
+
+// UseMaxAge uses the given age as the value of the option MaxAge. Then
+// return to the option instance itself.
+func (o *RetentionManagerOption) UseMaxAge(age time.Duration) *RetentionManagerOption {
+	o.MaxAge = age
+	return o
+}
+
+// UseMaxSize uses the given number of bytes as the value of the option
+// MaxSize. Then return to the option instance itself.
+func (o *RetentionManagerOption) UseMaxSize(size int64) *RetentionManagerOption {
+	o.MaxSize = size
+	return o
+}
+
+// UseMaxCount uses the given number of log files as the value of the
+// option MaxCount. Then return to the option instance itself.
+func (o *RetentionManagerOption) UseMaxCount(count int) *RetentionManagerOption {
+	o.MaxCount = count
+	return o
+}
+
+// UseInterval uses the given interval as the value of the option
+// Interval. Then return to the option instance itself.
+func (o *RetentionManagerOption) UseInterval(interval time.Duration) *RetentionManagerOption {
+	o.Interval = interval
+	return o
+}
+
+// UseBeforeDelete uses the given function as the value of the option
+// BeforeDelete. Then return to the option instance itself.
+func (o *RetentionManagerOption) UseBeforeDelete(hook RetentionHook) *RetentionManagerOption {
+	o.BeforeDelete = hook
+	return o
+}
+
+// UseErrorHandler uses the given function as the value of the option
+// ErrorHandler. Then return to the option instance itself.
+func (o *RetentionManagerOption) UseErrorHandler(handler func(err error)) *RetentionManagerOption {
+	o.ErrorHandler = handler
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *RetentionManagerOption) Validate() error {
+	if len(o.Directory) == 0 {
+		return newOptionError("Directory", "must not be empty", nil)
+	}
+	if len(o.Pattern) == 0 {
+		return newOptionError("Pattern", "must not be empty", nil)
+	}
+	if _, err := filepath.Match(o.Pattern, ""); err != nil {
+		return newOptionError("Pattern", "must be a valid glob pattern",
+			err)
+	}
+	if o.MaxAge < 0 {
+		return newOptionError("MaxAge", "must not be negative", nil)
+	}
+	if o.MaxSize < 0 {
+		return newOptionError("MaxSize", "must not be negative", nil)
+	}
+	if o.MaxCount < 0 {
+		return newOptionError("MaxCount", "must not be negative", nil)
+	}
+	if o.Interval < 0 {
+		return newOptionError("Interval", "must not be negative", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a retention manager instance, which applies
+// the retention policies on the schedule until it is closed.
+func (o *RetentionManagerOption) Build() (*RetentionManager, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	context, contextCancel := context.WithCancel(context.Background())
+	instance := &RetentionManager {
+		directory: o.Directory,
+		pattern: o.Pattern,
+		maxAge: o.MaxAge,
+		maxSize: o.MaxSize,
+		maxCount: o.MaxCount,
+		before: o.BeforeDelete,
+		handler: o.ErrorHandler,
+		context: context,
+		contextCancel: contextCancel,
+		contextWaitGroup: &sync.WaitGroup { },
+	}
+	if o.Interval > 0 {
+		instance.contextWaitGroup.Add(1)
+		go instance.run(o.Interval)
+	}
+	return instance, nil
+}
+
+// NewRetentionManagerOption creates and returns a retention manager option
+// instance with default option values.
+func NewRetentionManagerOption() *RetentionManagerOption {
+	return &RetentionManagerOption {
+		Pattern: "*.log.*",
+		Interval: time.Hour,
+	}
+}
@@ -0,0 +1,93 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionManager(t *testing.T) {
+	directory := t.TempDir()
+	now := time.Now()
+	for index := 0; index < 5; index++ {
+		name := filepath.Join(directory, "app.log." + strconv.Itoa(index))
+		assert.NoError(t, os.WriteFile(name, make([]byte, 100), 0644),
+			"Unexpected write error")
+		modified := now.Add(-time.Hour * time.Duration(index * 24))
+		assert.NoError(t, os.Chtimes(name, modified, modified),
+			"Unexpected chtimes error")
+	}
+	active := filepath.Join(directory, "app.log")
+	assert.NoError(t, os.WriteFile(active, nil, 0644), "Unexpected write error")
+
+	var uploaded []string
+	manager, err := NewRetentionManagerOption().
+		UseDirectory(directory, "app.log.*").UseInterval(0).
+		UseMaxAge(time.Hour * 24 * 3 + time.Minute).UseMaxSize(350).
+		UseBeforeDelete(func(file LogFile) error {
+			if filepath.Base(file.Path) == "app.log.3" {
+				return errors.New("upload failed")
+			}
+			uploaded = append(uploaded, filepath.Base(file.Path))
+			return nil
+		}).Build()
+	assert.NoError(t, err, "Unexpected build error")
+	defer manager.Close()
+
+	files, err := manager.Index()
+	assert.NoError(t, err, "Unexpected index error")
+	assert.Len(t, files, 5, "Unexpected indexed files")
+	assert.Equal(t, "app.log.4", filepath.Base(files[0].Path),
+		"Unexpected oldest file")
+	assert.True(t, files[0].Start.IsZero(), "Unexpected time window")
+	assert.Equal(t, files[0].End, files[1].Start, "Unexpected time window")
+
+	files, err = manager.Window(now.Add(-time.Hour * 36),
+		now.Add(-time.Hour * 12))
+	assert.NoError(t, err, "Unexpected window error")
+	assert.Len(t, files, 2, "Unexpected window files")
+
+	// The age policy expires app.log.4, and the size policy expires
+	// app.log.3, which is retained because the hook fails.
+	deleted, err := manager.Apply()
+	assert.Error(t, err, "Unexpected apply result")
+	assert.Len(t, deleted, 1, "Unexpected deleted files")
+	assert.Equal(t, []string { "app.log.4" }, uploaded,
+		"Unexpected uploaded files")
+
+	files, err = manager.Index()
+	assert.NoError(t, err, "Unexpected index error")
+	assert.Len(t, files, 4, "Unexpected indexed files")
+	_, err = os.Stat(active)
+	assert.NoError(t, err, "Unexpected deletion of the active file")
+
+	_, err = NewRetentionManagerOption().Build()
+	assert.Error(t, err, "Unexpected build result")
+}
@@ -0,0 +1,373 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationTimeLayout is the layout of the times in the names of the backup
+// files of a file synchronizer, which are the times the backup files were
+// rotated in UTC. For example, the log file "app.log" rotated at noon on
+// January 2, 2006 is renamed to "app-2006-01-02T12-00-00.000.log".
+const RotationTimeLayout = "2006-01-02T15-04-05.000"
+
+// rotationGroup is a structure that contains the state shared by the file
+// rotators of the same file, such as the output and error output of a
+// logger that both use the file (see the WithFile functional option). When
+// any of them rotates the file, the others open the new file before their
+// next write, so that no log entry is written to a backup file after it
+// has been rotated.
+type rotationGroup struct {
+	mutex sync.Mutex
+	name string
+	size int64
+	generation uint64
+	references int
+}
+
+// rotationGroups contains the rotation groups of the opened files, keyed
+// by their absolute names.
+var rotationGroups = struct {
+	sync.Mutex
+	groups map[string]*rotationGroup
+} {
+	groups: make(map[string]*rotationGroup),
+}
+
+// joinRotationGroup returns the rotation group of the file with the given
+// name, creating it if it does not exist, and adds a reference to it. The
+// given size is the current size of the file.
+func joinRotationGroup(name string, size int64) *rotationGroup {
+	if name == os.DevNull {
+		return &rotationGroup { name: name, references: 1 }
+	}
+	key, err := filepath.Abs(name)
+	if err != nil {
+		key = name
+	}
+	rotationGroups.Lock()
+	defer rotationGroups.Unlock()
+	group, ok := rotationGroups.groups[key]
+	if !ok {
+		group = &rotationGroup { name: key }
+		rotationGroups.groups[key] = group
+	}
+	group.mutex.Lock()
+	group.size = size
+	group.references++
+	group.mutex.Unlock()
+	return group
+}
+
+// leave removes a reference to the rotation group, and removes the group
+// when it is no longer referenced.
+func (g *rotationGroup) leave() {
+	rotationGroups.Lock()
+	defer rotationGroups.Unlock()
+	g.mutex.Lock()
+	g.references--
+	references := g.references
+	g.mutex.Unlock()
+	if references == 0 && rotationGroups.groups[g.name] == g {
+		delete(rotationGroups.groups, g.name)
+	}
+}
+
+// fileRotator is the writer of a file synchronizer. It writes to the file,
+// and renames the file to a backup file and opens a new file when the file
+// would exceed the maximum size. For details, please refer to the comment
+// section of the MaxSize option of the FileSyncerOption structure.
+type fileRotator struct {
+	name string
+	flag int
+	prepare func(handle *os.File) error
+	file *os.File
+	verifier *fileVerifier
+	group *rotationGroup
+	generation uint64
+	maxSize int64
+	maxBackups int
+	maxAge time.Duration
+}
+
+// newFileRotator creates and returns a file rotator of the given opened
+// file, and then returns any errors encountered.
+func newFileRotator(name string, flag int, handle *os.File,
+	verifier *fileVerifier, option *FileSyncerOption) (*fileRotator, error) {
+	info, err := handle.Stat()
+	if err != nil {
+		return nil, err
+	}
+	// The options are copied, so that changing them after the file
+	// synchronizer is built does not affect the rotated files.
+	copied := *option
+	group := joinRotationGroup(name, info.Size())
+	group.mutex.Lock()
+	generation := group.generation
+	group.mutex.Unlock()
+	return &fileRotator {
+		name: name,
+		flag: flag,
+		prepare: copied.prepare,
+		file: handle,
+		verifier: verifier,
+		group: group,
+		generation: generation,
+		maxSize: option.MaxSize,
+		maxBackups: option.MaxBackups,
+		maxAge: option.MaxAge,
+	}, nil
+}
+
+// writer returns the writer of the current file.
+func (r *fileRotator) writer() io.Writer {
+	if r.verifier != nil {
+		return r.verifier
+	}
+	return r.file
+}
+
+// Write rotates the file if writing the data of the given buffer slice
+// would make it exceed the maximum size, and then writes the data to the
+// file. The data is never split across files. If another file rotator of
+// the same file has rotated it, the new file is opened first.
+//
+// Finally, it returns the number of bytes actually written and any
+// errors encountered.
+func (r *fileRotator) Write(buffer []byte) (int, error) {
+	group := r.group
+	group.mutex.Lock()
+	defer group.mutex.Unlock()
+	if r.maxSize > 0 && group.size > 0 &&
+		group.size + int64(len(buffer)) > r.maxSize {
+		if err := r.rotateLocked(); err != nil {
+			return 0, err
+		}
+	}
+	if r.generation != group.generation {
+		if err := r.reopen(); err != nil {
+			return 0, err
+		}
+	}
+	size, err := r.writer().Write(buffer)
+	group.size += int64(size)
+	return size, err
+}
+
+// backupName returns the name of the backup file of the file rotated at
+// the given time. If a backup file with the name already exists (for
+// example: the file was rotated twice within a millisecond), the time is
+// advanced by a millisecond until the name is unused.
+func (r *fileRotator) backupName(at time.Time) string {
+	extension := filepath.Ext(r.name)
+	prefix := strings.TrimSuffix(r.name, extension)
+	for {
+		name := prefix + "-" + at.UTC().Format(RotationTimeLayout) + extension
+		if _, err := os.Lstat(name); os.IsNotExist(err) {
+			return name
+		}
+		at = at.Add(time.Millisecond)
+	}
+}
+
+// open opens and prepares the file with the name of the file rotator, and
+// then returns the handle, the size of the file and any errors
+// encountered.
+func (r *fileRotator) open() (*os.File, int64, error) {
+	handle, err := os.OpenFile(r.name, r.flag, os.ModeAppend)
+	if err != nil {
+		return nil, 0, err
+	}
+	if err = r.prepare(handle); err != nil {
+		_ = handle.Close()
+		return nil, 0, err
+	}
+	info, err := handle.Stat()
+	if err != nil {
+		_ = handle.Close()
+		return nil, 0, err
+	}
+	return handle, info.Size(), nil
+}
+
+// swap replaces the file of the file rotator with the given opened file of
+// the given size, and then closes the previous file.
+func (r *fileRotator) swap(handle *os.File, size int64) {
+	previous := r.file
+	r.file = handle
+	if r.verifier != nil {
+		r.verifier.reset(handle, size)
+	}
+	_ = previous.Close()
+}
+
+// reopen opens the file again after another file rotator of the same file
+// has rotated it, and then returns any errors encountered. If the file
+// cannot be opened, the previous file is kept. The caller must hold the
+// mutex of the rotation group.
+func (r *fileRotator) reopen() error {
+	handle, size, err := r.open()
+	if err != nil {
+		return err
+	}
+	r.swap(handle, size)
+	r.generation = r.group.generation
+	return nil
+}
+
+// rotate renames the file to a backup file, opens a new file with the same
+// name, and then removes the backup files that exceed the retention limits.
+// For details, please refer to the comment section of the rotateLocked
+// function.
+func (r *fileRotator) rotate() error {
+	r.group.mutex.Lock()
+	defer r.group.mutex.Unlock()
+	return r.rotateLocked()
+}
+
+// rotateLocked renames the file to a backup file, opens a new file with
+// the same name, and then removes the backup files that exceed the
+// retention limits. The new file is opened before the previous file is
+// closed, so if the file cannot be renamed or opened, the file rotator
+// keeps writing to the previous file and the error is returned. The caller
+// must hold the mutex of the rotation group.
+//
+// Finally, any errors encountered are returned.
+func (r *fileRotator) rotateLocked() error {
+	if r.name == os.DevNull {
+		return nil
+	}
+	if r.generation != r.group.generation {
+		// Another file rotator has just rotated the file.
+		return r.reopen()
+	}
+	backup := r.backupName(time.Now())
+	if err := os.Rename(r.name, backup); err != nil {
+		return err
+	}
+	handle, size, err := r.open()
+	if err != nil {
+		// The file is renamed back, so that the previous file, which is
+		// still used, keeps its name.
+		_ = os.Rename(backup, r.name)
+		return err
+	}
+	r.swap(handle, size)
+	r.group.generation++
+	r.group.size = size
+	r.generation = r.group.generation
+	r.prune(time.Now())
+	return nil
+}
+
+// close closes the file and leaves the rotation group, and then returns
+// any errors encountered.
+func (r *fileRotator) close() error {
+	r.group.mutex.Lock()
+	err := r.file.Close()
+	r.group.mutex.Unlock()
+	r.group.leave()
+	return err
+}
+
+// prune removes the backup files that exceed the maximum number of backup
+// files or are older than the maximum age at the given time. Errors are
+// ignored, so that the backup files that cannot be removed are tried
+// again by the next rotation.
+func (r *fileRotator) prune(now time.Time) {
+	if r.maxBackups <= 0 && r.maxAge <= 0 {
+		return
+	}
+	extension := filepath.Ext(r.name)
+	prefix := filepath.Base(strings.TrimSuffix(r.name, extension)) + "-"
+	directory := filepath.Dir(r.name)
+	entries, err := os.ReadDir(directory)
+	if err != nil {
+		return
+	}
+	type backup struct {
+		name string
+		time time.Time
+	}
+	var backups []backup
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, prefix) ||
+			!strings.HasSuffix(name, extension) {
+			continue
+		}
+		stamp := name[len(prefix) : len(name) - len(extension)]
+		at, err := time.Parse(RotationTimeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup {
+			name: filepath.Join(directory, name),
+			time: at,
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].time.After(backups[j].time)
+	})
+	for index, backup := range backups {
+		if (r.maxBackups > 0 && index >= r.maxBackups) ||
+			(r.maxAge > 0 && now.Sub(backup.time) > r.maxAge) {
+			_ = os.Remove(backup.name)
+		}
+	}
+}
+
+// Rotate writes the internally cached data to the file, renames the file
+// to a backup file named with the current time, opens a new file with the
+// same name, and then removes the backup files that exceed the MaxBackups
+// and MaxAge options. It is usually used to rotate the file on a schedule
+// or on a signal, and can be passed to the Rotate function of a lifecycle
+// synchronizer. For details, please refer to the comment section of the
+// MaxSize option of the FileSyncerOption structure.
+//
+// If the file synchronizer uses os.DevNull, it does nothing.
+//
+// Finally, any errors encountered are returned.
+func (s *FileSyncer) Rotate() error {
+	if s.mutex != nil {
+		s.mutex.LockAndSuspend()
+	}
+	var err error
+	if len(s.buffer) > 0 {
+		_, err = s.flush()
+	}
+	if err == nil {
+		err = s.rotator.rotate()
+	}
+	if s.mutex != nil {
+		s.mutex.UnlockAndResume()
+	}
+	return err
+}
@@ -23,6 +23,8 @@
 package santa
 
 import (
+	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 )
@@ -45,6 +47,29 @@ type Sampler interface {
 	Sample(entry *Entry) bool
 }
 
+// SamplerCloner is the public interface of samplers whose state can be
+// isolated between copies of a logger.
+//
+// By default, all copies of a logger share the same sampler instance and
+// therefore the same sampler state (for example: rate counters), so a rate
+// budget is respected across all copies. If the SamplingOption.Isolated
+// option is enabled, each copy created by the Duplicate function uses a
+// clone of the sampler instead.
+type SamplerCloner interface {
+	Sampler
+
+	// CloneSampler creates and returns a new sampler instance with the same
+	// configuration and a fresh state.
+	CloneSampler() Sampler
+}
+
+// DropCounter is the public interface of samplers that count the log
+// entries they have discarded.
+type DropCounter interface {
+	// Dropped returns the number of log entries discarded by the sampler.
+	Dropped() uint64
+}
+
 type textSamplerCounter struct {
 	// count represents the value of the counter.
 	count uint64
@@ -74,6 +99,7 @@ type textSamplerCounter struct {
 // of the TextSamplerOption.Counters option. More counters means more memory
 // resources will be used.
 type TextSampler struct {
+	dropped uint64
 	span LevelSpan
 	tick int64
 	first uint64
@@ -115,7 +141,7 @@ func (s *TextSampler) Sample(entry *Entry) bool {
 
 	index := s.hash64(parser.SampleText()) % uint64(len(s.counters))
 	count := atomic.LoadUint64(&s.counters[index].count)
-	clock := entry.Time.UnixNano()
+	clock := entry.clock()
 	after := atomic.LoadInt64(&s.counters[index].after)
 	
 	// If it has been more than or equal to one sampling period since the
@@ -143,12 +169,30 @@ func (s *TextSampler) Sample(entry *Entry) bool {
 	// a sampling period, and the condition of printing once after the interval
 	// <s.thereafter> times is not met, it will be discarded.
 	if count > s.first && (count - s.first) % s.thereafter != 0 {
+		atomic.AddUint64(&s.dropped, 1)
 		return false
 	}
 
 	return true
 }
 
+// Dropped returns the number of log entries discarded by the sampler.
+func (s *TextSampler) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// CloneSampler creates and returns a new text sampler instance with the
+// same configuration and fresh counters.
+func (s *TextSampler) CloneSampler() Sampler {
+	return &TextSampler {
+		span: s.span,
+		tick: s.tick,
+		first: s.first,
+		thereafter: s.thereafter,
+		counters: make([]textSamplerCounter, len(s.counters)),
+	}
+}
+
 // TextSamplerOption is a structure containing text sampler options.
 type TextSamplerOption struct {
 	// Span represents the log level span for which sampling strategy
@@ -185,6 +229,26 @@ type TextSamplerOption struct {
 	Counters uint64
 }
 
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *TextSamplerOption) Validate() error {
+	if o.Span.Start > o.Span.End {
+		return newOptionError("Span",
+			"start level must not be greater than end level", nil)
+	}
+	if o.Tick <= 0 {
+		return newOptionError("Tick", "must be greater than 0", nil)
+	}
+	if o.Thereafter == 0 {
+		return newOptionError("Thereafter", "must be greater than 0", nil)
+	}
+	if o.Counters == 0 {
+		return newOptionError("Counters", "must be greater than 0", nil)
+	}
+	return nil
+}
+
 // Build builds and returns a text sampler instance using the option value.
 //
 // Please note that this function does not check the validity of the option
@@ -249,3 +313,919 @@ func NewTextSamplerOption() *TextSamplerOption {
 func NewTextSampler() (*TextSampler, error) {
 	return NewTextSamplerOption().Build()
 }
+
+// RetainingSampler is the public interface of samplers that retain some of
+// the log entries they discard, and release them to be output later.
+//
+// After each log entry has been sampled, the logger calls the Release
+// function, and outputs each released log entry with its exporters. The
+// standard logger also releases all retained log entries when it is
+// synchronized or closed.
+type RetainingSampler interface {
+	Sampler
+
+	// Release calls the given handler with each retained log entry that
+	// is due for output, or with all retained log entries if the given
+	// force is true, and removes them from the sampler.
+	Release(handler func(entry *Entry), force bool)
+}
+
+// retainMessage returns a copy of the given log entry message that does
+// not share any pooled data with it, so that a log entry can be retained
+// after its original message has been returned to a pool. Structured and
+// template messages are copied with their fields and parameters, so that
+// the hooks and the field options of the encoder (see the FieldOption
+// structure) still apply to them when they are released. Other messages
+// are not pooled, and are returned as they are.
+func retainMessage(message Message) Message {
+	switch message := message.(type) {
+	case *StringMessage:
+		if message != nil {
+			return *message
+		}
+	case StructMessage:
+		return StructMessage {
+			Text: message.Text,
+			Fields: append(ElementObject(nil), message.Fields...),
+		}
+	case *StructMessage:
+		if message != nil {
+			return retainMessage(*message)
+		}
+	case TemplateMessage:
+		return TemplateMessage {
+			Template: message.Template,
+			Args: append([]interface { }(nil), message.Args...),
+		}
+	case *TemplateMessage:
+		if message != nil {
+			return retainMessage(*message)
+		}
+	case NamedTemplateMessage:
+		return NamedTemplateMessage {
+			Template: message.Template,
+			Args: append([]interface { }(nil), message.Args...),
+		}
+	case *NamedTemplateMessage:
+		if message != nil {
+			return retainMessage(*message)
+		}
+	case LocalizedMessage:
+		message.Fields = append(ElementObject(nil), message.Fields...)
+		return message
+	}
+	return message
+}
+
+// retainEntry returns a copy of the given log entry that does not share
+// any pooled data with it, so that it can be output later.
+func retainEntry(entry *Entry) *Entry {
+	retained := *entry
+	retained.Context = nil
+	// The pooled objects owned by the log entry are not shared with the
+	// copy, which is not returned to the pool.
+	retained.text = nil
+	retained.labels = nil
+	if entry.labels != nil && entry.labels.owns(entry.Labels) {
+		retained.Labels = NewSerializedLabels(entry.Labels.labels...)
+	}
+	retained.Message = retainMessage(entry.Message)
+	return &retained
+}
+
+// headTailWindow is a structure that contains the state of the sampling
+// window of a key of the head and tail sampler.
+type headTailWindow struct {
+	end int64
+	count uint64
+	tail []*Entry
+	next int
+}
+
+// appendTail appends the retained log entries of the window to the given
+// slice in the order in which they were discarded, removes them from the
+// window, and then returns the appended slice.
+func (w *headTailWindow) appendTail(entries []*Entry) []*Entry {
+	entries = append(entries, w.tail[w.next : ]...)
+	entries = append(entries, w.tail[ : w.next]...)
+	w.tail = nil
+	w.next = 0
+	return entries
+}
+
+// HeadTailSampler is the structure of the head and tail sampler instance.
+//
+// The head and tail sampler groups log entries by the text of their
+// messages (see the TextSampleParser interface) and samples each group in
+// windows of a fixed duration. In each window, the first Head log entries
+// of a group are always output, and the log entries in the middle of the
+// window are sampled once every Thereafter log entries. The last Tail log
+// entries discarded in the window are retained and released when the
+// window ends, so bursts keep both their beginning and end for debugging
+// while the volume is still bounded.
+//
+// Retained log entries are released when the logger outputs a log entry
+// after the window ends, or when the logger is synchronized or closed.
+// Their messages are copied when they are retained, and pass through the
+// hooks and the encoders like any other log entry when they are released.
+//
+// The number of groups tracked at the same time is bounded by the Keys
+// option, and the log entries of other groups are not sampled.
+type HeadTailSampler struct {
+	due int64
+	dropped uint64
+	span LevelSpan
+	window int64
+	head uint64
+	tail int
+	thereafter uint64
+	keys int
+
+	mutex sync.Mutex
+	windows map[string]*headTailWindow
+	pending []*Entry
+}
+
+// Sample checks whether a given log entry needs to be sampled. It returns
+// true if needed, otherwise it returns false.
+func (s *HeadTailSampler) Sample(entry *Entry) bool {
+	if !s.span.Contains(entry.Level) {
+		return true
+	}
+	parser, ok := entry.Message.(TextSampleParser)
+	if !ok {
+		return true
+	}
+	text := parser.SampleText()
+	clock := entry.clock()
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	window, ok := s.windows[text]
+	if !ok {
+		if len(s.windows) >= s.keys {
+			s.sweep(clock)
+		}
+		if len(s.windows) >= s.keys {
+			return true
+		}
+		window = &headTailWindow { }
+		s.windows[text] = window
+	}
+	if window.end <= clock {
+		// The retained log entries of the ended window are moved to the
+		// pending log entries, which are released by the next release.
+		if len(window.tail) > 0 {
+			s.pending = window.appendTail(s.pending)
+			atomic.StoreInt64(&s.due, window.end)
+		}
+		window.end = clock + s.window
+		window.count = 0
+	}
+	window.count++
+	if window.count <= s.head {
+		return true
+	}
+	if s.thereafter > 0 && (window.count - s.head) % s.thereafter == 0 {
+		return true
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	if s.tail == 0 {
+		return false
+	}
+	retained := retainEntry(entry)
+	if len(window.tail) < s.tail {
+		window.tail = append(window.tail, retained)
+	} else {
+		window.tail[window.next] = retained
+		window.next = (window.next + 1) % s.tail
+	}
+	due := atomic.LoadInt64(&s.due)
+	if due == 0 || window.end < due {
+		atomic.StoreInt64(&s.due, window.end)
+	}
+	return false
+}
+
+// sweep removes the windows that have ended before the given time and do
+// not retain any log entries.
+//
+// Please note that this function is not thread-safe.
+func (s *HeadTailSampler) sweep(clock int64) {
+	for text, window := range s.windows {
+		if window.end <= clock && len(window.tail) == 0 {
+			delete(s.windows, text)
+		}
+	}
+}
+
+// Release calls the given handler with the retained log entries of each
+// window that has ended, or of all windows if the given force is true, in
+// the order in which they were discarded. For details, please refer to
+// the comment section of the RetainingSampler interface.
+func (s *HeadTailSampler) Release(handler func(entry *Entry), force bool) {
+	due := atomic.LoadInt64(&s.due)
+	if due == 0 {
+		return
+	}
+	clock := time.Now().UnixNano()
+	if !force && clock < due {
+		return
+	}
+	var released []*Entry
+
+	s.mutex.Lock()
+	released, s.pending = s.pending, nil
+	s.sweep(clock)
+	due = 0
+	for _, window := range s.windows {
+		if len(window.tail) == 0 {
+			continue
+		}
+		if !force && window.end > clock {
+			if due == 0 || window.end < due {
+				due = window.end
+			}
+			continue
+		}
+		released = window.appendTail(released)
+	}
+	atomic.StoreInt64(&s.due, due)
+	s.mutex.Unlock()
+
+	for index := 0; index < len(released); index++ {
+		handler(released[index])
+	}
+}
+
+// Dropped returns the number of log entries discarded by the sampler,
+// including the log entries that are retained.
+func (s *HeadTailSampler) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// CloneSampler creates and returns a new head and tail sampler instance
+// with the same configuration and a fresh state.
+func (s *HeadTailSampler) CloneSampler() Sampler {
+	return &HeadTailSampler {
+		span: s.span,
+		window: s.window,
+		head: s.head,
+		tail: s.tail,
+		thereafter: s.thereafter,
+		keys: s.keys,
+		windows: make(map[string]*headTailWindow),
+	}
+}
+
+// HeadTailSamplerOption is a structure that contains options for the head
+// and tail sampler.
+type HeadTailSamplerOption struct {
+	// Span represents the log level span of the sampled log entries. Log
+	// entries whose levels are not in the span are not sampled. If not
+	// provided, the default value is INFO level to WARNING level.
+	Span LevelSpan
+
+	// Window represents the duration of each sampling window. If not
+	// provided, the default value is 1 second.
+	Window time.Duration
+
+	// Head represents the number of log entries of a group that are always
+	// output at the beginning of each window. If not provided, the default
+	// value is 10.
+	Head uint64
+
+	// Tail represents the number of the last discarded log entries of a
+	// group that are retained and output at the end of each window. If not
+	// provided, the default value is 10.
+	Tail int
+
+	// Thereafter represents that a log entry in the middle of a window is
+	// output once every Thereafter log entries. If the value is 0, all log
+	// entries in the middle of a window are discarded. If not provided,
+	// the default value is 100.
+	Thereafter uint64
+
+	// Keys represents the maximum number of groups tracked at the same
+	// time. If not provided, the default value is 1024.
+	Keys int
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *HeadTailSamplerOption) Validate() error {
+	if o.Span.Start > o.Span.End {
+		return newOptionError("Span",
+			"start level must not be greater than end level", nil)
+	}
+	if o.Window <= 0 {
+		return newOptionError("Window", "must be greater than 0", nil)
+	}
+	if o.Tail < 0 {
+		return newOptionError("Tail", "must not be negative", nil)
+	}
+	if o.Keys <= 0 {
+		return newOptionError("Keys", "must be greater than 0", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a head and tail sampler instance.
+func (o *HeadTailSamplerOption) Build() (*HeadTailSampler, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &HeadTailSampler {
+		span: o.Span,
+		window: int64(o.Window),
+		head: o.Head,
+		tail: o.Tail,
+		thereafter: o.Thereafter,
+		keys: o.Keys,
+		windows: make(map[string]*headTailWindow),
+	}, nil
+}
+
+// UseWindow uses the given duration as the value of the option Window.
+// Then return to the option instance itself.
+func (o *HeadTailSamplerOption) UseWindow(window time.Duration) *HeadTailSamplerOption {
+	o.Window = window
+	return o
+}
+
+// UseHeadTail uses the given numbers of log entries as the values of the
+// options Head and Tail. Then return to the option instance itself.
+func (o *HeadTailSamplerOption) UseHeadTail(head uint64, tail int) *HeadTailSamplerOption {
+	o.Head = head
+	o.Tail = tail
+	return o
+}
+
+// UseThereafter uses the given value as the value of the option
+// Thereafter. Then return to the option instance itself.
+func (o *HeadTailSamplerOption) UseThereafter(thereafter uint64) *HeadTailSamplerOption {
+	o.Thereafter = thereafter
+	return o
+}
+
+// NewHeadTailSamplerOption creates and returns a head and tail sampler
+// option instance with default option values.
+func NewHeadTailSamplerOption() *HeadTailSamplerOption {
+	return &HeadTailSamplerOption {
+		Span: LevelSpan {
+			Start: LevelInfo,
+			End: LevelWarning,
+		},
+		Window: time.Second,
+		Head: 10,
+		Tail: 10,
+		Thereafter: 100,
+		Keys: 1024,
+	}
+}
+
+// PressureReporter is the public interface of components that report the
+// backpressure of the log entry pipeline, which is used by the adaptive
+// sampler. For details, please refer to the comment section of the
+// AdaptiveSampler structure.
+type PressureReporter interface {
+	// Pressure returns the current backpressure, from 0 (the pipeline is
+	// idle) to 1 (the pipeline is saturated).
+	Pressure() float64
+}
+
+// PressureFunc is a function type that implements the PressureReporter
+// interface.
+type PressureFunc func() float64
+
+// Pressure calls the function and returns its result.
+func (f PressureFunc) Pressure() float64 {
+	return f()
+}
+
+// clampPressure returns the given pressure limited to the range from 0
+// to 1.
+func clampPressure(pressure float64) float64 {
+	switch {
+	case pressure < 0 || pressure != pressure:
+		return 0
+	case pressure > 1:
+		return 1
+	default:
+		return pressure
+	}
+}
+
+// QueuePressure returns a pressure reporter that reports the ratio of the
+// size of the internal cache of the given synchronizer to the given
+// capacity, which is the saturation of its queue.
+func QueuePressure(syncer BufferedSyncer, capacity int) PressureReporter {
+	return PressureFunc(func() float64 {
+		if capacity <= 0 {
+			return 0
+		}
+		return clampPressure(float64(syncer.Buffered()) / float64(capacity))
+	})
+}
+
+// LatencyPressure returns a pressure reporter that compares the mean of
+// the durations recorded by the given latency histogram since the last
+// report with the given latency budget. The pressure is 0 while the mean
+// is within the budget, and rises to 1 as the mean reaches twice the
+// budget.
+//
+// Please note that the returned pressure reporter is not thread-safe,
+// which is sufficient for the adaptive sampler.
+func LatencyPressure(histogram *LatencyHistogram, budget time.Duration) PressureReporter {
+	count, sum := histogram.Count(), histogram.Sum()
+	return PressureFunc(func() float64 {
+		currentCount, currentSum := histogram.Count(), histogram.Sum()
+		recorded := currentCount - count
+		elapsed := currentSum - sum
+		count, sum = currentCount, currentSum
+		if recorded == 0 || budget <= 0 {
+			return 0
+		}
+		mean := elapsed / time.Duration(recorded)
+		return clampPressure(float64(mean - budget) / float64(budget))
+	})
+}
+
+// adaptiveRateScale is the fixed-point scale of the keep rate of the
+// adaptive sampler.
+const adaptiveRateScale = 1 << 16
+
+// AdaptiveSampler is the structure of the adaptive sampler instance.
+//
+// The adaptive sampler keeps a fraction of the log entries, called the
+// keep rate, and adjusts it to the backpressure reported by one or more
+// pressure reporters (for example: the queue saturation of a synchronizer
+// or the output latency of a logger, see the QueuePressure and
+// LatencyPressure functions). The pressure is evaluated once per interval,
+// when a log entry is sampled. If the highest reported pressure reaches
+// the high watermark, the keep rate is halved, down to the minimum keep
+// rate. If it is below the low watermark, the keep rate is raised by the
+// recovery step, up to keeping all log entries. So the logging overhead
+// is kept within the budget while the pipeline is saturated, and is
+// relaxed when the pipeline recovers.
+//
+// Log entries are kept deterministically (for example: every other log
+// entry with a keep rate of 0.5) rather than randomly. The sampler does not
+// implement the SamplerCloner interface, because its state reflects the
+// pipeline shared by all copies of a logger.
+type AdaptiveSampler struct {
+	count uint64
+	dropped uint64
+	next int64
+	rate uint32
+	evaluating int32
+
+	span LevelSpan
+	reporters []PressureReporter
+	interval int64
+	high float64
+	low float64
+	minimum uint32
+	step uint32
+}
+
+// evaluate evaluates the pressure reported by the pressure reporters and
+// adjusts the keep rate.
+func (s *AdaptiveSampler) evaluate() {
+	pressure := 0.0
+	for index := 0; index < len(s.reporters); index++ {
+		if current := s.reporters[index].Pressure(); current > pressure {
+			pressure = current
+		}
+	}
+	rate := atomic.LoadUint32(&s.rate)
+	switch {
+	case pressure >= s.high:
+		rate /= 2
+		if rate < s.minimum {
+			rate = s.minimum
+		}
+	case pressure < s.low:
+		rate += s.step
+		if rate > adaptiveRateScale {
+			rate = adaptiveRateScale
+		}
+	}
+	atomic.StoreUint32(&s.rate, rate)
+}
+
+// Sample checks whether a given log entry needs to be sampled. It returns
+// true if needed, otherwise it returns false.
+func (s *AdaptiveSampler) Sample(entry *Entry) bool {
+	if !s.span.Contains(entry.Level) {
+		return true
+	}
+	clock := entry.clock()
+	if next := atomic.LoadInt64(&s.next); clock >= next &&
+		atomic.CompareAndSwapInt32(&s.evaluating, 0, 1) {
+		// Only one goroutine evaluates the pressure at a time, and the
+		// pressure reporters are not called concurrently.
+		if atomic.LoadInt64(&s.next) == next {
+			s.evaluate()
+			atomic.StoreInt64(&s.next, clock + s.interval)
+		}
+		atomic.StoreInt32(&s.evaluating, 0)
+	}
+
+	rate := uint64(atomic.LoadUint32(&s.rate))
+	if rate >= adaptiveRateScale {
+		return true
+	}
+	// The log entry is kept if the accumulated keep rate crosses an
+	// integer boundary.
+	count := atomic.AddUint64(&s.count, 1)
+	if (count * rate) / adaptiveRateScale != ((count - 1) * rate) /
+		adaptiveRateScale {
+		return true
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	return false
+}
+
+// Rate returns the current keep rate of the sampler, from the minimum
+// keep rate to 1.
+func (s *AdaptiveSampler) Rate() float64 {
+	return float64(atomic.LoadUint32(&s.rate)) / adaptiveRateScale
+}
+
+// Dropped returns the number of log entries discarded by the sampler.
+func (s *AdaptiveSampler) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// AdaptiveSamplerOption is a structure that contains options for the
+// adaptive sampler.
+type AdaptiveSamplerOption struct {
+	// Span represents the log level span of the sampled log entries. Log
+	// entries whose levels are not in the span are not sampled. If not
+	// provided, the default value is DEBUG level to WARNING level.
+	Span LevelSpan
+
+	// Reporters represents the pressure reporters that report the
+	// backpressure of the pipeline. This option is required.
+	Reporters []PressureReporter
+
+	// Interval represents the interval between two evaluations of the
+	// pressure. If not provided, the default value is 1 second.
+	Interval time.Duration
+
+	// HighWatermark represents the pressure at or above which the keep
+	// rate is halved. If not provided, the default value is 0.8.
+	HighWatermark float64
+
+	// LowWatermark represents the pressure below which the keep rate is
+	// raised. If not provided, the default value is 0.5.
+	LowWatermark float64
+
+	// MinRate represents the minimum keep rate, greater than 0 and not
+	// greater than 1. If not provided, the default value is 0.01.
+	MinRate float64
+
+	// RecoveryStep represents the amount by which the keep rate is raised
+	// in each evaluation, greater than 0 and not greater than 1. If not
+	// provided, the default value is 0.1.
+	RecoveryStep float64
+}
+
+// UseReporters uses the given pressure reporters as the value of the
+// option Reporters. Then return to the option instance itself.
+func (o *AdaptiveSamplerOption) UseReporters(reporters ...PressureReporter) *AdaptiveSamplerOption {
+	o.Reporters = reporters
+	return o
+}
+
+// UseInterval uses the given interval as the value of the option
+// Interval. Then return to the option instance itself.
+func (o *AdaptiveSamplerOption) UseInterval(interval time.Duration) *AdaptiveSamplerOption {
+	o.Interval = interval
+	return o
+}
+
+// UseWatermarks uses the given pressures as the values of the options
+// LowWatermark and HighWatermark. Then return to the option instance
+// itself.
+func (o *AdaptiveSamplerOption) UseWatermarks(low, high float64) *AdaptiveSamplerOption {
+	o.LowWatermark = low
+	o.HighWatermark = high
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *AdaptiveSamplerOption) Validate() error {
+	if o.Span.Start > o.Span.End {
+		return newOptionError("Span",
+			"start level must not be greater than end level", nil)
+	}
+	if len(o.Reporters) == 0 {
+		return newOptionError("Reporters", "must not be empty", nil)
+	}
+	for index := 0; index < len(o.Reporters); index++ {
+		if o.Reporters[index] == nil {
+			return newOptionError("Reporters[" + strconv.Itoa(index) + "]",
+				"must not be nil", nil)
+		}
+	}
+	if o.Interval <= 0 {
+		return newOptionError("Interval", "must be greater than 0", nil)
+	}
+	if o.LowWatermark > o.HighWatermark {
+		return newOptionError("LowWatermark",
+			"must not be greater than HighWatermark", nil)
+	}
+	if o.MinRate <= 0 || o.MinRate > 1 {
+		return newOptionError("MinRate", "must be in (0, 1]", nil)
+	}
+	if o.RecoveryStep <= 0 || o.RecoveryStep > 1 {
+		return newOptionError("RecoveryStep", "must be in (0, 1]", nil)
+	}
+	return nil
+}
+
+// Build builds and returns an adaptive sampler instance.
+func (o *AdaptiveSamplerOption) Build() (*AdaptiveSampler, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	minimum := uint32(o.MinRate * adaptiveRateScale)
+	if minimum == 0 {
+		minimum = 1
+	}
+	step := uint32(o.RecoveryStep * adaptiveRateScale)
+	if step == 0 {
+		step = 1
+	}
+	return &AdaptiveSampler {
+		rate: adaptiveRateScale,
+		span: o.Span,
+		reporters: append([]PressureReporter(nil), o.Reporters...),
+		interval: int64(o.Interval),
+		high: o.HighWatermark,
+		low: o.LowWatermark,
+		minimum: minimum,
+		step: step,
+	}, nil
+}
+
+// NewAdaptiveSamplerOption creates and returns an adaptive sampler option
+// instance with default option values.
+func NewAdaptiveSamplerOption() *AdaptiveSamplerOption {
+	return &AdaptiveSamplerOption {
+		Span: LevelSpan {
+			Start: LevelDebug,
+			End: LevelWarning,
+		},
+		Interval: time.Second,
+		HighWatermark: 0.8,
+		LowWatermark: 0.5,
+		MinRate: 0.01,
+		RecoveryStep: 0.1,
+	}
+}
+
+// RateSampler is the structure of the rate sampler instance.
+//
+// The rate sampler keeps one of every N log entries in its log level span
+// (for example: a rate of 1:100 keeps the 1st, 101st, 201st, ... log
+// entries) regardless of their messages, and discards the others.
+type RateSampler struct {
+	count uint64
+	dropped uint64
+	span LevelSpan
+	every uint64
+}
+
+// Sample checks whether a given log entry needs to be sampled. It returns
+// true if needed, otherwise it returns false.
+func (s *RateSampler) Sample(entry *Entry) bool {
+	if !s.span.Contains(entry.Level) {
+		return true
+	}
+	if (atomic.AddUint64(&s.count, 1) - 1) % s.every == 0 {
+		return true
+	}
+	atomic.AddUint64(&s.dropped, 1)
+	return false
+}
+
+// Dropped returns the number of log entries discarded by the sampler.
+func (s *RateSampler) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// CloneSampler creates and returns a new rate sampler instance with the
+// same configuration and a fresh counter.
+func (s *RateSampler) CloneSampler() Sampler {
+	return &RateSampler {
+		span: s.span,
+		every: s.every,
+	}
+}
+
+// RateSamplerOption is a structure that contains options for the rate
+// sampler.
+type RateSamplerOption struct {
+	// Span represents the log level span of the sampled log entries. Log
+	// entries whose levels are not in the span are not sampled. If not
+	// provided, the default value is DEBUG level to FATAL level.
+	Span LevelSpan
+
+	// Every represents that one of every how many log entries is kept.
+	// If not provided, the default value is 10.
+	Every uint64
+}
+
+// UseSpan sets the Span option using the given log level span. Then
+// return to the option instance itself.
+func (o *RateSamplerOption) UseSpan(start, end Level) *RateSamplerOption {
+	o.Span = LevelSpan {
+		Start: start,
+		End: end,
+	}
+	return o
+}
+
+// UseEvery uses the given number as the value of the option Every. Then
+// return to the option instance itself.
+func (o *RateSamplerOption) UseEvery(every uint64) *RateSamplerOption {
+	o.Every = every
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *RateSamplerOption) Validate() error {
+	if o.Span.Start > o.Span.End {
+		return newOptionError("Span",
+			"start level must not be greater than end level", nil)
+	}
+	if o.Every == 0 {
+		return newOptionError("Every", "must be greater than 0", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a rate sampler instance.
+func (o *RateSamplerOption) Build() (*RateSampler, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &RateSampler {
+		span: o.Span,
+		every: o.Every,
+	}, nil
+}
+
+// NewRateSamplerOption creates and returns a rate sampler option instance
+// with default option values.
+func NewRateSamplerOption() *RateSamplerOption {
+	return &RateSamplerOption {
+		Span: LevelSpan {
+			Start: LevelDebug,
+			End: LevelFatal,
+		},
+		Every: 10,
+	}
+}
+
+// LevelSampler is the structure of the level sampler instance.
+//
+// The level sampler delegates each log entry to the sampler configured for
+// its log level, so that log entries of different levels are sampled
+// differently (for example: DEBUG 1:100, INFO 1:10 and WARNING and above
+// never sampled). Log entries of a level without a sampler are not
+// sampled. The level sampler is built from the Levels option of the
+// SamplingOption structure.
+type LevelSampler struct {
+	samplers [LevelFatal + 1]Sampler
+}
+
+// Sample checks whether a given log entry needs to be sampled. It returns
+// true if needed, otherwise it returns false.
+func (s *LevelSampler) Sample(entry *Entry) bool {
+	if entry.Level > LevelFatal || s.samplers[entry.Level] == nil {
+		return true
+	}
+	return s.samplers[entry.Level].Sample(entry)
+}
+
+// Sampler returns the sampler configured for the given log level, or nil
+// if log entries of the level are not sampled.
+func (s *LevelSampler) Sampler(level Level) Sampler {
+	if level > LevelFatal {
+		return nil
+	}
+	return s.samplers[level]
+}
+
+// distinct calls the given function with each distinct sampler of the
+// level sampler once, because a sampler may be configured for more than
+// one log level.
+func (s *LevelSampler) distinct(function func(sampler Sampler)) {
+	for index := 0; index < len(s.samplers); index++ {
+		if s.samplers[index] == nil {
+			continue
+		}
+		duplicated := false
+		for previous := 0; previous < index; previous++ {
+			if s.samplers[previous] == s.samplers[index] {
+				duplicated = true
+				break
+			}
+		}
+		if !duplicated {
+			function(s.samplers[index])
+		}
+	}
+}
+
+// Dropped returns the number of log entries discarded by the samplers of
+// all log levels that implement the DropCounter interface.
+func (s *LevelSampler) Dropped() uint64 {
+	dropped := uint64(0)
+	s.distinct(func(sampler Sampler) {
+		if counter, ok := sampler.(DropCounter); ok {
+			dropped += counter.Dropped()
+		}
+	})
+	return dropped
+}
+
+// clone returns a level sampler whose samplers are clones of the samplers
+// of the level sampler, if they implement the SamplerCloner interface, and
+// are shared otherwise.
+func (s *LevelSampler) clone() *LevelSampler {
+	instance := &LevelSampler { }
+	for index := 0; index < len(s.samplers); index++ {
+		sampler := s.samplers[index]
+		for previous := 0; previous < index; previous++ {
+			if s.samplers[previous] == sampler {
+				// Levels that shared a sampler share its clone.
+				sampler = instance.samplers[previous]
+				break
+			}
+		}
+		if sampler == s.samplers[index] {
+			if cloner, ok := sampler.(SamplerCloner); ok {
+				sampler = cloner.CloneSampler()
+			}
+		}
+		instance.samplers[index] = sampler
+	}
+	return instance
+}
+
+// CloneSampler creates and returns a new level sampler instance with
+// clones of the samplers of each log level.
+func (s *LevelSampler) CloneSampler() Sampler {
+	return s.clone()
+}
+
+// retainingLevelSampler is the structure of a level sampler that has one
+// or more retaining samplers, which releases their retained log entries.
+// For details, please refer to the comment section of the RetainingSampler
+// interface.
+type retainingLevelSampler struct {
+	*LevelSampler
+}
+
+// Release calls the Release function of each retaining sampler of the
+// level sampler with the given handler and force.
+func (s retainingLevelSampler) Release(handler func(entry *Entry), force bool) {
+	s.distinct(func(sampler Sampler) {
+		if retaining, ok := sampler.(RetainingSampler); ok {
+			retaining.Release(handler, force)
+		}
+	})
+}
+
+// CloneSampler creates and returns a new level sampler instance with
+// clones of the samplers of each log level.
+func (s retainingLevelSampler) CloneSampler() Sampler {
+	return retainingLevelSampler { s.clone() }
+}
+
+// newLevelSampler creates and returns a level sampler with the given
+// samplers of each log level. If any of the samplers is a retaining
+// sampler, the returned sampler also implements the RetainingSampler
+// interface.
+func newLevelSampler(samplers [LevelFatal + 1]Sampler) Sampler {
+	instance := &LevelSampler {
+		samplers: samplers,
+	}
+	for index := 0; index < len(samplers); index++ {
+		if _, ok := samplers[index].(RetainingSampler); ok {
+			return retainingLevelSampler { instance }
+		}
+	}
+	return instance
+}
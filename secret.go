@@ -0,0 +1,382 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"sync/atomic"
+)
+
+// SecretDetector is a structure that contains a heuristic that detects
+// secrets, such as credentials and keys, in log entries.
+type SecretDetector struct {
+	// Name represents the name of the detector, which identifies the
+	// detector in the statistics of the secret scanning Hook.
+	Name string
+
+	// Pattern represents the regular expression that matches the secret
+	// candidates.
+	Pattern *regexp.Regexp
+
+	// Verify represents the optional function that checks whether a
+	// secret candidate matched by the pattern is a secret. If not
+	// provided, all candidates are secrets.
+	Verify func(candidate string) bool
+}
+
+// DetectAWSKeys returns a secret detector that detects AWS access key IDs.
+func DetectAWSKeys() SecretDetector {
+	return SecretDetector {
+		Name: "aws_key",
+		Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)` +
+			`[0-9A-Z]{16}\b`),
+	}
+}
+
+// DetectJWTs returns a secret detector that detects JSON Web Tokens.
+func DetectJWTs() SecretDetector {
+	return SecretDetector {
+		Name: "jwt",
+		Pattern: regexp.MustCompile(`\beyJ[0-9A-Za-z_-]+\.eyJ` +
+			`[0-9A-Za-z_-]+\.[0-9A-Za-z_-]*`),
+	}
+}
+
+// DetectPrivateKeys returns a secret detector that detects PEM private
+// key blocks. A block that is cut off is detected up to the end of the
+// text.
+func DetectPrivateKeys() SecretDetector {
+	return SecretDetector {
+		Name: "private_key",
+		Pattern: regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----` +
+			`(?s:.*?)(?:-----END [A-Z ]*PRIVATE KEY-----|$)`),
+	}
+}
+
+// DetectHighEntropy returns a secret detector that detects words of at
+// least the given length whose Shannon entropy is at least the given
+// number of bits per character, which are likely to be random tokens.
+// Random base64 tokens usually have an entropy above 4.5 bits, and
+// random hexadecimal tokens above 3.5 bits.
+func DetectHighEntropy(length int, threshold float64) SecretDetector {
+	if length < 1 {
+		length = 1
+	}
+	return SecretDetector {
+		Name: "high_entropy",
+		Pattern: regexp.MustCompile(`[0-9A-Za-z+/_-]{` +
+			strconv.Itoa(length) + `,}={0,2}`),
+		Verify: func(candidate string) bool {
+			return entropy(candidate) >= threshold
+		},
+	}
+}
+
+// entropy returns the Shannon entropy of the given text in bits per
+// character.
+func entropy(text string) float64 {
+	var counts [256]int
+	for index := 0; index < len(text); index++ {
+		counts[text[index]]++
+	}
+	var result float64
+	for _, count := range counts {
+		if count > 0 {
+			probability := float64(count) / float64(len(text))
+			result -= probability * math.Log2(probability)
+		}
+	}
+	return result
+}
+
+// DefaultSecretDetectors returns the built-in secret detectors, which
+// detect AWS access key IDs, JSON Web Tokens, PEM private key blocks and
+// high-entropy words of at least 32 characters.
+func DefaultSecretDetectors() []SecretDetector {
+	return []SecretDetector {
+		DetectPrivateKeys(),
+		DetectJWTs(),
+		DetectAWSKeys(),
+		DetectHighEntropy(32, 4.5),
+	}
+}
+
+// SecretScanHook is the structure of the secret scanning Hook instance.
+//
+// The secret scanning Hook scans the text of each log entry message and
+// the string fields of structured messages, including nested objects,
+// using the secret detectors, and then replaces each detected secret with
+// the mask. The number of secrets detected by each detector is counted,
+// so that leaks can be monitored and alerted on. Candidates that match
+// any of the allowlist patterns are kept.
+//
+// Template messages that contain a secret are replaced with string
+// messages that contain the masked text. The original messages are never
+// modified, because they may be owned by the application.
+type SecretScanHook struct {
+	detectors []SecretDetector
+	allowlist []*regexp.Regexp
+	mask string
+	counts []uint64
+}
+
+// allowed returns true if the given candidate matches any of the
+// allowlist patterns, otherwise it returns false.
+func (h *SecretScanHook) allowed(candidate string) bool {
+	for _, pattern := range h.allowlist {
+		if pattern.MatchString(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// scan replaces the secrets in the given text with the mask, and then
+// returns the masked text and whether any secret was detected.
+func (h *SecretScanHook) scan(text string) (string, bool) {
+	detected := false
+	for index := range h.detectors {
+		detector := &h.detectors[index]
+		if !detector.Pattern.MatchString(text) {
+			continue
+		}
+		text = detector.Pattern.ReplaceAllStringFunc(text,
+			func(candidate string) string {
+				if detector.Verify != nil && !detector.Verify(candidate) {
+					return candidate
+				}
+				if h.allowed(candidate) {
+					return candidate
+				}
+				atomic.AddUint64(&h.counts[index], 1)
+				detected = true
+				return h.mask
+			})
+	}
+	return text, detected
+}
+
+// scanFields returns a copy of the given fields in which the secrets of
+// the string fields are masked, and whether any secret was detected. If
+// no secret was detected, the given fields are returned.
+func (h *SecretScanHook) scanFields(fields []Field) ([]Field, bool) {
+	var masked []Field
+	for index := range fields {
+		field := fields[index]
+		detected := false
+		switch field.Type {
+		case TypeString:
+			field.String, detected = h.scan(field.String)
+			if detected {
+				// The original value of an error field may contain the
+				// secret as well.
+				field.Interface = nil
+			}
+		case TypeValue:
+			if object, ok := field.Interface.(ElementObject); ok {
+				var nested []Field
+				if nested, detected = h.scanFields(object); detected {
+					field.Interface = ElementObject(nested)
+				}
+			}
+		}
+		if detected && masked == nil {
+			masked = append([]Field(nil), fields...)
+		}
+		if masked != nil {
+			masked[index] = field
+		}
+	}
+	if masked == nil {
+		return fields, false
+	}
+	return masked, true
+}
+
+// Print masks the secrets in the given log entry message.
+func (h *SecretScanHook) Print(entry *Entry) error {
+	switch message := entry.Message.(type) {
+	case StringMessage:
+		if text, ok := h.scan(string(message)); ok {
+			entry.Message = StringMessage(text)
+		}
+	case *StringMessage:
+		if message != nil {
+			if text, ok := h.scan(string(*message)); ok {
+				entry.Message = StringMessage(text)
+			}
+		}
+	case *StructMessage:
+		if message != nil {
+			h.printStruct(entry, *message)
+		}
+	case StructMessage:
+		h.printStruct(entry, message)
+	case *TemplateMessage:
+		if message != nil {
+			h.printTemplate(entry, *message)
+		}
+	case TemplateMessage:
+		h.printTemplate(entry, message)
+	}
+	return nil
+}
+
+// printStruct masks the secrets in the given structured message of the
+// given log entry.
+func (h *SecretScanHook) printStruct(entry *Entry, message StructMessage) {
+	text, detected := h.scan(message.Text)
+	fields, masked := h.scanFields(message.Fields)
+	if detected || masked {
+		message.Text = text
+		message.Fields = fields
+		entry.Message = message
+	}
+}
+
+// printTemplate masks the secrets in the given template message of the
+// given log entry.
+func (h *SecretScanHook) printTemplate(entry *Entry,
+	message TemplateMessage) {
+	text := string(appendTemplate(nil, message.Template, message.Args))
+	if text, ok := h.scan(text); ok {
+		entry.Message = StringMessage(text)
+	}
+}
+
+// Stats returns the number of secrets detected by each detector, keyed by
+// the detector names.
+func (h *SecretScanHook) Stats() map[string]uint64 {
+	stats := make(map[string]uint64, len(h.detectors))
+	for index := range h.detectors {
+		stats[h.detectors[index].Name] += atomic.LoadUint64(
+			&h.counts[index])
+	}
+	return stats
+}
+
+// Detected returns the total number of detected secrets.
+func (h *SecretScanHook) Detected() uint64 {
+	var total uint64
+	for index := range h.counts {
+		total += atomic.LoadUint64(&h.counts[index])
+	}
+	return total
+}
+
+// SecretScanHookOption is a structure that contains options for the
+// secret scanning Hook.
+type SecretScanHookOption struct {
+	// Detectors represents the secret detectors. If not provided, the
+	// default value is the result of the DefaultSecretDetectors function.
+	Detectors []SecretDetector
+
+	// Allowlist represents the regular expressions of the secret
+	// candidates that are kept, such as well-known example keys. If not
+	// provided, no candidate is kept.
+	Allowlist []string
+
+	// Mask represents the text that replaces the detected secrets. If not
+	// provided, the default value is "[REDACTED]".
+	Mask string
+}
+
+// UseDetectors uses the given detectors as the value of the option
+// Detectors. For details, please refer to the comment section of the
+// Detectors option. Then return to the option instance itself.
+func (o *SecretScanHookOption) UseDetectors(detectors ...SecretDetector) *SecretScanHookOption {
+	o.Detectors = detectors
+	return o
+}
+
+// UseAllowlist uses the given regular expressions as the value of the
+// option Allowlist. For details, please refer to the comment section of
+// the Allowlist option. Then return to the option instance itself.
+func (o *SecretScanHookOption) UseAllowlist(patterns ...string) *SecretScanHookOption {
+	o.Allowlist = patterns
+	return o
+}
+
+// UseMask uses the given text as the value of the option Mask. For details,
+// please refer to the comment section of the Mask option. Then return to
+// the option instance itself.
+func (o *SecretScanHookOption) UseMask(mask string) *SecretScanHookOption {
+	o.Mask = mask
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *SecretScanHookOption) Validate() error {
+	if len(o.Detectors) == 0 {
+		return newOptionError("Detectors", "must not be empty", nil)
+	}
+	for index, detector := range o.Detectors {
+		if detector.Pattern == nil {
+			return newOptionError("Detectors[" + strconv.Itoa(index) + "]",
+				"pattern must not be nil", nil)
+		}
+	}
+	for index, pattern := range o.Allowlist {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return newOptionError("Allowlist[" + strconv.Itoa(index) + "]",
+				"invalid pattern", err)
+		}
+	}
+	return nil
+}
+
+// Build builds and returns a secret scanning Hook instance.
+func (o *SecretScanHookOption) Build() (*SecretScanHook, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	allowlist := make([]*regexp.Regexp, 0, len(o.Allowlist))
+	for _, pattern := range o.Allowlist {
+		allowlist = append(allowlist, regexp.MustCompile(pattern))
+	}
+	return &SecretScanHook {
+		detectors: append([]SecretDetector(nil), o.Detectors...),
+		allowlist: allowlist,
+		mask: o.Mask,
+		counts: make([]uint64, len(o.Detectors)),
+	}, nil
+}
+
+// NewSecretScanHookOption creates and returns a secret scanning Hook option
+// instance with default option values.
+func NewSecretScanHookOption() *SecretScanHookOption {
+	return &SecretScanHookOption {
+		Detectors: DefaultSecretDetectors(),
+		Mask: "[REDACTED]",
+	}
+}
+
+// NewSecretScanHook creates and returns a secret scanning Hook instance
+// using default option values.
+func NewSecretScanHook() (*SecretScanHook, error) {
+	return NewSecretScanHookOption().Build()
+}
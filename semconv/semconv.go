@@ -0,0 +1,283 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+// Package semconv provides the names of well-known fields and typed
+// constructors of these fields, which are aligned with the OpenTelemetry
+// semantic conventions, so that the log entries of different teams and
+// services use consistent field names.
+//
+// For example:
+//
+//	logger.Infos("Request handled",
+//		semconv.HTTPMethod(request.Method),
+//		semconv.HTTPStatusCode(status),
+//		semconv.UserID(user))
+package semconv
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/nobody-night/santa"
+)
+
+const (
+	// KeyHTTPMethod represents the name of the field that contains the
+	// HTTP request method, for example: "GET".
+	KeyHTTPMethod = "http.method"
+
+	// KeyHTTPRoute represents the name of the field that contains the
+	// matched route template, for example: "/users/:id".
+	KeyHTTPRoute = "http.route"
+
+	// KeyHTTPTarget represents the name of the field that contains the
+	// full request target, for example: "/users/1?active=true".
+	KeyHTTPTarget = "http.target"
+
+	// KeyHTTPURL represents the name of the field that contains the full
+	// request URL.
+	KeyHTTPURL = "http.url"
+
+	// KeyHTTPStatusCode represents the name of the field that contains the
+	// HTTP response status code.
+	KeyHTTPStatusCode = "http.status_code"
+
+	// KeyHTTPUserAgent represents the name of the field that contains the
+	// value of the User-Agent request header.
+	KeyHTTPUserAgent = "http.user_agent"
+
+	// KeyHTTPRequestContentLength represents the name of the field that
+	// contains the size of the request body in bytes.
+	KeyHTTPRequestContentLength = "http.request_content_length"
+
+	// KeyHTTPResponseContentLength represents the name of the field that
+	// contains the size of the response body in bytes.
+	KeyHTTPResponseContentLength = "http.response_content_length"
+
+	// KeyDBSystem represents the name of the field that contains the
+	// database management system, for example: "postgresql".
+	KeyDBSystem = "db.system"
+
+	// KeyDBName represents the name of the field that contains the name
+	// of the database being accessed.
+	KeyDBName = "db.name"
+
+	// KeyDBStatement represents the name of the field that contains the
+	// database statement being executed.
+	KeyDBStatement = "db.statement"
+
+	// KeyDBOperation represents the name of the field that contains the
+	// name of the operation being executed, for example: "SELECT".
+	KeyDBOperation = "db.operation"
+
+	// KeyNetPeerIP represents the name of the field that contains the IP
+	// address of the remote peer.
+	KeyNetPeerIP = "net.peer.ip"
+
+	// KeyNetPeerName represents the name of the field that contains the
+	// host name of the remote peer.
+	KeyNetPeerName = "net.peer.name"
+
+	// KeyNetPeerPort represents the name of the field that contains the
+	// port of the remote peer.
+	KeyNetPeerPort = "net.peer.port"
+
+	// KeyNetHostName represents the name of the field that contains the
+	// host name of the local host.
+	KeyNetHostName = "net.host.name"
+
+	// KeyUserID represents the name of the field that contains the ID of
+	// the user of the request.
+	KeyUserID = "user.id"
+
+	// KeyServiceName represents the name of the field that contains the
+	// logical name of the service.
+	KeyServiceName = "service.name"
+
+	// KeyServiceVersion represents the name of the field that contains the
+	// version of the service.
+	KeyServiceVersion = "service.version"
+
+	// KeyExceptionType represents the name of the field that contains the
+	// type of an exception (error).
+	KeyExceptionType = "exception.type"
+
+	// KeyExceptionMessage represents the name of the field that contains
+	// the message of an exception (error).
+	KeyExceptionMessage = "exception.message"
+
+	// KeyDuration represents the name of the field that contains the
+	// duration of an operation in nanoseconds.
+	KeyDuration = "duration"
+)
+
+// HTTPMethod returns a field named KeyHTTPMethod with the given method.
+func HTTPMethod(method string) santa.Field {
+	return santa.String(KeyHTTPMethod, method)
+}
+
+// HTTPRoute returns a field named KeyHTTPRoute with the given route.
+func HTTPRoute(route string) santa.Field {
+	return santa.String(KeyHTTPRoute, route)
+}
+
+// HTTPTarget returns a field named KeyHTTPTarget with the given target.
+func HTTPTarget(target string) santa.Field {
+	return santa.String(KeyHTTPTarget, target)
+}
+
+// HTTPURL returns a field named KeyHTTPURL with the given URL.
+func HTTPURL(url string) santa.Field {
+	return santa.String(KeyHTTPURL, url)
+}
+
+// HTTPStatusCode returns a field named KeyHTTPStatusCode with the given
+// status code.
+func HTTPStatusCode(code int) santa.Field {
+	return santa.Int(KeyHTTPStatusCode, int64(code))
+}
+
+// HTTPUserAgent returns a field named KeyHTTPUserAgent with the given user
+// agent.
+func HTTPUserAgent(agent string) santa.Field {
+	return santa.String(KeyHTTPUserAgent, agent)
+}
+
+// HTTPRequestContentLength returns a field named
+// KeyHTTPRequestContentLength with the given size.
+func HTTPRequestContentLength(size int64) santa.Field {
+	return santa.Int(KeyHTTPRequestContentLength, size)
+}
+
+// HTTPResponseContentLength returns a field named
+// KeyHTTPResponseContentLength with the given size.
+func HTTPResponseContentLength(size int64) santa.Field {
+	return santa.Int(KeyHTTPResponseContentLength, size)
+}
+
+// DBSystem returns a field named KeyDBSystem with the given system.
+func DBSystem(system string) santa.Field {
+	return santa.String(KeyDBSystem, system)
+}
+
+// DBName returns a field named KeyDBName with the given name.
+func DBName(name string) santa.Field {
+	return santa.String(KeyDBName, name)
+}
+
+// DBStatement returns a field named KeyDBStatement with the given
+// statement.
+//
+// Please note that the statement may contain sensitive values, so it is
+// recommended to use parameterized statements.
+func DBStatement(statement string) santa.Field {
+	return santa.String(KeyDBStatement, statement)
+}
+
+// DBOperation returns a field named KeyDBOperation with the given
+// operation.
+func DBOperation(operation string) santa.Field {
+	return santa.String(KeyDBOperation, operation)
+}
+
+// NetPeerIP returns a field named KeyNetPeerIP with the given IP address.
+// If the given IP address is nil, the value of the field is empty.
+func NetPeerIP(ip net.IP) santa.Field {
+	if ip == nil {
+		return santa.String(KeyNetPeerIP, "")
+	}
+	return santa.String(KeyNetPeerIP, ip.String())
+}
+
+// NetPeerName returns a field named KeyNetPeerName with the given name.
+func NetPeerName(name string) santa.Field {
+	return santa.String(KeyNetPeerName, name)
+}
+
+// NetPeerPort returns a field named KeyNetPeerPort with the given port.
+func NetPeerPort(port int) santa.Field {
+	return santa.Int(KeyNetPeerPort, int64(port))
+}
+
+// NetPeerAddr returns the fields named KeyNetPeerIP (or KeyNetPeerName if
+// the host is not an IP address) and KeyNetPeerPort parsed from the given
+// address in the form "host:port" (for example: the RemoteAddr of an HTTP
+// request). If the address can not be parsed, only the KeyNetPeerName
+// field with the given address is returned.
+func NetPeerAddr(address string) []santa.Field {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return []santa.Field { NetPeerName(address) }
+	}
+	fields := make([]santa.Field, 0, 2)
+	if ip := net.ParseIP(host); ip != nil {
+		fields = append(fields, NetPeerIP(ip))
+	} else {
+		fields = append(fields, NetPeerName(host))
+	}
+	if number, err := strconv.Atoi(port); err == nil {
+		fields = append(fields, NetPeerPort(number))
+	}
+	return fields
+}
+
+// NetHostName returns a field named KeyNetHostName with the given name.
+func NetHostName(name string) santa.Field {
+	return santa.String(KeyNetHostName, name)
+}
+
+// UserID returns a field named KeyUserID with the given user ID.
+func UserID(id string) santa.Field {
+	return santa.String(KeyUserID, id)
+}
+
+// ServiceName returns a field named KeyServiceName with the given name.
+func ServiceName(name string) santa.Field {
+	return santa.String(KeyServiceName, name)
+}
+
+// ServiceVersion returns a field named KeyServiceVersion with the given
+// version.
+func ServiceVersion(version string) santa.Field {
+	return santa.String(KeyServiceVersion, version)
+}
+
+// Exception returns the fields named KeyExceptionType and
+// KeyExceptionMessage with the type and the message of the given error.
+// If the given error is nil, it returns nil.
+func Exception(err error) []santa.Field {
+	if err == nil {
+		return nil
+	}
+	return []santa.Field {
+		santa.String(KeyExceptionType, fmt.Sprintf("%T", err)),
+		santa.String(KeyExceptionMessage, err.Error()),
+	}
+}
+
+// Duration returns a field named KeyDuration with the given duration.
+func Duration(duration time.Duration) santa.Field {
+	return santa.Duration(KeyDuration, duration)
+}
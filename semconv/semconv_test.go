@@ -0,0 +1,64 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package semconv
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/nobody-night/santa"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFields(t *testing.T) {
+	assert.Equal(t, santa.String("http.method", "GET"), HTTPMethod("GET"),
+		"Unexpected field")
+	assert.Equal(t, santa.Int("http.status_code", 404), HTTPStatusCode(404),
+		"Unexpected field")
+	assert.Equal(t, santa.String("db.statement", "SELECT 1"),
+		DBStatement("SELECT 1"), "Unexpected field")
+	assert.Equal(t, santa.String("user.id", "alice"), UserID("alice"),
+		"Unexpected field")
+	assert.Equal(t, santa.String("net.peer.ip", "10.0.0.1"),
+		NetPeerIP(net.IPv4(10, 0, 0, 1)), "Unexpected field")
+
+	assert.Equal(t, []santa.Field {
+		santa.String("net.peer.ip", "::1"),
+		santa.Int("net.peer.port", 8080),
+	}, NetPeerAddr("[::1]:8080"), "Unexpected fields")
+	assert.Equal(t, []santa.Field {
+		santa.String("net.peer.name", "example.com"),
+		santa.Int("net.peer.port", 443),
+	}, NetPeerAddr("example.com:443"), "Unexpected fields")
+	assert.Equal(t, []santa.Field {
+		santa.String("net.peer.name", "invalid"),
+	}, NetPeerAddr("invalid"), "Unexpected fields")
+
+	assert.Nil(t, Exception(nil), "Unexpected fields")
+	assert.Equal(t, []santa.Field {
+		santa.String("exception.type", "*errors.errorString"),
+		santa.String("exception.message", "failed"),
+	}, Exception(errors.New("failed")), "Unexpected fields")
+}
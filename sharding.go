@@ -0,0 +1,242 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"strconv"
+)
+
+// ShardingExporter is the structure of a sharding exporter instance.
+//
+// The sharding exporter routes each log entry to one of the wrapped
+// exporters (shards), based on a hash of the value of a label or a field
+// of the structured message of the log entry (for example: a tenant ID).
+// It enables per-tenant files or per-shard network targets from a single
+// logger. Log entries with the same value are always routed to the same
+// shard.
+//
+// The shard is chosen by a jump consistent hash, so when the number of
+// shards grows from N to N+1, only about 1/(N+1) of the values are routed
+// to a different shard. Log entries without the label or the field are
+// routed to the default shard.
+type ShardingExporter struct {
+	exporters []Exporter
+	label string
+	field string
+	fallback int
+}
+
+// shardHash returns the FNV64-A hash of the given text.
+func shardHash(text string) uint64 {
+	hash := uint64(14695981039346656037)
+	for index := 0; index < len(text); index++ {
+		hash ^= uint64(text[index])
+		hash *= 1099511628211
+	}
+	return hash
+}
+
+// jumpHash returns the shard of the given hash within the given number of
+// shards, using the jump consistent hash algorithm of Lamping and Veach.
+func jumpHash(hash uint64, shards int) int {
+	var shard, next int64 = -1, 0
+	for next < int64(shards) {
+		shard = next
+		hash = hash * 2862933555777941757 + 1
+		next = int64(float64(shard + 1) * (float64(int64(1) << 31) /
+			float64((hash >> 33) + 1)))
+	}
+	return int(shard)
+}
+
+// value returns the value of the sharding key of the given log entry, and
+// whether the log entry contains the sharding key.
+func (e *ShardingExporter) value(entry *Entry) (string, bool) {
+	if len(e.label) > 0 {
+		return entry.Labels.Lookup(e.label)
+	}
+	var fields []Field
+	switch message := entry.Message.(type) {
+	case *StructMessage:
+		fields = message.Fields
+	case StructMessage:
+		fields = message.Fields
+	default:
+		return "", false
+	}
+	for index := len(fields) - 1; index >= 0; index-- {
+		field := &fields[index]
+		if field.Name != e.field {
+			continue
+		}
+		switch field.Type {
+		case TypeString:
+			return field.String, true
+		case TypeInt:
+			return strconv.FormatInt(field.Number, 10), true
+		case TypeUint:
+			return strconv.FormatUint(field.Uint64(), 10), true
+		}
+		return string(field.SerializeJSON(nil)), true
+	}
+	return "", false
+}
+
+// Shard returns the index of the shard that the given value of the
+// sharding key is routed to.
+func (e *ShardingExporter) Shard(value string) int {
+	return jumpHash(shardHash(value), len(e.exporters))
+}
+
+// Export routes the given log entry to its shard, and then exports the
+// log entry using the exporter of the shard.
+//
+// Finally, any errors encountered are returned.
+func (e *ShardingExporter) Export(entry *Entry) error {
+	shard := e.fallback
+	if value, ok := e.value(entry); ok {
+		shard = e.Shard(value)
+	}
+	return newExporterError(e.exporters, shard,
+		e.exporters[shard].Export(entry))
+}
+
+// Flush flushes the exporters of all shards. Exporters that implement the
+// Flusher interface are flushed, and the others are synchronized.
+//
+// Finally, all errors encountered are returned.
+func (e *ShardingExporter) Flush() error {
+	return flushExporters(e.exporters)
+}
+
+// Sync synchronizes the exporters of all shards.
+//
+// Finally, all errors encountered are returned.
+func (e *ShardingExporter) Sync() error {
+	return syncExporters(e.exporters)
+}
+
+// Close closes the exporters of all shards.
+//
+// Finally, all errors encountered are returned.
+func (e *ShardingExporter) Close() error {
+	return closeExporters(e.exporters)
+}
+
+// ShardingExporterOption is a structure that contains sharding exporter
+// options.
+type ShardingExporterOption struct {
+	// Exporters represents the exporters of the shards. The number of
+	// shards is the number of exporters. This option is required.
+	Exporters []Exporter
+
+	// Label represents the key of the label whose value is the sharding
+	// key. Only one of the Label and Field options can be provided.
+	Label string
+
+	// Field represents the name of the field of the structured message
+	// whose value is the sharding key. Only one of the Label and Field
+	// options can be provided.
+	Field string
+
+	// DefaultShard represents the index of the shard that the log entries
+	// without the sharding key are routed to. If not provided, the default
+	// value is 0.
+	DefaultShard int
+}
+
+// UseExporters uses the given exporters as the value of the option
+// Exporters. Then return to the option instance itself.
+func (o *ShardingExporterOption) UseExporters(exporters ...Exporter) *ShardingExporterOption {
+	o.Exporters = exporters
+	return o
+}
+
+// UseLabel uses the given label key as the value of the option Label, and
+// clears the option Field. Then return to the option instance itself.
+func (o *ShardingExporterOption) UseLabel(key string) *ShardingExporterOption {
+	o.Label = key
+	o.Field = ""
+	return o
+}
+
+// UseField uses the given field name as the value of the option Field,
+// and clears the option Label. Then return to the option instance itself.
+func (o *ShardingExporterOption) UseField(name string) *ShardingExporterOption {
+	o.Field = name
+	o.Label = ""
+	return o
+}
+
+// UseDefaultShard uses the given index as the value of the option
+// DefaultShard. Then return to the option instance itself.
+func (o *ShardingExporterOption) UseDefaultShard(shard int) *ShardingExporterOption {
+	o.DefaultShard = shard
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *ShardingExporterOption) Validate() error {
+	if len(o.Exporters) == 0 {
+		return newOptionError("Exporters", "must not be empty", nil)
+	}
+	for index := 0; index < len(o.Exporters); index++ {
+		if o.Exporters[index] == nil {
+			return newOptionError("Exporters", "must not contain nil", nil)
+		}
+	}
+	if len(o.Label) == 0 && len(o.Field) == 0 {
+		return newOptionError("Label", "either Label or Field must be " +
+			"provided", nil)
+	}
+	if len(o.Label) > 0 && len(o.Field) > 0 {
+		return newOptionError("Label", "only one of Label and Field can " +
+			"be provided", nil)
+	}
+	if o.DefaultShard < 0 || o.DefaultShard >= len(o.Exporters) {
+		return newOptionError("DefaultShard", "must be the index of an " +
+			"exporter", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a sharding exporter instance.
+func (o *ShardingExporterOption) Build() (*ShardingExporter, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &ShardingExporter {
+		exporters: append([]Exporter(nil), o.Exporters...),
+		label: o.Label,
+		field: o.Field,
+		fallback: o.DefaultShard,
+	}, nil
+}
+
+// NewShardingExporterOption creates and returns a sharding exporter option
+// instance with default option values.
+func NewShardingExporterOption() *ShardingExporterOption {
+	return &ShardingExporterOption { }
+}
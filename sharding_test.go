@@ -0,0 +1,95 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardingExporter(t *testing.T) {
+	shards := []*testExporter { { }, { }, { }, { } }
+	exporters := make([]Exporter, len(shards))
+	for index := range shards {
+		exporters[index] = shards[index]
+	}
+
+	_, err := NewShardingExporterOption().UseExporters(exporters...).Build()
+	assert.Error(t, err, "Unexpected build result")
+
+	exporter, err := NewShardingExporterOption().UseExporters(exporters...).
+		UseField("tenant").UseDefaultShard(3).Build()
+	assert.NoError(t, err, "Unexpected build error")
+
+	counts := make([]int, len(shards))
+	for tenant := 0; tenant < 1000; tenant++ {
+		value := "tenant-" + strconv.Itoa(tenant)
+		shard := exporter.Shard(value)
+		counts[shard]++
+		entry := &Entry {
+			Message: StructMessage {
+				Text: "Hello Test!",
+				Fields: []Field { String("tenant", value) },
+			},
+		}
+		assert.NoError(t, exporter.Export(entry), "Unexpected export error")
+		assert.Equal(t, entry, shards[shard].entry, "Unexpected shard")
+		assert.Equal(t, shard, exporter.Shard(value), "Unexpected shard")
+	}
+	for shard, count := range counts {
+		assert.True(t, count > 150, "Unexpected distribution of shard %d",
+			shard)
+	}
+
+	entry := &Entry { Message: StringMessage("Hello Test!") }
+	assert.NoError(t, exporter.Export(entry), "Unexpected export error")
+	assert.Equal(t, entry, shards[3].entry, "Unexpected default shard")
+
+	exporter, err = NewShardingExporterOption().UseExporters(exporters...).
+		UseLabel("tenant").Build()
+	assert.NoError(t, err, "Unexpected build error")
+	entry = &Entry {
+		Message: StringMessage("Hello Test!"),
+		Labels: NewSerializedLabels(NewLabel("tenant", "alice")),
+	}
+	assert.NoError(t, exporter.Export(entry), "Unexpected export error")
+	assert.Equal(t, entry, shards[exporter.Shard("alice")].entry,
+		"Unexpected shard")
+
+	assert.NoError(t, exporter.Sync(), "Unexpected sync error")
+	assert.NoError(t, exporter.Flush(), "Unexpected flush error")
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+}
+
+func TestJumpHash(t *testing.T) {
+	moved := 0
+	for value := 0; value < 1000; value++ {
+		hash := shardHash(strconv.Itoa(value))
+		if jumpHash(hash, 10) != jumpHash(hash, 11) {
+			moved++
+		}
+	}
+	assert.True(t, moved < 150, "Unexpected number of moved values")
+}
@@ -0,0 +1,74 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"os"
+	"sync/atomic"
+)
+
+// SilenceEnvironment is the name of the environment variable that silences
+// all loggers when the application is initialized if it is set to a
+// non-empty value other than "0". For details, please refer to the comment
+// section of the Silence function.
+const SilenceEnvironment = "SANTA_SILENCE"
+
+// silenced is 1 if all loggers are silenced, otherwise it is 0.
+var silenced int32 = silenceFromEnvironment()
+
+// silenceFromEnvironment returns the initial value of the silenced switch
+// according to the environment variable SilenceEnvironment.
+func silenceFromEnvironment() int32 {
+	if value := os.Getenv(SilenceEnvironment); len(value) > 0 && value != "0" {
+		return 1
+	}
+	return 0
+}
+
+// Silence silences all loggers of the application, which then discard
+// every log entry instead of passing it to their samplers, hooks and
+// exporters, until the Unsilence function is called. It is intended for
+// noisy test suites and benchmarks (for example: called by TestMain), so
+// that log output can be suppressed without changing the code that builds
+// the loggers. Synchronizing and closing the loggers are not affected.
+//
+// Setting the environment variable SANTA_SILENCE to a non-empty value other
+// than "0" silences all loggers when the application is initialized.
+//
+// This API is thread-safe, and the change takes effect for subsequent log
+// entries.
+func Silence() {
+	atomic.StoreInt32(&silenced, 1)
+}
+
+// Unsilence stops discarding the log entries of all loggers. For details,
+// please refer to the comment section of the Silence function.
+func Unsilence() {
+	atomic.StoreInt32(&silenced, 0)
+}
+
+// IsSilenced checks whether all loggers are silenced. For details, please
+// refer to the comment section of the Silence function.
+func IsSilenced() bool {
+	return atomic.LoadInt32(&silenced) == 1
+}
@@ -0,0 +1,56 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSilence(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStandardOption().DisableSampling()
+	option.Outputting.UseStandard(writer)
+	option.ErrorOutputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected build error")
+	defer logger.Close()
+
+	assert.False(t, IsSilenced(), "Unexpected silenced loggers")
+	Silence()
+	defer Unsilence()
+	assert.True(t, IsSilenced(), "Unexpected unsilenced loggers")
+	assert.NoError(t, logger.Info(StringMessage("silenced")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Error(StringMessage("silenced")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Sync(), "Unexpected sync error")
+	assert.Empty(t, writer.String(), "Unexpected output data")
+
+	Unsilence()
+	assert.NoError(t, logger.Info(StringMessage("unsilenced")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Sync(), "Unexpected sync error")
+	assert.Contains(t, writer.String(), "unsilenced", "Unexpected output data")
+}
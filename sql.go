@@ -0,0 +1,552 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// QueryParameters represents how the parameters of a query field are
+// encoded. Its optional options are constants starting with
+// QueryParameters...
+type QueryParameters int
+
+const (
+	// QueryParametersCount represents that only the number of parameters
+	// is encoded. This is the default.
+	QueryParametersCount QueryParameters = iota
+
+	// QueryParametersTypes represents that the data type of each parameter
+	// is also encoded, without its value.
+	QueryParametersTypes
+
+	// QueryParametersValues represents that the value of each parameter is
+	// also encoded, except the parameters redacted by the Redactor option
+	// of the QueryOption structure.
+	QueryParametersValues
+)
+
+// QueryOption is a structure that contains options for query fields. For
+// details, please refer to the comment section of the Query function.
+type QueryOption struct {
+	// Parameters represents how the parameters of the query are encoded.
+	// For details, please refer to the QueryParameters... constants. If not
+	// provided, the default value is QueryParametersCount.
+	Parameters QueryParameters
+
+	// Redactor represents a function that returns true if the value of the
+	// parameter with the given index (starting from 0) and value should be
+	// replaced by the RedactedMarker constant. It is only used if the
+	// Parameters option is QueryParametersValues. If not provided, no
+	// parameters are redacted.
+	Redactor func(index int, value interface { }) bool
+
+	// Value represents the dump option used to encode the values of the
+	// parameters. For details, please refer to the comment section of the
+	// DumpElement structure. If not provided, the default value is the
+	// default dump option.
+	Value DumpOption
+}
+
+// UseParameters uses the given value as the value of the option
+// Parameters. Then return to the option instance itself.
+func (o *QueryOption) UseParameters(parameters QueryParameters) *QueryOption {
+	o.Parameters = parameters
+	return o
+}
+
+// UseRedactor uses the given function as the value of the option Redactor.
+// Then return to the option instance itself.
+func (o *QueryOption) UseRedactor(redactor func(index int,
+	value interface { }) bool) *QueryOption {
+	o.Redactor = redactor
+	return o
+}
+
+// NewQueryOption creates and returns a query option instance with default
+// optional values.
+func NewQueryOption() *QueryOption {
+	return &QueryOption {
+		Value: *NewDumpOption(),
+	}
+}
+
+// defaultQueryOption is the query option used by the Query function.
+var defaultQueryOption = *NewQueryOption()
+
+// NormalizeQuery returns the given query text with each run of whitespace
+// characters outside quoted literals and identifiers collapsed into a
+// single space, and leading and trailing whitespace characters removed.
+func NormalizeQuery(query string) string {
+	var builder strings.Builder
+	builder.Grow(len(query))
+	var quote rune
+	space := false
+	for _, character := range query {
+		if quote == 0 && unicode.IsSpace(character) {
+			space = true
+			continue
+		}
+		if space && builder.Len() > 0 {
+			builder.WriteByte(' ')
+		}
+		space = false
+		switch {
+		case quote == character:
+			quote = 0
+		case quote == 0 && (character == '\'' || character == '"' ||
+			character == '`'):
+			quote = character
+		}
+		builder.WriteRune(character)
+	}
+	return builder.String()
+}
+
+// QueryWith returns the value of a field with a given name and the given
+// query and its parameters encoded as an object with the given query
+// option, which contains the normalized query text (see the NormalizeQuery
+// function), the number of parameters, and the data types or values of the
+// parameters if enabled by the option.
+func QueryWith(name string, query string, args []interface { },
+	option *QueryOption) Field {
+	fields := ElementObject {
+		quoted("text", NormalizeQuery(query)),
+		Int("args", int64(len(args))),
+	}
+	switch option.Parameters {
+	case QueryParametersTypes:
+		types := make(ElementObject, len(args))
+		for index := 0; index < len(args); index++ {
+			text := "nil"
+			if args[index] != nil {
+				text = reflect.TypeOf(args[index]).String()
+			}
+			types[index] = quoted("", text)
+		}
+		fields = append(fields, Field {
+			Element: Element {
+				Type: TypeValue,
+				Interface: elementList(types),
+			},
+			Name: "types",
+		})
+	case QueryParametersValues:
+		values := make(ElementObject, len(args))
+		for index := 0; index < len(args); index++ {
+			if option.Redactor != nil && option.Redactor(index, args[index]) {
+				values[index] = quoted("", RedactedMarker)
+				continue
+			}
+			values[index] = DumpWith("", args[index], &option.Value)
+		}
+		fields = append(fields, Field {
+			Element: Element {
+				Type: TypeValue,
+				Interface: elementList(values),
+			},
+			Name: "values",
+		})
+	}
+	return Object(name, fields...)
+}
+
+// Query returns the value of a field with a given name and the given query
+// and its parameters encoded as an object with the default query option.
+// For details, please refer to the comment section of the QueryWith
+// function.
+func Query(name string, query string, args []interface { }) Field {
+	return QueryWith(name, query, args, &defaultQueryOption)
+}
+
+// elementList represents an element whose native data type is []Field,
+// whose values are serialized as a JSON array and whose names are ignored.
+type elementList []Field
+
+// SerializeJSON serializes the element into a JSON array and appends it
+// to the given buffer slice, and then returns the appended buffer slice.
+func (e elementList) SerializeJSON(buffer []byte) []byte {
+	buffer = append(buffer, '[')
+	for index := 0; index < len(e); index++ {
+		if index > 0 {
+			buffer = append(buffer, ", "...)
+		}
+		buffer = e[index].SerializeJSON(buffer)
+	}
+	return append(buffer, ']')
+}
+
+// sqlLogger is a structure that contains the logger and the query option
+// used by a wrapped database driver.
+type sqlLogger struct {
+	logger *StructLogger
+	option *QueryOption
+}
+
+// log outputs a structured log message describing the given query of the
+// given operation with the given parameters, which started at the given
+// time and returned the given error. Successful queries are logged with a
+// log level of DEBUG and failed queries with a log level of ERROR.
+// driver.ErrSkip is not a failure and is not logged.
+func (l *sqlLogger) log(ctx context.Context, operation string, query string,
+	args []driver.NamedValue, start time.Time, err error) {
+	if errors.Is(err, driver.ErrSkip) {
+		return
+	}
+	level, text := LevelDebug, "SQL query completed."
+	if err != nil {
+		level, text = LevelError, "SQL query failed."
+	}
+	if !l.logger.Level().Enabled(level) {
+		return
+	}
+	values := make([]interface { }, len(args))
+	for index := 0; index < len(args); index++ {
+		values[index] = args[index].Value
+	}
+	fields := []Field {
+		String("operation", operation),
+		QueryWith("query", query, values, l.option),
+		Int("latency_ns", int64(time.Since(start))),
+	}
+	if err != nil {
+		fields = append(fields, Error("error", err))
+	}
+	// Any errors encountered are discarded, so that logging does not fail
+	// the query.
+	logger := l.logger.WithContext(ctx)
+	if logger != nil {
+		_ = logger.Prints(level, text, fields...)
+		_ = logger.Release()
+	}
+}
+
+// namedValues converts the given values to named values.
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for index := 0; index < len(args); index++ {
+		named[index] = driver.NamedValue {
+			Ordinal: index + 1,
+			Value: args[index],
+		}
+	}
+	return named
+}
+
+// plainValues converts the given named values to values. It returns an
+// error if any of the values has a name.
+func plainValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for index := 0; index < len(args); index++ {
+		if len(args[index].Name) > 0 {
+			return nil, errors.New("santa: driver does not support " +
+				"named parameters")
+		}
+		values[index] = args[index].Value
+	}
+	return values, nil
+}
+
+// sqlDriver is the structure of a database driver that logs the queries
+// of its connections.
+type sqlDriver struct {
+	driver driver.Driver
+	logger *sqlLogger
+}
+
+// Open opens a connection of the wrapped driver and wraps it.
+func (d *sqlDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn { conn, d.logger }, nil
+}
+
+// sqlConnector is the structure of a database connector that logs the
+// queries of its connections.
+type sqlConnector struct {
+	connector driver.Connector
+	logger *sqlLogger
+}
+
+// Connect opens a connection of the wrapped connector and wraps it.
+func (c *sqlConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlConn { conn, c.logger }, nil
+}
+
+// Driver returns the wrapped driver of the wrapped connector.
+func (c *sqlConnector) Driver() driver.Driver {
+	return &sqlDriver { c.connector.Driver(), c.logger }
+}
+
+// sqlConn is the structure of a database connection that logs its
+// queries.
+type sqlConn struct {
+	conn driver.Conn
+	logger *sqlLogger
+}
+
+// Prepare prepares a statement of the wrapped connection and wraps it.
+func (c *sqlConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+// PrepareContext prepares a statement of the wrapped connection with the
+// given context and wraps it. Only failed preparations are logged, because
+// the executions of the statement are logged.
+func (c *sqlConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	var stmt driver.Stmt
+	var err error
+	if preparer, ok := c.conn.(driver.ConnPrepareContext); ok {
+		stmt, err = preparer.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.conn.Prepare(query)
+	}
+	if err != nil {
+		c.logger.log(ctx, "prepare", query, nil, start, err)
+		return nil, err
+	}
+	return &sqlStmt { stmt, query, c.logger }, nil
+}
+
+// Close closes the wrapped connection.
+func (c *sqlConn) Close() error {
+	return c.conn.Close()
+}
+
+// Begin starts a transaction of the wrapped connection.
+func (c *sqlConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions { })
+}
+
+// BeginTx starts a transaction of the wrapped connection with the given
+// context and options.
+func (c *sqlConn) BeginTx(ctx context.Context, options driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, options)
+	}
+	return c.conn.Begin()
+}
+
+// ExecContext executes a query of the wrapped connection and logs it. If
+// the wrapped connection does not support it, driver.ErrSkip is returned,
+// so the query is executed by a prepared statement instead.
+func (c *sqlConn) ExecContext(ctx context.Context, query string,
+	args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	switch execer := c.conn.(type) {
+	case driver.ExecerContext:
+		result, err = execer.ExecContext(ctx, query, args)
+	case driver.Execer:
+		var values []driver.Value
+		if values, err = plainValues(args); err == nil {
+			result, err = execer.Exec(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.logger.log(ctx, "exec", query, args, start, err)
+	return result, err
+}
+
+// QueryContext executes a query of the wrapped connection and logs it. If
+// the wrapped connection does not support it, driver.ErrSkip is returned,
+// so the query is executed by a prepared statement instead.
+func (c *sqlConn) QueryContext(ctx context.Context, query string,
+	args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	switch queryer := c.conn.(type) {
+	case driver.QueryerContext:
+		rows, err = queryer.QueryContext(ctx, query, args)
+	case driver.Queryer:
+		var values []driver.Value
+		if values, err = plainValues(args); err == nil {
+			rows, err = queryer.Query(query, values)
+		}
+	default:
+		return nil, driver.ErrSkip
+	}
+	c.logger.log(ctx, "query", query, args, start, err)
+	return rows, err
+}
+
+// Ping checks the wrapped connection if it supports it.
+func (c *sqlConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession resets the session of the wrapped connection if it supports
+// it.
+func (c *sqlConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// IsValid checks whether the wrapped connection is valid if it supports
+// it.
+func (c *sqlConn) IsValid() bool {
+	if validator, ok := c.conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// CheckNamedValue checks the given named value with the wrapped connection
+// if it supports it, otherwise driver.ErrSkip is returned so the default
+// conversion is used.
+func (c *sqlConn) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := c.conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// sqlStmt is the structure of a prepared statement that logs its
+// executions.
+type sqlStmt struct {
+	stmt driver.Stmt
+	query string
+	logger *sqlLogger
+}
+
+// Close closes the wrapped statement.
+func (s *sqlStmt) Close() error {
+	return s.stmt.Close()
+}
+
+// NumInput returns the number of parameters of the wrapped statement.
+func (s *sqlStmt) NumInput() int {
+	return s.stmt.NumInput()
+}
+
+// Exec executes the wrapped statement with the given parameters.
+func (s *sqlStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+// ExecContext executes the wrapped statement with the given context and
+// parameters, and logs it.
+func (s *sqlStmt) ExecContext(ctx context.Context,
+	args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var result driver.Result
+	var err error
+	if execer, ok := s.stmt.(driver.StmtExecContext); ok {
+		result, err = execer.ExecContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = plainValues(args); err == nil {
+			result, err = s.stmt.Exec(values)
+		}
+	}
+	s.logger.log(ctx, "exec", s.query, args, start, err)
+	return result, err
+}
+
+// Query executes the wrapped statement with the given parameters.
+func (s *sqlStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+// QueryContext executes the wrapped statement with the given context and
+// parameters, and logs it.
+func (s *sqlStmt) QueryContext(ctx context.Context,
+	args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if queryer, ok := s.stmt.(driver.StmtQueryContext); ok {
+		rows, err = queryer.QueryContext(ctx, args)
+	} else {
+		var values []driver.Value
+		if values, err = plainValues(args); err == nil {
+			rows, err = s.stmt.Query(values)
+		}
+	}
+	s.logger.log(ctx, "query", s.query, args, start, err)
+	return rows, err
+}
+
+// CheckNamedValue checks the given named value with the wrapped statement
+// if it supports it, otherwise driver.ErrSkip is returned so the default
+// conversion is used.
+func (s *sqlStmt) CheckNamedValue(value *driver.NamedValue) error {
+	if checker, ok := s.stmt.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(value)
+	}
+	return driver.ErrSkip
+}
+
+// WrapDriver returns a database driver that wraps the given driver, which
+// outputs a structured log message with the given logger for each query
+// executed by its connections, containing the operation, the query (see
+// the QueryWith function, encoded with the given query option) and its
+// latency in nanoseconds. Successful queries are logged with a log level of
+// DEBUG, and failed queries with a log level of ERROR. If the given option
+// is nil, the default query option is used.
+//
+// The returned driver can be registered with the sql.Register function.
+// For example:
+//
+//   sql.Register("logged-postgres", santa.WrapDriver(&pq.Driver { },
+//   	logger, nil))
+func WrapDriver(wrapped driver.Driver, logger *StructLogger,
+	option *QueryOption) driver.Driver {
+	if option == nil {
+		option = &defaultQueryOption
+	}
+	return &sqlDriver { wrapped, &sqlLogger { logger, option } }
+}
+
+// WrapConnector returns a database connector that wraps the given
+// connector, which can be used with the sql.OpenDB function. For details,
+// please refer to the comment section of the WrapDriver function.
+func WrapConnector(wrapped driver.Connector, logger *StructLogger,
+	option *QueryOption) driver.Connector {
+	if option == nil {
+		option = &defaultQueryOption
+	}
+	return &sqlConnector { wrapped, &sqlLogger { logger, option } }
+}
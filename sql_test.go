@@ -0,0 +1,155 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package santa
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type testSQLDriver struct { }
+
+func (d testSQLDriver) Open(string) (driver.Conn, error) {
+	return testSQLConn { }, nil
+}
+
+type testSQLConnector struct { }
+
+func (c testSQLConnector) Connect(context.Context) (driver.Conn, error) {
+	return testSQLConn { }, nil
+}
+
+func (c testSQLConnector) Driver() driver.Driver {
+	return testSQLDriver { }
+}
+
+type testSQLConn struct { }
+
+func (c testSQLConn) Prepare(query string) (driver.Stmt, error) {
+	if strings.Contains(query, "missing") {
+		return nil, errors.New("no such table")
+	}
+	return testSQLStmt { }, nil
+}
+
+func (c testSQLConn) Close() error {
+	return nil
+}
+
+func (c testSQLConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not supported")
+}
+
+type testSQLStmt struct { }
+
+func (s testSQLStmt) Close() error {
+	return nil
+}
+
+func (s testSQLStmt) NumInput() int {
+	return -1
+}
+
+func (s testSQLStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+
+func (s testSQLStmt) Query([]driver.Value) (driver.Rows, error) {
+	return testSQLRows { }, nil
+}
+
+type testSQLRows struct { }
+
+func (r testSQLRows) Columns() []string {
+	return nil
+}
+
+func (r testSQLRows) Close() error {
+	return nil
+}
+
+func (r testSQLRows) Next([]driver.Value) error {
+	return io.EOF
+}
+
+func TestQueryField(t *testing.T) {
+	assert.Equal(t, `SELECT * FROM users WHERE name = 'a  b'`,
+		NormalizeQuery("\n  SELECT *\n\tFROM users   WHERE name = 'a  b'  "),
+		"Unexpected normalized query")
+
+	args := []interface { } { 1, "secret", nil }
+	assert.Equal(t, `{"text": "SELECT 1", "args": 3}`, string(Query("query",
+		" SELECT  1", args).Element.SerializeJSON(nil)),
+		"Unexpected field data")
+	assert.Equal(t, `{"text": "SELECT 1", "args": 3, "types": ` +
+		`["int", "string", "nil"]}`, string(QueryWith("query", "SELECT 1",
+		args, NewQueryOption().UseParameters(QueryParametersTypes)).Element.
+		SerializeJSON(nil)), "Unexpected field data")
+	assert.Equal(t, `{"text": "SELECT 1", "args": 3, "values": ` +
+		`[1, "[REDACTED]", null]}`, string(QueryWith("query", "SELECT 1",
+		args, NewQueryOption().UseParameters(QueryParametersValues).
+		UseRedactor(func(index int, value interface { }) bool {
+			return index == 1
+		})).Element.SerializeJSON(nil)), "Unexpected field data")
+}
+
+func TestWrapConnector(t *testing.T) {
+	writer := &testLockedWriter { }
+	option := NewStructOption().DisableFlushing().DisableCache().
+		DisableSampling()
+	option.Outputting.UseStandard(writer)
+	option.ErrorOutputting.UseStandard(writer)
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	defer logger.Close()
+
+	db := sql.OpenDB(WrapConnector(testSQLConnector { }, logger, nil))
+	defer db.Close()
+	_, err = db.Exec("UPDATE users SET name = ?", "alice")
+	assert.NoError(t, err, "Unexpected exec error")
+	rows, err := db.Query("SELECT name FROM users")
+	assert.NoError(t, err, "Unexpected query error")
+	assert.NoError(t, rows.Close(), "Unexpected close error")
+	_, err = db.Exec("DELETE FROM missing")
+	assert.Error(t, err, "Unexpected exec error")
+
+	output := writer.String()
+	assert.Contains(t, output, `"operation": "exec", "query": {"text": ` +
+		`"UPDATE users SET name = ?", "args": 1}, "latency_ns": `,
+		"Unexpected output data")
+	assert.Contains(t, output, `"operation": "query", "query": {"text": ` +
+		`"SELECT name FROM users", "args": 0}`, "Unexpected output data")
+	assert.Contains(t, output, `"SQL query failed.", "payload": ` +
+		`{"operation": "prepare", "query": {"text": "DELETE FROM missing", ` +
+		`"args": 0}`, "Unexpected output data")
+	assert.Contains(t, output, `"error": "no such table"`,
+		"Unexpected output data")
+}
@@ -0,0 +1,118 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package santa
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newStressLogger(t *testing.T) *StandardLogger {
+	option := NewStandardOption()
+	option.Outputting.UseDiscard()
+	option.ErrorOutputting.UseDiscard()
+	option.Flushing.Interval = time.Millisecond * 100
+
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	return logger
+}
+
+func TestStandardLoggerStress(t *testing.T) {
+	logger := newStressLogger(t)
+
+	var printed int64
+	hook := NewSimpleHook(func(entry *Entry) error {
+		atomic.AddInt64(&printed, 1)
+		return nil
+	})
+
+	var group sync.WaitGroup
+	for worker := 0; worker < 16; worker++ {
+		group.Add(1)
+		go func(worker int) {
+			defer group.Done()
+			for index := 0; index < 200; index++ {
+				instance := logger.Duplicate()
+				if !assert.NotNil(t, instance, "Unexpected nil value") {
+					return
+				}
+				instance.SetLevel(LevelDebug)
+				instance.AddHooks(hook)
+				assert.NoError(t, instance.Info(StringMessage("Hello Test!")),
+					"Unexpected print error")
+
+				logger.SetName("stress")
+				logger.SetLabels(NewLabel("worker", "stress"))
+				logger.SetSampler(nil)
+				logger.SetLevel(Level(index % int(LevelFatal + 1)))
+				logger.AddHooks(hook)
+				if index % 50 == 0 {
+					logger.ResetHooks()
+				}
+				assert.NoError(t, logger.Fatal(StringMessage("Hello Test!")),
+					"Unexpected print error")
+				assert.NoError(t, logger.Sync(), "Unexpected sync error")
+				assert.NoError(t, instance.Close(), "Unexpected close error")
+			}
+		}(worker)
+	}
+	group.Wait()
+
+	assert.NotZero(t, atomic.LoadInt64(&printed), "Hook is not called")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+	assert.Nil(t, logger.Duplicate(), "Unexpected duplicate of closed logger")
+}
+
+func TestStandardLoggerCloseStress(t *testing.T) {
+	logger := newStressLogger(t)
+
+	copies := make([]*StandardLogger, 32)
+	for index := range copies {
+		copies[index] = logger.Duplicate()
+	}
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+
+	var group sync.WaitGroup
+	for index := range copies {
+		group.Add(1)
+		go func(instance *StandardLogger) {
+			defer group.Done()
+			if duplicate := instance.Duplicate(); duplicate != nil {
+				assert.NoError(t, duplicate.Close(), "Unexpected close error")
+			}
+			assert.NoError(t, instance.Close(), "Unexpected close error")
+			assert.Equal(t, ErrClosed, instance.Close(),
+				"Unexpected repeated close result")
+		}(copies[index])
+	}
+	group.Wait()
+
+	assert.Equal(t, int32(0), atomic.LoadInt32(logger.contextReferences),
+		"Unexpected reference count")
+	assert.Nil(t, copies[0].Duplicate(), "Unexpected duplicate of closed logger")
+}
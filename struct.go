@@ -22,7 +22,10 @@
 
 package santa
 
-import "sync/atomic"
+import (
+	"context"
+	"time"
+)
 
 // StructLogger is the structure of a structured logger instance.
 //
@@ -51,74 +54,169 @@ import "sync/atomic"
 // details, please refer to the comment section of the Syncer interface.
 //
 // Unless explicitly stated, the API provided by the logger is
-// thread-safe. It’s worth noting that APIs that allow post-build
-// changes to logger instances are generally not thread-safe. If you
-// need to change the logger instance (including but not limited to:
-// minimum log entry level, etc.), use the Duplicate function to create
-// a copy of the logger instance, and then make changes to the copy of
-// the logger instance.
+// thread-safe, including the APIs that allow post-build changes to
+// logger instances (including but not limited to: minimum log entry
+// level, etc.). Changes only take effect for subsequent log entries of
+// the changed logger instance. If you need a differently configured
+// logger, use the Duplicate function to create a copy of the logger
+// instance, and then make changes to the copy of the logger instance.
 type StructLogger struct {
 	StandardLogger
+
+	fields []Field
+	prefix fieldPrefix
+	ctx context.Context
+	pooled bool
+}
+
+// output outputs a structured log message associated with the given
+// context with the fields bound by the With function followed by the given
+// fields, and then returns any errors encountered. The given stacks is the
+// number of stack frames to skip to find the caller of the logger. If sync
+// is true, the exporters are flushed after the log entry is output. For
+// details, please refer to the comment section of the OutputSync function.
+func (l *StructLogger) output(ctx context.Context, stacks int, level Level,
+	text string, fields []Field, sync bool) error {
+	return l.outputAt(ctx, stacks + 1, time.Time { }, level, text, fields,
+		sync)
+}
+
+// outputAt outputs a structured log message like the output function with
+// the given time. If the given time is zero, the time is read from the time
+// source of the logger. For details, please refer to the comment section
+// of the OutputAt function of the Logger structure.
+func (l *StructLogger) outputAt(ctx context.Context, stacks int, at time.Time,
+	level Level, text string, fields []Field, sync bool) error {
+	var buffer *[]Field
+	if len(l.fields) > 0 {
+		buffer = pool.Buffer.Field.New()
+		*buffer = append(append(*buffer, l.fields...), fields...)
+		fields = *buffer
+	}
+	message := pool.Message.Structure.New(text, fields)
+	if buffer != nil {
+		message.prefix = &l.prefix
+		message.owner = &fields[0]
+	}
+	var err error
+	if sync {
+		err = l.outputSync(ctx, stacks, at, level, message, false)
+	} else {
+		err = l.StandardLogger.outputAt(ctx, stacks, at, level, message)
+	}
+	pool.Message.Structure.Free(message)
+	if buffer != nil {
+		pool.Buffer.Field.Free(buffer)
+	}
+	return err
 }
 
 // Prints outputs a structured log message with a given log level,
 // given description text and fields, and then returns any errors
 // encountered.
 func (l *StructLogger) Prints(level Level, text string, fields ...Field) error {
-	message := pool.Message.Structure.New(text, fields)
-	err := l.Output(2, level, message)
-	pool.Message.Structure.Free(message)
-	return err
+	return l.output(l.ctx, 3, level, text, fields, false)
+}
+
+// PrintsAt outputs a structured log message with a given time, log level,
+// description text and fields, and then returns any errors encountered.
+// Unlike the Prints function, the time of the log entry is the given time
+// instead of the current time, which is preserved by encoders and
+// exporters, so that tools that backfill, migrate or replay log entries
+// can keep their original times. For details, please refer to the comment
+// section of the TimeOverridden field of the Entry structure.
+func (l *StructLogger) PrintsAt(at time.Time, level Level, text string,
+	fields ...Field) error {
+	return l.outputAt(l.ctx, 3, at, level, text, fields, false)
+}
+
+// PrintsSync outputs a structured log message with a given log level,
+// given description text and fields, and then returns any errors
+// encountered after the log entry has been handed to the operating system.
+// For details, please refer to the comment section of the OutputSync
+// function.
+func (l *StructLogger) PrintsSync(level Level, text string, fields ...Field) error {
+	return l.output(l.ctx, 3, level, text, fields, true)
+}
+
+// PrintsFields outputs a structured log message with a given log level,
+// given description text and the given slice of fields, and then returns
+// any errors encountered.
+//
+// Unlike the Prints function, the logger takes ownership of the given
+// slice for the duration of the call and uses it as the fields of the
+// message as it is, so call sites that already hold a slice of fields (for
+// example: a FieldBuilder) do not need to build a variadic argument list.
+// The slice must not be modified until the function returns, after which
+// the caller can reuse it.
+func (l *StructLogger) PrintsFields(level Level, text string, fields []Field) error {
+	return l.output(l.ctx, 3, level, text, fields, false)
+}
+
+// OutputFields outputs a structured log message with a given log level,
+// given description text and the given slice of fields, and then returns
+// any errors encountered. The given stacks is the number of stack frames
+// to skip to find the caller of the logger, where 1 is the caller of the
+// function. For details about the slice of fields, please refer to the
+// comment section of the PrintsFields function.
+//
+// Please note that this is a low-level API intended for adapters that
+// forward log messages from other logging APIs to the structured logger,
+// so that the source location of each log entry is the caller of the
+// adapter instead of the adapter itself.
+func (l *StructLogger) OutputFields(stacks int, level Level, text string,
+	fields []Field) error {
+	return l.output(l.ctx, stacks + 2, level, text, fields, false)
+}
+
+// Dump outputs a structured log message with a log level of DEBUG, whose
+// only field has the given name and the given value dumped (see the Dump
+// function), and then returns any errors encountered.
+//
+// If the DEBUG level is not enabled, it returns immediately without
+// creating the field, so dumps of large objects left in the code cost
+// nothing in production.
+func (l *StructLogger) Dump(name string, value interface { }) error {
+	if !l.Level().Enabled(LevelDebug) {
+		return nil
+	}
+	return l.output(l.ctx, 3, LevelDebug, "dump",
+		[]Field { Dump(name, value) }, false)
 }
 
 // Debugs outputs a structured log message with a log level of DEBUG,
 // given description text and fields, and then returns any errors
 // encountered.
 func (l *StructLogger) Debugs(text string, fields ...Field) error {
-	message := pool.Message.Structure.New(text, fields)
-	err := l.Output(2, LevelDebug, message)
-	pool.Message.Structure.Free(message)
-	return err
+	return l.output(l.ctx, 3, LevelDebug, text, fields, false)
 }
 
 // Infos outputs a structured log message with a log level of INFO,
 // given description text and fields, and then returns any errors
 // encountered.
 func (l *StructLogger) Infos(text string, fields ...Field) error {
-	message := pool.Message.Structure.New(text, fields)
-	err := l.Output(2, LevelInfo, message)
-	pool.Message.Structure.Free(message)
-	return err
+	return l.output(l.ctx, 3, LevelInfo, text, fields, false)
 }
 
 // Warnings outputs a structured log message with a log level of WARNING,
 // given description text and fields, and then returns any errors
 // encountered.
 func (l *StructLogger) Warnings(text string, fields ...Field) error {
-	message := pool.Message.Structure.New(text, fields)
-	err := l.Output(2, LevelWarning, message)
-	pool.Message.Structure.Free(message)
-	return err
+	return l.output(l.ctx, 3, LevelWarning, text, fields, false)
 }
 
 // Errors outputs a structured log message with a log level of ERROR,
 // given description text and fields, and then returns any errors
 // encountered.
 func (l *StructLogger) Errors(text string, fields ...Field) error {
-	message := pool.Message.Structure.New(text, fields)
-	err := l.Output(2, LevelError, message)
-	pool.Message.Structure.Free(message)
-	return err
+	return l.output(l.ctx, 3, LevelError, text, fields, false)
 }
 
 // Fatals outputs a structured log message with a log level of FATAL,
 // given description text and fields, and then returns any errors
 // encountered.
 func (l *StructLogger) Fatals(text string, fields ...Field) error {
-	message := pool.Message.Structure.New(text, fields)
-	err := l.Output(2, LevelFatal, message)
-	pool.Message.Structure.Free(message)
-	return err
+	return l.output(l.ctx, 3, LevelFatal, text, fields, false)
 }
 
 // Duplicate creates and returns a copy of the logger. If the logger is
@@ -127,13 +225,108 @@ func (l *StructLogger) Fatals(text string, fields ...Field) error {
 // Please note that the application must explicitly close each copy of
 // the logger, otherwise the logger may be leaked.
 func (l *StructLogger) Duplicate() *StructLogger {
-	if atomic.AddInt32(l.contextReferences, 1) == 1 {
-		// The logger has been shut down, and using the created copy
-		// may cause panic.
+	if !l.acquire() {
 		return nil
 	}
-	instance := *l
-	return &instance
+	instance := &StructLogger {
+		StandardLogger: *l.StandardLogger.duplicate(),
+		fields: append([]Field(nil), l.fields...),
+		ctx: l.ctx,
+	}
+	instance.prefix.reset(instance.fields)
+	trackLeak(instance, (*StructLogger).closedForLeak)
+	return instance
+}
+
+// derive gets a logger from the pool, initializes it as a copy of the
+// logger with the given context and the given fields appended to the bound
+// fields, and then returns it. If the logger is closed, it returns nil.
+func (l *StructLogger) derive(ctx context.Context, fields []Field) *StructLogger {
+	if !l.acquire() {
+		return nil
+	}
+	instance := pool.Logger.Structure.New()
+	l.StandardLogger.duplicateTo(&instance.StandardLogger)
+	instance.fields = append(append(instance.fields[ : 0], l.fields...),
+		fields...)
+	instance.prefix.reset(instance.fields)
+	instance.ctx = ctx
+	instance.pooled = true
+	return instance
+}
+
+// With creates and returns a logger derived from the logger, which adds
+// the given fields before the fields of each structured log message it
+// outputs. If the logger is closed, it returns nil.
+//
+// The bound fields are serialized once for each encoder the first time
+// they are encoded, and the cached data is then appended to each log entry,
+// so that the bound fields cost almost nothing to encode. Please note that
+// changes to the values referenced by the bound fields after they have been
+// encoded are not reflected.
+//
+// Derived loggers are taken from a pool to avoid allocating a logger for
+// each request, for example in HTTP or gRPC middleware. The application
+// must call the Release function of the derived logger after it is no
+// longer used, usually at the end of the request.
+func (l *StructLogger) With(fields ...Field) *StructLogger {
+	return l.derive(l.ctx, fields)
+}
+
+// Named creates and returns a logger derived from the logger, whose name
+// is the name of the logger followed by the name separator and the given
+// name (for example: "app" and "db" are joined as "app.db"). If the logger
+// has no name, the given name is used as is. If the logger is closed, it
+// returns nil.
+//
+// Like the With function, the application must call the Release function
+// of the derived logger after it is no longer used.
+func (l *StructLogger) Named(name string) *StructLogger {
+	instance := l.derive(l.ctx, nil)
+	if instance == nil || len(name) == 0 {
+		return instance
+	}
+	instance.update(func(config *loggerConfig) {
+		if len(config.name) == 0 {
+			config.name = name
+		} else {
+			config.name += config.nameSeparator + name
+		}
+	})
+	return instance
+}
+
+// WithContext creates and returns a logger derived from the logger, which
+// associates the given context with each log entry it outputs. For details,
+// please refer to the comment section of the Context field of the Entry
+// structure and the With function. If the logger is closed, it returns nil.
+//
+// The application must call the Release function of the derived logger
+// after it is no longer used.
+func (l *StructLogger) WithContext(ctx context.Context) *StructLogger {
+	return l.derive(ctx, nil)
+}
+
+// Release closes the logger, and then returns it to the pool if it was
+// derived by the With or WithContext function. Finally, any errors
+// encountered are returned. For details, please refer to the comment
+// section of the Close function.
+//
+// After it is released, the logger is not allowed to be used again,
+// otherwise the behavior is undefined.
+func (l *StructLogger) Release() error {
+	err := l.Close()
+	if err != nil || !l.pooled {
+		return err
+	}
+	for index := 0; index < len(l.fields); index++ {
+		l.fields[index] = Field { }
+	}
+	l.fields = l.fields[ : 0]
+	l.prefix.reset(nil)
+	l.ctx = nil
+	pool.Logger.Structure.Free(l)
+	return nil
 }
 
 // StructOption is a structure that contains options for structured
@@ -214,12 +407,13 @@ func (o *StructOption) UseFlushing(option *FlushingOption) *StructOption {
 	return o
 }
 
-// DisableCache Disable the internal cache of output and error output. For
-// details, please refer to the DisableCache option of the OutputtingOption
-// structure. Then return to the option instance itself.
+// DisableCache Disable the internal cache of output, error output and
+// fallback output. For details, please refer to the DisableCache option of
+// the OutputtingOption structure. Then return to the option instance itself.
 func (o *StructOption) DisableCache() *StructOption {
 	o.Outputting.DisableCache = true
 	o.ErrorOutputting.DisableCache = true
+	o.FallbackOutputting.DisableCache = true
 	return o
 }
 
@@ -241,13 +435,15 @@ func (o *StructOption) DisableFlushing() *StructOption {
 
 // Build builds and returns a structured logger instance.
 func (o *StructOption) Build() (*StructLogger, error) {
-	logger, err := o.StandardOption.Build()
+	logger, err := o.StandardOption.build()
 	if err != nil {
 		return nil, err
 	}
-	return &StructLogger {
+	instance := &StructLogger {
 		StandardLogger: *logger,
-	}, nil
+	}
+	trackLeak(instance, (*StructLogger).closedForLeak)
+	return instance, nil
 }
 
 // NewStructOption creates an instance of a structured logger option with
@@ -268,7 +464,13 @@ func NewStruct() (*StructLogger, error) {
 
 // NewStructBenchmark creates and returns an instance of a structured logger
 // suitable for benchmark performance testing and any errors encountered.
-func NewStructBenchmark(sampling bool, encoder string) (*StructLogger, error) {
+//
+// If a latency histogram is given, the latency of each log entry output
+// operation is recorded into it, so that the tail latency can be read after
+// the benchmark. For details, please refer to the Latency option of the
+// StandardOption structure.
+func NewStructBenchmark(sampling bool, encoder string,
+	latency ...*LatencyHistogram) (*StructLogger, error) {
 	option := NewStructOption()
 	switch encoder {
 	case EncoderStandard:
@@ -283,6 +485,9 @@ func NewStructBenchmark(sampling bool, encoder string) (*StructLogger, error) {
 	option.Outputting.UseDiscard()
 	option.ErrorOutputting.UseDiscard()
 	option.UseLevel(LevelDebug)
+	if len(latency) > 0 {
+		option.UseLatency(latency[0])
+	}
 	if !sampling {
 		option.DisableSampling()
 	}
@@ -23,13 +23,16 @@
 package santa
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"hash"
 	"io"
 	"io/ioutil"
 	"net"
 	"os"
 	"runtime"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -93,6 +96,42 @@ type Syncer interface {
 	Close() error
 }
 
+// Flusher is the public interface of synchronizers and exporters that can
+// hand their internal cache to the operating system without waiting for
+// the data cached by the file system to reach the persistent storage
+// device.
+//
+// Synchronizers and exporters that do not implement this interface are
+// flushed by calling their Sync function instead.
+type Flusher interface {
+	// Flush writes the internally cached data to a specific storage device,
+	// but does not write the data cached by the file system to the
+	// persistent storage device.
+	//
+	// Finally, any errors encountered are returned.
+	Flush() error
+}
+
+// LevelSyncer is the public interface of synchronizers that react to the
+// level of the log entries written to them.
+//
+// After an exporter writes the encoded data of a log entry to a synchronizer
+// that implements this interface, it calls the SyncLevel function with the
+// level of the log entry.
+type LevelSyncer interface {
+	// SyncLevel is called after the data of a log entry with the given
+	// level has been written, and then returns any errors encountered.
+	SyncLevel(level Level) error
+}
+
+// BufferedSyncer is the public interface of synchronizers that can report
+// the size of their internal cache, which is the depth of the queue of log
+// entry data that has not yet been written to a specific storage device.
+type BufferedSyncer interface {
+	// Buffered returns the number of bytes of the internally cached data.
+	Buffered() int
+}
+
 // SyncerOption is a structure containing basic synchronizer options.
 //
 // The synchronizer options include basic synchronizer options. Normally,
@@ -108,6 +147,31 @@ type SyncerOption struct {
 	CacheCapacity int
 }
 
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+//
+// Please note that the Build function of the synchronizer options adjusts
+// a cache capacity less than 1,024 bytes to 1,024 bytes before validating
+// the options, but this function reports it as invalid.
+func (o SyncerOption) Validate() error {
+	if o.CacheCapacity < 0 {
+		return newOptionError("CacheCapacity", "must not be negative", nil)
+	}
+	if o.CacheCapacity > 0 && o.CacheCapacity < 1024 {
+		return newOptionError("CacheCapacity",
+			"must be 0 or greater than or equal to 1024", nil)
+	}
+	return nil
+}
+
+// adjust adjusts a cache capacity less than 1,024 bytes to 1,024 bytes.
+func (o *SyncerOption) adjust() {
+	if o.CacheCapacity < 1024 && o.CacheCapacity > 0 {
+		o.CacheCapacity = 1024
+	}
+}
+
 // NewSyncerOption returns the value of a synchronizer option with the
 // default optional value.
 func NewSyncerOption() SyncerOption {
@@ -116,6 +180,27 @@ func NewSyncerOption() SyncerOption {
 	}
 }
 
+// syncerCache is the internal cache of a synchronizer. It implements the
+// io.WriterTo interface, so the cached data is handed to a specific storage
+// device in place, without being copied to an intermediate buffer.
+type syncerCache []byte
+
+// WriteTo writes the cached data to the given writer, and then returns
+// the actual number of bytes written and any errors encountered. The data
+// that has been written is removed from the cache, and the data that has
+// not been written is kept for the next call.
+func (c *syncerCache) WriteTo(writer io.Writer) (int64, error) {
+	size, err := writer.Write(*c)
+	if err != nil {
+		if size > 0 {
+			*c = append((*c)[ : 0], (*c)[size : ]...)
+		}
+		return int64(size), err
+	}
+	*c = (*c)[ : 0]
+	return int64(size), nil
+}
+
 // StandardSyncer is the structure of a standard synchronizer instance.
 //
 // The standard synchronizer uses an instance that implements the io.Writer
@@ -127,9 +212,14 @@ func NewSyncerOption() SyncerOption {
 // the synchronizer is not thread-safe.
 type StandardSyncer struct {
 	writer io.Writer
-	buffer []byte
+	buffer syncerCache
 	capacity int
 	mutex *SpinLock
+
+	// vectored represents whether the writer supports vectored writes,
+	// in which case the internal cache and the data that saturates it are
+	// written by a single call.
+	vectored bool
 }
 
 // flush writes the data stored in the internal cache to a specific storage
@@ -139,21 +229,33 @@ type StandardSyncer struct {
 // Please note that this function is not thread-safe.
 func (s *StandardSyncer) flush() (int, error) {
 	suspended := s.mutex != nil && s.mutex.Suspend()
-	size, err := s.writer.Write(s.buffer)
-	if err != nil {
-		if size > 0 {
-			s.buffer = append(s.buffer[ : 0], s.buffer[size : ]...)
-		}
-		if suspended {
-			s.mutex.Resume()
-		}
-		return size, err
+	size, err := s.buffer.WriteTo(s.writer)
+	if suspended {
+		s.mutex.Resume()
 	}
-	s.buffer = s.buffer[ : 0]
+	return int(size), err
+}
+
+// writeVectored writes the data stored in the internal cache and the data
+// of a given buffer slice to a specific storage device by a single vectored
+// write (for example: writev(2)), and then returns the actual number of
+// bytes of the given buffer slice written and any errors encountered.
+//
+// Please note that this function is not thread-safe.
+func (s *StandardSyncer) writeVectored(buffer []byte) (int, error) {
+	suspended := s.mutex != nil && s.mutex.Suspend()
+	buffers := net.Buffers { s.buffer, buffer }
+	size, err := buffers.WriteTo(s.writer)
 	if suspended {
 		s.mutex.Resume()
 	}
-	return size, nil
+	cached := int64(len(s.buffer))
+	if size < cached {
+		s.buffer = append(s.buffer[ : 0], s.buffer[size : ]...)
+		return 0, err
+	}
+	s.buffer = s.buffer[ : 0]
+	return int(size - cached), err
 }
 
 // Write writes the data of a given buffer slice to a specific storage
@@ -169,6 +271,13 @@ func (s *StandardSyncer) Write(buffer []byte) (int, error) {
 	}
 	if s.buffer != nil {
 		size := len(s.buffer) + len(buffer)
+		if size >= s.capacity && s.vectored && len(s.buffer) > 0 {
+			size, err := s.writeVectored(buffer)
+			if s.mutex != nil {
+				s.mutex.Unlock()
+			}
+			return size, err
+		}
 		if size >= s.capacity {
 			_, err := s.flush()
 			if err != nil {
@@ -197,6 +306,35 @@ func (s *StandardSyncer) Write(buffer []byte) (int, error) {
 	return size, err
 }
 
+// Buffered returns the number of bytes of the internally cached data that
+// has not yet been written to a specific storage device.
+func (s *StandardSyncer) Buffered() int {
+	if s.mutex != nil {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+	}
+	return len(s.buffer)
+}
+
+// Flush writes the internally cached data to a specific storage device.
+// Unlike the Sync function, the data cached by the file system is not
+// written to the persistent storage device.
+//
+// Finally, any errors encountered are returned.
+func (s *StandardSyncer) Flush() error {
+	if s.mutex != nil {
+		s.mutex.LockAndSuspend()
+	}
+	var err error
+	if len(s.buffer) > 0 {
+		_, err = s.flush()
+	}
+	if s.mutex != nil {
+		s.mutex.UnlockAndResume()
+	}
+	return err
+}
+
 // Sync writes the internally cached data to a specific storage device.
 // If the specific storage device is based on the file system, write the
 // data cached by the file system to the persistent storage device.
@@ -215,8 +353,16 @@ func (s *StandardSyncer) Sync() error {
 			return err
 		}
 	}
-	handle, ok := s.writer.(*os.File)
-	if !ok {
+	var handle *os.File
+	switch writer := s.writer.(type) {
+	case *os.File:
+		handle = writer
+	case *fileVerifier:
+		handle = writer.file
+	case *fileRotator:
+		handle = writer.file
+	}
+	if handle == nil {
 		if s.mutex != nil {
 			s.mutex.UnlockAndResume()
 		}
@@ -278,21 +424,45 @@ func (o *StandardSyncerOption) UseWriter(writer io.Writer) *StandardSyncerOption
 	return o
 }
 
-// Build builds and returns a standard synchronizer instance.
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *StandardSyncerOption) Validate() error {
+	if o.Writer == nil {
+		return newOptionError("Writer", "must not be nil", nil)
+	}
+	if o.DisableMutex {
+		return nil
+	}
+	return o.SyncerOption.Validate()
+}
+
+// Build builds and returns a standard synchronizer instance. If the values
+// of the options are invalid, an OptionError is returned. For details,
+// please refer to the comment section of the Validate function.
 func (o *StandardSyncerOption) Build() (*StandardSyncer, error) {
+	if !o.DisableMutex {
+		o.adjust()
+	}
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
 	var buffer []byte
 	var mutex *SpinLock
 	if !o.DisableMutex {
-		if o.CacheCapacity < 1024 && o.CacheCapacity > 0 {
-			o.CacheCapacity = 1024
-		}
 		if o.CacheCapacity > 0 {
 			buffer = make([]byte, 0, o.CacheCapacity)
 		}
 		mutex = NewSpinLock()
 	}
+	// Consoles whose output code page is not UTF-8 are written in UTF-16
+	// on Windows, so that non-ASCII characters are not garbled.
+	writer := o.Writer
+	if file, ok := writer.(*os.File); ok {
+		writer = consoleWriter(file)
+	}
 	return &StandardSyncer {
-		writer: o.Writer,
+		writer: writer,
 		buffer: buffer,
 		capacity: o.CacheCapacity,
 		mutex: mutex,
@@ -317,12 +487,88 @@ func NewStandardSyncer() (*StandardSyncer, error) {
 // FileSyncer is the structure of the file synchronizer instance.
 //
 // The file synchronizer is based on the standard synchronizer and
-// uses a file on the local hard disk as a specific storage device.
+// uses a file on the local hard disk as a specific storage device. The
+// file can be rotated when it exceeds a maximum size, and the rotated
+// backup files can be pruned by count and age. For details, please refer
+// to the comment section of the MaxSize option of the FileSyncerOption
+// structure.
 //
 // Please note that if the mutex is disabled, the API provided by
 // the synchronizer is not thread-safe.
 type FileSyncer struct {
 	*StandardSyncer
+
+	rotator *fileRotator
+	verifier *fileVerifier
+	policy FsyncPolicy
+	synced int64
+	closed int32
+}
+
+// verify verifies the last written block of the file after the given error
+// of a flush, if read-back verification is enabled and due. For details,
+// please refer to the comment section of the Verify option of the
+// FileSyncerOption structure.
+//
+// Finally, any errors encountered are returned.
+func (s *FileSyncer) verify(err error) error {
+	if err != nil || s.verifier == nil || !s.verifier.due() {
+		return err
+	}
+	err = s.verifier.verify()
+	if err != nil && s.verifier.handler != nil {
+		s.verifier.handler(err)
+		return nil
+	}
+	return err
+}
+
+// Flush writes the internally cached data to the file, but does not write
+// the data cached by the file system to the persistent storage device.
+//
+// Finally, any errors encountered are returned.
+func (s *FileSyncer) Flush() error {
+	return s.verify(s.StandardSyncer.Flush())
+}
+
+// Sync writes the internally cached data to the file, and then writes the
+// data cached by the file system to the persistent storage device if the
+// fsync policy allows it. For details, please refer to the comment section
+// of the FsyncPolicy structure.
+//
+// Finally, any errors encountered are returned.
+func (s *FileSyncer) Sync() error {
+	switch s.policy.Mode {
+	case FsyncModeNever, FsyncModeOnLevel:
+		return s.Flush()
+	case FsyncModeInterval:
+		now := time.Now().UnixNano()
+		last := atomic.LoadInt64(&s.synced)
+		if now - last < int64(s.policy.Interval) ||
+			!atomic.CompareAndSwapInt64(&s.synced, last, now) {
+			return s.Flush()
+		}
+	}
+	return s.verify(s.StandardSyncer.Sync())
+}
+
+// SyncLevel writes the internally cached data and the data cached by the
+// file system to the persistent storage device if the fsync policy is
+// FsyncModeOnLevel and the given level is greater than or equal to the
+// level of the policy. Otherwise, it does nothing.
+//
+// Finally, any errors encountered are returned.
+func (s *FileSyncer) SyncLevel(level Level) error {
+	if s.policy.Mode != FsyncModeOnLevel || level < s.policy.Level {
+		return nil
+	}
+	return s.verify(s.StandardSyncer.Sync())
+}
+
+// closedForLeak checks whether the synchronizer has been closed for leak
+// detection.
+func (s *FileSyncer) closedForLeak() bool {
+	return atomic.LoadInt32(&s.closed) == 1
 }
 
 // Close automatically flushes the internal cache once, and then releases
@@ -331,8 +577,283 @@ type FileSyncer struct {
 //
 // Finally, any errors encountered are returned.
 func (s *FileSyncer) Close() error {
-	_ = s.StandardSyncer.Close()
-	return s.writer.(*os.File).Close()
+	atomic.StoreInt32(&s.closed, 1)
+	if s.policy.Mode == FsyncModeNever {
+		_ = s.Flush()
+	} else {
+		_ = s.StandardSyncer.Close()
+	}
+	return s.rotator.close()
+}
+
+var (
+	// ErrVerifyMismatch represents that the checksum of the data read back
+	// from a file does not match the checksum of the data written to it.
+	// This is usually because a write failed silently.
+	ErrVerifyMismatch = errors.New("checksum mismatch")
+)
+
+// VerifyError is a structure that contains an error of a read-back
+// verification of a file synchronizer. For details, please refer to the
+// comment section of the Verify option of the FileSyncerOption structure.
+type VerifyError struct {
+	// Name represents the path name of the verified file.
+	Name string
+
+	// Offset represents the offset of the verified block in the file.
+	Offset int64
+
+	// Size represents the number of bytes of the verified block.
+	Size int
+
+	// Err represents the underlying error, which is ErrVerifyMismatch if
+	// the checksums do not match, or the error encountered by reading the
+	// block back.
+	Err error
+}
+
+// Error returns the description string of the error.
+func (e *VerifyError) Error() string {
+	return "verify " + e.Name + " at offset " +
+		strconv.FormatInt(e.Offset, 10) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error of the read-back verification.
+func (e *VerifyError) Unwrap() error {
+	return e.Err
+}
+
+// fileVerifier is the writer of a file synchronizer in read-back
+// verification mode. It writes to the file, and keeps the last block of
+// written data and its offset, so that the block can be read back and
+// verified.
+type fileVerifier struct {
+	file *os.File
+	handler func(err error)
+	interval int64
+	verified int64
+
+	mutex sync.Mutex
+	offset int64
+	size int
+	block []byte
+	hash hash.Hash
+}
+
+// newFileVerifier creates and returns a file verifier of the given file,
+// which is opened in append mode, and then returns any errors encountered.
+func newFileVerifier(file *os.File, size int, interval time.Duration,
+	handler func(err error)) (*fileVerifier, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if size <= 0 {
+		size = 4096
+	}
+	// The blocks are checksummed with CRC-32C, or SHA-256 in FIPS mode.
+	hash, err := newHash(checksumAlgorithm(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return &fileVerifier {
+		file: file,
+		handler: handler,
+		interval: int64(interval),
+		offset: info.Size(),
+		size: size,
+		block: make([]byte, 0, size),
+		hash: hash,
+	}, nil
+}
+
+// Write writes the data of a given buffer slice to the file, and keeps the
+// last block of written data.
+//
+// Finally, it returns the number of bytes actually written and any
+// errors encountered.
+func (v *fileVerifier) Write(buffer []byte) (int, error) {
+	size, err := v.file.Write(buffer)
+	v.mutex.Lock()
+	v.offset += int64(size)
+	if size >= v.size {
+		v.block = append(v.block[ : 0], buffer[size - v.size : size]...)
+	} else {
+		if overflow := len(v.block) + size - v.size; overflow > 0 {
+			v.block = v.block[ : copy(v.block, v.block[overflow : ])]
+		}
+		v.block = append(v.block, buffer[ : size]...)
+	}
+	v.mutex.Unlock()
+	return size, err
+}
+
+// reset makes the verifier write to the given file, whose size is the
+// given offset, after the file has been rotated.
+func (v *fileVerifier) reset(file *os.File, offset int64) {
+	v.mutex.Lock()
+	v.file = file
+	v.offset = offset
+	v.block = v.block[ : 0]
+	v.mutex.Unlock()
+}
+
+// due checks whether the next read-back verification is due, and then
+// returns true if it is.
+func (v *fileVerifier) due() bool {
+	if v.interval == 0 {
+		return true
+	}
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&v.verified)
+	return now - last >= v.interval &&
+		atomic.CompareAndSwapInt64(&v.verified, last, now)
+}
+
+// checksum returns the checksum of the given data. The caller must hold
+// the mutex.
+func (v *fileVerifier) checksum(data []byte) []byte {
+	v.hash.Reset()
+	v.hash.Write(data)
+	return v.hash.Sum(nil)
+}
+
+// verify reads the last block of written data back from the file, and then
+// returns a VerifyError if its checksum does not match the checksum of the
+// written data, or if it cannot be read back.
+func (v *fileVerifier) verify() error {
+	v.mutex.Lock()
+	defer v.mutex.Unlock()
+	if len(v.block) == 0 {
+		return nil
+	}
+	offset := v.offset - int64(len(v.block))
+	buffer := make([]byte, len(v.block))
+	_, err := v.file.ReadAt(buffer, offset)
+	if err == nil && !bytes.Equal(v.checksum(buffer), v.checksum(v.block)) {
+		err = ErrVerifyMismatch
+	}
+	if err != nil {
+		return &VerifyError {
+			Name: v.file.Name(),
+			Offset: offset,
+			Size: len(v.block),
+			Err: err,
+		}
+	}
+	return nil
+}
+
+// FsyncMode represents when the file synchronizer writes the data cached by
+// the file system to the persistent storage device.
+type FsyncMode int
+
+const (
+	// FsyncModeEveryFlush represents that the data cached by the file system
+	// is written to the persistent storage device every time the Sync
+	// function is called, including automatic flushing.
+	FsyncModeEveryFlush FsyncMode = iota
+
+	// FsyncModeNever represents that the data cached by the file system is
+	// never written explicitly, and the operating system decides when to
+	// write it to the persistent storage device.
+	FsyncModeNever
+
+	// FsyncModeInterval represents that the data cached by the file system
+	// is written to the persistent storage device by the Sync function at
+	// most once per interval.
+	FsyncModeInterval
+
+	// FsyncModeOnLevel represents that the data cached by the file system
+	// is written to the persistent storage device only after a log entry
+	// with a level greater than or equal to a given level is written.
+	FsyncModeOnLevel
+)
+
+// FsyncPolicy is a structure that describes when the file synchronizer
+// writes the data cached by the file system to the persistent storage
+// device. Regardless of the policy, the Sync function always writes the
+// internal cache to the file.
+//
+// The policy makes the durability and performance trade-off explicit:
+// writing the file system cache is the most expensive part of the Sync
+// function. Use the FsyncNever, FsyncInterval, FsyncEveryFlush and
+// FsyncOnLevel functions to create a policy.
+type FsyncPolicy struct {
+	// Mode represents when the data cached by the file system is written
+	// to the persistent storage device. If not provided, the default value
+	// is FsyncModeEveryFlush.
+	Mode FsyncMode
+
+	// Interval represents the minimum interval between two writes of the
+	// file system cache. It is only used by the FsyncModeInterval mode.
+	Interval time.Duration
+
+	// Level represents the lowest level of log entries that write the file
+	// system cache. It is only used by the FsyncModeOnLevel mode.
+	Level Level
+}
+
+// Validate checks whether the values of the policy are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (p FsyncPolicy) Validate() error {
+	switch p.Mode {
+	case FsyncModeEveryFlush, FsyncModeNever:
+		return nil
+	case FsyncModeInterval:
+		if p.Interval <= 0 {
+			return newOptionError("Interval", "must be greater than 0", nil)
+		}
+		return nil
+	case FsyncModeOnLevel:
+		if p.Level > LevelFatal {
+			return newOptionError("Level", "unknown level " +
+				p.Level.String(), ErrInvalidLevel)
+		}
+		return nil
+	default:
+		return newOptionError("Mode", "unsupported fsync mode", ErrInvalidType)
+	}
+}
+
+// FsyncNever returns a policy that never writes the data cached by the file
+// system explicitly. For details, please refer to the comment section of
+// the FsyncModeNever constant.
+func FsyncNever() FsyncPolicy {
+	return FsyncPolicy {
+		Mode: FsyncModeNever,
+	}
+}
+
+// FsyncInterval returns a policy that writes the data cached by the file
+// system at most once per given interval. For details, please refer to the
+// comment section of the FsyncModeInterval constant.
+func FsyncInterval(interval time.Duration) FsyncPolicy {
+	return FsyncPolicy {
+		Mode: FsyncModeInterval,
+		Interval: interval,
+	}
+}
+
+// FsyncEveryFlush returns a policy that writes the data cached by the file
+// system every time the Sync function is called. For details, please refer
+// to the comment section of the FsyncModeEveryFlush constant.
+func FsyncEveryFlush() FsyncPolicy {
+	return FsyncPolicy {
+		Mode: FsyncModeEveryFlush,
+	}
+}
+
+// FsyncOnLevel returns a policy that writes the data cached by the file
+// system after a log entry with a level greater than or equal to the given
+// level is written. For details, please refer to the comment section of the
+// FsyncModeOnLevel constant.
+func FsyncOnLevel(level Level) FsyncPolicy {
+	return FsyncPolicy {
+		Mode: FsyncModeOnLevel,
+		Level: level,
+	}
 }
 
 // FileSyncerOption is a structure containing file synchronizer options.
@@ -343,6 +864,105 @@ type FileSyncerOption struct {
 	// as a specific storage device. If not provided, the default value is
 	// os.DevNull.
 	FileName string
+
+	// FsyncPolicy represents when the data cached by the file system is
+	// written to the persistent storage device. For details, please refer
+	// to the comment section of the FsyncPolicy structure. If not provided,
+	// the default value is the FsyncEveryFlush policy.
+	FsyncPolicy FsyncPolicy
+
+	// Preallocate represents the number of bytes of disk space allocated
+	// for the file when it is opened, so that subsequent writes do not
+	// need to allocate file extents. The file size is not changed. This
+	// option is only supported on Linux and is ignored on other platforms
+	// or file systems that do not support it. If not provided, the default
+	// value is 0, which means that no disk space is preallocated.
+	Preallocate int64
+
+	// AdviseSequential represents whether to advise the kernel that the
+	// file is accessed sequentially, which may improve the performance of
+	// the file system cache. This option is only a hint and is only
+	// supported on Linux. If not provided, the default value is false.
+	AdviseSequential bool
+
+	// DataSync represents whether to open the file with the O_DSYNC flag,
+	// so that each write to the file returns only after the data has been
+	// written to the persistent storage device. Platforms without O_DSYNC
+	// use O_SYNC instead. It is usually used with the internal cache to
+	// reduce the number of writes. If not provided, the default value is
+	// false.
+	DataSync bool
+
+	// Verify represents whether the file synchronizer runs in read-back
+	// verification mode, in which the last block of data written to the
+	// file is read back after each flush and its checksum is compared with
+	// the checksum of the written data, so that silent write failures (for
+	// example: on flaky network file systems) are detected. If not
+	// provided, the default value is false.
+	//
+	// Please note that the position of the last block is tracked by the
+	// file synchronizer, so the file must not be written by other
+	// processes or synchronizers.
+	Verify bool
+
+	// VerifyInterval represents the minimum interval between two read-back
+	// verifications. It is only used if the Verify option is enabled. If
+	// not provided, the default value is 0, which means that the file is
+	// verified after each flush.
+	VerifyInterval time.Duration
+
+	// VerifySize represents the maximum number of bytes of the last block
+	// of data that is read back. It is only used if the Verify option is
+	// enabled. If not provided, the default value is 4096.
+	VerifySize int
+
+	// ErrorHandler represents the function that is called with the errors
+	// of read-back verifications, which are VerifyError errors. If not
+	// provided, the errors are returned by the Flush and Sync functions.
+	ErrorHandler func(err error)
+
+	// MaxSize represents the maximum size in bytes of the file. When
+	// writing to the file would make it exceed the maximum size, the file
+	// is renamed to a backup file named with the current time (for
+	// example: "app-2006-01-02T15-04-05.000.log", see the
+	// RotationTimeLayout constant), and a new file with the same name is
+	// opened. The internal cache is written to the file as a whole, so it
+	// is never split across files. If not provided, the default value is
+	// 0, which means that the file is only rotated by the Rotate function
+	// of the FileSyncer structure.
+	//
+	// Please note that file synchronizers of the same file (for example:
+	// the output and the error output of a logger using the same file name)
+	// rotate the file together: when any of them rotates it, the others
+	// open the new file before their next write.
+	MaxSize int64
+
+	// MaxBackups represents the maximum number of backup files kept after
+	// each rotation. The oldest backup files are removed first. If not
+	// provided, the default value is 0, which means that backup files are
+	// not removed because of their number.
+	MaxBackups int
+
+	// MaxAge represents the maximum age of the backup files kept after
+	// each rotation, according to the times in their names. If not
+	// provided, the default value is 0, which means that backup files are
+	// not removed because of their age.
+	MaxAge time.Duration
+}
+
+// prepare preallocates disk space for the given opened file and advises
+// the kernel of its access pattern according to the options, and then
+// returns any errors encountered.
+func (o *FileSyncerOption) prepare(handle *os.File) error {
+	if o.Preallocate > 0 && o.FileName != os.DevNull {
+		if err := preallocateFile(handle, o.Preallocate); err != nil {
+			return err
+		}
+	}
+	if o.AdviseSequential {
+		adviseSequential(handle)
+	}
+	return nil
 }
 
 // UseCacheCapacity uses the given capacity as the value of the option
@@ -360,27 +980,155 @@ func (o *FileSyncerOption) UseName(name string) *FileSyncerOption {
 	return o
 }
 
-// Build builds and returns a file synchronizer instance.
+// UseFsyncPolicy uses the given policy as the value of the option
+// FsyncPolicy. For details, please refer to the comment section of the
+// FsyncPolicy option. Then return to the option instance itself.
+func (o *FileSyncerOption) UseFsyncPolicy(policy FsyncPolicy) *FileSyncerOption {
+	o.FsyncPolicy = policy
+	return o
+}
+
+// UsePreallocate uses the given number of bytes as the value of the option
+// Preallocate. For details, please refer to the comment section of the
+// Preallocate option. Then return to the option instance itself.
+func (o *FileSyncerOption) UsePreallocate(size int64) *FileSyncerOption {
+	o.Preallocate = size
+	return o
+}
+
+// UseSequentialAdvice enables the option AdviseSequential. For details,
+// please refer to the comment section of the AdviseSequential option. Then
+// return to the option instance itself.
+func (o *FileSyncerOption) UseSequentialAdvice() *FileSyncerOption {
+	o.AdviseSequential = true
+	return o
+}
+
+// UseDataSync enables the option DataSync. For details, please refer to
+// the comment section of the DataSync option. Then return to the option
+// instance itself.
+func (o *FileSyncerOption) UseDataSync() *FileSyncerOption {
+	o.DataSync = true
+	return o
+}
+
+// UseVerify enables the option Verify, and then uses the given interval
+// as the value of the option VerifyInterval. For details, please refer to
+// the comment section of the Verify option. Then return to the option
+// instance itself.
+func (o *FileSyncerOption) UseVerify(interval time.Duration) *FileSyncerOption {
+	o.Verify = true
+	o.VerifyInterval = interval
+	return o
+}
+
+// UseErrorHandler uses the given function as the value of the option
+// ErrorHandler. Then return to the option instance itself.
+func (o *FileSyncerOption) UseErrorHandler(handler func(err error)) *FileSyncerOption {
+	o.ErrorHandler = handler
+	return o
+}
+
+// UseRotation uses the given maximum size in bytes, maximum number of
+// backup files and maximum age of backup files as the values of the
+// options MaxSize, MaxBackups and MaxAge. For details, please refer to the
+// comment section of the MaxSize option. Then return to the option
+// instance itself.
+func (o *FileSyncerOption) UseRotation(maxSize int64, maxBackups int,
+	maxAge time.Duration) *FileSyncerOption {
+	o.MaxSize = maxSize
+	o.MaxBackups = maxBackups
+	o.MaxAge = maxAge
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+//
+// Please note that the Build function uses os.DevNull if the value of the
+// FileName option is empty, but this function reports it as invalid.
+func (o *FileSyncerOption) Validate() error {
+	if len(o.FileName) == 0 {
+		return newOptionError("FileName", "must not be empty", nil)
+	}
+	if o.Preallocate < 0 {
+		return newOptionError("Preallocate", "must not be negative", nil)
+	}
+	if o.VerifyInterval < 0 {
+		return newOptionError("VerifyInterval", "must not be negative", nil)
+	}
+	if o.Verify && o.VerifySize <= 0 {
+		return newOptionError("VerifySize", "must be greater than 0", nil)
+	}
+	if o.MaxSize < 0 {
+		return newOptionError("MaxSize", "must not be negative", nil)
+	}
+	if o.MaxBackups < 0 {
+		return newOptionError("MaxBackups", "must not be negative", nil)
+	}
+	if o.MaxAge < 0 {
+		return newOptionError("MaxAge", "must not be negative", nil)
+	}
+	if err := o.FsyncPolicy.Validate(); err != nil {
+		return prefixOptionError("FsyncPolicy", err)
+	}
+	return o.SyncerOption.Validate()
+}
+
+// Build builds and returns a file synchronizer instance. If the values of
+// the options are invalid, an OptionError is returned. For details, please
+// refer to the comment section of the Validate function.
 func (o *FileSyncerOption) Build() (*FileSyncer, error) {
 	if len(o.FileName) == 0 {
 		o.FileName = os.DevNull
 	}
-	handle, err := os.OpenFile(o.FileName, os.O_RDWR |
-		os.O_CREATE | os.O_APPEND, os.ModeAppend)
+	o.adjust()
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	flag := os.O_RDWR | os.O_CREATE | os.O_APPEND
+	if o.DataSync {
+		flag |= fileDataSyncFlag
+	}
+	handle, err := os.OpenFile(o.FileName, flag, os.ModeAppend)
 	if err != nil {
 		return nil, err
 	}
+	if err = o.prepare(handle); err != nil {
+		_ = handle.Close()
+		return nil, err
+	}
 	option := NewStandardSyncerOption()
 	option.SyncerOption = o.SyncerOption
-	option.Writer = handle
+	var verifier *fileVerifier
+	if o.Verify && o.FileName != os.DevNull {
+		verifier, err = newFileVerifier(handle, o.VerifySize,
+			o.VerifyInterval, o.ErrorHandler)
+		if err != nil {
+			_ = handle.Close()
+			return nil, err
+		}
+	}
+	rotator, err := newFileRotator(o.FileName, flag, handle, verifier, o)
+	if err != nil {
+		_ = handle.Close()
+		return nil, err
+	}
+	option.Writer = rotator
 	syncer, err := option.Build()
 	if err != nil {
 		_ = handle.Close()
 		return nil, err
 	}
-	return &FileSyncer {
+	instance := &FileSyncer {
 		StandardSyncer: syncer,
-	}, nil
+		rotator: rotator,
+		verifier: verifier,
+		policy: o.FsyncPolicy,
+	}
+	trackLeak(instance, (*FileSyncer).closedForLeak)
+	return instance, nil
 }
 
 // NewFileSyncerOption creates and returns an instance of a file
@@ -389,6 +1137,7 @@ func NewFileSyncerOption() *FileSyncerOption {
 	return &FileSyncerOption {
 		SyncerOption: NewSyncerOption(),
 		FileName: os.DevNull,
+		VerifySize: 4096,
 	}
 }
 
@@ -402,7 +1151,10 @@ func NewFileSyncer() (*FileSyncer, error) {
 // synchronizer.
 //
 // The network synchronizer is based on the standard synchronizer
-// and uses TCP/IP or Unix streams as a specific storage device.
+// and uses TCP/IP or Unix streams as a specific storage device. When
+// the internal cache is saturated, the cached data and the data being
+// written are sent by a single vectored write (net.Buffers), instead of
+// one system call for each of them.
 //
 // Please note that if the mutex is disabled, the API provided by
 // the synchronizer is not thread-safe.
@@ -417,6 +1169,13 @@ type NetworkSyncer struct {
 	contextWaitGroup *sync.WaitGroup
 
 	disconnected int32
+	closed int32
+}
+
+// closedForLeak checks whether the synchronizer has been closed for leak
+// detection.
+func (s *NetworkSyncer) closedForLeak() bool {
+	return atomic.LoadInt32(&s.closed) == 1
 }
 
 func (s *NetworkSyncer) reconnect() {
@@ -479,6 +1238,7 @@ func (s *NetworkSyncer) Write(buffer []byte) (int, error) {
 //
 // Finally, any errors encountered are returned.
 func (s *NetworkSyncer) Close() error {
+	atomic.StoreInt32(&s.closed, 1)
 	s.contextCancel()
 	s.contextWaitGroup.Wait()
 	_ = s.StandardSyncer.Close()
@@ -552,14 +1312,39 @@ func (o *NetworkSyncerOption) UseAddress(address string) *NetworkSyncerOption {
 	return o
 }
 
-// Build builds and returns an instance of the network synchronizer and
-// any errors encountered.
-func (o *NetworkSyncerOption) Build() (*NetworkSyncer, error) {
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *NetworkSyncerOption) Validate() error {
 	switch o.Protocol {
 	case ProtocolTCP:
+		if len(o.Address) == 0 {
+			return newOptionError("Address", "must not be empty", nil)
+		}
+		_, port, err := net.SplitHostPort(o.Address)
+		if err != nil || len(port) == 0 {
+			return newOptionError("Address",
+				"must be in the form of host:port for TCP", nil)
+		}
 	case ProtocolUnix:
+		if len(o.Address) == 0 {
+			return newOptionError("Address", "must not be empty", nil)
+		}
 	default:
-		return nil, ErrInvalidProtocol
+		return newOptionError("Protocol", "unsupported protocol \"" +
+			o.Protocol + "\"", ErrInvalidProtocol)
+	}
+	return o.SyncerOption.Validate()
+}
+
+// Build builds and returns an instance of the network synchronizer and
+// any errors encountered. If the values of the options are invalid, an
+// OptionError is returned. For details, please refer to the comment
+// section of the Validate function.
+func (o *NetworkSyncerOption) Build() (*NetworkSyncer, error) {
+	o.adjust()
+	if err := o.Validate(); err != nil {
+		return nil, err
 	}
 
 	connect, err := net.Dial(o.Protocol, o.Address)
@@ -574,9 +1359,10 @@ func (o *NetworkSyncerOption) Build() (*NetworkSyncer, error) {
 		_ = connect.Close()
 		return nil, err
 	}
+	syncer.vectored = true
 	context, contextCancel := context.WithCancel(
 		context.Background())
-	return &NetworkSyncer {
+	instance := &NetworkSyncer {
 		StandardSyncer: syncer,
 
 		protocol: o.Protocol,
@@ -585,7 +1371,9 @@ func (o *NetworkSyncerOption) Build() (*NetworkSyncer, error) {
 		context: context,
 		contextCancel: contextCancel,
 		contextWaitGroup: &sync.WaitGroup { },
-	}, nil
+	}
+	trackLeak(instance, (*NetworkSyncer).closedForLeak)
+	return instance, nil
 }
 
 // NewNetworkSyncerOption creates and returns a network synchronizer
@@ -624,3 +1412,200 @@ func NewDiscardSyncer() (*DiscardSyncer, error) {
 		StandardSyncer: syncer,
 	}, nil
 }
+
+// FallbackSyncer is the structure of a fallback synchronizer instance.
+//
+// The fallback synchronizer writes log entry data to a primary
+// synchronizer. If the primary synchronizer fails a number of consecutive
+// times (for example: the network is down or the disk is full), the log
+// entry data is routed to a fallback synchronizer instead, starting with
+// the data of the last failed write. While the data is routed to the
+// fallback synchronizer, the primary synchronizer is probed by retrying
+// a write on it at most once per probe interval, and the data is routed
+// back to the primary synchronizer once a probe succeeds.
+//
+// Please note that the data cached by the primary synchronizer when it
+// fails is not moved to the fallback synchronizer.
+type FallbackSyncer struct {
+	probe int64
+	interval int64
+	primary Syncer
+	fallback Syncer
+	threshold int32
+
+	failures int32
+	active int32
+}
+
+// Write writes the data of a given buffer slice to the primary synchronizer,
+// or to the fallback synchronizer if the primary synchronizer has failed.
+// For details, please refer to the comment section of the FallbackSyncer
+// structure.
+//
+// Finally, it returns the number of bytes actually written and any
+// errors encountered.
+func (s *FallbackSyncer) Write(buffer []byte) (int, error) {
+	if atomic.LoadInt32(&s.active) == 1 {
+		clock := time.Now().UnixNano()
+		probe := atomic.LoadInt64(&s.probe)
+		if clock < probe || !atomic.CompareAndSwapInt64(&s.probe, probe,
+			clock + s.interval) {
+			return s.fallback.Write(buffer)
+		}
+		size, err := s.primary.Write(buffer)
+		if err != nil {
+			return s.fallback.Write(buffer)
+		}
+		atomic.StoreInt32(&s.failures, 0)
+		atomic.StoreInt32(&s.active, 0)
+		return size, nil
+	}
+
+	size, err := s.primary.Write(buffer)
+	if err == nil {
+		if atomic.LoadInt32(&s.failures) != 0 {
+			atomic.StoreInt32(&s.failures, 0)
+		}
+		return size, nil
+	}
+	if atomic.AddInt32(&s.failures, 1) < s.threshold {
+		return size, err
+	}
+	atomic.StoreInt64(&s.probe, time.Now().UnixNano() + s.interval)
+	atomic.StoreInt32(&s.active, 1)
+	return s.fallback.Write(buffer)
+}
+
+// Active returns true if the log entry data is currently routed to the
+// fallback synchronizer, otherwise it returns false.
+func (s *FallbackSyncer) Active() bool {
+	return atomic.LoadInt32(&s.active) == 1
+}
+
+// Flush writes the internally cached data of the synchronizer currently in
+// use to a specific storage device. If the synchronizer does not implement
+// the Flusher interface, it is synchronized instead.
+//
+// Finally, any errors encountered are returned.
+func (s *FallbackSyncer) Flush() error {
+	syncer := s.primary
+	if s.Active() {
+		syncer = s.fallback
+	}
+	if flusher, ok := syncer.(Flusher); ok {
+		return flusher.Flush()
+	}
+	return syncer.Sync()
+}
+
+// Sync synchronizes the synchronizer currently in use. For details, please
+// refer to the Sync function of the Syncer interface.
+//
+// Finally, any errors encountered are returned.
+func (s *FallbackSyncer) Sync() error {
+	if s.Active() {
+		return s.fallback.Sync()
+	}
+	return s.primary.Sync()
+}
+
+// Close closes the primary synchronizer and the fallback synchronizer, and
+// then returns any errors encountered.
+func (s *FallbackSyncer) Close() error {
+	var errs MultiError
+	errs = errs.Append(s.primary.Close())
+	errs = errs.Append(s.fallback.Close())
+	return errs.ErrorOrNil()
+}
+
+// FallbackSyncerOption is a structure that contains options for fallback
+// synchronizers.
+type FallbackSyncerOption struct {
+	// Primary represents the synchronizer to which log entry data is
+	// normally written. This option is required.
+	Primary Syncer
+
+	// Fallback represents the synchronizer to which log entry data is
+	// routed when the primary synchronizer fails. This option is required.
+	Fallback Syncer
+
+	// Threshold represents the number of consecutive failed writes of the
+	// primary synchronizer after which log entry data is routed to the
+	// fallback synchronizer. If not provided, the default value is 3.
+	Threshold int
+
+	// ProbeInterval represents the minimum interval between two probes of
+	// the primary synchronizer while log entry data is routed to the
+	// fallback synchronizer. If not provided, the default value is 10
+	// seconds.
+	ProbeInterval time.Duration
+}
+
+// UsePrimary uses the given synchronizer as the value of the option
+// Primary. Then return to the option instance itself.
+func (o *FallbackSyncerOption) UsePrimary(syncer Syncer) *FallbackSyncerOption {
+	o.Primary = syncer
+	return o
+}
+
+// UseFallback uses the given synchronizer as the value of the option
+// Fallback. Then return to the option instance itself.
+func (o *FallbackSyncerOption) UseFallback(syncer Syncer) *FallbackSyncerOption {
+	o.Fallback = syncer
+	return o
+}
+
+// UseThreshold uses the given number of failures as the value of the
+// option Threshold. Then return to the option instance itself.
+func (o *FallbackSyncerOption) UseThreshold(threshold int) *FallbackSyncerOption {
+	o.Threshold = threshold
+	return o
+}
+
+// UseProbeInterval uses the given interval as the value of the option
+// ProbeInterval. Then return to the option instance itself.
+func (o *FallbackSyncerOption) UseProbeInterval(interval time.Duration) *FallbackSyncerOption {
+	o.ProbeInterval = interval
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *FallbackSyncerOption) Validate() error {
+	if o.Primary == nil {
+		return newOptionError("Primary", "must not be nil", nil)
+	}
+	if o.Fallback == nil {
+		return newOptionError("Fallback", "must not be nil", nil)
+	}
+	if o.Threshold <= 0 {
+		return newOptionError("Threshold", "must be greater than 0", nil)
+	}
+	if o.ProbeInterval <= 0 {
+		return newOptionError("ProbeInterval", "must be greater than 0", nil)
+	}
+	return nil
+}
+
+// Build builds and returns a fallback synchronizer instance.
+func (o *FallbackSyncerOption) Build() (*FallbackSyncer, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	return &FallbackSyncer {
+		primary: o.Primary,
+		fallback: o.Fallback,
+		threshold: int32(o.Threshold),
+		interval: int64(o.ProbeInterval),
+	}, nil
+}
+
+// NewFallbackSyncerOption creates and returns a fallback synchronizer
+// option instance with default optional values.
+func NewFallbackSyncerOption() *FallbackSyncerOption {
+	return &FallbackSyncerOption {
+		Threshold: 3,
+		ProbeInterval: 10 * time.Second,
+	}
+}
@@ -0,0 +1,50 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+//go:build linux
+
+package santa
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// fileDataSyncFlag is the flag used to open a file whose writes return only
+// after the written data has been written to the persistent storage device.
+const fileDataSyncFlag = syscall.O_DSYNC
+
+// preallocateFile allocates the given number of bytes of disk space for the
+// given file without changing its size, so that subsequent appends do not
+// need to allocate file extents. If the file system does not support
+// preallocation, it does nothing.
+func preallocateFile(handle *os.File, size int64) error {
+	// The FALLOC_FL_KEEP_SIZE flag keeps the file size unchanged, which
+	// is required because the file is opened in append mode.
+	const keepSize = 0x01
+	err := syscall.Fallocate(int(handle.Fd()), keepSize, 0, size)
+	if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.ENOSYS) {
+		return nil
+	}
+	return err
+}
@@ -23,9 +23,15 @@
 package santa
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -133,6 +139,58 @@ func TestStandardSyncerWrite(t *testing.T) {
 	assert.NoError(t, syncer.Close(), "Unexpected close error")
 }
 
+type testShortWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *testShortWriter) Write(buffer []byte) (int, error) {
+	if len(buffer) > w.limit {
+		size, _ := w.Buffer.Write(buffer[ : w.limit])
+		return size, io.ErrShortWrite
+	}
+	return w.Buffer.Write(buffer)
+}
+
+func TestSyncerCacheWriteTo(t *testing.T) {
+	writer := &testShortWriter { limit: 4 }
+	cache := syncerCache("Hello Test!")
+
+	size, err := cache.WriteTo(writer)
+	assert.True(t, errors.Is(err, io.ErrShortWrite), "Unexpected write error")
+	assert.Equal(t, int64(4), size, "Unexpected number of bytes written")
+	assert.Equal(t, "o Test!", string(cache), "Unexpected cached data")
+
+	writer.limit = 1024
+	size, err = cache.WriteTo(writer)
+	assert.NoError(t, err, "Unexpected write error")
+	assert.Equal(t, int64(7), size, "Unexpected number of bytes written")
+	assert.Empty(t, cache, "Unexpected cached data")
+	assert.Equal(t, "Hello Test!", writer.String(), "Unexpected written data")
+}
+
+func TestStandardSyncerVectoredWrite(t *testing.T) {
+	writer := &bytes.Buffer { }
+	syncer, err := NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(1024).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	syncer.vectored = true
+	expected := make([]byte, 0, 4096)
+
+	for count := 0; count < 200; count++ {
+		buffer := []byte(fmt.Sprintf("Hello Test %d!\n", count))
+		expected = append(expected, buffer...)
+		size, err := syncer.Write(buffer)
+		assert.NoError(t, err, "Unexpected write error")
+		assert.Equal(t, len(buffer), size, "Unexpected write size")
+	}
+
+	assert.True(t, syncer.Buffered() < 1024, "Unexpected cached data")
+	assert.NoError(t, syncer.Flush(), "Unexpected flush error")
+	assert.Equal(t, string(expected), writer.String(), "Unexpected written data")
+}
+
 func TestFileSyncerWrite(t *testing.T) {
 	syncer, err := NewFileSyncer()
 	assert.NoError(t, err, "Unexpected create error")
@@ -142,6 +200,160 @@ func TestFileSyncerWrite(t *testing.T) {
 	assert.NoError(t, syncer.Close(), "Unexpected close error")
 }
 
+func TestFileSyncerFsyncPolicy(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "fsync.log")
+	size := func() int64 {
+		info, err := os.Stat(name)
+		assert.NoError(t, err, "Unexpected stat error")
+		return info.Size()
+	}
+
+	syncer, err := NewFileSyncerOption().UseName(name).
+		UseFsyncPolicy(FsyncOnLevel(LevelError)).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	_, err = syncer.Write([]byte("Hello Test!"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.NoError(t, syncer.SyncLevel(LevelInfo), "Unexpected sync error")
+	assert.Equal(t, int64(0), size(), "Unexpected file size")
+	assert.NoError(t, syncer.SyncLevel(LevelError), "Unexpected sync error")
+	assert.Equal(t, int64(11), size(), "Unexpected file size")
+	assert.NoError(t, syncer.Close(), "Unexpected close error")
+
+	syncer, err = NewFileSyncerOption().UseName(name).
+		UseFsyncPolicy(FsyncNever()).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	_, err = syncer.Write([]byte("Hello Test!"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.NoError(t, syncer.Sync(), "Unexpected sync error")
+	assert.Equal(t, int64(22), size(), "Unexpected file size")
+	assert.NoError(t, syncer.Close(), "Unexpected close error")
+
+	syncer, err = NewFileSyncerOption().UseName(name).
+		UseFsyncPolicy(FsyncInterval(time.Hour)).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, syncer.Sync(), "Unexpected sync error")
+	synced := syncer.synced
+	assert.NotZero(t, synced, "Unexpected fsync time")
+	assert.NoError(t, syncer.Sync(), "Unexpected sync error")
+	assert.Equal(t, synced, syncer.synced, "Unexpected fsync time")
+	assert.NoError(t, syncer.Close(), "Unexpected close error")
+
+	option := NewFileSyncerOption().UseFsyncPolicy(FsyncInterval(0))
+	err = option.Validate()
+	var optionErr *OptionError
+	assert.True(t, errors.As(err, &optionErr), "Unexpected validate error")
+	assert.Equal(t, "FsyncPolicy.Interval", optionErr.Option,
+		"Unexpected invalid option")
+	option.UseFsyncPolicy(FsyncOnLevel(Level(10)))
+	assert.True(t, errors.Is(option.Validate(), ErrInvalidLevel),
+		"Unexpected validate error")
+	option.UseFsyncPolicy(FsyncEveryFlush())
+	assert.NoError(t, option.Validate(), "Unexpected validate error")
+}
+
+func TestFileSyncerHints(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "hints.log")
+	syncer, err := NewFileSyncerOption().UseName(name).
+		UsePreallocate(1 << 20).UseSequentialAdvice().UseDataSync().Build()
+	assert.NoError(t, err, "Unexpected create error")
+	_, err = syncer.Write([]byte("Hello Test!"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.NoError(t, syncer.Close(), "Unexpected close error")
+
+	info, err := os.Stat(name)
+	assert.NoError(t, err, "Unexpected stat error")
+	assert.Equal(t, int64(11), info.Size(), "Unexpected file size")
+
+	option := NewFileSyncerOption().UsePreallocate(-1)
+	assert.Error(t, option.Validate(), "Unexpected validate result")
+}
+
+func TestFileSyncerVerify(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "verify.log")
+	assert.NoError(t, os.WriteFile(name, []byte("existing\n"), 0644),
+		"Unexpected write error")
+
+	var errs []error
+	option := NewFileSyncerOption().UseName(name).UseVerify(0).
+		UseErrorHandler(func(err error) {
+			errs = append(errs, err)
+		})
+	option.VerifySize = 8
+	syncer, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	_, err = syncer.Write([]byte("Hello Test!\n"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.NoError(t, syncer.Flush(), "Unexpected flush error")
+	assert.NoError(t, syncer.Sync(), "Unexpected sync error")
+	assert.Empty(t, errs, "Unexpected verify error")
+
+	handle, err := os.OpenFile(name, os.O_WRONLY, 0)
+	assert.NoError(t, err, "Unexpected open error")
+	_, err = handle.WriteAt([]byte("X"), 18)
+	assert.NoError(t, err, "Unexpected write error")
+	assert.NoError(t, handle.Close(), "Unexpected close error")
+
+	assert.NoError(t, syncer.Flush(), "Unexpected flush error")
+	assert.Len(t, errs, 1, "Unexpected verify errors")
+	assert.True(t, errors.Is(errs[0], ErrVerifyMismatch),
+		"Unexpected verify error")
+	var verifyErr *VerifyError
+	assert.True(t, errors.As(errs[0], &verifyErr), "Unexpected verify error")
+	assert.Equal(t, int64(13), verifyErr.Offset, "Unexpected verify offset")
+	assert.NoError(t, syncer.Close(), "Unexpected close error")
+
+	option = NewFileSyncerOption().UseVerify(-time.Second)
+	option.FileName = name
+	assert.Error(t, option.Validate(), "Unexpected validate result")
+}
+
+func TestFileSyncerRotation(t *testing.T) {
+	directory := t.TempDir()
+	name := filepath.Join(directory, "app.log")
+	expired := filepath.Join(directory, "app-2000-01-01T00-00-00.000.log")
+	assert.NoError(t, os.WriteFile(expired, []byte("expired\n"), 0644),
+		"Unexpected write error")
+	other := filepath.Join(directory, "app-error.log")
+	assert.NoError(t, os.WriteFile(other, []byte("other\n"), 0644),
+		"Unexpected write error")
+
+	syncer, err := NewFileSyncerOption().UseName(name).UseCacheCapacity(0).
+		UseRotation(16, 2, 24 * time.Hour).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	backups := func() []string {
+		matches, err := filepath.Glob(filepath.Join(directory, "app-2*.log"))
+		assert.NoError(t, err, "Unexpected glob error")
+		return matches
+	}
+	for _, text := range []string { "first line\n", "second line\n",
+		"third line\n" } {
+		_, err = syncer.Write([]byte(text))
+		assert.NoError(t, err, "Unexpected write error")
+	}
+	data, err := os.ReadFile(name)
+	assert.NoError(t, err, "Unexpected read error")
+	assert.Equal(t, "third line\n", string(data), "Unexpected file data")
+	assert.Len(t, backups(), 2, "Unexpected backup files")
+	_, err = os.Stat(expired)
+	assert.True(t, os.IsNotExist(err), "Unexpected expired backup file")
+	_, err = os.Stat(other)
+	assert.NoError(t, err, "Unexpected removed file")
+
+	assert.NoError(t, syncer.Rotate(), "Unexpected rotate error")
+	assert.Len(t, backups(), 2, "Unexpected backup files")
+	data, err = os.ReadFile(name)
+	assert.NoError(t, err, "Unexpected read error")
+	assert.Empty(t, data, "Unexpected file data")
+	data, err = os.ReadFile(backups()[1])
+	assert.NoError(t, err, "Unexpected read error")
+	assert.Equal(t, "third line\n", string(data), "Unexpected backup data")
+	assert.NoError(t, syncer.Close(), "Unexpected close error")
+
+	option := NewFileSyncerOption().UseRotation(-1, 0, 0)
+	option.FileName = name
+	assert.Error(t, option.Validate(), "Unexpected validate result")
+}
+
 func TestNetworkSyncerWrite(t *testing.T) {
 	closed := make(chan byte, 1)
 
@@ -198,3 +410,96 @@ func TestNetworkSyncerWrite(t *testing.T) {
 	<-closed
 	syncer.Close()
 }
+
+func TestSyncerOptionValidate(t *testing.T) {
+	option := NewFileSyncerOption()
+	assert.NoError(t, option.Validate(), "Unexpected validate error")
+
+	option.UseCacheCapacity(256)
+	assert.Error(t, option.Validate(), "Unexpected validate result")
+
+	option.UseCacheCapacity(0)
+	assert.NoError(t, option.Validate(), "Unexpected validate error")
+
+	networkOption := NewNetworkSyncerOption()
+	assert.NoError(t, networkOption.Validate(), "Unexpected validate error")
+
+	networkOption.UseProtocol("udp")
+	assert.True(t, errors.Is(networkOption.Validate(), ErrInvalidProtocol),
+		"Unexpected validate error")
+
+	networkOption.UseProtocol(ProtocolTCP).UseAddress("127.0.0.1")
+	assert.Error(t, networkOption.Validate(), "Unexpected validate result")
+
+	networkOption.UseAddress("127.0.0.1:10001")
+	assert.NoError(t, networkOption.Validate(), "Unexpected validate error")
+}
+
+type testFailingWriter struct {
+	testLockedWriter
+	failing int32
+}
+
+func (w *testFailingWriter) Write(buffer []byte) (int, error) {
+	if atomic.LoadInt32(&w.failing) == 1 {
+		return 0, errors.New("test write failure")
+	}
+	return w.testLockedWriter.Write(buffer)
+}
+
+func TestFallbackSyncer(t *testing.T) {
+	_, err := NewFallbackSyncerOption().Build()
+	assert.Error(t, err, "Unexpected create error")
+
+	writer := &testFailingWriter { failing: 1 }
+	primary, err := NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	fallbackWriter := &testLockedWriter { }
+	fallback, err := NewStandardSyncerOption().UseWriter(fallbackWriter).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected create error")
+	syncer, err := NewFallbackSyncerOption().UsePrimary(primary).
+		UseFallback(fallback).UseThreshold(2).
+		UseProbeInterval(50 * time.Millisecond).Build()
+	assert.NoError(t, err, "Unexpected create error")
+
+	_, err = syncer.Write([]byte("first\n"))
+	assert.Error(t, err, "Unexpected write error")
+	assert.False(t, syncer.Active(), "Unexpected fallback state")
+	_, err = syncer.Write([]byte("second\n"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.True(t, syncer.Active(), "Unexpected fallback state")
+	_, err = syncer.Write([]byte("third\n"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.Equal(t, "second\nthird\n", fallbackWriter.String(),
+		"Unexpected fallback data")
+
+	atomic.StoreInt32(&writer.failing, 0)
+	_, err = syncer.Write([]byte("fourth\n"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.True(t, syncer.Active(), "Unexpected fallback state")
+
+	time.Sleep(60 * time.Millisecond)
+	_, err = syncer.Write([]byte("fifth\n"))
+	assert.NoError(t, err, "Unexpected write error")
+	assert.False(t, syncer.Active(), "Unexpected fallback state")
+	assert.Equal(t, "fifth\n", writer.String(), "Unexpected primary data")
+	assert.NoError(t, syncer.Close(), "Unexpected close error")
+
+	writer = &testFailingWriter { failing: 1 }
+	fallbackWriter = &testLockedWriter { }
+	option := NewStandardOption().DisableFlushing().DisableCache().
+		DisableSampling().UseFallbackOutputting(NewOutputtingOption().
+		UseStandard(fallbackWriter))
+	option.Outputting.UseStandard(writer)
+	option.FallbackThreshold = 1
+	logger, err := option.Build()
+	assert.NoError(t, err, "Unexpected create error")
+	assert.NoError(t, logger.Info(StringMessage("Hello Test!")),
+		"Unexpected print error")
+	assert.NoError(t, logger.Sync(), "Unexpected sync error")
+	assert.Contains(t, fallbackWriter.String(), "Hello Test!",
+		"Unexpected fallback data")
+	assert.NoError(t, logger.Close(), "Unexpected close error")
+}
@@ -22,8 +22,6 @@
 
 package santa
 
-import "sync/atomic"
-
 // TemplateLogger is the structure of the template logger instance.
 //
 // The template logger is based on the standard logger. Template Logger
@@ -49,14 +47,17 @@ import "sync/atomic"
 // details, please refer to the comment section of the Syncer interface.
 //
 // Unless explicitly stated, the API provided by the logger is
-// thread-safe. It’s worth noting that APIs that allow post-build
-// changes to logger instances are generally not thread-safe. If you
-// need to change the logger instance (including but not limited to:
-// minimum log entry level, etc.), use the Duplicate function to create
-// a copy of the logger instance, and then make changes to the copy of
-// the logger instance.
+// thread-safe, including the APIs that allow post-build changes to
+// logger instances (including but not limited to: minimum log entry
+// level, etc.). Changes only take effect for subsequent log entries of
+// the changed logger instance. If you need a differently configured
+// logger, use the Duplicate function to create a copy of the logger
+// instance, and then make changes to the copy of the logger instance.
 type TemplateLogger struct {
 	StandardLogger
+
+	catalog *Catalog
+	locale string
 }
 
 // Printf outputs a template log message with a given log level, a given
@@ -69,6 +70,18 @@ func (l *TemplateLogger) Printf(level Level, template string, args ...interface
 	return err
 }
 
+// PrintfSync outputs a template log message with a given log level, a
+// given template string and one or more parameters, and then returns any
+// errors encountered after the log entry has been handed to the operating
+// system. For details, please refer to the comment section of the
+// OutputSync function.
+func (l *TemplateLogger) PrintfSync(level Level, template string, args ...interface { }) error {
+	message := pool.Message.Template.New(template, args)
+	err := l.OutputSync(2, level, message, false)
+	pool.Message.Template.Free(message)
+	return err
+}
+
 // Debugf outputs a template log message with a log level of DEBUG, a given
 // template string and one or more parameters, and then returns any errors
 // encountered.
@@ -119,25 +132,167 @@ func (l *TemplateLogger) Fatalf(template string, args ...interface { }) error {
 	return err
 }
 
+// Printn outputs a named template log message with a given log level, a
+// given template string with named placeholders and one or more parameters,
+// and then returns any errors encountered. For details, please refer to the
+// comment section of the NamedTemplateMessage structure.
+func (l *TemplateLogger) Printn(level Level, template string, args ...interface { }) error {
+	message := pool.Message.NamedTemplate.New(template, args)
+	err := l.Output(2, level, message)
+	pool.Message.NamedTemplate.Free(message)
+	return err
+}
+
+// Debugn outputs a named template log message with a log level of DEBUG, a
+// given template string with named placeholders and one or more parameters,
+// and then returns any errors encountered.
+func (l *TemplateLogger) Debugn(template string, args ...interface { }) error {
+	message := pool.Message.NamedTemplate.New(template, args)
+	err := l.Output(2, LevelDebug, message)
+	pool.Message.NamedTemplate.Free(message)
+	return err
+}
+
+// Infon outputs a named template log message with a log level of INFO, a
+// given template string with named placeholders and one or more parameters,
+// and then returns any errors encountered.
+func (l *TemplateLogger) Infon(template string, args ...interface { }) error {
+	message := pool.Message.NamedTemplate.New(template, args)
+	err := l.Output(2, LevelInfo, message)
+	pool.Message.NamedTemplate.Free(message)
+	return err
+}
+
+// Warningn outputs a named template log message with a log level of WARNING, a
+// given template string with named placeholders and one or more parameters,
+// and then returns any errors encountered.
+func (l *TemplateLogger) Warningn(template string, args ...interface { }) error {
+	message := pool.Message.NamedTemplate.New(template, args)
+	err := l.Output(2, LevelWarning, message)
+	pool.Message.NamedTemplate.Free(message)
+	return err
+}
+
+// Errorn outputs a named template log message with a log level of ERROR, a
+// given template string with named placeholders and one or more parameters,
+// and then returns any errors encountered.
+func (l *TemplateLogger) Errorn(template string, args ...interface { }) error {
+	message := pool.Message.NamedTemplate.New(template, args)
+	err := l.Output(2, LevelError, message)
+	pool.Message.NamedTemplate.Free(message)
+	return err
+}
+
+// Fataln outputs a named template log message with a log level of FATAL, a
+// given template string with named placeholders and one or more parameters,
+// and then returns any errors encountered.
+func (l *TemplateLogger) Fataln(template string, args ...interface { }) error {
+	message := pool.Message.NamedTemplate.New(template, args)
+	err := l.Output(2, LevelFatal, message)
+	pool.Message.NamedTemplate.Free(message)
+	return err
+}
+
+// localize creates and returns a localized message with the given key and
+// fields using the message catalog and the locale of the logger.
+func (l *TemplateLogger) localize(key string, fields []Field) LocalizedMessage {
+	message := LocalizedMessage {
+		Key: key,
+		Locale: l.locale,
+		Fields: fields,
+	}
+	if l.catalog != nil {
+		message.Template, _ = l.catalog.Lookup(l.locale, key)
+	}
+	return message
+}
+
+// Printl outputs a localized log message with a given log level, a given
+// message catalog key and zero or more fields, and then returns any errors
+// encountered. For details, please refer to the comment section of the
+// LocalizedMessage structure.
+func (l *TemplateLogger) Printl(level Level, key string, fields ...Field) error {
+	return l.Output(2, level, l.localize(key, fields))
+}
+
+// Debugl outputs a localized log message with a log level of DEBUG, a
+// given message catalog key and zero or more fields, and then returns any
+// errors encountered.
+func (l *TemplateLogger) Debugl(key string, fields ...Field) error {
+	return l.Output(2, LevelDebug, l.localize(key, fields))
+}
+
+// Infol outputs a localized log message with a log level of INFO, a
+// given message catalog key and zero or more fields, and then returns any
+// errors encountered.
+func (l *TemplateLogger) Infol(key string, fields ...Field) error {
+	return l.Output(2, LevelInfo, l.localize(key, fields))
+}
+
+// Warningl outputs a localized log message with a log level of WARNING, a
+// given message catalog key and zero or more fields, and then returns any
+// errors encountered.
+func (l *TemplateLogger) Warningl(key string, fields ...Field) error {
+	return l.Output(2, LevelWarning, l.localize(key, fields))
+}
+
+// Errorl outputs a localized log message with a log level of ERROR, a
+// given message catalog key and zero or more fields, and then returns any
+// errors encountered.
+func (l *TemplateLogger) Errorl(key string, fields ...Field) error {
+	return l.Output(2, LevelError, l.localize(key, fields))
+}
+
+// Fatall outputs a localized log message with a log level of FATAL, a
+// given message catalog key and zero or more fields, and then returns any
+// errors encountered.
+func (l *TemplateLogger) Fatall(key string, fields ...Field) error {
+	return l.Output(2, LevelFatal, l.localize(key, fields))
+}
+
+// SetLocale sets the locale used to select localized template strings
+// to the given locale. For details, please refer to the comment section of
+// the Locale field of the TemplateOption structure.
+//
+// Please note that this API is not thread-safe.
+func (l *TemplateLogger) SetLocale(locale string) {
+	l.locale = locale
+}
+
 // Duplicate creates and returns a copy of the logger. If the logger is
 // closed, it returns nil.
 //
 // Please note that the application must explicitly close each copy of
 // the logger, otherwise the logger may be leaked.
 func (l *TemplateLogger) Duplicate() *TemplateLogger {
-	if atomic.AddInt32(l.contextReferences, 1) == 1 {
-		// The logger has been shut down, and using the created copy
-		// may cause panic.
+	if !l.acquire() {
 		return nil
 	}
-	instance := *l
-	return &instance
+	instance := &TemplateLogger {
+		StandardLogger: *l.StandardLogger.duplicate(),
+		catalog: l.catalog,
+		locale: l.locale,
+	}
+	trackLeak(instance, (*TemplateLogger).closedForLeak)
+	return instance
 }
 
 // TemplateOption is a structure that contains options for the template
 // logger.
 type TemplateOption struct {
 	StandardOption
+
+	// Catalog represents the message catalog used to look up localized
+	// template strings of localized log messages. If not provided, the
+	// key of each localized log message is used as the template string.
+	// For details, please refer to the comment section of the Catalog
+	// structure.
+	Catalog *Catalog
+
+	// Locale represents the locale used to select localized template
+	// strings from the message catalog. If not provided, the fallback
+	// locale of the message catalog is used.
+	Locale string
 }
 
 // UseName uses the given name as the value of the option Name. For details,
@@ -212,12 +367,13 @@ func (o *TemplateOption) UseFlushing(option *FlushingOption) *TemplateOption {
 	return o
 }
 
-// DisableCache disable the internal cache of output and error output. For
-// details, please refer to the DisableCache option of the OutputtingOption
-// structure. Then return to the option instance itself.
+// DisableCache disable the internal cache of output, error output and
+// fallback output. For details, please refer to the DisableCache option of
+// the OutputtingOption structure. Then return to the option instance itself.
 func (o *TemplateOption) DisableCache() *TemplateOption {
 	o.Outputting.DisableCache = true
 	o.ErrorOutputting.DisableCache = true
+	o.FallbackOutputting.DisableCache = true
 	return o
 }
 
@@ -237,15 +393,29 @@ func (o *TemplateOption) DisableFlushing() *TemplateOption {
 	return o
 }
 
+// UseCatalog uses the given message catalog and locale as the values of
+// the options Catalog and Locale. For details, please refer to the comment
+// section of the Catalog and Locale options. Then return to the option
+// instance itself.
+func (o *TemplateOption) UseCatalog(catalog *Catalog, locale string) *TemplateOption {
+	o.Catalog = catalog
+	o.Locale = locale
+	return o
+}
+
 // Build builds and returns a template logger instance.
 func (o *TemplateOption) Build() (*TemplateLogger, error) {
-	logger, err := o.StandardOption.Build()
+	logger, err := o.StandardOption.build()
 	if err != nil {
 		return nil, err
 	}
-	return &TemplateLogger {
+	instance := &TemplateLogger {
 		StandardLogger: *logger,
-	}, nil
+		catalog: o.Catalog,
+		locale: o.Locale,
+	}
+	trackLeak(instance, (*TemplateLogger).closedForLeak)
+	return instance, nil
 }
 
 // NewTemplateOption creates an instance of a template logger option with
@@ -264,7 +434,13 @@ func NewTemplate() (*TemplateLogger, error) {
 
 // NewTemplateBenchmark creates and returns an instance of a template logger
 // suitable for benchmark performance testing and any errors encountered.
-func NewTemplateBenchmark(sampling bool, encoder string) (*TemplateLogger, error) {
+//
+// If a latency histogram is given, the latency of each log entry output
+// operation is recorded into it, so that the tail latency can be read after
+// the benchmark. For details, please refer to the Latency option of the
+// StandardOption structure.
+func NewTemplateBenchmark(sampling bool, encoder string,
+	latency ...*LatencyHistogram) (*TemplateLogger, error) {
 	option := NewTemplateOption()
 	switch encoder {
 	case EncoderStandard:
@@ -279,6 +455,9 @@ func NewTemplateBenchmark(sampling bool, encoder string) (*TemplateLogger, error
 	option.Outputting.UseDiscard()
 	option.ErrorOutputting.UseDiscard()
 	option.UseLevel(LevelDebug)
+	if len(latency) > 0 {
+		option.UseLatency(latency[0])
+	}
 	if !sampling {
 		option.DisableSampling()
 	}
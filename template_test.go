@@ -69,13 +69,13 @@ func TestTemplateLoggerOption(t *testing.T) {
 	logger, err := option.Build()
 	assert.NoError(t, err, "Unexpected build error")
 
-	assert.NotNil(t, logger.sampler, "Unexpected instance error")
-	assert.Len(t, logger.exporters, 2, "Unexpected instance error")
-	assert.NotNil(t, logger.exporters[0], "Unexpected instance error")
-	assert.NotNil(t, logger.exporters[1], "Unexpected instance error")
+	assert.NotNil(t, logger.config().sampler, "Unexpected instance error")
+	assert.Len(t, logger.config().exporters, 2, "Unexpected instance error")
+	assert.NotNil(t, logger.config().exporters[0], "Unexpected instance error")
+	assert.NotNil(t, logger.config().exporters[1], "Unexpected instance error")
 
-	assert.Equal(t, option.Level, logger.level, "Unexpected instance error")
-	assert.Equal(t, option.Name, logger.name, "Unexpected instance error")
+	assert.Equal(t, option.Level, logger.Level(), "Unexpected instance error")
+	assert.Equal(t, option.Name, logger.Name(), "Unexpected instance error")
 
 	option.DisableCache()
 	option.DisableFlushing()
@@ -143,6 +143,27 @@ func TestTemplateLoggerPrint(t *testing.T) {
 	err = logger.Printf(LevelError, "Hello Test! %s %d", "test", 100)
 	assert.NoError(t, err, "Unexpected print error")
 
+	err = logger.PrintfSync(LevelError, "Hello Test! %s %d", "test", 100)
+	assert.NoError(t, err, "Unexpected print error")
+
+	err = logger.Debugn("Hello Test! {name} {count}", "test", 100)
+	assert.NoError(t, err, "Unexpected print error")
+
+	err = logger.Infon("Hello Test! {name} {count}", "test", 100)
+	assert.NoError(t, err, "Unexpected print error")
+
+	err = logger.Warningn("Hello Test! {name} {count}", "test", 100)
+	assert.NoError(t, err, "Unexpected print error")
+
+	err = logger.Errorn("Hello Test! {name} {count}", "test", 100)
+	assert.NoError(t, err, "Unexpected print error")
+
+	err = logger.Fataln("Hello Test! {name} {count}", "test", 100)
+	assert.NoError(t, err, "Unexpected print error")
+
+	err = logger.Printn(LevelError, "Hello Test! {name} {count}", "test", 100)
+	assert.NoError(t, err, "Unexpected print error")
+
 	assert.NoError(t, logger.Close(), "Unexpected close error")
 }
 
@@ -155,8 +176,8 @@ func TestTemplateLoggerDuplicate(t *testing.T) {
 	assert.NotNil(t, instance, "Unexpected nil value")
 
 	instance.SetName("testing")
-	assert.Equal(t, "testing", instance.name, "Unexpected instance error")
-	assert.Equal(t, "", logger.name, "Unexpected instance error")
+	assert.Equal(t, "testing", instance.Name(), "Unexpected instance error")
+	assert.Equal(t, "", logger.Name(), "Unexpected instance error")
 
 	assert.NoError(t, instance.Close(), "Unexpected close error")
 	assert.NoError(t, logger.Close(), "Unexpected close error")
@@ -0,0 +1,18 @@
+module github.com/nobody-night/santa/tracing
+
+go 1.22.0
+
+require (
+	github.com/nobody-night/santa v0.0.0
+	github.com/stretchr/testify v1.10.0
+	go.opentelemetry.io/otel v1.35.0
+	go.opentelemetry.io/otel/trace v1.35.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace github.com/nobody-night/santa => ../
@@ -0,0 +1,232 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+// Package tracing provides a Hook that records log entries as events of
+// the OpenTelemetry spans carried by their contexts, so that traces show
+// the log entries inline without duplicating instrumentation.
+//
+// The package is a separate module, so that the OpenTelemetry dependency is only
+// required by the users of the package.
+package tracing
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/nobody-night/santa"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanEventHook is the structure of the span event Hook instance.
+//
+// When the context associated with a log entry (see the WithContext
+// function of the loggers) carries a recording span, the span event Hook
+// records the log entry as an event of the span, whose name is the text of
+// the log entry message and whose attributes are the level of the log
+// entry and the selected fields of the message. Log entries without a
+// recording span are ignored, so the Hook costs almost nothing when tracing
+// is disabled.
+//
+// The span event Hook never modifies or cancels log entries.
+type SpanEventHook struct {
+	span santa.LevelSpan
+	fields map[string]struct { }
+	levelKey string
+}
+
+// text returns the text and the fields of the given log entry message.
+func (*SpanEventHook) text(message santa.Message) (string, []santa.Field) {
+	switch value := message.(type) {
+	case *santa.StructMessage:
+		return value.Text, value.Fields
+	case santa.StructMessage:
+		return value.Text, value.Fields
+	case *santa.TemplateMessage:
+		return fmt.Sprintf(value.Template, value.Args...), nil
+	case santa.TemplateMessage:
+		return fmt.Sprintf(value.Template, value.Args...), nil
+	case santa.NamedTemplateMessage:
+		return string(value.AppendText(nil)), value.Fields()
+	case santa.StringMessage:
+		return string(value), nil
+	case *santa.StringMessage:
+		return string(*value), nil
+	case interface { AppendText(buffer []byte) []byte }:
+		return string(value.AppendText(nil)), nil
+	case santa.TextSampleParser:
+		return value.SampleText(), nil
+	}
+	return "", nil
+}
+
+// attribute returns the span event attribute of the given field.
+func (*SpanEventHook) attribute(field santa.Field) attribute.KeyValue {
+	switch field.Type {
+	case santa.TypeInt:
+		return attribute.Int64(field.Name, field.Number)
+	case santa.TypeUint:
+		if field.Uint64() > math.MaxInt64 {
+			// The value overflows int64, which is not supported by the
+			// span event attributes.
+			return attribute.String(field.Name,
+				strconv.FormatUint(field.Uint64(), 10))
+		}
+		return attribute.Int64(field.Name, field.Number)
+	case santa.TypeBigNumber:
+		// Big numbers are not supported by the span event attributes.
+		return attribute.String(field.Name, string(field.SerializeJSON(nil)))
+	case santa.TypeFloat32:
+		return attribute.Float64(field.Name, float64(math.Float32frombits(
+			uint32(field.Number))))
+	case santa.TypeFloat64:
+		return attribute.Float64(field.Name, math.Float64frombits(
+			uint64(field.Number)))
+	case santa.TypeBoolean:
+		return attribute.Bool(field.Name, field.Number > 0)
+	case santa.TypeString:
+		return attribute.String(field.Name, field.String)
+	}
+	return attribute.String(field.Name, string(field.SerializeJSON(nil)))
+}
+
+// Print records the given log entry as an event of the span carried by
+// the context of the log entry, if the span is recording and the level of
+// the log entry is within the level span.
+func (h *SpanEventHook) Print(entry *santa.Entry) error {
+	if entry.Context == nil || !h.span.Contains(entry.Level) {
+		return nil
+	}
+	span := trace.SpanFromContext(entry.Context)
+	if !span.IsRecording() {
+		return nil
+	}
+	text, fields := h.text(entry.Message)
+	attributes := make([]attribute.KeyValue, 0, len(fields) + 1)
+	attributes = append(attributes, attribute.String(h.levelKey,
+		entry.Level.String()))
+	for index := 0; index < len(fields); index++ {
+		if h.fields != nil {
+			if _, ok := h.fields[fields[index].Name]; !ok {
+				continue
+			}
+		}
+		attributes = append(attributes, h.attribute(fields[index]))
+	}
+	options := []trace.EventOption { trace.WithAttributes(attributes...) }
+	if !entry.Time.IsZero() {
+		options = append(options, trace.WithTimestamp(entry.Time))
+	}
+	span.AddEvent(text, options...)
+	return nil
+}
+
+// SpanEventHookOption is a structure that contains options for the span
+// event Hook.
+type SpanEventHookOption struct {
+	// Span represents the log level span of log entries that are recorded
+	// as span events. If not provided, the default is DEBUG to FATAL.
+	Span santa.LevelSpan
+
+	// Fields represents the names of the fields of the log entry messages
+	// that are recorded as span event attributes. If the value is nil, all
+	// fields are recorded. If not provided, the default value is nil.
+	Fields []string
+
+	// LevelKey represents the name of the span event attribute that
+	// contains the level of the log entry. If not provided, the default
+	// value is "log.severity".
+	LevelKey string
+}
+
+// UseSpan uses the given level span as the value of the option Span. For
+// details, please refer to the comment section of the Span option. Then
+// return to the option instance itself.
+func (o *SpanEventHookOption) UseSpan(span santa.LevelSpan) *SpanEventHookOption {
+	o.Span = span
+	return o
+}
+
+// UseFields uses the given field names as the value of the option Fields.
+// For details, please refer to the comment section of the Fields option.
+// Then return to the option instance itself.
+func (o *SpanEventHookOption) UseFields(names ...string) *SpanEventHookOption {
+	o.Fields = names
+	return o
+}
+
+// Validate checks whether the values of the options are valid, and then
+// returns an OptionError describing the first invalid option found, or
+// nil if all options are valid.
+func (o *SpanEventHookOption) Validate() error {
+	if o.Span.Start > o.Span.End {
+		return &santa.OptionError {
+			Option: "Span",
+			Reason: "start level must not be greater than end level",
+		}
+	}
+	if len(o.LevelKey) == 0 {
+		return &santa.OptionError {
+			Option: "LevelKey",
+			Reason: "must not be empty",
+		}
+	}
+	return nil
+}
+
+// Build builds and returns a span event Hook instance.
+func (o *SpanEventHookOption) Build() (*SpanEventHook, error) {
+	if err := o.Validate(); err != nil {
+		return nil, err
+	}
+	hook := &SpanEventHook {
+		span: o.Span,
+		levelKey: o.LevelKey,
+	}
+	if o.Fields != nil {
+		hook.fields = make(map[string]struct { }, len(o.Fields))
+		for _, name := range o.Fields {
+			hook.fields[name] = struct { } { }
+		}
+	}
+	return hook, nil
+}
+
+// NewSpanEventHookOption creates and returns a span event Hook option
+// instance with default option values.
+func NewSpanEventHookOption() *SpanEventHookOption {
+	return &SpanEventHookOption {
+		Span: santa.LevelSpan {
+			Start: santa.LevelDebug,
+			End: santa.LevelFatal,
+		},
+		LevelKey: "log.severity",
+	}
+}
+
+// NewSpanEventHook creates and returns a span event Hook instance using
+// default option values.
+func NewSpanEventHook() (*SpanEventHook, error) {
+	return NewSpanEventHookOption().Build()
+}
@@ -0,0 +1,109 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+
+package tracing
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nobody-night/santa"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+type testSpanEvent struct {
+	name string
+	config trace.EventConfig
+}
+
+type testSpan struct {
+	noop.Span
+	events []testSpanEvent
+}
+
+func (s *testSpan) IsRecording() bool {
+	return true
+}
+
+func (s *testSpan) AddEvent(name string, options ...trace.EventOption) {
+	s.events = append(s.events, testSpanEvent {
+		name: name,
+		config: trace.NewEventConfig(options...),
+	})
+}
+
+func TestSpanEventHook(t *testing.T) {
+	hook, err := NewSpanEventHookOption().UseFields("user", "size").Build()
+	assert.NoError(t, err, "Unexpected span event hook creation error")
+
+	span := &testSpan { }
+	now := time.Now()
+	entry := &santa.Entry {
+		Time: now,
+		Level: santa.LevelWarning,
+		Message: &santa.StructMessage {
+			Text: "Upload failed",
+			Fields: []santa.Field {
+				santa.String("user", "alice"),
+				santa.Int("size", 1024),
+				santa.String("secret", "hidden"),
+			},
+		},
+		Context: trace.ContextWithSpan(context.Background(), span),
+	}
+	assert.NoError(t, hook.Print(entry), "Unexpected hook error")
+
+	entry.Message = santa.TemplateMessage {
+		Template: "Retry %d",
+		Args: []interface { } { 2 },
+	}
+	assert.NoError(t, hook.Print(entry), "Unexpected hook error")
+
+	entry.Context = context.Background()
+	assert.NoError(t, hook.Print(entry), "Unexpected hook error")
+
+	if !assert.Len(t, span.events, 2, "Unexpected span events") {
+		return
+	}
+	assert.Equal(t, "Upload failed", span.events[0].name,
+		"Unexpected span event name")
+	assert.Equal(t, now, span.events[0].config.Timestamp(),
+		"Unexpected span event timestamp")
+	assert.Equal(t, []attribute.KeyValue {
+		attribute.String("log.severity", santa.LevelWarning.String()),
+		attribute.String("user", "alice"),
+		attribute.Int64("size", 1024),
+	}, span.events[0].config.Attributes(), "Unexpected span event attributes")
+	assert.Equal(t, "Retry 2", span.events[1].name,
+		"Unexpected span event name")
+
+	_, err = NewSpanEventHookOption().UseSpan(santa.LevelSpan {
+		Start: santa.LevelFatal,
+		End: santa.LevelDebug,
+	}).Build()
+	assert.Error(t, err, "Unexpected span event hook creation result")
+}
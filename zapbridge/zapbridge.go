@@ -0,0 +1,486 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+// Package zapbridge provides a zapcore.Core that writes log entries to
+// santa exporters, and a santa exporter that writes log entries to a
+// zapcore.Core, so that large codebases can migrate between zap and santa
+// incrementally in either direction.
+//
+// The package is a separate module, so that the zap dependency is only
+// required by the users of the package.
+package zapbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"runtime"
+	"time"
+
+	"github.com/nobody-night/santa"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// santaLevel returns the santa log level of the given zap log level. The
+// DPANIC, PANIC and FATAL levels are mapped to the FATAL level.
+func santaLevel(level zapcore.Level) santa.Level {
+	switch {
+	case level <= zapcore.DebugLevel:
+		return santa.LevelDebug
+	case level == zapcore.InfoLevel:
+		return santa.LevelInfo
+	case level == zapcore.WarnLevel:
+		return santa.LevelWarning
+	case level == zapcore.ErrorLevel:
+		return santa.LevelError
+	}
+	return santa.LevelFatal
+}
+
+// zapLevel returns the zap log level of the given santa log level.
+func zapLevel(level santa.Level) zapcore.Level {
+	switch level {
+	case santa.LevelDebug:
+		return zapcore.DebugLevel
+	case santa.LevelInfo:
+		return zapcore.InfoLevel
+	case santa.LevelWarning:
+		return zapcore.WarnLevel
+	case santa.LevelError:
+		return zapcore.ErrorLevel
+	}
+	return zapcore.FatalLevel
+}
+
+// anyField returns a santa field with the given name and value of any
+// type. Values that are not supported by the Value function of santa are
+// dumped (see the Dump function of santa).
+func anyField(name string, value interface { }) santa.Field {
+	field := santa.Value(name, value)
+	if field.Type == santa.TypeValue {
+		return santa.Dump(name, value)
+	}
+	return field
+}
+
+// fieldEncoder is a zapcore.ObjectEncoder that converts zap fields to santa
+// fields. The fields added after a namespace is opened are nested in an
+// object field named after the namespace.
+type fieldEncoder struct {
+	fields []santa.Field
+	namespace *fieldEncoder
+	namespaceKey string
+}
+
+// add adds the given field to the innermost open namespace.
+func (e *fieldEncoder) add(field santa.Field) {
+	for e.namespace != nil {
+		e = e.namespace
+	}
+	e.fields = append(e.fields, field)
+}
+
+// result closes the open namespaces, and then returns the converted fields.
+func (e *fieldEncoder) result() []santa.Field {
+	if e.namespace != nil {
+		e.fields = append(e.fields, santa.Object(e.namespaceKey,
+			e.namespace.result()...))
+		e.namespace = nil
+	}
+	return e.fields
+}
+
+// convert converts the given zap field and adds it to the encoder.
+func (e *fieldEncoder) convert(field zapcore.Field) {
+	if err, ok := field.Interface.(error); ok &&
+		field.Type == zapcore.ErrorType {
+		e.add(santa.Error(field.Key, err))
+		return
+	}
+	field.AddTo(e)
+}
+
+// AddArray implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddArray(key string, marshaler zapcore.ArrayMarshaler) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	err := encoder.AddArray(key, marshaler)
+	e.add(santa.Dump(key, encoder.Fields[key]))
+	return err
+}
+
+// AddObject implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddObject(key string, marshaler zapcore.ObjectMarshaler) error {
+	nested := &fieldEncoder { }
+	err := marshaler.MarshalLogObject(nested)
+	e.add(santa.Object(key, nested.result()...))
+	return err
+}
+
+// AddBinary implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddBinary(key string, value []byte) {
+	e.add(santa.Bytes(key, value))
+}
+
+// AddByteString implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddByteString(key string, value []byte) {
+	e.add(santa.String(key, string(value)))
+}
+
+// AddBool implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddBool(key string, value bool) {
+	e.add(santa.Boolean(key, value))
+}
+
+// AddComplex128 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddComplex128(key string, value complex128) {
+	e.add(santa.String(key, fmt.Sprint(value)))
+}
+
+// AddComplex64 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddComplex64(key string, value complex64) {
+	e.add(santa.String(key, fmt.Sprint(value)))
+}
+
+// AddDuration implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddDuration(key string, value time.Duration) {
+	e.add(santa.Duration(key, value))
+}
+
+// AddFloat64 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddFloat64(key string, value float64) {
+	e.add(santa.Float64(key, value))
+}
+
+// AddFloat32 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddFloat32(key string, value float32) {
+	e.add(santa.Float32(key, value))
+}
+
+// AddInt implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddInt(key string, value int) {
+	e.add(santa.Int(key, int64(value)))
+}
+
+// AddInt64 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddInt64(key string, value int64) {
+	e.add(santa.Int(key, value))
+}
+
+// AddInt32 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddInt32(key string, value int32) {
+	e.add(santa.Int(key, int64(value)))
+}
+
+// AddInt16 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddInt16(key string, value int16) {
+	e.add(santa.Int(key, int64(value)))
+}
+
+// AddInt8 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddInt8(key string, value int8) {
+	e.add(santa.Int(key, int64(value)))
+}
+
+// AddString implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddString(key, value string) {
+	e.add(santa.String(key, value))
+}
+
+// AddTime implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddTime(key string, value time.Time) {
+	e.add(santa.Time(key, value))
+}
+
+// AddUint implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddUint(key string, value uint) {
+	e.add(santa.Uint(key, uint64(value)))
+}
+
+// AddUint64 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddUint64(key string, value uint64) {
+	e.add(santa.Uint(key, value))
+}
+
+// AddUint32 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddUint32(key string, value uint32) {
+	e.add(santa.Uint(key, uint64(value)))
+}
+
+// AddUint16 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddUint16(key string, value uint16) {
+	e.add(santa.Uint(key, uint64(value)))
+}
+
+// AddUint8 implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddUint8(key string, value uint8) {
+	e.add(santa.Uint(key, uint64(value)))
+}
+
+// AddUintptr implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddUintptr(key string, value uintptr) {
+	e.add(santa.Uint(key, uint64(value)))
+}
+
+// AddReflected implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) AddReflected(key string, value interface { }) error {
+	e.add(anyField(key, value))
+	return nil
+}
+
+// OpenNamespace implements the zapcore.ObjectEncoder interface.
+func (e *fieldEncoder) OpenNamespace(key string) {
+	for e.namespace != nil {
+		e = e.namespace
+	}
+	e.namespace = &fieldEncoder { }
+	e.namespaceKey = key
+}
+
+// convertFields converts the given zap fields to santa fields, and then
+// appends them to the given santa fields.
+func convertFields(fields []santa.Field, zapFields []zapcore.Field) []santa.Field {
+	encoder := &fieldEncoder {
+		fields: fields,
+	}
+	for index := 0; index < len(zapFields); index++ {
+		encoder.convert(zapFields[index])
+	}
+	return encoder.result()
+}
+
+// Core is the structure of a zap core instance backed by santa exporters.
+//
+// The core converts each zap log entry and its fields into a santa log
+// entry with a structured message, and then exports it to the santa
+// exporters, so that code using zap writes through the santa encoders and
+// synchronizers. The DPANIC, PANIC and FATAL levels of zap are exported as
+// the FATAL level of santa. The fields added by the With function are
+// added before the fields of each log entry, and namespaces are converted
+// to nested object fields.
+//
+// The core does not own the exporters, so they must be closed by the
+// application after the core is no longer used.
+type Core struct {
+	zapcore.LevelEnabler
+	exporters []santa.Exporter
+	fields []santa.Field
+	labels santa.SerializedLabels
+}
+
+// NewCore creates and returns a zap core instance that writes the log
+// entries whose level is enabled by the given level enabler to the given
+// santa exporters.
+func NewCore(enabler zapcore.LevelEnabler, exporters ...santa.Exporter) *Core {
+	return &Core {
+		LevelEnabler: enabler,
+		exporters: exporters,
+	}
+}
+
+// WithLabels returns a copy of the core that adds the given santa labels to
+// each log entry.
+func (c *Core) WithLabels(labels ...santa.Label) *Core {
+	clone := *c
+	clone.labels = santa.NewSerializedLabels(labels...)
+	return &clone
+}
+
+// With returns a copy of the core that adds the given fields to each log
+// entry.
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	clone := *c
+	clone.fields = convertFields(append([]santa.Field(nil), c.fields...),
+		fields)
+	return &clone
+}
+
+// Check adds the core to the given checked entry if the level of the given
+// entry is enabled, and then returns the checked entry.
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write converts the given zap log entry and fields into a santa log entry,
+// and then exports it to the santa exporters. Finally, any errors
+// encountered are returned.
+//
+// The santa log entry, its message and its fields are taken from the
+// global pool of santa, so writing a log entry does not allocate them.
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	pool := santa.GetGlobalPool()
+	buffer := pool.Buffer.Field.New()
+	*buffer = convertFields(append(*buffer, c.fields...), fields)
+	message := pool.Message.Structure.New(entry.Message, *buffer)
+	record := pool.Entry.New()
+	record.Name = entry.LoggerName
+	record.Level = santaLevel(entry.Level)
+	record.Time = entry.Time
+	record.Message = message
+	record.Labels = c.labels
+	record.Context = nil
+	record.SourceLocation = santa.EntrySourceLocation { }
+	if entry.Caller.Defined {
+		record.SourceLocation = santa.EntrySourceLocation {
+			Proc: entry.Caller.PC,
+			File: entry.Caller.File,
+			Line: entry.Caller.Line,
+			Parsed: true,
+		}
+	}
+	var errs santa.MultiError
+	for _, exporter := range c.exporters {
+		errs = errs.Append(exporter.Export(record))
+	}
+	pool.Entry.Free(record)
+	pool.Message.Structure.Free(message)
+	pool.Buffer.Field.Free(buffer)
+	return errs.ErrorOrNil()
+}
+
+// Sync synchronizes the santa exporters, and then returns any errors
+// encountered.
+func (c *Core) Sync() error {
+	var errs santa.MultiError
+	for _, exporter := range c.exporters {
+		errs = errs.Append(exporter.Sync())
+	}
+	return errs.ErrorOrNil()
+}
+
+// Exporter is the structure of a santa exporter instance backed by a zap
+// core.
+//
+// The exporter converts each santa log entry into a zap log entry whose
+// message is the text of the santa message, and whose fields are the
+// fields of the structured message and the labels, and then writes it to
+// the zap core, so that code using santa writes through the zap encoders
+// and write syncers. The FATAL level of santa is written as the FATAL
+// level of zap, but the zap core does not exit the process.
+type Exporter struct {
+	core zapcore.Core
+	labels bool
+}
+
+// NewExporter creates and returns a santa exporter instance that writes
+// log entries to the given zap core. If labels is true, the labels of each
+// log entry are written as string fields.
+func NewExporter(core zapcore.Core, labels bool) *Exporter {
+	return &Exporter {
+		core: core,
+		labels: labels,
+	}
+}
+
+// text returns the text and the fields of the given santa log message.
+func (*Exporter) text(message santa.Message) (string, []santa.Field) {
+	switch value := message.(type) {
+	case *santa.StructMessage:
+		return value.Text, value.Fields
+	case santa.StructMessage:
+		return value.Text, value.Fields
+	case santa.NamedTemplateMessage:
+		return string(value.AppendText(nil)), value.Fields()
+	case santa.StringMessage:
+		return string(value), nil
+	case *santa.StringMessage:
+		return string(*value), nil
+	case interface { AppendText(buffer []byte) []byte }:
+		return string(value.AppendText(nil)), nil
+	case santa.TextSampleParser:
+		return value.SampleText(), nil
+	}
+	return "", nil
+}
+
+// field returns the zap field of the given santa field.
+func (*Exporter) field(field santa.Field) zapcore.Field {
+	switch field.Type {
+	case santa.TypeInt:
+		return zap.Int64(field.Name, field.Number)
+	case santa.TypeUint:
+		return zap.Uint64(field.Name, field.Uint64())
+	case santa.TypeFloat32:
+		return zap.Float32(field.Name, math.Float32frombits(
+			uint32(field.Number)))
+	case santa.TypeFloat64:
+		return zap.Float64(field.Name, math.Float64frombits(
+			uint64(field.Number)))
+	case santa.TypeBoolean:
+		return zap.Bool(field.Name, field.Number > 0)
+	case santa.TypeString:
+		if err, ok := field.Interface.(error); ok {
+			return zap.NamedError(field.Name, err)
+		}
+		return zap.String(field.Name, field.String)
+	}
+	return zap.Any(field.Name, json.RawMessage(field.SerializeJSON(nil)))
+}
+
+// Export converts the given santa log entry into a zap log entry, and then
+// writes it to the zap core if its level is enabled. Finally, any errors
+// encountered are returned.
+func (e *Exporter) Export(entry *santa.Entry) error {
+	level := zapLevel(entry.Level)
+	if !e.core.Enabled(level) {
+		return nil
+	}
+	text, santaFields := e.text(entry.Message)
+	fields := make([]zapcore.Field, 0, len(santaFields) +
+		entry.Labels.Count())
+	for index := 0; index < len(santaFields); index++ {
+		fields = append(fields, e.field(santaFields[index]))
+	}
+	if e.labels {
+		for _, label := range entry.Labels.Labels() {
+			fields = append(fields, zap.String(label.Key, label.Value))
+		}
+	}
+	record := zapcore.Entry {
+		Level: level,
+		Time: entry.Time,
+		LoggerName: entry.Name,
+		Message: text,
+	}
+	if location := entry.SourceLocation; location.Parsed {
+		record.Caller = zapcore.NewEntryCaller(location.Proc, location.File,
+			location.Line, true)
+		if function := runtime.FuncForPC(location.Proc); function != nil {
+			record.Caller.Function = function.Name()
+		}
+	}
+	return e.core.Write(record, fields)
+}
+
+// Sync synchronizes the zap core, and then returns any errors encountered.
+func (e *Exporter) Sync() error {
+	return e.core.Sync()
+}
+
+// Close synchronizes the zap core, and then returns any errors encountered.
+// The zap core does not need to be closed.
+func (e *Exporter) Close() error {
+	return e.core.Sync()
+}
@@ -0,0 +1,160 @@
+// MIT License
+//
+// Copyright (c) 2020 Nobody Night
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in all
+// copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
+// SOFTWARE.
+
+package zapbridge
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/nobody-night/santa"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type testWriter struct {
+	mutex sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (w *testWriter) Write(data []byte) (int, error) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.Write(data)
+}
+
+func (w *testWriter) String() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.buffer.String()
+}
+
+func TestCore(t *testing.T) {
+	writer := &testWriter { }
+	syncer, err := santa.NewStandardSyncerOption().UseWriter(writer).
+		UseCacheCapacity(0).Build()
+	assert.NoError(t, err, "Unexpected syncer creation error")
+	encoder, err := santa.NewJSONEncoderOption().Build()
+	assert.NoError(t, err, "Unexpected encoder creation error")
+	exporter, err := santa.NewStandardExporterOption().UseEncoder(encoder).
+		UseSyncer(syncer).Build()
+	assert.NoError(t, err, "Unexpected exporter creation error")
+
+	core := NewCore(zapcore.InfoLevel, exporter).WithLabels(
+		santa.NewLabel("env", "test"))
+	logger := zap.New(core).Named("api").With(zap.String("service", "web"))
+	logger.Debug("hidden")
+	logger.Warn("Request failed", zap.Int("status", 503),
+		zap.Error(errors.New("timeout")), zap.Namespace("request"),
+		zap.String("path", "/"), zap.Strings("tags", []string { "a" }))
+	assert.NoError(t, logger.Sync(), "Unexpected sync error")
+
+	output := writer.String()
+	assert.NotContains(t, output, "hidden", "Unexpected disabled entry")
+	assert.Contains(t, output, `"level": "WARNING"`, "Unexpected level")
+	assert.Contains(t, output, `"name": "api"`, "Unexpected name")
+	assert.Contains(t, output, `"env": "test"`, "Unexpected labels")
+	assert.Contains(t, output, `"text": "Request failed"`,
+		"Unexpected message")
+	assert.Contains(t, output, `"service": "web", "status": 503, ` +
+		`"error": "timeout", "request": {"path": "/", "tags": ["a"]}`,
+		"Unexpected fields")
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+}
+
+func TestExporter(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	exporter := NewExporter(core, true)
+
+	entry := &santa.Entry {
+		Name: "api",
+		Level: santa.LevelError,
+		Message: &santa.StructMessage {
+			Text: "Upload failed",
+			Fields: []santa.Field {
+				santa.String("user", "alice"),
+				santa.Uint("size", 1024),
+				santa.Float64("ratio", 0.5),
+				santa.Boolean("retry", true),
+				santa.Error("error", errors.New("denied")),
+				santa.Object("geo", santa.String("country", "NZ")),
+			},
+		},
+		Labels: santa.NewSerializedLabels(santa.NewLabel("env", "test")),
+	}
+	assert.NoError(t, exporter.Export(entry), "Unexpected export error")
+	assert.NoError(t, exporter.Export(&santa.Entry {
+		Level: santa.LevelDebug,
+		Message: santa.StringMessage("hidden"),
+	}), "Unexpected export error")
+	assert.NoError(t, exporter.Export(&santa.Entry {
+		Level: santa.LevelFatal,
+		Message: santa.StringMessage("fatal"),
+	}), "Unexpected export error")
+	assert.NoError(t, exporter.Close(), "Unexpected close error")
+
+	entries := logs.AllUntimed()
+	assert.Len(t, entries, 2, "Unexpected entries")
+	assert.Equal(t, zapcore.ErrorLevel, entries[0].Level, "Unexpected level")
+	assert.Equal(t, "api", entries[0].LoggerName, "Unexpected name")
+	assert.Equal(t, "Upload failed", entries[0].Message, "Unexpected message")
+	context := entries[0].ContextMap()
+	assert.Equal(t, "alice", context["user"], "Unexpected field")
+	assert.Equal(t, uint64(1024), context["size"], "Unexpected field")
+	assert.Equal(t, 0.5, context["ratio"], "Unexpected field")
+	assert.Equal(t, true, context["retry"], "Unexpected field")
+	assert.Equal(t, "denied", context["error"], "Unexpected field")
+	assert.Equal(t, "test", context["env"], "Unexpected label")
+	assert.NotNil(t, context["geo"], "Unexpected field")
+	assert.Equal(t, zapcore.FatalLevel, entries[1].Level, "Unexpected level")
+	assert.Equal(t, "fatal", entries[1].Message, "Unexpected message")
+}
+
+func BenchmarkCoreWrite(b *testing.B) {
+	syncer, err := santa.NewStandardSyncerOption().UseWriter(io.Discard).
+		UseCacheCapacity(0).Build()
+	assert.NoError(b, err, "Unexpected syncer creation error")
+	encoder, err := santa.NewJSONEncoderOption().Build()
+	assert.NoError(b, err, "Unexpected encoder creation error")
+	exporter, err := santa.NewStandardExporterOption().UseEncoder(encoder).
+		UseSyncer(syncer).Build()
+	assert.NoError(b, err, "Unexpected exporter creation error")
+	defer exporter.Close()
+
+	core := NewCore(zapcore.InfoLevel, exporter).WithLabels(
+		santa.NewLabel("env", "test"))
+	entry := zapcore.Entry {
+		Level: zapcore.InfoLevel,
+		Message: "Request completed",
+	}
+	fields := []zapcore.Field { zap.Int("status", 200) }
+	b.ReportAllocs()
+	b.ResetTimer()
+	for index := 0; index < b.N; index++ {
+		_ = core.Write(entry, fields)
+	}
+}